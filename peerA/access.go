@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/hmac"   // For the password proof
+	"crypto/sha256" // For the password proof
+	"encoding/hex"  // For sending the proof as text
+	"errors"        // For access configuration errors
+	"fmt"           // For command output
+	"strings"       // For the pin prefix
+)
+
+/*
+Access modes
+
+حالت‌های دسترسی به گفتگو:
+- open: هر کلیدی که مسدود نباشد
+- invite: فقط کلیدهای دعوت‌شده
+- password: فقط peerی که رمز را بداند
+*/
+const (
+	accessOpen     = "open"     // Anyone not banned | هر کسی که مسدود نیست
+	accessInvite   = "invite"   // Only invited keys | فقط کلیدهای دعوت‌شده
+	accessPassword = "password" // Only peers that know the password | فقط دارندگان رمز
+)
+
+var (
+	errAccessMode = errors.New(`access must be "open", "invite" or "password"`) // Unknown access value | مقدار نامعتبر access
+	errNoPassword = errors.New(`access "password" needs a password`)            // Password mode without one | حالت رمز بدون رمز
+	errPinFormat  = errors.New(`pin must be "sha256:" and 16 to 64 hex digits`) // Unreadable pin | pin نامعتبر
+	errPinLink    = errors.New("the link names another key than the pin")       // Link and pin disagree | تعارض پیوند و pin
+)
+
+/*
+peerAuth is what the handshake needs to prove who we are and to decide
+whether the remote may join this conversation at all.
+
+این ساختار چیزهایی است که handshake برای اثبات هویت ما و تصمیم‌گیری
+درباره‌ی پذیرش peer مقابل در این گفتگو لازم دارد
+*/
+type peerAuth struct {
+	id       *identity    // Our signing key | کلید امضای ما
+	bans     *entrySet    // Banned key fingerprints | fingerprintهای مسدودشده
+	members  *entrySet    // Invited key fingerprints | fingerprintهای دعوت‌شده
+	access   string       // One of the access modes | یکی از حالت‌های دسترسی
+	password string       // Shared password, presented and required | رمز مشترک
+	pin      string       // Hex SHA-256 prefix of the only key the remote may prove, if set | پیشوند hash تنها کلید مجاز طرف مقابل در صورت تعیین
+	tokens   *tokenStore  // One-time invite tokens, if any | tokenهای یک‌بارمصرف دعوت در صورت وجود
+	resume   *resumeStore // Resumption tickets, if any | ticketهای ازسرگیری در صورت وجود
+}
+
+// defaultMembersPath returns the per-name invite list file | مسیر پیش‌فرض لیست دعوت‌شدگان
+func defaultMembersPath(name string) string {
+	return dataPath(name + ".members")
+}
+
+// newPeerAuth validates the access settings | اعتبارسنجی تنظیمات دسترسی
+func newPeerAuth(id *identity, bans, members *entrySet, access, password string) (*peerAuth, error) {
+	switch access {
+	case accessOpen, accessInvite:
+	case accessPassword:
+		if password == "" {
+			return nil, errNoPassword
+		}
+	default:
+		return nil, errAccessMode
+	}
+	return &peerAuth{id: id, bans: bans, members: members, access: access, password: password}, nil
+}
+
+const noProof = "-" // Password proof of a peer without a password | اثبات رمز peer بدون رمز
+
+/*
+pinKey reads the pin setting ("sha256:" and at least the 16 hex digits
+of a fingerprint, up to the whole hash of the key) and merges it with
+the fingerprint an invitation link names. Both must agree; the longer
+one is kept.
+
+این تابع تنظیم pin ("sha256:" و دست‌کم ۱۶ رقم hex یک fingerprint تا کل
+hash کلید) را می‌خواند و با fingerprint نام‌برده در پیوند دعوت ترکیب
+می‌کند؛ هر دو باید هم‌خوان باشند و بلندتر نگه داشته می‌شود
+*/
+func pinKey(setting, invited string) (string, error) {
+	pin := ""
+	if setting != "" {
+		h, ok := strings.CutPrefix(setting, "sha256:")
+		h = strings.ToLower(h)
+		if _, err := hex.DecodeString(h); !ok || err != nil || len(h) < 16 || len(h) > 64 {
+			return "", errPinFormat
+		}
+		pin = h
+	}
+	switch {
+	case strings.HasPrefix(pin, invited):
+		return pin, nil
+	case strings.HasPrefix(invited, pin):
+		return invited, nil
+	}
+	return "", errPinLink
+}
+
+/*
+admit returns why a remote with the given proven key and password proof
+over hellos, the HELLO lines in its order, may not join, or "" when it
+may. A proof made with one of our invite
+tokens stands in for an invite or the password; token then reports it,
+and the handshake redeems the token once the link is kept. A resumed
+key skips those checks, but never a ban or a pin. The reason is sent to
+the remote as-is.
+
+این تابع دلیل رد peer با کلید و اثبات رمز داده‌شده روی hellos (خطوط HELLO به ترتیب او) را برمی‌گرداند یا در
+صورت پذیرش رشته‌ی خالی؛ اثباتی که با یکی از tokenهای دعوت ما ساخته شده
+جای دعوت یا رمز را می‌گیرد؛ در این صورت token آن را گزارش می‌دهد و handshake
+پس از نگه‌داشتن اتصال token را مصرف می‌کند. کلید ازسرگیری‌شده از این
+بررسی‌ها معاف است ولی نه از مسدودی یا سنجاق. دلیل همان‌طور برای طرف مقابل
+ارسال می‌شود
+*/
+func (a *peerAuth) admit(key, proof string, hellos [2]string, resumed bool) (reason string, token bool) {
+	fp := keyFingerprint(key)
+	switch {
+	case fp == "" && (a.access != accessOpen || a.bans.len() > 0 || a.pin != ""):
+		return "unverifiable key", false
+	case a.pin != "" && !strings.HasPrefix(keyDigest(key), a.pin):
+		return "not the expected key", false
+	case a.bans.has(fp):
+		return "you are banned", false
+	case resumed:
+		// Admitted last time, within the window | دفعه‌ی قبل پذیرفته شده، در مهلت
+	case a.access == accessInvite && !a.members.has(fp):
+		if a.tokens.holds(proof, hellos) {
+			return "", true
+		}
+		if proof != noProof {
+			return "invite token expired or already used", false
+		}
+		return "this chat is invite-only", false
+	case a.access == accessPassword && !a.checkPassword(proof, hellos):
+		if a.tokens.holds(proof, hellos) {
+			return "", true
+		}
+		return "wrong password", false
+	}
+	return "", false
+}
+
+/*
+passwordProof proves knowledge of our password to the remote without
+revealing it: an HMAC over the handshake transcript, our HELLO line
+then the remote's, or "-" without a password. The remote's HELLO holds
+its fresh nonce, so the proof is worth nothing on any other connection.
+
+این تابع دانستن رمز را بدون افشای آن ثابت می‌کند: HMAC روی رونوشت
+handshake یعنی خط HELLO ما و سپس خط طرف مقابل، یا "-" در صورت نبود رمز؛
+HELLO طرف مقابل nonce تازه‌ی او را دارد، پس اثبات در هیچ اتصال دیگری
+ارزشی ندارد
+*/
+func (a *peerAuth) passwordProof(hellos [2]string) string {
+	if a.password == "" {
+		return noProof
+	}
+	return hex.EncodeToString(transcriptMAC(a.password, proofPassword, hellos))
+}
+
+// checkPassword reports whether the remote's proof over hellos, in its order, matches our password | آیا اثبات طرف مقابل روی hellos به ترتیب او با رمز ما می‌خواند
+func (a *peerAuth) checkPassword(proof string, hellos [2]string) bool {
+	if a.password == "" {
+		return true
+	}
+	got, err := hex.DecodeString(proof)
+	return err == nil && hmac.Equal(got, transcriptMAC(a.password, proofPassword, hellos))
+}
+
+/*
+policy names the access rules a resumption ticket is issued under: the
+access mode and a hash of the password. A ticket from other rules is
+not honoured.
+
+این تابع قوانین دسترسی‌ای را که ticket ازسرگیری تحت آن صادر می‌شود نام
+می‌برد: حالت دسترسی و hash رمز؛ ticket مربوط به قوانین دیگر پذیرفته نمی‌شود
+*/
+func (a *peerAuth) policy() string {
+	m := hmac.New(sha256.New, []byte(a.password))
+	m.Write([]byte(authContext + "\x00resume"))
+	return a.access + ":" + hex.EncodeToString(m.Sum(nil))
+}
+
+const proofPassword = "password" // What a password or invite token proof is made for | کاربرد اثبات رمز یا token دعوت
+
+/*
+transcriptMAC binds a secret to one handshake: an HMAC over what the
+proof is for and both HELLO lines, the prover's first, each after its
+length like a signed field.
+
+این تابع یک راز را به یک handshake گره می‌زند: HMAC روی کاربرد اثبات و هر
+دو خط HELLO، اول خط اثبات‌کننده، هر کدام پس از طولش مانند فیلد امضاشده
+*/
+func transcriptMAC(secret, purpose string, hellos [2]string) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(signedPayload([]string{authContext, purpose, hellos[0], hellos[1]}))
+	return m.Sum(nil)
+}
+
+func init() {
+	registerCommand("invite", "/invite <fingerprint>  let a key join an invite-only chat", inviteCommand)
+	registerCommand("uninvite", "/uninvite <fingerprint>  revoke an invite", uninviteCommand)
+	registerCommand("members", "/members  list invited keys", func(s *session, args []string) {
+		printEntries(s.auth.members, "No invited keys")
+	})
+}
+
+// inviteCommand adds a fingerprint to the invite list | افزودن fingerprint به لیست دعوت
+func inviteCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /invite <fingerprint>")
+		return
+	}
+	if err := s.auth.members.set(args[0], true); err != nil {
+		fmt.Fprintln(stdout, "Invite error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, "Invited", args[0])
+}
+
+// uninviteCommand removes a fingerprint from the invite list | حذف fingerprint از لیست دعوت
+func uninviteCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /uninvite <fingerprint>")
+		return
+	}
+	if err := s.auth.members.set(args[0], false); err != nil {
+		fmt.Fprintln(stdout, "Invite error:", err)
+		return
+	}
+	s.auth.resume.revoke(args[0]) // No way back in on an old ticket | بدون ورود دوباره با ticket قدیمی
+	fmt.Fprintln(stdout, "Invite revoked for", args[0])
+}
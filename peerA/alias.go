@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"  // For alias error values
+	"fmt"     // For command output
+	"sort"    // For a stable /alias listing
+	"strings" // For expanding aliases
+	"sync"    // For guarding the table
+
+	"peerA/config" // For saving aliases to the config file
+)
+
+const aliasDepth = 5 // Most nested alias expansions | بیشترین تودرتویی نام مستعار
+
+var (
+	errAliasCommand = errors.New("is a built-in command") // Aliases cannot shadow commands | نام مستعار نمی‌تواند جای دستور را بگیرد
+	errAliasDepth   = errors.New("aliases nested too deeply")
+	errAliasName    = errors.New("alias names are a single word") // Spaces or a slash | فاصله یا /
+)
+
+func init() {
+	registerCommand("alias", "/alias [name [expansion]]  list, show or define a command alias; steps are separated by ;", aliasCommand)
+	registerCommand("unalias", "/unalias <name>  remove a command alias", unaliasCommand)
+	registerArgCompleter("unalias", func(s *session, args []string, word string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return matching(word, s.aliases.names()...)
+	})
+}
+
+/*
+aliasTable holds the user's command aliases. An alias expands to one or
+more steps separated by ";", each a command or a chat message; "$*"
+stands for the alias's arguments, which are otherwise appended to the
+last step. Changes are written to the config file when there is one.
+
+این نوع نام‌های مستعار دستورهای کاربر را نگه می‌دارد؛ هر نام مستعار به یک
+یا چند گام جداشده با ";" باز می‌شود که هر کدام یک دستور یا پیام چت است؛
+"$*" جای آرگومان‌های آن است و در غیر این صورت آن‌ها به گام آخر افزوده
+می‌شوند. تغییرات در صورت وجود فایل پیکربندی در آن نوشته می‌شوند
+*/
+type aliasTable struct {
+	mu     sync.Mutex
+	file   string // Config file, empty keeps changes in memory | فایل پیکربندی
+	byName map[string]string
+}
+
+// newAliasTable starts from the aliases of the config | ساخت جدول از نام‌های مستعار پیکربندی
+func newAliasTable(aliases map[string]string, file string) *aliasTable {
+	t := &aliasTable{file: file, byName: make(map[string]string, len(aliases))}
+	for name, exp := range aliases {
+		t.byName[strings.TrimPrefix(name, "/")] = exp
+	}
+	return t
+}
+
+// get returns the expansion of name | بازشده‌ی name
+func (t *aliasTable) get(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	exp, ok := t.byName[name]
+	return exp, ok
+}
+
+// names lists the aliases | فهرست نام‌های مستعار
+func (t *aliasTable) names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]string, 0, len(t.byName))
+	for name := range t.byName {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+/*
+set defines name, or removes it when exp is empty, and saves the table.
+saved reports whether it reached the config file.
+
+این تابع name را تعریف یا با exp خالی حذف می‌کند و جدول را ذخیره می‌کند؛
+saved مشخص می‌کند که آیا در فایل پیکربندی نوشته شد
+*/
+func (t *aliasTable) set(name, exp string) (saved bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if exp == "" {
+		delete(t.byName, name)
+	} else {
+		t.byName[name] = exp
+	}
+	if t.file == "" {
+		return false, nil
+	}
+	return true, config.SaveAliases(t.file, t.byName)
+}
+
+/*
+expand runs the steps of alias name with args, reporting false when
+there is no such alias. Aliases may use other aliases up to aliasDepth
+deep, which also stops loops.
+
+این تابع گام‌های نام مستعار name را با args اجرا می‌کند و اگر چنین نام
+مستعاری نباشد false برمی‌گرداند؛ نام‌های مستعار تا عمق aliasDepth می‌توانند از
+یکدیگر استفاده کنند که جلوی حلقه را هم می‌گیرد
+*/
+func (t *aliasTable) expand(s *session, name string, args []string, depth int) bool {
+	exp, ok := t.get(name)
+	if !ok {
+		return false
+	}
+	if depth >= aliasDepth {
+		fmt.Fprintln(stdout, "Alias error:", errAliasDepth)
+		return true
+	}
+	steps := splitSteps(exp)
+	rest := strings.Join(args, " ")
+	if strings.Contains(exp, "$*") {
+		for i := range steps {
+			steps[i] = strings.TrimSpace(strings.ReplaceAll(steps[i], "$*", rest))
+		}
+	} else if rest != "" {
+		steps[len(steps)-1] += " " + rest
+	}
+	for _, step := range steps {
+		if !strings.HasPrefix(step, "/") {
+			if _, err := sendChat(s, step, nil, false); err != nil {
+				fmt.Fprintln(stdout, "Send error:", err)
+			}
+			continue
+		}
+		fields := strings.Fields(step[1:])
+		if len(fields) > 0 {
+			if _, builtin := commands[fields[0]]; !builtin && t.expand(s, fields[0], fields[1:], depth+1) {
+				continue // Commands win here as on the prompt | دستورها اینجا هم مانند خط ورودی مقدم‌اند
+			}
+		}
+		runCommand(s, step)
+	}
+	return true
+}
+
+// splitSteps splits an expansion at ";", where "\;" is a literal one | جداسازی گام‌ها با ";"؛ "\;" خود نویسه است
+func splitSteps(exp string) []string {
+	var steps []string
+	var b strings.Builder
+	for i := 0; i < len(exp); i++ {
+		switch {
+		case exp[i] == '\\' && i+1 < len(exp) && exp[i+1] == ';':
+			b.WriteByte(';')
+			i++
+		case exp[i] == ';':
+			steps = append(steps, strings.TrimSpace(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(exp[i])
+		}
+	}
+	steps = append(steps, strings.TrimSpace(b.String()))
+	out := steps[:0]
+	for _, st := range steps {
+		if st != "" {
+			out = append(out, st)
+		}
+	}
+	if len(out) == 0 {
+		return []string{""}
+	}
+	return out
+}
+
+/*
+aliasCommand lists the aliases, shows one, or defines one, e.g.
+"/alias brb /away back in 5" or "/alias hi hello everyone".
+
+این دستور نام‌های مستعار را فهرست، یکی را نمایش یا یکی را تعریف می‌کند،
+مثلاً "/alias brb /away back in 5" یا "/alias hi hello everyone"
+*/
+func aliasCommand(s *session, args []string) {
+	if len(args) == 0 {
+		names := s.aliases.names()
+		if len(names) == 0 {
+			fmt.Fprintln(stdout, "No aliases")
+		}
+		for _, name := range names {
+			exp, _ := s.aliases.get(name)
+			fmt.Fprintf(stdout, "  /%s = %s\n", name, exp)
+		}
+		return
+	}
+	name := strings.TrimPrefix(args[0], "/")
+	if len(args) == 1 {
+		exp, ok := s.aliases.get(name)
+		if !ok {
+			fmt.Fprintln(stdout, "No alias", "/"+name)
+			return
+		}
+		fmt.Fprintf(stdout, "/%s = %s\n", name, exp)
+		return
+	}
+	if name == "" || strings.Contains(name, "/") {
+		fmt.Fprintln(stdout, "Alias error:", errAliasName)
+		return
+	}
+	if _, ok := commands[name]; ok {
+		fmt.Fprintf(stdout, "Alias error: /%s %v\n", name, errAliasCommand)
+		return
+	}
+	saved, err := s.aliases.set(name, strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Fprintln(stdout, "Alias error:", err)
+		return
+	}
+	fmt.Fprintf(stdout, "Alias /%s defined%s\n", name, aliasSaveNote(saved))
+}
+
+// unaliasCommand removes an alias | حذف یک نام مستعار
+func unaliasCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /unalias <name>")
+		return
+	}
+	name := strings.TrimPrefix(args[0], "/")
+	if _, ok := s.aliases.get(name); !ok {
+		fmt.Fprintln(stdout, "No alias", "/"+name)
+		return
+	}
+	saved, err := s.aliases.set(name, "")
+	if err != nil {
+		fmt.Fprintln(stdout, "Alias error:", err)
+		return
+	}
+	fmt.Fprintf(stdout, "Alias /%s removed%s\n", name, aliasSaveNote(saved))
+}
+
+// aliasSaveNote tells whether a change outlives the run | آیا تغییر پس از این اجرا باقی می‌ماند
+func aliasSaveNote(saved bool) string {
+	if saved {
+		return ""
+	}
+	return " for this run (start with -config to keep aliases)"
+}
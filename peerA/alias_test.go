@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitSteps(t *testing.T) {
+	for exp, want := range map[string]string{
+		"/away brb":                "/away brb",
+		"/away brb; back soon ;; ": "/away brb|back soon",
+		`smile \; wink;/dnd 5m`:    "smile ; wink|/dnd 5m",
+		" ; ":                      "",
+	} {
+		if got := strings.Join(splitSteps(exp), "|"); got != want {
+			t.Errorf("splitSteps(%q) = %q, want %q", exp, got, want)
+		}
+	}
+}
+
+func TestAliasExpand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	keys, _ := loadRegistry("")
+	out := make(chan string, 8)
+	s := codeSession(t, capabilities{MaxMessage: 4096}, out)
+	s.aliases = newAliasTable(map[string]string{
+		"/hi":   "hello $*; /wave",
+		"wave":  "*waves*",
+		"shout": "hey",
+		"loop":  "/loop",
+		"alias": "not the command", // Shadows a command, so never runs | هم‌نام یک دستور است پس اجرا نمی‌شود
+		"list":  "/alias",
+	}, "")
+
+	sent := func() []string {
+		var texts []string
+		for len(out) > 0 {
+			m, _ := decodeChatLine(<-out, keys)
+			texts = append(texts, m.Text)
+		}
+		return texts
+	}
+	runCommand(s, "/hi there all")
+	runCommand(s, "/shout at me")
+	if got := strings.Join(sent(), "|"); got != "hello there all|*waves*|hey at me" {
+		t.Errorf("sent %q", got)
+	}
+
+	buf.Reset()
+	runCommand(s, "/loop")
+	if buf.String() != "Alias error: aliases nested too deeply\n" || len(out) != 0 {
+		t.Errorf("looping alias: output %q, sent %q", buf.String(), sent())
+	}
+
+	buf.Reset()
+	runCommand(s, "/list")
+	if !strings.Contains(buf.String(), "  /shout = hey\n") || len(out) != 0 {
+		t.Errorf("an alias shadowed /alias inside an expansion: output %q, sent %q", buf.String(), sent())
+	}
+}
+
+func TestAliasCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	s := &session{aliases: newAliasTable(nil, "")}
+
+	for _, line := range []string{
+		"/alias",
+		"/alias /brb /away back in 5; /dnd 5m",
+		"/alias brb",
+		"/alias help me",
+		"/alias a/b x",
+		"/alias",
+		"/unalias brb",
+		"/unalias brb",
+	} {
+		runCommand(s, line)
+	}
+	want := "No aliases\n" +
+		"Alias /brb defined for this run (start with -config to keep aliases)\n" +
+		"/brb = /away back in 5; /dnd 5m\n" +
+		"Alias error: /help is a built-in command\n" +
+		"Alias error: alias names are a single word\n" +
+		"  /brb = /away back in 5; /dnd 5m\n" +
+		"Alias /brb removed for this run (start with -config to keep aliases)\n" +
+		"No alias /brb\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	path := filepath.Join(t.TempDir(), "peerchat.conf")
+	s.aliases = newAliasTable(nil, path)
+	buf.Reset()
+	runCommand(s, "/alias hi /me waves")
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "/me waves") || buf.String() != "Alias /hi defined\n" {
+		t.Errorf("saved %q, %v; output %q", data, err, buf.String())
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestARQFinAfterData(t *testing.T) {
+	client, server, err := benchLink("udp", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	want := bytes.Repeat([]byte("0123456789"), 20*arqPayload)
+	go func() {
+		_, _ = client.Write(want)
+		_ = client.Close() // Right away: the fin must not overtake the data | فوراً: fin نباید از داده جلو بزند
+	}()
+	_ = server.SetReadDeadline(time.Now().Add(arqLinger))
+	got, err := io.ReadAll(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read %d bytes before EOF, want %d", len(got), len(want))
+	}
+}
+
+func TestARQReadDeadline(t *testing.T) {
+	client, server, err := benchLink("udp", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := server.Read(make([]byte, 1))
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_ = server.SetReadDeadline(time.Now().Add(50 * time.Millisecond)) // Wakes the blocked Read | بیدارکردن Read منتظر
+	select {
+	case err := <-read:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("Read = %v, want a deadline error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read kept blocking past its deadline")
+	}
+
+	_ = server.SetReadDeadline(time.Time{})
+	if _, err := client.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read after clearing the deadline: %v", err)
+	}
+}
+
+func TestARQWriteDeadline(t *testing.T) {
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0") // Never acknowledges | هرگز تأیید نمی‌کند
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newARQConn(pc, silent.LocalAddr(), 0)
+	defer c.Close()
+
+	_ = c.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	n, err := c.Write(make([]byte, (arqWindow+1)*arqPayload))
+	if !errors.Is(err, os.ErrDeadlineExceeded) || n != arqWindow*arqPayload {
+		t.Fatalf("Write = %d, %v; want the window, then a deadline error", n, err)
+	}
+}
+
+func TestUDPTransport(t *testing.T) {
+	ln, err := udpTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	for _, name := range []string{"a", "b"} { // Two remotes share the port | دو طرف یک پورت را تقسیم می‌کنند
+		c, err := udpTransport{}.dial(ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := io.WriteString(c, "HELLO "+name+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		s, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		_ = s.SetDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 64)
+		n, err := s.Read(b)
+		if err != nil || string(b[:n]) != "HELLO "+name+"\n" {
+			t.Fatalf("listener read %q, %v", b[:n], err)
+		}
+		if _, err := io.WriteString(s, "HELLO back\n"); err != nil {
+			t.Fatal(err)
+		}
+		_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+		if n, err = c.Read(b); err != nil || string(b[:n]) != "HELLO back\n" {
+			t.Fatalf("dialer read %q, %v", b[:n], err)
+		}
+	}
+}
+
+// wirePackets records what an arqConn puts on the wire | ثبت بسته‌هایی که arqConn ارسال می‌کند
+type wirePackets struct {
+	net.PacketConn
+	mu   sync.Mutex
+	sent [][]byte
+}
+
+func (w *wirePackets) WriteTo(p []byte, _ net.Addr) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sent = append(w.sent, slices.Clone(p))
+	return len(p), nil
+}
+
+// take returns and forgets the packets sent so far | بازگرداندن و پاک‌کردن بسته‌های ارسال‌شده
+func (w *wirePackets) take() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sent := w.sent
+	w.sent = nil
+	return sent
+}
+
+// idleARQ returns an arqConn with no receive or retransmit loop, sending into a recorder | arqConn بدون حلقه‌های دریافت و ارسال دوباره
+func idleARQ() (*arqConn, *wirePackets) {
+	w := &wirePackets{}
+	c := &arqConn{pc: w, raddr: &net.UDPAddr{}, unacked: make(map[uint32]*arqSegment), early: make(map[uint32][]byte)}
+	c.cond = sync.NewCond(&c.mu)
+	return c, w
+}
+
+// sackOf encodes [start, end) pairs as SACK blocks | تبدیل جفت‌ها به بلوک‌های SACK
+func sackOf(blocks ...[2]uint32) []byte {
+	var out []byte
+	for _, b := range blocks {
+		out = binary.BigEndian.AppendUint32(out, b[0])
+		out = binary.BigEndian.AppendUint32(out, b[1])
+	}
+	return out
+}
+
+func TestSACKBlocks(t *testing.T) {
+	many := make(map[uint32][]byte)
+	for seq := uint32(1); seq < 2*arqWindow; seq += 2 {
+		many[seq] = nil // Every other packet: more runs than fit | یکی در میان: بیش از حد جا
+	}
+	for _, c := range []struct {
+		name  string
+		early []uint32
+		want  [][2]uint32
+	}{
+		{"none", nil, nil},
+		{"one", []uint32{4}, [][2]uint32{{4, 5}}},
+		{"run", []uint32{7, 5, 6}, [][2]uint32{{5, 8}}},
+		{"gaps", []uint32{3, 4, 9, 12, 13, 14}, [][2]uint32{{3, 5}, {9, 10}, {12, 15}}},
+	} {
+		early := make(map[uint32][]byte)
+		for _, seq := range c.early {
+			early[seq] = nil
+		}
+		if got := sackBlocks(early); !bytes.Equal(got, sackOf(c.want...)) {
+			t.Errorf("%s: blocks %v, want %v", c.name, got, sackOf(c.want...))
+		}
+	}
+	if got := sackBlocks(many); len(got) != arqSACKMax*8 || binary.BigEndian.Uint32(got) != 1 {
+		t.Errorf("many runs: %d bytes starting at %d, want the lowest %d blocks", len(got), binary.BigEndian.Uint32(got), arqSACKMax)
+	}
+}
+
+func TestARQAcked(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		next     uint32
+		sack     []byte
+		wantBase uint32
+		wantLeft []uint32
+	}{
+		{"cumulative", 4, nil, 4, []uint32{4, 5, 6, 7, 8, 9}},
+		{"selective", 2, sackOf([2]uint32{4, 6}, [2]uint32{8, 9}), 2, []uint32{2, 3, 6, 7, 9}},
+		{"everything", 10, nil, 10, nil},
+		{"past what was sent", 11, nil, 0, []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"block wider than the window", 0, sackOf([2]uint32{0, arqWindow + 1}), 0, []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"truncated block", 0, sackOf([2]uint32{0, 3})[:6], 0, []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	} {
+		a, _ := idleARQ()
+		for ; a.next < 10; a.next++ {
+			a.unacked[a.next] = &arqSegment{}
+		}
+		a.acked(c.next, c.sack)
+		var left []uint32
+		for seq := range a.unacked {
+			left = append(left, seq)
+		}
+		slices.Sort(left)
+		if a.base != c.wantBase || !slices.Equal(left, c.wantLeft) {
+			t.Errorf("%s: base %d, unacked %v; want %d, %v", c.name, a.base, left, c.wantBase, c.wantLeft)
+		}
+	}
+}
+
+func TestARQDeliverHoldsEarlyPackets(t *testing.T) {
+	a, w := idleARQ()
+	a.deliver(arqData, 0, []byte("a"))
+	a.deliver(arqData, 2, []byte("c"))
+	a.deliver(arqData, 3, []byte("d"))
+	if string(a.buf) != "a" {
+		t.Fatalf("buffered %q before the gap was filled, want %q", a.buf, "a")
+	}
+	var nacks []uint32
+	var last []byte
+	for _, p := range w.take() {
+		switch p[0] {
+		case arqNack:
+			nacks = append(nacks, binary.BigEndian.Uint32(p[1:arqHeader]))
+		case arqAck:
+			last = p
+		}
+	}
+	if !slices.Equal(nacks, []uint32{1, 1}) {
+		t.Errorf("NACKs %v, want the gap for each early packet", nacks)
+	}
+	if binary.BigEndian.Uint32(last[1:arqHeader]) != 1 || !bytes.Equal(last[arqHeader:], sackOf([2]uint32{2, 4})) {
+		t.Errorf("last ACK %v, want 1 with SACK [2, 4)", last)
+	}
+
+	a.deliver(arqData, 1, []byte("b"))
+	a.deliver(arqData, 2, []byte("x")) // Duplicate | تکراری
+	if string(a.buf) != "abcd" || len(a.early) != 0 {
+		t.Fatalf("buffered %q with %d held, want %q and none", a.buf, len(a.early), "abcd")
+	}
+	sent := w.take()
+	if ack := sent[len(sent)-1]; binary.BigEndian.Uint32(ack[1:arqHeader]) != 4 || len(ack) != arqHeader {
+		t.Errorf("ACK after the gap %v, want 4 without SACK", ack)
+	}
+}
+
+func TestARQWindowAccounting(t *testing.T) {
+	a, w := idleARQ()
+	if n, err := a.Write(make([]byte, arqWindow*arqPayload)); err != nil || n != arqWindow*arqPayload {
+		t.Fatalf("filling the window: %d, %v", n, err)
+	}
+	if sent := len(w.take()); sent != arqWindow {
+		t.Fatalf("%d packets in flight, want %d", sent, arqWindow)
+	}
+	full := func() error {
+		_ = a.SetWriteDeadline(time.Now().Add(30 * time.Millisecond))
+		_, err := a.Write([]byte("x"))
+		return err
+	}
+	if err := full(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("write into a full window: %v, want a deadline error", err)
+	}
+	a.acked(0, sackOf([2]uint32{1, arqWindow})) // Everything but the first, selectively | همه جز اولی به‌صورت انتخابی
+	if err := full(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("SACKed packets moved the window: %v", err)
+	}
+	if len(a.unacked) != 1 {
+		t.Fatalf("%d packets left to resend, want 1", len(a.unacked))
+	}
+	a.acked(arqWindow, nil)
+	if err := full(); err != nil {
+		t.Fatalf("write after a cumulative ACK: %v", err)
+	}
+	if a.next-a.base != 1 {
+		t.Fatalf("%d packets in flight, want 1", a.next-a.base)
+	}
+}
+
+func TestARQResendOnNack(t *testing.T) {
+	a, w := idleARQ()
+	if _, err := a.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	w.take()
+	a.resend(0)
+	a.resend(0) // Within the same tick | در همان دوره
+	a.resend(7) // Never sent | هرگز ارسال نشده
+	if sent := w.take(); len(sent) != 1 || string(sent[0][arqHeader:]) != "hello" {
+		t.Fatalf("resent %d packets, want the NACKed one once", len(sent))
+	}
+	time.Sleep(arqTick)
+	a.resend(0)
+	if sent := w.take(); len(sent) != 1 {
+		t.Fatalf("resent %d packets a tick later, want 1", len(sent))
+	}
+	a.acked(1, nil)
+	a.resend(0)
+	if sent := w.take(); len(sent) != 0 {
+		t.Fatalf("resent an acknowledged packet")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"         // For the /stats line
+	"math"        // For the smoothing factor
+	"net"         // For the metered connection
+	"sync"        // For guarding the rates
+	"sync/atomic" // For the byte counters
+	"time"        // For the sampling period
+)
+
+/*
+Bandwidth sampling
+
+نمونه‌برداری پهنای باند:
+- هر bandwidthSample تعداد بایت‌ها خوانده می‌شود
+- نرخ‌ها با میانگین نمایی در حدود bandwidthWindow هموار می‌شوند
+*/
+const (
+	bandwidthSample = time.Second     // Time between samples | فاصله‌ی نمونه‌ها
+	bandwidthWindow = 5 * time.Second // Smoothing window | پنجره‌ی هموارسازی
+)
+
+// bandwidth meters the bytes on the peer link | اندازه‌گیری بایت‌های اتصال به peer
+var bandwidth = &bandwidthMeter{}
+
+/*
+bandwidthMeter counts the bytes read from and written to the link and
+keeps a smoothed rate of each, updated every bandwidthSample.
+
+این نوع بایت‌های خوانده‌شده و نوشته‌شده روی اتصال را می‌شمارد و نرخ
+هموارشده‌ی هر کدام را نگه می‌دارد که هر bandwidthSample به‌روز می‌شود
+*/
+type bandwidthMeter struct {
+	up, down atomic.Int64 // Bytes written and read | بایت‌های نوشته و خوانده‌شده
+	once     sync.Once
+	mu       sync.Mutex
+	upRate   float64 // Smoothed bytes per second written | نرخ هموارشده‌ی ارسال
+	downRate float64 // Smoothed bytes per second read | نرخ هموارشده‌ی دریافت
+}
+
+/*
+wrap returns conn with its traffic counted, starting the sampler on
+first use.
+
+این تابع conn را با شمارش ترافیک آن برمی‌گرداند و در اولین استفاده
+نمونه‌بردار را راه می‌اندازد
+*/
+func (b *bandwidthMeter) wrap(conn net.Conn) net.Conn {
+	b.once.Do(func() { go b.sample() })
+	return meteredConn{Conn: conn, meter: b}
+}
+
+// sample folds each period's bytes into the smoothed rates | افزودن بایت‌های هر دوره به نرخ‌های هموارشده
+func (b *bandwidthMeter) sample() {
+	alpha := 1 - math.Exp(-bandwidthSample.Seconds()/bandwidthWindow.Seconds())
+	var lastUp, lastDown int64
+	for range time.Tick(bandwidthSample) {
+		up, down := b.up.Load(), b.down.Load()
+		b.mu.Lock()
+		b.upRate += (float64(up-lastUp)/bandwidthSample.Seconds() - b.upRate) * alpha
+		b.downRate += (float64(down-lastDown)/bandwidthSample.Seconds() - b.downRate) * alpha
+		b.mu.Unlock()
+		lastUp, lastDown = up, down
+	}
+}
+
+// rates returns the smoothed bytes per second up and down | نرخ‌های هموارشده‌ی ارسال و دریافت
+func (b *bandwidthMeter) rates() (up, down float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.upRate, b.downRate
+}
+
+// String renders the current rates for /stats | نمایش نرخ‌های فعلی برای /stats
+func (b *bandwidthMeter) String() string {
+	up, down := b.rates()
+	return fmt.Sprintf("Bandwidth: up %s/s, down %s/s", formatBytes(int64(up)), formatBytes(int64(down)))
+}
+
+// addMetrics exports the link counters and rates | صادرکردن شمارنده‌ها و نرخ‌های اتصال
+func (b *bandwidthMeter) addMetrics(m *metrics) {
+	m.add(`link_bytes_total{direction="up"}`, "counter", "Bytes written to the peer link.", func() float64 { return float64(b.up.Load()) })
+	m.add(`link_bytes_total{direction="down"}`, "counter", "Bytes read from the peer link.", func() float64 { return float64(b.down.Load()) })
+	m.add(`link_throughput_bytes{direction="up"}`, "gauge", "Smoothed bytes per second on the peer link.", func() float64 { up, _ := b.rates(); return up })
+	m.add(`link_throughput_bytes{direction="down"}`, "gauge", "Smoothed bytes per second on the peer link.", func() float64 { _, down := b.rates(); return down })
+}
+
+// meteredConn counts the bytes passing through a connection | شمارش بایت‌های عبوری از اتصال
+type meteredConn struct {
+	net.Conn
+	meter *bandwidthMeter
+}
+
+func (c meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.meter.down.Add(int64(n))
+	return n, err
+}
+
+func (c meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.meter.up.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"       // For the skipped handshake's reader
+	"errors"      // For bench argument errors
+	"flag"        // For the bench subcommand flags
+	"fmt"         // For the result table
+	"io"          // For draining relay clients
+	"net"         // For the links under test
+	"slices"      // For sorting latencies
+	"strconv"     // For parsing sizes and sequence IDs
+	"strings"     // For splitting flag lists and building payloads
+	"sync/atomic" // For send timestamps shared with the receiver
+	"time"        // For timing the runs
+
+	"github.com/hashicorp/yamux" // For the sessions under test
+)
+
+var (
+	errBenchSize      = errors.New("sizes must be positive byte counts that fit in one chat line") // Bad --sizes value | مقدار نامعتبر sizes
+	errBenchTransport = errors.New(`transports must be "pipe", "tcp" or "udp"`)                    // Bad --transport value | مقدار نامعتبر transport
+	errBenchCount     = errors.New("n must be at least 100 messages")                              // Too few for a p99 | برای p99 کافی نیست
+)
+
+/*
+runBench implements "bench": it pushes n signed chat messages of each
+size between two in-process peers, over an in-process pipe, loopback
+TCP or loopback UDP through the ARQ layer, through the same mux, writer
+and reader the chat uses, then fans lines out through the daemon relay.
+Every row reports messages and megabytes per second and the p99 latency
+under full load. It times the runs itself, so the testing package stays
+out of the binary; go test -bench runs the same code as benchmarks:
+
+	peerA bench --sizes 64,1024,16384 --transport pipe,tcp --relay-clients 4
+
+این تابع زیرفرمان bench را اجرا می‌کند: n پیام امضاشده با هر اندازه را بین دو
+peer درون یک پردازه، روی pipe درون برنامه، TCP محلی یا UDP محلی با لایه‌ی ARQ و
+از همان مسیر mux، نویسنده و خواننده‌ی چت ارسال می‌کند و سپس خطوط را از relay
+حالت daemon پخش می‌کند. هر سطر تعداد پیام و مگابایت در ثانیه و تأخیر p99 زیر
+بار کامل را گزارش می‌دهد. زمان‌سنجی را خودش انجام می‌دهد تا بسته‌ی testing وارد
+باینری نشود؛ go test -bench همین کد را به‌صورت benchmark اجرا می‌کند
+*/
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	sizesFlag := fs.String("sizes", "64,1024,16384", "comma-separated message sizes in bytes")
+	transportFlag := fs.String("transport", "pipe,tcp", `comma-separated transports: "pipe", "tcp", "udp"`)
+	relayClients := fs.Int("relay-clients", 4, "attached clients in the relay fan-out run (0 skips it)")
+	count := fs.Int("n", 5000, "messages per row")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count < 100 {
+		return errBenchCount
+	}
+
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		return err
+	}
+	defer id.wipe()
+
+	var sizes []int
+	for _, f := range strings.Split(*sizesFlag, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n <= 0 || len(encodeChat(id, benchMessage(n, 0)))+1 > maxMessageSize {
+			return fmt.Errorf("%w: %q", errBenchSize, f)
+		}
+		sizes = append(sizes, n)
+	}
+	transports := strings.Split(*transportFlag, ",")
+	for _, t := range transports {
+		if t != "pipe" && t != "tcp" && t != "udp" {
+			return fmt.Errorf("%w: %q", errBenchTransport, t)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%-16s %8s %12s %10s %12s\n", "benchmark", "size", "msgs/sec", "MB/sec", "p99")
+	for _, t := range transports {
+		for _, size := range sizes {
+			elapsed, p99, err := benchChat(t, id, size, *count, nil)
+			if err != nil {
+				return err
+			}
+			printBenchRow("chat/"+t, size, *count, elapsed, p99.Round(time.Microsecond).String())
+		}
+	}
+	if *relayClients > 0 {
+		for _, size := range sizes {
+			printBenchRow(fmt.Sprintf("relay/%d", *relayClients), size, *count, benchRelay(*relayClients, size, *count), "-")
+		}
+	}
+	return nil
+}
+
+// printBenchRow prints one line of the result table | چاپ یک سطر از جدول نتایج
+func printBenchRow(name string, size, n int, elapsed time.Duration, p99 string) {
+	secs := elapsed.Seconds()
+	fmt.Fprintf(stdout, "%-16s %8d %12.0f %10.2f %12s\n", name, size, float64(n)/secs, float64(n)*float64(size)/secs/1e6, p99)
+}
+
+// benchMessage returns a message whose text is size bytes long | ساخت پیامی با متن size بایتی
+func benchMessage(size, seq int) message {
+	return message{Time: time.Now(), From: "bench", Text: strings.Repeat("x", size), ID: strconv.Itoa(seq)}
+}
+
+/*
+benchChat sends n messages of size bytes from one peer to the other
+over the given transport and returns how long that took and the p99
+latency. The message ID carries its sequence number, which the receiver
+uses to find the send time. start, when set, runs once the link is up,
+just before the timed part.
+
+این تابع n پیام size بایتی را روی transport داده‌شده از یک peer به دیگری
+می‌فرستد و مدت آن و تأخیر p99 را برمی‌گرداند. شناسه‌ی پیام شماره‌ی ترتیب آن
+است و گیرنده با آن زمان ارسال را پیدا می‌کند. start در صورت وجود پس از
+برقراری اتصال و درست پیش از بخش زمان‌سنجی‌شده اجرا می‌شود
+*/
+func benchChat(transport string, id *identity, size, n int, start func()) (elapsed, p99 time.Duration, err error) {
+	client, server, err := benchLink(transport, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer client.Close()
+	defer server.Close()
+
+	done := newDoneSignal()
+	defer done.close()
+	cs, ss, err := benchMux(client, server)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cs.Close()
+	defer ss.Close()
+	out, err := openStream(cs, streamChat)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	keys, _ := loadRegistry("") // Memory only | فقط در حافظه
+	outgoing := make(chan string, 32)
+	incoming := make(chan message, 32)
+	var sent, taken atomic.Int64
+	var framesOut, framesIn atomic.Uint64
+	go connWriter(out, outgoing, &sent, &taken, &framesOut, nil, done)
+	go acceptStreams(ss, map[string]func(net.Conn){
+		streamChat: func(st net.Conn) { connReader(st, incoming, keys, nil, &framesIn, done) },
+	}, done)
+
+	sentAt := make([]atomic.Int64, n)
+	latencies := make([]time.Duration, 0, n)
+	if start != nil {
+		start()
+	}
+	began := time.Now()
+	go func() {
+		for i := 0; i < n; i++ {
+			m := benchMessage(size, i)
+			sentAt[i].Store(time.Now().UnixNano())
+			select {
+			case outgoing <- encodeChat(id, m):
+			case <-done.c:
+				return
+			}
+		}
+	}()
+	for len(latencies) < n {
+		select {
+		case m := <-incoming:
+			seq, err := strconv.Atoi(m.ID)
+			if err != nil || seq >= n || !m.Verified {
+				return 0, 0, fmt.Errorf("bench: unexpected message %q", m.ID)
+			}
+			latencies = append(latencies, time.Duration(time.Now().UnixNano()-sentAt[seq].Load()))
+		case <-done.c:
+			return 0, 0, errors.New("bench: link closed early")
+		}
+	}
+	elapsed = time.Since(began)
+
+	slices.Sort(latencies)
+	return elapsed, latencies[len(latencies)*99/100], nil
+}
+
+/*
+benchRelay publishes n lines of size bytes to a daemon relay with the
+given number of attached clients, each draining its end of a net.Pipe,
+and returns how long that took.
+
+این تابع n خط size بایتی را برای relay حالت daemon با تعداد کلاینت
+داده‌شده منتشر می‌کند و مدت آن را برمی‌گرداند؛ هر کلاینت سر خود از
+net.Pipe را تخلیه می‌کند
+*/
+func benchRelay(clients, size, n int) time.Duration {
+	d := &daemonRelay{clients: make(map[net.Conn]struct{}), local: io.Discard}
+	for i := 0; i < clients; i++ {
+		relayEnd, clientEnd := net.Pipe()
+		d.clients[relayEnd] = struct{}{}
+		go func() { _, _ = io.Copy(io.Discard, clientEnd) }()
+	}
+	defer d.closeClients()
+
+	line := []byte(strings.Repeat("x", size))
+	began := time.Now()
+	for i := 0; i < n; i++ {
+		d.publish(line)
+	}
+	return time.Since(began)
+}
+
+/*
+benchLink returns the two ends of a link: an in-process pipe, a
+loopback TCP connection tuned like a real chat link, or a pair of
+loopback UDP sockets made reliable by the ARQ layer, with loss of the
+packets dropped on purpose. Pipe and TCP links are made through their
+transports, as the chat makes them. The bench and soak subcommands and
+the benchmarks in bench_test.go run over it.
+
+این تابع دو سر یک اتصال را برمی‌گرداند: pipe درون برنامه، اتصال TCP
+محلی با همان تنظیمات اتصال واقعی چت، یا دو socket محلی UDP که لایه‌ی
+ARQ آن‌ها را قابل‌اعتماد می‌کند و کسر loss از بسته‌ها عمداً حذف می‌شود؛
+اتصال‌های pipe و TCP مانند چت از طریق انتقال خود ساخته می‌شوند. زیرفرمان‌های
+bench و soak و benchmarkهای bench_test.go روی آن اجرا می‌شوند
+*/
+func benchLink(kind string, loss float64) (client, server net.Conn, err error) {
+	var tr transport = tcpTransport{}
+	addr := "127.0.0.1:0"
+	switch kind {
+	case "pipe":
+		tr, addr = inprocTransport{}, ""
+	case "udp":
+		a, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			a.Close()
+			return nil, nil, err
+		}
+		return newARQConn(a, b.LocalAddr(), loss), newARQConn(b, a.LocalAddr(), loss), nil
+	}
+	ln, err := tr.listen(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go acceptOnce(ln, accepted, nil) // A nil stop never fires | stop برابر nil هرگز فعال نمی‌شود
+	client, err = tr.dial(ln.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	server = <-accepted
+	opts := socketOptions{keepAlive: defaultKeepAlive, noDelay: true, linger: -1}
+	tuneSocket(client, opts)
+	tuneSocket(server, opts)
+	return client, server, nil
+}
+
+/*
+benchMux layers the chat's yamux sessions over both ends of a link;
+the handshake is skipped, so the client end simply acts as arbiter.
+
+این تابع sessionهای yamux چت را روی دو سر اتصال می‌سازد؛ handshake
+انجام نمی‌شود و سر client نقش داور را دارد
+*/
+func benchMux(client, server net.Conn) (*yamux.Session, *yamux.Session, error) {
+	cs, err := newMuxSession(&handshakeConn{Conn: client, r: bufio.NewReader(client), arbiter: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := newMuxSession(&handshakeConn{Conn: server, r: bufio.NewReader(server)})
+	if err != nil {
+		_ = cs.Close()
+		return nil, nil, err
+	}
+	return cs, ss, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"  // For address error values
+	"fmt"     // For printing addresses
+	"net"     // For parsing the device address
+	"strconv" // For the channel number
+	"strings" // For splitting address and channel
+)
+
+const rfcommDefaultChannel = 1 // Channel used when the address names none | کانال پیش‌فرض وقتی آدرس کانالی ندارد
+
+var errRFCOMMAddr = errors.New(`bluetooth addresses look like "AA:BB:CC:DD:EE:FF/1", the channel from 1 to 30`) // Unreadable address | آدرس نامعتبر
+
+/*
+rfcommAddr is a Bluetooth device address and RFCOMM channel, written
+"AA:BB:CC:DD:EE:FF/1". The device part may be left out when listening,
+which listens on every adapter, and a missing channel is
+rfcommDefaultChannel.
+
+این نوع آدرس دستگاه بلوتوث و کانال RFCOMM است که به شکل
+"AA:BB:CC:DD:EE:FF/1" نوشته می‌شود؛ هنگام listen می‌توان بخش دستگاه را
+حذف کرد تا روی همه‌ی adapterها گوش داده شود و کانال حذف‌شده
+rfcommDefaultChannel است
+*/
+type rfcommAddr struct {
+	device  [6]byte // As written, most significant byte first | به ترتیب نوشتن، پرارزش‌ترین بایت اول
+	channel uint8
+}
+
+// parseRFCOMMAddr reads an address; anyDevice tells whether the device may be left out | خواندن آدرس؛ anyDevice یعنی دستگاه می‌تواند حذف شود
+func parseRFCOMMAddr(s string, anyDevice bool) (rfcommAddr, error) {
+	var a rfcommAddr
+	device, channel, found := strings.Cut(s, "/")
+	a.channel = rfcommDefaultChannel
+	if found {
+		n, err := strconv.Atoi(channel)
+		if err != nil || n < 1 || n > 30 {
+			return a, errRFCOMMAddr
+		}
+		a.channel = uint8(n)
+	}
+	if device == "" && anyDevice {
+		return a, nil
+	}
+	mac, err := net.ParseMAC(device)
+	if err != nil || len(mac) != len(a.device) {
+		return a, errRFCOMMAddr
+	}
+	copy(a.device[:], mac)
+	return a, nil
+}
+
+func (rfcommAddr) Network() string { return "rfcomm" }
+
+func (a rfcommAddr) String() string {
+	d := a.device
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X/%d", d[0], d[1], d[2], d[3], d[4], d[5], a.channel)
+}
+
+/*
+bluetoothTransport links two nearby machines over Bluetooth RFCOMM, a
+reliable byte stream like TCP, with no network at all. dial and listen
+take rfcommAddr addresses; the devices are paired beforehand with the
+system's tools, such as bluetoothctl.
+
+این نوع دو ماشین نزدیک را بدون هیچ شبکه‌ای با RFCOMM بلوتوث، یک stream
+بایتی قابل‌اعتماد مانند TCP، به هم وصل می‌کند؛ dial و listen آدرس
+rfcommAddr می‌گیرند و دستگاه‌ها پیش‌تر با ابزار سیستم مانند bluetoothctl
+جفت شده‌اند
+*/
+type bluetoothTransport struct{}
+
+func (bluetoothTransport) dial(remote string) (net.Conn, error) {
+	a, err := parseRFCOMMAddr(remote, false)
+	if err != nil {
+		return nil, err
+	}
+	return dialRFCOMM(a)
+}
+
+func (bluetoothTransport) listen(addr string) (net.Listener, error) {
+	a, err := parseRFCOMMAddr(addr, true)
+	if err != nil {
+		return nil, err
+	}
+	return listenRFCOMM(a)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestParseRFCOMMAddr(t *testing.T) {
+	for _, c := range []struct {
+		addr      string
+		anyDevice bool
+		want      string // As String prints it, "" for an error | همان‌طور که String چاپ می‌کند، خالی برای خطا
+	}{
+		{"AA:BB:CC:DD:EE:FF/3", false, "AA:BB:CC:DD:EE:FF/3"},
+		{"aa:bb:cc:dd:ee:ff", false, "AA:BB:CC:DD:EE:FF/1"},
+		{"AA-BB-CC-DD-EE-FF/30", false, "AA:BB:CC:DD:EE:FF/30"},
+		{"/5", true, "00:00:00:00:00:00/5"},
+		{"", true, "00:00:00:00:00:00/1"},
+		{"/5", false, ""}, // Dialing needs a device | dial دستگاه می‌خواهد
+		{"AA:BB:CC:DD:EE:FF/0", false, ""},
+		{"AA:BB:CC:DD:EE:FF/31", false, ""},
+		{"AA:BB:CC:DD:EE:FF/x", false, ""},
+		{"AA:BB:CC:DD:EE:FF:00:11/1", false, ""}, // EUI-64 is not a Bluetooth address | EUI-64 آدرس بلوتوث نیست
+		{"127.0.0.1:9000", true, ""},
+	} {
+		a, err := parseRFCOMMAddr(c.addr, c.anyDevice)
+		if c.want == "" {
+			if err != errRFCOMMAddr {
+				t.Errorf("%q: parsed as %v, want %v", c.addr, a, errRFCOMMAddr)
+			}
+			continue
+		}
+		if err != nil || a.String() != c.want {
+			t.Errorf("%q: %v, %v; want %s", c.addr, a, err, c.want)
+		}
+	}
+}
+
+func TestBluetoothTransportRefusesBadAddresses(t *testing.T) {
+	if _, err := (bluetoothTransport{}).dial("chat.example.org:9000"); err != errRFCOMMAddr {
+		t.Errorf("dial: %v, want %v", err, errRFCOMMAddr)
+	}
+	if _, err := (bluetoothTransport{}).listen("0.0.0.0:9000"); err != errRFCOMMAddr {
+		t.Errorf("listen: %v, want %v", err, errRFCOMMAddr)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"   // For the chat line size limit
+	"fmt"     // For command output
+	"runtime" // For build information
+	"strconv" // For encoding capability values
+	"strings" // For parsing the capability list
+)
+
+const maxMessageSize = bufio.MaxScanTokenSize // Longest chat line the reader accepts | طولانی‌ترین خط چت قابل دریافت
+
+/*
+capabilities lists the protocol features one side supports. Each side
+announces its own in the HELLO line; the negotiated set is what both
+support.
+
+این ساختار قابلیت‌های پروتکل یک طرف را نشان می‌دهد؛ هر طرف قابلیت‌های
+خودش را در خط HELLO اعلام می‌کند و مجموعه‌ی توافقی، اشتراک هر دو است
+*/
+type capabilities struct {
+	Encryption    bool // Link is encrypted | رمزنگاری اتصال
+	Compression   bool // Payload compression | فشرده‌سازی داده
+	MaxMessage    int  // Largest chat line in bytes | حداکثر اندازه پیام
+	FileWindow    bool // File receivers advertise window updates | اعلام پنجره توسط گیرنده‌ی فایل
+	Streaming     bool // Long messages may arrive in parts | پیام طولانی ممکن است بخش‌بخش برسد
+	FileOffer     bool // File receivers accept or refuse a checksummed header | گیرنده‌ی فایل هدر دارای checksum را می‌پذیرد یا رد می‌کند
+	InlineImages  bool // Small images may travel inside a chat message | تصویر کوچک ممکن است داخل پیام چت بیاید
+	ParallelFiles bool // Large files may be split across streams | فایل بزرگ ممکن است روی چند stream تقسیم شود
+	DirSync       bool // Directories can be mirrored with /syncdir | پوشه‌ها با /syncdir آینه می‌شوند
+	CodeSnippets  bool // Messages can carry a highlighted code snippet | پیام می‌تواند قطعه کد رنگی داشته باشد
+	Resume        bool // Handshakes carry resumption tickets | handshake ticket ازسرگیری دارد
+	Padding       bool // Cover lines on the chat stream are understood | خطوط پوششی stream چت شناخته می‌شوند
+	ResumeFrames  bool // Resumed links carry on frame numbering | اتصال ازسرگرفته شماره‌ی فریم را ادامه می‌دهد
+}
+
+// localCapabilities is what this build supports | قابلیت‌های این build
+var localCapabilities = capabilities{MaxMessage: maxMessageSize, FileWindow: true, Streaming: true, FileOffer: true, InlineImages: true, ParallelFiles: true, DirSync: true, CodeSnippets: true, Resume: true, Padding: true, ResumeFrames: true}
+
+/*
+String encodes the capabilities as a single HELLO field,
+e.g. "enc=0,zip=0,max=65536,fwin=1,stream=1,offer=1,img=1,par=1,sync=1,code=1,res=1,pad=1,rseq=1".
+
+این تابع قابلیت‌ها را به یک فیلد خط HELLO تبدیل می‌کند
+*/
+func (c capabilities) String() string {
+	return fmt.Sprintf("enc=%d,zip=%d,max=%d,fwin=%d,stream=%d,offer=%d,img=%d,par=%d,sync=%d,code=%d,res=%d,pad=%d,rseq=%d", boolDigit(c.Encryption), boolDigit(c.Compression), c.MaxMessage, boolDigit(c.FileWindow), boolDigit(c.Streaming), boolDigit(c.FileOffer), boolDigit(c.InlineImages), boolDigit(c.ParallelFiles), boolDigit(c.DirSync), boolDigit(c.CodeSnippets), boolDigit(c.Resume), boolDigit(c.Padding), boolDigit(c.ResumeFrames))
+}
+
+/*
+parseCapabilities decodes a HELLO capability field. Unknown keys are
+ignored so newer peers can announce features older ones do not know.
+
+این تابع فیلد قابلیت‌های خط HELLO را می‌خواند؛ کلیدهای ناشناخته
+نادیده گرفته می‌شوند تا peerهای جدیدتر بتوانند قابلیت تازه اعلام کنند
+*/
+func parseCapabilities(field string) capabilities {
+	c := capabilities{MaxMessage: maxMessageSize} // Defaults for peers that send nothing | پیش‌فرض برای peerهای قدیمی
+	for _, kv := range strings.Split(field, ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "enc":
+			c.Encryption = v == "1"
+		case "zip":
+			c.Compression = v == "1"
+		case "fwin":
+			c.FileWindow = v == "1"
+		case "stream":
+			c.Streaming = v == "1"
+		case "offer":
+			c.FileOffer = v == "1"
+		case "img":
+			c.InlineImages = v == "1"
+		case "par":
+			c.ParallelFiles = v == "1"
+		case "sync":
+			c.DirSync = v == "1"
+		case "code":
+			c.CodeSnippets = v == "1"
+		case "res":
+			c.Resume = v == "1"
+		case "pad":
+			c.Padding = v == "1"
+		case "rseq":
+			c.ResumeFrames = v == "1"
+		case "max":
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				c.MaxMessage = n
+			}
+		}
+	}
+	return c
+}
+
+/*
+negotiate returns the features both sides support.
+
+این تابع قابلیت‌هایی را برمی‌گرداند که هر دو طرف پشتیبانی می‌کنند
+*/
+func negotiate(local, remote capabilities) capabilities {
+	return capabilities{
+		Encryption:    local.Encryption && remote.Encryption,
+		Compression:   local.Compression && remote.Compression,
+		MaxMessage:    min(local.MaxMessage, remote.MaxMessage),
+		FileWindow:    local.FileWindow && remote.FileWindow,
+		Streaming:     local.Streaming && remote.Streaming,
+		FileOffer:     local.FileOffer && remote.FileOffer,
+		InlineImages:  local.InlineImages && remote.InlineImages,
+		ParallelFiles: local.ParallelFiles && remote.ParallelFiles && local.FileOffer && remote.FileOffer, // Parts need the verdict | بخش‌ها به پاسخ گیرنده نیاز دارند
+		DirSync:       local.DirSync && remote.DirSync,
+		CodeSnippets:  local.CodeSnippets && remote.CodeSnippets,
+		Resume:        local.Resume && remote.Resume,
+		Padding:       local.Padding && remote.Padding,
+		ResumeFrames:  local.ResumeFrames && remote.ResumeFrames && local.Resume && remote.Resume, // Numbers travel with the tickets | شماره‌ها همراه ticketها می‌آیند
+	}
+}
+
+func init() {
+	registerCommand("version", "/version  show local and remote build info", versionCommand)
+	registerCommand("capabilities", "/capabilities  show the negotiated protocol features", capabilitiesCommand)
+}
+
+/*
+versionCommand prints the local build info and the remote's version
+as announced in the handshake.
+
+این دستور اطلاعات build محلی و نسخه‌ی اعلام‌شده‌ی peer مقابل را چاپ می‌کند
+*/
+func versionCommand(s *session, args []string) {
+	fmt.Fprintf(stdout, "Local : %s (protocol %d, %s, %s/%s)\n", version, protocolVersion, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(stdout, "Remote: %s (protocol %d)\n", s.conn.remoteVersion, s.conn.remoteProtocol)
+}
+
+/*
+capabilitiesCommand prints the features negotiated with the remote peer.
+
+این دستور قابلیت‌های توافق‌شده با peer مقابل را چاپ می‌کند
+*/
+func capabilitiesCommand(s *session, args []string) {
+	c := s.conn.caps
+	fmt.Fprintln(stdout, "Encryption :", onOff(c.Encryption))
+	fmt.Fprintln(stdout, "Compression:", onOff(c.Compression))
+	fmt.Fprintln(stdout, "Max message:", c.MaxMessage, "bytes")
+	fmt.Fprintln(stdout, "File window:", onOff(c.FileWindow))
+	fmt.Fprintln(stdout, "Streaming  :", onOff(c.Streaming))
+	fmt.Fprintln(stdout, "File offers:", onOff(c.FileOffer))
+	fmt.Fprintln(stdout, "Images     :", onOff(c.InlineImages))
+	fmt.Fprintln(stdout, "Parallel   :", onOff(c.ParallelFiles))
+	fmt.Fprintln(stdout, "Dir sync   :", onOff(c.DirSync))
+	fmt.Fprintln(stdout, "Code       :", onOff(c.CodeSnippets))
+	fmt.Fprintln(stdout, "Resume     :", onOff(c.Resume))
+	fmt.Fprintln(stdout, "Padding    :", onOff(c.Padding))
+	fmt.Fprintln(stdout, "Res. frames:", onOff(c.ResumeFrames))
+}
+
+// boolDigit encodes a flag as 0 or 1 | تبدیل bool به ۰ یا ۱
+func boolDigit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// onOff renders a flag for display | نمایش bool به‌صورت on/off
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
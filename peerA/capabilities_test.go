@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesRoundTrip(t *testing.T) {
+	if got := parseCapabilities(localCapabilities.String()); got != localCapabilities {
+		t.Fatalf("parsed %+v, want %+v", got, localCapabilities)
+	}
+	if got := parseCapabilities(capabilities{MaxMessage: 1024}.String()); got != (capabilities{MaxMessage: 1024}) {
+		t.Fatalf("features that are off came back as %+v", got)
+	}
+	// Older peers send nothing, newer ones send keys we do not know | peerهای قدیمی چیزی نمی‌فرستند و جدیدترها کلیدهای ناشناخته
+	for _, field := range []string{"", "none", "warp=1,max=0", "max=-5"} {
+		if got := parseCapabilities(field); got != (capabilities{MaxMessage: maxMessageSize}) {
+			t.Errorf("%q parsed as %+v, want the defaults", field, got)
+		}
+	}
+	if got := parseCapabilities("warp=1,stream=1,max=4096"); !got.Streaming || got.MaxMessage != 4096 {
+		t.Errorf("known keys lost next to an unknown one: %+v", got)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	all := localCapabilities
+	if got := negotiate(all, all); got != all {
+		t.Fatalf("two equal peers negotiated %+v", got)
+	}
+	old := capabilities{MaxMessage: 4096, Streaming: true}
+	if got := negotiate(all, old); got != old {
+		t.Errorf("with an older peer: %+v, want only what it has", got)
+	}
+	noOffer := all
+	noOffer.FileOffer = false
+	if got := negotiate(all, noOffer); got.ParallelFiles {
+		t.Error("parallel files negotiated without file offers")
+	}
+	noResume := all
+	noResume.Resume = false
+	if got := negotiate(noResume, all); got.ResumeFrames || got.Resume {
+		t.Error("resumed frame numbers negotiated without resume tickets")
+	}
+}
+
+func TestCapabilitiesCommand(t *testing.T) {
+	var out bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&out))
+	s := &session{conn: &handshakeConn{caps: capabilities{MaxMessage: 4096, Streaming: true}, remoteVersion: "v1.2.3", remoteProtocol: 2}}
+	capabilitiesCommand(s, nil)
+	for _, want := range []string{"Max message: 4096 bytes\n", "Streaming  : on\n", "Padding    : off\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("/capabilities output lacks %q:\n%s", want, out.String())
+		}
+	}
+	out.Reset()
+	versionCommand(s, nil)
+	if !strings.Contains(out.String(), "Remote: v1.2.3 (protocol 2)\n") || !strings.Contains(out.String(), "Local : "+version) {
+		t.Errorf("/version printed:\n%s", out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"     // For printing command output
+	"sort"    // For a stable /help listing
+	"strings" // For splitting command lines
+)
+
+/*
+command is a local slash command typed at the prompt; it is never
+sent to the remote peer.
+
+هر command یک دستور محلی است که با / شروع می‌شود
+و هرگز برای peer مقابل ارسال نمی‌شود
+*/
+type command struct {
+	usage string                          // One-line usage | راهنمای یک‌خطی
+	run   func(s *session, args []string) // Command body | بدنه دستور
+}
+
+// commands holds every registered command by name | همه‌ی دستورهای ثبت‌شده بر اساس نام
+var commands = make(map[string]command)
+
+/*
+registerCommand adds a command; features call it from init so each one
+keeps its commands next to its own code.
+
+این تابع یک دستور را ثبت می‌کند؛ هر قابلیت آن را در init صدا می‌زند
+تا دستورهایش کنار کد خودش بماند
+*/
+func registerCommand(name, usage string, run func(s *session, args []string)) {
+	commands[name] = command{usage: usage, run: run}
+}
+
+func init() {
+	registerCommand("help", "/help", func(*session, []string) {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(stdout, "  "+commands[name].usage)
+		}
+	})
+}
+
+/*
+runCommand parses a "/name args..." line and runs the matching command,
+or expands the user's alias of that name.
+
+این تابع خط "/name args..." را تجزیه و دستور مربوطه را اجرا می‌کند یا
+نام مستعار کاربر با آن نام را باز می‌کند
+*/
+func runCommand(s *session, line string) {
+	fields := strings.Fields(strings.TrimPrefix(line, "/"))
+	if len(fields) == 0 {
+		return
+	}
+	cmd, ok := commands[fields[0]]
+	if !ok && s.aliases.expand(s, fields[0], fields[1:], 0) {
+		return
+	}
+	if !ok {
+		fmt.Fprintln(stdout, "Unknown command:", fields[0], "(try /help)")
+		return
+	}
+	cmd.run(s, fields[1:])
+}
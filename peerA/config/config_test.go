@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load runs Load with a fresh flag set | اجرای Load با مجموعه‌ی پرچم تازه
+func load(t *testing.T, args ...string) (Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args, Config{Listen: "0.0.0.0:8080", Name: "default", Wait: time.Second})
+}
+
+// writeConfig writes a config file and returns its path | نوشتن فایل پیکربندی
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeConfig(t, `{"listen": "file:1", "name": "file", "dial": "file:2", "wait": "5s", "tcp-nodelay": true, "tcp-sndbuf": 1048576}`)
+	t.Setenv("PEERCHAT_CONFIG", path)
+	t.Setenv("PEERCHAT_NAME", "env")
+	t.Setenv("PEERCHAT_DIAL", "env:2")
+	t.Setenv("PEERCHAT_SPAM_COOLDOWN", "2m") // Dashes become underscores | خط تیره به زیرخط تبدیل می‌شود
+	cfg, err := load(t, "-dial", "flag:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		setting   string
+		got, want any
+	}{
+		{"listen", cfg.Listen, "file:1"},
+		{"name", cfg.Name, "env"},
+		{"dial", cfg.Dial, "flag:2"},
+		{"wait", cfg.Wait, 5 * time.Second},
+		{"tcp-nodelay", cfg.NoDelay, true},
+		{"tcp-sndbuf", cfg.SendBuf, 1 << 20},
+		{"spam-cooldown", cfg.SpamCooldown, 2 * time.Minute},
+		{"config", cfg.File, path},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.setting, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadDefaultFlagDoesNotOverride(t *testing.T) {
+	t.Setenv("PEERCHAT_LISTEN", "env:1")
+	cfg, err := load(t, "-name", "flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != "env:1" || cfg.Name != "flag" || cfg.Wait != time.Second {
+		t.Fatalf("got listen %q, name %q, wait %v; want the environment, the flag and the default", cfg.Listen, cfg.Name, cfg.Wait)
+	}
+}
+
+func TestLoadBadValues(t *testing.T) {
+	t.Setenv("PEERCHAT_WAIT", "soon")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "PEERCHAT_WAIT") {
+		t.Errorf("bad variable: %v, want an error naming it", err)
+	}
+	os.Unsetenv("PEERCHAT_WAIT")
+
+	path := writeConfig(t, `{"baud": "fast"}`)
+	if _, err := load(t, "-config", path); err == nil || !strings.Contains(err.Error(), "baud") {
+		t.Errorf("bad file value: %v, want an error naming the key", err)
+	}
+	path = writeConfig(t, `{"aliases": ["not", "an", "object"]}`)
+	if _, err := load(t, "-config", path); err == nil {
+		t.Error("aliases that are not an object were accepted")
+	}
+}
+
+func TestLoadFileObjects(t *testing.T) {
+	path := writeConfig(t, `{"aliases": {"hi": "/me waves"}, "keys": {"ctrl-k": "clear"}, "themes": {"sea": {"remote": "cyan"}}}`)
+	cfg, err := load(t, "-config", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Aliases["hi"] != "/me waves" || cfg.Keys["ctrl-k"] != "clear" || cfg.Themes["sea"]["remote"] != "cyan" {
+		t.Fatalf("aliases %v, keys %v, themes %v", cfg.Aliases, cfg.Keys, cfg.Themes)
+	}
+}
+
+func TestSaveAliasesKeepsOtherKeys(t *testing.T) {
+	path := writeConfig(t, `{"name": "ann", "aliases": {"old": "/quit"}}`)
+	if err := SaveAliases(path, map[string]string{"hi": "/me waves"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	aliases, _ := raw["aliases"].(map[string]any)
+	if raw["name"] != "ann" || len(aliases) != 1 || aliases["hi"] != "/me waves" {
+		t.Fatalf("saved %s", data)
+	}
+
+	fresh := filepath.Join(t.TempDir(), "new.json")
+	if err := SaveAliases(fresh, map[string]string{"hi": "/me waves"}); err != nil {
+		t.Fatalf("a missing file was not created: %v", err)
+	}
+	if info, err := os.Stat(fresh); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("created file: %v, %v; want mode 0600", info, err)
+	}
+}
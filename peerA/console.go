@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"      // For recognising a pasted line
+	"io"          // For wiring the terminal's reader and writer
+	"os"          // For the terminal file descriptors
+	"reflect"     // For reading the line being typed
+	"sync"        // For guarding type-ahead input
+	"sync/atomic" // For the last keystroke time
+	"time"        // For keystroke timestamps
+
+	"golang.org/x/term" // For raw mode and line editing
+)
+
+const consolePrompt = "> " // Shown in front of the line being typed | نمایش‌داده‌شده پیش از خط در حال تایپ
+
+/*
+console is the interactive line editor used when both stdin and stdout
+are terminals. The terminal runs in raw mode so every keystroke is seen
+(for idle detection); stdout is redirected into the editor so nothing
+the program prints tramples the line being typed.
+
+این نوع ویرایشگر خط تعاملی است که وقتی stdin و stdout هر دو ترمینال
+باشند استفاده می‌شود. ترمینال در حالت raw اجرا می‌شود تا هر کلید دیده شود
+(برای تشخیص بیکاری) و stdout به ویرایشگر هدایت می‌شود تا هیچ چیزی که
+برنامه چاپ می‌کند خط در حال تایپ را خراب نکند
+*/
+type console struct {
+	term    *term.Terminal
+	state   *term.State  // Terminal mode to restore | حالت ترمینال برای بازگردانی
+	prev    io.Writer    // Where stdout went before the editor | مقصد stdout پیش از ویرایشگر
+	lastKey atomic.Int64 // Last keystroke (unix nano) | زمان آخرین کلید
+	onKey   func()       // Called on every keystroke | با هر کلید صدا زده می‌شود
+
+	mu    sync.Mutex
+	ahead []byte // Input fed to the editor before stdin | ورودی‌ای که پیش از stdin به ویرایشگر داده می‌شود
+}
+
+/*
+startConsole switches the terminal to raw mode and installs the line
+editor; it returns nil (and no error) when stdin or stdout is not a
+terminal. stop must be called to restore the terminal.
+
+این تابع ترمینال را به حالت raw می‌برد و ویرایشگر خط را راه‌اندازی می‌کند؛
+اگر stdin یا stdout ترمینال نباشد nil برمی‌گرداند. برای بازگردانی
+ترمینال باید stop صدا زده شود
+*/
+func startConsole() (*console, error) {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return nil, nil
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &console{state: state}
+	c.lastKey.Store(time.Now().UnixNano())
+	c.term = term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{keyReader{c}, os.Stdout}, consolePrompt)
+	c.term.SetBracketedPasteMode(true) // Pasted lines are marked, see composer.paste | خطوط چسبانده‌شده علامت می‌خورند
+	if width, height, err := term.GetSize(out); err == nil && width > 0 {
+		_ = c.term.SetSize(width, height)
+	}
+	c.prev = stdout.redirect(c.term) // Printed above the prompt | چاپ بالای خط ورودی
+	return c, nil
+}
+
+// stop takes stdout back from the editor and restores the terminal | بازگرداندن stdout و ترمینال
+func (c *console) stop() {
+	if c == nil {
+		return
+	}
+	stdout.redirect(c.prev)
+	c.term.SetBracketedPasteMode(false)
+	_ = term.Restore(int(os.Stdin.Fd()), c.state)
+	_, _ = io.WriteString(os.Stdout, "\n")
+}
+
+// idleFor returns the time since the last keystroke | مدت زمان از آخرین کلید
+func (c *console) idleFor() time.Duration {
+	return time.Since(time.Unix(0, c.lastKey.Load()))
+}
+
+// typeAhead makes the editor read text as if it had been typed | تایپ متن در ویرایشگر
+func (c *console) typeAhead(text string) {
+	c.mu.Lock()
+	c.ahead = append(c.ahead, text...)
+	c.mu.Unlock()
+}
+
+/*
+line returns what is typed but not yet entered. The editor keeps it in
+an unexported field, read here without its lock: it is only used once
+the chat is over, and a missing field yields "".
+
+این تابع متن تایپ‌شده‌ای را که هنوز Enter نخورده برمی‌گرداند؛ ویرایشگر آن را
+در فیلدی داخلی نگه می‌دارد که اینجا بدون قفل آن خوانده می‌شود، چون فقط پس
+از پایان گفتگو استفاده می‌شود؛ نبود این فیلد "" برمی‌گرداند
+*/
+func (c *console) line() string {
+	v := reflect.ValueOf(c.term).Elem().FieldByName("line")
+	if !v.IsValid() || v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Int32 {
+		return ""
+	}
+	runes := make([]rune, v.Len())
+	for i := range runes {
+		runes[i] = rune(v.Index(i).Int())
+	}
+	return string(runes)
+}
+
+// keyReader feeds stdin to the editor and records keyboard activity | خواندن stdin و ثبت فعالیت صفحه‌کلید
+type keyReader struct{ c *console }
+
+func (k keyReader) Read(p []byte) (int, error) {
+	k.c.mu.Lock()
+	if len(k.c.ahead) > 0 {
+		n := copy(p, k.c.ahead)
+		k.c.ahead = k.c.ahead[n:]
+		k.c.mu.Unlock()
+		return n, nil // Not a keystroke | کلید فشرده نشده
+	}
+	k.c.mu.Unlock()
+	n, err := os.Stdin.Read(p)
+	if n > 0 {
+		k.c.lastKey.Store(time.Now().UnixNano())
+		if k.c.onKey != nil {
+			k.c.onKey()
+		}
+	}
+	return n, err
+}
+
+/*
+consoleReader handles lines typed in the editor like stdinReader does;
+pasted lines are gathered into one message. Ctrl+C, or Ctrl+D on an
+empty line, ends the chat. Each line goes to the session of the link
+up at the time.
+
+این تابع خطوط تایپ‌شده در ویرایشگر را مانند stdinReader پردازش می‌کند؛
+خطوط چسبانده‌شده در یک پیام جمع می‌شوند. Ctrl+C یا Ctrl+D روی خط خالی
+گفتگو را پایان می‌دهد. هر خط به نشست اتصالِ برقرار در همان لحظه می‌رسد
+*/
+func consoleReader(live *atomic.Pointer[session], c *console, done *doneSignal) {
+	for {
+		line, err := c.term.ReadLine()
+		pasted := errors.Is(err, term.ErrPasteIndicator)
+		if err != nil && !pasted {
+			done.close() // Raw mode: no SIGINT, so exit here | در حالت raw سیگنال SIGINT نمی‌آید
+			return
+		}
+		select {
+		case <-done.c:
+			return
+		default:
+		}
+		s := live.Load()
+		if pasted {
+			s.compose.paste(s, line)
+			continue
+		}
+		handleInput(s, line)
+	}
+}
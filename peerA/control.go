@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json" // For encoding control frames
+	"io"            // For writing padded frames
+	"net"           // For the stream connection type
+	"sync/atomic"   // For heartbeat bookkeeping shared between goroutines
+	"time"          // For heartbeat intervals and deadlines
+)
+
+/*
+Control frame types
+
+انواع فریم‌های کنترلی:
+- ping / pong: ضربان قلب برای تشخیص اتصال مرده
+*/
+const (
+	ctrlPing = "ping" // Heartbeat request | درخواست ضربان
+	ctrlPong = "pong" // Heartbeat reply | پاسخ ضربان
+)
+
+/*
+Heartbeat configuration
+
+مقادیر پیکربندی ضربان قلب:
+- فاصله ارسال ping
+- حداکثر زمان سکوت قبل از قطع اتصال
+*/
+const (
+	heartbeatEvery   = 15 * time.Second   // Ping interval | فاصله ارسال ping
+	heartbeatTimeout = 3 * heartbeatEvery // Silence before giving up | سکوت مجاز قبل از قطع
+)
+
+/*
+controlFrame is one protocol metadata message on the control stream.
+Chat text never travels here, so the display loop never sees it.
+
+هر فریم کنترلی یک پیام متادیتای پروتکل روی stream کنترل است؛
+متن چت هیچ‌وقت از این مسیر نمی‌آید و حلقه نمایش آن را نمی‌بیند
+*/
+type controlFrame struct {
+	Type string `json:"type"`           // Frame type | نوع فریم
+	Time int64  `json:"time,omitempty"` // Sender clock in unix nanoseconds | زمان فرستنده
+	Text string `json:"text,omitempty"` // Human-readable detail | توضیح قابل‌خواندن
+	Note string `json:"note,omitempty"` // Custom status message with presence | پیام وضعیت همراه حضور
+}
+
+/*
+controlLink owns the control stream: the outgoing frame queue,
+the handlers per frame type and heartbeat bookkeeping.
+
+این نوع مالک stream کنترل است: صف فریم‌های خروجی،
+handler هر نوع فریم و اطلاعات ضربان قلب
+*/
+type controlLink struct {
+	out      chan controlFrame             // Outgoing frames | فریم‌های خروجی
+	handlers map[string]func(controlFrame) // Per-type handlers | handler هر نوع فریم
+	lastSeen atomic.Int64                  // Last frame received (unix nano) | زمان آخرین فریم دریافتی
+	rtt      atomic.Int64                  // Last measured round trip | آخرین زمان رفت‌وبرگشت
+	done     *doneSignal                   // Shutdown signal | سیگنال خروج
+}
+
+/*
+newControlLink creates a control link with the heartbeat handlers
+already registered.
+
+این تابع یک controlLink با handlerهای ضربان قلب می‌سازد
+*/
+func newControlLink(done *doneSignal) *controlLink {
+	c := &controlLink{
+		out:      make(chan controlFrame, 32),
+		handlers: make(map[string]func(controlFrame)),
+		done:     done,
+	}
+	c.lastSeen.Store(time.Now().UnixNano())
+
+	c.handlers[ctrlPing] = func(f controlFrame) {
+		c.send(controlFrame{Type: ctrlPong, Time: f.Time}) // Echo the sender's clock | برگرداندن زمان فرستنده
+	}
+	c.handlers[ctrlPong] = func(f controlFrame) {
+		c.rtt.Store(time.Now().UnixNano() - f.Time)
+	}
+	return c
+}
+
+/*
+handle registers the handler for a control frame type.
+It must be called before the reader starts.
+
+این تابع handler یک نوع فریم کنترلی را ثبت می‌کند
+(باید قبل از شروع reader صدا زده شود)
+*/
+func (c *controlLink) handle(kind string, h func(controlFrame)) {
+	c.handlers[kind] = h
+}
+
+/*
+send queues a control frame without blocking the caller forever:
+it gives up once done is closed.
+
+این تابع یک فریم کنترلی را در صف قرار می‌دهد
+و در صورت بسته‌شدن done منصرف می‌شود
+*/
+func (c *controlLink) send(f controlFrame) {
+	select {
+	case c.out <- f:
+	case <-c.done.c:
+	}
+}
+
+/*
+writer encodes queued control frames as JSON lines on the control
+stream. With a shaper they are padded to the same buckets as chat lines.
+
+این تابع فریم‌های کنترلی صف را به‌صورت JSON روی stream کنترل می‌نویسد؛
+با shaper تا همان اندازه‌های خطوط چت پر می‌شوند
+*/
+func (c *controlLink) writer(st net.Conn, shaper *trafficShaper) {
+	for {
+		select {
+		case <-c.done.c:
+			return // Stop on shutdown | توقف در صورت خروج
+		case f := <-c.out:
+			line, _ := json.Marshal(f)                                // Plain strings and numbers cannot fail | رشته و عدد ساده خطا نمی‌دهد
+			_ = st.SetWriteDeadline(time.Now().Add(connWriteTimeout)) // Set write timeout | تنظیم تایم‌اوت
+			if _, err := io.WriteString(st, shaper.pad(string(line))+"\n"); err != nil {
+				c.done.close()
+				return
+			}
+		}
+	}
+}
+
+/*
+reader decodes control frames from the remote's control stream and
+dispatches them to the registered handlers; unknown types are ignored.
+
+این تابع فریم‌های کنترلی طرف مقابل را می‌خواند و به handler
+مربوطه می‌سپارد؛ انواع ناشناخته نادیده گرفته می‌شوند
+*/
+func (c *controlLink) reader(st net.Conn) {
+	dec := json.NewDecoder(st)
+	for {
+		var f controlFrame
+		if err := dec.Decode(&f); err != nil {
+			c.done.close() // Control stream lost | stream کنترل قطع شد
+			return
+		}
+		c.lastSeen.Store(time.Now().UnixNano())
+		if h, ok := c.handlers[f.Type]; ok {
+			h(f)
+		}
+	}
+}
+
+/*
+heartbeat pings the remote periodically and closes done when nothing
+arrived on the control stream for heartbeatTimeout.
+
+این تابع به‌صورت دوره‌ای ping می‌فرستد و اگر در مدت heartbeatTimeout
+هیچ فریمی دریافت نشود، کانال done را می‌بندد
+*/
+func (c *controlLink) heartbeat() {
+	t := time.NewTicker(heartbeatEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done.c:
+			return
+		case now := <-t.C:
+			if now.Sub(time.Unix(0, c.lastSeen.Load())) > heartbeatTimeout {
+				c.done.close() // Remote went silent | طرف مقابل ساکت شده
+				return
+			}
+			c.send(controlFrame{Type: ctrlPing, Time: now.UnixNano()})
+		}
+	}
+}
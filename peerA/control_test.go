@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestControlLinkDispatch(t *testing.T) {
+	aDone, bDone := newDoneSignal(), newDoneSignal()
+	defer aDone.close()
+	defer bDone.close()
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+	a, b := newControlLink(aDone), newControlLink(bDone)
+	got := make(chan controlFrame, 1)
+	b.handle(ctrlPresence, func(f controlFrame) { got <- f })
+	go a.writer(left, nil)
+	go a.reader(left)
+	go b.writer(right, nil)
+	go b.reader(right)
+
+	a.send(controlFrame{Type: "from-the-future", Text: "ignored"}) // Unknown types are skipped | انواع ناشناخته رد می‌شوند
+	a.send(controlFrame{Type: ctrlPresence, Text: presenceAway, Note: "lunch"})
+	select {
+	case f := <-got:
+		if f.Text != presenceAway || f.Note != "lunch" {
+			t.Fatalf("handler got %+v", f)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("frame never reached its handler")
+	}
+
+	a.send(controlFrame{Type: ctrlPing, Time: time.Now().UnixNano()})
+	deadline := time.Now().Add(5 * time.Second)
+	for a.rtt.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if a.rtt.Load() <= 0 {
+		t.Fatal("a ping was not answered with a pong")
+	}
+}
+
+func TestControlStreamLossEndsLink(t *testing.T) {
+	done := newDoneSignal()
+	left, right := net.Pipe()
+	c := newControlLink(done)
+	go c.reader(left)
+	_, _ = io.WriteString(right, `{"type":"ping","time":1}`+"\n")
+	right.Close()
+	select {
+	case <-done.c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("losing the control stream did not end the link")
+	}
+}
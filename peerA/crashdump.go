@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json" // For the dump file
+	"net/url"       // For credentials in configured URLs
+	"os"            // For the dump file
+	"path/filepath" // For the dump path
+	"runtime"       // For stacks and platform details
+	"strings"       // For splitting captured output into lines
+	"sync"          // For guarding the output ring
+	"sync/atomic"   // For the crash context
+	"time"          // For the dump timestamp
+
+	"peerA/config" // For the redacted settings
+)
+
+const crashOutputLines = 200 // Recent output lines kept for a dump | تعداد خطوط خروجی اخیر در گزارش
+
+const crashRedacted = "[redacted]" // Stands in for a secret in a dump | جایگزین راز در گزارش
+
+/*
+crashDump is the diagnostic bundle written when the session dies of a
+fatal error: what happened, the connection, the settings with secrets
+redacted, the last lines printed and the stacks of every goroutine.
+
+این نوع بسته‌ی تشخیصی است که هنگام پایان نشست با خطای مهلک نوشته
+می‌شود: چه اتفاقی افتاد، وضعیت اتصال، تنظیمات با حذف اطلاعات محرمانه،
+آخرین خطوط چاپ‌شده و stack همه‌ی goroutineها
+*/
+type crashDump struct {
+	Time       time.Time        `json:"time"`
+	Reason     string           `json:"reason"`          // Why the dump was written | دلیل نوشتن گزارش
+	Version    string           `json:"version"`         // Build version | نسخه‌ی build
+	Protocol   int              `json:"protocol"`        // Wire protocol version | نسخه‌ی پروتکل
+	Go         string           `json:"go"`              // Go version and platform | نسخه‌ی Go و سکو
+	Panic      *panicReport     `json:"panic,omitempty"` // Set for recovered panics | برای panicهای بازیابی‌شده
+	Connection *crashConnection `json:"connection,omitempty"`
+	Config     *config.Config   `json:"config,omitempty"`
+	Output     []string         `json:"recent_output"` // Last printed lines | آخرین خطوط چاپ‌شده
+	Goroutines string           `json:"goroutines"`    // Every goroutine's stack | stack همه‌ی goroutineها
+}
+
+// crashConnection is the state of the link at the time of the dump | وضعیت اتصال هنگام گزارش
+type crashConnection struct {
+	Remote        string       `json:"remote"`
+	RemoteName    string       `json:"remote_name,omitempty"`
+	RemoteKey     string       `json:"remote_key,omitempty"`
+	RemoteVersion string       `json:"remote_version"`
+	Protocol      int          `json:"remote_protocol"`
+	Capabilities  capabilities `json:"capabilities"`
+	Arbiter       bool         `json:"arbiter"`
+	RTT           string       `json:"rtt"`
+	Sent          int64        `json:"sent"`
+	Taken         int64        `json:"taken"`
+	Queued        int          `json:"queued"`
+}
+
+// crashConfig and crashSession are what main registered for dumps | تنظیمات و نشست ثبت‌شده برای گزارش
+var (
+	crashConfig  atomic.Pointer[config.Config]
+	crashSession atomic.Pointer[session]
+)
+
+// recentOutput keeps the last lines printed to stdout | آخرین خطوط چاپ‌شده روی stdout
+var recentOutput = &lineRing{size: crashOutputLines}
+
+/*
+recordCrashConfig keeps a copy of the settings for crash dumps, with
+the secrets replaced: the password, the HTTP bearer token and any
+credentials inside the dial or DNS-over-HTTPS URLs.
+
+این تابع کپی تنظیمات را برای گزارش خرابی نگه می‌دارد و رازها را حذف
+می‌کند: رمز، token از نوع bearer برای HTTP و هر اطلاعات ورود داخل URLهای
+dial یا DNS-over-HTTPS
+*/
+func recordCrashConfig(cfg config.Config) {
+	for _, secret := range []*string{&cfg.Password, &cfg.HTTPToken} {
+		if *secret != "" {
+			*secret = crashRedacted
+		}
+	}
+	cfg.Dial, cfg.DoH = redactURL(cfg.Dial), redactURL(cfg.DoH)
+	crashConfig.Store(&cfg)
+}
+
+// redactURL hides the user info and query of a URL; anything else is kept as is | حذف اطلاعات ورود و query یک URL؛ بقیه بدون تغییر
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.User == nil && u.RawQuery == "") {
+		return s // host:port, a nick or a path | host:port، نام یا مسیر
+	}
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "redacted"
+	}
+	return u.String()
+}
+
+/*
+writeCrashDump gathers a crashDump and saves it as
+peerchat-crash-<time>.json in the temp directory, returning its path.
+
+این تابع crashDump را جمع‌آوری و در پوشه‌ی موقت با نام
+peerchat-crash-<time>.json ذخیره می‌کند و مسیر آن را برمی‌گرداند
+*/
+func writeCrashDump(reason string, p *panicReport) (string, error) {
+	d := crashDump{
+		Time:       time.Now(),
+		Reason:     reason,
+		Version:    version,
+		Protocol:   protocolVersion,
+		Go:         runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH,
+		Panic:      p,
+		Config:     crashConfig.Load(),
+		Output:     recentOutput.lines(),
+		Goroutines: allStacks(),
+	}
+	if s := crashSession.Load(); s != nil {
+		d.Connection = &crashConnection{
+			Remote:        s.conn.RemoteAddr().String(),
+			RemoteName:    s.presence.peerName(),
+			RemoteKey:     s.conn.remoteKey,
+			RemoteVersion: s.conn.remoteVersion,
+			Protocol:      s.conn.remoteProtocol,
+			Capabilities:  s.conn.caps,
+			Arbiter:       s.conn.arbiter,
+			RTT:           time.Duration(s.ctrl.rtt.Load()).String(),
+			Sent:          s.sent.Load(),
+			Taken:         s.taken.Load(),
+			Queued:        len(s.outgoing),
+		}
+	}
+	path := filepath.Join(os.TempDir(), "peerchat-crash-"+d.Time.Format("20060102-150405.000")+".json")
+	data, _ := json.MarshalIndent(d, "", "  ")                            // Plain fields cannot fail | برای این فیلدها خطا نمی‌دهد
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // Never through a file or link planted in the shared temp directory | هرگز از طریق فایل یا لینکی که در پوشه‌ی موقت مشترک گذاشته شده
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// allStacks returns the stacks of every goroutine | stack همه‌ی goroutineها
+func allStacks() string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf)) // Truncated, try a larger buffer | ناقص بود، بافر بزرگ‌تر
+	}
+}
+
+/*
+lineRing is an io.Writer that keeps the last size complete lines
+written to it.
+
+این نوع یک io.Writer است که آخرین size خط کامل نوشته‌شده را نگه می‌دارد
+*/
+type lineRing struct {
+	mu      sync.Mutex
+	size    int
+	buf     []string
+	partial string // Text after the last newline | متن پس از آخرین newline
+}
+
+func (r *lineRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	parts := strings.Split(r.partial+string(p), "\n")
+	r.partial = parts[len(parts)-1]
+	r.buf = append(r.buf, parts[:len(parts)-1]...)
+	if len(r.buf) > 2*r.size {
+		r.buf = append([]string(nil), r.buf[len(r.buf)-r.size:]...) // Compact now and then | فشرده‌سازی گاه‌به‌گاه
+	}
+	return len(p), nil
+}
+
+// lines returns a copy of the kept lines, oldest first | کپی خطوط نگه‌داشته‌شده، قدیمی‌ترین اول
+func (r *lineRing) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.buf[max(len(r.buf)-r.size, 0):]...)
+}
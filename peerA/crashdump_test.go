@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"peerA/config"
+)
+
+func TestRecordCrashConfigRedacts(t *testing.T) {
+	defer crashConfig.Store(nil)
+	recordCrashConfig(config.Config{
+		Password:  "hunter2",
+		HTTPToken: "s3cret-token",
+		Dial:      "https://alice:pw@chat.example.org/?key=k3y",
+		DoH:       "https://dns.example/dns-query?token=t0k",
+		Name:      "ali",
+	})
+	b, err := json.Marshal(crashConfig.Load())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "s3cret-token", "alice", ":pw@", "k3y", "t0k"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("dump config still holds %q: %s", secret, b)
+		}
+	}
+	if got := crashConfig.Load(); got.Name != "ali" || !strings.Contains(got.Dial, "chat.example.org") {
+		t.Errorf("non-secret settings lost: %+v", got)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"127.0.0.1:8081":                  "127.0.0.1:8081",
+		"ali":                             "ali",
+		"https://chat.example.org/":       "https://chat.example.org/",
+		"https://u:p@chat.example.org/x":  "https://redacted@chat.example.org/x",
+		"https://dns.example/q?token=abc": "https://dns.example/q?redacted",
+	} {
+		if got := redactURL(in); got != want {
+			t.Errorf("redactURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
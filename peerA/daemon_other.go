@@ -0,0 +1,27 @@
+//go:build !unix
+
+package main
+
+import (
+	"net" // For the unix listener
+	"os"  // For the socket permissions
+)
+
+// listenPrivate listens on a unix socket and limits it to our user | گوش‌دادن روی socket یونیکس و محدودکردن آن به کاربر ما
+func listenPrivate(path string) (net.Listener, error) {
+	if err := checkStaleSocket(path); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(path) // Only ever a stale socket here | اینجا فقط socket کهنه
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Chmod(path, 0o600)
+	return ln, nil
+}
+
+// checkSocketOwner has no owner to compare on this platform | روی این سیستم مالکی برای مقایسه نیست
+func checkSocketOwner(path string) error {
+	return nil
+}
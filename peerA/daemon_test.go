@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// discardConn is an attached client that swallows everything, so only the relay is measured | کلاینتی که همه چیز را دور می‌ریزد تا فقط relay سنجیده شود
+type discardConn struct{ net.Conn }
+
+func (discardConn) Write(p []byte) (int, error)        { return len(p), nil }
+func (discardConn) SetWriteDeadline(t time.Time) error { return nil }
+func (discardConn) Close() error                       { return nil }
+
+func TestRelayHistoryOrder(t *testing.T) {
+	d := &daemonRelay{clients: make(map[net.Conn]struct{})}
+	for i := 0; i < daemonHistoryLines+3; i++ {
+		d.publish([]byte(strconv.Itoa(i)))
+	}
+	relayEnd, clientEnd := net.Pipe()
+	defer clientEnd.Close()
+	go d.handleClient(relayEnd, nil)
+	sc := bufio.NewScanner(clientEnd)
+	for want := 3; want < daemonHistoryLines+3; want++ {
+		if !sc.Scan() || sc.Text() != strconv.Itoa(want) {
+			t.Fatalf("replayed %q, want %d", sc.Text(), want)
+		}
+	}
+}
+
+func TestRelayPublishAllocatesNothing(t *testing.T) {
+	d := &daemonRelay{clients: map[net.Conn]struct{}{&discardConn{}: {}}, local: io.Discard}
+	line := []byte("a line of daemon output")
+	for i := 0; i < daemonHistoryLines; i++ {
+		d.publish(line)
+	}
+	if n := testing.AllocsPerRun(100, func() { d.publish(line) }); n != 0 {
+		t.Errorf("publish allocates %v times per line", n)
+	}
+}
+
+func TestRelayWriteSplitsLines(t *testing.T) {
+	d := &daemonRelay{clients: make(map[net.Conn]struct{})}
+	for _, chunk := range []string{"one\r\ntw", "o\n", "three"} {
+		if _, err := d.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.flush()
+	var got []string
+	for _, line := range d.history {
+		got = append(got, string(line))
+	}
+	if want := []string{"one\n", "two\n", "three\n"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("history %q, want %q", got, want)
+	}
+}
+
+func TestDaemonSocketIsPrivate(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(t.TempDir(), "run"))
+	path := defaultSocketPath("T")
+	if filepath.Dir(path) != os.Getenv("XDG_RUNTIME_DIR") {
+		t.Fatalf("socket %s outside XDG_RUNTIME_DIR", path)
+	}
+	_, stop, err := startDaemon(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		t.Errorf("socket mode %v, want no access for others", perm)
+	}
+	if err := checkSocketOwner(path); err != nil {
+		t.Errorf("our own socket refused: %v", err)
+	}
+}
+
+func TestListenPrivateReplacesOnlySockets(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, []byte("keep me"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenPrivate(file); err != errNotSocket {
+		t.Fatalf("listening over a regular file: %v, want %v", err, errNotSocket)
+	}
+	if b, err := os.ReadFile(file); err != nil || string(b) != "keep me" {
+		t.Fatalf("the regular file was touched: %q, %v", b, err)
+	}
+
+	path := filepath.Join(dir, "chat.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close() // Left behind, as after a crash | مانند پس از خرابی باقی می‌ماند
+	ln, err := listenPrivate(path)
+	if err != nil {
+		t.Fatalf("a stale socket was not replaced: %v", err)
+	}
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dialing the socket under its final name: %v", err)
+	}
+	c.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("%d entries next to the socket, want the socket and the file", len(entries))
+	}
+	ln.Close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left after Close: %v", err)
+	}
+}
+
+func TestUnixTransportListenIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "peer.sock")
+	if err := os.WriteFile(file, []byte("keep me"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (unixTransport{}).listen(file); err != errNotSocket {
+		t.Fatalf("listening over a regular file: %v, want %v", err, errNotSocket)
+	}
+	if b, err := os.ReadFile(file); err != nil || string(b) != "keep me" {
+		t.Fatalf("the regular file was touched: %q, %v", b, err)
+	}
+
+	path := filepath.Join(dir, "link.sock")
+	ln, err := (unixTransport{}).listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		t.Errorf("socket mode %v, open to other users", perm)
+	}
+}
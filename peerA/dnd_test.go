@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDNDHoldsAlerts(t *testing.T) {
+	n, err := newNotifier("ann", "message,flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{notify: n}
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+
+	runCommand(s, "/dnd in a meeting")
+	if !strings.Contains(buf.String(), "on until /dnd off; auto-reply: in a meeting") {
+		t.Errorf("/dnd: %q", buf.String())
+	}
+	buf.Reset()
+	if r := n.alert(message{From: "bob", Text: "lunch?", Auto: true}); r != "" {
+		t.Errorf("auto-replied %q to an auto-reply", r)
+	}
+	if r := n.alert(message{From: "bob", Text: "ann, are you there?"}); r != "in a meeting" {
+		t.Errorf("auto-reply %q, want the /dnd text", r)
+	}
+	if r := n.alert(message{From: "bob", Text: "@ann?"}); r != "" {
+		t.Errorf("auto-replied %q twice", r)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("alerts fired during do not disturb: %q", buf.String())
+	}
+
+	runCommand(s, "/dnd off")
+	out := buf.String()
+	if !strings.HasPrefix(out, "Do not disturb is off: 3 messages, 2 mentions while you were away\n") ||
+		!strings.Contains(out, "bob: ann, are you there?") || !strings.Contains(out, "bob: @ann?") || strings.Contains(out, "lunch") {
+		t.Errorf("summary:\n%s", out)
+	}
+	buf.Reset()
+	runCommand(s, "/dnd off")
+	if !strings.Contains(buf.String(), "Do not disturb is not on") {
+		t.Errorf("second /dnd off: %q", buf.String())
+	}
+}
+
+func TestDNDExpires(t *testing.T) {
+	n, err := newNotifier("ann", "off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	restore := stdout.redirect(&buf)
+	n.startDND(50*time.Millisecond, "")
+	n.startDND(300*time.Millisecond, "") // The first timer must not end this one | تایمر اول نباید این را پایان دهد
+	n.alert(message{Text: "one"})
+	time.Sleep(150 * time.Millisecond)
+	n.mu.Lock()
+	early := n.dnd.active
+	n.mu.Unlock()
+	time.Sleep(300 * time.Millisecond)
+	n.mu.Lock()
+	late := n.dnd.active
+	n.mu.Unlock()
+	stdout.redirect(restore)
+
+	if !early || late {
+		t.Errorf("active after 150ms: %v, after 450ms: %v; want true then false", early, late)
+	}
+	if got := buf.String(); got != "Do not disturb is off: 1 message, 0 mentions while you were away\n" {
+		t.Errorf("summary: %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json" // For the event payloads
+	"fmt"           // For the event stream format
+	"net/http"      // For the /events endpoint
+	"sync"          // For the subscriber set
+	"time"          // For keep-alive comments
+)
+
+const eventBuffer = 64 // Events held for a slow subscriber before it misses some | رویدادهای نگه‌داشته برای مشترک کند
+
+// events fans received messages and errors out to /events subscribers | پخش پیام‌های دریافتی و خطاها برای مشترکان /events
+var events = &eventStream{subs: make(map[chan sseEvent]struct{})}
+
+/*
+eventStream serves received messages as Server-Sent Events, so scripts
+and dashboards can follow the chat with a plain HTTP client. Every
+subscriber has its own buffer; one that falls behind by eventBuffer
+events misses the newer ones instead of slowing the chat.
+
+این نوع پیام‌های دریافتی را به‌صورت Server-Sent Events ارائه می‌کند تا
+اسکریپت‌ها و داشبوردها با یک کلاینت HTTP ساده چت را دنبال کنند؛ هر مشترک
+بافر خود را دارد و مشترکی که eventBuffer رویداد عقب بیفتد رویدادهای تازه‌تر
+را از دست می‌دهد تا چت کند نشود
+*/
+type eventStream struct {
+	mu   sync.Mutex
+	subs map[chan sseEvent]struct{}
+}
+
+// sseEvent is one event for the stream: its type and JSON data | یک رویداد stream: نوع و داده‌ی JSON آن
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// publish sends a message to every subscriber | ارسال پیام برای همه‌ی مشترکان
+func (e *eventStream) publish(m message) {
+	e.send(eventMessage, m)
+}
+
+// publishEvent sends an output event, such as an error, under its own type | ارسال رویداد خروجی، مانند خطا، با نوع خودش
+func (e *eventStream) publishEvent(ev outputEvent) {
+	e.send(ev.Event, ev)
+}
+
+// send sends v as a name event to every subscriber | ارسال v به‌عنوان رویداد name برای همه‌ی مشترکان
+func (e *eventStream) send(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for sub := range e.subs {
+		select {
+		case sub <- sseEvent{name: name, data: data}:
+		default: // Behind; drop for this one only | عقب افتاده؛ فقط برای همین مشترک حذف می‌شود
+		}
+	}
+}
+
+// closeAll ends every stream, for the server shutdown | پایان همه‌ی streamها هنگام خاموشی سرور
+func (e *eventStream) closeAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for sub := range e.subs {
+		close(sub)
+		delete(e.subs, sub)
+	}
+}
+
+/*
+serveEvents answers GET /events with a text/event-stream: one
+"message" event per received message, with the same JSON as the pipe
+mode output, one "error" event per recovered panic, with the same JSON
+as -output json, and a comment every heartbeatEvery to keep proxies
+from closing an idle stream.
+
+این تابع به GET /events با یک text/event-stream پاسخ می‌دهد: برای هر پیام
+دریافتی یک رویداد "message" با همان JSON خروجی حالت pipe، برای هر panic
+بازیابی‌شده یک رویداد "error" با همان JSON خروجی -output json، و هر
+heartbeatEvery یک توضیح تا proxyها stream بیکار را نبندند
+*/
+func (e *eventStream) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := make(chan sseEvent, eventBuffer)
+	e.mu.Lock()
+	e.subs[sub] = struct{}{}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		if _, ok := e.subs[sub]; ok {
+			delete(e.subs, sub)
+		}
+		e.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	tick := time.NewTicker(heartbeatEvery)
+	defer tick.Stop()
+	for {
+		select {
+		case ev, ok := <-sub:
+			if !ok {
+				return // Server shutting down | خاموشی سرور
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+		case <-tick.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
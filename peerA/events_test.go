@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads one event of the stream as its type and data | خواندن یک رویداد stream به‌صورت نوع و داده
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		switch line = strings.TrimSuffix(line, "\n"); {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// subscribers counts the open streams | شمارش streamهای باز
+func (e *eventStream) subscribers() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.subs)
+}
+
+func TestEventStream(t *testing.T) {
+	e := &eventStream{subs: make(map[chan sseEvent]struct{})}
+	srv := httptest.NewServer(http.HandlerFunc(e.serveEvents))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	for e.subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A stalled subscriber loses events but never holds up the others | مشترک گیرکرده رویداد از دست می‌دهد ولی بقیه را معطل نمی‌کند
+	stalled := make(chan sseEvent)
+	e.mu.Lock()
+	e.subs[stalled] = struct{}{}
+	e.mu.Unlock()
+
+	body := bufio.NewReader(resp.Body)
+	e.publish(message{From: "ann", Text: "hi\nthere", ID: "1"})
+	name, data := readEvent(t, body)
+	var m message
+	if err := json.Unmarshal([]byte(data), &m); name != eventMessage || err != nil || m.Text != "hi\nthere" || m.From != "ann" {
+		t.Errorf("event %q with %s: %v", name, data, err)
+	}
+	e.publishEvent(outputEvent{Event: eventError, Error: "boom"})
+	if name, data := readEvent(t, body); name != eventError || !strings.Contains(data, `"boom"`) {
+		t.Errorf("event %q with %s", name, data)
+	}
+
+	e.closeAll()
+	if rest, err := io.ReadAll(body); err != nil || len(rest) != 0 {
+		t.Errorf("after shutdown: %q, %v", rest, err)
+	}
+	if n := e.subscribers(); n != 0 {
+		t.Errorf("%d subscribers left", n)
+	}
+}
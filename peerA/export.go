@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"        // For export argument errors
+	"flag"          // For the export subcommand flags
+	"fmt"           // For Markdown output
+	"html/template" // For escaped HTML output
+	"io"            // For the output writer
+	"os"            // For the output file
+	"strings"       // For Markdown escaping
+	"time"          // For parsing --since
+)
+
+var errExportFormat = errors.New(`format must be "md" or "html"`) // Unknown --format | فرمت نامعتبر
+
+/*
+runExport implements "export": it renders the stored transcript of
+one name as Markdown or HTML, with roster aliases applied:
+
+	peerA export --since 24h --format html -o chat.html
+
+این تابع زیر‌فرمان export را اجرا می‌کند و تاریخچه‌ی ذخیره‌شده‌ی یک نام
+را به‌صورت Markdown یا HTML با نام‌های نمایشی فهرست دوستان خروجی می‌دهد
+*/
+func runExport(defaultName string, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	name := fs.String("name", defaultName, "whose history to export")
+	since := fs.String("since", "", `oldest message to include: a duration ("24h") or a date ("2006-01-02")`)
+	format := fs.String("format", "md", `output format: "md" or "html"`)
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	from, err := parseSince(*since)
+	if err != nil {
+		return err
+	}
+	msgs, err := readHistory(defaultHistoryPath(*name), from)
+	if err != nil {
+		return err
+	}
+	buddies, err := loadRoster(defaultRosterPath(*name))
+	if err != nil {
+		return err
+	}
+	for i := range msgs {
+		msgs[i] = buddies.relabel(msgs[i]) // Senders under their display aliases | فرستندگان با نام‌های نمایشی
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "md":
+		return exportMarkdown(w, *name, msgs)
+	case "html":
+		return exportHTML(w, *name, msgs)
+	default:
+		return errExportFormat
+	}
+}
+
+/*
+parseSince accepts a duration back from now, a date or an RFC 3339
+time; empty means the whole history.
+
+این تابع مدت زمان تا اکنون، تاریخ یا زمان RFC 3339 را می‌پذیرد؛
+رشته‌ی خالی یعنی کل تاریخچه
+*/
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// exportMarkdown writes the transcript as a Markdown list | خروجی Markdown
+func exportMarkdown(w io.Writer, name string, msgs []message) error {
+	md := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, "`", "\\`", `<`, `&lt;`, `[`, `\[`)
+	if _, err := fmt.Fprintf(w, "# Chat transcript (%s)\n\n", name); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		_, err := fmt.Fprintf(w, "- `%s` **%s**: %s\n", m.Time.Local().Format("2006-01-02 15:04"), md.Replace(m.From), md.Replace(m.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transcriptHTML is the HTML export page | قالب صفحه‌ی خروجی HTML
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"stamp": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Chat transcript ({{.Name}})</title>
+<style>body{font-family:sans-serif;max-width:50em;margin:2em auto}time{color:#888;margin-right:.5em}b{margin-right:.3em}</style>
+</head><body>
+<h1>Chat transcript ({{.Name}})</h1>
+{{range .Messages}}<p><time>{{stamp .Time}}</time><b>{{.From}}:</b>{{.Text}}</p>
+{{end}}</body></html>
+`))
+
+// exportHTML writes the transcript as a standalone HTML page | خروجی HTML
+func exportHTML(w io.Writer, name string, msgs []message) error {
+	return transcriptHTML.Execute(w, struct {
+		Name     string
+		Messages []message
+	}{name, msgs})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportEscapes(t *testing.T) {
+	msgs := []message{{Time: time.Now(), From: "b_o*b", Text: "<script>alert(1)</script> [x](javascript:1) `code` \\"}}
+	var md bytes.Buffer
+	if err := exportMarkdown(&md, "ann", msgs); err != nil {
+		t.Fatal(err)
+	}
+	want := "**b\\_o\\*b**: &lt;script>alert(1)&lt;/script> \\[x](javascript:1) \\`code\\` \\\\\n"
+	if !strings.HasPrefix(md.String(), "# Chat transcript (ann)\n\n- `") || !strings.HasSuffix(md.String(), want) {
+		t.Errorf("Markdown export:\n%s\nwant a line ending in\n%s", md.String(), want)
+	}
+
+	var page bytes.Buffer
+	if err := exportHTML(&page, "<ann>", msgs); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(page.String(), "<script>") || strings.Contains(page.String(), "<ann>") {
+		t.Errorf("HTML export is not escaped:\n%s", page.String())
+	}
+	if !strings.Contains(page.String(), "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Errorf("HTML export lost the text:\n%s", page.String())
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	if got, err := parseSince(""); err != nil || !got.IsZero() {
+		t.Errorf("empty: %v, %v; want the whole history", got, err)
+	}
+	if got, err := parseSince("24h"); err != nil || time.Since(got) < 24*time.Hour || time.Since(got) > 25*time.Hour {
+		t.Errorf("24h: %v, %v", got, err)
+	}
+	if got, err := parseSince("2024-03-01"); err != nil || !got.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("date: %v, %v; want local midnight", got, err)
+	}
+	if got, err := parseSince("2024-03-01T12:00:00Z"); err != nil || !got.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339: %v, %v", got, err)
+	}
+	if _, err := parseSince("yesterday"); err == nil {
+		t.Error("yesterday was accepted")
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	h, err := openHistory(defaultHistoryPath("ann"), rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.add(message{Time: time.Now().Add(-48 * time.Hour), From: "bob", Text: "old news", Key: "k1", Verified: true})
+	h.add(message{Time: time.Now(), From: "bob", Text: "fresh", Key: "k1", Verified: true})
+	h.add(message{Time: time.Now(), From: "bob", Text: "forged", Key: "k1"}) // Unsigned, keeps its claimed nick | بدون امضا، با همان نام ادعایی
+	h.close()
+	buddies, err := loadRoster(defaultRosterPath("ann"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buddies.file(rosterEntry{Nick: "bob", Fingerprint: "k1", Alias: "Robert"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "chat.md")
+	if err := runExport("ann", []string{"--since", "24h", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if strings.Contains(got, "old news") || !strings.Contains(got, "**Robert**: fresh") || !strings.Contains(got, "**bob**: forged") {
+		t.Errorf("export since 24h:\n%s", got)
+	}
+	if err := runExport("ann", []string{"--format", "pdf", "-o", out}); err != errExportFormat {
+		t.Errorf("pdf: %v, want %v", err, errExportFormat)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestWordFilterMask(t *testing.T) {
+	in, out, err := newFilters("darn, heck,کلمه,café,ab,abc", "mask", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil {
+		t.Fatal("outgoing messages filtered without filter-outbound")
+	}
+	for text, want := range map[string]string{
+		"darn it":               "**** it",
+		"DARN, Heck!":           "****, ****!",
+		"darned hecktic":        "darned hecktic", // Whole words only | فقط کل کلمه
+		"this کلمه here":        "this **** here",
+		"یک‌کلمه":               "یک‌****", // A zero-width non-joiner splits words | نیم‌فاصله کلمه‌ها را جدا می‌کند
+		"کلمه‌ها":               "****‌ها",
+		"کلمات":                 "کلمات",
+		"un café noir":          "un **** noir",
+		"cafés":                 "cafés",
+		"abc ab abcd":           "*** ** abcd",
+		"snake_darn and darn_x": "snake_darn and darn_x",
+	} {
+		m := message{Text: text}
+		if !in.apply(&m) || m.Text != want {
+			t.Errorf("%q masked to %q, want %q", text, m.Text, want)
+		}
+	}
+}
+
+func TestWordFilterDrop(t *testing.T) {
+	in, out, err := newFilters("spam", "drop", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, kept := range map[string]bool{"buy SPAM now": false, "spammer": true, "hello": true} {
+		for _, chain := range []filterChain{in, out} {
+			m := message{Text: text}
+			if chain.apply(&m) != kept || m.Text != text {
+				t.Errorf("%q: kept %v as %q, want kept %v unchanged", text, !kept, m.Text, kept)
+			}
+		}
+	}
+	if got, ok := outgoingText(&session{outbound: out}, "more spam"); ok {
+		t.Errorf("our own filtered text was sent as %q", got)
+	}
+}
+
+func TestNewFiltersConfig(t *testing.T) {
+	if _, _, err := newFilters("x", "burn", false); err != errFilterAction {
+		t.Errorf("unknown action: %v, want %v", err, errFilterAction)
+	}
+	in, out, err := newFilters(" , ,", "mask", true)
+	if err != nil || in != nil || out != nil {
+		t.Errorf("empty word list: %v, %v, %v; want no filters", in, out, err)
+	}
+	in, _, _ = newFilters("a.b", "mask", false)
+	m := message{Text: "axb a.b"}
+	if in.apply(&m); m.Text != "axb ***" {
+		t.Errorf("words are not matched literally: %q", m.Text)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"         // For reading the history file line by line
+	"encoding/json" // For one JSON message per line
+	"errors"        // For a missing history file
+	"fmt"           // For write errors
+	"os"            // For the history file
+	"sync"          // For serialising appends
+	"time"          // For the since filter
+)
+
+/*
+history is the on-disk transcript: every message shown or sent is
+appended as one JSON line, and the file is rotated under the
+configured limits. A nil history (anonymous mode) records nothing.
+
+این نوع تاریخچه‌ی ذخیره‌شده روی دیسک است: هر پیام نمایش‌داده‌شده یا
+ارسالی به‌صورت یک خط JSON اضافه می‌شود و فایل طبق محدودیت‌های تنظیم‌شده
+چرخانده می‌شود؛ history برابر nil (حالت ناشناس) چیزی ذخیره نمی‌کند
+*/
+type history struct {
+	mu   sync.Mutex
+	path string
+	f    *rotatingFile
+	enc  *json.Encoder
+}
+
+// defaultHistoryPath returns the per-name transcript file | مسیر پیش‌فرض فایل تاریخچه
+func defaultHistoryPath(name string) string {
+	return dataPath(name + ".history")
+}
+
+/*
+openHistory opens the transcript for appending; an empty path returns
+a nil history that records nothing.
+
+این تابع تاریخچه را برای افزودن باز می‌کند؛ path خالی یعنی history
+برابر nil که چیزی ذخیره نمی‌کند
+*/
+func openHistory(path string, rot rotation) (*history, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := openRotating(path, rot)
+	if err != nil {
+		return nil, err
+	}
+	return &history{path: path, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// add appends one message | افزودن یک پیام
+func (h *history) add(m message) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.enc.Encode(m); err != nil {
+		fmt.Fprintln(stdout, "History error:", err)
+	}
+}
+
+// close flushes and closes the file | بستن فایل تاریخچه
+func (h *history) close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_ = h.f.Close()
+}
+
+/*
+readHistory returns the stored messages at or after since, oldest
+first, including the rotated segments still kept; a missing file is an
+empty history and unreadable lines are skipped.
+
+این تابع پیام‌های ذخیره‌شده از زمان since به بعد را، همراه با فایل‌های
+چرخانده‌شده‌ی باقی‌مانده، به ترتیب زمانی برمی‌گرداند؛ نبود فایل یعنی
+تاریخچه‌ی خالی و خطوط خراب نادیده گرفته می‌شوند
+*/
+func readHistory(path string, since time.Time) ([]message, error) {
+	var out []message
+	for _, p := range append(rotatedFiles(path), path) {
+		var err error
+		if out, err = readHistoryFile(p, since, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// readHistoryFile appends the messages of one segment to out | افزودن پیام‌های یک فایل به out
+func readHistoryFile(path string, since time.Time, out []message) ([]message, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 4096), 2*maxMessageSize)
+	for sc.Scan() {
+		var m message
+		if json.Unmarshal(sc.Bytes(), &m) != nil || m.Time.Before(since) {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, sc.Err()
+}
+
+// recordSent stores one of our own messages | ذخیره‌ی یکی از پیام‌های خودمان
+func recordSent(s *session, text string) {
+	s.history.add(message{Time: time.Now(), From: s.name, Text: text, Key: s.id.fingerprint, Verified: true})
+}
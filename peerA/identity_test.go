@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, sig := id.sign("ctx", "a", "b\x00c")
+	_, nulSig := id.sign("ctx", "a\x00b", "c") // Same bytes when joined with NUL | همان بایت‌ها در صورت اتصال با NUL
+	plainKey, plainSig := id.sign("ctx", "ab", "c")
+	for _, c := range []struct {
+		name     string
+		key, sig string
+		fields   []string
+		ok       bool
+	}{
+		{"as signed", plainKey, plainSig, []string{"ctx", "ab", "c"}, true},
+		{"tampered field", plainKey, plainSig, []string{"ctx", "ab", "d"}, false},
+		{"moved boundary", plainKey, plainSig, []string{"ctx", "a", "bc"}, false},
+		{"merged fields", plainKey, plainSig, []string{"ctx", "abc"}, false},
+		{"extra field", plainKey, plainSig, []string{"ctx", "ab", "c", ""}, false},
+		{"missing field", plainKey, plainSig, []string{"ctx", "ab"}, false},
+		{"other context", plainKey, plainSig, []string{"other", "ab", "c"}, false},
+		{"NUL inside a field", key, sig, []string{"ctx", "a", "b\x00c"}, true},
+		{"NUL moved across fields", key, nulSig, []string{"ctx", "a", "b\x00c"}, false},
+		{"other key", other.publicKey(), plainSig, []string{"ctx", "ab", "c"}, false},
+		{"short key", base64.StdEncoding.EncodeToString([]byte("short")), plainSig, []string{"ctx", "ab", "c"}, false},
+		{"key not base64", "!!", plainSig, []string{"ctx", "ab", "c"}, false},
+		{"signature not base64", plainKey, "!!", []string{"ctx", "ab", "c"}, false},
+		{"empty signature", plainKey, "", []string{"ctx", "ab", "c"}, false},
+	} {
+		fp, ok := verifySignature(c.key, c.sig, c.fields...)
+		if ok != c.ok || ok && fp != id.fingerprint {
+			t.Errorf("%s: verified %v as %q, want %v", c.name, ok, fp, c.ok)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt" // For command output
+)
+
+// defaultIgnorePath returns the per-name ignore list file | مسیر پیش‌فرض لیست نادیده‌گیری
+func defaultIgnorePath(name string) string {
+	return dataPath(name + ".ignore")
+}
+
+func init() {
+	registerCommand("ignore", "/ignore <nick|fingerprint> | /ignore list  drop messages from someone", ignoreCommand)
+	registerCommand("unignore", "/unignore <nick|fingerprint>  show their messages again", unignoreCommand)
+}
+
+/*
+ignoreCommand adds a nick or fingerprint to the ignore list, or prints
+the list. Messages matching either are dropped before display and
+storage; fingerprints only match signed messages, so they cannot be
+dodged by changing the nick.
+
+این دستور یک نام یا fingerprint را به لیست نادیده‌گیری اضافه یا لیست را
+چاپ می‌کند؛ پیام‌های منطبق پیش از نمایش و ذخیره حذف می‌شوند و
+fingerprint با تغییر نام دور زده نمی‌شود
+*/
+func ignoreCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /ignore <nick|fingerprint> | /ignore list")
+		return
+	}
+	if args[0] == "list" {
+		printEntries(s.ignores, "Ignore list is empty")
+		return
+	}
+	if err := s.ignores.set(args[0], true); err != nil {
+		fmt.Fprintln(stdout, "Ignore error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, "Ignoring", args[0])
+}
+
+// unignoreCommand removes an entry from the ignore list | حذف یک مورد از لیست نادیده‌گیری
+func unignoreCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /unignore <nick|fingerprint>")
+		return
+	}
+	if err := s.ignores.set(args[0], false); err != nil {
+		fmt.Fprintln(stdout, "Ignore error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, "No longer ignoring", args[0])
+}
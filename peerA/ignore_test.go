@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreList(t *testing.T) {
+	var out bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&out))
+	path := filepath.Join(t.TempDir(), "ann.ignore")
+	ignores, err := loadEntrySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{ignores: ignores}
+	ignoreCommand(s, []string{"bob"})
+	ignoreCommand(s, []string{"0123456789abcdef"})
+
+	for _, c := range []struct {
+		from, key string
+		want      bool
+	}{
+		{"bob", "", true},
+		{"bob", "fedcba9876543210", true},
+		{"mallory", "0123456789abcdef", true}, // A new nick does not dodge the key | نام تازه از کلید فرار نمی‌کند
+		{"0123456789abcdef", "", true},        // Only as a nick | فقط به‌عنوان نام
+		{"carol", "", false},
+		{"carol", "fedcba9876543210", false},
+		{"", "", false},
+	} {
+		if got := s.ignores.has(c.from, c.key); got != c.want {
+			t.Errorf("from %q key %q: ignored %v, want %v", c.from, c.key, got, c.want)
+		}
+	}
+
+	ignores, err = loadEntrySet(path) // Kept across runs | در اجراهای بعدی می‌ماند
+	if err != nil || !ignores.has("bob") || ignores.len() != 2 {
+		t.Fatalf("reloaded %v (%v), want both entries", ignores.sorted(), err)
+	}
+	out.Reset()
+	ignoreCommand(s, []string{"list"})
+	if got := out.String(); got != "  0123456789abcdef\n  bob\n" {
+		t.Errorf("/ignore list printed %q", got)
+	}
+	unignoreCommand(s, []string{"bob"})
+	if s.ignores.has("bob") {
+		t.Error("/unignore kept the nick")
+	}
+	if ignores, _ = loadEntrySet(path); ignores.has("bob") {
+		t.Error("/unignore was not saved")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"           // For command output
+	"os"            // For reading and storing images
+	"path/filepath" // For the image name
+	"strings"       // For the MIME check
+	"time"          // For the message timestamp
+)
+
+const inlineImageMax = 32 << 10 // Largest image sent inside a chat line | بزرگ‌ترین تصویری که داخل خط چت ارسال می‌شود
+
+func init() {
+	registerCommand("image", "/image <path>  send an image, inline when it is small", imageCommand)
+	registerArgCompleter("image", completePath)
+}
+
+/*
+imageCommand sends an image. One of at most inlineImageMax bytes travels
+inside a single signed chat message when the remote supports it, so a
+quick screenshot skips the file transfer handshake; anything else falls
+back to /send.
+
+این دستور یک تصویر ارسال می‌کند؛ تصویر حداکثر inlineImageMax بایتی اگر طرف
+مقابل پشتیبانی کند داخل یک پیام چت امضاشده می‌رود تا اسکرین‌شات سریع بدون
+handshake انتقال فایل برسد و در غیر این صورت مانند /send ارسال می‌شود
+*/
+func imageCommand(s *session, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(stdout, "Usage: /image <path>")
+		return
+	}
+	p := strings.Join(args, " ") // Paths may contain spaces | مسیر ممکن است فاصله داشته باشد
+
+	f, err := os.Open(p)
+	if err != nil {
+		fmt.Fprintln(stdout, "Image error:", err)
+		return
+	}
+	mimeType := detectMIME(f)
+	info, err := f.Stat()
+	_ = f.Close()
+	if err != nil {
+		fmt.Fprintln(stdout, "Image error:", err)
+		return
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		fmt.Fprintf(stdout, "Image error: %s is %s, not an image\n", p, mimeType)
+		return
+	}
+	if s.conn.caps.InlineImages && info.Size() <= inlineImageMax {
+		if err := sendInlineImage(s, p, mimeType); err == nil {
+			return
+		}
+		// Too long for the negotiated line limit: use a transfer | بیش از حد خط: انتقال فایل
+	}
+	sendCommand(s, args)
+}
+
+// sendInlineImage queues an image inside one chat message | قراردادن تصویر داخل یک پیام چت در صف
+func sendInlineImage(s *session, p, mimeType string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	m := message{
+		Time:     time.Now(),
+		From:     s.name,
+		Text:     filepath.Base(p),
+		ID:       newMessageID(),
+		Image:    data,
+		MIME:     mimeType,
+		Key:      s.id.fingerprint,
+		Verified: true,
+	}
+	if err := queueChat(s, m); err != nil {
+		return err
+	}
+	s.acks.track(m.ID)
+	s.threads.add(m)
+	fmt.Fprintf(stdout, "Image sent: %s  #%s\n", m.Text, m.ID)
+	return nil
+}
+
+/*
+storeImage saves an inline image from the remote among the received
+files and replaces the message text with a placeholder naming its ID
+and local path. Images refused by the accept-files patterns are not
+saved, and ones that cannot be saved keep their bare name. The remote's
+name and type are shown without control characters, as describeFile
+does for files.
+
+این تابع تصویر داخل پیام طرف مقابل را کنار فایل‌های دریافتی ذخیره و متن
+پیام را با متنی شامل شناسه و مسیر محلی آن جایگزین می‌کند؛ تصویری که با
+الگوهای accept-files رد شود ذخیره نمی‌شود و اگر ذخیره ممکن نباشد فقط نام آن
+باقی می‌ماند. نام و نوع ارسالی طرف مقابل مانند describeFile بدون نویسه‌های
+کنترلی نمایش داده می‌شوند
+*/
+func storeImage(s *session, m *message) {
+	name := m.Text
+	h := fileHeader{From: m.From, Name: name, MIME: m.MIME, Size: int64(len(m.Image))}
+	reason := refusal(s, h)
+	if reason == "" {
+		if err := s.files.reserve(h.Size); err != nil {
+			reason = err.Error()
+		}
+	}
+	if reason != "" {
+		m.Text = fmt.Sprintf("[image %s refused: %s]", stripControl(name), stripControl(reason))
+		return
+	}
+	f, err := s.files.create(name)
+	if err != nil {
+		s.files.release(h.Size)
+		fmt.Fprintln(stdout, "Image error:", err)
+		return
+	}
+	_, err = f.Write(m.Image)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		s.files.release(h.Size)
+		fmt.Fprintln(stdout, "Image error:", err)
+		return
+	}
+	id := s.files.add(h, f.Name())
+	m.Text = fmt.Sprintf("[image #%d: %s, %s, %d bytes] %s", id, stripControl(name), stripControl(m.MIME), h.Size, f.Name())
+}
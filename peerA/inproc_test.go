@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestInprocTransport(t *testing.T) {
+	tr := inprocTransport{}
+	ln, err := tr.listen("bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.listen("bot"); err == nil {
+		t.Error("a second listener took a used address")
+	}
+	if _, err := tr.dial("nobody"); !errors.Is(err, errInprocRefused) {
+		t.Errorf("dialing an unknown name: %v", err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	client, err := tr.dial("bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server := <-accepted
+	defer server.Close()
+	go func() { _, _ = client.Write([]byte("ping")) }()
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("read %q, %v", buf, err)
+	}
+
+	ln.Close()
+	if _, err := ln.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("accept after close: %v", err)
+	}
+	if _, err := tr.dial("bot"); !errors.Is(err, errInprocRefused) {
+		t.Errorf("dialing a closed listener: %v", err)
+	}
+	again, err := tr.listen("bot")
+	if err != nil {
+		t.Fatalf("the address was not freed: %v", err)
+	}
+	again.Close()
+}
+
+func TestInprocFreshNames(t *testing.T) {
+	tr := inprocTransport{}
+	first, err := tr.listen("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	inprocListeners.Lock()
+	next := inprocListeners.next
+	inprocListeners.Unlock()
+	taken, err := tr.listen("inproc-" + strconv.Itoa(next+1)) // The name the next fresh listener would get | نامی که listener تازه‌ی بعدی می‌گرفت
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	second, err := tr.listen("")
+	if err != nil {
+		t.Fatalf("a fresh name collided with a chosen one: %v", err)
+	}
+	defer second.Close()
+	if second.Addr().String() == first.Addr().String() || second.Addr().String() == taken.Addr().String() {
+		t.Errorf("fresh names %s and %s next to %s", first.Addr(), second.Addr(), taken.Addr())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"        // For a missing file on first run
+	"fmt"           // For command output
+	"os"            // For reading and writing the file
+	"path/filepath" // For creating the data directory
+	"strings"       // For splitting the file
+	"sync"          // For recording from the editor and commands
+)
+
+const defaultInputHistory = 500 // Lines kept when unset | تعداد خطوط پیش‌فرض
+
+func init() {
+	registerCommand("inputhistory", "/inputhistory [on|off|clear]  pause, resume or erase the recalled input lines", inputHistoryCommand)
+	registerArgCompleter("inputhistory", func(_ *session, args []string, word string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return matching(word, "on", "off", "clear")
+	})
+}
+
+/*
+inputHistory is the list of lines typed in the editor, recalled with the
+arrow keys and kept in a file across runs like a shell history. It
+holds at most max lines; lines starting with a space are not kept, and
+recording can be paused for the rest of the run.
+
+این نوع فهرست خطوط تایپ‌شده در ویرایشگر است که با کلیدهای جهت بازیابی
+می‌شوند و مانند تاریخچه‌ی shell بین اجراها در فایلی نگه داشته می‌شوند؛ حداکثر
+max خط نگه می‌دارد، خطوطی که با فاصله شروع شوند ذخیره نمی‌شوند و ثبت را
+می‌توان تا پایان اجرا متوقف کرد
+*/
+type inputHistory struct {
+	mu     sync.Mutex
+	path   string
+	max    int
+	lines  []string // Oldest first | قدیمی‌ترین در ابتدا
+	paused bool
+}
+
+// defaultInputHistoryPath returns the per-name input history file | مسیر پیش‌فرض فایل تاریخچه‌ی ورودی
+func defaultInputHistoryPath(name string) string {
+	return dataPath(name + ".input_history")
+}
+
+/*
+loadInputHistory reads the last max lines of the file at path. An
+empty path keeps the history in memory only, and max 0 turns it off.
+
+این تابع آخرین max خط فایل path را می‌خواند؛ path خالی تاریخچه را فقط در
+حافظه نگه می‌دارد و max برابر ۰ آن را خاموش می‌کند
+*/
+func loadInputHistory(path string, max int) (*inputHistory, error) {
+	h := &inputHistory{path: path, max: max}
+	if path == "" || max <= 0 {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if len(h.lines) > max {
+		h.lines = h.lines[len(h.lines)-max:]
+	}
+	return h, nil
+}
+
+// Add records an entered line, as the editor's History | ثبت خط واردشده
+func (h *inputHistory) Add(entry string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.max <= 0 || h.paused || strings.TrimSpace(entry) == "" || strings.HasPrefix(entry, " ") {
+		return // Off, paused, blank or kept private | خاموش، متوقف، خالی یا خصوصی
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == entry {
+		return // Same as the last one | تکراری
+	}
+	h.lines = append(h.lines, entry)
+	if len(h.lines) > h.max {
+		h.lines = h.lines[len(h.lines)-h.max:]
+	}
+	if err := h.save(); err != nil {
+		fmt.Fprintln(stdout, "Input history error:", err)
+	}
+}
+
+// Len returns the number of recalled lines | تعداد خطوط
+func (h *inputHistory) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.lines)
+}
+
+// At returns a line, 0 being the most recent | خط شماره‌ی idx، ۰ جدیدترین
+func (h *inputHistory) At(idx int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if idx < 0 || idx >= len(h.lines) {
+		return "" // Cleared since Len | پس از Len پاک شده
+	}
+	return h.lines[len(h.lines)-1-idx]
+}
+
+// save writes the file; the caller holds mu | ذخیره فایل (mu باید گرفته شده باشد)
+func (h *inputHistory) save() error {
+	if h.path == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if len(h.lines) == 0 {
+		if err := os.Remove(h.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(h.lines, "\n")+"\n"), 0o600)
+}
+
+/*
+inputHistoryCommand pauses or resumes recording typed lines, or erases
+the recalled lines and their file.
+
+این دستور ثبت خطوط تایپ‌شده را متوقف یا ادامه می‌دهد یا خطوط ذخیره‌شده و
+فایل آن‌ها را پاک می‌کند
+*/
+func inputHistoryCommand(s *session, args []string) {
+	h := s.inputs
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case len(args) == 0:
+		state := "on"
+		if h.max <= 0 {
+			state = "off (input-history is 0)"
+		} else if h.paused {
+			state = "paused"
+		}
+		fmt.Fprintf(stdout, "Input history: %s, %d of %d lines\n", state, len(h.lines), h.max)
+	case args[0] == "on" || args[0] == "off":
+		h.paused = args[0] == "off"
+		if h.paused {
+			fmt.Fprintln(stdout, "Input history paused")
+		} else {
+			fmt.Fprintln(stdout, "Input history resumed")
+		}
+	case args[0] == "clear":
+		h.lines = nil
+		if err := h.save(); err != nil {
+			fmt.Fprintln(stdout, "Input history error:", err)
+			return
+		}
+		fmt.Fprintln(stdout, "Input history cleared")
+	default:
+		fmt.Fprintln(stdout, "Usage: /inputhistory [on|off|clear]")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	kb, err := parseKeys(map[string]string{"Ctrl-G": " send ", "ctrl-t": "", "ctrl-y": "/away brb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kb) != 3 || kb['g'-'a'+1] != keySend || kb['j'-'a'+1] != keyNewline || kb['y'-'a'+1] != "/away brb" {
+		t.Errorf("bindings %q", kb)
+	}
+	for name, want := range map[string]error{
+		"ctrl-1":  errKeyName,
+		"alt-x":   errKeyName,
+		"ctrl-gg": errKeyName,
+		"ctrl-c":  errKeyReserved,
+		"ctrl-r":  errKeyAction,
+	} {
+		action := "send"
+		if want == errKeyAction {
+			action = "shout"
+		}
+		if _, err := parseKeys(map[string]string{name: action}); !errors.Is(err, want) || !strings.HasPrefix(err.Error(), name+": ") {
+			t.Errorf("binding %s: %v, want %v", name, err, want)
+		}
+	}
+}
+
+func TestEditorKeys(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	keys, _ := loadRegistry("")
+	out := make(chan string, 2)
+	s := codeSession(t, capabilities{MaxMessage: 4096}, out)
+	s.theme, _ = newThemeTable(nil, "")
+	s.notify, _ = newNotifier("ann", "")
+	s.aliases = newAliasTable(nil, "")
+	s.keymap, _ = parseKeys(map[string]string{"ctrl-g": keySend, "ctrl-y": "/alias"})
+	var live atomic.Pointer[session]
+	live.Store(s)
+	press := editorKeys(&live, s.keymap)
+	ctrl := func(letter byte) rune { return rune(letter - 'a' + 1) }
+
+	if line, pos, ok := press("first", 5, ctrl('j')); line != "" || pos != 0 || !ok || len(out) != 0 {
+		t.Errorf("ctrl-j: %q at %d, %v; %d sent", line, pos, ok, len(out))
+	}
+	if line, _, ok := press("second", 6, ctrl('g')); line != "" || !ok {
+		t.Errorf("ctrl-g: %q, %v", line, ok)
+	}
+	if m, _ := decodeChatLine(<-out, keys); m.Text != "first\nsecond" {
+		t.Errorf("sent %q, want both lines as one message", m.Text)
+	}
+	if !strings.Contains(buf.String(), consolePrompt+"first\n") || !strings.Contains(buf.String(), consolePrompt+"second\n") {
+		t.Errorf("lines not left above the prompt:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if line, pos, ok := press("half typed", 4, ctrl('y')); line != "half typed" || pos != 4 || !ok || buf.String() != "No aliases\n" {
+		t.Errorf("ctrl-y: %q at %d, %v; output %q", line, pos, ok, buf.String())
+	}
+	press("", 0, ctrl('t'))
+	press("", 0, ctrl('t'))
+	if !strings.Contains(buf.String(), "Do not disturb is on") || !strings.Contains(buf.String(), "Do not disturb is off") {
+		t.Errorf("ctrl-t twice:\n%s", buf.String())
+	}
+	if line, _, ok := press("/ali", 4, '\t'); line != "/alias " || !ok {
+		t.Errorf("Tab: %q, %v", line, ok)
+	}
+	if _, _, ok := press("x", 1, 'x'); ok {
+		t.Error("an unbound key was taken")
+	}
+
+	buf.Reset()
+	keysCommand(s, nil)
+	if want := "  ctrl-g  send\n  ctrl-j  newline\n  ctrl-t  dnd\n  ctrl-y  /alias\n"; buf.String() != want {
+		t.Errorf("/keys:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"   // For reading typed lines
+	"fmt"     // For received messages and status
+	"io"      // For the status writer
+	"net"     // For the multicast socket
+	"os"      // For stdin
+	"regexp"  // For highlighting mentions
+	"strings" // For skipping blank lines
+	"sync"    // For the dedup set shared by sending and showing
+	"time"    // For timestamps and the dedup window
+)
+
+/*
+LAN mode settings
+
+تنظیمات حالت LAN:
+- lanGroup گروه multicast در محدوده‌ی محلی سازمان است
+- lanMaxLine بزرگ‌ترین خط چت در یک datagram است
+- lanDedupWindow مدت به خاطر سپردن پیام‌های دیده‌شده است
+*/
+const (
+	lanGroup       = "239.255.67.80:8067"
+	lanMaxLine     = 60 << 10
+	lanDedupWindow = 10 * time.Minute
+)
+
+/*
+lanChat is the connectionless LAN mode: every chat line is one signed
+envelope in a UDP datagram to a multicast group, so everyone on the
+subnet running the tool sees it and nobody dials anyone. Received lines
+go through the same signature check, ignore list, word filter, history
+and display as on a link. Multicast may deliver a datagram twice, and
+our own come back to us, so lines are remembered by key and ID. A
+signed line whose signed time is lanDedupWindow or more away from our
+clock has outlived that memory and is dropped as a replay.
+
+این نوع حالت LAN بدون اتصال است: هر خط چت یک پاکت امضاشده در یک datagram
+UDP به گروه multicast است، پس همه‌ی کسانی که در زیرشبکه برنامه را اجرا
+می‌کنند آن را می‌بینند و کسی به کسی dial نمی‌کند. خطوط دریافتی از همان
+بررسی امضا، فهرست نادیده‌گرفتن، فیلتر کلمات، تاریخچه و نمایش اتصال عبور
+می‌کنند؛ multicast ممکن است datagram را دو بار برساند و خطوط خود ما هم
+برمی‌گردند، پس خطوط بر اساس کلید و شناسه به خاطر سپرده می‌شوند. خط امضاشده‌ای
+که زمان امضاشده‌اش lanDedupWindow یا بیشتر با ساعت ما فاصله دارد از این حافظه
+بیرون مانده و به‌عنوان بازپخش دور ریخته می‌شود
+*/
+type lanChat struct {
+	name     string
+	id       *identity
+	keys     *registry
+	ignores  *entrySet
+	inbound  filterChain
+	outbound filterChain
+	history  *history
+	theme    *themeTable
+	mention  *regexp.Regexp
+	links    bool // Clickable URLs | لینک‌های قابل کلیک
+	status   io.Writer
+
+	conn *net.UDPConn // Joined to the group | عضو گروه
+	out  *net.UDPConn // Sends with loopback on, for peers on this host | ارسال با loopback برای peerهای همین میزبان
+	mu   sync.Mutex
+	seen map[string]time.Time // Key and ID of recent lines | کلید و شناسه‌ی خطوط اخیر
+}
+
+/*
+run joins the group and chats until stdin ends or done is closed.
+
+این تابع به گروه می‌پیوندد و تا پایان stdin یا بسته‌شدن done گفتگو می‌کند
+*/
+func (l *lanChat) run(done *doneSignal) error {
+	group, err := net.ResolveUDPAddr("udp4", lanGroup)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	out, err := net.DialUDP("udp4", nil, group) // The joined socket does not loop back | socket عضو گروه loopback ندارد
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	l.conn, l.out, l.seen = conn, out, make(map[string]time.Time)
+
+	fmt.Fprintln(l.status, "LAN mode    :", lanGroup, "(everyone on the local network sees what you type)")
+	fmt.Fprintln(l.status, "Identity    :", l.id.fingerprint)
+	incoming := make(chan message, 32)
+	go l.receive(incoming, done)
+	go l.read(done)
+	for {
+		select {
+		case m := <-incoming:
+			l.show(m)
+		case <-done.c:
+			fmt.Fprintln(l.status, "Left the LAN chat. Bye.")
+			return nil
+		}
+	}
+}
+
+// read sends each typed line to the group | ارسال هر خط تایپ‌شده به گروه
+func (l *lanChat) read(done *doneSignal) {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		if err := l.send(sc.Text()); err != nil {
+			fmt.Fprintln(l.status, "Send error:", err)
+		}
+	}
+	done.close() // End of input | پایان ورودی
+}
+
+// send filters, signs and multicasts one of our messages | فیلتر، امضا و ارسال multicast یکی از پیام‌های ما
+func (l *lanChat) send(text string) error {
+	m := message{Time: time.Now(), From: l.name, Text: text, ID: newMessageID(), Key: l.id.fingerprint, Verified: true}
+	if !l.outbound.apply(&m) {
+		return errBlocked
+	}
+	line := encodeChat(l.id, m)
+	if len(line) > lanMaxLine {
+		return errTooLong
+	}
+	l.remember(m) // Our own copy comes back | نسخه‌ی خود ما برمی‌گردد
+	if _, err := l.out.Write([]byte(line)); err != nil {
+		return err
+	}
+	l.history.add(m)
+	return nil
+}
+
+// receive decodes datagrams from the group until the socket closes | رمزگشایی datagramهای گروه تا بسته‌شدن socket
+func (l *lanChat) receive(incoming chan<- message, done *doneSignal) {
+	b := make([]byte, lanMaxLine)
+	for {
+		n, _, err := l.conn.ReadFromUDP(b)
+		if err != nil {
+			done.close()
+			return
+		}
+		m, ok := decodeChatLine(strings.TrimSuffix(string(b[:n]), "\n"), l.keys)
+		if !ok || m.Cover || m.Part != "" {
+			continue // Impersonation, or nothing to show alone | جعل هویت یا چیزی که به‌تنهایی نمایش ندارد
+		}
+		select {
+		case incoming <- m:
+		case <-done.c:
+			return
+		}
+	}
+}
+
+/*
+remember records a line and reports whether it is new. Entries older
+than lanDedupWindow are forgotten as new ones come in.
+
+این تابع یک خط را ثبت می‌کند و می‌گوید آیا تازه است؛ ورودی‌های قدیمی‌تر از
+lanDedupWindow با آمدن ورودی‌های تازه فراموش می‌شوند
+*/
+func (l *lanChat) remember(m message) bool {
+	key := m.Key + "/" + m.ID
+	if m.ID == "" {
+		key = m.From + "/" + m.Text // Plain lines from older peers | خطوط ساده‌ی peerهای قدیمی
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.seen[key]; ok && now.Sub(t) < lanDedupWindow {
+		return false
+	}
+	for k, t := range l.seen {
+		if now.Sub(t) >= lanDedupWindow {
+			delete(l.seen, k)
+		}
+	}
+	l.seen[key] = now
+	return true
+}
+
+/*
+stale reports whether a signed line was sent too long ago, or too far
+ahead, to be told apart from a replay once remember has forgotten it.
+Unsigned lines carry no time we can trust and are shown as unverified.
+
+این تابع می‌گوید آیا خط امضاشده آن‌قدر قدیمی یا جلوتر از ساعت ما است که
+پس از فراموش‌شدن در remember از بازپخش قابل تشخیص نباشد؛ خطوط بدون امضا
+زمان قابل اعتمادی ندارند و بدون تأیید نمایش داده می‌شوند
+*/
+func stale(m message, now time.Time) bool {
+	if !m.Verified {
+		return false
+	}
+	age := now.Sub(m.Sent)
+	return age >= lanDedupWindow || age <= -lanDedupWindow
+}
+
+// show prints one received message unless it is stale, a repeat, ignored or filtered | نمایش پیام دریافتی مگر کهنه، تکراری، نادیده‌گرفته یا فیلترشده
+func (l *lanChat) show(m message) {
+	if stale(m, time.Now()) || !l.remember(m) || l.ignores.has(m.From, m.Key) || !l.inbound.apply(&m) {
+		return
+	}
+	l.history.add(m)
+	line := l.theme.remote(displayMessage(m), l.mention)
+	if l.links {
+		line = linkify(line)
+	}
+	fmt.Fprintln(stdout, line)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"        // For request bodies
+	"context"      // For the open request budget
+	"crypto/rand"  // For link IDs
+	"encoding/hex" // For link IDs as text
+	"errors"       // For link error values
+	"fmt"          // For status errors
+	"io"           // For reading bodies
+	"net"          // For the link ends and addresses
+	"net/http"     // For the polling endpoints
+	"strings"      // For the dial URL and path
+	"sync"         // For the link table
+	"time"         // For the poll window
+)
+
+/*
+Long-polling settings
+
+تنظیمات long-polling:
+- pollWait مدتی است که GET برای رسیدن داده منتظر می‌ماند
+- pollChunk بیشترین بایت‌های یک درخواست یا پاسخ است
+- pollPath مسیر endpointهای اتصال است
+*/
+const (
+	pollWait  = 25 * time.Second
+	pollChunk = 64 << 10
+	pollPath  = "/link"
+)
+
+var (
+	errPollBusy = errors.New("the remote is not accepting a link") // Nobody took the link | کسی اتصال را نپذیرفت
+	errPollGone = errors.New("the remote ended the link")          // Unknown or closed link ID | شناسه‌ی ناشناخته یا بسته
+)
+
+// pollClient makes the polling requests; a GET waits up to pollWait | کلاینت درخواست‌های polling؛ GET تا pollWait منتظر می‌ماند
+var pollClient = &http.Client{Timeout: pollWait + dialTimeout}
+
+/*
+pollTransport carries the link over plain HTTP requests for networks
+that only let HTTP(S) out. The dialing side opens a link with
+POST /link, then POSTs what it writes to /link/<id> and long-polls the
+same path with GET for what the other side wrote; DELETE ends it. The
+listening side serves those endpoints, and an HTTPS proxy in front of
+it may terminate TLS. Each end of a link is a net.Pipe whose far side
+is pumped through the requests, so deadlines work as on any link.
+
+این نوع اتصال را برای شبکه‌هایی که فقط HTTP(S) خروجی دارند روی درخواست‌های
+HTTP ساده می‌برد: سمت dial با POST /link اتصال را باز می‌کند، نوشته‌هایش را
+با POST به /link/<id> می‌فرستد و همان مسیر را با GET برای نوشته‌های طرف دیگر
+long-poll می‌کند و DELETE آن را پایان می‌دهد. سمت listen این endpointها را
+سرویس می‌دهد و یک proxy HTTPS جلوی آن می‌تواند TLS را پایان دهد؛ هر سر اتصال
+یک net.Pipe است که سر دیگرش از طریق درخواست‌ها پمپ می‌شود تا deadlineها
+مانند هر اتصال دیگری کار کنند
+*/
+type pollTransport struct{}
+
+// pollAddr is an HTTP endpoint or client address | آدرس endpoint یا کلاینت HTTP
+type pollAddr string
+
+func (pollAddr) Network() string  { return "http" }
+func (a pollAddr) String() string { return string(a) }
+
+// pollConn is a pipe end with the link's addresses | سر pipe با آدرس‌های اتصال
+type pollConn struct {
+	net.Conn
+	local, remote pollAddr
+}
+
+func (c *pollConn) LocalAddr() net.Addr  { return c.local }
+func (c *pollConn) RemoteAddr() net.Addr { return c.remote }
+
+// dial opens a link at remote, a URL or host:port | باز کردن اتصال در remote که URL یا host:port است
+func (pollTransport) dial(remote string) (net.Conn, error) {
+	base := strings.TrimSuffix(remote, "/")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+pollPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pollClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	id, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	resp.Body.Close()
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s: %s", base, strings.TrimSpace(string(id)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	url := base + pollPath + "/" + string(id)
+	app, inner := net.Pipe()
+	go pollSend(url, inner)
+	go pollReceive(url, inner)
+	return &pollConn{Conn: app, local: "client", remote: pollAddr(base)}, nil
+}
+
+// pollSend POSTs what the local end writes, in order | ارسال نوشته‌های سر محلی با POST به ترتیب
+func pollSend(url string, inner net.Conn) {
+	defer inner.Close()
+	b := make([]byte, pollChunk)
+	for {
+		n, err := inner.Read(b)
+		if err != nil {
+			pollRequest(http.MethodDelete, url, nil) // Let the listener go | آزادکردن listener
+			return
+		}
+		if _, err := pollRequest(http.MethodPost, url, b[:n]); err != nil {
+			return
+		}
+	}
+}
+
+// pollReceive long-polls for what the remote wrote | long-poll برای نوشته‌های طرف مقابل
+func pollReceive(url string, inner net.Conn) {
+	defer inner.Close()
+	for {
+		data, err := pollRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return
+		}
+		if _, err := inner.Write(data); err != nil {
+			return
+		}
+	}
+}
+
+// pollRequest makes one request and returns the response body | یک درخواست و بدنه‌ی پاسخ آن
+func pollRequest(method, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pollClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, pollChunk))
+	switch {
+	case err != nil:
+		return nil, err
+	case resp.StatusCode == http.StatusGone:
+		return nil, errPollGone
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent:
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return data, nil
+}
+
+// listen serves the polling endpoints on addr | سرویس endpointهای polling روی addr
+func (pollTransport) listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	l := &pollListener{addr: pollAddr(ln.Addr().String()), links: make(map[string]net.Conn), conns: make(chan net.Conn), closed: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+pollPath, l.open)
+	mux.HandleFunc(pollPath+"/{id}", l.serve)
+	l.srv = &http.Server{Handler: mux, ReadHeaderTimeout: dialTimeout}
+	go l.srv.Serve(ln)
+	return l, nil
+}
+
+// pollListener hands opened links to Accept | تحویل اتصال‌های بازشده به Accept
+type pollListener struct {
+	addr   pollAddr
+	srv    *http.Server
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	links map[string]net.Conn // Far pipe ends by link ID | سر دور pipeها بر اساس شناسه‌ی اتصال
+}
+
+func (l *pollListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops serving; links already accepted end with it | توقف سرویس؛ اتصال‌های پذیرفته‌شده هم پایان می‌یابند
+func (l *pollListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		_ = l.srv.Close()
+	})
+	return nil
+}
+
+func (l *pollListener) Addr() net.Addr {
+	return l.addr
+}
+
+// open answers POST /link with a new link ID once Accept takes it | پاسخ POST /link با شناسه‌ی تازه پس از پذیرش
+func (l *pollListener) open(w http.ResponseWriter, r *http.Request) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := hex.EncodeToString(b[:])
+	app, inner := net.Pipe()
+	select {
+	case l.conns <- &pollConn{Conn: app, local: l.addr, remote: pollAddr(r.RemoteAddr)}:
+	case <-time.After(dialTimeout):
+		http.Error(w, errPollBusy.Error(), http.StatusServiceUnavailable)
+		return
+	case <-l.closed:
+		http.Error(w, errPollBusy.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	l.mu.Lock()
+	l.links[id] = inner
+	l.mu.Unlock()
+	_, _ = io.WriteString(w, id)
+}
+
+/*
+serve handles one link: POST delivers the body, GET waits up to
+pollWait for data (204 when none came) and DELETE ends the link. An
+unknown or ended link answers 410.
+
+این تابع یک اتصال را سرویس می‌دهد: POST بدنه را تحویل می‌دهد، GET تا pollWait
+برای داده منتظر می‌ماند (اگر نیامد 204) و DELETE اتصال را پایان می‌دهد؛
+اتصال ناشناخته یا پایان‌یافته 410 پاسخ می‌دهد
+*/
+func (l *pollListener) serve(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	l.mu.Lock()
+	inner := l.links[id]
+	l.mu.Unlock()
+	if inner == nil {
+		http.Error(w, errPollGone.Error(), http.StatusGone)
+		return
+	}
+	gone := func() {
+		l.mu.Lock()
+		delete(l.links, id)
+		l.mu.Unlock()
+		_ = inner.Close()
+		http.Error(w, errPollGone.Error(), http.StatusGone)
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, pollChunk))
+		if err != nil {
+			return
+		}
+		if _, err := inner.Write(data); err != nil {
+			gone()
+		}
+	case http.MethodGet:
+		_ = inner.SetReadDeadline(time.Now().Add(pollWait))
+		b := make([]byte, pollChunk)
+		n, err := inner.Read(b)
+		var ne net.Error
+		switch {
+		case errors.As(err, &ne) && ne.Timeout():
+			w.WriteHeader(http.StatusNoContent) // Poll again | دوباره poll کنید
+		case err != nil:
+			gone()
+		default:
+			_, _ = w.Write(b[:n])
+		}
+	case http.MethodDelete:
+		gone()
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPollTransport(t *testing.T) {
+	ln, err := pollTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		s, err := ln.Accept()
+		if err == nil {
+			accepted <- s
+		}
+	}()
+	c, err := pollTransport{}.dial("http://" + ln.Addr().String() + "/") // As a proxy URL is written | همان‌طور که URL یک proxy نوشته می‌شود
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := <-accepted
+	_ = c.SetDeadline(time.Now().Add(10 * time.Second))
+	_ = s.SetDeadline(time.Now().Add(10 * time.Second))
+
+	big := bytes.Repeat([]byte("0123456789abcdef"), pollChunk/8) // Two requests' worth | به اندازه‌ی دو درخواست
+	go func() { _, _ = c.Write(big) }()
+	got := make([]byte, len(big))
+	if _, err := io.ReadFull(s, got); err != nil || !bytes.Equal(got, big) {
+		t.Fatalf("listener read %d bytes (%v), want the written %d in order", len(got), err, len(big))
+	}
+	if _, err := io.WriteString(s, "HELLO back\n"); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 64)
+	if n, err := c.Read(b); err != nil || string(b[:n]) != "HELLO back\n" {
+		t.Fatalf("dialer read %q, %v", b[:n], err)
+	}
+
+	c.Close() // Sends DELETE | DELETE ارسال می‌شود
+	if _, err := s.Read(b); err == nil {
+		t.Fatal("the listener's end outlived the dialer closing the link")
+	}
+}
+
+func TestPollUnknownLinkGone(t *testing.T) {
+	ln, err := pollTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	url := "http://" + ln.Addr().String() + pollPath + "/0123456789abcdef"
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		if _, err := pollRequest(method, url, []byte("x")); err != errPollGone {
+			t.Errorf("%s of an unknown link: %v, want %v", method, err, errPollGone)
+		}
+	}
+}
@@ -1,68 +1,494 @@
 package main // Main package: entry point of the Go application
 
 import (
-	"bufio"   // For buffered I/O (reading from stdin, writing to TCP)
-	"fmt"     // For formatted input/output (printing logs)
-	"net"     // For TCP networking
-	"os"      // For accessing OS features (stdin)
-	"strings" // For string manipulation (TrimSpace)
-	"time"    // For timeouts and retry intervals
+	"bufio"       // For buffered I/O (reading from stdin, writing to TCP)
+	"errors"      // For inspecting handshake errors
+	"flag"        // For command-line flags
+	"fmt"         // For formatted input/output (printing logs)
+	"io"          // For where notices are printed
+	"net"         // For TCP networking
+	"os"          // For accessing OS features (stdin)
+	"strings"     // For string manipulation (TrimSpace)
+	"sync"        // For the once-only close of the done signal
+	"sync/atomic" // For the handshake arbiter flag
+	"time"        // For timeouts and retry intervals
+
+	"peerA/config" // Layered settings loader (file, env, flags)
 )
 
 /*
 Constant configuration values
 
-مقادیر ثابت پیکربندی برنامه:
+Listen/dial addresses and the name are only defaults;
+see package config for how they are overridden.
+
+مقادیر ثابت پیکربندی برنامه
+(آدرس‌ها و نام فقط مقدار پیش‌فرض هستند و از طریق پکیج config قابل تغییرند):
 - آدرس گوش‌دادن محلی
 - آدرس اتصال به peer مقابل
 - فاصله تلاش مجدد برای اتصال
 - timeout نوشتن روی TCP
+- نام پیش‌فرض این peer
 */
 const (
 	localListenAddr  = "0.0.0.0:8080"         // Peer A listens on this address | آدرس Listen این برنامه
 	remoteDialAddr   = "127.0.0.1:8081"       // Peer A dials Peer B | آدرس Peer مقابل
 	dialRetryEvery   = 700 * time.Millisecond // Delay between dial retries | فاصله تلاش مجدد اتصال
 	connWriteTimeout = 5 * time.Second        // TCP write timeout | تایم‌اوت نوشتن روی TCP
+	connBufferSize   = 32 << 10               // Bytes of a burst coalesced per write | حجم پیام‌های پشت سر هم در هر نوشتن
+	defaultName      = "A"                    // Default name shown to the remote peer | نام پیش‌فرض این peer نزد طرف مقابل
 )
 
 func main() {
+	defer crashOnPanic() // A dump even when main itself panics | گزارش حتی هنگام panic در main
+	os.Exit(run())       // Only once every deferred cleanup in run is done | فقط پس از همه‌ی پاک‌سازی‌های run
+}
+
+/*
+run is the whole program and returns its exit status: 0, exitFailed
+when setup or the link failed, exitUsage for bad arguments, or the
+delivery status of a one-shot send. Its deferred cleanups all run
+before main exits.
+
+این تابع کل برنامه است و کد خروج را برمی‌گرداند: ۰، exitFailed در صورت
+شکست راه‌اندازی یا اتصال، exitUsage برای آرگومان نادرست یا وضعیت تحویل ارسال
+یک‌باره؛ همه‌ی پاک‌سازی‌های deferشده‌ی آن پیش از خروج main اجرا می‌شوند
+*/
+func run() (code int) {
+	// Subcommands run instead of the chat | زیرفرمان‌ها به‌جای چت اجرا می‌شوند
+	args := os.Args[1:]
+	connectTo := "" // Roster nick given to "connect" | نام فهرست دوستان داده‌شده به connect
+	reconnect := false
+	pair := false
+	var inviteTTL *time.Duration // Set by "invite" | با invite مقدار می‌گیرد
+	var sendTo *string           // Set by "send" | با send مقدار می‌گیرد
+	var sendTimeout *time.Duration
+	flag.Usage = printUsage
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "chat":
+			args = os.Args[2:] // The default, spelled out | همان حالت پیش‌فرض به‌صورت صریح
+		case "serve":
+			args = append([]string{"-daemon"}, os.Args[2:]...) // Hold the link for -attach terminals | نگه‌داشتن اتصال برای ترمینال‌های -attach
+		case "connect":
+			if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+				fmt.Fprintln(stdout, "Usage: connect <name> [flags]")
+				return exitUsage
+			}
+			connectTo = os.Args[2]
+			args = append([]string{"-dial", connectTo}, os.Args[3:]...) // Chat as usual, dialing the roster entry | چت معمولی با dial به ورودی فهرست
+		case "pair":
+			pair = true // Needs the identity and roster, so it runs after loading them | به identity و فهرست نیاز دارد و پس از بارگذاری آن‌ها اجرا می‌شود
+			args = os.Args[2:]
+		case "invite":
+			inviteTTL = flag.Duration("ttl", defaultInviteTTL, "how long the invite token stays valid")
+			args = os.Args[2:]
+		case "send":
+			sendTo = flag.String("to", "", "address of the peer to deliver the message to")
+			sendTimeout = flag.Duration("timeout", defaultSendTimeout, "give up unless the message is acknowledged within this long")
+			args = os.Args[2:]
+		case "reconnect":
+			reconnect = true // Chat as usual, dialing the last peer | چت معمولی با dial به آخرین peer
+			args = os.Args[2:]
+		case "history":
+			return subcommandExit(stdout, "History", runHistory(defaultName, os.Args[2:]))
+		case "export":
+			return subcommandExit(stdout, "Export", runExport(defaultName, os.Args[2:]))
+		case "bench":
+			return subcommandExit(os.Stderr, "Bench", runBench(os.Args[2:]))
+		case "soak":
+			return subcommandExit(stdout, "Soak", runSoak(os.Args[2:]))
+		case "help":
+			args = []string{"-h"} // Printed once the chat flags are defined | پس از تعریف پرچم‌های چت چاپ می‌شود
+		default:
+			if !strings.HasPrefix(os.Args[1], "-") {
+				fmt.Fprintf(stdout, "Unknown command %q (try help)\n", os.Args[1])
+				return exitUsage
+			}
+		}
+	}
+
+	attach := flag.Bool("attach", false, "attach this terminal to a running daemon")
+	showVersion := flag.Bool("version", false, "print the build version and exit")
+
+	// Settings: defaults < config file < PEERCHAT_* env < flags | اولویت تنظیمات: پیش‌فرض < فایل < محیط < پرچم
+	cfg, err := config.Load(flag.CommandLine, args, config.Config{
+		Listen: localListenAddr,
+		Dial:   remoteDialAddr,
+		Name:   defaultName,
+
+		FilterAction:  "mask",
+		Access:        accessOpen,
+		Transport:     transportTCP,
+		Baud:          defaultBaud,
+		Idle:          5 * time.Minute,
+		KeepAlive:     defaultKeepAlive,
+		NoDelay:       true,
+		Linger:        -1,
+		Hyperlinks:    hyperlinksAuto,
+		Notify:        notifyOff,
+		SpamRate:      spamDefaultRate,
+		SpamRepeat:    spamDefaultRepeat,
+		SpamCooldown:  spamDefaultCooldown,
+		LogFormat:     "text",
+		Output:        outputText,
+		RotateKeep:    5,
+		MaxFile:       maxFileSize >> 20,
+		DownloadQuota: defaultDownloadQuota >> 20,
+		InputHistory:  defaultInputHistory,
+		Theme:         themePlain,
+	})
+	if err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+	if cfg.Socket == "" {
+		cfg.Socket = defaultSocketPath(cfg.Name)
+	}
+	if cfg.Identity == "" {
+		cfg.Identity = defaultIdentityPath(cfg.Name)
+	}
+
+	if *showVersion {
+		fmt.Fprintf(stdout, "PeerA %s (protocol %d)\n", version, protocolVersion)
+		return 0
+	}
+
+	// Thin client: no peer connection of its own | کلاینت سبک: بدون اتصال مستقیم به peer
+	if *attach {
+		if err := runAttach(cfg.Socket); err != nil {
+			fmt.Fprintln(stdout, "Attach error:", err)
+			return exitFailed
+		}
+		return 0
+	}
+
+	// One message, then exit with its delivery status | یک پیام و سپس خروج با وضعیت تحویل آن
+	sendText := ""
+	var sendCode atomic.Int32
+	if sendTo != nil {
+		sendText = strings.TrimSpace(strings.Join(flag.Args(), " "))
+		if *sendTo == "" || sendText == "" {
+			fmt.Fprintln(stdout, `Usage: send --to <host:port> [flags] "message"`)
+			return exitUsage
+		}
+		cfg.Dial, cfg.Daemon, cfg.LAN = *sendTo, false, false
+		sendCode.Store(exitFailed)
+		defer func() { code = int(sendCode.Load()) }() // After every other cleanup | پس از همه‌ی پاک‌سازی‌های دیگر
+	}
+
+	// Long-term signing key, or a throwaway one with a guest nick | کلید بلندمدت، یا کلید موقت با نام مهمان
+	var id *identity
+	if cfg.Anon {
+		id, err = newEphemeralIdentity()
+	} else {
+		id, err = loadIdentity(cfg.Identity)
+	}
+	if err != nil {
+		fmt.Fprintln(stdout, "Identity error:", err)
+		return exitFailed
+	}
+	defer id.wipe() // Key material leaves memory on exit | پاک‌شدن کلید از حافظه هنگام خروج
+	if cfg.Anon {
+		cfg.Name = guestName(id)
+	}
+	recordCrashConfig(cfg) // Settings for crash dumps, password removed | تنظیمات برای گزارش خرابی بدون رمز
+	ignores, err := loadEntrySet(stateFile(cfg.Anon, defaultIgnorePath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Ignore list error:", err)
+		return exitFailed
+	}
+	bans, err := loadEntrySet(stateFile(cfg.Anon, defaultBanPath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Ban list error:", err)
+		return exitFailed
+	}
+	keys, err := loadRegistry(stateFile(cfg.Anon, defaultRegistryPath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Registry error:", err)
+		return exitFailed
+	}
+	members, err := loadEntrySet(stateFile(cfg.Anon, defaultMembersPath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Invite list error:", err)
+		return exitFailed
+	}
+	seen, err := loadLastSeen(stateFile(cfg.Anon, defaultLastSeenPath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Last seen error:", err)
+		return exitFailed
+	}
+	keymap, err := parseKeys(cfg.Keys)
+	if err != nil {
+		fmt.Fprintln(stdout, "Keys error:", err)
+		return exitFailed
+	}
+	buddies, err := loadRoster(stateFile(cfg.Anon, defaultRosterPath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Roster error:", err)
+		return exitFailed
+	}
+	if err := buddies.loadRecent(stateFile(cfg.Anon, defaultRecentPath(cfg.Name))); err != nil {
+		fmt.Fprintln(stdout, "Recent error:", err)
+		return exitFailed
+	}
+	tokens := &tokenStore{path: stateFile(cfg.Anon, defaultTokensPath(cfg.Name))}
+	if inviteTTL != nil {
+		if err := runInvite(cfg.Listen, *inviteTTL, id, tokens); err != nil {
+			fmt.Fprintln(stdout, "Invite error:", err)
+			return exitFailed
+		}
+		return 0
+	}
+	if pair {
+		token := ""
+		if cfg.Access == accessPassword {
+			token = cfg.Password // The link must let the other side in | پیوند باید اجازه‌ی ورود طرف مقابل را بدهد
+		}
+		if err := runPair(cfg.Name, cfg.Listen, token, id, keys, buddies, flag.Args()); err != nil {
+			fmt.Fprintln(stdout, "Pair error:", err)
+			return exitFailed
+		}
+		return 0
+	}
+	if reconnect {
+		last, ok := buddies.last()
+		if !ok {
+			fmt.Fprintln(stdout, "Reconnect error:", errNoRecent)
+			return exitFailed
+		}
+		cfg.Dial = last.Address
+	}
+	invitedKey := "" // Key named by a peerchat:// link | کلید نام‌برده در پیوند peerchat://
+	if isPeerLink(cfg.Dial) {
+		link, err := parsePairing(cfg.Dial)
+		if err != nil {
+			fmt.Fprintln(stdout, "Dial error:", err)
+			return exitFailed
+		}
+		cfg.Dial, invitedKey = link.Address, link.Fingerprint
+		if cfg.Password == "" {
+			cfg.Password = link.Token // Presented in the handshake | در handshake ارائه می‌شود
+		}
+	}
+	if addr, ok := buddies.dialTarget(cfg.Dial); ok {
+		cfg.Dial = addr // A roster nick dials its last address | نام فهرست دوستان به آخرین آدرس آن وصل می‌شود
+	} else if connectTo != "" {
+		fmt.Fprintf(stdout, "Connect error: %s: %v\n", connectTo, errRosterNoAddress)
+		return exitFailed
+	}
+	tr, err := newTransport(cfg.Transport, cfg.Device, cfg.Baud)
+	if err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+	if cfg.DoH != "" {
+		doh, err := newDoHResolver(cfg.DoH)
+		if err != nil {
+			fmt.Fprintln(stdout, "Config error:", err)
+			return exitFailed
+		}
+		lookupHost = doh.lookup // The contact's name stays off the local network | نام طرف مقابل از شبکه‌ی محلی دور می‌ماند
+	}
+	themes, err := newThemeTable(cfg.Themes, cfg.Theme)
+	if err != nil {
+		fmt.Fprintln(stdout, "Theme error:", err)
+		return exitFailed
+	}
+	inputs, err := loadInputHistory(stateFile(cfg.Anon, defaultInputHistoryPath(cfg.Name)), cfg.InputHistory)
+	if err != nil {
+		fmt.Fprintln(stdout, "Input history error:", err)
+		return exitFailed
+	}
+	auth, err := newPeerAuth(id, bans, members, cfg.Access, cfg.Password)
+	if err != nil {
+		fmt.Fprintln(stdout, "Access error:", err)
+		return exitFailed
+	}
+	auth.pin, err = pinKey(cfg.Pin, invitedKey) // Only that key gets in | فقط همان کلید وارد می‌شود
+	if err != nil {
+		fmt.Fprintln(stdout, "Pin error:", err)
+		return exitFailed
+	}
+	auth.tokens = tokens
+	auth.resume, err = loadResume(stateFile(cfg.Anon, defaultResumePath(cfg.Name)))
+	if err != nil {
+		fmt.Fprintln(stdout, "Resume error:", err)
+		return exitFailed
+	}
+	rot := rotation{maxSize: int64(cfg.RotateSize) << 20, maxAge: cfg.RotateAge, keep: cfg.RotateKeep}
+	hist, err := openHistory(stateFile(cfg.Anon, defaultHistoryPath(cfg.Name)), rot)
+	if err != nil {
+		fmt.Fprintln(stdout, "History error:", err)
+		return exitFailed
+	}
+	defer hist.close()
+	oplog, err = openOpLog(stateFile(cfg.Anon, cfg.Log), cfg.LogFormat, cfg.Name, rot)
+	if err != nil {
+		fmt.Fprintln(stdout, "Log error:", err)
+		return exitFailed
+	}
+	defer oplog.close()
+	inbound, outbound, err := newFilters(cfg.FilterWords, cfg.FilterAction, cfg.FilterOutbound)
+	if err != nil {
+		fmt.Fprintln(stdout, "Filter error:", err)
+		return exitFailed
+	}
+	hyperlinks, err := useHyperlinks(cfg.Hyperlinks, cfg.Daemon)
+	if err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+	notify, err := newNotifier(cfg.Name, cfg.Notify)
+	if err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+	gen, err := newLoadGenerator(cfg.Generate)
+	if err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+	if err := setOutput(cfg.Output); err != nil {
+		fmt.Fprintln(stdout, "Config error:", err)
+		return exitFailed
+	}
+
+	// LAN mode: no link, everyone on the subnet | حالت LAN: بدون اتصال، همه‌ی افراد زیرشبکه
+	if cfg.LAN {
+		done := newDoneSignal()
+		handleShutdownSignals(done)
+		lan := &lanChat{
+			name:     cfg.Name,
+			id:       id,
+			keys:     keys,
+			ignores:  ignores,
+			inbound:  inbound,
+			outbound: outbound,
+			history:  hist,
+			theme:    themes,
+			mention:  notify.mention,
+			links:    hyperlinks,
+			status:   themes.system(stdout),
+		}
+		if err := lan.run(done); err != nil {
+			fmt.Fprintln(stdout, "LAN error:", err)
+			return exitFailed
+		}
+		return 0
+	}
+
+	// Daemon: terminals attach over a local socket | daemon: ترمینال‌ها از طریق socket محلی وصل می‌شوند
+	var daemonInput <-chan string
+	if cfg.Daemon {
+		input, stop, err := startDaemon(cfg.Socket)
+		if err != nil {
+			fmt.Fprintln(stdout, "Daemon error:", err)
+			return exitFailed
+		}
+		defer stop()
+		daemonInput = input
+	}
+
+	// Health/readiness endpoints for orchestrators | endpointهای سلامت برای ابزارهای مدیریت سرویس
+	var ready atomic.Bool
+	stats := newMetrics()
+	if cfg.HTTP != "" {
+		stopWeb, err := startWebServer(cfg.HTTP, cfg.HTTPToken, &ready, stats)
+		if err != nil {
+			fmt.Fprintln(stdout, "HTTP error:", err)
+			return exitFailed
+		}
+		defer stopWeb()
+	}
+
+	// In pipe mode stdout carries NDJSON only, status goes to stderr | در حالت pipe وضعیت روی stderr چاپ می‌شود
+	pipe := !cfg.Daemon && (sendText != "" || stdinIsPipe())
+	status := statusWriter(pipe || jsonOutput) // Stdout is for machines in both | در هر دو stdout برای ماشین است
+	if pipe && !jsonOutput {
+		setPipeOutput() // Stray prints go to stderr too | چاپ‌های پراکنده هم به stderr می‌روند
+	}
+	auth.resume.status = status
+	if !pipe && !cfg.Daemon && !reconnect && connectTo == "" && cfg.Dial == remoteDialAddr {
+		cfg.Dial = buddies.offerLast(cfg.Dial) // Only when no peer was chosen | فقط وقتی peerی انتخاب نشده
+	}
+
+	// Printed output also feeds crash dumps; none of it is kept when anonymous | خروجی برای گزارش خرابی هم ضبط می‌شود؛ در حالت ناشناس هیچ
+	if !cfg.Anon {
+		stdout.capture(recentOutput)
+		defer stdout.capture(nil)
+	}
+
 	// Startup logs | پیام‌های شروع برنامه
-	fmt.Println("PeerA starting...")
-	fmt.Println("Local listen:", localListenAddr)
-	fmt.Println("Remote dial :", remoteDialAddr)
-	fmt.Println("Type and press Enter to send. Ctrl+C to exit.")
+	fmt.Fprintln(status, "PeerA", version, "starting...")
+	if cfg.Transport == transportSerial {
+		fmt.Fprintf(status, "Serial line : %s at %d baud\n", cfg.Device, cfg.Baud) // Nothing is listened on or dialed | نه listen و نه dial
+	} else {
+		if sendText == "" {
+			fmt.Fprintln(status, "Local listen:", cfg.Listen)
+		}
+		fmt.Fprintln(status, "Remote dial :", cfg.Dial)
+	}
+	fmt.Fprintln(status, "Identity    :", id.fingerprint)
+	fmt.Fprintln(status, "Key hash    : sha256:"+keyDigest(id.publicKey())) // What the other side can pin | چیزی که طرف مقابل می‌تواند pin کند
+	if auth.pin != "" {
+		fmt.Fprintln(status, "Pinned key  : sha256:"+auth.pin)
+	}
+	if cfg.Anon {
+		fmt.Fprintln(status, "Anonymous   :", cfg.Name, "(nothing is saved)")
+	}
+	printLastSeen(status, seen) // Who was around recently | چه کسی اخیراً در دسترس بوده
+	oplog.logf(priInfo, "Starting version %s, key %s", version, id.fingerprint)
+	if !pipe {
+		fmt.Fprintln(status, "Type and press Enter to send. Ctrl+C to exit.")
+	}
+	if cfg.CheckUpdate {
+		go checkForUpdate(status) // Runs in the background | در پس‌زمینه اجرا می‌شود
+	}
 
 	/*
 		Channels definition
 
 		outgoing: messages typed by user (to be sent)
 		incoming: messages received from TCP
-		done:     signal channel for shutdown
+		quit:     shutdown signal of the run
+		done:     end of the current link
 
 		تعریف کانال‌ها:
 		- outgoing: پیام‌های خروجی کاربر
 		- incoming: پیام‌های دریافتی از شبکه
-		- done: اعلام پایان و قطع اتصال
+		- quit: سیگنال خروج از برنامه
+		- done: پایان اتصال فعلی
 	*/
 	outgoing := make(chan string, 32)
-	incoming := make(chan string, 32)
-	done := make(chan struct{})
-
-	// Start TCP listener | شروع گوش‌دادن روی TCP
-	ln, err := net.Listen("tcp", localListenAddr)
-	if err != nil {
-		fmt.Println("Listen error:", err)
-		return
+	incoming := make(chan message, 32)
+	quit := newDoneSignal()
+	watchQueue(stats, "outgoing", "Chat lines", outgoing)        // Backpressure towards the peer | فشار برگشتی به سمت peer
+	watchQueue(stats, "incoming", "Received messages", incoming) // Backlog of the display loop | صف حلقه‌ی نمایش
+	handleShutdownSignals(quit)                                  // Ctrl+C / SIGTERM shut down cleanly | خروج امن با Ctrl+C یا SIGTERM
+	if sendText != "" {
+		time.AfterFunc(*sendTimeout, func() {
+			fmt.Fprintln(status, "Send error: not delivered within", *sendTimeout)
+			quit.close()
+		})
 	}
-	defer ln.Close() // Ensure listener is closed on exit | بستن listener هنگام خروج
-
-	/*
-		acceptCh is used to receive an incoming connection asynchronously
 
-		کانالی برای دریافت اتصال ورودی به‌صورت همزمان
-	*/
-	acceptCh := make(chan net.Conn, 1)
-	go acceptOnce(ln, acceptCh) // Run accept in a goroutine | اجرای Accept در goroutine
+	var ln net.Listener // None for a one-shot send | برای ارسال یک‌باره وجود ندارد
+	if sendText == "" { // A one-shot send only dials | ارسال یک‌باره فقط dial می‌کند
+		// Start listening on the transport, unless systemd handed us a socket | شروع گوش‌دادن روی انتقال، مگر اینکه systemd socket را داده باشد
+		ln, err = activationListener()
+		if ln == nil && err == nil {
+			ln, err = tr.listen(cfg.Listen)
+		} else if ln != nil {
+			fmt.Fprintln(status, "Socket activation:", ln.Addr())
+		}
+		if err != nil {
+			fmt.Fprintln(status, "Listen error:", err)
+			oplog.logf(priErr, "Listen error: %v", err)
+			return exitFailed
+		}
+		defer ln.Close() // Ensure listener is closed on exit | بستن listener هنگام خروج
+	}
 
 	/*
 		Establish connection:
@@ -73,89 +499,421 @@ func main() {
 		- یا اتصال ورودی را می‌پذیرد
 		- یا به peer مقابل وصل می‌شود
 	*/
-	conn := establishConn(acceptCh, remoteDialAddr)
-	if conn == nil {
-		fmt.Println("Failed to establish connection.")
-		return
+	sockOpts := socketOptions{
+		keepAlive: cfg.KeepAlive,
+		noDelay:   cfg.NoDelay,
+		sendBuf:   cfg.SendBuf,
+		recvBuf:   cfg.RecvBuf,
+		linger:    cfg.Linger,
 	}
-	defer conn.Close() // Close connection on exit | بستن اتصال هنگام خروج
 
-	fmt.Println("Connected to:", conn.RemoteAddr())
+	// Throttle flooding senders ahead of the other filters | محدودکردن اسپم پیش از فیلترهای دیگر
+	if cfg.SpamRate > 0 || cfg.SpamRepeat > 0 {
+		spam := newSpamFilter(cfg.SpamRate, cfg.SpamRepeat, cfg.SpamCooldown, func(note string) {
+			fmt.Fprintln(status, note)
+		})
+		inbound = append(filterChain{spam}, inbound...)
+	}
+	mutes := newMuteFilter()
+	inbound = append(filterChain{mutes}, inbound...) // Muted senders never reach the others | پیام ساکت‌شده‌ها به بقیه نمی‌رسد
 
-	// Start concurrent I/O goroutines | شروع goroutineهای ورودی/خروجی
-	go stdinReader(outgoing, done)      // Read user input | خواندن ورودی کاربر
-	go connWriter(conn, outgoing, done) // Write to TCP | ارسال پیام روی TCP
-	go connReader(conn, incoming, done) // Read from TCP | دریافت پیام از TCP
+	var live atomic.Pointer[session] // Session of the current link, what typed input acts on | نشست اتصال فعلی که ورودی تایپ‌شده روی آن اجرا می‌شود
+	var con *console                 // Line editor, started with the first link | ویرایشگر خط، با اولین اتصال
+	defer func() {
+		if s := live.Load(); s != nil {
+			_ = s.mux.Close()  // Close all streams on exit | بستن همه‌ی streamها هنگام خروج
+			_ = s.conn.Close() // Close connection on exit | بستن اتصال هنگام خروج
+		}
+	}()
+	draftPath := stateFile(cfg.Anon, defaultDraftPath(cfg.Name))
+	redial := sendText == "" && !pipe && gen == nil // Only typed input outlives a link | فقط ورودی تایپ‌شده از یک اتصال بیشتر عمر می‌کند
 
 	/*
-		Main event loop:
-		- Prints incoming messages
-		- Exits on done signal
+		Link loop: each pass establishes one link and serves it until it
+		ends. A link the watchdog tore down is dialed again; any other
+		end of the link ends the run.
 
-		حلقه اصلی:
-		- نمایش پیام‌های دریافتی
-		- خروج امن در صورت بسته‌شدن اتصال
+		حلقه‌ی اتصال: هر دور یک اتصال برقرار و تا پایان آن سرویس می‌دهد.
+		اتصالی که watchdog بسته باشد دوباره dial می‌شود؛ هر پایان دیگری
+		اجرا را تمام می‌کند
 	*/
+links:
 	for {
-		select {
-		case msg := <-incoming:
-			fmt.Println(msg)
-		case <-done:
-			fmt.Println("Connection closed. Bye.")
-			return
+		done := newDoneSignal() // Ends this link | پایان این اتصال
+		done.follow(quit)       // Shutting down ends it too | خروج آن را هم پایان می‌دهد
+		first := live.Load() == nil
+		conn := establishConn(tr, ln, cfg.Dial, auth, sockOpts, status, quit.c)
+		if conn == nil && first {
+			fmt.Fprintln(status, "Failed to establish connection.")
+			oplog.logf(priErr, "Failed to establish a connection")
+			return exitFailed
+		}
+		if conn == nil {
+			break links // Shut down while redialing | خروج در حین dial دوباره
+		}
+
+		fmt.Fprintln(status, "Connected to:", conn.RemoteAddr())
+		oplog.logf(priNotice, "Connected to: %s, key %q, resumed %t", conn.RemoteAddr(), conn.remoteKey, conn.resumed)
+		emitEvent(outputEvent{Event: eventConnected, Remote: conn.RemoteAddr().String(), Key: conn.remoteKey})
+		if conn.resumed {
+			fmt.Fprintln(status, "Resumed: admitted on its ticket from the last link")
+		}
+		dialedAddr := ""
+		if conn.dialed {
+			dialedAddr = cfg.Dial // Accepted links come from an ephemeral port | اتصال ورودی از پورت موقت می‌آید
+		}
+		if e, ok := buddies.connected(conn.remoteKey, dialedAddr); ok {
+			fmt.Fprintf(status, "Roster: this is %s's key%s\n", e.Nick, noteSuffix(e.Notes))
 		}
+		buddies.remember(dialedAddr, conn.RemoteAddr().String(), conn.remoteKey)
+		warnVersionMismatch(status, conn) // Compare protocol versions | مقایسه نسخه‌های پروتکل
+
+		/*
+			Multiplex the link:
+			- our chat text goes out on a stream we open
+			- the remote's chat text arrives on a stream it opens
+
+			چندگانه‌سازی اتصال:
+			- پیام‌های ما روی streamی که خودمان باز می‌کنیم ارسال می‌شوند
+			- پیام‌های طرف مقابل روی stream باز‌شده توسط او دریافت می‌شوند
+		*/
+		sess, err := newMuxSession(conn)
+		if err != nil {
+			_ = conn.Close()
+			fmt.Fprintln(status, "Mux error:", err)
+			oplog.logf(priErr, "Mux error: %v", err)
+			return exitFailed
+		}
+
+		chatOut, err := openStream(sess, streamChat)
+		if err != nil {
+			_ = sess.Close()
+			fmt.Fprintln(status, "Stream error:", err)
+			oplog.logf(priErr, "Stream error: %v", err)
+			return exitFailed
+		}
+
+		// Control frames get their own stream, never mixed with chat | فریم‌های کنترلی stream جداگانه دارند
+		ctrl := newControlLink(done)
+		ctrlOut, err := openStream(sess, streamControl)
+		if err != nil {
+			_ = sess.Close()
+			fmt.Fprintln(status, "Stream error:", err)
+			oplog.logf(priErr, "Stream error: %v", err)
+			return exitFailed
+		}
+
+		// Shared state for commands and stream handlers | وضعیت مشترک دستورها و handlerها
+		var s *session
+		if first {
+			// Line editor when attached to a real terminal | ویرایشگر خط روی ترمینال واقعی
+			if !cfg.Daemon && !pipe && !jsonOutput {
+				con, err = startConsole()
+				if err != nil {
+					_ = sess.Close()
+					fmt.Fprintln(status, "Console error:", err)
+					return exitFailed
+				}
+				defer con.stop()
+				if con != nil {
+					con.term.History = inputs // Arrow keys recall earlier runs | کلیدهای جهت اجراهای قبلی را بازمی‌گردانند
+
+					// Colours on a terminal only, the prompt in the status colour | رنگ فقط روی ترمینال، خط ورودی با رنگ وضعیت
+					themes.attach(func(t theme) { con.term.SetPrompt(paint(t.Status, consolePrompt)) })
+					status = themes.system(status)
+				}
+			}
+
+			s = &session{
+				name:     cfg.Name,
+				conn:     conn,
+				mux:      sess,
+				ctrl:     ctrl,
+				files:    newFileStore(cfg.Downloads, int64(cfg.MaxFile)<<20, int64(cfg.DownloadQuota)<<20),
+				accepts:  parseAccept(cfg.AcceptFiles),
+				parts:    newPartTable(),
+				history:  hist,
+				threads:  newThreadIndex(),
+				notify:   notify,
+				presence: newPresence(cfg.AwayReply),
+				seen:     seen,
+				inputs:   inputs,
+				aliases:  newAliasTable(cfg.Aliases, cfg.File),
+				keymap:   keymap,
+				theme:    themes,
+				roster:   buddies,
+				metrics:  stats,
+				acks:     newAckTracker(stats),
+				sched:    newLinkScheduler(),
+				id:       id,
+				keys:     keys,
+				ignores:  ignores,
+				auth:     auth,
+				mutes:    mutes,
+				inbound:  inbound,
+				outbound: outbound,
+				incoming: incoming,
+				outgoing: outgoing,
+				status:   status,
+				done:     done,
+			}
+			stats.add("messages_sent_total", "counter", "Chat lines flushed to the wire.", func() float64 { return float64(live.Load().sent.Load()) })
+		} else {
+			s = live.Load().relinked(conn, sess, ctrl, done)
+		}
+		s.framesOut.Store(conn.sentFrames) // Numbering carries on after a resume | شماره‌گذاری پس از ازسرگیری ادامه می‌یابد
+		s.framesIn.Store(conn.seenFrames)
+		live.Store(s)
+		crashSession.Store(s)
+		handleAckFrames(s)                                       // Delivery latency | تأخیر تحویل
+		handleOpsFrames(s)                                       // Render kicks and mutes from the remote | نمایش kick و mute طرف مقابل
+		handleLoginFrames(s)                                     // Nick registration | ثبت نام‌ها
+		handlePresenceFrames(s)                                  // Remote away/online | وضعیت حضور طرف مقابل
+		s.ctrl.send(controlFrame{Type: ctrlLogin, Text: s.name}) // Claim our nick | ادعای نام ما
+
+		// Input outlives the link, so it starts only once | ورودی از اتصال بیشتر عمر می‌کند، پس فقط یک‌بار شروع می‌شود
+		if first {
+			if con != nil {
+				con.term.AutoCompleteCallback = editorKeys(&live, s.keymap) // Tab completion and bound keys | تکمیل با Tab و کلیدهای میانبر
+			}
+			if err := restoreDraft(draftPath, s, con); err != nil {
+				fmt.Fprintln(status, "Draft error:", err)
+			}
+
+			// Start concurrent I/O goroutines | شروع goroutineهای ورودی/خروجی
+			switch {
+			case cfg.Daemon:
+				goSafe("daemonReader", quit, func() { daemonReader(&live, daemonInput, quit.c) }) // Read attached terminals | خواندن ترمینال‌های متصل
+			case sendText != "":
+				sendOnce(s, sendText, &sendCode) // Exits once acknowledged | پس از تأیید خارج می‌شود
+			case pipe:
+				goSafe("pipeReader", done, func() { pipeReader(s, cfg.Wait, cfg.Stream) }) // Send piped input, then exit | ارسال ورودی pipe و خروج
+			case con != nil:
+				if cfg.Idle > 0 {
+					trackIdle(&live, con, cfg.Idle, quit.c) // Presence follows the keyboard | وضعیت حضور بر اساس صفحه‌کلید
+				}
+				goSafe("consoleReader", quit, func() { consoleReader(&live, con, quit) }) // Line editor input | ورودی ویرایشگر خط
+			default:
+				goSafe("stdinReader", quit, func() { stdinReader(&live, quit.c) }) // Read user input | خواندن ورودی کاربر
+			}
+			if gen != nil {
+				goSafe("gen.run", done, func() { gen.run(s) }) // Synthetic soak-test traffic | ترافیک ساختگی برای آزمون طولانی
+			}
+		}
+		shaper := newTrafficShaper(cfg.Padding, s.conn.caps) // Nil unless padding is on and understood | nil مگر padding روشن و پشتیبانی‌شده باشد
+		if cfg.Padding && shaper == nil {
+			fmt.Fprintln(status, "Padding: off, the remote does not support it")
+		}
+		goSafe("connWriter", done, func() {
+			connWriter(s.sched.wrap(chatOut, prioChat), outgoing, &s.sent, &s.taken, &s.framesOut, shaper, done)
+		}) // Write to chat stream | ارسال پیام روی stream چت
+		goSafe("watchWriter", done, func() { watchWriter(s, &s.taken) })                                // Catch a wedged writer | تشخیص نویسنده‌ی قفل‌شده
+		goSafe("ctrl.writer", done, func() { ctrl.writer(s.sched.wrap(ctrlOut, prioControl), shaper) }) // Write control frames | ارسال فریم‌های کنترلی
+		goSafe("ctrl.heartbeat", done, ctrl.heartbeat)                                                  // Detect a silent peer | تشخیص peer ساکت
+		handlers := map[string]func(net.Conn){
+			streamChat:     func(st net.Conn) { connReader(st, incoming, s.keys, s.metrics, &s.framesIn, done) }, // Read from chat stream | دریافت پیام از stream چت
+			streamControl:  ctrl.reader,                                                                          // Read control frames | دریافت فریم‌های کنترلی
+			streamFile:     func(st net.Conn) { receiveFile(s, st) },                                             // Receive a file transfer | دریافت انتقال فایل
+			streamFilePart: func(st net.Conn) { receiveFilePart(s, st) },                                         // Another part of a large one | بخش دیگری از انتقال بزرگ
+			streamSync:     func(st net.Conn) { receiveSync(s, st) },                                             // A /syncdir manifest | manifest یک /syncdir
+		}
+		goSafe("acceptStreams", done, func() { acceptStreams(sess, handlers, done) })
+
+		ready.Store(true) // Link is up | اتصال برقرار است
+
+		/*
+			Main event loop:
+			- Prints incoming messages
+			- Redials when the link was torn down to be redialed
+			- Exits when the link ends otherwise
+
+			حلقه اصلی:
+			- نمایش پیام‌های دریافتی
+			- dial دوباره وقتی اتصال برای همین بسته شده
+			- خروج امن در صورت بسته‌شدن اتصال به هر دلیل دیگر
+		*/
+		for {
+			select {
+			case msg := <-incoming:
+				acknowledge(s, msg) // Delivered, whether shown or not | تحویل شد، چه نمایش داده شود چه نه
+				if msg.Cover {
+					continue // Cover traffic carries nothing | ترافیک پوششی چیزی ندارد
+				}
+				if msg.Lost > 0 {
+					fmt.Fprintf(status, "Warning: %d message(s) may have been lost\n", msg.Lost) // Gap in the remote's numbering | شکاف در شماره‌گذاری طرف مقابل
+				}
+				if s.ignores.has(msg.From, msg.Key) {
+					s.metrics.drop(dropIgnored)
+					continue // Dropped before display | حذف پیش از نمایش
+				}
+				if !s.inbound.apply(&msg) {
+					s.metrics.drop(dropFiltered)
+					continue // Word filter dropped it | فیلتر کلمات آن را حذف کرد
+				}
+				if len(msg.Image) > 0 {
+					storeImage(s, &msg) // Saved like a received file | مانند فایل دریافتی ذخیره می‌شود
+				}
+				s.history.add(msg)  // Kept for export | ذخیره برای خروجی گرفتن
+				events.publish(msg) // To /events subscribers | برای مشترکان /events
+				if msg.Verified {
+					s.seen.touch(msg.From, false) // Saved on the next login or disconnect | در ورود یا قطع بعدی ذخیره می‌شود
+				}
+				if jsonOutput {
+					emitEvent(outputEvent{Event: eventMessage, Message: &msg})
+				} else if pipe {
+					writeNDJSON(msg) // One JSON object per line | یک شیء JSON در هر خط
+				} else {
+					if ctx := s.threads.replyContext(msg); ctx != "" {
+						fmt.Fprintln(stdout, ctx) // What this replies to | پیامی که به آن پاسخ داده شده
+					}
+					shown := s.roster.relabel(msg) // Under the sender's display alias | با نام نمایشی فرستنده
+					line := s.theme.remote(displayMessage(shown), s.notify.mention)
+					if hyperlinks {
+						line = linkify(line) // Clickable URLs | لینک‌های قابل کلیک
+					}
+					fmt.Fprintln(stdout, line)
+					if !msg.continued() { // Alerts and replies go with the start | هشدار و پاسخ فقط همراه start
+						reply := s.notify.alert(shown) // Do not disturb auto-reply | پاسخ خودکار حالت DND
+						if reply == "" {
+							reply = s.presence.autoReply(msg) // Away auto-reply | پاسخ خودکار حالت away
+						}
+						if reply != "" {
+							sendAutoReply(s, msg, reply)
+						}
+					}
+					if cfg.LinkPreviews {
+						go showPreviews(msg.Text) // Printed when fetched | پس از دریافت چاپ می‌شود
+					}
+				}
+				if !msg.continued() {
+					s.threads.add(msg) // Replies quote the start | پاسخ‌ها از start نقل می‌کنند
+				}
+			case <-done.c:
+				ready.Store(false)
+				s.seen.touch(s.presence.peerName(), true)                                     // Connected until now | تا این لحظه متصل بود
+				s.auth.resume.closed(s.conn.remoteKey, s.framesOut.Load(), s.framesIn.Load()) // Tickets count from now | مهلت ticketها از اکنون
+				emitEvent(outputEvent{Event: eventDisconnected, Remote: s.conn.RemoteAddr().String(), Key: s.conn.remoteKey})
+				if !done.redialing() {
+					break links
+				}
+				if !redial {
+					code = exitFailed // Piped or generated input cannot follow onto a new link | ورودی pipe یا ساختگی به اتصال تازه منتقل نمی‌شود
+					break links
+				}
+				_ = sess.Close()
+				_ = conn.Close()
+				fmt.Fprintln(status, "Link torn down; redialing", cfg.Dial)
+				oplog.logf(priWarning, "Link to %s torn down; redialing %s", conn.RemoteAddr(), cfg.Dial)
+				continue links
+			}
+		}
+	}
+
+	s := live.Load()
+	gen.report(status) // Load summary, if generating | گزارش بار ساختگی
+	if err := saveDraft(draftPath, s, con); err != nil {
+		fmt.Fprintln(status, "Draft error:", err)
 	}
+	fmt.Fprintln(status, "Connection closed. Bye.")
+	oplog.logf(priNotice, "Connection closed: %s, %d messages sent", s.conn.RemoteAddr(), s.sent.Load())
+	return code
 }
 
 /*
-acceptOnce waits for a single incoming TCP connection
-and sends it into acceptCh.
+acceptOnce waits for a single incoming connection and sends it into
+acceptCh, or closes it when stop was closed first because a link was
+already chosen.
 
-این تابع منتظر یک اتصال TCP ورودی می‌ماند
-و آن را داخل کانال acceptCh ارسال می‌کند
+این تابع منتظر یک اتصال ورودی می‌ماند و آن را داخل کانال acceptCh
+ارسال می‌کند، یا اگر stop زودتر بسته شده باشد (اتصال انتخاب شده) آن را
+می‌بندد
 */
-func acceptOnce(ln net.Listener, acceptCh chan<- net.Conn) {
+func acceptOnce(ln net.Listener, acceptCh chan<- net.Conn, stop <-chan struct{}) {
 	conn, err := ln.Accept() // Block until a connection arrives | انتظار برای اتصال
 	if err != nil {
 		return
 	}
-	acceptCh <- conn // Send accepted connection | ارسال اتصال پذیرفته‌شده
+	select {
+	case acceptCh <- conn: // Send accepted connection | ارسال اتصال پذیرفته‌شده
+	case <-stop:
+		_ = conn.Close() // Too late | دیر رسید
+	}
 }
 
 /*
 establishConn races between:
 - accepting an incoming connection
-- dialing the remote peer
+- dialing the remote peer over tr
+Every candidate goes through the handshake, which keeps exactly one link
+even when both peers dial each other at the same moment. The listener
+keeps accepting while a candidate handshakes, so one that stalls, fails
+or is refused cannot keep the real peer out. ln is nil when we only dial.
+Refusals are reported on status.
 
 این تابع بین دو حالت رقابت ایجاد می‌کند:
 - دریافت اتصال ورودی
-- تلاش برای اتصال به peer مقابل
+- تلاش برای اتصال به peer مقابل از طریق tr
+هر اتصال کاندید از handshake عبور می‌کند تا دقیقاً یک اتصال باقی بماند،
+حتی وقتی هر دو peer همزمان به هم وصل می‌شوند؛ listener در حین handshake
+یک کاندید به پذیرش ادامه می‌دهد تا کاندیدی که گیر کند، شکست بخورد یا رد
+شود نتواند peer واقعی را بیرون نگه دارد. اگر فقط dial کنیم ln برابر nil است.
+ردشدن‌ها روی status اعلام می‌شوند
 */
-func establishConn(acceptCh <-chan net.Conn, remote string) net.Conn {
+func establishConn(tr transport, ln net.Listener, remote string, auth *peerAuth, opts socketOptions, status io.Writer, done <-chan struct{}) *handshakeConn {
+	var claimed atomic.Bool                   // Set once the arbiter keeps a link | پس از انتخاب اتصال توسط داور
+	dialCh := make(chan net.Conn, 1)          // Successful dials | اتصال‌های موفق dial
+	results := make(chan handshakeResult, 4)  // Handshake outcomes | نتایج handshake
+	stopDial := make(chan struct{})           // Stops dialing once linked | توقف dial پس از اتصال
+	go dialLoop(tr, remote, dialCh, stopDial) // Try dialing remote peer | تلاش برای اتصال به peer مقابل
+	acceptCh := make(chan net.Conn, 1)        // Accepted candidates | کاندیدهای پذیرفته‌شده
+	if ln != nil {
+		go acceptOnce(ln, acceptCh, stopDial)
+	}
+
 	for {
 		select {
 		case c := <-acceptCh:
-			// Incoming connection wins | اتصال ورودی برنده می‌شود
-			return c
-		default:
-			// Try dialing remote peer | تلاش برای اتصال به peer مقابل
-			c, err := net.Dial("tcp", remote)
-			if err == nil {
-				return c
+			tuneSocket(c, opts)
+			go runHandshake(c, false, &claimed, auth, results) // Incoming candidate | کاندید ورودی
+			go acceptOnce(ln, acceptCh, stopDial)              // The next one may come while this handshakes or fails | کاندید بعدی در حین handshake یا شکست این یکی
+		case c := <-dialCh:
+			tuneSocket(c, opts)
+			go runHandshake(c, true, &claimed, auth, results) // Dialed candidate | کاندید خروجی
+		case <-done:
+			close(stopDial) // Shutdown requested | درخواست خروج
+			return nil
+		case r := <-results:
+			if r.err == nil {
+				close(stopDial)
+				go discardLate(acceptCh, dialCh, results) // Close stray candidates | بستن کاندیدهای اضافه
+				return r.conn
+			}
+			var refused *refusedError
+			if errors.As(r.err, &refused) && r.dialed {
+				fmt.Fprintln(status, "Remote refused the connection:", refused.reason) // Not admitted there | آنجا پذیرفته نشدیم
+				oplog.logf(priWarning, "The remote refused the connection")            // Its reason is the remote's text | دلیل آن متن طرف مقابل است
+				continue
+			}
+			if errors.Is(r.err, errDenied) && r.dialed {
+				fmt.Fprintln(status, "Refused the dialed peer:", r.err) // e.g. not the key of an invitation | مثلاً کلید دعوت نیست
+				oplog.logf(priWarning, "Refused the dialed peer: %v", r.err)
+				continue
+			}
+			if r.dialed && r.err != errDropped && r.err != errSelfConnect {
+				go dialLoop(tr, remote, dialCh, stopDial) // Dialed link failed, retry | اتصال ناموفق، تلاش مجدد
 			}
-			time.Sleep(dialRetryEvery) // Wait before retry | صبر قبل از تلاش مجدد
 		}
 	}
 }
 
 /*
 stdinReader reads user input from terminal
-and sends it to outgoing channel.
+and hands it to the session of the current link.
 
 این تابع ورودی کاربر را از ترمینال می‌خواند
-و داخل کانال outgoing قرار می‌دهد
+و به نشست اتصال فعلی می‌سپارد
 */
-func stdinReader(outgoing chan<- string, done <-chan struct{}) {
+func stdinReader(live *atomic.Pointer[session], done <-chan struct{}) {
 	sc := bufio.NewScanner(os.Stdin)
 	for {
 		select {
@@ -167,39 +925,81 @@ func stdinReader(outgoing chan<- string, done <-chan struct{}) {
 		if !sc.Scan() {
 			return // End of input | پایان ورودی
 		}
-		line := strings.TrimSpace(sc.Text()) // Remove extra spaces | حذف فاصله‌های اضافی
-		if line == "" {
-			continue // Ignore empty lines | نادیده گرفتن خطوط خالی
-		}
-		outgoing <- "A: " + line // Send message | ارسال پیام
+		handleInput(live.Load(), sc.Text())
+	}
+}
+
+/*
+handleInput sends one typed line as a chat message, or runs it as a
+local command when it starts with "/". While a multi-line capture such
+as /code is running, the line goes to it untrimmed.
+
+این تابع یک خط تایپ‌شده را به‌عنوان پیام ارسال می‌کند
+یا اگر با "/" شروع شود، آن را به‌عنوان دستور محلی اجرا می‌کند؛
+در حین ضبط چندخطی مانند /code خط بدون تغییر به آن می‌رسد
+*/
+func handleInput(s *session, line string) {
+	if s.compose.feed(line) {
+		return // Part of a capture, indentation kept | بخشی از ضبط، با حفظ تورفتگی
+	}
+	line = strings.TrimSpace(line) // Remove extra spaces | حذف فاصله‌های اضافی
+	if line == "" {
+		return // Ignore empty lines | نادیده گرفتن خطوط خالی
+	}
+	if strings.HasPrefix(line, "/") {
+		runCommand(s, line) // Local command, not sent | دستور محلی، ارسال نمی‌شود
+		return
+	}
+	if _, err := sendChat(s, line, nil, false); err != nil {
+		fmt.Fprintln(s.status, "Send error:", err)
 	}
 }
 
 /*
-connWriter writes messages from outgoing channel
-to the TCP connection.
+connWriter writes messages from outgoing channel to the TCP
+connection. A burst (piped input, bot traffic) is coalesced: while more
+messages are already queued they only fill the buffer, which goes out
+when it holds connBufferSize bytes or the queue runs dry. A lone typed
+message therefore still leaves at once. With a shaper, lines are padded
+and cover lines are mixed in.
 
-این تابع پیام‌ها را از outgoing گرفته
-و روی اتصال TCP می‌نویسد
+این تابع پیام‌ها را از outgoing گرفته و روی اتصال TCP می‌نویسد.
+پیام‌های پشت سر هم (ورودی pipe یا ربات) با هم ارسال می‌شوند: تا وقتی
+پیام دیگری در صف باشد فقط بافر پر می‌شود و با رسیدن به connBufferSize
+بایت یا خالی‌شدن صف ارسال می‌شود؛ پس پیام تکی تایپ‌شده بلافاصله می‌رود.
+با shaper خطوط پر می‌شوند و خطوط پوششی هم ارسال می‌شوند
 */
-func connWriter(conn net.Conn, outgoing <-chan string, done chan struct{}) {
-	w := bufio.NewWriter(conn)
+func connWriter(conn net.Conn, outgoing <-chan string, sent, taken *atomic.Int64, frames *atomic.Uint64, shaper *trafficShaper, done *doneSignal) {
+	w := bufio.NewWriterSize(conn, connBufferSize) // Full buffers flush on their own | بافر پر خودکار ارسال می‌شود
+	var unflushed int64                            // Lines written since the last flush | خطوط نوشته‌شده از آخرین ارسال
+	cover := shaper.nextCover()                    // Nil without a shaper | بدون shaper مقدار nil
 	for {
+		var msg string
 		select {
-		case <-done:
+		case <-done.c:
 			return // Stop on shutdown | توقف در صورت خروج
-		case msg := <-outgoing:
-			_ = conn.SetWriteDeadline(time.Now().Add(connWriteTimeout)) // Set write timeout | تنظیم تایم‌اوت
-			_, err := w.WriteString(msg + "\n")                         // Write message | نوشتن پیام
-			if err != nil {
-				closeDone(done)
-				return
-			}
-			if err := w.Flush(); err != nil { // Flush buffer | ارسال نهایی داده
-				closeDone(done)
-				return
-			}
+		case <-cover:
+			msg, cover = coverLine, shaper.nextCover() // Not counted as sent | جزو ارسال‌شده‌ها شمرده نمی‌شود
+		case msg = <-outgoing:
+			taken.Add(1) // Progress for the watchdog | پیشرفت برای watchdog
+			unflushed++
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(connWriteTimeout))   // Set write timeout | تنظیم تایم‌اوت
+		seq := frames.Add(1)                                          // Continues a resumed link | ادامه‌ی اتصال ازسرگرفته
+		_, err := w.WriteString(shaper.pad(withSeq(msg, seq)) + "\n") // Numbered in wire order | شماره‌گذاری به ترتیب ارسال
+		if err != nil {
+			done.close()
+			return
+		}
+		if len(outgoing) > 0 {
+			continue // More queued: keep batching | پیام‌های بیشتر در صف: ادامه‌ی تجمیع
+		}
+		if err = w.Flush(); err != nil { // Queue empty: send now | صف خالی: ارسال فوری
+			done.close()
+			return
 		}
+		sent.Add(unflushed) // Count delivered lines | شمارش پیام‌های ارسال‌شده
+		unflushed = 0
 	}
 }
 
@@ -210,24 +1010,83 @@ and sends them to incoming channel.
 این تابع پیام‌ها را از TCP می‌خواند
 و داخل incoming قرار می‌دهد
 */
-func connReader(conn net.Conn, incoming chan<- string, done chan struct{}) {
+func connReader(conn net.Conn, incoming chan<- message, keys *registry, stats *metrics, seen *atomic.Uint64, done *doneSignal) {
 	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 0, 4096), maxMessageSize) // Matches the announced limit | مطابق حد اعلام‌شده
+	next, lost := seen.Load()+1, uint64(0)           // Expected frame number, frames missing so far | شماره‌ی مورد انتظار و فریم‌های گم‌شده
 	for sc.Scan() {
-		incoming <- "RECV -> " + sc.Text() // Forward received message | ارسال پیام دریافتی
+		m, ok := decodeChatLine(sc.Text(), keys)
+		if m.Seq > next {
+			lost += m.Seq - next // Skipped numbers | شماره‌های جاافتاده
+			stats.lose(m.Seq - next)
+		}
+		if m.Seq >= next {
+			next = m.Seq + 1 // Older peers send no numbers | peerهای قدیمی شماره نمی‌فرستند
+			seen.Store(m.Seq)
+		}
+		if m.Cover {
+			incoming <- m // Acknowledged like a real line, then dropped | مانند خط واقعی تأیید و سپس حذف می‌شود
+			continue
+		}
+		stats.receive()
+		if !ok {
+			stats.drop(dropRejected) // Impersonation | جعل هویت
+			continue
+		}
+		m.Lost, lost = lost, 0 // Warned about with the next shown message | همراه پیام بعدی اعلام می‌شود
+		incoming <- m          // Forward received message | ارسال پیام دریافتی
 	}
-	closeDone(done) // Connection closed | قطع اتصال
+	done.close() // Connection closed | قطع اتصال
 }
 
 /*
-closeDone safely closes the done channel only once.
+doneSignal is a shutdown channel that any goroutine may close: close is
+guarded by a sync.Once, so racing closers (a reader at EOF, the
+heartbeat, a signal) never close it twice. Waiters receive from c. A
+link's signal closed by redial also records that the run should dial
+again rather than exit.
 
-این تابع کانال done را فقط یک‌بار و ایمن می‌بندد
+این نوع کانال خروجی است که هر goroutineی می‌تواند آن را ببندد: بستن با
+sync.Once محافظت می‌شود تا بستن‌های همزمان (خواننده در پایان اتصال، heartbeat،
+سیگنال) هرگز آن را دو بار نبندند. منتظرها از c دریافت می‌کنند. اگر سیگنال یک
+اتصال با redial بسته شود، ثبت می‌شود که اجرا باید به‌جای خروج دوباره dial کند
 */
-func closeDone(done chan struct{}) {
-	select {
-	case <-done:
-		// already closed | قبلاً بسته شده
-	default:
-		close(done)
-	}
+type doneSignal struct {
+	c    chan struct{} // Closed once on shutdown | یک‌بار هنگام خروج بسته می‌شود
+	once sync.Once     // Guards the close | محافظ بستن
+	redo atomic.Bool   // Closed by redial | با redial بسته شده
+}
+
+// newDoneSignal returns an open doneSignal | ساخت doneSignal باز
+func newDoneSignal() *doneSignal {
+	return &doneSignal{c: make(chan struct{})}
+}
+
+// close closes c the first time it is called | بستن c فقط در اولین فراخوانی
+func (d *doneSignal) close() {
+	d.once.Do(func() { close(d.c) })
+}
+
+// redial closes c, unless already closed, asking for a new link | بستن c با درخواست اتصال تازه، اگر قبلاً بسته نشده
+func (d *doneSignal) redial() {
+	d.once.Do(func() {
+		d.redo.Store(true)
+		close(d.c)
+	})
+}
+
+// redialing reports whether redial was what closed c | آیا c با redial بسته شده
+func (d *doneSignal) redialing() bool {
+	return d.redo.Load()
+}
+
+// follow closes d once parent is closed | بستن d همراه با parent
+func (d *doneSignal) follow(parent *doneSignal) {
+	go func() {
+		select {
+		case <-parent.c:
+			d.close()
+		case <-d.c:
+		}
+	}()
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"         // For the Prometheus text format and /stats
+	"io"          // For the metrics writer
+	"runtime"     // For the goroutine count
+	"slices"      // For finding a histogram bucket
+	"strconv"     // For printing values
+	"strings"     // For splitting labels off names
+	"sync"        // For guarding the metric list
+	"sync/atomic" // For the counters
+)
+
+const metricsPrefix = "peerchat_" // Prefix of every exported metric | پیشوند همه‌ی متریک‌ها
+
+/*
+Drop reasons
+
+دلایل حذف پیام:
+- rejected: امضا با کلید اشتباه برای نام ثبت‌شده
+- ignored: فرستنده در لیست ignore است
+- filtered: فیلتر کلمات، اسپم یا mute
+- blocked: پیام خروجی ما توسط فیلتر حذف شد
+*/
+const (
+	dropRejected = "rejected" // Signed by the wrong key for the nick | امضا با کلید اشتباه
+	dropIgnored  = "ignored"  // Sender is on the ignore list | فرستنده نادیده گرفته می‌شود
+	dropFiltered = "filtered" // Word filter, spam throttle or mute | فیلتر کلمات، اسپم یا mute
+	dropBlocked  = "blocked"  // Our own message blocked by the outbound filter | پیام خروجی ما حذف شد
+)
+
+// dropReasons lists every reason in /stats order | همه‌ی دلایل حذف به ترتیب نمایش
+var dropReasons = []string{dropRejected, dropIgnored, dropFiltered, dropBlocked}
+
+/*
+metrics keeps the process's self-metrics: counters the chat bumps as it
+runs plus gauges read on demand, such as queue depths. Both /stats and
+the Prometheus endpoint print the same list.
+
+این نوع متریک‌های خود برنامه را نگه می‌دارد: شمارنده‌هایی که چت هنگام
+اجرا افزایش می‌دهد و gaugeهایی که هنگام درخواست خوانده می‌شوند، مانند
+عمق صف‌ها. /stats و endpoint پرومتئوس همین فهرست را چاپ می‌کنند
+*/
+type metrics struct {
+	received atomic.Int64             // Chat lines read off the wire | خطوط چت دریافتی
+	lost     atomic.Int64             // Frames skipped in the remote's numbering | فریم‌های جاافتاده در شماره‌گذاری طرف مقابل
+	dropped  map[string]*atomic.Int64 // Messages dropped, by reason | پیام‌های حذف‌شده بر اساس دلیل
+
+	mu      sync.Mutex
+	entries []metric
+}
+
+// metric is one exported value | یک مقدار صادرشده
+type metric struct {
+	name   string         // Name without prefix, with labels if any | نام بدون پیشوند، همراه برچسب‌ها
+	family string         // Family for HELP/TYPE when it differs from name, e.g. for histogram series | نام خانواده برای سری‌های histogram
+	kind   string         // "counter", "gauge" or "histogram" | نوع متریک
+	help   string         // One-line description | توضیح یک‌خطی
+	value  func() float64 // Read when printed | هنگام چاپ خوانده می‌شود
+}
+
+// newMetrics creates the counters and process-wide gauges | ساخت شمارنده‌ها و gaugeهای سراسری
+func newMetrics() *metrics {
+	m := &metrics{dropped: make(map[string]*atomic.Int64)}
+	m.add("goroutines", "gauge", "Live goroutines.", func() float64 { return float64(runtime.NumGoroutine()) })
+	m.add("panics_recovered_total", "counter", "Panics in long-running goroutines turned into reports.", func() float64 { return float64(recoveredPanics.Load()) })
+	m.add("messages_received_total", "counter", "Chat lines read off the wire.", func() float64 { return float64(m.received.Load()) })
+	m.add("messages_lost_total", "counter", "Chat frames missing from the remote's sequence numbers.", func() float64 { return float64(m.lost.Load()) })
+	for _, reason := range dropReasons {
+		n := new(atomic.Int64)
+		m.dropped[reason] = n
+		m.add(`messages_dropped_total{reason="`+reason+`"}`, "counter", "Messages dropped before display or sending.", func() float64 { return float64(n.Load()) })
+	}
+	transfers.addMetrics(m)
+	bandwidth.addMetrics(m)
+	return m
+}
+
+// add registers a metric | ثبت یک متریک
+func (m *metrics) add(name, kind, help string, value func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, metric{name: name, kind: kind, help: help, value: value})
+}
+
+/*
+addHistogram registers the _bucket, _sum and _count series of h under
+one histogram family.
+
+این تابع سری‌های _bucket، _sum و _count مربوط به h را زیر یک خانواده‌ی
+histogram ثبت می‌کند
+*/
+func (m *metrics) addHistogram(name, help string, h *histogram) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range len(h.bounds) + 1 {
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = formatMetric(h.bounds[i])
+		}
+		m.entries = append(m.entries, metric{name: name + `_bucket{le="` + le + `"}`, family: name, kind: "histogram", help: help,
+			value: func() float64 { return float64(h.cumulative(i)) }})
+	}
+	m.entries = append(m.entries,
+		metric{name: name + "_sum", family: name, kind: "histogram", help: help, value: func() float64 { return h.total() }},
+		metric{name: name + "_count", family: name, kind: "histogram", help: help, value: func() float64 { return float64(h.cumulative(len(h.bounds))) }})
+}
+
+// watchQueue exports the depth and capacity of a channel | صادرکردن عمق و ظرفیت یک کانال
+func watchQueue[T any](m *metrics, name, what string, ch chan T) {
+	m.add(name+"_queue_depth", "gauge", what+" waiting in the queue.", func() float64 { return float64(len(ch)) })
+	m.add(name+"_queue_capacity", "gauge", "Size of the "+name+" queue.", func() float64 { return float64(cap(ch)) })
+}
+
+// receive counts one chat line read; nil-safe | شمارش یک خط دریافتی
+func (m *metrics) receive() {
+	if m != nil {
+		m.received.Add(1)
+	}
+}
+
+// lose counts frames missing before a received one; nil-safe | شمارش فریم‌های گم‌شده
+func (m *metrics) lose(n uint64) {
+	if m != nil {
+		m.lost.Add(int64(n))
+	}
+}
+
+// drop counts one dropped message; nil-safe | شمارش یک پیام حذف‌شده
+func (m *metrics) drop(reason string) {
+	if m != nil {
+		m.dropped[reason].Add(1)
+	}
+}
+
+// list returns a copy of the registered metrics | کپی فهرست متریک‌ها
+func (m *metrics) list() []metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]metric(nil), m.entries...)
+}
+
+/*
+writePrometheus prints every metric in the Prometheus text format,
+with one HELP/TYPE header per metric family.
+
+این تابع همه‌ی متریک‌ها را در قالب متنی پرومتئوس چاپ می‌کند و برای
+هر خانواده‌ی متریک یک سرآیند HELP/TYPE می‌نویسد
+*/
+func (m *metrics) writePrometheus(w io.Writer) {
+	described := make(map[string]bool)
+	for _, e := range m.list() {
+		family, _, _ := strings.Cut(e.name, "{")
+		if e.family != "" {
+			family = e.family
+		}
+		if !described[family] {
+			described[family] = true
+			fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, family, e.help, metricsPrefix, family, e.kind)
+		}
+		fmt.Fprintf(w, "%s%s %s\n", metricsPrefix, e.name, formatMetric(e.value()))
+	}
+}
+
+// formatMetric prints whole numbers without an exponent | چاپ اعداد صحیح بدون نماد علمی
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func init() {
+	registerCommand("stats", "/stats  show queue depths, goroutines, message counters, bandwidth and transfers", statsCommand)
+}
+
+// statsCommand prints every metric with its current value, then bandwidth and the transfers in flight | چاپ همه‌ی متریک‌ها، پهنای باند و انتقال‌های در جریان
+func statsCommand(s *session, _ []string) {
+	for _, e := range s.metrics.list() {
+		fmt.Fprintf(stdout, "  %-44s %s\n", e.name, formatMetric(e.value()))
+	}
+	fmt.Fprintln(stdout, " ", bandwidth)
+	for _, p := range transfers.list() {
+		fmt.Fprintln(stdout, " ", p)
+	}
+}
+
+/*
+histogram counts observations into fixed buckets, Prometheus style:
+bounds are the upper limits and a final bucket takes everything above.
+
+این نوع مشاهدات را به سبک پرومتئوس در سطل‌های ثابت می‌شمارد: bounds
+حدهای بالا هستند و سطل آخر هر چیزی بیشتر از آن‌ها را می‌گیرد
+*/
+type histogram struct {
+	bounds []float64 // Ascending upper limits | حدهای بالای صعودی
+
+	mu     sync.Mutex
+	counts []int64 // Per bucket, not cumulative | تعداد هر سطل، غیرتجمعی
+	sum    float64
+}
+
+// newHistogram creates a histogram with the given upper limits | ساخت histogram با حدهای داده‌شده
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+// observe records one value | ثبت یک مقدار
+func (h *histogram) observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v) // First bound >= v | اولین حد بزرگ‌تر یا مساوی v
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += v
+}
+
+// cumulative returns the observations in buckets 0..i | تعداد مشاهدات سطل‌های ۰ تا i
+func (h *histogram) cumulative(i int) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var n int64
+	for _, c := range h.counts[:i+1] {
+		n += c
+	}
+	return n
+}
+
+// total returns the sum of all observations | مجموع همه‌ی مشاهدات
+func (h *histogram) total() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
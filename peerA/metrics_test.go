@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExport(t *testing.T) {
+	m := newMetrics()
+	queue := make(chan string, 8)
+	queue <- "a"
+	queue <- "b"
+	watchQueue(m, "outgoing", "Lines", queue)
+	m.receive()
+	m.receive()
+	m.lose(3)
+	m.drop(dropIgnored)
+	var nilMetrics *metrics
+	nilMetrics.drop(dropIgnored) // Sessions without metrics | نشست‌های بدون متریک
+
+	var prom bytes.Buffer
+	m.writePrometheus(&prom)
+	out := prom.String()
+	for _, want := range []string{
+		"peerchat_outgoing_queue_depth 2\n",
+		"peerchat_outgoing_queue_capacity 8\n",
+		"peerchat_messages_received_total 2\n",
+		"peerchat_messages_lost_total 3\n",
+		`peerchat_messages_dropped_total{reason="ignored"} 1` + "\n",
+		`peerchat_messages_dropped_total{reason="rejected"} 0` + "\n",
+		"# TYPE peerchat_goroutines gauge\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Prometheus output lacks %q", want)
+		}
+	}
+	if n := strings.Count(out, "# TYPE peerchat_messages_dropped_total counter\n"); n != 1 {
+		t.Errorf("dropped counters described %d times, want once per family", n)
+	}
+
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+	runCommand(&session{metrics: m}, "/stats")
+	if !strings.Contains(buf.String(), "outgoing_queue_depth") || !strings.Contains(buf.String(), `messages_dropped_total{reason="ignored"}`) {
+		t.Errorf("/stats:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors" // For stream header errors
+	"io"     // For discarding yamux's internal logs
+	"net"    // For the stream connection type
+	"time"   // For the stream header deadline
+
+	"github.com/hashicorp/yamux" // Stream multiplexer over the single TCP link
+)
+
+/*
+Stream kinds
+
+هر stream با یک خط سرآیند نوع خود را اعلام می‌کند:
+- chat: متن چت در یک جهت
+- control: فریم‌های کنترلی پروتکل در یک جهت
+- file: یک انتقال فایل (هر فایل stream جداگانه)
+- file-part: یک بخش دیگر از انتقال فایل بزرگ
+- sync: manifest یک /syncdir و پاسخ آن
+*/
+const (
+	streamChat     = "chat"      // One-directional chat text | متن چت (یک‌طرفه)
+	streamControl  = "control"   // One-directional protocol metadata | متادیتای پروتکل (یک‌طرفه)
+	streamFile     = "file"      // One file transfer per stream | یک انتقال فایل در هر stream
+	streamFilePart = "file-part" // Another part of a large file transfer | بخش دیگری از انتقال فایل بزرگ
+	streamSync     = "sync"      // A /syncdir manifest and its reply | manifest یک /syncdir و پاسخ آن
+)
+
+const streamHeaderTimeout = 5 * time.Second // Max wait for a stream's kind header | حداکثر انتظار برای سرآیند stream
+
+// coreStreams carry the session itself, so a panic in their handler ends it | streamهایی که خود نشست را حمل می‌کنند؛ panic در handler آن‌ها نشست را پایان می‌دهد
+var coreStreams = map[string]bool{streamChat: true, streamControl: true}
+
+var errBadStreamHeader = errors.New("malformed stream header") // Header line too long or missing | سرآیند stream نامعتبر
+
+/*
+newMuxSession layers a yamux session over the established link, so
+chat, control traffic and file transfers each get their own stream
+with independent flow control. The handshake arbiter acts as the
+yamux client, the other peer as the server.
+
+این تابع یک session از نوع yamux روی اتصال برقرارشده می‌سازد
+تا چت، پیام‌های کنترلی و انتقال فایل هر کدام stream و
+کنترل جریان مستقل داشته باشند. داور handshake نقش client را دارد
+*/
+func newMuxSession(conn *handshakeConn) (*yamux.Session, error) {
+	cfg := yamux.DefaultConfig()
+	cfg.LogOutput = io.Discard                    // Keep yamux logs off the chat terminal | لاگ yamux در ترمینال چاپ نشود
+	cfg.ConnectionWriteTimeout = connWriteTimeout // Same write timeout as chat | همان تایم‌اوت نوشتن چت
+
+	link := bandwidth.wrap(conn) // Counted for /stats | شمارش برای /stats
+	if conn.arbiter {
+		return yamux.Client(link, cfg)
+	}
+	return yamux.Server(link, cfg)
+}
+
+/*
+openStream opens a new stream and announces its kind with a header line.
+
+این تابع یک stream جدید باز می‌کند و نوع آن را در خط اول اعلام می‌کند
+*/
+func openStream(sess *yamux.Session, kind string) (net.Conn, error) {
+	st, err := sess.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := st.Write([]byte(kind + "\n")); err != nil {
+		_ = st.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+/*
+acceptStreams accepts streams opened by the remote peer and hands each
+one to the handler registered for its kind. When the session dies the
+done channel is closed.
+
+این تابع streamهای باز‌شده توسط peer مقابل را می‌پذیرد و هر کدام را
+به handler مربوط به نوعش می‌سپارد؛ با از بین رفتن session کانال done بسته می‌شود
+*/
+func acceptStreams(sess *yamux.Session, handlers map[string]func(net.Conn), done *doneSignal) {
+	for {
+		st, err := sess.AcceptStream()
+		if err != nil {
+			done.close() // Session closed | session بسته شد
+			return
+		}
+		goSafe("dispatchStream", done, func() { dispatchStream(st, handlers) }) // A panicking core handler ends the session, not the process | panic در handler اصلی فقط نشست را می‌بندد
+	}
+}
+
+/*
+dispatchStream reads the kind header of a stream and runs its handler;
+streams of unknown kind are closed. A panic in the handler of a stream
+that is not in coreStreams, such as a file transfer, is reported and
+closes only that stream, and the session carries on.
+
+این تابع سرآیند نوع stream را می‌خواند و handler آن را اجرا می‌کند؛
+streamهای ناشناخته بسته می‌شوند. panic در handler streamی که در coreStreams
+نیست، مانند انتقال فایل، گزارش می‌شود و فقط همان stream را می‌بندد و نشست
+ادامه می‌یابد
+*/
+func dispatchStream(st *yamux.Stream, handlers map[string]func(net.Conn)) {
+	_ = st.SetReadDeadline(time.Now().Add(streamHeaderTimeout))
+	kind, err := readHeaderLine(st)
+	_ = st.SetReadDeadline(time.Time{})
+	if err != nil {
+		_ = st.Close()
+		return
+	}
+
+	handler, ok := handlers[kind]
+	if !ok {
+		_ = st.Close() // Unknown stream kind | نوع stream ناشناخته
+		return
+	}
+	if !coreStreams[kind] {
+		defer recoverStream(kind, st)
+	}
+	handler(st)
+}
+
+// recoverStream is deferred around a stream handler: a panic is reported and closes only st | پس از handler اجرا می‌شود: panic گزارش و فقط st بسته می‌شود
+func recoverStream(kind string, st net.Conn) {
+	if v := recover(); v != nil {
+		reportPanic(kind+" stream", v)
+		_ = st.Close()
+	}
+}
+
+/*
+readHeaderLine reads a short line byte by byte, so nothing past the
+newline is consumed from the stream.
+
+این تابع یک خط کوتاه را بایت‌به‌بایت می‌خواند
+تا چیزی بعد از newline از stream مصرف نشود
+*/
+func readHeaderLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 256 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", errBadStreamHeader
+}
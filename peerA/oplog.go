@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json" // For JSON log events
+	"errors"        // For an unknown format
+	"fmt"           // For reporting a failing log
+	"net"           // For the syslog and journal sockets
+	"os"            // For stderr and the process ID
+	"regexp"        // For stripping terminal escapes
+	"strings"       // For keeping an event on one line
+	"sync"          // For serialising writes
+	"time"          // For line timestamps
+)
+
+/*
+Operational log targets: besides a file path, -log accepts these names
+to send the log to the local syslog daemon or the systemd journal.
+
+مقصدهای log عملیاتی: علاوه بر مسیر فایل، -log این نام‌ها را برای ارسال
+log به syslog محلی یا journal در systemd می‌پذیرد
+*/
+const (
+	logSyslog  = "syslog"
+	logJournal = "journald"
+)
+
+/*
+Syslog priorities (RFC 5424), shared by syslog and the journal
+
+اولویت‌های syslog که syslog و journal هر دو به کار می‌برند
+*/
+const (
+	priCrit    = 2 // The session died of an internal failure | پایان نشست با خرابی داخلی
+	priErr     = 3 // An operation failed | شکست یک عملیات
+	priWarning = 4
+	priNotice  = 5 // The link came up or went down | برقراری یا قطع اتصال
+	priInfo    = 6
+)
+
+/*
+Operational log formats: plain lines, or one JSON object per line for
+log shippers
+
+قالب‌های log عملیاتی: خطوط ساده، یا یک شیء JSON در هر خط برای ابزارهای
+جمع‌آوری log
+*/
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+const syslogDaemon = 3 // The "daemon" syslog facility | facility مربوط به daemonها
+
+var errLogFormat = errors.New(`log format must be "text" or "json"`) // Unknown -log-format | قالب نامعتبر
+
+// priorityNames are the level field of JSON events | نام سطح‌ها در رویدادهای JSON
+var priorityNames = map[int]string{priCrit: "crit", priErr: "error", priWarning: "warning", priNotice: "notice", priInfo: "info"}
+
+/*
+logEvent is one JSON log line; the field names are stable so pipelines
+can parse them:
+
+	{"time":"2026-01-02T15:04:05.123Z","level":"error","peer":"A","msg":"Send error: ..."}
+
+این نوع یک خط log به قالب JSON است؛ نام فیلدها ثابت است تا ابزارهای
+جمع‌آوری log بتوانند آن‌ها را تجزیه کنند
+*/
+type logEvent struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"` // crit, error, warning, notice or info | سطح رویداد
+	Peer  string    `json:"peer"`  // Our name | نام ما
+	Msg   string    `json:"msg"`   // What happened | آنچه رخ داد
+}
+
+// terminalEscape matches colour codes and hyperlink wrappers | کدهای رنگ و لینک ترمینال
+var terminalEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b\][^\x1b\a]*(\x1b\\|\a)`)
+
+/*
+opLog is the operational log: the link coming up and going down,
+errors and file transfers, each recorded by an explicit call and handed
+to a logSink. Message text never goes in; the transcript is the
+history's job. A file sink rotates so a long-lived daemon keeps a
+record without filling the disk.
+
+این نوع log عملیاتی است: برقراری و قطع اتصال، خطاها و انتقال فایل‌ها که
+هر کدام با یک فراخوانی صریح ثبت و به یک logSink داده می‌شوند. متن پیام‌ها
+هرگز وارد آن نمی‌شود؛ متن گفتگو کار تاریخچه است. مقصد فایل چرخانده می‌شود
+تا daemon طولانی‌مدت بدون پرشدن دیسک سابقه داشته باشد
+*/
+type opLog struct {
+	mu     sync.Mutex
+	sink   logSink
+	name   string // Our name, for JSON events | نام ما برای رویدادهای JSON
+	json   bool   // One JSON object per line | یک شیء JSON در هر خط
+	failed bool   // A write error was already reported | خطای نوشتن قبلاً گزارش شده
+}
+
+// oplog is the operational log main opened; nil when -log is unset | log عملیاتی بازشده در main؛ بدون -log برابر nil
+var oplog *opLog
+
+// logSink is where log lines end up | مقصد خطوط log
+type logSink interface {
+	write(t time.Time, pri int, line string) error
+	close() error
+}
+
+/*
+openOpLog opens the operational log named by target: "syslog",
+"journald" or a file path, writing lines in format ("text" or "json").
+name tags the entries; an empty target returns nil.
+
+این تابع log عملیاتی را بر اساس target باز می‌کند: "syslog"، "journald"
+یا مسیر فایل، با قالب format ("text" یا "json"). name برچسب ورودی‌هاست؛
+target خالی یعنی nil
+*/
+func openOpLog(target, format, name string, rot rotation) (*opLog, error) {
+	if format != logText && format != logJSON {
+		return nil, fmt.Errorf("%w: %q", errLogFormat, format)
+	}
+	var sink logSink
+	var err error
+	switch target {
+	case "":
+		return nil, nil
+	case logSyslog:
+		sink, err = dialSyslog("peerchat-" + name)
+	case logJournal:
+		sink, err = dialJournal("peerchat-" + name)
+	default:
+		var f *rotatingFile
+		f, err = openRotating(target, rot)
+		sink = fileSink{f: f, stamp: format == logText}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &opLog{sink: sink, name: name, json: format == logJSON}, nil
+}
+
+/*
+logf records one operational event at the priority the caller gives; a
+nil log records nothing. Callers pass only what this peer knows about
+itself and the link, never text a remote sent, so a peer can neither
+write into the system journal nor choose the priority of a line.
+
+این تابع یک رویداد عملیاتی را با اولویتی که فراخواننده می‌دهد ثبت می‌کند؛
+log برابر nil چیزی ثبت نمی‌کند. فراخواننده فقط چیزی را می‌دهد که این peer
+درباره‌ی خودش و اتصال می‌داند، هرگز متنی که طرف مقابل فرستاده، پس peer
+نه در journal سیستم چیزی می‌نویسد و نه اولویت خطی را انتخاب می‌کند
+*/
+func (l *opLog) logf(pri int, format string, args ...any) {
+	if l == nil {
+		return
+	}
+	line := terminalEscape.ReplaceAllString(fmt.Sprintf(format, args...), "")
+	line = strings.ReplaceAll(line, "\n", " ") // One event, one line and one journal field | هر رویداد یک خط و یک فیلد journal
+	t := time.Now()
+	if l.json {
+		data, _ := json.Marshal(logEvent{Time: t, Level: priorityNames[pri], Peer: l.name, Msg: line}) // Strings cannot fail | برای رشته‌ها خطا نمی‌دهد
+		line = string(data)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.sink.write(t, pri, line)
+	if err != nil && !l.failed {
+		l.failed = true // The chat goes on; report once on stderr | گفتگو ادامه می‌یابد؛ یک بار گزارش
+		fmt.Fprintln(os.Stderr, "Log error:", err)
+	}
+}
+
+// close closes the sink; a nil log is a no-op | بستن مقصد؛ برای nil کاری انجام نمی‌شود
+func (l *opLog) close() {
+	if l == nil {
+		return
+	}
+	_ = l.sink.close()
+}
+
+// fileSink writes lines to a rotating file, timestamped in text format | نوشتن خطوط در فایل چرخشی
+type fileSink struct {
+	f     *rotatingFile
+	stamp bool // Prefix a timestamp (text format) | افزودن برچسب زمانی در قالب متنی
+}
+
+func (s fileSink) write(t time.Time, _ int, line string) error {
+	if s.stamp {
+		line = t.Format(time.RFC3339) + " " + line
+	}
+	_, err := s.f.Write([]byte(line + "\n"))
+	return err
+}
+
+func (s fileSink) close() error { return s.f.Close() }
+
+/*
+syslogSink sends each line as one datagram to the local syslog socket
+in the traditional BSD format with the daemon facility.
+
+این نوع هر خط را به‌صورت یک datagram با قالب سنتی BSD و facility
+مربوط به daemonها به socket محلی syslog می‌فرستد
+*/
+type syslogSink struct {
+	conn net.Conn
+	tag  string // Program name and PID | نام برنامه و PID
+}
+
+// dialSyslog connects to the first syslog socket found | اتصال به اولین socket موجود syslog
+func dialSyslog(name string) (logSink, error) {
+	var err error
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		var c net.Conn
+		if c, err = net.Dial("unixgram", path); err == nil {
+			return syslogSink{conn: c, tag: fmt.Sprintf("%s[%d]", name, os.Getpid())}, nil
+		}
+	}
+	return nil, fmt.Errorf("no syslog socket: %w", err)
+}
+
+func (s syslogSink) write(t time.Time, pri int, line string) error {
+	_, err := fmt.Fprintf(s.conn, "<%d>%s %s: %s", syslogDaemon*8+pri, t.Format(time.Stamp), s.tag, line)
+	return err
+}
+
+func (s syslogSink) close() error { return s.conn.Close() }
+
+/*
+journalSink sends each line to systemd-journald over its native
+protocol, so the journal keeps the priority and identifier as fields.
+
+این نوع هر خط را با پروتکل بومی systemd-journald ارسال می‌کند تا
+journal اولویت و شناسه را به‌صورت فیلد نگه دارد
+*/
+type journalSink struct {
+	conn net.Conn
+	name string // SYSLOG_IDENTIFIER field | فیلد شناسه
+}
+
+// dialJournal connects to the journal's socket | اتصال به socket مربوط به journal
+func dialJournal(name string) (logSink, error) {
+	c, err := net.Dial("unixgram", "/run/systemd/journal/socket")
+	if err != nil {
+		return nil, err
+	}
+	return journalSink{conn: c, name: name}, nil
+}
+
+func (s journalSink) write(_ time.Time, pri int, line string) error {
+	_, err := fmt.Fprintf(s.conn, "PRIORITY=%d\nSYSLOG_FACILITY=%d\nSYSLOG_IDENTIFIER=%s\nMESSAGE=%s\n", pri, syslogDaemon, s.name, line)
+	return err
+}
+
+func (s journalSink) close() error { return s.conn.Close() }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"   // For the current destination and the tee
+	"os"   // For the real stdout
+	"sync" // For serialising writes and redirects
+)
+
+/*
+stdout is where everything the peer prints goes. The console, the
+daemon relay and the machine-readable modes redirect it rather than
+os.Stdout, so goroutines printing at any moment always see a whole
+writer and their lines never interleave.
+
+همه‌ی چیزهایی که peer چاپ می‌کند به stdout می‌رود. ویرایشگر، relay حالت
+daemon و حالت‌های ماشینی به‌جای os.Stdout مقصد آن را عوض می‌کنند تا
+goroutineهایی که هر لحظه چاپ می‌کنند همیشه یک نویسنده‌ی کامل ببینند و
+خطوطشان در هم نرود
+*/
+var stdout = &outputSwitch{w: os.Stdout}
+
+/*
+outputSwitch writes to one destination at a time, swapped under its
+lock, and copies everything into the tee as well: the recent lines
+kept for crash dumps.
+
+این نوع در هر لحظه روی یک مقصد می‌نویسد که زیر قفل آن عوض می‌شود و
+همه چیز را در tee هم کپی می‌کند: خطوط اخیر برای گزارش خرابی
+*/
+type outputSwitch struct {
+	mu  sync.Mutex
+	w   io.Writer
+	tee io.Writer // Also gets every write, if set | در صورت وجود هر نوشتن را هم می‌گیرد
+}
+
+func (o *outputSwitch) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.tee != nil {
+		_, _ = o.tee.Write(p)
+	}
+	return o.w.Write(p)
+}
+
+// redirect sends later writes to w and returns the previous destination | تغییر مقصد و برگرداندن مقصد قبلی
+func (o *outputSwitch) redirect(w io.Writer) io.Writer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	prev := o.w
+	o.w = w
+	return prev
+}
+
+// target returns the current destination | مقصد جاری
+func (o *outputSwitch) target() io.Writer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w
+}
+
+// capture copies later writes into w as well; nil stops copying | کپی نوشتن‌های بعدی در w؛ nil کپی را متوقف می‌کند
+func (o *outputSwitch) capture(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tee = w
+}
+
+// statusWriter returns where notices go: stderr in pipe mode, where stdout carries NDJSON only | محل چاپ اعلان‌ها
+func statusWriter(pipe bool) io.Writer {
+	if pipe {
+		return os.Stderr
+	}
+	return stdout
+}
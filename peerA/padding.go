@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math/rand" // For the cover traffic intervals
+	"strings"   // For the padding spaces
+	"time"      // For the cover traffic timer
+)
+
+/*
+Traffic shaping
+
+شکل‌دهی ترافیک:
+- padBucketMin کوچک‌ترین اندازه‌ی خط پرشده است و اندازه‌ها توان‌های دوی بعدی هستند
+- coverMean میانگین فاصله‌ی خطوط پوششی است
+- coverLine خط پوششی است که گیرنده دور می‌ریزد
+*/
+const (
+	padBucketMin = 256
+	coverMean    = 5 * time.Second
+	coverLine    = `{"cover":true}`
+)
+
+/*
+trafficShaper hides what the chat stream reveals to someone watching
+the link: every line is padded to a power-of-two bucket, and cover lines
+go out at random (exponential) intervals, so sizes and timing say less
+about the messages. Padding is JSON whitespace, which any peer ignores;
+cover lines need the remote to know them.
+
+این نوع آنچه stream چت به ناظر اتصال نشان می‌دهد را می‌پوشاند: هر خط تا
+اندازه‌ای توان دو پر می‌شود و خطوط پوششی در فاصله‌های تصادفی (نمایی) ارسال
+می‌شوند تا اندازه و زمان کمتر درباره‌ی پیام‌ها بگویند؛ پرکردن با فاصله‌ی
+JSON است که هر peerی نادیده می‌گیرد ولی طرف مقابل باید خطوط پوششی را بشناسد
+*/
+type trafficShaper struct {
+	limit int // Largest line with its newline the remote reads | بزرگ‌ترین خط قابل خواندن برای طرف مقابل
+}
+
+// newTrafficShaper returns a shaper when enabled and negotiated, else nil | ساخت shaper در صورت فعال بودن و توافق
+func newTrafficShaper(enabled bool, caps capabilities) *trafficShaper {
+	if !enabled || !caps.Padding {
+		return nil
+	}
+	return &trafficShaper{limit: caps.MaxMessage}
+}
+
+/*
+pad fills a JSON line with spaces after its opening brace up to the next
+bucket, counting the newline. Lines the bucket would push past the
+remote's limit are padded to the limit, and plain lines are left alone.
+
+این تابع یک خط JSON را پس از آکولاد ابتدایی با فاصله تا اندازه‌ی بعدی (با
+احتساب newline) پر می‌کند؛ خطی که از حد طرف مقابل بگذرد تا همان حد پر
+می‌شود و خط ساده دست‌نخورده می‌ماند
+*/
+func (t *trafficShaper) pad(line string) string {
+	if t == nil || !strings.HasPrefix(line, "{") {
+		return line
+	}
+	size := len(line) + 1
+	bucket := padBucketMin
+	for bucket < size {
+		bucket *= 2
+	}
+	bucket = min(bucket, t.limit)
+	if bucket <= size {
+		return line
+	}
+	return "{" + strings.Repeat(" ", bucket-size) + line[1:]
+}
+
+// nextCover returns when to send the next cover line; nil never fires | زمان خط پوششی بعدی؛ nil هرگز
+func (t *trafficShaper) nextCover() <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return time.After(time.Duration(rand.ExpFloat64() * float64(coverMean)))
+}
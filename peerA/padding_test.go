@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlFramesPadded(t *testing.T) {
+	done := newDoneSignal()
+	defer done.close()
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	c := newControlLink(done)
+	go c.writer(local, &trafficShaper{limit: maxMessageSize})
+
+	r := bufio.NewReader(remote)
+	for _, f := range []controlFrame{{Type: ctrlAck, Text: newMessageID()}, {Type: ctrlPing, Time: 1}} {
+		c.send(f)
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(line) != padBucketMin {
+			t.Errorf("%s frame is %d bytes, want %d", f.Type, len(line), padBucketMin)
+		}
+		var got controlFrame
+		if err := json.Unmarshal([]byte(line), &got); err != nil || got != f {
+			t.Errorf("decoded %+v (%v), want %+v", got, err, f)
+		}
+	}
+}
+
+func TestCoverLineAcknowledged(t *testing.T) {
+	done := newDoneSignal()
+	defer done.close()
+	s := &session{ctrl: newControlLink(done)}
+	acknowledge(s, message{Cover: true})
+	acknowledge(s, message{ID: "0123abcd"})
+	cover, real := <-s.ctrl.out, <-s.ctrl.out
+	if cover.Type != ctrlAck || real.Type != ctrlAck || len(cover.Text) != len(real.Text) {
+		t.Errorf("cover ack %+v does not look like the real one %+v", cover, real)
+	}
+}
+
+func TestPadBuckets(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := &trafficShaper{limit: 4096}
+	for _, c := range []struct {
+		text string
+		want int // Padded size with the newline, 0 when left alone | اندازه‌ی پرشده با newline، صفر یعنی دست‌نخورده
+	}{
+		{"hi", padBucketMin},
+		{strings.Repeat("a", 100), 2 * padBucketMin},
+		{strings.Repeat("a", 3000), 4096}, // The next bucket is past the limit | اندازه‌ی بعدی از حد می‌گذرد
+		{strings.Repeat("a", 5000), 0},
+	} {
+		line := encodeChat(id, message{Time: time.Now(), From: "ann", Text: c.text, ID: newMessageID()})
+		padded := shaper.pad(line)
+		if c.want == 0 {
+			if padded != line {
+				t.Errorf("%d byte line over the limit was padded", len(line))
+			}
+			continue
+		}
+		if len(padded)+1 != c.want {
+			t.Errorf("%d byte line padded to %d, want %d", len(line), len(padded)+1, c.want)
+		}
+		keys, _ := loadRegistry("")
+		if m, ok := decodeChatLine(padded, keys); !ok || !m.Verified || m.Text != c.text {
+			t.Errorf("%d byte line does not verify once padded", len(line))
+		}
+	}
+	if got := shaper.pad("ann: hi"); got != "ann: hi" {
+		t.Errorf("plain line padded to %q", got)
+	}
+	if got := (*trafficShaper)(nil).pad(coverLine); got != coverLine || (*trafficShaper)(nil).nextCover() != nil {
+		t.Error("a nil shaper shapes traffic")
+	}
+	keys, _ := loadRegistry("")
+	if m, ok := decodeChatLine(shaper.pad(coverLine), keys); !ok || !m.Cover {
+		t.Errorf("padded cover line decoded as %+v", m)
+	}
+}
+
+func TestTrafficShaperNegotiated(t *testing.T) {
+	for _, c := range []struct {
+		enabled, understood bool
+	}{
+		{false, false}, {false, true}, {true, false}, {true, true},
+	} {
+		shaper := newTrafficShaper(c.enabled, capabilities{Padding: c.understood, MaxMessage: 1024})
+		if (shaper != nil) != (c.enabled && c.understood) {
+			t.Errorf("enabled %v, understood %v: shaper %v", c.enabled, c.understood, shaper)
+		}
+		if shaper != nil && shaper.limit != 1024 {
+			t.Errorf("shaper limit %d, want the remote's 1024", shaper.limit)
+		}
+	}
+}
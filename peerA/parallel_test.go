@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestPartSinkClaimsEachIndexOnce(t *testing.T) {
+	sink := newPartTable().open(fileHeader{Transfer: "t", Parts: 4}, nil, nil)
+	for _, c := range []struct {
+		index int
+		want  bool
+	}{
+		{0, true}, {1, true}, {1, false}, {3, true}, {4, false}, {-1, false}, {0, false}, {2, true},
+	} {
+		if got := sink.claim(c.index); got != c.want {
+			t.Errorf("claim(%d) = %v, want %v", c.index, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"         // For reading stdin line by line
+	"encoding/json" // For NDJSON output
+	"errors"        // For recognising a closed session
+	"fmt"           // For status messages on stderr
+	"os"            // For stdin/stdout access
+	"strings"       // For trimming input lines
+	"time"          // For polling and the reply window
+)
+
+const pipeFlushPoll = 10 * time.Millisecond // How often to check that input was sent | فاصله بررسی ارسال ورودی
+
+/*
+stdinIsPipe reports whether stdin is redirected from a pipe or file
+rather than attached to a terminal.
+
+این تابع مشخص می‌کند ورودی استاندارد از pipe یا فایل می‌آید
+و به ترمینال وصل نیست
+*/
+func stdinIsPipe() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+/*
+pipeReader sends every stdin line as a message (no commands), or with
+whole set all of stdin as one streamed message, waits until all of it
+was written to the wire, then keeps the link open for replyWait before
+shutting down — e.g. `echo "deploy done" | peerA`.
+
+این تابع هر خط ورودی را به‌عنوان پیام ارسال می‌کند (بدون دستور)، یا با
+whole کل ورودی را به‌صورت یک پیام جریانی می‌فرستد، منتظر می‌ماند تا همه
+روی شبکه نوشته شوند و سپس به مدت replyWait برای پاسخ‌ها صبر می‌کند و
+برنامه را می‌بندد
+*/
+func pipeReader(s *session, replyWait time.Duration, whole bool) {
+	if whole {
+		pipeWhole(s, replyWait)
+		return
+	}
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue // Ignore empty lines | نادیده گرفتن خطوط خالی
+		}
+		_, err := sendChat(s, line, nil, false)
+		if errors.Is(err, errClosed) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Send error:", err)
+		}
+	}
+	pipeDone(s, replyWait)
+}
+
+/*
+pipeWhole streams all of stdin as one message, never holding more than
+one part of it in memory.
+
+این تابع کل ورودی را به‌صورت یک پیام جریانی ارسال می‌کند و هیچ‌گاه بیش
+از یک بخش آن را در حافظه نگه نمی‌دارد
+*/
+func pipeWhole(s *session, replyWait time.Duration) {
+	if !s.conn.caps.Streaming {
+		fmt.Fprintln(os.Stderr, "Send error: the remote cannot receive streamed messages")
+		s.done.close()
+		return
+	}
+	_, err := sendStreamed(s, os.Stdin, nil, false)
+	if errors.Is(err, errClosed) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Send error:", err)
+	}
+	pipeDone(s, replyWait)
+}
+
+// pipeDone waits for our lines to go out and for replies, then shuts down | انتظار برای ارسال و پاسخ‌ها و سپس خروج
+func pipeDone(s *session, replyWait time.Duration) {
+	if !waitSent(s, s.queued.Load()) {
+		return
+	}
+	select {
+	case <-s.done.c:
+	case <-time.After(replyWait): // Window for replies | فرصت دریافت پاسخ
+	}
+	s.done.close()
+}
+
+/*
+waitSent waits until the chat writer has flushed n lines in total; it
+returns false if the session ended first.
+
+این تابع منتظر می‌ماند تا نویسنده‌ی چت در مجموع n خط را ارسال کند؛
+اگر نشست زودتر تمام شود false برمی‌گرداند
+*/
+func waitSent(s *session, n int64) bool {
+	for s.sent.Load() < n {
+		select {
+		case <-s.done.c:
+			return false
+		case <-time.After(pipeFlushPoll):
+		}
+	}
+	return true
+}
+
+var ndjsonOut = os.Stdout // The real stdout in pipe mode, messages only | stdout واقعی در حالت pipe، فقط پیام‌ها
+
+/*
+setPipeOutput keeps the real stdout for NDJSON and redirects stdout to
+stderr, like -output json, so a notice printed anywhere never lands
+between the messages.
+
+این تابع stdout واقعی را برای NDJSON نگه می‌دارد و مانند -output json
+stdout را به stderr هدایت می‌کند تا اعلانی که هر جا چاپ شود میان پیام‌ها نیاید
+*/
+func setPipeOutput() {
+	stdout.redirect(os.Stderr)
+}
+
+/*
+writeNDJSON prints a received message as one JSON object per line.
+
+این تابع پیام دریافتی را به‌صورت یک شیء JSON در هر خط چاپ می‌کند
+*/
+func writeNDJSON(m message) {
+	_ = json.NewEncoder(ndjsonOut).Encode(m)
+}
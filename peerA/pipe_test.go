@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain lets a test start this binary as a whole peer | اجرای همین باینری به‌عنوان یک peer کامل در آزمون
+func TestMain(m *testing.M) {
+	if os.Getenv("PEERCHAT_TEST_PEER") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// freeAddr returns a loopback address nothing listens on | آدرس loopback آزاد
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// peerOutput collects what a child peer prints and lets a test wait for a line | خروجی peer فرزند با امکان انتظار برای یک خط
+type peerOutput struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	wrote chan struct{} // Closed and replaced on every write | با هر نوشتن بسته و جایگزین می‌شود
+}
+
+func newPeerOutput() *peerOutput {
+	return &peerOutput{wrote: make(chan struct{})}
+}
+
+func (o *peerOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	close(o.wrote)
+	o.wrote = make(chan struct{})
+	return o.buf.Write(p)
+}
+
+func (o *peerOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// waitFor blocks until the output holds text, or fails once exited is closed | انتظار تا وقتی خروجی text را داشته باشد
+func (o *peerOutput) waitFor(t *testing.T, text string, exited <-chan struct{}) {
+	t.Helper()
+	for {
+		o.mu.Lock()
+		got, wrote := strings.Contains(o.buf.String(), text), o.wrote
+		o.mu.Unlock()
+		if got {
+			return
+		}
+		select {
+		case <-wrote:
+		case <-exited:
+			t.Fatalf("the peer exited before printing %q:\n%s", text, o)
+		}
+	}
+}
+
+// pipePeer starts a peer in pipe mode with its own state directory | اجرای یک peer در حالت pipe با پوشه‌ی وضعیت جدا
+func pipePeer(t *testing.T, stdin string, args ...string) (*exec.Cmd, *peerOutput, *peerOutput) {
+	t.Helper()
+	return pipePeerIn(t, t.TempDir(), stdin, args...)
+}
+
+// pipePeerIn starts a peer in pipe mode with dir as its home | اجرای یک peer در حالت pipe با dir به‌عنوان پوشه‌ی خانه
+func pipePeerIn(t *testing.T, dir, stdin string, args ...string) (*exec.Cmd, *peerOutput, *peerOutput) {
+	t.Helper()
+	stdout, stderr := newPeerOutput(), newPeerOutput()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "PEERCHAT_TEST_PEER=1", "XDG_CONFIG_HOME="+dir, "HOME="+dir)
+	cmd.Stdin = strings.NewReader(stdin) // Not a terminal: pipe mode
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, stdout, stderr
+}
+
+func TestPipeStdoutIsOnlyNDJSON(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two peers")
+	}
+
+	// A listener that refuses every candidate, for a notice on the way | listenerی که هر کاندید را رد می‌کند تا اعلانی چاپ شود
+	dir := t.TempDir()
+	refuser, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, err := loadEntrySet(filepath.Join(dir, "bans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := loadEntrySet(filepath.Join(dir, "members"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newPeerAuth(refuser, bans, members, accessPassword, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tcpTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := newDoneSignal()
+	defer done.close()
+	go establishConn(tcpTransport{}, ln, "127.0.0.1:1", auth, socketOptions{linger: -1}, io.Discard, done.c)
+
+	// Y starts only once X has been refused, so Y cannot link first | Y فقط پس از رد شدن X شروع می‌شود تا زودتر وصل نشود
+	x, y := freeAddr(t), freeAddr(t)
+	xCmd, xOut, xErr := pipePeer(t, "hi from x\n", "-name", "X", "-listen", x, "-dial", ln.Addr().String(), "-wait", "2s")
+	xExited := make(chan struct{})
+	var xWait error
+	go func() { xWait = xCmd.Wait(); close(xExited) }()
+	xErr.waitFor(t, "Remote refused the connection", xExited)
+	yCmd, _, yErr := pipePeer(t, "hello from y\n", "-name", "Y", "-listen", y, "-dial", x, "-wait", "2s")
+	yExited := make(chan struct{})
+	var yWait error
+	go func() { yWait = yCmd.Wait(); close(yExited) }()
+	for _, c := range []struct {
+		cmd    *exec.Cmd
+		exited chan struct{}
+		err    *error
+	}{{xCmd, xExited, &xWait}, {yCmd, yExited, &yWait}} {
+		select {
+		case <-c.exited:
+			if *c.err != nil {
+				t.Fatalf("peer: %v\nX: %s\nY: %s", *c.err, xErr, yErr)
+			}
+		case <-time.After(20 * time.Second):
+			_ = c.cmd.Process.Kill()
+			t.Fatalf("peer did not exit\nX: %s\nY: %s", xErr, yErr)
+		}
+	}
+
+	got := false
+	for _, line := range strings.Split(strings.TrimSuffix(xOut.String(), "\n"), "\n") {
+		var m message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Errorf("stdout line is not JSON: %q", line)
+			continue
+		}
+		got = got || m.Text == "hello from y"
+	}
+	if !got {
+		t.Errorf("the message never reached stdout:\n%s\nstderr:\n%s", xOut, xErr)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"help"}, 0},
+		{[]string{"history", "-h"}, 0}, // Help was asked for, not an error | راهنما خواسته شد، نه خطا
+		{[]string{"export", "-h"}, 0},
+		{[]string{"chat", "-version"}, 0},
+		{[]string{"history", "--no-such-flag"}, exitFailed},
+		{[]string{"export", "--no-such-flag"}, exitFailed},
+		{[]string{"connect"}, exitUsage},
+		{[]string{"send", "--to", "127.0.0.1:1"}, exitUsage}, // Nothing to send | چیزی برای ارسال نیست
+		{[]string{"no-such-command"}, exitUsage},
+	} {
+		cmd, _, _ := pipePeer(t, "", c.args...)
+		if code := exitCode(t, cmd.Wait()); code != c.want {
+			t.Errorf("%v: exit status %d, want %d", c.args, code, c.want)
+		}
+	}
+}
+
+func TestHelpListsCommands(t *testing.T) {
+	cmd, _, errOut := pipePeer(t, "", "help")
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range subcommands {
+		if !strings.Contains(errOut.String(), "  "+c.name+" ") {
+			t.Errorf("help does not list %s:\n%s", c.name, errOut)
+		}
+	}
+	if !strings.Contains(errOut.String(), "-listen") {
+		t.Errorf("help does not list the chat flags:\n%s", errOut)
+	}
+
+	cmd, out, _ := pipePeer(t, "", "chat", "-version")
+	if err := cmd.Wait(); err != nil || !strings.Contains(out.String(), "(protocol ") {
+		t.Errorf("chat -version: %v\n%s", err, out)
+	}
+	cmd, out, errOut = pipePeer(t, "", "history", "-h")
+	if err := cmd.Wait(); err != nil || strings.Contains(out.String(), "error") || !strings.Contains(errOut.String(), "-since") {
+		t.Errorf("history -h: %v\n%s%s", err, out, errOut)
+	}
+}
+
+// exitCode returns the exit status of a finished peer | کد خروج peer پایان‌یافته
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return exit.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestSendSubcommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts peers")
+	}
+	y := freeAddr(t)
+	yCmd, yOut, yErr := pipePeer(t, "", "-name", "Y", "-listen", y, "-dial", freeAddr(t), "-wait", "20s")
+	defer func() { _ = yCmd.Process.Kill(); _ = yCmd.Wait() }()
+	yExited := make(chan struct{})
+	go func() { _ = yCmd.Wait(); close(yExited) }()
+	yErr.waitFor(t, "starting", yExited)
+
+	cmd, _, errOut := pipePeer(t, "", "send", "--to", y, "-name", "X", "deploy", "finished")
+	if code := exitCode(t, cmd.Wait()); code != exitDelivered || !strings.Contains(errOut.String(), "Delivered:") {
+		t.Errorf("send: exit status %d\n%s", code, errOut)
+	}
+	yOut.waitFor(t, `"text":"deploy finished"`, yExited)
+
+	// Nobody there: nothing went out | کسی آنجا نیست: چیزی ارسال نشد
+	cmd, _, errOut = pipePeer(t, "", "send", "--to", freeAddr(t), "--timeout", "1s", "-name", "X", "hello")
+	if code := exitCode(t, cmd.Wait()); code != exitFailed || !strings.Contains(errOut.String(), "not delivered within 1s") {
+		t.Errorf("send to nobody: exit status %d\n%s", code, errOut)
+	}
+
+	// A peer that takes the link but never acknowledges | peerی که اتصال را می‌پذیرد ولی هرگز تأیید نمی‌کند
+	dir := t.TempDir()
+	mute, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, err := loadEntrySet(filepath.Join(dir, "bans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := loadEntrySet(filepath.Join(dir, "members"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newPeerAuth(mute, bans, members, accessOpen, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tcpTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := newDoneSignal()
+	defer done.close()
+	go func() {
+		if c := establishConn(tcpTransport{}, ln, "127.0.0.1:1", auth, socketOptions{linger: -1}, io.Discard, done.c); c != nil {
+			<-done.c
+			c.Close()
+		}
+	}()
+	cmd, _, errOut = pipePeer(t, "", "send", "--to", ln.Addr().String(), "--timeout", "2s", "-name", "X", "hello")
+	if code := exitCode(t, cmd.Wait()); code != exitUnconfirmed {
+		t.Errorf("send without an acknowledgement: exit status %d\n%s", code, errOut)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatusMessage(t *testing.T) {
+	defer stdout.redirect(stdout.redirect(io.Discard))
+	done := newDoneSignal()
+	defer done.close()
+	s := &session{ctrl: newControlLink(done), presence: newPresence("")}
+
+	statusCommand(s, []string{`"in`, "a", `meeting"`})
+	if f := <-s.ctrl.out; f.Type != ctrlPresence || f.Text != presenceOnline || f.Note != "in a meeting" {
+		t.Fatalf("sent %+v, want the note without its quotes", f)
+	}
+	awayCommand(s, nil)
+	if f := <-s.ctrl.out; f.Text != presenceAway || f.Note != "in a meeting" {
+		t.Fatalf("sent %+v, want the note kept while away", f)
+	}
+	if f, changed := s.presence.setIdle(true); changed {
+		t.Fatalf("away turned idle: %+v", f)
+	}
+	backCommand(s, nil)
+	<-s.ctrl.out
+	if f, changed := s.presence.setIdle(true); !changed || f.Text != presenceIdle || f.Note != "in a meeting" {
+		t.Fatalf("idle sent %+v, want the note kept while idle", f)
+	}
+	statusCommand(s, nil)
+	if f := <-s.ctrl.out; f.Note != "" || f.Text != presenceIdle {
+		t.Fatalf("sent %+v, want the note cleared and the state kept", f)
+	}
+}
+
+func TestRemoteStatusShownOnChange(t *testing.T) {
+	done := newDoneSignal()
+	defer done.close()
+	var status bytes.Buffer
+	s := &session{ctrl: newControlLink(done), presence: newPresence(""), status: &status}
+	s.presence.setRemoteName("ann")
+	handlePresenceFrames(s)
+	h := s.ctrl.handlers[ctrlPresence]
+
+	h(controlFrame{Type: ctrlPresence, Text: presenceOnline, Note: "lunch"})
+	if got := status.String(); got != "ann (online) \"lunch\"\n" {
+		t.Fatalf("status %q, want the new note", got)
+	}
+	status.Reset()
+	h(controlFrame{Type: ctrlPresence, Text: presenceAway, Note: "lunch"})
+	if got := status.String(); got != "ann is now away\n" {
+		t.Fatalf("status %q, want only the state change", got)
+	}
+	status.Reset()
+	h(controlFrame{Type: ctrlPresence, Text: presenceAway})
+	if got := status.String(); !strings.Contains(got, "ann (away)") || strings.Contains(got, "lunch") {
+		t.Fatalf("status %q, want the cleared note shown", got)
+	}
+}
+
+func TestAwayAutoReply(t *testing.T) {
+	defer stdout.redirect(stdout.redirect(io.Discard))
+	done := newDoneSignal()
+	defer done.close()
+	s := &session{ctrl: newControlLink(done), presence: newPresence("gone fishing")}
+	p := s.presence
+
+	if r := p.autoReply(message{From: "bob"}); r != "" {
+		t.Fatalf("auto-replied %q while online", r)
+	}
+	awayCommand(s, nil)
+	<-s.ctrl.out
+	if r := p.autoReply(message{From: "bob", Auto: true}); r != "" {
+		t.Errorf("auto-replied %q to an auto-reply", r)
+	}
+	if r := p.autoReply(message{From: "bob", Key: "k1"}); r != "gone fishing" {
+		t.Errorf("first message got %q, want the configured reply", r)
+	}
+	if r := p.autoReply(message{From: "carol", Key: "k2"}); r != "" {
+		t.Errorf("a second reply within %v: %q", awayReplyEvery, r)
+	}
+	p.lastReply = p.lastReply.Add(-awayReplyEvery) // As if a minute had passed | گویی یک دقیقه گذشته
+	if r := p.autoReply(message{From: "bobby", Key: "k1"}); r != "" {
+		t.Errorf("the same key under another nick got a second reply %q", r)
+	}
+	if r := p.autoReply(message{From: "carol", Key: "k2"}); r != "gone fishing" {
+		t.Errorf("another peer after the interval got %q", r)
+	}
+
+	backCommand(s, nil)
+	<-s.ctrl.out
+	awayCommand(s, []string{"back", "soon"})
+	<-s.ctrl.out
+	p.lastReply = time.Time{}
+	if r := p.autoReply(message{From: "bob", Key: "k1"}); r != "back soon" {
+		t.Errorf("a new away spell got %q, want its own reply", r)
+	}
+}
+
+func TestIdleTracking(t *testing.T) {
+	done := newDoneSignal()
+	defer done.close()
+	s := &session{ctrl: newControlLink(done), presence: newPresence("")}
+	var live atomic.Pointer[session]
+	live.Store(s)
+	c := &console{}
+	c.lastKey.Store(time.Now().UnixNano())
+	stop := make(chan struct{})
+	defer close(stop)
+	trackIdle(&live, c, 100*time.Millisecond, stop)
+
+	select {
+	case f := <-s.ctrl.out:
+		if f.Text != presenceIdle {
+			t.Fatalf("sent %+v, want idle", f)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no idle frame without keystrokes")
+	}
+	c.lastKey.Store(time.Now().UnixNano()) // What keyReader does on a keystroke | کاری که keyReader با هر کلید می‌کند
+	c.onKey()
+	if f := <-s.ctrl.out; f.Text != presenceOnline {
+		t.Fatalf("sent %+v after a keystroke, want online", f)
+	}
+	c.onKey()
+	select {
+	case f := <-s.ctrl.out:
+		t.Fatalf("a second keystroke sent %+v", f)
+	default:
+	}
+}
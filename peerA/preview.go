@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"  // For the fetch timeout
+	"errors"   // For refused links
+	"fmt"      // For printing preview lines
+	"html"     // For unescaping entities in titles
+	"io"       // For the size cap
+	"net"      // For checking where a link leads
+	"net/http" // For fetching linked pages
+	"net/url"  // For checking redirect targets
+	"regexp"   // For finding URLs and page metadata
+	"strings"  // For tidying extracted text
+	"syscall"  // For the dialer's address check
+	"time"     // For the fetch timeout
+	"unicode"  // For dropping control characters
+)
+
+/*
+Link preview configuration
+
+مقادیر پیکربندی پیش‌نمایش لینک:
+- حداکثر زمان دریافت صفحه
+- حداکثر حجم خوانده‌شده از هر صفحه
+- حداکثر تعداد لینک‌های هر پیام
+- طول خط پیش‌نمایش
+*/
+const (
+	previewTimeout   = 5 * time.Second // Max wait per page | حداکثر انتظار برای هر صفحه
+	previewMaxBytes  = 256 << 10       // Bytes read per page | حجم خوانده‌شده از هر صفحه
+	previewMaxLinks  = 2               // Links previewed per message | لینک‌های پیش‌نمایش‌شده در هر پیام
+	previewLength    = 100             // Characters per preview line | طول خط پیش‌نمایش
+	previewRedirects = 5               // Redirects followed per link | تعداد تغییر مسیرهای دنبال‌شده
+)
+
+var (
+	errPreviewAddress  = errors.New("link leads to a local or private address") // Loopback, LAN, link-local or metadata | آدرس محلی یا خصوصی
+	errPreviewRedirect = errors.New("too many redirects")                       // Redirect loop | حلقه‌ی تغییر مسیر
+)
+
+var (
+	urlPattern   = regexp.MustCompile(`https?://[^\s<>"'\x00-\x1f\x7f]+[^\s<>"'.,;:!?)\]\x00-\x1f\x7f]`) // Links in message text, ending before any colour or control code | لینک‌های داخل متن، تا پیش از کد رنگ یا کنترل
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)                                  // Page title | عنوان صفحه
+	metaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)                                              // Meta tags | تگ‌های meta
+	attrPattern  = regexp.MustCompile(`([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)                         // Tag attributes | ویژگی‌های تگ
+	spacePattern = regexp.MustCompile(`\s+`)                                                             // Runs of whitespace | فاصله‌های پشت سر هم
+)
+
+/*
+previewClient bounds each fetch, redirects included, and only connects
+to public addresses: a link in a message is chosen by the remote, so it
+must not make us request our loopback, LAN, link-local or cloud
+metadata (169.254.169.254) services. The check runs on the address
+actually dialed, after DNS, so a name that resolves to a private
+address is refused too, and on every redirect target. No proxy is
+used, since it would make the request on our behalf.
+
+این کلاینت هر دریافت را همراه با تغییر مسیرها محدود می‌کند و فقط به
+آدرس‌های عمومی وصل می‌شود: لینک داخل پیام را طرف مقابل انتخاب می‌کند، پس
+نباید ما را به درخواست از سرویس‌های loopback، شبکه‌ی محلی، link-local یا
+metadata ابری (169.254.169.254) وادار کند. بررسی روی آدرسی که واقعاً dial
+می‌شود و پس از DNS انجام می‌شود، پس نامی که به آدرس خصوصی برسد هم رد می‌شود،
+و روی مقصد هر تغییر مسیر نیز؛ از proxy استفاده نمی‌شود چون درخواست را به جای
+ما می‌فرستد
+*/
+var previewClient = &http.Client{
+	Timeout: previewTimeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: previewTimeout, Control: previewDialControl}).DialContext,
+		TLSHandshakeTimeout: previewTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= previewRedirects {
+			return errPreviewRedirect
+		}
+		return checkPreviewURL(req.URL)
+	},
+}
+
+// previewDialControl refuses to connect to an address that is not public | رد اتصال به آدرس غیرعمومی
+func previewDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return errPreviewAddress
+	}
+	return nil
+}
+
+// checkPreviewURL refuses non-HTTP links and literal addresses that are not public | رد لینک غیر HTTP و آدرس صریح غیرعمومی
+func checkPreviewURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicIP(ip) {
+		return errPreviewAddress
+	}
+	return nil
+}
+
+// publicIP reports whether ip is neither loopback, private, link-local, multicast nor unspecified | آیا ip عمومی است
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+/*
+showPreviews fetches the first links in text and prints a short
+"title — description" line for each one. It is only called when
+link-previews is enabled, since fetching tells the linked site that
+this peer read the message.
+
+این تابع اولین لینک‌های متن را دریافت و برای هر کدام یک خط کوتاه
+«عنوان — توضیح» چاپ می‌کند. فقط وقتی link-previews فعال باشد صدا زده
+می‌شود، چون دریافت صفحه به سایت مقصد نشان می‌دهد که پیام خوانده شده است
+*/
+func showPreviews(text string) {
+	for _, link := range urlPattern.FindAllString(text, previewMaxLinks) {
+		title, desc, err := fetchPreview(link)
+		if err != nil || title == "" && desc == "" {
+			continue // Previews are best effort | پیش‌نمایش اختیاری است
+		}
+		line := title
+		if desc != "" && desc != title {
+			if line != "" {
+				line += " — "
+			}
+			line += desc
+		}
+		fmt.Fprintln(stdout, "  ⤷ "+snippet(line, previewLength))
+	}
+}
+
+/*
+fetchPreview downloads at most previewMaxBytes of an HTML page and
+returns its title and description.
+
+این تابع حداکثر previewMaxBytes از یک صفحه‌ی HTML را دریافت و
+عنوان و توضیح آن را برمی‌گرداند
+*/
+func fetchPreview(link string) (title, desc string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := checkPreviewURL(req.URL); err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := previewClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "html") {
+		return "", "", fmt.Errorf("not an HTML page: %s", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, previewMaxBytes))
+	if err != nil {
+		return "", "", err
+	}
+	title, desc = parsePreview(string(body))
+	return title, desc, nil
+}
+
+/*
+parsePreview extracts the title and description of a page, preferring
+Open Graph tags over <title> and the plain description meta tag.
+
+این تابع عنوان و توضیح صفحه را استخراج می‌کند و تگ‌های Open Graph
+را بر <title> و تگ description معمولی ترجیح می‌دهد
+*/
+func parsePreview(page string) (title, desc string) {
+	var ogTitle, ogDesc string
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, a := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(a[1])] = a[2] + a[3]
+		}
+		key := strings.ToLower(attrs["property"] + attrs["name"])
+		switch key {
+		case "og:title":
+			ogTitle = attrs["content"]
+		case "og:description":
+			ogDesc = attrs["content"]
+		case "description":
+			desc = attrs["content"]
+		}
+	}
+	if m := titlePattern.FindStringSubmatch(page); m != nil {
+		title = m[1]
+	}
+	if ogTitle != "" {
+		title = ogTitle
+	}
+	if ogDesc != "" {
+		desc = ogDesc
+	}
+	return cleanText(title), cleanText(desc)
+}
+
+/*
+cleanText unescapes entities, drops control characters and collapses
+whitespace. Control characters go after unescaping, since a hostile
+page can spell an escape sequence as &#27; to retitle the terminal,
+write the clipboard or redraw the screen.
+
+این تابع entityها را باز می‌کند، نویسه‌های کنترلی را حذف و فاصله‌های اضافی
+را یکی می‌کند؛ نویسه‌های کنترلی پس از بازکردن entityها حذف می‌شوند، چون
+صفحه‌ی مخرب می‌تواند دنباله‌ی escape را به شکل &#27; بنویسد تا عنوان ترمینال،
+clipboard یا صفحه را تغییر دهد
+*/
+func cleanText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, html.UnescapeString(s))
+	return strings.TrimSpace(spacePattern.ReplaceAllString(stripControl(s), " "))
+}
+
+/*
+stripControl drops control characters from text a remote chose, such as
+a page title or a file name, so printing it cannot drive the terminal.
+
+این تابع نویسه‌های کنترلی را از متنی که طرف مقابل انتخاب کرده، مانند عنوان
+صفحه یا نام فایل، حذف می‌کند تا چاپ آن نتواند ترمینال را کنترل کند
+*/
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
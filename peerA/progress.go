@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json" // For the /transfers endpoint
+	"errors"        // For /transfer error values
+	"fmt"           // For progress lines
+	"io"            // For the progress writer
+	"net/http"      // For the /transfers endpoint
+	"slices"        // For listing transfers in start order
+	"strconv"       // For parsing transfer IDs
+	"strings"       // For the optional # before an ID
+	"sync"          // For guarding the table
+	"sync/atomic"   // For the byte counters
+	"time"          // For speed and ETA
+)
+
+const progressInterval = 2 * time.Second // Time between progress lines | فاصله‌ی خطوط پیشرفت
+
+var (
+	errNoTransfer  = errors.New("no transfer with that id")   // Unknown or finished | ناشناخته یا تمام‌شده
+	errPauseRecv   = errors.New("only sends can be paused")   // Receives are paced by the sender | دریافت را فرستنده تنظیم می‌کند
+	errNotPaused   = errors.New("transfer is not paused")     // Nothing to resume | چیزی برای ادامه نیست
+	errAlreadyHeld = errors.New("transfer is already paused") // Nothing to pause | قبلاً متوقف شده
+)
+
+func init() {
+	registerCommand("transfer", "/transfer [list] | /transfer pause|resume <id>  hold or continue one of our sends", transferCommand)
+	registerArgCompleter("transfer", func(_ *session, args []string, word string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return matching(word, "list", "pause", "resume")
+	})
+}
+
+/*
+transferCommand lists the transfers in flight, or pauses and resumes
+one of our sends by its ID. A paused send keeps its streams open, so
+it picks up where it stopped.
+
+این دستور انتقال‌های در جریان را فهرست می‌کند یا یکی از ارسال‌های ما را با
+شناسه‌اش متوقف یا ادامه می‌دهد؛ ارسال متوقف‌شده streamهایش را باز نگه
+می‌دارد و از همان جا ادامه می‌یابد
+*/
+func transferCommand(s *session, args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		list := transfers.list()
+		if len(list) == 0 {
+			fmt.Fprintln(stdout, "No transfers in flight")
+		}
+		for _, p := range list {
+			fmt.Fprintf(stdout, "  #%d %s\n", p.ID, p)
+		}
+		return
+	}
+	if len(args) != 2 || (args[0] != "pause" && args[0] != "resume") {
+		fmt.Fprintln(stdout, "Usage: /transfer [list] | /transfer pause|resume <id>")
+		return
+	}
+	id, _ := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err := transfers.hold(id, args[0] == "pause"); err != nil {
+		fmt.Fprintln(stdout, "Transfer error:", err)
+		return
+	}
+	fmt.Fprintf(stdout, "Transfer #%d %sd\n", id, args[0])
+}
+
+/*
+Transfer directions
+
+جهت انتقال:
+- send: فایل ما به طرف مقابل
+- recv: فایل طرف مقابل به ما
+*/
+const (
+	directionSend = "send"
+	directionRecv = "recv"
+)
+
+// transfers tracks the file transfers of this process | انتقال‌های فایل این برنامه
+var transfers = &transferTable{active: make(map[int]*transferProgress)}
+
+/*
+transferProgress is one transfer in flight. It counts the bytes written
+to it, so it can sit in a copy as a tee.
+
+این نوع یک انتقال در جریان است؛ بایت‌هایی را که در آن نوشته می‌شود
+می‌شمارد تا بتوان آن را در مسیر کپی قرار داد
+*/
+type transferProgress struct {
+	transferState
+	done atomic.Int64
+	stop chan struct{} // Closed by finish | با finish بسته می‌شود
+
+	mu    sync.Mutex
+	gate  chan struct{}   // Set while paused, closed on resume | در حالت توقف مقدار دارد و با ادامه بسته می‌شود
+	ended <-chan struct{} // Closed when the link of a send ends | با پایان اتصال یک ارسال بسته می‌شود
+}
+
+// transferState is a snapshot of a transfer, as served on /transfers | تصویر لحظه‌ای یک انتقال
+type transferState struct {
+	ID        int       `json:"id"`
+	Direction string    `json:"direction"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Done      int64     `json:"done"`   // Filled in by state | در state پر می‌شود
+	Paused    bool      `json:"paused"` // Filled in by state | در state پر می‌شود
+	Started   time.Time `json:"started"`
+}
+
+// state returns the transfer with its current byte count | وضعیت انتقال با تعداد بایت فعلی
+func (p *transferProgress) state() transferState {
+	st := p.transferState
+	st.Done = p.done.Load()
+	st.Paused = p.paused()
+	return st
+}
+
+/*
+Write counts b, first waiting out a pause. A send paused when its link
+ends fails with errClosed instead of waiting for a resume that cannot
+come.
+
+این تابع b را پس از انتظار برای پایان توقف می‌شمارد؛ ارسال متوقفی که
+اتصالش تمام شود به جای انتظار برای ادامه‌ای که نخواهد آمد با errClosed
+شکست می‌خورد
+*/
+func (p *transferProgress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	gate := p.gate
+	p.mu.Unlock()
+	if gate != nil {
+		select {
+		case <-gate: // A send's copy stops here | کپی ارسال اینجا متوقف می‌شود
+		case <-p.ended:
+			return 0, errClosed
+		}
+	}
+	p.done.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// paused reports whether the transfer is on hold | آیا انتقال متوقف است
+func (p *transferProgress) paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.gate != nil
+}
+
+// String renders percentage, speed and ETA | نمایش درصد، سرعت و زمان باقی‌مانده
+func (p *transferProgress) String() string {
+	done := p.done.Load()
+	verb := "Sending"
+	if p.Direction == directionRecv {
+		verb = "Receiving"
+	}
+	line := fmt.Sprintf("%s %s: %d%% of %s", verb, p.Name, percent(done, p.Size), formatBytes(p.Size))
+	if p.paused() {
+		return line + ", paused"
+	}
+	elapsed := time.Since(p.Started).Seconds()
+	if elapsed <= 0 || done == 0 {
+		return line
+	}
+	rate := float64(done) / elapsed
+	eta := time.Duration(float64(p.Size-done) / rate * float64(time.Second))
+	return fmt.Sprintf("%s, %s/s, ETA %s", line, formatBytes(int64(rate)), eta.Round(time.Second))
+}
+
+// percent returns done as a whole percentage of size | درصد صحیح done از size
+func percent(done, size int64) int64 {
+	if size <= 0 {
+		return 100
+	}
+	return done * 100 / size
+}
+
+// formatBytes prints a byte count with a binary unit | نمایش تعداد بایت با واحد دودویی
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+/*
+transferTable lists the transfers in flight and totals the bytes moved
+in each direction for the metrics.
+
+این نوع انتقال‌های در جریان را فهرست می‌کند و مجموع بایت‌های جابه‌جاشده
+در هر جهت را برای متریک‌ها نگه می‌دارد
+*/
+type transferTable struct {
+	mu     sync.Mutex
+	next   int
+	active map[int]*transferProgress
+	sent   atomic.Int64 // Bytes of finished sends | بایت‌های ارسال‌های تمام‌شده
+	recv   atomic.Int64 // Bytes of finished receives | بایت‌های دریافت‌های تمام‌شده
+}
+
+/*
+start registers a transfer and prints its progress to w every
+progressInterval until finish is called, so short transfers print
+nothing.
+
+این تابع یک انتقال را ثبت می‌کند و تا فراخوانی finish هر progressInterval
+پیشرفت آن را در w چاپ می‌کند؛ انتقال‌های کوتاه چیزی چاپ نمی‌کنند
+*/
+func (t *transferTable) start(w io.Writer, direction, name string, size int64) *transferProgress {
+	t.mu.Lock()
+	t.next++
+	p := &transferProgress{transferState: transferState{ID: t.next, Direction: direction, Name: name, Size: size, Started: time.Now()}, stop: make(chan struct{})}
+	t.active[p.ID] = p
+	t.mu.Unlock()
+
+	go func() {
+		tick := time.NewTicker(progressInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				if !p.paused() {
+					fmt.Fprintln(w, p)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// finish removes a transfer and adds its bytes to the totals | حذف انتقال و افزودن بایت‌هایش به مجموع
+func (t *transferTable) finish(p *transferProgress) {
+	t.mu.Lock()
+	delete(t.active, p.ID)
+	t.mu.Unlock()
+	close(p.stop)
+	if p.Direction == directionSend {
+		t.sent.Add(p.done.Load())
+	} else {
+		t.recv.Add(p.done.Load())
+	}
+}
+
+/*
+hold pauses or resumes the send with the given ID. Receives cannot be
+held here: the sender would give up waiting for a window.
+
+این تابع ارسال با شناسه‌ی داده‌شده را متوقف یا ادامه می‌دهد؛ دریافت‌ها را
+نمی‌توان اینجا نگه داشت چون فرستنده از انتظار برای پنجره منصرف می‌شود
+*/
+func (t *transferTable) hold(id int, pause bool) error {
+	t.mu.Lock()
+	p, ok := t.active[id]
+	t.mu.Unlock()
+	if !ok {
+		return errNoTransfer
+	}
+	if p.Direction != directionSend {
+		return errPauseRecv
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case pause && p.gate != nil:
+		return errAlreadyHeld
+	case pause:
+		p.gate = make(chan struct{})
+	case p.gate == nil:
+		return errNotPaused
+	default:
+		close(p.gate)
+		p.gate = nil
+	}
+	return nil
+}
+
+// list returns the transfers in flight, oldest first | انتقال‌های در جریان به ترتیب شروع
+func (t *transferTable) list() []*transferProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]*transferProgress, 0, len(t.active))
+	for _, p := range t.active {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b *transferProgress) int { return a.ID - b.ID })
+	return out
+}
+
+// addMetrics exports the transfer counters | صادرکردن شمارنده‌های انتقال
+func (t *transferTable) addMetrics(m *metrics) {
+	m.add("transfers_active", "gauge", "File transfers in flight.", func() float64 {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return float64(len(t.active))
+	})
+	m.add(`transfer_bytes_total{direction="send"}`, "counter", "Bytes of finished file transfers.", func() float64 { return float64(t.sent.Load()) })
+	m.add(`transfer_bytes_total{direction="recv"}`, "counter", "Bytes of finished file transfers.", func() float64 { return float64(t.recv.Load()) })
+}
+
+// serveTransfers answers /transfers with the transfers in flight as JSON | پاسخ /transfers با انتقال‌های در جریان
+func (t *transferTable) serveTransfers(w http.ResponseWriter, _ *http.Request) {
+	list := make([]transferState, 0)
+	for _, p := range t.list() {
+		list = append(list, p.state())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
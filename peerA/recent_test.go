@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecentConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "ann.recent")
+	r, _ := loadRoster("")
+	if err := r.loadRecent(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.last(); ok {
+		t.Error("a dialable connection before any link")
+	}
+	if err := r.file(rosterEntry{Nick: "bob", Fingerprint: "fp-bob", Address: "bob.example:9000", Alias: "Robert"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.remember("10.0.0.3:9000", "10.0.0.3:9000", "")
+	r.named("cy")
+	r.remember("", "10.0.0.2:51000", "fp-bob") // Incoming, but filed with an address | ورودی، با آدرس ثبت‌شده
+	r.named("bob")
+	r.remember("", "10.0.0.4:52000", "fp-dan")
+	if c, ok := r.last(); !ok || c.Address != "bob.example:9000" || c.Nick != "bob" {
+		t.Errorf("last dialable %+v, %v; want bob's filed address", c, ok)
+	}
+
+	r2, _ := loadRoster("")
+	if err := r2.loadRecent(path); err != nil {
+		t.Fatal(err)
+	}
+	list := r2.recentList()
+	if len(list) != 3 || r.label(list[0]) != "fp-dan" || r.label(list[1]) != "Robert" || r.label(list[2]) != "cy" {
+		t.Errorf("reloaded %+v", list)
+	}
+	for range recentMax {
+		r.remember("host:1", "host:1", "")
+	}
+	if list := r.recentList(); len(list) != recentMax || list[recentMax-1].Address != "host:1" {
+		t.Errorf("kept %d connections, want the newest %d", len(list), recentMax)
+	}
+	if got := r.offerLast("other:2"); got != "other:2" {
+		t.Errorf("offered the last peer without a terminal: dialing %q", got)
+	}
+}
+
+func TestRecentCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	r, _ := loadRoster("")
+	s := &session{roster: r}
+	runCommand(s, "/recent")
+	r.remember("bob.example:9000", "198.51.100.7:9000", "fp-bob")
+	r.named("bob")
+	r.remember("", "10.0.0.4:52000", "")
+	runCommand(s, "/recent")
+	want := "No recent connections\n" +
+		"  10.0.0.4:52000  incoming from 10.0.0.4:52000  just now\n" +
+		"  bob  bob.example:9000  just now\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestReconnectSubcommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a peer")
+	}
+	dir := t.TempDir()
+	cmd, out, _ := pipePeerIn(t, dir, "", "reconnect", "-name", "X")
+	if err := cmd.Wait(); err == nil || !strings.Contains(out.String(), "Reconnect error: no recent connection to dial") {
+		t.Errorf("reconnecting with no history: %v\n%s", err, out)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	r, _ := loadRoster("")
+	if err := r.loadRecent(filepath.Join(dir, "peerchat", "X.recent")); err != nil {
+		t.Fatal(err)
+	}
+	r.remember(ln.Addr().String(), ln.Addr().String(), "")
+	r.remember("", "10.0.0.4:52000", "") // Newer, but cannot be dialed | جدیدتر اما قابل dial نیست
+	cmd, _, _ = pipePeerIn(t, dir, "", "reconnect", "-name", "X", "-listen", freeAddr(t))
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	_ = ln.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("the last dialable peer was not dialed: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamPanicKeepsSession(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // Crash dumps | گزارش‌های خرابی
+	client, server, err := benchLink("pipe", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+	cs, ss, err := benchMux(client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	defer ss.Close()
+
+	done := newDoneSignal()
+	defer done.close()
+	go acceptStreams(ss, map[string]func(net.Conn){
+		streamFile: func(net.Conn) { panic("bad file") },
+		streamChat: func(st net.Conn) { _, _ = io.Copy(st, st) },
+	}, done)
+
+	before := recoveredPanics.Load()
+	st, err := openStream(cs, streamFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = st.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := st.Read(make([]byte, 1)); err == nil {
+		t.Fatal("the panicking stream was not closed")
+	}
+	if got := recoveredPanics.Load() - before; got != 1 {
+		t.Fatalf("counted %d panics, want 1", got)
+	}
+	select {
+	case <-done.c:
+		t.Fatal("a file stream panic ended the session")
+	default:
+	}
+
+	chat, err := openStream(cs, streamChat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = chat.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(chat, "still here\n"); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 64)
+	if n, err := chat.Read(b); err != nil || string(b[:n]) != "still here\n" {
+		t.Fatalf("chat after the panic: %q, %v", b[:n], err)
+	}
+}
+
+func TestCoreStreamPanicRedials(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	client, server, err := benchLink("pipe", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+	cs, ss, err := benchMux(client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	defer ss.Close()
+
+	done := newDoneSignal()
+	go acceptStreams(ss, map[string]func(net.Conn){
+		streamControl: func(net.Conn) { panic("bad frame") },
+	}, done)
+	if _, err := openStream(cs, streamControl); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done.c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a control stream panic left the session running")
+	}
+	if !done.redialing() {
+		t.Fatal("a control stream panic ended the link without a redial")
+	}
+}
+
+func TestPanicErrorEvent(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	oldJSON, oldOut := jsonOutput, eventOut
+	jsonOutput, eventOut = true, w
+	defer func() { jsonOutput, eventOut = oldJSON, oldOut }()
+	sub := make(chan sseEvent, 1)
+	events.mu.Lock()
+	events.subs[sub] = struct{}{}
+	events.mu.Unlock()
+	defer func() {
+		events.mu.Lock()
+		delete(events.subs, sub)
+		events.mu.Unlock()
+	}()
+
+	done := newDoneSignal()
+	goSafe("tester", done, func() { panic("boom") })
+	<-done.c
+
+	var line outputEvent
+	if err := json.NewDecoder(r).Decode(&line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Event != eventError || line.Goroutine != "tester" || line.Error != "boom" || line.Dump == "" {
+		t.Fatalf("JSON output event %+v", line)
+	}
+	select {
+	case ev := <-sub:
+		var got outputEvent
+		if err := json.Unmarshal(ev.data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if ev.name != eventError || got.Goroutine != "tester" || got.Error != "boom" || got.Dump != line.Dump {
+			t.Fatalf("/events event %s %+v", ev.name, got)
+		}
+	default:
+		t.Fatal("no /events error event")
+	}
+}
+
+func TestDoneSignalClosesOnce(t *testing.T) {
+	done := newDoneSignal()
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			done.close() // Racing closers must not panic | بستن‌های همزمان نباید panic کنند
+		}()
+	}
+	close(start)
+	wg.Wait()
+	select {
+	case <-done.c:
+	default:
+		t.Fatal("done is still open")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"        // For a missing registry on first run
+	"fmt"           // For notices and command output
+	"os"            // For reading and writing the registry file
+	"path/filepath" // For creating the registry directory
+	"sort"          // For a stable listing
+	"strings"       // For parsing the registry file
+	"sync"          // For guarding the bindings
+)
+
+/*
+Registration frame types
+
+انواع فریم‌های ثبت نام:
+- login: اعلام نام ما به طرف مقابل (کلید در handshake ثابت شده)
+- welcome: پاسخ طرف مقابل؛ ثبت اولیه یا خوش‌آمد دوباره
+*/
+const (
+	ctrlLogin   = "login"   // Claim a nick for our proven key | ادعای نام برای کلید اثبات‌شده
+	ctrlWelcome = "welcome" // Nick accepted | نام پذیرفته شد
+)
+
+/*
+registry binds nicknames to key fingerprints and keeps the bindings on
+disk, so a nick stays reserved for its key across restarts. A message
+or login under a registered nick but another key is impersonation.
+
+این نوع نام‌ها را به fingerprint کلیدها متصل می‌کند و روی دیسک نگه می‌دارد
+تا هر نام بین اجراها برای کلید خودش رزرو بماند؛ پیام یا ورود با نام
+ثبت‌شده ولی کلید دیگر، جعل هویت است
+*/
+type registry struct {
+	mu     sync.Mutex
+	path   string
+	byNick map[string]string
+}
+
+// defaultRegistryPath returns the per-name known peers file | مسیر پیش‌فرض فایل peerهای شناخته‌شده
+func defaultRegistryPath(name string) string {
+	return dataPath(name + ".known_peers")
+}
+
+/*
+loadRegistry reads "nick fingerprint" lines from path; a missing file
+is an empty registry. An empty path keeps it in memory only.
+
+این تابع خطوط "nick fingerprint" را از path می‌خواند؛ نبود فایل یعنی
+registry خالی و path خالی یعنی نگهداری فقط در حافظه
+*/
+func loadRegistry(path string) (*registry, error) {
+	r := &registry{path: path, byNick: make(map[string]string)}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if nick, fp, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			r.byNick[nick] = fp
+		}
+	}
+	return r, nil
+}
+
+/*
+check registers nick for fp on first use and reports whether fp is the
+key registered for nick.
+
+این تابع در اولین استفاده nick را برای fp ثبت می‌کند و مشخص می‌کند
+که آیا fp همان کلید ثبت‌شده برای nick است
+*/
+func (r *registry) check(nick, fp string) bool {
+	known, isNew := r.bind(nick, fp)
+	return isNew || known == fp
+}
+
+// bind returns the key registered for nick, registering fp if there was none | برگرداندن کلید ثبت‌شده یا ثبت fp
+func (r *registry) bind(nick, fp string) (known string, isNew bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if known, ok := r.byNick[nick]; ok {
+		return known, false
+	}
+	r.byNick[nick] = fp
+	if err := r.save(); err != nil {
+		fmt.Fprintln(stdout, "Registry error:", err)
+	}
+	return fp, true
+}
+
+// nicks lists the registered nicks | فهرست نام‌های ثبت‌شده
+func (r *registry) nicks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.byNick))
+	for nick := range r.byNick {
+		out = append(out, nick)
+	}
+	return out
+}
+
+// forget drops the binding for nick (e.g. after a key change) | حذف ثبت nick (مثلاً پس از تغییر کلید)
+func (r *registry) forget(nick string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byNick, nick)
+	return r.save()
+}
+
+// save writes the registry to disk; the caller holds mu | ذخیره registry روی دیسک (mu باید گرفته شده باشد)
+func (r *registry) save() error {
+	if r.path == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	nicks := make([]string, 0, len(r.byNick))
+	for nick := range r.byNick {
+		nicks = append(nicks, nick)
+	}
+	sort.Strings(nicks)
+	var b strings.Builder
+	for _, nick := range nicks {
+		b.WriteString(nick + " " + r.byNick[nick] + "\n")
+	}
+	return os.WriteFile(r.path, []byte(b.String()), 0o600)
+}
+
+/*
+handleLoginFrames registers the login/welcome handlers: a login binds
+the remote's nick to the key it proved in the handshake, and a nick
+that belongs to another key gets the remote kicked.
+
+این تابع handlerهای login و welcome را ثبت می‌کند: login نام طرف مقابل
+را به کلید اثبات‌شده در handshake متصل می‌کند و نامی که متعلق به کلید
+دیگری باشد باعث اخراج طرف مقابل می‌شود
+*/
+func handleLoginFrames(s *session) {
+	s.ctrl.handle(ctrlLogin, func(f controlFrame) {
+		s.presence.setRemoteName(f.Text) // Shown by /who | برای نمایش در /who
+		if _, ok := s.seen.get(f.Text); ok {
+			fmt.Fprintf(s.status, "%s was %s\n", f.Text, s.seen.describe(f.Text))
+		}
+		s.seen.touch(f.Text, true)
+		s.roster.named(f.Text) // Recent connections show who it was | اتصال‌های اخیر نشان می‌دهند چه کسی بود
+		if s.conn.remoteKey == "" {
+			return // Nothing proven to bind to | کلیدی برای ثبت اثبات نشده
+		}
+		known, isNew := s.keys.bind(f.Text, s.conn.remoteKey)
+		switch {
+		case isNew:
+			s.ctrl.send(controlFrame{Type: ctrlWelcome, Text: "registered as " + f.Text})
+		case known == s.conn.remoteKey:
+			s.ctrl.send(controlFrame{Type: ctrlWelcome, Text: "welcome back, " + f.Text})
+		default:
+			fmt.Fprintf(s.status, "Rejected login as %s: nickname belongs to another key\n", f.Text)
+			kick(s, fmt.Sprintf("nickname %s is registered to another key", f.Text))
+		}
+	})
+	s.ctrl.handle(ctrlWelcome, func(f controlFrame) {
+		fmt.Fprintln(s.status, "Remote:", f.Text)
+	})
+}
+
+func init() {
+	registerCommand("forget", "/forget <nick>  drop a nick's registered key", func(s *session, args []string) {
+		if len(args) != 1 {
+			fmt.Fprintln(stdout, "Usage: /forget <nick>")
+			return
+		}
+		if err := s.keys.forget(args[0]); err != nil {
+			fmt.Fprintln(stdout, "Registry error:", err)
+			return
+		}
+		fmt.Fprintln(stdout, "Forgot the key of", args[0])
+	})
+}
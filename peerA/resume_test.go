@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// ticketProof is what the holder of ticket presents to us | اثبات دارنده‌ی ticket برای ما
+func ticketProof(ticket string) string {
+	return hex.EncodeToString(transcriptMAC(ticket, proofTicket, testHellos))
+}
+
+// issued returns a store with a ticket for fp whose link has ended | فروشگاهی با ticket پایان‌یافته برای fp
+func issued(t *testing.T, fp, policy string) (*resumeStore, string) {
+	t.Helper()
+	r, err := loadResume("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := r.issue(fp, policy)
+	r.closed(fp, 7, 5)
+	return r, ticket
+}
+
+func TestResumeTicketCarriesFrames(t *testing.T) {
+	r, ticket := issued(t, "fp", "open:x")
+	got, ok := r.check("fp", ticketProof(ticket), "open:x", testHellos)
+	if !ok || got.Sent != 7 || got.Seen != 5 {
+		t.Fatalf("check = %+v, %v; want sent 7, seen 5", got, ok)
+	}
+}
+
+func TestResumeTicketUsedOnlyOnKeptLink(t *testing.T) {
+	r, ticket := issued(t, "fp", "open:x")
+	for i := 0; i < 2; i++ {
+		if _, ok := r.check("fp", ticketProof(ticket), "open:x", testHellos); !ok {
+			t.Fatal("checking the ticket used it up")
+		}
+	}
+	if r.use("fp", "another ticket") {
+		t.Fatal("used a ticket that was never issued")
+	}
+	if !r.use("fp", ticket) {
+		t.Fatal("live ticket was not used")
+	}
+	if _, ok := r.check("fp", ticketProof(ticket), "open:x", testHellos); ok || r.use("fp", ticket) {
+		t.Fatal("ticket worked twice")
+	}
+}
+
+func TestResumeProofBoundToHandshake(t *testing.T) {
+	r, ticket := issued(t, "fp", "open:x")
+	for name, proof := range map[string]string{
+		"other handshake": hex.EncodeToString(transcriptMAC(ticket, proofTicket, [2]string{testHellos[0], "HELLO 1 9 test none key nonce-c"})),
+		"swapped lines":   hex.EncodeToString(transcriptMAC(ticket, proofTicket, [2]string{testHellos[1], testHellos[0]})),
+		"password proof":  hex.EncodeToString(transcriptMAC(ticket, proofPassword, testHellos)),
+	} {
+		if _, ok := r.check("fp", proof, "open:x", testHellos); ok {
+			t.Errorf("%s: ticket proof was honoured", name)
+		}
+	}
+}
+
+func TestResumeTicketBoundToPolicy(t *testing.T) {
+	r, ticket := issued(t, "fp", "password:old")
+	if _, ok := r.check("fp", ticketProof(ticket), "password:new", testHellos); ok {
+		t.Fatal("ticket survived a password change")
+	}
+	if _, ok := r.check("fp", ticketProof(ticket), "password:old", testHellos); ok {
+		t.Fatal("stale ticket was kept")
+	}
+}
+
+func TestResumeTicketRevoked(t *testing.T) {
+	r, ticket := issued(t, "fp", "invite:x")
+	r.revoke("fp")
+	if _, ok := r.check("fp", ticketProof(ticket), "invite:x", testHellos); ok {
+		t.Fatal("revoked ticket still works")
+	}
+}
+
+func TestResumeFrames(t *testing.T) {
+	for _, c := range []struct {
+		ticket           resumeTicket
+		remoteLast       uint64
+		wantSent, wantIn uint64
+	}{
+		{resumeTicket{}, 0, 0, 0},                              // Fresh link | اتصال تازه
+		{resumeTicket{Ticket: "t", Sent: 9, Seen: 4}, 6, 9, 4}, // Two frames lost in flight | دو فریم در راه گم شد
+		{resumeTicket{Ticket: "t", Sent: 9, Seen: 4}, 0, 9, 0}, // Remote starts over | طرف مقابل از نو شروع می‌کند
+		{resumeTicket{}, 6, 0, 6},                              // No record of the remote | سابقه‌ای از طرف مقابل نیست
+		{resumeTicket{Ticket: "t", Sent: 1, Seen: 8}, 6, 1, 6}, // Record ahead of the remote | سابقه جلوتر از طرف مقابل
+	} {
+		sent, seen := resumeFrames(c.ticket, c.remoteLast)
+		if sent != c.wantSent || seen != c.wantIn {
+			t.Errorf("resumeFrames(%+v, %d) = %d, %d; want %d, %d", c.ticket, c.remoteLast, sent, seen, c.wantSent, c.wantIn)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"    // For the history subcommand flags
+	"fmt"     // For printing results
+	"strconv" // For parsing result numbers
+	"strings" // For case-insensitive matching
+	"time"    // For reading the whole history
+)
+
+/*
+Search configuration
+
+مقادیر پیکربندی جستجو:
+- تعداد نتیجه در هر صفحه
+- تعداد پیام‌های اطراف هنگام نمایش یک نتیجه
+*/
+const (
+	searchPageSize = 10 // Results per page | نتیجه در هر صفحه
+	searchContext  = 3  // Messages shown around a result | پیام‌های اطراف هر نتیجه
+)
+
+/*
+searchState remembers the last /search so /more can page through it
+and /show can jump to one of its results.
+
+این ساختار آخرین جستجو را نگه می‌دارد تا /more صفحه‌ی بعد را نشان دهد
+و /show به یکی از نتایج برود
+*/
+type searchState struct {
+	query   string
+	msgs    []message // History snapshot taken by the search | تصویر تاریخچه هنگام جستجو
+	matches []int     // Indexes into msgs, newest first | اندیس نتایج، جدیدترین اول
+	page    int       // Next page to print | صفحه‌ی بعدی برای چاپ
+}
+
+func init() {
+	registerCommand("search", "/search <words>  find messages in the history", searchCommand)
+	registerCommand("more", "/more  next page of search results", func(s *session, args []string) {
+		printSearchPage(&s.search)
+	})
+	registerCommand("show", "/show <n>  show search result n with surrounding messages", showCommand)
+}
+
+/*
+searchCommand finds history messages containing every given word
+(case-insensitive) and prints the first page, newest first.
+
+این دستور پیام‌هایی از تاریخچه را که همه‌ی کلمات داده‌شده را (بدون
+حساسیت به حروف) دارند پیدا و صفحه‌ی اول را از جدیدترین چاپ می‌کند
+*/
+func searchCommand(s *session, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(stdout, "Usage: /search <words>")
+		return
+	}
+	if s.history == nil {
+		fmt.Fprintln(stdout, "History is not kept in anonymous mode")
+		return
+	}
+	msgs, err := readHistory(s.history.path, time.Time{})
+	if err != nil {
+		fmt.Fprintln(stdout, "Search error:", err)
+		return
+	}
+	for i := range msgs {
+		msgs[i] = s.roster.relabel(msgs[i]) // Found and shown under display aliases | جستجو و نمایش با نام‌های نمایشی
+	}
+
+	words := make([]string, len(args))
+	for i, a := range args {
+		words[i] = strings.ToLower(a)
+	}
+	st := searchState{query: strings.Join(args, " "), msgs: msgs}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if matchesAll(msgs[i], words) {
+			st.matches = append(st.matches, i)
+		}
+	}
+	s.search = st
+	printSearchPage(&s.search)
+}
+
+// matchesAll reports whether every word occurs in the sender or text | آیا همه‌ی کلمات در فرستنده یا متن هستند
+func matchesAll(m message, words []string) bool {
+	hay := strings.ToLower(m.From + " " + m.Text)
+	for _, w := range words {
+		if !strings.Contains(hay, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// printSearchPage prints the next page of results | چاپ صفحه‌ی بعدی نتایج
+func printSearchPage(st *searchState) {
+	if st.query == "" {
+		fmt.Fprintln(stdout, "No search yet (try /search <words>)")
+		return
+	}
+	if len(st.matches) == 0 {
+		fmt.Fprintf(stdout, "No matches for %q\n", st.query)
+		return
+	}
+	pages := (len(st.matches) + searchPageSize - 1) / searchPageSize
+	if st.page >= pages {
+		fmt.Fprintf(stdout, "No more results for %q\n", st.query)
+		return
+	}
+
+	fmt.Fprintf(stdout, "%d matches for %q (page %d/%d)\n", len(st.matches), st.query, st.page+1, pages)
+	start := st.page * searchPageSize
+	for n := start; n < len(st.matches) && n < start+searchPageSize; n++ {
+		fmt.Fprintf(stdout, "  [%d] %s\n", n+1, formatHistoryLine(st.msgs[st.matches[n]]))
+	}
+	st.page++
+	if st.page < pages {
+		fmt.Fprintln(stdout, "  /more for the next page, /show <n> to see a result in context")
+	}
+}
+
+/*
+showCommand prints one search result with the messages around it, the
+closest thing to jumping to it in the scrollback.
+
+این دستور یک نتیجه‌ی جستجو را همراه با پیام‌های اطرافش چاپ می‌کند
+*/
+func showCommand(s *session, args []string) {
+	n := 0
+	if len(args) == 1 {
+		n, _ = strconv.Atoi(args[0])
+	}
+	st := &s.search
+	if n < 1 || n > len(st.matches) {
+		fmt.Fprintln(stdout, "Usage: /show <n>  (a number from the last /search)")
+		return
+	}
+
+	at := st.matches[n-1]
+	for i := max(0, at-searchContext); i <= min(len(st.msgs)-1, at+searchContext); i++ {
+		marker := "   "
+		if i == at {
+			marker = ">> "
+		}
+		fmt.Fprintln(stdout, marker+formatHistoryLine(st.msgs[i]))
+	}
+}
+
+// formatHistoryLine renders a stored message on one line | نمایش یک پیام ذخیره‌شده در یک خط
+func formatHistoryLine(m message) string {
+	line := m.Time.Local().Format("2006-01-02 15:04") + " " + m.From + ": " + m.Text
+	if m.ID != "" {
+		line += "  #" + m.ID
+	}
+	return line
+}
+
+/*
+runHistory implements "history": it prints the stored transcript of one
+name, oldest first, optionally only the messages containing every given
+word and only the last -n of them:
+
+	peerA history --since 24h -n 50 release
+
+این تابع زیرفرمان history را اجرا می‌کند و تاریخچه‌ی ذخیره‌شده‌ی یک نام را
+از قدیمی‌ترین چاپ می‌کند؛ در صورت نیاز فقط پیام‌هایی که همه‌ی کلمات داده‌شده
+را دارند و فقط -n پیام آخر
+*/
+func runHistory(defaultName string, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	name := fs.String("name", defaultName, "whose history to print")
+	since := fs.String("since", "", `oldest message to include: a duration ("24h") or a date ("2006-01-02")`)
+	last := fs.Int("n", 0, "print only the last n messages (0 prints all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	from, err := parseSince(*since)
+	if err != nil {
+		return err
+	}
+	msgs, err := readHistory(defaultHistoryPath(*name), from)
+	if err != nil {
+		return err
+	}
+	buddies, err := loadRoster(defaultRosterPath(*name))
+	if err != nil {
+		return err
+	}
+	words := make([]string, fs.NArg())
+	for i, a := range fs.Args() {
+		words[i] = strings.ToLower(a)
+	}
+	var shown []message
+	for _, m := range msgs {
+		m = buddies.relabel(m) // Matched and shown under display aliases | جستجو و نمایش با نام‌های نمایشی
+		if matchesAll(m, words) {
+			shown = append(shown, m)
+		}
+	}
+	if *last > 0 && len(shown) > *last {
+		shown = shown[len(shown)-*last:]
+	}
+	for _, m := range shown {
+		fmt.Fprintln(stdout, formatHistoryLine(m))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"         // For the outcome
+	"sync/atomic" // For the exit status set by the sender
+	"time"        // For the default deadline
+)
+
+const defaultSendTimeout = 30 * time.Second // Budget for the link and the acknowledgement | مهلت اتصال و تأیید دریافت
+
+/*
+Exit statuses of the send subcommand
+
+وضعیت‌های خروج زیرفرمان send:
+- exitDelivered: طرف مقابل دریافت پیام را تأیید کرد
+- exitFailed: پیام ارسال نشد (اتصالی برقرار نشد، رد شد یا فیلتر شد)
+- exitUsage: آرگومان‌ها نادرست بودند
+- exitUnconfirmed: پیام ارسال شد اما تأییدی به موقع نرسید و شاید رسیده باشد
+*/
+const (
+	exitDelivered   = 0
+	exitFailed      = 1
+	exitUsage       = 2
+	exitUnconfirmed = 3
+)
+
+/*
+sendOnce implements the chat side of "send": it sends text as one
+message, waits until the remote acknowledges its ID and then ends the
+session, so cron jobs and CI steps can tell from the exit status
+whether the message arrived:
+
+	peerA send --to host:8081 "deploy finished"
+
+code moves from exitFailed to exitUnconfirmed once the message is on
+the wire and to exitDelivered with the acknowledgement. The ack handler
+is set here, before the control stream is read.
+
+این تابع سمت چت زیرفرمان send را اجرا می‌کند: text را به‌صورت یک پیام
+می‌فرستد، تا تأیید شناسه‌ی آن توسط طرف مقابل منتظر می‌ماند و سپس نشست را
+پایان می‌دهد تا cron و مراحل CI از وضعیت خروج بفهمند پیام رسیده یا نه؛
+code با رسیدن پیام به شبکه به exitUnconfirmed و با تأیید به exitDelivered
+تغییر می‌کند. handler تأیید پیش از خواندن stream کنترلی اینجا تنظیم می‌شود
+*/
+func sendOnce(s *session, text string, code *atomic.Int32) {
+	acked := make(chan string, 16)
+	s.ctrl.handle(ctrlAck, func(f controlFrame) {
+		s.acks.ack(f.Text) // Latency as usual | ثبت تأخیر مانند همیشه
+		select {
+		case acked <- f.Text:
+		default:
+		}
+	})
+	goSafe("sendOnce", s.done, func() {
+		m, err := sendChat(s, text, nil, false)
+		if err != nil {
+			fmt.Fprintln(s.status, "Send error:", err)
+			s.done.close()
+			return
+		}
+		if !waitSent(s, s.queued.Load()) {
+			return // Link gone before it went out | اتصال پیش از ارسال قطع شد
+		}
+		code.Store(exitUnconfirmed)
+		for {
+			select {
+			case id := <-acked:
+				if id == m.ID {
+					code.Store(exitDelivered)
+					fmt.Fprintln(s.status, "Delivered:", m.ID)
+					s.done.close()
+					return
+				}
+			case <-s.done.c:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors" // For the unsupported platform
+	"os"     // For the device file
+)
+
+var errSerialPlatform = errors.New("serial lines are only supported on Linux") // No termios code here | کد termios برای این سیستم نیست
+
+// openSerial is not available on this platform | openSerial روی این سیستم در دسترس نیست
+func openSerial(device string, baud int) (*os.File, error) {
+	return nil, errSerialPlatform
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"          // For the notice writer
+	"sync/atomic" // For counters shared between goroutines
+
+	"github.com/hashicorp/yamux" // Stream multiplexer over the single TCP link
+)
+
+/*
+session groups everything that lives as long as one established link,
+so commands and stream handlers share a single view of the connection.
+
+این نوع همه‌ی چیزهایی را که به اندازه‌ی عمر یک اتصال زنده‌اند
+کنار هم نگه می‌دارد تا دستورها و handlerها دید یکسانی از اتصال داشته باشند
+*/
+type session struct {
+	name      string         // Our name shown to the remote | نام ما نزد طرف مقابل
+	conn      *handshakeConn // The established link | اتصال برقرارشده
+	mux       *yamux.Session // Streams over the link | streamهای روی اتصال
+	ctrl      *controlLink   // Control stream | stream کنترل
+	files     *fileStore     // Files received from the remote | فایل‌های دریافتی
+	accepts   []string       // MIME patterns of files we take | الگوهای MIME فایل‌های پذیرفتنی
+	parts     *partTable     // Parallel transfers being received | انتقال‌های موازی در حال دریافت
+	history   *history       // On-disk transcript | تاریخچه‌ی ذخیره‌شده
+	threads   *threadIndex   // Recent messages by ID | پیام‌های اخیر بر اساس شناسه
+	notify    *notifier      // Bell and flash settings | تنظیمات زنگ و چشمک
+	presence  *presence      // Our and the remote's presence | وضعیت حضور ما و طرف مقابل
+	seen      *lastSeen      // When each nick was last around | آخرین زمان حضور هر نام
+	metrics   *metrics       // Self-metrics for /stats and /metrics | متریک‌های برنامه
+	acks      *ackTracker    // Delivery latency of our messages | تأخیر تحویل پیام‌های ما
+	sched     *linkScheduler // Priority order of stream writes | ترتیب اولویت نوشتن روی streamها
+	search    searchState    // Last /search results | نتایج آخرین جستجو
+	compose   composer       // Multi-line input capture | ضبط ورودی چندخطی
+	inputs    *inputHistory  // Typed lines for recall | خطوط تایپ‌شده برای بازیابی
+	aliases   *aliasTable    // User-defined command aliases | نام‌های مستعار دستورها
+	keymap    keyBindings    // Editor key bindings | کلیدهای میانبر ویرایشگر
+	theme     *themeTable    // Terminal colours | رنگ‌های ترمینال
+	roster    *roster        // Known peers and notes | peerهای شناخته‌شده و یادداشت‌ها
+	id        *identity      // Our signing key | کلید امضای ما
+	keys      *registry      // Nick to key bindings | اتصال نام‌ها به کلیدها
+	ignores   *entrySet      // Blocked nicks and keys | نام‌ها و کلیدهای مسدودشده
+	auth      *peerAuth      // Access rules and lists | قوانین و لیست‌های دسترسی
+	mutes     *muteFilter    // Temporarily silenced nicks | نام‌های موقتاً ساکت‌شده
+	inbound   filterChain    // Filters for received messages | فیلترهای پیام دریافتی
+	outbound  filterChain    // Filters for our own messages | فیلترهای پیام خروجی
+	incoming  chan<- message // Messages for the display loop | پیام‌های حلقه نمایش
+	outgoing  chan<- string  // Signed lines for the chat writer | خطوط امضاشده برای ارسال
+	status    io.Writer      // Where notices are printed | محل چاپ اعلان‌ها
+	sent      atomic.Int64   // Chat lines flushed to the wire | تعداد پیام‌های ارسال‌شده
+	taken     atomic.Int64   // Chat lines the writer picked up, for the watchdog | خطوط برداشته‌شده توسط نویسنده
+	queued    atomic.Int64   // Chat lines put on the outgoing queue | خطوط قرارگرفته در صف ارسال
+	framesOut atomic.Uint64  // Last chat frame number written | آخرین شماره‌ی فریم چت نوشته‌شده
+	framesIn  atomic.Uint64  // Last chat frame number read | آخرین شماره‌ی فریم چت خوانده‌شده
+	done      *doneSignal    // Shutdown signal | سیگنال خروج
+}
+
+/*
+relinked returns the session for a new link after s was torn down to be
+redialed. The link's own parts are new; everything else, the counters
+included, carries over so commands and /stats go on where they were.
+
+این تابع نشست یک اتصال تازه را پس از بسته‌شدن s برای dial دوباره
+برمی‌گرداند. بخش‌های خود اتصال تازه‌اند و بقیه، از جمله شمارنده‌ها، منتقل
+می‌شوند تا دستورها و /stats از همان‌جا ادامه دهند
+*/
+func (s *session) relinked(conn *handshakeConn, mux *yamux.Session, ctrl *controlLink, done *doneSignal) *session {
+	n := &session{
+		name:     s.name,
+		conn:     conn,
+		mux:      mux,
+		ctrl:     ctrl,
+		files:    s.files,
+		accepts:  s.accepts,
+		parts:    newPartTable(),
+		history:  s.history,
+		threads:  s.threads,
+		notify:   s.notify,
+		presence: s.presence,
+		seen:     s.seen,
+		metrics:  s.metrics,
+		acks:     s.acks,
+		sched:    newLinkScheduler(),
+		inputs:   s.inputs,
+		aliases:  s.aliases,
+		keymap:   s.keymap,
+		theme:    s.theme,
+		roster:   s.roster,
+		id:       s.id,
+		keys:     s.keys,
+		ignores:  s.ignores,
+		auth:     s.auth,
+		mutes:    s.mutes,
+		inbound:  s.inbound,
+		outbound: s.outbound,
+		incoming: s.incoming,
+		outgoing: s.outgoing,
+		status:   s.status,
+		done:     done,
+	}
+	n.sent.Store(s.sent.Load())
+	n.taken.Store(s.taken.Load())
+	n.queued.Store(s.queued.Load())
+	return n
+}
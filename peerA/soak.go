@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"       // For the failed-invariant result
+	"flag"         // For the soak subcommand flags
+	"fmt"          // For progress and summary lines
+	"math/rand/v2" // For random restart times
+	"net"          // For the stream handlers
+	"strconv"      // For sequence numbers in message IDs
+	"sync"         // For the checker lock and sender wait group
+	"sync/atomic"  // For the sent counter
+	"time"         // For pacing and the run length
+)
+
+const soakDrainTimeout = 5 * time.Second // Max wait for in-flight messages at the end | حداکثر انتظار برای پیام‌های در راه در پایان
+
+var errSoakFailed = errors.New("messages were lost, duplicated or reordered") // An invariant did not hold | یکی از شرط‌ها برقرار نبود
+
+/*
+runSoak implements "soak": two in-process peers exchange numbered
+messages in both directions for a long time while the link between
+them is torn down and rebuilt at random moments. The receiver of each
+direction checks that no message is lost, duplicated or reordered;
+progress is printed every --report and the command fails if any
+invariant broke:
+
+	peerA soak --duration 4h --rate 50/s --restart 30s
+
+The link is killed abruptly, like a dropped connection. Each receiver
+acknowledges every message on the control stream, and each sender keeps
+a session ACK tracker across links and sends whatever it still holds
+again on the next one; the receiver drops the copies of messages that
+had arrived. With --transport udp the link runs over the ARQ layer and
+--loss drops that fraction of packets, which the layer must recover
+without a single lost message:
+
+	peerA soak --transport udp --loss 0.05 --restart 0
+
+این تابع زیرفرمان soak را اجرا می‌کند: دو peer درون یک پردازه برای مدتی
+طولانی در هر دو جهت پیام‌های شماره‌دار مبادله می‌کنند و اتصال بینشان در
+لحظه‌های تصادفی قطع و دوباره ساخته می‌شود. گیرنده‌ی هر جهت بررسی می‌کند که
+هیچ پیامی گم، تکراری یا جابه‌جا نشود؛ پیشرفت هر --report چاپ می‌شود و اگر
+شرطی نقض شود فرمان با خطا تمام می‌شود. اتصال مانند قطع واقعی ناگهانی
+بسته می‌شود؛ هر گیرنده هر پیام را روی stream کنترل تأیید می‌کند و هر فرستنده
+ردیاب تأیید نشست را در طول همه‌ی اتصال‌ها نگه می‌دارد و هر چه هنوز تأیید
+نشده روی اتصال بعدی دوباره می‌فرستد؛ گیرنده نسخه‌های تکراری پیام‌هایی را که
+رسیده بودند کنار می‌گذارد. با --transport udp اتصال
+روی لایه‌ی ARQ اجرا می‌شود و --loss آن کسر از بسته‌ها را حذف می‌کند که
+لایه باید بدون گم‌شدن حتی یک پیام جبران کند
+*/
+func runSoak(args []string) error {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	duration := fs.Duration("duration", time.Minute, "how long to run")
+	rateFlag := fs.String("rate", "50/s", "messages per direction: <n>/s, /m or /h")
+	size := fs.Int("size", 64, "bytes per message")
+	restart := fs.Duration("restart", 10*time.Second, "mean time between link restarts (0 keeps one link)")
+	transport := fs.String("transport", "tcp", `"tcp", "pipe" or "udp"`)
+	loss := fs.Float64("loss", 0, "fraction of packets dropped on purpose (udp only)")
+	every := fs.Duration("report", time.Minute, "how often to print progress")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rate, err := parseRate(*rateFlag)
+	if err != nil || rate <= 0 {
+		return fmt.Errorf("%w: %q", errLoadSpec, *rateFlag)
+	}
+	if *size <= 0 {
+		return fmt.Errorf("%w: size %d", errLoadSpec, *size)
+	}
+	if *transport != "pipe" && *transport != "tcp" && *transport != "udp" {
+		return fmt.Errorf("%w: %q", errBenchTransport, *transport)
+	}
+	if *loss < 0 || *loss >= 1 || (*loss > 0 && *transport != "udp") {
+		return fmt.Errorf("%w: loss %v", errLoadSpec, *loss)
+	}
+
+	ab, err := newSoakDirection("A->B")
+	if err != nil {
+		return err
+	}
+	defer ab.id.wipe()
+	ba, err := newSoakDirection("B->A")
+	if err != nil {
+		return err
+	}
+	defer ba.id.wipe()
+
+	start := time.Now()
+	deadline := start.Add(*duration)
+	nextReport := start.Add(*every)
+	links := 0
+	for last := false; !last; { // The final link always drains, even one started past the deadline | آخرین اتصال همیشه تخلیه می‌شود، حتی اگر پس از مهلت شروع شود
+		now := time.Now()
+		life := max(deadline.Sub(now), 0)
+		if *restart > 0 {
+			life = min(life, *restart/2+rand.N(*restart)) // Uniform around the mean | توزیع یکنواخت حول میانگین
+		}
+		last = !now.Add(life).Before(deadline)
+		links++
+		if err := soakLink(*transport, *loss, ab, ba, rate, *size, life, last); err != nil {
+			return err
+		}
+		if time.Now().After(nextReport) {
+			fmt.Fprintf(stdout, "%s: %d links\n", time.Since(start).Round(time.Second), links)
+			ab.report()
+			ba.report()
+			nextReport = nextReport.Add(*every)
+		}
+	}
+
+	fmt.Fprintf(stdout, "Soak finished after %s over %d links\n", time.Since(start).Round(time.Second), links)
+	okAB, okBA := ab.report(), ba.report()
+	if !okAB || !okBA {
+		return errSoakFailed
+	}
+	return nil
+}
+
+/*
+soakLink builds one link, sends again what the last one left
+unacknowledged, runs both senders over it for life and then kills it.
+On the last link the senders stop first and the link stays up until
+everything sent has arrived, so the run ends without losses of its own
+making.
+
+این تابع یک اتصال می‌سازد، آنچه اتصال قبلی تأییدنشده گذاشته دوباره
+می‌فرستد، هر دو فرستنده را به مدت life روی آن اجرا و سپس اتصال را قطع
+می‌کند. در آخرین اتصال ابتدا فرستنده‌ها متوقف می‌شوند و اتصال تا رسیدن
+همه‌ی پیام‌ها باز می‌ماند تا پایان اجرا خودش باعث گم‌شدن نشود
+*/
+func soakLink(transport string, loss float64, ab, ba *soakDirection, rate float64, size int, life time.Duration, last bool) error {
+	client, server, err := benchLink(transport, loss)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	defer server.Close()
+	cs, ss, err := benchMux(client, server)
+	if err != nil {
+		return err
+	}
+	defer cs.Close()
+	defer ss.Close()
+	aOut, err := openStream(cs, streamChat)
+	if err != nil {
+		return err
+	}
+	bOut, err := openStream(ss, streamChat)
+	if err != nil {
+		return err
+	}
+	aCtrlOut, err := openStream(cs, streamControl)
+	if err != nil {
+		return err
+	}
+	bCtrlOut, err := openStream(ss, streamControl)
+	if err != nil {
+		return err
+	}
+
+	done := newDoneSignal()
+	aCtrl, bCtrl := newControlLink(done), newControlLink(done)
+	aCtrl.handle(ctrlAck, func(f controlFrame) { ab.acks.ack(f.Text) })
+	bCtrl.handle(ctrlAck, func(f controlFrame) { ba.acks.ack(f.Text) })
+	go aCtrl.writer(aCtrlOut, nil)
+	go bCtrl.writer(bCtrlOut, nil)
+	aQueue, bQueue := make(chan string, 32), make(chan string, 32)
+	var aSent, bSent, aTaken, bTaken atomic.Int64
+	var aFrames, bFrames, abSeen, baSeen atomic.Uint64
+	go connWriter(aOut, aQueue, &aSent, &aTaken, &aFrames, nil, done)
+	go connWriter(bOut, bQueue, &bSent, &bTaken, &bFrames, nil, done)
+	keysA, _ := loadRegistry("")
+	keysB, _ := loadRegistry("")
+	abIn, baIn := make(chan message, 64), make(chan message, 64)
+	go acceptStreams(ss, map[string]func(net.Conn){
+		streamChat:    func(st net.Conn) { connReader(st, abIn, keysB, nil, &abSeen, done); close(abIn) },
+		streamControl: bCtrl.reader,
+	}, done)
+	go acceptStreams(cs, map[string]func(net.Conn){
+		streamChat:    func(st net.Conn) { connReader(st, baIn, keysA, nil, &baSeen, done); close(baIn) },
+		streamControl: aCtrl.reader,
+	}, done)
+	var receivers sync.WaitGroup
+	receivers.Add(2)
+	go ab.receive(abIn, bCtrl, done.c, &receivers)
+	go ba.receive(baIn, aCtrl, done.c, &receivers)
+
+	senders := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { ab.resend(aQueue, size, done.c); ab.send(aQueue, rate, size, senders, done.c, &wg) }()
+	go func() { ba.resend(bQueue, size, done.c); ba.send(bQueue, rate, size, senders, done.c, &wg) }()
+
+	select {
+	case <-time.After(life):
+	case <-done.c: // The link died by itself | اتصال خودبه‌خود قطع شد
+	}
+	close(senders)
+	wg.Wait()
+	if last {
+		for t := time.Now(); time.Since(t) < soakDrainTimeout && !(ab.drained() && ba.drained()); {
+			time.Sleep(pipeFlushPoll)
+		}
+	}
+	done.close()
+	receivers.Wait() // Nothing from this link is checked after the next one starts | پس از شروع اتصال بعدی چیزی از این اتصال بررسی نمی‌شود
+	go drainMessages(abIn)
+	go drainMessages(baIn)
+	return nil
+}
+
+// drainMessages lets a link's reader finish once nobody receives from it | آزادکردن خواننده‌ی اتصال وقتی کسی از آن نمی‌خواند
+func drainMessages(in <-chan message) {
+	for range in {
+	}
+}
+
+/*
+soakDirection is one direction of the soak test: its sender numbers
+the messages and its receiver checks the order they arrive in.
+
+این نوع یک جهت از آزمون soak است: فرستنده‌ی آن پیام‌ها را شماره‌گذاری
+و گیرنده‌ی آن ترتیب رسیدنشان را بررسی می‌کند
+*/
+type soakDirection struct {
+	name string
+	id   *identity
+	acks *ackTracker  // Outlives every link, like the session's | مانند ردیاب نشست در طول همه‌ی اتصال‌ها می‌ماند
+	sent atomic.Int64 // Messages queued so far; the next sequence number | تعداد پیام‌های ارسال‌شده
+
+	mu        sync.Mutex
+	next      int64           // Next sequence number expected | شماره‌ی مورد انتظار بعدی
+	missing   map[int64]bool  // Skipped numbers not yet seen | شماره‌های جاافتاده
+	copies    map[int64]int64 // Numbers sent again, by copies not yet seen | شماره‌های دوباره ارسال‌شده و تعداد نسخه‌های نرسیده
+	delivered int64
+	resent    int64
+	dups      int64
+	reordered int64
+	corrupt   int64 // Unsigned or unnumbered lines | خطوط بدون امضا یا شماره
+}
+
+// newSoakDirection creates a direction with its own sender identity | ساخت یک جهت با هویت فرستنده‌ی جداگانه
+func newSoakDirection(name string) (*soakDirection, error) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return &soakDirection{name: name, id: id, acks: newAckTracker(newMetrics()), missing: make(map[int64]bool), copies: make(map[int64]int64)}, nil
+}
+
+// send queues numbered messages at rate until stop or done closes | ارسال پیام‌های شماره‌دار با نرخ ثابت
+func (d *soakDirection) send(queue chan<- string, rate float64, size int, stop, done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		seq := d.sent.Load()
+		select {
+		case queue <- d.encode(seq, size):
+			d.acks.track(strconv.FormatInt(seq, 10))
+			d.sent.Add(1)
+		case <-stop:
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+/*
+resend queues again every message the receiver has not acknowledged,
+oldest first, before the sender carries on. The receiver is told how
+many copies to expect, so only those count as redelivered rather than
+duplicated.
+
+این تابع هر پیامی را که گیرنده تأیید نکرده، از قدیمی‌ترین، پیش از ادامه‌ی
+فرستنده دوباره در صف می‌گذارد؛ تعداد نسخه‌های مورد انتظار به گیرنده گفته
+می‌شود تا فقط همان‌ها دوباره‌رسیده حساب شوند و نه تکراری
+*/
+func (d *soakDirection) resend(queue chan<- string, size int, done <-chan struct{}) {
+	for _, id := range d.acks.unacked() {
+		seq, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		d.mu.Lock()
+		d.copies[seq]++
+		d.resent++
+		d.mu.Unlock()
+		select {
+		case queue <- d.encode(seq, size):
+		case <-done:
+			return // Left for the next link | برای اتصال بعدی می‌ماند
+		}
+	}
+}
+
+// encode signs message number seq | امضای پیام شماره‌ی seq
+func (d *soakDirection) encode(seq int64, size int) string {
+	return encodeChat(d.id, message{Time: time.Now(), From: d.name, Text: loadPayload(seq, size), ID: strconv.FormatInt(seq, 10)})
+}
+
+// receive checks and acknowledges every message from one link until done closes | بررسی و تأیید پیام‌های یک اتصال
+func (d *soakDirection) receive(in <-chan message, ctrl *controlLink, done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		select {
+		case m, ok := <-in:
+			if !ok {
+				return
+			}
+			d.check(m)
+			ctrl.send(controlFrame{Type: ctrlAck, Text: m.ID}) // Copies too, so the sender forgets them | نسخه‌ها هم، تا فرستنده آن‌ها را فراموش کند
+		case <-done:
+			return
+		}
+	}
+}
+
+/*
+check records one received message: the expected number advances the
+sequence, a higher one marks the skipped numbers missing, and a lower
+one is either a missing message arriving late (reordered), a copy the
+sender resent and the receiver drops, or a duplicate.
+
+این تابع یک پیام دریافتی را ثبت می‌کند: شماره‌ی مورد انتظار ترتیب را جلو
+می‌برد، شماره‌ی بزرگ‌تر شماره‌های جاافتاده را گم‌شده علامت می‌زند و شماره‌ی
+کوچک‌تر یا پیام گم‌شده‌ای است که دیر رسیده (جابه‌جا)، یا نسخه‌ای که فرستنده
+دوباره فرستاده و گیرنده کنار می‌گذارد، یا تکراری است
+*/
+func (d *soakDirection) check(m message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seq, err := strconv.ParseInt(m.ID, 10, 64)
+	if err == nil && d.copies[seq] > 0 {
+		if d.copies[seq]--; d.copies[seq] == 0 {
+			delete(d.copies, seq)
+		}
+		if seq < d.next && !d.missing[seq] {
+			return // Arrived before, its ack did not | قبلاً رسیده بود، تأییدش نه
+		}
+	}
+	switch {
+	case err != nil || !m.Verified:
+		d.corrupt++
+	case seq == d.next:
+		d.delivered++
+		d.next++
+	case seq > d.next:
+		for i := d.next; i < seq; i++ {
+			d.missing[i] = true
+		}
+		d.delivered++
+		d.next = seq + 1
+	case d.missing[seq]:
+		delete(d.missing, seq)
+		d.delivered++
+		d.reordered++
+	default:
+		d.dups++
+	}
+}
+
+// drained reports whether everything sent so far has been seen | آیا همه‌ی پیام‌های ارسالی دیده شده‌اند
+func (d *soakDirection) drained() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.next >= d.sent.Load()
+}
+
+// report prints the counters and whether every invariant held | چاپ شمارنده‌ها و برقراری شرط‌ها
+func (d *soakDirection) report() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sent := d.sent.Load()
+	lost := int64(len(d.missing)) + max(sent-d.next, 0) // Gaps plus a missing tail | جاافتاده‌ها به‌علاوه‌ی انتهای نرسیده
+	fmt.Fprintf(stdout, "  %s: %d sent, %d resent, %d delivered, %d lost, %d duplicated, %d reordered, %d corrupt\n",
+		d.name, sent, d.resent, d.delivered, lost, d.dups, d.reordered, d.corrupt)
+	return lost == 0 && d.dups == 0 && d.reordered == 0 && d.corrupt == 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSoakSurvivesRestarts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a soak for a few seconds")
+	}
+	defer stdout.redirect(stdout.redirect(io.Discard))
+	for _, transport := range []string{"tcp", "udp"} {
+		// Twenty or so restarts, each killing a link with messages in flight | حدود بیست قطع، هر بار با پیام‌های در راه
+		err := runSoak([]string{"--duration", "3s", "--rate", "500/s", "--restart", "150ms", "--transport", transport})
+		if err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+	}
+}
+
+func TestSoakCheckCatchesBrokenInvariants(t *testing.T) {
+	defer stdout.redirect(stdout.redirect(io.Discard))
+	signed := func(d *soakDirection, seq int64) message {
+		keys, _ := loadRegistry("") // Each direction has its own key for "a" | هر جهت کلید خود را برای "a" دارد
+		m, _ := decodeChatLine(d.encode(seq, 16), keys)
+		return m
+	}
+	for _, c := range []struct {
+		name  string
+		order []int64 // Arrival order; -1 is an unsigned line | ترتیب رسیدن؛ ‎-1 خط بدون امضا است
+		ok    bool
+	}{
+		{"in order", []int64{0, 1, 2}, true},
+		{"duplicated", []int64{0, 1, 1, 2}, false},
+		{"reordered", []int64{0, 2, 1}, false},
+		{"lost", []int64{0, 2}, false},
+		{"lost tail", []int64{0, 1}, false},
+		{"corrupt", []int64{0, 1, 2, -1}, false},
+	} {
+		d, err := newSoakDirection("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.sent.Store(3)
+		for _, seq := range c.order {
+			if seq < 0 {
+				d.check(message{From: "a", Text: "x", ID: "9"})
+				continue
+			}
+			d.check(signed(d, seq))
+		}
+		if got := d.report(); got != c.ok {
+			t.Errorf("%s: invariants held %v, want %v", c.name, got, c.ok)
+		}
+	}
+
+	d, err := newSoakDirection("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.sent.Store(2)
+	d.check(signed(d, 0))
+	d.check(signed(d, 1))
+	d.copies[1] = 1 // Its ack was lost, so it goes again | تأییدش گم شد، پس دوباره می‌رود
+	d.check(signed(d, 1))
+	if !d.report() || d.dups != 0 {
+		t.Errorf("an expected resend counted as a duplicate")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"  // For reporting options the OS refused
+	"net"  // For TCP socket options
+	"time" // For the keepalive period
+)
+
+const defaultKeepAlive = 15 * time.Second // Same as Go's dialer default | برابر پیش‌فرض dialer در Go
+
+/*
+socketOptions are the TCP settings applied to every candidate link,
+accepted or dialed, before its handshake. Zero buffer sizes keep the OS
+defaults and a negative linger keeps the default close behaviour.
+
+این ساختار تنظیمات TCP است که پیش از handshake روی هر اتصال کاندید
+(ورودی یا خروجی) اعمال می‌شود؛ اندازه‌ی بافر صفر یعنی پیش‌فرض سیستم‌عامل
+و linger منفی یعنی رفتار پیش‌فرض هنگام بستن
+*/
+type socketOptions struct {
+	keepAlive time.Duration // Keepalive probe period, 0 disables | فاصله‌ی keepalive، صفر یعنی خاموش
+	noDelay   bool          // Disable Nagle for low latency | غیرفعال‌کردن Nagle برای تأخیر کم
+	sendBuf   int           // SO_SNDBUF in bytes | اندازه‌ی بافر ارسال
+	recvBuf   int           // SO_RCVBUF in bytes | اندازه‌ی بافر دریافت
+	linger    int           // SO_LINGER in seconds, negative for default | linger بر حسب ثانیه
+}
+
+/*
+apply sets the options on c; non-TCP links are left alone. It stops at
+the first option the OS refuses.
+
+این تابع تنظیمات را روی c اعمال می‌کند؛ اتصال‌های غیر TCP دست‌نخورده
+می‌مانند و با اولین خطای سیستم‌عامل متوقف می‌شود
+*/
+func (o socketOptions) apply(c net.Conn) error {
+	tc, ok := c.(*net.TCPConn)
+	if !ok {
+		return nil // Unix socket or test pipe | socket یونیکس یا pipe آزمایشی
+	}
+	if err := setKeepAlive(tc, o.keepAlive); err != nil {
+		return fmt.Errorf("keepalive: %w", err)
+	}
+	if err := tc.SetNoDelay(o.noDelay); err != nil {
+		return fmt.Errorf("nodelay: %w", err)
+	}
+	if o.sendBuf > 0 {
+		if err := tc.SetWriteBuffer(o.sendBuf); err != nil {
+			return fmt.Errorf("send buffer: %w", err)
+		}
+	}
+	if o.recvBuf > 0 {
+		if err := tc.SetReadBuffer(o.recvBuf); err != nil {
+			return fmt.Errorf("receive buffer: %w", err)
+		}
+	}
+	if o.linger >= 0 {
+		if err := tc.SetLinger(o.linger); err != nil {
+			return fmt.Errorf("linger: %w", err)
+		}
+	}
+	return nil
+}
+
+/*
+setKeepAlive enables OS-level TCP keepalive probes every period, so a
+half-open connection (peer vanished without a FIN) is noticed even when
+no data flows. A period of 0 turns probes off.
+
+این تابع keepalive سطح سیستم‌عامل را با فاصله‌ی period فعال می‌کند تا
+اتصال نیمه‌باز (peer بدون FIN ناپدید شده) حتی بدون تبادل داده تشخیص
+داده شود؛ period صفر آن را خاموش می‌کند
+*/
+func setKeepAlive(tc *net.TCPConn, period time.Duration) error {
+	if period <= 0 {
+		return tc.SetKeepAlive(false)
+	}
+	// SetKeepAlivePeriod only sets the idle time and leaves probes 15s apart | SetKeepAlivePeriod فقط زمان بیکاری را تنظیم می‌کند
+	return tc.SetKeepAliveConfig(net.KeepAliveConfig{Enable: true, Idle: period, Interval: period})
+}
+
+// tuneSocket applies opts to a candidate, reporting but tolerating refusals | اعمال تنظیمات روی کاندید؛ خطا فقط گزارش می‌شود
+func tuneSocket(c net.Conn, opts socketOptions) {
+	if err := opts.apply(c); err != nil {
+		fmt.Fprintln(stdout, "Socket option error:", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpamRateLimit(t *testing.T) {
+	var notices []string
+	f := newSpamFilter(3, 0, time.Minute, func(s string) { notices = append(notices, s) })
+	for i, text := range []string{"a", "b", "c"} {
+		if !f.filter(&message{From: "bob", Key: "k1", Text: text}) {
+			t.Fatalf("message %d within the rate was dropped", i+1)
+		}
+	}
+	if f.filter(&message{From: "bob", Key: "k1", Text: "d"}) {
+		t.Fatal("a message over the rate got through")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "Muted bob for 1m0s: more than 3 messages a minute") {
+		t.Fatalf("notices %q", notices)
+	}
+	if f.filter(&message{From: "bobby", Key: "k1", Text: "e"}) {
+		t.Fatal("a new nick on the same key escaped the mute")
+	}
+	if !f.filter(&message{From: "ann", Key: "k2", Text: "hi"}) {
+		t.Fatal("another sender was muted too")
+	}
+
+	f.senders["k1"].mutedUntil = time.Now().Add(-time.Second) // Cooldown over | پایان مدت سکوت
+	if !f.filter(&message{From: "bob", Key: "k1", Text: "sorry"}) {
+		t.Fatal("still muted after the cooldown")
+	}
+	if len(notices) != 1 {
+		t.Fatalf("muted senders were announced again: %q", notices)
+	}
+}
+
+func TestSpamRepeats(t *testing.T) {
+	var notices []string
+	f := newSpamFilter(0, 3, time.Minute, func(s string) { notices = append(notices, s) })
+	for _, text := range []string{"buy", "buy", "hello", "buy", "buy"} {
+		if !f.filter(&message{From: "bob", Text: text}) {
+			t.Fatalf("%q dropped before three in a row", text)
+		}
+	}
+	if f.filter(&message{From: "bob", Text: "buy"}) {
+		t.Fatal("the third identical message in a row got through")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "3 identical messages in a row") {
+		t.Fatalf("notices %q", notices)
+	}
+}
+
+func TestSpamDisabled(t *testing.T) {
+	f := newSpamFilter(0, 0, time.Minute, func(s string) { t.Fatalf("muted with every check off: %s", s) })
+	for i := 0; i < 200; i++ {
+		if !f.filter(&message{From: "bob", Text: "same"}) {
+			t.Fatal("dropped with every check off")
+		}
+	}
+}
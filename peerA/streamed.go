@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"        // For reading the source in parts
+	"bytes"        // For cutting parts at line breaks
+	"errors"       // For reading errors
+	"fmt"          // For wrapping the unsupported error
+	"io"           // For the streamed source
+	"time"         // For the shared timestamp
+	"unicode/utf8" // For cutting parts between characters
+)
+
+/*
+Streamed message parts: a message too long for one chat line travels
+as one start part, any number of more parts and one end part, all with
+the same ID. Each part is a separately signed chat line, so neither
+side ever holds the whole message.
+
+بخش‌های پیام جریانی: پیامی که در یک خط چت جا نمی‌شود به‌صورت یک بخش
+start، هر تعداد بخش more و یک بخش end با شناسه‌ی یکسان ارسال می‌شود. هر
+بخش خط چت جداگانه و امضاشده‌ای است، پس هیچ‌کدام از دو طرف کل پیام را در
+حافظه نگه نمی‌دارد
+*/
+const (
+	partStart = "start"
+	partMore  = "more"
+	partEnd   = "end"
+)
+
+// streamPartRatio sizes parts so even fully escaped JSON fits the line limit | نسبت اندازه‌ی بخش به حد خط
+const streamPartRatio = 8
+
+/*
+sendStreamed sends the text read from r as one message, cut into parts
+that each fit the negotiated line limit. Parts end at a line break when
+the chunk has one, so the receiver can print them as they arrive. Text
+that fits one part goes out as a plain message. The outbound word
+filter applies to each part; a blocked part ends the message early.
+
+این تابع متن خوانده‌شده از r را به‌صورت یک پیام ارسال می‌کند و آن را به
+بخش‌هایی تقسیم می‌کند که هر کدام در حد خط توافق‌شده جا شوند. اگر تکه‌ای
+newline داشته باشد بخش در آن تمام می‌شود تا گیرنده بتواند هر بخش را هنگام
+رسیدن چاپ کند. متنی که در یک بخش جا شود پیام ساده ارسال می‌شود. فیلتر
+کلمات خروجی روی هر بخش اعمال می‌شود و بخش مسدودشده پیام را زودتر تمام می‌کند
+*/
+func sendStreamed(s *session, r io.Reader, parent *message, auto bool) (message, error) {
+	size := s.conn.caps.MaxMessage / streamPartRatio
+	br := bufio.NewReaderSize(r, size)
+	head := message{Time: time.Now(), From: s.name, ID: newMessageID(), Auto: auto, Key: s.id.fingerprint, Verified: true}
+	if parent != nil {
+		head.Parent = parent.ID
+		head.Quote = quoteOf(*parent)
+	}
+
+	cur, err := nextPart(br, size)
+	if err != nil {
+		return message{}, err
+	}
+	for n := 0; ; n++ {
+		next, err := nextPart(br, size)
+		if err != nil {
+			return message{}, err
+		}
+		m := head
+		m.Text = string(cur)
+		switch {
+		case n == 0 && len(next) == 0:
+			m.Part = "" // Fits one line | در یک خط جا می‌شود
+		case n == 0:
+			m.Part = partStart
+		case len(next) == 0:
+			m.Part = partEnd
+		default:
+			m.Part = partMore
+		}
+		if n > 0 {
+			m.Parent, m.Quote = "", "" // Only the start carries the reply | فقط start پاسخ را همراه دارد
+		}
+		text, ok := outgoingText(s, m.Text)
+		if !ok {
+			s.metrics.drop(dropBlocked)
+			if n > 0 {
+				m.Text, m.Part = "", partEnd
+				_ = queueChat(s, m) // Close the message on the remote | بستن پیام نزد طرف مقابل
+			}
+			return message{}, errBlocked
+		}
+		m.Text = text
+		if err := queueChat(s, m); err != nil {
+			return message{}, err
+		}
+		if n == 0 {
+			s.threads.add(m)
+			head = m
+		}
+		if m.Part == "" || m.Part == partEnd {
+			s.acks.track(m.ID) // Delivered once the end arrives | تحویل با رسیدن end
+			return head, nil
+		}
+		cur = next
+	}
+}
+
+/*
+nextPart reads up to size bytes from br, cutting after the last line
+break or, without one, between two characters. It returns an empty
+part at the end of the input.
+
+این تابع حداکثر size بایت از br می‌خواند و پس از آخرین newline یا در
+نبود آن بین دو نویسه برش می‌زند؛ در پایان ورودی بخش خالی برمی‌گرداند
+*/
+func nextPart(br *bufio.Reader, size int) ([]byte, error) {
+	buf, err := br.Peek(size)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	n := len(buf)
+	if n == size {
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			n = i + 1
+		} else {
+			i := n - 1
+			for i > 0 && !utf8.RuneStart(buf[i]) {
+				i-- // Back to the start of the last character | بازگشت به ابتدای آخرین نویسه
+			}
+			if !utf8.FullRune(buf[i:]) && i > 0 {
+				n = i // It was cut; leave it for the next part | بریده شده؛ برای بخش بعد می‌ماند
+			}
+		}
+	}
+	part := make([]byte, n)
+	_, _ = br.Read(part) // Already buffered by Peek | قبلاً با Peek بافر شده
+	return part, nil
+}
+
+// queueChat signs one of our messages and queues its line | امضا و قراردادن یک پیام در صف
+func queueChat(s *session, m message) error {
+	line := encodeChat(s.id, m)
+	if len(line)+seqOverhead+1 > s.conn.caps.MaxMessage {
+		return fmt.Errorf("%w; the limit is %d bytes", errTooLong, s.conn.caps.MaxMessage)
+	}
+	select {
+	case s.outgoing <- line:
+	case <-s.done.c:
+		return errClosed
+	}
+	s.queued.Add(1)
+	s.history.add(m)
+	return nil
+}
+
+// continued reports whether m is a later part of a streamed message | آیا m بخش بعدی یک پیام جریانی است
+func (m message) continued() bool {
+	return m.Part == partMore || m.Part == partEnd
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"         // For reading the manifest reply
+	"encoding/json" // For the manifest and the reply
+	"errors"        // For sync error values
+	"fmt"           // For the summary
+	"io"            // For bounding the manifest
+	"io/fs"         // For walking the directory
+	"net"           // For the stream connection type
+	"os"            // For the mirror files
+	"path/filepath" // For local paths
+	"strings"       // For splitting mirror paths
+	"time"          // For the reply deadline
+)
+
+/*
+Directory sync limits
+
+محدودیت‌های همگام‌سازی پوشه:
+- syncMaxFiles بیشترین تعداد فایل یک manifest است
+- syncMaxManifest بیشترین اندازه‌ی manifest به بایت است
+*/
+const (
+	syncMaxFiles    = 10000   // Most files in one manifest | بیشترین فایل‌های manifest
+	syncMaxManifest = 4 << 20 // Largest manifest in bytes | بزرگ‌ترین manifest
+)
+
+var (
+	errSyncUnsupported = errors.New("the remote does not support directory sync") // Capability missing | قابلیت وجود ندارد
+	errSyncTooMany     = errors.New("too many files to sync")                     // Over syncMaxFiles | بیش از syncMaxFiles
+)
+
+func init() {
+	registerCommand("syncdir", "/syncdir <path>  mirror a directory to the remote, sending new and changed files", syncDirCommand)
+	registerArgCompleter("syncdir", completePath)
+}
+
+// syncEntry is one file of a manifest | یک فایل از manifest
+type syncEntry struct {
+	Path   string `json:"path"` // Slash-separated, relative to the synced directory | مسیر نسبی با /
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+/*
+syncManifest opens a sync stream: it lists every file of the directory
+with its checksum, and the receiver answers with the paths its mirror
+is missing or holds a different version of.
+
+این ساختار stream همگام‌سازی را آغاز می‌کند: همه‌ی فایل‌های پوشه را با
+checksum فهرست می‌کند و گیرنده مسیرهایی را برمی‌گرداند که در آینه‌اش نیستند
+یا نسخه‌ی دیگری از آن‌ها را دارد
+*/
+type syncManifest struct {
+	From  string      `json:"from"`
+	Dir   string      `json:"dir"` // Name of the mirror directory | نام پوشه‌ی آینه
+	Files []syncEntry `json:"files"`
+}
+
+// syncReply is the receiver's answer to a manifest | پاسخ گیرنده به manifest
+type syncReply struct {
+	Need []string `json:"need"`
+}
+
+/*
+syncDirCommand mirrors a directory to the remote: files the remote does
+not have yet, or has with a different checksum, are sent as transfers
+into its copy of the directory, and a summary is printed at the end.
+Hidden files and directories are skipped.
+
+این دستور یک پوشه را برای طرف مقابل آینه می‌کند: فایل‌هایی که طرف مقابل
+ندارد یا با checksum دیگری دارد به‌صورت انتقال در نسخه‌ی او از پوشه ارسال
+می‌شوند و در پایان خلاصه‌ای چاپ می‌شود؛ فایل‌ها و پوشه‌های پنهان نادیده
+گرفته می‌شوند
+*/
+func syncDirCommand(s *session, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(stdout, "Usage: /syncdir <path>")
+		return
+	}
+	root := strings.Join(args, " ") // Paths may contain spaces | مسیر ممکن است فاصله داشته باشد
+	if !s.conn.caps.DirSync {
+		fmt.Fprintln(stdout, "Sync error:", errSyncUnsupported)
+		return
+	}
+
+	go func() {
+		m, err := scanDir(s.name, root)
+		if err != nil {
+			fmt.Fprintln(stdout, "Sync error:", err)
+			return
+		}
+		need, err := offerManifest(s, m)
+		if err != nil {
+			fmt.Fprintln(stdout, "Sync error:", err)
+			return
+		}
+
+		sizes := make(map[string]int64, len(m.Files))
+		for _, e := range m.Files {
+			sizes[e.Path] = e.Size
+		}
+		var sent, failed int
+		var bytes int64
+		for _, p := range need {
+			size, ok := sizes[p]
+			if !ok {
+				continue // Not in our manifest | در manifest ما نیست
+			}
+			h := fileHeader{From: s.name, Name: filepath.Base(filepath.FromSlash(p)), Sync: m.Dir, Path: p}
+			if _, err := sendFile(s, filepath.Join(root, filepath.FromSlash(p)), h); err != nil {
+				fmt.Fprintf(stdout, "Sync error: %s: %v\n", p, err)
+				failed++
+				continue
+			}
+			sent++
+			bytes += size
+		}
+		summary := fmt.Sprintf("Synced %s: %d sent (%s), %d unchanged, %d failed", m.Dir, sent, formatBytes(bytes), len(m.Files)-len(need), failed)
+		fmt.Fprintln(stdout, summary)
+		recordSent(s, "["+summary+"]")
+	}()
+}
+
+// scanDir lists and hashes the visible files under root | فهرست و hash فایل‌های قابل‌مشاهده زیر root
+func scanDir(from, root string) (syncManifest, error) {
+	m := syncManifest{From: from, Dir: filepath.Base(filepath.Clean(root))}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil // Hidden | پنهان
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(m.Files) == syncMaxFiles {
+			return errSyncTooMany
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := hashPath(path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, syncEntry{Path: filepath.ToSlash(rel), Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	return m, err
+}
+
+// offerManifest sends m on a sync stream and returns the paths the remote needs | ارسال manifest و دریافت مسیرهای لازم
+func offerManifest(s *session, m syncManifest) ([]string, error) {
+	st, err := openStream(s.mux, streamSync)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	if err := json.NewEncoder(st).Encode(m); err != nil {
+		return nil, err
+	}
+	_ = st.SetReadDeadline(time.Now().Add(fileStallTimeout))
+	var reply syncReply
+	if err := json.NewDecoder(bufio.NewReader(st)).Decode(&reply); err != nil {
+		return nil, err
+	}
+	return reply.Need, nil
+}
+
+/*
+receiveSync answers a manifest with the paths whose mirror copy is
+missing or differs.
+
+این تابع به manifest با مسیرهایی پاسخ می‌دهد که نسخه‌ی آینه‌ی آن‌ها
+وجود ندارد یا متفاوت است
+*/
+func receiveSync(s *session, st net.Conn) {
+	defer st.Close()
+
+	var m syncManifest
+	if err := json.NewDecoder(io.LimitReader(st, syncMaxManifest)).Decode(&m); err != nil || len(m.Files) > syncMaxFiles {
+		return
+	}
+	if s.ignores.has(m.From, "") {
+		return // Ignored senders get no answer | فرستنده‌ی نادیده‌گرفته پاسخی نمی‌گیرد
+	}
+	reply := syncReply{Need: []string{}}
+	for _, e := range m.Files {
+		path, err := s.files.mirrorPath(m.Dir, e.Path)
+		if err != nil {
+			continue
+		}
+		if sum, err := hashPath(path); err != nil || sum != e.SHA256 {
+			reply.Need = append(reply.Need, e.Path)
+		}
+	}
+	_ = json.NewEncoder(s.sched.wrap(st, prioControl)).Encode(reply)
+}
+
+/*
+mirrorPath maps a sync directory name and a slash-separated path from
+the remote to a local path inside the store's directory. Every element
+is sanitized like a file name, so the result cannot leave the mirror.
+
+این تابع نام پوشه‌ی همگام‌سازی و مسیر ارسالی طرف مقابل را به مسیری محلی
+داخل پوشه‌ی store تبدیل می‌کند؛ هر جزء مانند نام فایل پاک‌سازی می‌شود تا
+نتیجه نتواند از آینه بیرون برود
+*/
+func (fs *fileStore) mirrorPath(dir, rel string) (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.ensureDir(); err != nil {
+		return "", err
+	}
+	parts := []string{fs.dir, sanitizeFileName(dir)}
+	for _, p := range strings.Split(rel, "/") {
+		parts = append(parts, sanitizeFileName(p))
+	}
+	return filepath.Join(parts...), nil
+}
+
+/*
+createMirror opens a partial file for a sync transfer next to its
+mirror path; finishMirror moves it into place once it is verified, so
+the old copy stays until the new one is complete.
+
+این تابع یک فایل ناقص برای انتقال همگام‌سازی کنار مسیر آینه‌ی آن باز
+می‌کند؛ finishMirror پس از تأیید آن را جایگزین می‌کند تا نسخه‌ی قبلی تا
+کامل‌شدن نسخه‌ی جدید باقی بماند
+*/
+func (fs *fileStore) createMirror(dir, rel string) (*os.File, error) {
+	path, err := fs.mirrorPath(dir, rel)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path+".part", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+}
+
+// finishMirror moves a completed partial file into place | جایگزینی فایل ناقص کامل‌شده
+func finishMirror(part string) (string, error) {
+	path := strings.TrimSuffix(part, ".part")
+	return path, os.Rename(part, path)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files under root, keyed by slash-separated path | ساخت فایل‌ها زیر root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "photos")
+	writeTree(t, root, map[string]string{"a.txt": "a", "sub/b.txt": "bb", ".secret": "x", ".git/config": "x", "sub/.cache": "x"})
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := scanDir("ann", root+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []syncEntry{{Path: "a.txt", Size: 1, SHA256: sha256Hex("a")}, {Path: "sub/b.txt", Size: 2, SHA256: sha256Hex("bb")}}
+	if m.From != "ann" || m.Dir != "photos" || !reflect.DeepEqual(m.Files, want) {
+		t.Errorf("manifest %+v, want photos with %+v", m, want)
+	}
+	if _, err := scanDir("ann", filepath.Join(root, "missing")); err == nil {
+		t.Error("scanning a missing directory succeeded")
+	}
+}
+
+func TestMirrorPath(t *testing.T) {
+	dir := t.TempDir()
+	fs := newFileStore(dir, maxFileSize, 0)
+	for _, c := range []struct{ dir, rel, want string }{
+		{"photos", "sub/b.txt", "photos/sub/b.txt"},
+		{"../..", "../../etc/passwd", "_../file/file/etc/passwd"},
+		{"/tmp", "a\\..\\b", "_tmp/a_.._b"},
+		{".hidden", "//x", "hidden/file/file/x"},
+	} {
+		got, err := fs.mirrorPath(c.dir, c.rel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, filepath.FromSlash(c.want)); got != want {
+			t.Errorf("mirrorPath(%q, %q) = %q, want %q", c.dir, c.rel, got, want)
+		}
+	}
+}
+
+func TestReceiveSync(t *testing.T) {
+	dir := t.TempDir()
+	s := fileSession(t, dir, "", io.Discard, nil)
+	writeTree(t, filepath.Join(dir, "photos"), map[string]string{"same.txt": "same", "sub/old.txt": "old"})
+
+	local, remote := net.Pipe()
+	go receiveSync(s, local)
+	m := syncManifest{From: "ann", Dir: "photos", Files: []syncEntry{
+		{Path: "same.txt", Size: 4, SHA256: sha256Hex("same")},
+		{Path: "sub/old.txt", Size: 3, SHA256: sha256Hex("new")},
+		{Path: "added.txt", Size: 5, SHA256: sha256Hex("added")},
+	}}
+	if err := json.NewEncoder(remote).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	var reply syncReply
+	if err := json.NewDecoder(remote).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sub/old.txt", "added.txt"}; !reflect.DeepEqual(reply.Need, want) {
+		t.Errorf("needs %q, want %q", reply.Need, want)
+	}
+}
+
+func TestReceiveSyncedFile(t *testing.T) {
+	dir := t.TempDir()
+	incoming := make(chan message, 2)
+	s := fileSession(t, dir, "text/*", io.Discard, incoming)
+	ann, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "photos", "sub", "b.txt")
+
+	for _, content := range []string{"first", "second version"} {
+		h := fileHeader{From: "ann", Name: "b.txt", MIME: "text/plain", Size: int64(len(content)), SHA256: sha256Hex(content), Sync: "photos", Path: "sub/b.txt"}
+		if v := offerFile(t, s, ann, h, content); !v.Accept {
+			t.Fatalf("synced file refused: %s", v.Reason)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Errorf("mirror holds %q, %v; want %q", data, err, content)
+		}
+		if m := <-incoming; !strings.Contains(m.Text, ": photos/sub/b.txt, ") {
+			t.Errorf("announced as %q", m.Text)
+		}
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
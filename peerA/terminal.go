@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"  // For terminal configuration errors
+	"os"      // For inspecting stdout and the environment
+	"strconv" // For the VTE version number
+	"strings" // For matching terminal names
+)
+
+/*
+Hyperlink modes
+
+حالت‌های لینک قابل کلیک:
+- auto: فقط اگر ترمینال از OSC 8 پشتیبانی کند
+- on: همیشه
+- off: هرگز
+*/
+const (
+	hyperlinksAuto = "auto" // Only on terminals known to support OSC 8 | فقط در ترمینال‌های سازگار
+	hyperlinksOn   = "on"   // Always | همیشه
+	hyperlinksOff  = "off"  // Never | هرگز
+)
+
+var errHyperlinksMode = errors.New(`hyperlinks must be "auto", "on" or "off"`) // Unknown hyperlinks value | مقدار نامعتبر hyperlinks
+
+/*
+useHyperlinks decides whether URLs are wrapped in OSC 8 escapes. In
+auto mode they are only used when stdout is a terminal that advertises
+support through its environment; daemon output goes to terminals we
+cannot inspect, so it stays plain.
+
+این تابع مشخص می‌کند آیا لینک‌ها در دنباله‌ی OSC 8 قرار بگیرند یا نه؛ در حالت
+auto فقط وقتی stdout ترمینالی است که از طریق متغیرهای محیطی پشتیبانی خود را
+اعلام کرده است. خروجی daemon به ترمینال‌های ناشناخته می‌رود و ساده می‌ماند
+*/
+func useHyperlinks(mode string, daemon bool) (bool, error) {
+	switch mode {
+	case hyperlinksOn:
+		return true, nil
+	case hyperlinksOff:
+		return false, nil
+	case hyperlinksAuto:
+		return !daemon && stdoutIsTerminal() && terminalSupportsHyperlinks(), nil
+	default:
+		return false, errHyperlinksMode
+	}
+}
+
+// stdoutIsTerminal reports whether stdout is a character device | آیا stdout ترمینال است
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+/*
+terminalSupportsHyperlinks checks the variables that terminals with
+OSC 8 support set; there is no query for it, so unknown terminals get
+plain text.
+
+این تابع متغیرهایی را بررسی می‌کند که ترمینال‌های دارای OSC 8 تنظیم
+می‌کنند؛ راهی برای پرسیدن وجود ندارد و ترمینال‌های ناشناخته متن ساده می‌گیرند
+*/
+func terminalSupportsHyperlinks() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true // Windows Terminal, kitty, Konsole | ترمینال‌های سازگار
+	}
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true // GNOME Terminal and other VTE 0.50+ | ترمینال‌های VTE جدید
+	}
+	term := os.Getenv("TERM")
+	return strings.Contains(term, "kitty") || strings.Contains(term, "foot") || strings.Contains(term, "alacritty")
+}
+
+/*
+linkify wraps every URL in line in an OSC 8 hyperlink escape; the
+visible text stays the same.
+
+این تابع هر لینک داخل line را در دنباله‌ی OSC 8 قرار می‌دهد؛
+متن نمایش‌داده‌شده تغییری نمی‌کند
+*/
+func linkify(line string) string {
+	return urlPattern.ReplaceAllStringFunc(line, func(url string) string {
+		return "\x1b]8;;" + url + "\x1b\\" + url + "\x1b]8;;\x1b\\"
+	})
+}
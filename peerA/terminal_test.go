@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestLinkify(t *testing.T) {
+	link := func(url string) string { return "\x1b]8;;" + url + "\x1b\\" + url + "\x1b]8;;\x1b\\" }
+	for _, c := range []struct{ in, want string }{
+		{"no links here", "no links here"},
+		{"see https://example.org/a?b=c.", "see " + link("https://example.org/a?b=c") + "."},
+		{"(http://example.org) and https://example.com/x", "(" + link("http://example.org") + ") and " + link("https://example.com/x")},
+		{paint("36", "RECV -> bob: https://example.org/a"), "\x1b[36mRECV -> bob: " + link("https://example.org/a") + "\x1b[0m"}, // Colour reset stays outside the link | بازنشانی رنگ بیرون از لینک
+		{"https://example.org/\x07\x1b]8;;https://evil.example\x07x", link("https://example.org/") + "\x07\x1b]8;;" + link("https://evil.example") + "\x07x"},
+	} {
+		if got := linkify(c.in); got != c.want {
+			t.Errorf("linkify(%q)\n got %q\nwant %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestUseHyperlinks(t *testing.T) {
+	for _, c := range []struct {
+		mode   string
+		daemon bool
+		want   bool
+	}{
+		{hyperlinksOn, true, true},
+		{hyperlinksOff, false, false},
+		{hyperlinksAuto, false, false}, // Test output is not a terminal | خروجی تست ترمینال نیست
+	} {
+		if got, err := useHyperlinks(c.mode, c.daemon); err != nil || got != c.want {
+			t.Errorf("useHyperlinks(%q, %v) = %v, %v; want %v", c.mode, c.daemon, got, err, c.want)
+		}
+	}
+	if _, err := useHyperlinks("yes", false); err != errHyperlinksMode {
+		t.Errorf("yes: %v, want %v", err, errHyperlinksMode)
+	}
+}
+
+func TestTerminalSupportsHyperlinks(t *testing.T) {
+	for _, c := range []struct {
+		env  map[string]string
+		want bool
+	}{
+		{map[string]string{"TERM": "xterm-256color"}, false},
+		{map[string]string{"TERM": "dumb", "TERM_PROGRAM": "iTerm.app"}, false},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, true},
+		{map[string]string{"KITTY_WINDOW_ID": "1"}, true},
+		{map[string]string{"VTE_VERSION": "4805"}, false},
+		{map[string]string{"VTE_VERSION": "6003"}, true},
+		{map[string]string{"TERM": "foot"}, true},
+	} {
+		for _, k := range []string{"TERM", "TERM_PROGRAM", "WT_SESSION", "KITTY_WINDOW_ID", "KONSOLE_VERSION", "VTE_VERSION"} {
+			t.Setenv(k, c.env[k])
+		}
+		if got := terminalSupportsHyperlinks(); got != c.want {
+			t.Errorf("%v: %v, want %v", c.env, got, c.want)
+		}
+	}
+}
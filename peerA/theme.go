@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"  // For theme error values
+	"fmt"     // For command output
+	"io"      // For the system output writer
+	"regexp"  // For highlighting mentions
+	"sort"    // For a stable /theme listing
+	"strconv" // For raw SGR numbers
+	"strings" // For parsing colour names
+	"sync"    // For switching themes at runtime
+)
+
+const themePlain = "plain" // No colours, the default | بدون رنگ، پیش‌فرض
+
+var (
+	errThemeUnknown = errors.New("no such theme") // Not built in or configured | نه داخلی و نه در پیکربندی
+	errThemePart    = errors.New(`theme parts are "remote", "own", "system", "mention" and "status"`)
+	errThemeColour  = errors.New("unknown colour") // Not a name or SGR number | نه نام و نه عدد SGR
+)
+
+/*
+theme holds the SGR parameters used for each kind of output: remote
+messages, our own, system notices, mentions of our name and the prompt
+that serves as the status line. An empty part is not coloured.
+
+این نوع پارامترهای SGR هر نوع خروجی را نگه می‌دارد: پیام‌های طرف مقابل،
+پیام‌های ما، اعلان‌های سیستمی، ذکر نام ما و خط ورودی که نقش خط وضعیت را
+دارد؛ بخش خالی رنگی نمی‌شود
+*/
+type theme struct {
+	Remote  string
+	Own     string
+	System  string
+	Mention string
+	Status  string
+}
+
+// builtinThemes are available without any config | پوسته‌های داخلی
+var builtinThemes = map[string]theme{
+	themePlain: {},
+	"dark":     {Remote: "36", Own: "32", System: "90", Mention: "1;33", Status: "1;34"},
+	"light":    {Remote: "34", Own: "32", System: "90", Mention: "1;35", Status: "1;36"},
+}
+
+// colourNames maps the names allowed in a theme to SGR parameters | نگاشت نام رنگ‌ها به پارامتر SGR
+var colourNames = map[string]string{
+	"bold": "1", "dim": "2", "italic": "3", "underline": "4", "reverse": "7",
+	"black": "30", "red": "31", "green": "32", "yellow": "33", "blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"gray": "90", "bright-red": "91", "bright-green": "92", "bright-yellow": "93", "bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+}
+
+/*
+parseColour turns a list of names and SGR numbers, such as "bold cyan"
+or "38;5;208", into one SGR parameter string.
+
+این تابع فهرستی از نام‌ها و اعداد SGR مانند "bold cyan" یا "38;5;208"
+را به یک رشته‌ی پارامتر SGR تبدیل می‌کند
+*/
+func parseColour(spec string) (string, error) {
+	var codes []string
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		if code, ok := colourNames[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		for _, n := range strings.Split(word, ";") {
+			if _, err := strconv.Atoi(n); err != nil {
+				return "", fmt.Errorf("%s: %w", word, errThemeColour)
+			}
+		}
+		codes = append(codes, word)
+	}
+	return strings.Join(codes, ";"), nil
+}
+
+// parseTheme builds a theme from a config object | ساخت پوسته از شیء پیکربندی
+func parseTheme(parts map[string]string) (theme, error) {
+	var t theme
+	for part, spec := range parts {
+		code, err := parseColour(spec)
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", part, err)
+		}
+		switch part {
+		case "remote":
+			t.Remote = code
+		case "own":
+			t.Own = code
+		case "system":
+			t.System = code
+		case "mention":
+			t.Mention = code
+		case "status":
+			t.Status = code
+		default:
+			return t, fmt.Errorf("%s: %w", part, errThemePart)
+		}
+	}
+	return t, nil
+}
+
+// paint wraps text in the SGR code, if any | قراردادن text در رنگ code
+func paint(code, text string) string {
+	if code == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+/*
+themeTable holds the available themes and the one in use. Colours are
+only applied on an interactive terminal; elsewhere every output stays
+plain. onChange lets the editor redraw its prompt.
+
+این نوع پوسته‌های موجود و پوسته‌ی در حال استفاده را نگه می‌دارد؛ رنگ‌ها فقط
+روی ترمینال تعاملی اعمال می‌شوند و در جاهای دیگر خروجی ساده می‌ماند؛
+onChange به ویرایشگر امکان بازسازی خط ورودی را می‌دهد
+*/
+type themeTable struct {
+	mu       sync.Mutex
+	themes   map[string]theme
+	name     string
+	enabled  bool
+	onChange func(t theme)
+}
+
+/*
+newThemeTable adds the config's themes to the built-in ones and selects
+name; configured themes may replace built-in ones.
+
+این تابع پوسته‌های پیکربندی را به پوسته‌های داخلی اضافه می‌کند و name را
+انتخاب می‌کند؛ پوسته‌های پیکربندی می‌توانند جای پوسته‌های داخلی را بگیرند
+*/
+func newThemeTable(configured map[string]map[string]string, name string) (*themeTable, error) {
+	t := &themeTable{themes: make(map[string]theme, len(builtinThemes)+len(configured)), name: themePlain}
+	for n, th := range builtinThemes {
+		t.themes[n] = th
+	}
+	for n, parts := range configured {
+		th, err := parseTheme(parts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n, err)
+		}
+		t.themes[n] = th
+	}
+	if name != "" {
+		if err := t.use(name); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+/*
+attach turns colours on for an interactive terminal and applies the
+theme in use through onChange, which is called again on every switch.
+
+این تابع رنگ‌ها را برای ترمینال تعاملی روشن می‌کند و پوسته‌ی فعلی را با
+onChange اعمال می‌کند که با هر تغییر دوباره فراخوانی می‌شود
+*/
+func (t *themeTable) attach(onChange func(t theme)) {
+	t.mu.Lock()
+	t.enabled = true
+	t.onChange = onChange
+	th := t.themes[t.name]
+	t.mu.Unlock()
+	onChange(th)
+}
+
+// use switches to the named theme | تغییر به پوسته‌ی name
+func (t *themeTable) use(name string) error {
+	t.mu.Lock()
+	th, ok := t.themes[name]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("%s: %w", name, errThemeUnknown)
+	}
+	t.name = name
+	onChange := t.onChange
+	t.mu.Unlock()
+	if onChange != nil {
+		onChange(th)
+	}
+	return nil
+}
+
+// current returns the theme in use, plain when colours are off | پوسته‌ی فعلی، ساده وقتی رنگ خاموش است
+func (t *themeTable) current() theme {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return theme{}
+	}
+	return t.themes[t.name]
+}
+
+// remote paints a received message, highlighting mention matches | رنگ‌آمیزی پیام دریافتی با برجسته‌کردن ذکر نام
+func (t *themeTable) remote(line string, mention *regexp.Regexp) string {
+	th := t.current()
+	if th.Mention != "" {
+		line = mention.ReplaceAllStringFunc(line, func(m string) string {
+			sub := mention.FindStringSubmatch(m) // The boundaries around the name | مرزهای اطراف نام
+			name := m[len(sub[1]) : len(m)-len(sub[2])]
+			return sub[1] + paint(th.Mention, name) + "\x1b[" + orReset(th.Remote) + "m" + sub[2] // Back to the message colour | بازگشت به رنگ پیام
+		})
+	}
+	return paint(th.Remote, line)
+}
+
+// system returns w with each write in the system colour | w با رنگ سیستمی برای هر نوشتن
+func (t *themeTable) system(w io.Writer) io.Writer {
+	return themedWriter{w: w, themes: t}
+}
+
+// themedWriter paints what is written to it as system output | رنگ‌آمیزی خروجی سیستمی
+type themedWriter struct {
+	w      io.Writer
+	themes *themeTable
+}
+
+func (tw themedWriter) Write(p []byte) (int, error) {
+	code := tw.themes.current().System
+	if code == "" {
+		return tw.w.Write(p)
+	}
+	text := strings.TrimSuffix(string(p), "\n")
+	if _, err := io.WriteString(tw.w, paint(code, text)+string(p[len(text):])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// orReset returns code, or the reset code when it is empty | code یا کد بازنشانی
+func orReset(code string) string {
+	if code == "" {
+		return "0"
+	}
+	return code
+}
+
+/*
+themeCommand lists the themes or switches to one for the rest of the
+run.
+
+این دستور پوسته‌ها را فهرست می‌کند یا تا پایان اجرا به یکی از آن‌ها تغییر می‌دهد
+*/
+func themeCommand(s *session, args []string) {
+	if len(args) == 0 {
+		s.theme.mu.Lock()
+		names := make([]string, 0, len(s.theme.themes))
+		for name := range s.theme.themes {
+			names = append(names, name)
+		}
+		current := s.theme.name
+		s.theme.mu.Unlock()
+		sort.Strings(names)
+		for _, name := range names {
+			marker := "  "
+			if name == current {
+				marker = "* "
+			}
+			fmt.Fprintln(stdout, marker+name)
+		}
+		return
+	}
+	if err := s.theme.use(args[0]); err != nil {
+		fmt.Fprintln(stdout, "Theme error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, paint(s.theme.current().System, "Theme: "+args[0]))
+}
+
+func init() {
+	registerCommand("theme", "/theme [name]  list the colour themes or switch to one", themeCommand)
+	registerArgCompleter("theme", func(s *session, args []string, word string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		s.theme.mu.Lock()
+		defer s.theme.mu.Unlock()
+		var out []string
+		for name := range s.theme.themes {
+			if strings.HasPrefix(name, word) {
+				out = append(out, name)
+			}
+		}
+		return out
+	})
+}
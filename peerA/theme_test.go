@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTheme(t *testing.T) {
+	th, err := parseTheme(map[string]string{"remote": "Bold Cyan", "mention": "38;5;208 underline", "own": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th != (theme{Remote: "1;36", Mention: "38;5;208;4"}) {
+		t.Errorf("parsed %+v", th)
+	}
+	for parts, want := range map[string]error{
+		"remote=teal":    errThemeColour,
+		"remote=38;x":    errThemeColour,
+		"sidebar=yellow": errThemePart,
+	} {
+		part, spec, _ := strings.Cut(parts, "=")
+		if _, err := parseTheme(map[string]string{part: spec}); !errors.Is(err, want) {
+			t.Errorf("%s: %v, want %v", parts, err, want)
+		}
+	}
+	if _, err := newThemeTable(map[string]map[string]string{"sea": {"remote": "teal"}}, ""); !errors.Is(err, errThemeColour) || !strings.HasPrefix(err.Error(), "sea: remote: ") {
+		t.Errorf("bad configured theme: %v", err)
+	}
+	if _, err := newThemeTable(nil, "sea"); !errors.Is(err, errThemeUnknown) {
+		t.Errorf("unknown theme selected: %v", err)
+	}
+}
+
+func TestThemePaints(t *testing.T) {
+	themes, err := newThemeTable(map[string]map[string]string{"dark": {"remote": "blue", "mention": "red", "system": "gray"}}, "dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := newNotifier("ann", "")
+	line := "RECV -> bob: hi @ann, anna"
+	if got := themes.remote(line, n.mention); got != line {
+		t.Errorf("coloured before attaching to a terminal: %q", got)
+	}
+	var applied []theme
+	themes.attach(func(th theme) { applied = append(applied, th) })
+	if got, want := themes.remote(line, n.mention), "\x1b[34mRECV -> bob: hi \x1b[31m@ann\x1b[0m\x1b[34m, anna\x1b[0m"; got != want {
+		t.Errorf("remote line %q, want %q", got, want)
+	}
+
+	var out strings.Builder
+	w := themes.system(&out)
+	if n, err := w.Write([]byte("Connected\n")); n != 10 || err != nil || out.String() != "\x1b[90mConnected\x1b[0m\n" {
+		t.Errorf("system write %d, %v: %q", n, err, out.String())
+	}
+	if err := themes.use(themePlain); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	_, _ = w.Write([]byte("Plain\n"))
+	if out.String() != "Plain\n" || len(applied) != 2 || applied[1] != (theme{}) {
+		t.Errorf("after switching to plain: %q, applied %+v", out.String(), applied)
+	}
+}
+
+func TestThemeCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	themes, err := newThemeTable(map[string]map[string]string{"sea": {"system": "cyan"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	themes.attach(func(theme) {})
+	s := &session{theme: themes}
+
+	runCommand(s, "/theme")
+	runCommand(s, "/theme sea")
+	runCommand(s, "/theme neon")
+	want := "  dark\n  light\n* plain\n  sea\n" +
+		"\x1b[36mTheme: sea\x1b[0m\n" +
+		"Theme error: neon: no such theme\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"     // For command output
+	"strings" // For trimming IDs and snippets
+	"sync"    // For guarding the index
+	"time"    // For walking the whole history
+)
+
+/*
+Thread configuration
+
+مقادیر پیکربندی رشته‌ی گفتگو:
+- تعداد پیام‌های اخیر که برای پاسخ‌ها در حافظه نگه داشته می‌شوند
+- طول نقل‌قول پیام والد
+*/
+const (
+	threadIndexSize = 1000 // Recent messages kept by ID | پیام‌های اخیر نگه‌داشته‌شده
+	quoteLength     = 60   // Characters of the parent quoted | تعداد نویسه‌های نقل‌قول والد
+)
+
+func init() {
+	registerCommand("reply", "/reply <id> <text>  reply to a message", replyCommand)
+	registerCommand("thread", "/thread <id>  show a whole reply thread from the history", threadCommand)
+}
+
+/*
+threadIndex remembers the most recent messages by ID so a reply can be
+shown with a snippet of the message it answers, even in anonymous mode
+where nothing is stored.
+
+این نوع پیام‌های اخیر را بر اساس شناسه نگه می‌دارد تا هر پاسخ با خلاصه‌ای
+از پیام والدش نمایش داده شود، حتی در حالت ناشناس که چیزی ذخیره نمی‌شود
+*/
+type threadIndex struct {
+	mu    sync.Mutex
+	byID  map[string]message
+	order []string // Oldest first, for eviction | قدیمی‌ترین اول، برای حذف
+}
+
+// newThreadIndex returns an empty index | ساخت فهرست خالی
+func newThreadIndex() *threadIndex {
+	return &threadIndex{byID: make(map[string]message)}
+}
+
+// add indexes m if it has an ID | افزودن پیام دارای شناسه
+func (t *threadIndex) add(m message) {
+	if m.ID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.byID[m.ID]; !ok {
+		t.order = append(t.order, m.ID)
+	}
+	t.byID[m.ID] = m
+	if len(t.order) > threadIndexSize {
+		delete(t.byID, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// get looks up a message by ID | جستجوی پیام بر اساس شناسه
+func (t *threadIndex) get(id string) (message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m, ok := t.byID[id]
+	return m, ok
+}
+
+/*
+replyContext returns the "> original text" line shown above a reply.
+The quote carried by the reply is used so context survives even when
+the parent was never seen here; the parent's author is added when the
+parent is still in the index.
+
+این تابع خط نقل‌قول ("> متن اصلی") بالای هر پاسخ را برمی‌گرداند. از
+نقل‌قول همراه پاسخ استفاده می‌شود تا حتی اگر پیام والد اینجا دیده نشده
+باشد زمینه حفظ شود؛ اگر والد هنوز در فهرست باشد نام نویسنده هم اضافه می‌شود
+*/
+func (t *threadIndex) replyContext(m message) string {
+	if m.Parent == "" {
+		return ""
+	}
+	quote, from := m.Quote, ""
+	if parent, ok := t.get(m.Parent); ok {
+		from = parent.From + ": "
+		if quote == "" {
+			quote = quoteOf(parent)
+		}
+	}
+	if quote == "" {
+		return "  > #" + m.Parent // Nothing known but the ID | فقط شناسه معلوم است
+	}
+	return "  > " + from + quote
+}
+
+// quoteOf returns the truncated excerpt of m sent along with replies | نقل‌قول کوتاه‌شده‌ی m برای پاسخ‌ها
+func quoteOf(m message) string {
+	return snippet(m.Text, quoteLength)
+}
+
+// snippet shortens text to at most n characters | کوتاه کردن متن تا n نویسه
+func snippet(text string, n int) string {
+	r := []rune(text)
+	if len(r) <= n {
+		return text
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// messageID accepts an ID with or without its leading '#' | پذیرش شناسه با یا بدون #
+func messageID(arg string) string {
+	return strings.TrimPrefix(arg, "#")
+}
+
+/*
+replyCommand sends text as a reply to the message with the given ID
+and echoes it under its quote.
+
+این دستور متن را به‌عنوان پاسخ به پیام با شناسه‌ی داده‌شده ارسال
+و آن را زیر نقل‌قولش نمایش می‌دهد
+*/
+func replyCommand(s *session, args []string) {
+	if len(args) < 2 {
+		fmt.Fprintln(stdout, "Usage: /reply <id> <text>")
+		return
+	}
+	id := messageID(args[0])
+	parent, ok := s.threads.get(id)
+	if !ok {
+		fmt.Fprintln(stdout, "Unknown message id; replying without a quote")
+		parent = message{ID: id}
+	}
+	m, err := sendChat(s, strings.Join(args[1:], " "), &parent, false)
+	if err != nil {
+		fmt.Fprintln(stdout, "Send error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, s.threads.replyContext(m))
+	fmt.Fprintln(stdout, paint(s.theme.current().Own, "SENT -> "+m.Text+"  #"+m.ID))
+}
+
+/*
+threadCommand prints the thread containing a message: it climbs to the
+root, then lists every reply below it, indented by depth.
+
+این دستور رشته‌ی شامل یک پیام را چاپ می‌کند: ابتدا تا ریشه بالا می‌رود
+و سپس همه‌ی پاسخ‌های زیر آن را با تورفتگی بر اساس عمق نمایش می‌دهد
+*/
+func threadCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /thread <id>")
+		return
+	}
+	if s.history == nil {
+		fmt.Fprintln(stdout, "History is not kept in anonymous mode")
+		return
+	}
+	msgs, err := readHistory(s.history.path, time.Time{})
+	if err != nil {
+		fmt.Fprintln(stdout, "Thread error:", err)
+		return
+	}
+
+	byID := make(map[string]message)
+	children := make(map[string][]message)
+	for _, m := range msgs {
+		if m.ID == "" {
+			continue
+		}
+		byID[m.ID] = m
+		if m.Parent != "" {
+			children[m.Parent] = append(children[m.Parent], m)
+		}
+	}
+
+	root, ok := byID[messageID(args[0])]
+	if !ok {
+		fmt.Fprintln(stdout, "No stored message with id", args[0])
+		return
+	}
+	for seen := map[string]bool{root.ID: true}; root.Parent != ""; {
+		parent, ok := byID[root.Parent]
+		if !ok || seen[parent.ID] {
+			break // Parent not stored, or a loop | والد ذخیره نشده یا حلقه
+		}
+		seen[parent.ID] = true
+		root = parent
+	}
+
+	var walk func(m message, depth int, seen map[string]bool)
+	walk = func(m message, depth int, seen map[string]bool) {
+		if seen[m.ID] {
+			return
+		}
+		seen[m.ID] = true
+		fmt.Fprintln(stdout, strings.Repeat("  ", depth)+formatHistoryLine(s.roster.relabel(m)))
+		for _, c := range children[m.ID] {
+			walk(c, depth+1, seen)
+		}
+	}
+	walk(root, 0, map[string]bool{})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThreadCommand(t *testing.T) {
+	h, err := openHistory(filepath.Join(t.TempDir(), "history.jsonl"), rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.close()
+	now := time.Now()
+	for i, m := range []message{
+		{ID: "r", Text: "root"},
+		{ID: "x", Text: "unrelated"},
+		{ID: "a", Parent: "r", Text: "first answer"},
+		{ID: "b", Parent: "a", Text: "nested answer"},
+		{ID: "c", Parent: "r", Text: "second answer"},
+		{ID: "l1", Parent: "l2", Text: "loop one"},
+		{ID: "l2", Parent: "l1", Text: "loop two"},
+	} {
+		m.Time, m.From = now.Add(time.Duration(i)*time.Second), "bob"
+		h.add(m)
+	}
+	buddies, _ := loadRoster("")
+	s := &session{history: h, roster: buddies}
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+
+	runCommand(s, "/thread #b") // Any message of the thread shows all of it | هر پیام رشته کل آن را نشان می‌دهد
+	var got []string
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		got = append(got, fmt.Sprintf("%d %s", indent, line[strings.Index(line, "bob: ")+5:]))
+	}
+	want := []string{"0 root  #r", "2 first answer  #a", "4 nested answer  #b", "2 second answer  #c"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("/thread b:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	runCommand(s, "/thread l1")
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("/thread on a reply loop:\n%s", buf.String())
+	}
+	buf.Reset()
+	runCommand(s, "/thread nope")
+	if !strings.Contains(buf.String(), "No stored message") {
+		t.Errorf("/thread on an unknown id: %q", buf.String())
+	}
+}
+
+func TestThreadIndexEvicts(t *testing.T) {
+	idx := newThreadIndex()
+	for i := 0; i <= threadIndexSize; i++ {
+		idx.add(message{ID: fmt.Sprint(i)})
+	}
+	idx.add(message{ID: "1"}) // Re-adding does not count twice | افزودن دوباره دو بار شمرده نمی‌شود
+	idx.add(message{Text: "no id"})
+	if _, ok := idx.get("0"); ok {
+		t.Error("the oldest message was kept past the limit")
+	}
+	if _, ok := idx.get(fmt.Sprint(threadIndexSize)); !ok {
+		t.Error("the newest message was evicted")
+	}
+	if len(idx.byID) != threadIndexSize || len(idx.order) != threadIndexSize {
+		t.Errorf("index holds %d messages in %d slots, want %d", len(idx.byID), len(idx.order), threadIndexSize)
+	}
+}
+
+func TestReplyQuote(t *testing.T) {
+	long := strings.Repeat("ب", quoteLength+10)
+	if q := quoteOf(message{Text: long}); len([]rune(q)) != quoteLength || !strings.HasSuffix(q, "…") {
+		t.Errorf("quote of a long message: %q", q)
+	}
+	if q := quoteOf(message{Text: "short"}); q != "short" {
+		t.Errorf("quote of a short message: %q", q)
+	}
+
+	idx := newThreadIndex()
+	idx.add(message{ID: "p", From: "ann", Text: "the original text"})
+	for _, c := range []struct {
+		m    message
+		want string
+	}{
+		{message{Text: "not a reply"}, ""},
+		{message{Parent: "p", Quote: "the original text"}, "  > ann: the original text"},
+		{message{Parent: "p"}, "  > ann: the original text"},             // Quote filled in from the index | نقل‌قول از فهرست
+		{message{Parent: "gone", Quote: "older text"}, "  > older text"}, // Parent never seen here | والد اینجا دیده نشده
+		{message{Parent: "gone"}, "  > #gone"},
+	} {
+		if got := idx.replyContext(c.m); got != c.want {
+			t.Errorf("context of %+v: %q, want %q", c.m, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/hmac"   // For matching a token proof
+	"crypto/rand"   // For minting tokens
+	"encoding/hex"  // For tokens and proofs as text
+	"encoding/json" // For the tokens file
+	"errors"        // For a missing file and error values
+	"fmt"           // For the invite output
+	"os"            // For the tokens file
+	"path/filepath" // For creating the data directory
+	"sync"          // For redeeming from several handshakes
+	"time"          // For expiry
+)
+
+const defaultInviteTTL = 10 * time.Minute // How long a minted token stays valid | مدت اعتبار token ساخته‌شده
+
+var (
+	errTokenTTL  = errors.New("ttl must be positive")                  // Zero or negative --ttl | ttl صفر یا منفی
+	errTokenAnon = errors.New("invite tokens are not kept with -anon") // Nothing would remember them | چیزی آن‌ها را نگه نمی‌دارد
+)
+
+/*
+inviteToken is a single-use secret that lets one peer in until it
+expires, whatever the access mode asks for.
+
+این نوع رازی یک‌بارمصرف است که تا پایان اعتبارش به یک peer اجازه‌ی ورود
+می‌دهد، هر چه حالت دسترسی بخواهد
+*/
+type inviteToken struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+/*
+tokenStore keeps the unredeemed invite tokens in a file, so the invite
+subcommand can mint them while the chat is running. The file is read on
+every use and a token is removed once the link it let in is kept.
+
+این نوع tokenهای دعوت مصرف‌نشده را در یک فایل نگه می‌دارد تا زیرفرمان
+invite بتواند هنگام اجرای چت آن‌ها را بسازد؛ فایل در هر استفاده خوانده
+می‌شود و token پس از نگه‌داشتن اتصالی که پذیرفته حذف می‌شود
+*/
+type tokenStore struct {
+	mu   sync.Mutex
+	path string // Empty: no tokens | خالی: بدون token
+}
+
+// defaultTokensPath returns the per-name invite tokens file | مسیر پیش‌فرض فایل tokenهای دعوت
+func defaultTokensPath(name string) string {
+	return dataPath(name + ".tokens")
+}
+
+// load reads the tokens that have not expired; the caller holds mu | خواندن tokenهای منقضی‌نشده (mu باید گرفته شده باشد)
+func (t *tokenStore) load() ([]inviteToken, error) {
+	if t.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var all []inviteToken
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.path, err)
+	}
+	live := all[:0]
+	for _, tok := range all {
+		if time.Now().Before(tok.Expires) {
+			live = append(live, tok)
+		}
+	}
+	return live, nil
+}
+
+// save writes the tokens; the caller holds mu | ذخیره tokenها (mu باید گرفته شده باشد)
+func (t *tokenStore) save(list []inviteToken) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return err
+	}
+	if list == nil {
+		list = []inviteToken{}
+	}
+	data, _ := json.MarshalIndent(list, "", "  ") // Plain structs cannot fail | ساختار ساده خطا نمی‌دهد
+	return os.WriteFile(t.path, append(data, '\n'), 0o600)
+}
+
+// mint adds a fresh token valid for ttl | افزودن token تازه با اعتبار ttl
+func (t *tokenStore) mint(ttl time.Duration) (inviteToken, error) {
+	if ttl <= 0 {
+		return inviteToken{}, errTokenTTL
+	}
+	if t.path == "" {
+		return inviteToken{}, errTokenAnon
+	}
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return inviteToken{}, err
+	}
+	tok := inviteToken{Token: hex.EncodeToString(b[:]), Expires: time.Now().Add(ttl).Round(time.Second)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list, err := t.load()
+	if err != nil {
+		return inviteToken{}, err
+	}
+	return tok, t.save(append(list, tok))
+}
+
+/*
+holds reports whether a password proof from the remote over hellos was
+made with one of our live tokens, without using it up: the handshake
+checks the token when admitting, and redeems it only once the link is
+kept. The proof covers our fresh nonce, so one sniffed before the token
+is used cannot be raced or replayed on another connection, and a
+listener posing as us collects nothing it could present here.
+
+این تابع بدون مصرف token بررسی می‌کند که اثبات رمز طرف مقابل روی hellos با
+یکی از tokenهای معتبر ما ساخته شده یا نه؛ handshake هنگام پذیرش token را
+بررسی می‌کند و فقط پس از نگه‌داشتن اتصال آن را مصرف می‌کند. اثبات nonce تازه‌ی
+ما را در بر دارد، پس اثباتی که پیش از مصرف token شنود شود در اتصال دیگری
+قابل رقابت یا تکرار نیست و listenerی که خود را جای ما جا بزند چیزی به دست
+نمی‌آورد که اینجا قابل ارائه باشد
+*/
+func (t *tokenStore) holds(proof string, hellos [2]string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list, err := t.load()
+	if err != nil {
+		fmt.Fprintln(stdout, "Token error:", err)
+		return false
+	}
+	return matchToken(list, proof, hellos) >= 0
+}
+
+/*
+redeem uses up the live token a password proof from the remote was made
+with, and reports whether there was one. An expired or already used
+token matches nothing, so of two links racing on one token only the
+first is let in.
+
+این تابع token معتبری را که اثبات رمز طرف مقابل با آن ساخته شده مصرف
+می‌کند و گزارش می‌دهد که چنین tokenی بود یا نه؛ token منقضی یا مصرف‌شده با
+چیزی تطبیق نمی‌کند، پس از دو اتصالی که با یک token رقابت می‌کنند فقط اولی
+پذیرفته می‌شود
+*/
+func (t *tokenStore) redeem(proof string, hellos [2]string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list, err := t.load()
+	if err != nil {
+		fmt.Fprintln(stdout, "Token error:", err)
+		return false
+	}
+	i := matchToken(list, proof, hellos)
+	if i < 0 {
+		return false
+	}
+	if err := t.save(append(list[:i], list[i+1:]...)); err != nil {
+		fmt.Fprintln(stdout, "Token error:", err)
+		return false // Not removed, so not honoured | حذف نشد، پس پذیرفته نمی‌شود
+	}
+	return true
+}
+
+// matchToken returns the index of the token the password proof over hellos was made with, or -1 | اندیس tokenی که اثبات رمز روی hellos با آن ساخته شده یا -1
+func matchToken(list []inviteToken, proof string, hellos [2]string) int {
+	got, err := hex.DecodeString(proof)
+	if err != nil {
+		return -1
+	}
+	for i, tok := range list {
+		if hmac.Equal(got, transcriptMAC(tok.Token, proofPassword, hellos)) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+runInvite implements "invite": it mints a token valid for ttl and
+prints it with an invitation link carrying it, which the other side
+passes to -dial. The chat must be running, or started later under the
+same name, to let the holder in.
+
+این تابع زیرفرمان invite را اجرا می‌کند: یک token با اعتبار ttl می‌سازد و
+آن را همراه پیوند دعوتی که آن را دارد چاپ می‌کند تا طرف مقابل به -dial
+بدهد؛ برای ورود دارنده‌ی آن، چت باید با همین نام در حال اجرا باشد یا بعداً
+اجرا شود
+*/
+func runInvite(listen string, ttl time.Duration, id *identity, tokens *tokenStore) error {
+	tok, err := tokens.mint(ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Invite token: %s (single use, valid until %s)\n", tok.Token, tok.Expires.Format("2006-01-02 15:04:05"))
+	addr, err := advertiseAddr(listen)
+	if err != nil {
+		fmt.Fprintln(stdout, "Pass it as -password, or pass -listen host:port for a link")
+		return nil
+	}
+	p := pairing{Address: addr, Transport: pairTransport, Fingerprint: id.fingerprint, Token: tok.Token}
+	fmt.Fprintln(stdout, "On the other side run: -dial '"+p.String()+"'")
+	return nil
+}
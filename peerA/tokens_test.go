@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testHellos stands in for the HELLO lines of one handshake, in the prover's order | خطوط HELLO یک handshake به ترتیب اثبات‌کننده
+var testHellos = [2]string{"HELLO 2 9 test none key nonce-a", "HELLO 1 9 test none key nonce-b"}
+
+// minted returns a store holding one fresh token and the proof made with it | فروشگاهی با یک token تازه و اثبات ساخته‌شده با آن
+func minted(t *testing.T) (*tokenStore, string) {
+	t.Helper()
+	s := &tokenStore{path: filepath.Join(t.TempDir(), "chat.tokens")}
+	tok, err := s.mint(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, hex.EncodeToString(transcriptMAC(tok.Token, proofPassword, testHellos))
+}
+
+func TestTokenRedeemedOnce(t *testing.T) {
+	s, proof := minted(t)
+	if !s.holds(proof, testHellos) || !s.holds(proof, testHellos) {
+		t.Fatal("checking the token used it up")
+	}
+	if !s.redeem(proof, testHellos) {
+		t.Fatal("live token was not redeemed")
+	}
+	if s.holds(proof, testHellos) || s.redeem(proof, testHellos) {
+		t.Fatal("token worked twice")
+	}
+}
+
+func TestTokenBadProof(t *testing.T) {
+	s, _ := minted(t)
+	list, err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := list[0].Token
+	for name, proof := range map[string]string{
+		"no proof":        noProof,
+		"not hex":         "zz",
+		"empty":           "",
+		"other token":     hex.EncodeToString(transcriptMAC("not-a-token", proofPassword, testHellos)),
+		"other handshake": hex.EncodeToString(transcriptMAC(tok, proofPassword, [2]string{testHellos[0], "HELLO 1 9 test none key nonce-c"})),
+		"swapped lines":   hex.EncodeToString(transcriptMAC(tok, proofPassword, [2]string{testHellos[1], testHellos[0]})),
+	} {
+		if s.holds(proof, testHellos) || s.redeem(proof, testHellos) {
+			t.Errorf("%s: proof was honoured", name)
+		}
+	}
+	if list, err := s.load(); err != nil || len(list) != 1 {
+		t.Fatalf("tokens = %v, %v; want the one minted", list, err)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	s := &tokenStore{path: filepath.Join(t.TempDir(), "chat.tokens")}
+	tok := inviteToken{Token: "0123", Expires: time.Now().Add(-time.Second)}
+	if err := s.save([]inviteToken{tok}); err != nil {
+		t.Fatal(err)
+	}
+	if s.redeem(hex.EncodeToString(transcriptMAC(tok.Token, proofPassword, testHellos)), testHellos) {
+		t.Fatal("expired token was redeemed")
+	}
+}
+
+func TestAdmitLeavesTokenForKeptLink(t *testing.T) {
+	s, proof := minted(t)
+	members, _ := loadEntrySet("")
+	bans, _ := loadEntrySet("")
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &peerAuth{id: server, bans: bans, members: members, access: accessInvite, tokens: s}
+	key := client.publicKey()
+	for i := 0; i < 2; i++ {
+		if reason, token := a.admit(key, proof, testHellos, false); reason != "" || !token {
+			t.Fatalf("admit = %q, %v; want let in on the token", reason, token)
+		}
+	}
+	s.redeem(proof, testHellos)
+	if reason, token := a.admit(key, proof, testHellos, false); reason == "" || token {
+		t.Fatalf("admit after redeem = %q, %v; want refused", reason, token)
+	}
+}
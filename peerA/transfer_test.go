@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefusalIsOptIn(t *testing.T) {
+	for _, c := range []struct {
+		accept string
+		mime   string
+		want   bool
+	}{
+		{"", "text/plain", false},
+		{"", "", false},
+		{"image/*", "image/png", true},
+		{"image/*", "text/plain; charset=utf-8", false},
+		{"*/*", "application/x-anything", true},
+	} {
+		s := &session{accepts: parseAccept(c.accept)}
+		if got := refusal(s, fileHeader{MIME: c.mime}) == ""; got != c.want {
+			t.Errorf("accept %q, type %q: accepted %v, want %v", c.accept, c.mime, got, c.want)
+		}
+	}
+}
+
+func TestDescribeFileDropsControl(t *testing.T) {
+	for _, h := range []fileHeader{
+		{Name: "a\x1b]0;pwned\x07.txt", MIME: "text/plain"},
+		{Name: "b.txt", MIME: "text/\x1b[2Jplain"},
+		{Name: "c.txt", MIME: "text/plain", Sync: "docs\x1b[1m", Path: "sub/\u009bc.txt"},
+	} {
+		if got := describeFile(1, h); strings.ContainsAny(got, "\x1b\x07\u009b") {
+			t.Errorf("describeFile kept a control character: %q", got)
+		}
+	}
+}
+
+func TestStoreImageDropsControl(t *testing.T) {
+	for _, accept := range []string{"", "*/*"} { // Refused, then saved | رد و سپس ذخیره
+		s := &session{accepts: parseAccept(accept), files: newFileStore(t.TempDir(), 1<<20, 1<<20)}
+		m := message{From: "B", Text: "a\x1b]0;pwned\x07.png", MIME: "image/\x1b[2Jpng", Image: []byte("png")}
+		storeImage(s, &m)
+		if !strings.HasPrefix(m.Text, "[image ") {
+			t.Fatalf("accept %q: not an image placeholder: %q", accept, m.Text)
+		}
+		if strings.ContainsAny(m.Text, "\x1b\x07") {
+			t.Errorf("accept %q: storeImage kept a control character: %q", accept, m.Text)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	for _, c := range []struct {
+		name, want string
+	}{
+		{"notes.txt", "notes.txt"},
+		{"../../etc/passwd", "_.._etc_passwd"},
+		{"/etc/passwd", "_etc_passwd"},
+		{`..\..\boot.ini`, "_.._boot.ini"},
+		{"C:\\Windows\\evil.dll", "C:_Windows_evil.dll"},
+		{".bashrc", "bashrc"},
+		{"...", "file"},
+		{"..", "file"},
+		{"", "file"},
+		{"  ", "file"},
+		{"a\x00b\nc\x1b[2J.txt", "abc[2J.txt"},
+		{"\u009b\x7f.txt", "txt"},
+		{"\t. hidden", "hidden"},
+		{". .profile", "profile"},
+	} {
+		if got := sanitizeFileName(c.name); got != c.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFileStoreQuota(t *testing.T) {
+	fs := newFileStore(t.TempDir(), 100, 250)
+	steps := []struct {
+		name string
+		run  func() error
+		want int64 // Reserved bytes afterwards
+	}{
+		{"first file", func() error { return fs.reserve(100) }, 100},
+		{"over the per-file limit", func() error { return fs.reserve(101) }, 100},
+		{"second file", func() error { return fs.reserve(100) }, 200},
+		{"over the quota", func() error { return fs.reserve(60) }, 200},
+		{"released", func() error { fs.release(100); return nil }, 100},
+		{"fits again", func() error { return fs.reserve(60) }, 160},
+	}
+	wantErr := map[string]error{"over the per-file limit": errFileLimit, "over the quota": errQuota}
+	for _, st := range steps {
+		if err := st.run(); err != wantErr[st.name] {
+			t.Fatalf("%s: error %v, want %v", st.name, err, wantErr[st.name])
+		}
+		if fs.reserved != st.want {
+			t.Fatalf("%s: reserved %d, want %d", st.name, fs.reserved, st.want)
+		}
+	}
+}
+
+func TestFileStoreCreditsReplacedMirror(t *testing.T) {
+	fs := newFileStore(t.TempDir(), 100, 250)
+	for i := 0; i < 5; i++ { // A sync keeps rewriting the same mirrored file | sync یک فایل آینه را بارها بازنویسی می‌کند
+		if err := fs.reserve(100); err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+		fs.add(fileHeader{Size: 100}, "docs/a.txt")
+	}
+	if fs.reserved != 100 {
+		t.Errorf("reserved %d after five updates of one file, want 100", fs.reserved)
+	}
+	if err := fs.reserve(100); err != nil {
+		t.Errorf("a second file: %v", err)
+	}
+	if err := fs.reserve(100); err != errQuota {
+		t.Errorf("a third file: %v, want %v", err, errQuota)
+	}
+}
+
+// countingReader counts the bytes read through it | شمارش بایت‌های خوانده‌شده
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func TestFileWindow(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sender, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	receiver, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	data := make([]byte, 1<<20)
+	_, _ = rand.Read(data)
+	src := &countingReader{r: bytes.NewReader(data)}
+	sent := make(chan error, 1)
+	go func() { sent <- copyChunked(sender, sender, src, true) }()
+
+	time.Sleep(200 * time.Millisecond) // The receiver stores nothing yet | گیرنده هنوز چیزی ذخیره نکرده
+	if n := src.n.Load(); n > fileWindow+fileChunk {
+		t.Fatalf("sender read %d bytes ahead of a silent receiver, want at most the window and one chunk", n)
+	}
+	var got bytes.Buffer
+	if err := receiveWindowed(receiver, receiver, &got, int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sender did not finish")
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Error("received content differs")
+	}
+}
+
+// fileSession returns a session that stores received files in dir and announces them on incoming | نشستی که فایل‌های دریافتی را در dir ذخیره می‌کند
+func fileSession(t *testing.T, dir, accept string, status io.Writer, incoming chan<- message) *session {
+	ignores, _ := loadEntrySet("")
+	keys, _ := loadRegistry("")
+	done := newDoneSignal()
+	t.Cleanup(done.close)
+	return &session{
+		conn:     &handshakeConn{caps: capabilities{FileOffer: true}},
+		files:    newFileStore(dir, maxFileSize, 0),
+		accepts:  parseAccept(accept),
+		ignores:  ignores,
+		keys:     keys,
+		sched:    newLinkScheduler(),
+		status:   status,
+		incoming: incoming,
+		done:     done,
+	}
+}
+
+// offerFile sends h signed by id and content to receiveFile and returns its verdict once it is done | ارسال هدر و محتوا به receiveFile
+func offerFile(t *testing.T, s *session, id *identity, h fileHeader, content string) fileVerdict {
+	h.Key, h.Sig = id.sign(h.signedFields()...)
+	local, remote := net.Pipe()
+	finished := make(chan struct{})
+	go func() { receiveFile(s, local); close(finished) }()
+	if err := json.NewEncoder(remote).Encode(h); err != nil {
+		t.Fatal(err)
+	}
+	var v fileVerdict
+	if err := json.NewDecoder(remote).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Accept {
+		if _, err := io.WriteString(remote, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remote.Close()
+	<-finished
+	return v
+}
+
+// sha256Hex returns the hex SHA-256 of text | محاسبه‌ی SHA-256 متن
+func sha256Hex(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReceiveFileOffer(t *testing.T) {
+	dir := t.TempDir()
+	var status bytes.Buffer
+	incoming := make(chan message, 1)
+	s := fileSession(t, dir, "text/*", &status, incoming)
+	content := "hello, file\n"
+	bob, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := offerFile(t, s, bob, fileHeader{From: "bob", Name: "../notes\x1b.txt", MIME: "text/plain; charset=utf-8", Size: int64(len(content)), SHA256: sha256Hex(content)}, content)
+	if !v.Accept {
+		t.Fatalf("text file refused: %s", v.Reason)
+	}
+	m := <-incoming
+	if !m.Verified || !strings.Contains(m.Text, "[file #1: ") || !strings.HasSuffix(m.Text, sha256Hex(content)) || strings.Contains(m.Text, "\x1b") {
+		t.Errorf("announced as %q", m.Text)
+	}
+	stored, ok := s.files.get(1)
+	if data, err := os.ReadFile(stored.path); !ok || err != nil || string(data) != content || filepath.Dir(stored.path) != dir {
+		t.Errorf("stored %+v: %q, %v; want the content inside %s", stored, data, err, dir)
+	}
+
+	v = offerFile(t, s, bob, fileHeader{From: "bob", Name: "cat.png", MIME: "image/png", Size: 4}, "")
+	if v.Accept || v.Reason != "type image/png not accepted" {
+		t.Errorf("image verdict %+v, want refused by type", v)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files stored, want only the accepted one", len(entries))
+	}
+	if !strings.Contains(status.String(), `Refused file "cat.png" from bob`) {
+		t.Errorf("status:\n%s", status.String())
+	}
+}
+
+func TestReceiveFileChecksum(t *testing.T) {
+	dir := t.TempDir()
+	var status bytes.Buffer
+	incoming := make(chan message, 1)
+	s := fileSession(t, dir, "*/*", &status, incoming)
+	s.files = newFileStore(dir, maxFileSize, 20)
+	bob, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := "the real content"
+	v := offerFile(t, s, bob, fileHeader{From: "bob", Name: "a.txt", MIME: "text/plain", Size: int64(len(sent)), SHA256: sha256Hex(sent)}, "the fake content")
+	if !v.Accept {
+		t.Fatalf("refused: %s", v.Reason)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("kept %d files whose content does not match the checksum", len(entries))
+	}
+	if !strings.Contains(status.String(), `"a.txt" from bob does not match its checksum; discarded`) {
+		t.Errorf("status:\n%s", status.String())
+	}
+	if len(incoming) != 0 {
+		t.Errorf("announced a discarded file: %+v", <-incoming)
+	}
+
+	// The discarded bytes no longer count against the quota | بایت‌های دورریخته از سهمیه کم نمی‌شوند
+	v = offerFile(t, s, bob, fileHeader{From: "bob", Name: "a.txt", MIME: "text/plain", Size: int64(len(sent)), SHA256: sha256Hex(sent)}, sent)
+	if !v.Accept {
+		t.Fatalf("second offer refused: %s", v.Reason)
+	}
+	if m := <-incoming; !strings.HasSuffix(m.Text, "sha256 "+sha256Hex(sent)) {
+		t.Errorf("announced as %q, want the checksum shown", m.Text)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors" // For transport error values
+	"net"    // For the links and listeners
+)
+
+/*
+Transports
+
+انتقال‌ها:
+- tcp: شبکه‌ی TCP با Happy Eyeballs (پیش‌فرض)
+- unix: socket یونیکس روی همین ماشین؛ listen و dial مسیر فایل هستند
+- serial: خط سریال روی device با سرعت baud
+- bluetooth: RFCOMM بلوتوث؛ listen و dial آدرس دستگاه و کانال هستند
+- http: درخواست‌های HTTP با long-polling برای شبکه‌هایی که فقط HTTP(S) خروجی دارند
+- udp: بسته‌های UDP که لایه‌ی ARQ آن‌ها را قابل‌اعتماد و مرتب می‌کند
+*/
+const (
+	transportTCP       = "tcp"
+	transportUnix      = "unix"
+	transportSerial    = "serial"
+	transportBluetooth = "bluetooth"
+	transportHTTP      = "http"
+	transportUDP       = "udp"
+)
+
+var errTransport = errors.New(`transport must be "tcp", "unix", "serial", "bluetooth", "http" or "udp"`) // Unknown transport value | مقدار نامعتبر transport
+
+/*
+transport is how candidate links are made. dial makes one attempt at
+the remote address and listen opens what Accept takes incoming links
+from. A link is any byte stream with deadlines: the handshake and the
+stream multiplexer on top of it do all the framing, so connReader and
+connWriter never see the transport.
+
+این interface شیوه‌ی ساخت اتصال‌های کاندید است: dial یک تلاش برای آدرس
+طرف مقابل و listen چیزی را باز می‌کند که Accept اتصال‌های ورودی را از آن
+می‌گیرد؛ اتصال هر stream بایتی با deadline است و handshake و multiplexer
+روی آن همه‌ی قاب‌بندی را انجام می‌دهند، پس connReader و connWriter انتقال
+را نمی‌بینند
+*/
+type transport interface {
+	dial(remote string) (net.Conn, error)
+	listen(addr string) (net.Listener, error)
+}
+
+// newTransport returns the transport with the given name; device and baud are for serial | انتقال با نام داده‌شده؛ device و baud برای serial
+func newTransport(name, device string, baud int) (transport, error) {
+	switch name {
+	case transportTCP:
+		return tcpTransport{}, nil
+	case transportUnix:
+		return unixTransport{}, nil
+	case transportSerial:
+		if device == "" {
+			return nil, errSerialDevice
+		}
+		return serialTransport{device: device, baud: baud}, nil
+	case transportBluetooth:
+		return bluetoothTransport{}, nil
+	case transportHTTP:
+		return pollTransport{}, nil
+	case transportUDP:
+		return udpTransport{}, nil
+	}
+	return nil, errTransport
+}
+
+// tcpTransport dials with Happy Eyeballs and listens on a TCP port | dial با Happy Eyeballs و listen روی پورت TCP
+type tcpTransport struct{}
+
+func (tcpTransport) dial(remote string) (net.Conn, error) {
+	return dialHappyEyeballs(remote)
+}
+
+func (tcpTransport) listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// unixTransport links two peers on one machine through socket files | اتصال دو peer روی یک ماشین با فایل socket
+type unixTransport struct{}
+
+func (unixTransport) dial(remote string) (net.Conn, error) {
+	return net.DialTimeout("unix", remote, dialTimeout)
+}
+
+func (unixTransport) listen(addr string) (net.Listener, error) {
+	return listenPrivate(addr) // Like the daemon socket: only our user, only over a stale socket | مانند socket daemon: فقط کاربر ما و فقط به‌جای socket کهنه
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/binary" // For spotting a link's first packet
+	"net"             // For the sockets and listener
+	"sync"            // For the peer table and closing once
+	"time"            // For the unused packet deadlines
+)
+
+const udpBacklog = 16 // Links waiting for Accept before new ones are dropped | اتصال‌های منتظر Accept پیش از رهاکردن اتصال‌های تازه
+
+/*
+udpTransport chats over UDP for networks that drop or throttle TCP, with
+the ARQ layer making every link reliable and ordered. dial opens a
+fresh socket for each attempt; listen shares one socket between all
+remotes and starts a link when a new address sends its first data
+packet. Nothing answers a dial, so a remote that is not there shows up
+as a handshake timeout.
+
+این نوع گفتگو را برای شبکه‌هایی که TCP را مسدود یا کند می‌کنند روی UDP
+انجام می‌دهد و لایه‌ی ARQ هر اتصال را قابل‌اعتماد و مرتب می‌کند؛ dial برای هر
+تلاش یک socket تازه باز می‌کند و listen یک socket را میان همه‌ی طرف‌ها تقسیم
+می‌کند و وقتی آدرس تازه‌ای اولین بسته‌ی داده‌اش را بفرستد اتصالی را شروع می‌کند.
+چیزی به dial پاسخ نمی‌دهد، پس نبودن طرف مقابل به‌صورت پایان مهلت handshake دیده می‌شود
+*/
+type udpTransport struct{}
+
+func (udpTransport) dial(remote string) (net.Conn, error) {
+	raddr, err := net.ResolveUDPAddr("udp", remote)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	return newARQConn(pc, raddr, 0), nil
+}
+
+func (udpTransport) listen(addr string) (net.Listener, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	l := &udpListener{pc: pc, peers: make(map[string]*udpPeer), conn: make(chan net.Conn, udpBacklog), closed: make(chan struct{})}
+	go l.serve()
+	return l, nil
+}
+
+/*
+udpListener hands out one link per remote address. Closing it stops new
+links; the socket stays open until the accepted ones have ended too.
+
+این نوع برای هر آدرس طرف مقابل یک اتصال می‌دهد؛ بستن آن اتصال تازه را
+متوقف می‌کند و socket تا پایان اتصال‌های پذیرفته‌شده باز می‌ماند
+*/
+type udpListener struct {
+	pc     net.PacketConn
+	mu     sync.Mutex
+	peers  map[string]*udpPeer // Live links by remote address | اتصال‌های زنده بر اساس آدرس طرف مقابل
+	conn   chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+// serve routes each packet to its remote's link, starting new ones | رساندن هر بسته به اتصال طرف مقابل و شروع اتصال‌های تازه
+func (l *udpListener) serve() {
+	b := make([]byte, arqHeader+arqPayload)
+	for {
+		n, from, err := l.pc.ReadFrom(b)
+		if err != nil {
+			_ = l.Close()
+			return
+		}
+		first := n >= arqHeader && b[0] == arqData && binary.BigEndian.Uint32(b[1:arqHeader]) == 0
+		l.mu.Lock()
+		p, ok := l.peers[from.String()]
+		if !ok && first && !l.isClosed() {
+			p = &udpPeer{l: l, key: from.String(), in: make(chan []byte, arqWindow), closed: make(chan struct{})}
+			l.peers[p.key] = p
+		}
+		l.mu.Unlock()
+		if p == nil {
+			continue // Stray packet from an ended link | بسته‌ی سرگردان از اتصال پایان‌یافته
+		}
+		if !ok {
+			c := newARQConn(p, from, 0)
+			select {
+			case l.conn <- c:
+			default:
+				c.fail(errARQClosed) // Backlog full | صف پر است
+			}
+		}
+		select {
+		case p.in <- append([]byte(nil), b[:n]...):
+		default: // Queue full: lost like any packet | صف پر است: مانند هر بسته گم می‌شود
+		}
+	}
+}
+
+func (l *udpListener) isClosed() bool {
+	select {
+	case <-l.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// remove forgets an ended link and releases the socket when it was the last | فراموش‌کردن اتصال پایان‌یافته و آزادسازی socket پس از آخرین
+func (l *udpListener) remove(key string) {
+	l.mu.Lock()
+	delete(l.peers, key)
+	idle := len(l.peers) == 0
+	l.mu.Unlock()
+	if idle && l.isClosed() {
+		_ = l.pc.Close()
+	}
+}
+
+func (l *udpListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conn:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *udpListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		l.mu.Lock()
+		idle := len(l.peers) == 0
+		l.mu.Unlock()
+		if idle {
+			_ = l.pc.Close()
+		}
+	})
+	return nil
+}
+
+func (l *udpListener) Addr() net.Addr {
+	return l.pc.LocalAddr()
+}
+
+// udpPeer is one remote's share of the listening socket | سهم یک طرف مقابل از socket گوش‌دادن
+type udpPeer struct {
+	l      *udpListener
+	key    string
+	in     chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (p *udpPeer) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case pkt := <-p.in:
+		return copy(b, pkt), udpAddrKey(p.key), nil
+	case <-p.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (p *udpPeer) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return p.l.pc.WriteTo(b, addr)
+}
+
+func (p *udpPeer) Close() error {
+	p.once.Do(func() {
+		close(p.closed)
+		p.l.remove(p.key)
+	})
+	return nil
+}
+
+func (p *udpPeer) LocalAddr() net.Addr                { return p.l.pc.LocalAddr() }
+func (p *udpPeer) SetDeadline(t time.Time) error      { return nil }
+func (p *udpPeer) SetReadDeadline(t time.Time) error  { return nil }
+func (p *udpPeer) SetWriteDeadline(t time.Time) error { return nil }
+
+// udpAddrKey is a remote address as the ARQ layer compares it | آدرس طرف مقابل به شکلی که لایه‌ی ARQ مقایسه می‌کند
+type udpAddrKey string
+
+func (udpAddrKey) Network() string  { return "udp" }
+func (a udpAddrKey) String() string { return string(a) }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors" // For spotting a help request
+	"flag"   // For the flag list after the commands
+	"fmt"    // For printing the usage
+	"io"     // For where a subcommand error is printed
+	"os"     // For the program name and stderr
+)
+
+// subcommands lists the commands in the order the usage shows them | فهرست زیرفرمان‌ها به ترتیب نمایش در راهنما
+var subcommands = []struct{ name, help string }{
+	{"chat", "chat with the peer (the default when no command is given)"},
+	{"serve", "keep the link in the background for -attach terminals (same as -daemon)"},
+	{"connect <nick>", "chat, dialing a roster peer at its saved address"},
+	{"reconnect", "chat, dialing the last peer again"},
+	{"send", "deliver one message and exit once it is acknowledged (--to, --timeout)"},
+	{"pair", "print our pairing code, or file the peer behind one"},
+	{"invite", "print an invite token and link (-ttl sets how long it lasts)"},
+	{"history", "print the stored transcript (-since, -n, words to match)"},
+	{"export", "render the stored transcript as Markdown or HTML"},
+	{"bench", "measure message throughput and p99 latency between in-process peers"},
+	{"soak", "exchange numbered messages for a long time and check none is lost"},
+	{"help", "show this help"},
+}
+
+/*
+printUsage describes the commands, then the chat flags. Only chat,
+serve, connect and reconnect start the interactive loop; send sets up
+a link for one message, and the others do their job and exit.
+
+این تابع زیرفرمان‌ها و سپس پرچم‌های چت را شرح می‌دهد؛ فقط chat، serve،
+connect و reconnect حلقه‌ی تعاملی را شروع می‌کنند؛ send برای یک پیام
+اتصال برقرار می‌کند و بقیه کار خود را انجام داده و خارج می‌شوند
+*/
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.help)
+	}
+	fmt.Fprintln(w, "\nRun a command with -h for its own flags. Chat flags:")
+	flag.PrintDefaults()
+}
+
+// subcommandExit prints the error of a one-shot subcommand and returns the exit status; -h is not an error | چاپ خطای زیرفرمان یک‌باره و بازگرداندن کد خروج؛ -h خطا نیست
+func subcommandExit(w io.Writer, what string, err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) { // Its flags were already printed | پرچم‌هایش از قبل چاپ شده‌اند
+		return 0
+	}
+	fmt.Fprintln(w, what+" error:", err)
+	return exitFailed
+}
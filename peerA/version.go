@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"       // For the update check timeout
+	"encoding/json" // For decoding the release endpoint response
+	"fmt"           // For printing notices
+	"io"            // For the status writer
+	"net/http"      // For querying the release endpoint
+	"regexp"        // For checking the shape of a release tag
+	"strconv"       // For comparing version numbers
+	"strings"       // For parsing version strings
+	"time"          // For the update check timeout
+)
+
+/*
+version is the build version, set at link time:
+
+	go build -ldflags "-X main.version=v1.4.0"
+
+protocolVersion changes whenever the wire protocol does; peers with a
+different protocol version are warned about at connect time.
+
+version نسخه‌ی build است که هنگام لینک تنظیم می‌شود؛
+protocolVersion با هر تغییر پروتکل شبکه افزایش می‌یابد و در صورت
+تفاوت با peer مقابل، هنگام اتصال هشدار داده می‌شود
+*/
+var version = "dev"
+
+const protocolVersion = 11 // Wire protocol revision | نسخه پروتکل شبکه
+
+/*
+Update check configuration
+
+مقادیر پیکربندی بررسی نسخه‌ی جدید:
+- آدرس آخرین release
+- حداکثر زمان انتظار برای پاسخ
+*/
+const (
+	releaseURL         = "https://api.github.com/repos/TheSilentBug/Channels_chat/releases/latest" // Latest release endpoint | آدرس آخرین release
+	updateCheckTimeout = 5 * time.Second                                                           // Max wait for the endpoint | حداکثر انتظار
+)
+
+/*
+warnVersionMismatch prints the remote build and warns when the remote
+speaks a different protocol version.
+
+این تابع نسخه‌ی peer مقابل را چاپ می‌کند و در صورت تفاوت
+نسخه‌ی پروتکل هشدار می‌دهد
+*/
+func warnVersionMismatch(w io.Writer, conn *handshakeConn) {
+	fmt.Fprintln(w, "Remote version:", conn.remoteVersion)
+	if conn.remoteProtocol != protocolVersion {
+		fmt.Fprintf(w, "Warning: remote speaks protocol %d, we speak %d; some features may not work\n",
+			conn.remoteProtocol, protocolVersion)
+	}
+}
+
+/*
+checkForUpdate asks the release endpoint for the latest version and
+prints a notice when it is newer than this build. Development builds
+and network errors are silently skipped.
+
+این تابع آخرین نسخه را از endpoint انتشار می‌گیرد و اگر از نسخه‌ی
+فعلی جدیدتر باشد اطلاع می‌دهد؛ نسخه‌های dev و خطاهای شبکه نادیده گرفته می‌شوند
+*/
+func checkForUpdate(w io.Writer) {
+	if version == "dev" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return
+	}
+	if notice := updateNotice(release.TagName, version); notice != "" {
+		fmt.Fprintln(w, notice)
+	}
+}
+
+// releaseTag matches the release tags we publish, such as "v1.4.0" or "v2.0.0-rc.1" | الگوی tagهای انتشار ما
+var releaseTag = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,3}(-[0-9A-Za-z.]{1,20})?$`)
+
+/*
+updateNotice returns the line announcing tag when it is newer than
+running, or "" otherwise. The tag comes from the network, so anything
+that is not shaped like one of our versions is ignored rather than
+printed to the terminal.
+
+این تابع خط اعلان tag را در صورتی که از running جدیدتر باشد برمی‌گرداند و در
+غیر این صورت رشته‌ی خالی؛ tag از شبکه می‌آید، پس هر چیزی که شکل نسخه‌های ما را
+نداشته باشد به‌جای چاپ در ترمینال نادیده گرفته می‌شود
+*/
+func updateNotice(tag, running string) string {
+	if !releaseTag.MatchString(tag) || compareVersions(tag, running) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("A newer release is available: %s (running %s)", tag, running)
+}
+
+/*
+compareVersions compares dotted versions such as "v1.10.2" numerically
+and returns -1, 0 or +1. Missing or non-numeric parts count as zero.
+
+این تابع نسخه‌هایی مثل "v1.10.2" را به‌صورت عددی مقایسه می‌کند
+و -1، 0 یا +1 برمی‌گرداند
+*/
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"         // For the diagnostic notice
+	"sync/atomic" // For the writer's progress counter
+	"time"        // For the check interval
+)
+
+/*
+Watchdog configuration
+
+مقادیر پیکربندی watchdog:
+- فاصله‌ی بررسی نویسنده‌ی چت
+- مدتی که نویسنده با وجود پیام در صف می‌تواند پیشرفتی نداشته باشد؛ چند برابر
+تایم‌اوت نوشتن، چون نویسنده‌ی سالم خیلی زودتر از آن خطا می‌دهد
+*/
+const (
+	watchdogEvery = 5 * time.Second      // How often the writer is checked | فاصله‌ی بررسی
+	watchdogStall = 4 * connWriteTimeout // No progress with a queue before tripping | مدت مجاز بدون پیشرفت
+)
+
+/*
+watchWriter trips when the chat writer has taken nothing from a
+non-empty outgoing queue for watchdogStall, e.g. wedged on a dead
+connection whose write deadline never fires. It writes a crash dump
+with every goroutine's stack and tears the link down for a redial: run
+dials again through establishConn and resumes on the ticket from this
+link, while the queued lines wait for the new writer.
+
+این تابع وقتی فعال می‌شود که نویسنده‌ی چت به مدت watchdogStall با وجود
+پیام در صف outgoing هیچ پیامی برنداشته باشد؛ مثلاً روی اتصال مرده‌ای گیر
+کرده باشد که deadline نوشتنش عمل نمی‌کند. stack همه‌ی goroutineها و وضعیت
+نشست را در گزارش خرابی ذخیره و اتصال را برای dial دوباره می‌بندد: run از طریق
+establishConn دوباره dial می‌کند و با ticket همین اتصال ازسر می‌گیرد و خطوط
+صف منتظر نویسنده‌ی تازه می‌مانند
+*/
+func watchWriter(s *session, progress *atomic.Int64) {
+	t := time.NewTicker(watchdogEvery)
+	defer t.Stop()
+	var last int64
+	var stuckSince time.Time
+	for {
+		select {
+		case <-s.done.c:
+			return
+		case now := <-t.C:
+			switch p := progress.Load(); {
+			case len(s.outgoing) == 0 || p != last:
+				last, stuckSince = p, time.Time{} // Idle or moving | بیکار یا در حال پیشرفت
+			case stuckSince.IsZero():
+				stuckSince = now
+			case now.Sub(stuckSince) >= watchdogStall:
+				fmt.Fprintf(s.status, "Watchdog: chat writer stuck for %s with %d queued; redialing\n",
+					now.Sub(stuckSince).Round(time.Second), len(s.outgoing))
+				oplog.logf(priCrit, "Watchdog: chat writer stuck for %s; tearing the link down to redial", now.Sub(stuckSince).Round(time.Second))
+				if path, err := writeCrashDump("chat writer stuck", nil); err != nil {
+					fmt.Fprintln(s.status, "Watchdog error:", err)
+				} else {
+					fmt.Fprintln(s.status, "Crash dump written to", path)
+				}
+				s.done.redial()
+				_ = s.conn.Close() // Unblocks the wedged write | آزادکردن نوشتن قفل‌شده
+				return
+			}
+		}
+	}
+}
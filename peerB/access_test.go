@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPinKey(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, fp := keyDigest(id.publicKey()), id.fingerprint
+	for _, c := range []struct {
+		name             string
+		setting, invited string
+		want             string
+		err              error
+	}{
+		{"none", "", "", "", nil},
+		{"by hash", "sha256:" + digest, "", digest, nil},
+		{"by fingerprint", "sha256:" + fp, "", fp, nil},
+		{"upper case", "sha256:" + strings.ToUpper(fp), "", fp, nil},
+		{"from the link", "", fp, fp, nil},
+		{"link and hash", "sha256:" + digest, fp, digest, nil},
+		{"hash narrows the link", "sha256:" + fp, digest, digest, nil},
+		{"link names another key", "sha256:" + digest, "0123456789abcdef", "", errPinLink},
+		{"no prefix", digest, "", "", errPinFormat},
+		{"too short", "sha256:" + fp[:15], "", "", errPinFormat},
+		{"too long", "sha256:" + digest + "0", "", "", errPinFormat},
+		{"not hex", "sha256:" + "zz" + fp[2:], "", "", errPinFormat},
+	} {
+		got, err := pinKey(c.setting, c.invited)
+		if got != c.want || err != c.err {
+			t.Errorf("%s: pinKey = %q, %v; want %q, %v", c.name, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestAdmitPin(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, _ := loadEntrySet("")
+	members, _ := loadEntrySet("")
+	digest := keyDigest(pinned.publicKey())
+	for _, c := range []struct {
+		name    string
+		pin     string
+		key     string
+		resumed bool
+		ok      bool
+	}{
+		{"no pin", "", other.publicKey(), false, true},
+		{"hash matches", digest, pinned.publicKey(), false, true},
+		{"fingerprint matches", pinned.fingerprint, pinned.publicKey(), false, true},
+		{"other key", digest, other.publicKey(), false, false},
+		{"other key by fingerprint", pinned.fingerprint, other.publicKey(), false, false},
+		{"other key resumed", digest, other.publicKey(), true, false},
+		{"unverifiable key", digest, "not a key", false, false},
+	} {
+		a := &peerAuth{id: server, bans: bans, members: members, access: accessOpen, pin: c.pin}
+		if reason, _ := a.admit(c.key, noProof, testHellos, c.resumed); (reason == "") != c.ok {
+			t.Errorf("%s: admit refused with %q, want admitted %v", c.name, reason, c.ok)
+		}
+	}
+}
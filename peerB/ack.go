@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices" // For ordering the unacknowledged IDs
+	"sync"   // For guarding the pending table
+	"time"   // For send times and latencies
+)
+
+const ctrlAck = "ack" // Delivery acknowledgement; Text is the message ID | تأیید دریافت؛ Text شناسه‌ی پیام است
+
+/*
+Acknowledgement configuration
+
+مقادیر پیکربندی تأیید دریافت:
+- مدتی که پیام ارسالی منتظر تأیید می‌ماند؛ peerهای قدیمی هیچ‌گاه تأیید نمی‌فرستند
+- حداکثر تعداد پیام‌های منتظر تأیید
+*/
+const (
+	ackExpiry     = 2 * time.Minute // Unacknowledged messages are forgotten after this | فراموش‌کردن پیام تأییدنشده
+	ackPendingMax = 4096            // Pending table size that triggers expiry | اندازه‌ای که پاک‌سازی را آغاز می‌کند
+)
+
+// deliveryBuckets are the latency histogram's upper limits in seconds | حدهای histogram تأخیر بر حسب ثانیه
+var deliveryBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+/*
+ackTracker remembers when each of our messages was queued and, when
+the remote acknowledges its ID, records the send-to-delivered latency
+in a histogram exported through /stats and /metrics.
+
+این نوع زمان قرارگرفتن هر پیام ما در صف را نگه می‌دارد و وقتی طرف
+مقابل شناسه‌ی آن را تأیید کند، تأخیر ارسال تا دریافت را در histogramی
+ثبت می‌کند که از طریق /stats و /metrics صادر می‌شود
+*/
+type ackTracker struct {
+	latency *histogram
+
+	mu      sync.Mutex
+	pending map[string]time.Time // Message ID to queue time | شناسه‌ی پیام به زمان صف
+}
+
+// newAckTracker creates a tracker and registers its metrics | ساخت ردیاب و ثبت متریک‌های آن
+func newAckTracker(m *metrics) *ackTracker {
+	a := &ackTracker{latency: newHistogram(deliveryBuckets...), pending: make(map[string]time.Time)}
+	m.addHistogram("delivery_latency_seconds", "Time from queueing a chat message to the remote's acknowledgement.", a.latency)
+	m.add("messages_unacked", "gauge", "Sent chat messages still waiting for an acknowledgement.", func() float64 {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return float64(len(a.pending))
+	})
+	return a
+}
+
+// track starts timing a queued message | شروع زمان‌سنجی یک پیام در صف
+func (a *ackTracker) track(id string) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending) >= ackPendingMax {
+		for k, t := range a.pending {
+			if now.Sub(t) > ackExpiry {
+				delete(a.pending, k) // Never acknowledged | هرگز تأیید نشد
+			}
+		}
+	}
+	a.pending[id] = now
+}
+
+// ack records the latency of an acknowledged message; unknown IDs are ignored | ثبت تأخیر پیام تأییدشده
+func (a *ackTracker) ack(id string) {
+	a.mu.Lock()
+	sent, ok := a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+	if ok {
+		a.latency.observe(time.Since(sent).Seconds())
+	}
+}
+
+/*
+unacked returns the IDs still waiting for an acknowledgement, oldest
+first: what has to be sent again on a new link.
+
+این تابع شناسه‌هایی را که هنوز منتظر تأییدند از قدیمی‌ترین برمی‌گرداند:
+همان‌هایی که باید روی اتصال تازه دوباره ارسال شوند
+*/
+func (a *ackTracker) unacked() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]string, 0, len(a.pending))
+	for id := range a.pending {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(x, y string) int { return a.pending[x].Compare(a.pending[y]) })
+	return ids
+}
+
+/*
+handleAckFrames feeds the remote's acknowledgements to the tracker.
+
+این تابع تأییدهای طرف مقابل را به ردیاب می‌دهد
+*/
+func handleAckFrames(s *session) {
+	s.ctrl.handle(ctrlAck, func(f controlFrame) {
+		s.acks.ack(f.Text)
+	})
+}
+
+/*
+acknowledge tells the remote a message with an ID arrived, streamed
+ones at their end. A cover line gets an ack for a made-up ID, so the
+acks on the control stream do not single out the real lines.
+
+این تابع دریافت پیام شناسه‌دار را اعلام می‌کند و پیام جریانی را در پایان؛
+خط پوششی تأییدی با شناسه‌ی ساختگی می‌گیرد تا تأییدهای stream کنترل خطوط
+واقعی را مشخص نکنند
+*/
+func acknowledge(s *session, m message) {
+	switch {
+	case m.Cover:
+		s.ctrl.send(controlFrame{Type: ctrlAck, Text: newMessageID()}) // Unknown to the remote, so ignored | برای طرف مقابل ناشناخته و نادیده گرفته می‌شود
+	case m.ID != "" && (m.Part == "" || m.Part == partEnd):
+		s.ctrl.send(controlFrame{Type: ctrlAck, Text: m.ID})
+	}
+}
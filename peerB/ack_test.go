@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	h := newHistogram(1, 5)
+	for _, v := range []float64{0.5, 1, 3, 5, 9} {
+		h.observe(v)
+	}
+	if got := []int64{h.cumulative(0), h.cumulative(1), h.cumulative(2)}; !slices.Equal(got, []int64{2, 4, 5}) {
+		t.Errorf("cumulative counts %v, want [2 4 5] (bounds are inclusive)", got)
+	}
+	if h.total() != 18.5 {
+		t.Errorf("sum %v, want 18.5", h.total())
+	}
+}
+
+func TestDeliveryLatency(t *testing.T) {
+	m := newMetrics()
+	acks := newAckTracker(m)
+	acks.track("a")
+	acks.track("b")
+	acks.track("c")
+	time.Sleep(20 * time.Millisecond)
+	acks.ack("b")
+	acks.ack("b")     // Acknowledged twice | دو بار تأیید
+	acks.ack("other") // Never sent | هرگز ارسال نشده
+	if got := acks.unacked(); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("unacked %v, want [a c] oldest first", got)
+	}
+
+	var prom bytes.Buffer
+	m.writePrometheus(&prom)
+	for _, want := range []string{
+		"# TYPE peerchat_delivery_latency_seconds histogram\n",
+		`peerchat_delivery_latency_seconds_bucket{le="0.01"} 0` + "\n",
+		`peerchat_delivery_latency_seconds_bucket{le="+Inf"} 1` + "\n",
+		"peerchat_delivery_latency_seconds_count 1\n",
+		"peerchat_messages_unacked 2\n",
+	} {
+		if !strings.Contains(prom.String(), want) {
+			t.Errorf("Prometheus output lacks %q:\n%s", want, prom.String())
+		}
+	}
+	if n := strings.Count(prom.String(), "# HELP peerchat_delivery_latency_seconds "); n != 1 {
+		t.Errorf("latency histogram described %d times, want once", n)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	done := newDoneSignal()
+	defer done.close()
+	s := &session{ctrl: newControlLink(done)}
+	acknowledge(s, message{ID: "x", Part: partStart}) // Streamed: acked at its end | جریانی: در پایان تأیید می‌شود
+	acknowledge(s, message{})                         // No ID, nothing to ack | بدون شناسه
+	acknowledge(s, message{ID: "x", Part: partEnd})
+	acknowledge(s, message{ID: "y"})
+	var got []string
+	for len(s.ctrl.out) > 0 {
+		f := <-s.ctrl.out
+		if f.Type != ctrlAck {
+			t.Errorf("sent %+v", f)
+		}
+		got = append(got, f.Text)
+	}
+	if !slices.Equal(got, []string{"x", "y"}) {
+		t.Errorf("acked %v, want [x y]", got)
+	}
+}
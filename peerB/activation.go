@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"       // For activation error messages
+	"net"       // For turning the inherited fd into a listener
+	"os"        // For the environment and file descriptors
+	"os/signal" // For catching shutdown signals
+	"strconv"   // For parsing LISTEN_PID / LISTEN_FDS
+	"syscall"   // For SIGTERM
+)
+
+const listenFDsStart = 3 // First fd passed by systemd (SD_LISTEN_FDS_START) | اولین fd ارسالی systemd
+
+/*
+activationListener returns the listening socket inherited through
+systemd socket activation (LISTEN_PID / LISTEN_FDS), or nil when the
+process was started normally. The variables are cleared so helpers
+started later (ffmpeg, ...) do not inherit them.
+
+این تابع socket دریافتی از systemd (socket activation) را برمی‌گرداند
+و اگر برنامه به‌صورت عادی اجرا شده باشد nil برمی‌گرداند. متغیرها پاک
+می‌شوند تا برنامه‌های فرزند آن‌ها را به ارث نبرند
+*/
+func activationListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil // Not meant for us | متعلق به این پردازه نیست
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if n > 1 {
+		return nil, fmt.Errorf("socket activation: expected 1 socket, got %d", n)
+	}
+	f := os.NewFile(listenFDsStart, "LISTEN_FD_3")
+	defer f.Close() // FileListener keeps its own dup | FileListener نسخه‌ی خودش را نگه می‌دارد
+	return net.FileListener(f)
+}
+
+/*
+handleShutdownSignals closes done on the first SIGINT/SIGTERM so every
+deferred cleanup runs (socket, daemon relay, connection). A second
+signal falls back to the default behaviour and kills the process.
+
+این تابع با اولین SIGINT/SIGTERM کانال done را می‌بندد تا همه‌ی
+پاک‌سازی‌ها اجرا شوند؛ سیگنال دوم برنامه را فوراً می‌بندد
+*/
+func handleShutdownSignals(done *doneSignal) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			done.close()
+		case <-done.c:
+		}
+		signal.Stop(sig)
+	}()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActivationListenerNotForUs(t *testing.T) {
+	for _, env := range [][2]string{
+		{"", ""},
+		{strconv.Itoa(os.Getpid() + 1), "1"}, // Meant for another process | برای پردازه‌ی دیگری
+		{strconv.Itoa(os.Getpid()), "0"},
+	} {
+		t.Setenv("LISTEN_PID", env[0])
+		t.Setenv("LISTEN_FDS", env[1])
+		if ln, err := activationListener(); ln != nil || err != nil {
+			t.Errorf("LISTEN_PID=%q LISTEN_FDS=%q: %v, %v; want a normal start", env[0], env[1], ln, err)
+		}
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "2")
+	if _, err := activationListener(); err == nil {
+		t.Error("two sockets were accepted")
+	}
+	if os.Getenv("LISTEN_PID") != "" || os.Getenv("LISTEN_FDS") != "" {
+		t.Error("the activation variables were left for child processes")
+	}
+}
+
+func TestSocketActivation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a peer")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The shell sets LISTEN_PID to its own PID, which exec keeps, as systemd does after fork | shell مانند systemd پس از fork شناسه‌ی خود را می‌گذارد و exec آن را نگه می‌دارد
+	dir := t.TempDir()
+	cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ exec "$0" "$@"`, os.Args[0], "-name", "A", "-listen", "127.0.0.1:1")
+	cmd.Env = append(os.Environ(), "PEERCHAT_TEST_PEER=1", "XDG_CONFIG_HOME="+dir, "HOME="+dir, "LISTEN_FDS=1")
+	cmd.ExtraFiles = []*os.File{f} // Becomes fd 3 | fd شماره‌ی ۳ می‌شود
+	out, errOut := newPeerOutput(), newPeerOutput()
+	cmd.Stdout, cmd.Stderr = out, errOut
+	r, w, err := os.Pipe() // Stdin stays open, so the peer keeps waiting | stdin باز می‌ماند تا peer منتظر بماند
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	cmd.Stdin = r
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	exited := make(chan struct{})
+	go func() { _ = cmd.Wait(); close(exited) }()
+	defer func() {
+		_ = cmd.Process.Kill()
+		<-exited
+	}()
+
+	timer := time.AfterFunc(20*time.Second, func() { _ = cmd.Process.Kill() })
+	defer timer.Stop()
+	errOut.waitFor(t, "Socket activation: "+ln.Addr().String(), exited)
+	if strings.Contains(errOut.String(), "Listen error") {
+		t.Errorf("the peer tried -listen as well:\n%s", errOut)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+/*
+stateFile returns where a per-name state file (ignore list, bans,
+known peers...) is kept, or "" in anonymous mode so it lives in memory
+only and nothing is written to disk.
+
+این تابع مسیر فایل وضعیت (لیست نادیده‌گیری، مسدودی، peerهای
+شناخته‌شده و ...) را برمی‌گرداند؛ در حالت ناشناس رشته‌ی خالی تا
+فقط در حافظه بماند و چیزی روی دیسک نوشته نشود
+*/
+func stateFile(anon bool, path string) string {
+	if anon {
+		return ""
+	}
+	return path
+}
+
+// guestName derives a throwaway nickname from an ephemeral key | ساخت نام موقت از کلید موقت
+func guestName(id *identity) string {
+	return "guest-" + id.fingerprint[:6]
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonWritesNothing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two peers")
+	}
+	anonDir := t.TempDir()
+	a, b := freeAddr(t), freeAddr(t)
+	anon, _, anonErr := pipePeerIn(t, anonDir, "hi from nobody\n", "-anon", "-name", "ann", "-listen", a, "-dial", b, "-wait", "2s")
+	named, namedOut, namedErr := pipePeer(t, "hello\n", "-name", "B", "-listen", b, "-dial", a, "-wait", "2s")
+	for _, cmd := range []*exec.Cmd{anon, named} {
+		waited := make(chan error, 1)
+		go func() { waited <- cmd.Wait() }()
+		select {
+		case err := <-waited:
+			if err != nil {
+				t.Fatalf("peer: %v\nanon: %s\nB: %s", err, anonErr, namedErr)
+			}
+		case <-time.After(20 * time.Second):
+			_ = anon.Process.Kill()
+			_ = named.Process.Kill()
+			t.Fatalf("peers did not exit\nanon: %s\nB: %s", anonErr, namedErr)
+		}
+	}
+
+	var from string
+	for _, line := range strings.Split(strings.TrimSpace(namedOut.String()), "\n") {
+		var m message
+		if json.Unmarshal([]byte(line), &m) == nil && m.Text == "hi from nobody" {
+			from = m.From
+		}
+	}
+	if !strings.HasPrefix(from, "guest-") {
+		t.Errorf("the anonymous message came from %q, want a guest nick instead of -name:\n%s", from, namedOut)
+	}
+	_ = filepath.WalkDir(anonDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("anonymous mode wrote %s", path)
+		}
+		return nil
+	})
+}
+
+func TestGuestName(t *testing.T) {
+	a, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guestName(a) != "guest-"+a.fingerprint[:6] || guestName(a) == guestName(b) {
+		t.Errorf("guest names %q and %q", guestName(a), guestName(b))
+	}
+	if stateFile(true, "/home/ann/.peerchat/ann.ignore") != "" || stateFile(false, "x") != "x" {
+		t.Error("stateFile does not keep anonymous state in memory")
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/binary" // For packet headers
+	"errors"          // For link failures
+	"io"              // For EOF after the remote closed
+	"math/rand/v2"    // For simulated loss
+	"net"             // For the packet link
+	"os"              // For the deadline error
+	"slices"          // For ordering held-back packets
+	"sync"            // For the shared send and receive state
+	"time"            // For retransmission timers and deadlines
+)
+
+/*
+ARQ packet kinds
+
+انواع بسته‌های ARQ:
+  - data: بخشی از جریان داده با شماره‌ی ترتیب
+  - ack: تأیید تجمعی؛ seq اولین شماره‌ای است که هنوز نرسیده و داده‌ی آن
+    بلوک‌های SACK از بسته‌های زودرس دریافت‌شده است
+  - nack: درخواست ارسال دوباره‌ی یک شماره‌ی جاافتاده
+  - fin: فرستنده اتصال را بست؛ مانند داده شماره‌ی ترتیب دارد، تا تأیید دوباره
+    ارسال می‌شود و پس از همه‌ی داده‌های پیش از خود EOF می‌شود
+*/
+const (
+	arqData byte = iota + 1
+	arqAck
+	arqNack
+	arqFin
+)
+
+/*
+ARQ configuration
+
+مقادیر پیکربندی ARQ:
+- اندازه‌ی سرآیند و حداکثر داده‌ی هر بسته (زیر MTU معمول)
+- تعداد بسته‌های تأییدنشده‌ی مجاز در راه
+- زمان انتظار پیش از ارسال دوباره و فاصله‌ی بررسی آن
+- حداکثر تلاش برای یک بسته پیش از اعلام قطع اتصال
+- مدتی که Close بسته‌های تأییدنشده را دوباره ارسال می‌کند
+*/
+const (
+	arqHeader   = 5                      // Kind byte plus 32-bit sequence number | نوع و شماره‌ی ترتیب
+	arqPayload  = 1200                   // Data bytes per packet, below common MTUs | داده‌ی هر بسته
+	arqWindow   = 32                     // Unacknowledged packets in flight | بسته‌های تأییدنشده‌ی در راه
+	arqRTO      = 200 * time.Millisecond // Initial retransmission timeout | زمان انتظار اولیه برای ارسال دوباره
+	arqTick     = 20 * time.Millisecond  // Retransmission check interval | فاصله‌ی بررسی ارسال دوباره
+	arqMaxTries = 10                     // Sends of one packet before giving up | حداکثر ارسال یک بسته
+	arqSACKMax  = arqWindow / 2          // SACK blocks per ACK, enough for every gap in the window | حداکثر بلوک‌های SACK در هر ACK
+	arqLinger   = 10 * time.Second       // Resending after Close before giving up | ارسال دوباره پس از Close پیش از رهاکردن
+)
+
+var (
+	errARQTimeout = errors.New("link lost: packets were never acknowledged") // Retries exhausted | تلاش‌ها تمام شد
+	errARQClosed  = errors.New("link closed")                                // Local Close | بسته‌شدن محلی
+)
+
+// arqSegment is one sent data or fin packet awaiting acknowledgement | یک بسته‌ی داده‌ی منتظر تأیید
+type arqSegment struct {
+	packet []byte
+	sentAt time.Time
+	rto    time.Duration // Doubles on every retransmission | با هر ارسال دوباره دو برابر می‌شود
+	tries  int
+}
+
+/*
+arqConn turns an unreliable packet link such as UDP into the reliable,
+ordered byte stream the chat expects: writes are cut into numbered
+packets kept until the remote acknowledges them, lost ones are resent
+on a NACK or after a timeout, and the receiver hands data to Read only
+in order, holding early packets back until the gap is filled. Close
+sends a numbered fin that is resent like data for up to arqLinger, so
+the remote reads EOF only after everything written before it. Deadlines
+wake blocked Read and Write calls with os.ErrDeadlineExceeded; a link
+that stops acknowledging fails after arqMaxTries sends.
+
+این نوع یک اتصال بسته‌ای غیرقابل‌اعتماد مانند UDP را به جریان بایت
+قابل‌اعتماد و مرتبی تبدیل می‌کند که چت انتظار دارد: نوشته‌ها به بسته‌های
+شماره‌دار تقسیم و تا تأیید طرف مقابل نگه داشته می‌شوند، بسته‌های گم‌شده با
+NACK یا پس از پایان مهلت دوباره ارسال می‌شوند و گیرنده داده را فقط به
+ترتیب به Read می‌دهد و بسته‌های زودرس را تا پرشدن شکاف نگه می‌دارد. Close یک
+fin شماره‌دار می‌فرستد که تا arqLinger مانند داده دوباره ارسال می‌شود، پس طرف
+مقابل فقط پس از همه‌ی داده‌های پیش از آن EOF می‌خواند. deadlineها فراخوانی‌های
+منتظر Read و Write را با os.ErrDeadlineExceeded بیدار می‌کنند؛ اتصالی که دیگر
+تأیید نفرستد پس از arqMaxTries ارسال قطع می‌شود
+*/
+type arqConn struct {
+	pc    net.PacketConn
+	raddr net.Addr
+	loss  float64 // Outgoing packets dropped on purpose, for tests | درصد بسته‌های عمداً حذف‌شده برای آزمون
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	next    uint32                 // Sequence number of the next data or fin packet | شماره‌ی بسته‌ی داده یا fin بعدی
+	base    uint32                 // First packet not cumulatively acknowledged | اولین بسته‌ی بدون تأیید تجمعی
+	unacked map[uint32]*arqSegment // Sent, not yet acknowledged | ارسال‌شده و تأییدنشده
+	expect  uint32                 // Next sequence number to deliver | شماره‌ی بعدی برای تحویل
+	early   map[uint32][]byte      // Kind and data that arrived ahead of a gap | نوع و داده‌ی زودتر از شکاف رسیده
+	buf     []byte                 // In order, not yet read | مرتب و هنوز خوانده‌نشده
+	eof     bool                   // Remote fin delivered in order | fin طرف مقابل به ترتیب رسید
+	closing time.Time              // When Close was called; zero while open | زمان Close؛ تا باز است صفر
+	err     error                  // Why the link died | دلیل قطع اتصال
+
+	readDeadline, writeDeadline time.Time   // Zero means none | صفر یعنی بدون مهلت
+	readTimer, writeTimer       *time.Timer // Wake blocked calls at the deadline | بیدارکردن فراخوانی‌های منتظر در مهلت
+}
+
+/*
+newARQConn runs the ARQ protocol with raddr over pc, which it owns from
+now on. loss drops that fraction of outgoing packets to simulate a bad
+network.
+
+این تابع پروتکل ARQ را با raddr روی pc اجرا می‌کند که از این پس متعلق
+به آن است؛ loss آن کسر از بسته‌های خروجی را برای شبیه‌سازی شبکه‌ی بد حذف می‌کند
+*/
+func newARQConn(pc net.PacketConn, raddr net.Addr, loss float64) *arqConn {
+	c := &arqConn{pc: pc, raddr: raddr, loss: loss, unacked: make(map[uint32]*arqSegment), early: make(map[uint32][]byte)}
+	c.cond = sync.NewCond(&c.mu)
+	go c.receive()
+	go c.retransmit()
+	return c
+}
+
+// arqPacket builds one ARQ packet | ساخت یک بسته‌ی ARQ
+func arqPacket(kind byte, seq uint32, data []byte) []byte {
+	p := make([]byte, arqHeader+len(data))
+	p[0] = kind
+	binary.BigEndian.PutUint32(p[1:arqHeader], seq)
+	copy(p[arqHeader:], data)
+	return p
+}
+
+// send puts a packet on the wire, unless simulated loss eats it | ارسال بسته، مگر حذف عمدی
+func (c *arqConn) send(p []byte) {
+	if c.loss > 0 && rand.Float64() < c.loss {
+		return
+	}
+	_, _ = c.pc.WriteTo(p, c.raddr) // Loss is what the protocol handles | گم‌شدن را خود پروتکل جبران می‌کند
+}
+
+// fail ends the link with err; the first error wins | پایان اتصال با err؛ اولین خطا می‌ماند
+func (c *arqConn) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	_ = c.pc.Close()
+}
+
+func (c *arqConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), arqPayload)
+		c.mu.Lock()
+		for c.next-c.base >= arqWindow && c.err == nil && c.closing.IsZero() && !arqExpired(c.writeDeadline) {
+			c.cond.Wait() // Window full; SACKed packets do not move it | پنجره پر است؛ بسته‌های SACKشده آن را جلو نمی‌برند
+		}
+		if err := c.blocked(c.writeDeadline); err != nil {
+			c.mu.Unlock()
+			return written, err
+		}
+		seg := &arqSegment{packet: arqPacket(arqData, c.next, p[:n]), sentAt: time.Now(), rto: arqRTO, tries: 1}
+		c.unacked[c.next] = seg
+		c.next++
+		c.mu.Unlock()
+		c.send(seg.packet)
+		p, written = p[n:], written+n
+	}
+	return written, nil
+}
+
+func (c *arqConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.buf) == 0 && !c.eof && c.err == nil && c.closing.IsZero() && !arqExpired(c.readDeadline) {
+		c.cond.Wait()
+	}
+	if !c.closing.IsZero() {
+		return 0, errARQClosed
+	}
+	if len(c.buf) > 0 {
+		n := copy(p, c.buf)
+		c.buf = c.buf[n:]
+		return n, nil
+	}
+	if c.eof {
+		return 0, io.EOF
+	}
+	return 0, c.blocked(c.readDeadline)
+}
+
+// blocked says why a call cannot go on, or nil when it can; c.mu is held | دلیل ادامه‌ندادن فراخوانی یا nil؛ c.mu گرفته شده است
+func (c *arqConn) blocked(deadline time.Time) error {
+	switch {
+	case !c.closing.IsZero():
+		return errARQClosed
+	case c.err != nil:
+		return c.err
+	case arqExpired(deadline):
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// arqExpired reports whether deadline has passed; a zero one never does | آیا مهلت گذشته است؛ مهلت صفر هرگز
+func arqExpired(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// receive handles packets from the remote until the link dies | پردازش بسته‌های طرف مقابل تا قطع اتصال
+func (c *arqConn) receive() {
+	b := make([]byte, arqHeader+arqPayload)
+	for {
+		n, from, err := c.pc.ReadFrom(b)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if n < arqHeader || from.String() != c.raddr.String() {
+			continue // Not ours | متعلق به این اتصال نیست
+		}
+		seq := binary.BigEndian.Uint32(b[1:arqHeader])
+		switch b[0] {
+		case arqData, arqFin:
+			c.deliver(b[0], seq, b[arqHeader:n])
+		case arqAck:
+			c.acked(seq, b[arqHeader:n])
+		case arqNack:
+			c.resend(seq)
+		}
+	}
+}
+
+/*
+deliver accepts one data or fin packet: the expected one is handed to
+Read together with any held-back packets that follow it, an early one
+is held back and NACKs the missing ones, and a duplicate is dropped. An
+ACK of everything delivered so far answers each of them. A fin may sit
+one past a full window, since Close does not wait for room.
+
+این تابع یک بسته‌ی داده یا fin را می‌پذیرد: بسته‌ی مورد انتظار همراه با بسته‌های
+نگه‌داشته‌ی پس از آن به Read داده می‌شود، بسته‌ی زودرس نگه داشته و برای
+بسته‌های جاافتاده NACK ارسال می‌شود و بسته‌ی تکراری کنار گذاشته می‌شود؛
+پاسخ همه‌ی آن‌ها یک ACK از همه‌ی داده‌های تحویل‌شده است. fin ممکن است یکی
+پس از پنجره‌ی پر باشد، چون Close منتظر جا نمی‌ماند
+*/
+func (c *arqConn) deliver(kind byte, seq uint32, data []byte) {
+	var sack []byte
+	c.mu.Lock()
+	var missing []uint32
+	switch {
+	case seq == c.expect:
+		c.take(kind, data)
+		for p, ok := c.early[c.expect]; ok; p, ok = c.early[c.expect] {
+			delete(c.early, c.expect)
+			c.take(p[0], p[1:])
+		}
+		c.cond.Broadcast()
+	case seq > c.expect && seq-c.expect <= arqWindow:
+		if _, held := c.early[seq]; !held {
+			c.early[seq] = append([]byte{kind}, data...)
+			for m := c.expect; m < seq; m++ {
+				if _, held := c.early[m]; !held {
+					missing = append(missing, m)
+				}
+			}
+		}
+	}
+	expect := c.expect
+	if len(c.early) > 0 {
+		sack = sackBlocks(c.early)
+	}
+	c.mu.Unlock()
+	for _, m := range missing {
+		c.send(arqPacket(arqNack, m, nil))
+	}
+	c.send(arqPacket(arqAck, expect, sack))
+}
+
+// take hands the next packet in order to Read: data to the buffer, fin as EOF | تحویل بسته‌ی مرتب بعدی به Read: داده به بافر و fin به‌صورت EOF
+func (c *arqConn) take(kind byte, data []byte) {
+	if kind == arqFin {
+		c.eof = true
+	} else {
+		c.buf = append(c.buf, data...)
+	}
+	c.expect++
+}
+
+/*
+sackBlocks encodes the held-back packets as selective acknowledgement
+blocks: pairs of 32-bit sequence numbers, each the first and one past
+the last of a run that arrived, lowest first.
+
+این تابع بسته‌های نگه‌داشته را به بلوک‌های تأیید انتخابی تبدیل می‌کند:
+جفت‌هایی از شماره‌های ۳۲ بیتی، اولین شماره و یکی پس از آخرین شماره‌ی هر
+بازه‌ی رسیده، از کوچک‌ترین
+*/
+func sackBlocks(early map[uint32][]byte) []byte {
+	seqs := make([]uint32, 0, len(early))
+	for seq := range early {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	var out []byte
+	for i := 0; i < len(seqs) && len(out) < arqSACKMax*8; {
+		start, end := seqs[i], seqs[i]+1
+		for i++; i < len(seqs) && seqs[i] == end; i++ {
+			end++
+		}
+		out = binary.BigEndian.AppendUint32(out, start)
+		out = binary.BigEndian.AppendUint32(out, end)
+	}
+	return out
+}
+
+/*
+acked forgets every packet below next and every packet inside the SACK
+blocks, so a timeout resends only what is really missing instead of
+everything after the first loss.
+
+این تابع همه‌ی بسته‌های پیش از next و بسته‌های داخل بلوک‌های SACK را
+فراموش می‌کند تا پایان مهلت فقط بسته‌های واقعاً گم‌شده را دوباره ارسال کند،
+نه همه‌ی بسته‌های پس از اولین گم‌شدن
+*/
+func (c *arqConn) acked(next uint32, sack []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if next-c.base > c.next-c.base {
+		return // Stale or malformed | قدیمی یا نامعتبر
+	}
+	for ; c.base < next; c.base++ {
+		delete(c.unacked, c.base)
+	}
+	for ; len(sack) >= 8; sack = sack[8:] {
+		start, end := binary.BigEndian.Uint32(sack), binary.BigEndian.Uint32(sack[4:])
+		if end-start > arqWindow {
+			continue // Malformed block | بلوک نامعتبر
+		}
+		for seq := start; seq < end; seq++ {
+			delete(c.unacked, seq) // Held by the receiver, which never drops it | نزد گیرنده نگه داشته شده است
+		}
+	}
+	c.cond.Broadcast()
+}
+
+// resend answers a NACK at once the first time, then at most once per tick | پاسخ فوری به اولین NACK و سپس حداکثر یک بار در هر دوره
+func (c *arqConn) resend(seq uint32) {
+	c.mu.Lock()
+	seg, ok := c.unacked[seq]
+	if !ok || (seg.tries > 1 && time.Since(seg.sentAt) < arqTick) {
+		c.mu.Unlock()
+		return
+	}
+	seg.sentAt, seg.tries = time.Now(), seg.tries+1
+	c.mu.Unlock()
+	c.send(seg.packet)
+}
+
+/*
+retransmit resends packets whose timeout passed, backing off each time.
+After Close it ends the link once everything, fin included, is
+acknowledged or arqLinger has passed.
+
+این تابع بسته‌های منقضی را با افزایش مهلت دوباره ارسال می‌کند؛ پس از
+Close وقتی همه‌چیز همراه با fin تأیید شد یا arqLinger گذشت اتصال را پایان می‌دهد
+*/
+func (c *arqConn) retransmit() {
+	t := time.NewTicker(arqTick)
+	defer t.Stop()
+	for now := range t.C {
+		var due [][]byte
+		c.mu.Lock()
+		if c.err != nil {
+			c.mu.Unlock()
+			return
+		}
+		if !c.closing.IsZero() && (len(c.unacked) == 0 || now.Sub(c.closing) > arqLinger) {
+			c.mu.Unlock()
+			c.fail(errARQClosed)
+			return
+		}
+		for _, seg := range c.unacked {
+			if now.Sub(seg.sentAt) < seg.rto {
+				continue
+			}
+			if seg.tries >= arqMaxTries {
+				c.mu.Unlock()
+				c.fail(errARQTimeout)
+				return
+			}
+			seg.sentAt, seg.rto, seg.tries = now, 2*seg.rto, seg.tries+1
+			due = append(due, seg.packet)
+		}
+		c.mu.Unlock()
+		for _, p := range due {
+			c.send(p)
+		}
+	}
+}
+
+/*
+Close fails local calls at once and queues a fin after everything
+written; retransmit keeps the link up until the remote has it all.
+
+این تابع فراخوانی‌های محلی را فوراً با خطا پایان می‌دهد و یک fin پس از
+همه‌ی داده‌های نوشته‌شده در صف می‌گذارد؛ retransmit تا رسیدن همه‌ی آن‌ها به
+طرف مقابل اتصال را نگه می‌دارد
+*/
+func (c *arqConn) Close() error {
+	c.mu.Lock()
+	if c.err != nil || !c.closing.IsZero() {
+		c.mu.Unlock()
+		return nil
+	}
+	seg := &arqSegment{packet: arqPacket(arqFin, c.next, nil), sentAt: time.Now(), rto: arqRTO, tries: 1}
+	c.unacked[c.next] = seg
+	c.next++
+	c.closing = seg.sentAt
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	c.send(seg.packet)
+	return nil
+}
+
+func (c *arqConn) LocalAddr() net.Addr  { return c.pc.LocalAddr() }
+func (c *arqConn) RemoteAddr() net.Addr { return c.raddr }
+
+func (c *arqConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline, c.readTimer = t, c.wakeAt(c.readTimer, t)
+	c.writeDeadline, c.writeTimer = t, c.wakeAt(c.writeTimer, t)
+	return nil
+}
+
+func (c *arqConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline, c.readTimer = t, c.wakeAt(c.readTimer, t)
+	return nil
+}
+
+func (c *arqConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline, c.writeTimer = t, c.wakeAt(c.writeTimer, t)
+	return nil
+}
+
+// wakeAt replaces timer with one that wakes blocked calls at t; c.mu is held | جایگزینی timer با یکی که فراخوانی‌های منتظر را در t بیدار می‌کند
+func (c *arqConn) wakeAt(timer *time.Timer, t time.Time) *time.Timer {
+	if timer != nil {
+		timer.Stop()
+	}
+	c.cond.Broadcast() // The new deadline may have passed already | مهلت جدید ممکن است گذشته باشد
+	if t.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(time.Until(t), func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"           // For command output
+	"io"            // For reading the sniffed prefix
+	"mime"          // For types by file extension
+	"net/http"      // For sniffing content types
+	"os"            // For the sniffed file
+	"path"          // For matching MIME patterns
+	"path/filepath" // For the attachment name
+	"strings"       // For splitting the accepted types
+)
+
+func init() {
+	registerCommand("send", "/send <path>  send a file as an attachment", sendCommand)
+	registerArgCompleter("send", completePath)
+}
+
+/*
+sendCommand sends a local file as a typed attachment. The transfer runs
+in the background so typing is not blocked while it flows.
+
+این دستور یک فایل محلی را به‌صورت پیوست نوع‌دار ارسال می‌کند؛ انتقال در
+پس‌زمینه انجام می‌شود تا تایپ کردن متوقف نشود
+*/
+func sendCommand(s *session, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(stdout, "Usage: /send <path>")
+		return
+	}
+	p := strings.Join(args, " ") // Paths may contain spaces | مسیر ممکن است فاصله داشته باشد
+
+	go func() {
+		h := fileHeader{From: s.name, Name: filepath.Base(p)}
+		sum, err := sendFile(s, p, h)
+		if err != nil {
+			fmt.Fprintln(stdout, "Send error:", err)
+			oplog.logf(priErr, "Send error: %v", err)
+			return
+		}
+		fmt.Fprintf(stdout, "File sent: %s  sha256 %s\n", h.Name, sum)
+		oplog.logf(priInfo, "Sent a file, sha256 %s", sum)
+		recordSent(s, fmt.Sprintf("[file: %s]", h.Name))
+	}()
+}
+
+/*
+detectMIME guesses the content type of f from its extension, falling
+back to sniffing its first bytes, and rewinds it.
+
+این تابع نوع محتوای f را از پسوند آن و در غیر این صورت از بایت‌های
+ابتدایی حدس می‌زند و به ابتدای فایل برمی‌گردد
+*/
+func detectMIME(f *os.File) string {
+	if t := mime.TypeByExtension(filepath.Ext(f.Name())); t != "" {
+		return t
+	}
+	buf := make([]byte, 512) // All DetectContentType considers | تمام بایت‌هایی که بررسی می‌شود
+	n, _ := io.ReadFull(f, buf)
+	_, _ = f.Seek(0, io.SeekStart)
+	return http.DetectContentType(buf[:n])
+}
+
+// parseAccept splits a comma separated list of MIME patterns | جداسازی الگوهای MIME
+func parseAccept(list string) []string {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, strings.ToLower(p))
+		}
+	}
+	return patterns
+}
+
+/*
+refusal returns why an offered file is refused by type, or "" to accept
+it: its type must match one of the accepted patterns, such as
+"image/*". Parameters like "; charset=utf-8" are ignored when matching.
+Nothing is written to disk unless the user opted in, so with no
+patterns every file is refused. Size limits are checked when the file
+store reserves room for it.
+
+این تابع دلیل رد یک فایل پیشنهادی بر اساس نوع را برمی‌گرداند یا "" برای پذیرش؛
+نوع فایل باید با یکی از الگوهای پذیرفتنی مانند "image/*" بخواند.
+پارامترهایی مانند "; charset=utf-8" در تطبیق نادیده گرفته می‌شوند.
+تا کاربر خودش نپذیرد چیزی روی دیسک نوشته نمی‌شود، پس بدون الگو همه‌ی فایل‌ها
+رد می‌شوند. محدودیت اندازه هنگام رزرو جا در fileStore بررسی می‌شود
+*/
+func refusal(s *session, h fileHeader) string {
+	if len(s.accepts) == 0 {
+		return "files are not accepted here"
+	}
+	t, _, err := mime.ParseMediaType(h.MIME)
+	if err != nil {
+		t = "application/octet-stream" // Untyped is plain bytes | بدون نوع یعنی بایت خام
+	}
+	for _, p := range s.accepts {
+		if ok, _ := path.Match(p, t); ok {
+			return ""
+		}
+	}
+	return "type " + t + " not accepted"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBandwidthMeter(t *testing.T) {
+	meter := &bandwidthMeter{}
+	local, remote := net.Pipe()
+	defer remote.Close()
+	conn := meter.wrap(local)
+	defer conn.Close()
+
+	go func() {
+		_, _ = io.CopyN(io.Discard, remote, 1000)
+		_, _ = remote.Write(make([]byte, 500))
+	}()
+	if _, err := conn.Write(make([]byte, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 500)); err != nil {
+		t.Fatal(err)
+	}
+	if meter.up.Load() != 1000 || meter.down.Load() != 500 {
+		t.Errorf("counted %d up and %d down, want 1000 and 500", meter.up.Load(), meter.down.Load())
+	}
+
+	time.Sleep(bandwidthSample + bandwidthSample/4)
+	up, down := meter.rates()
+	if up <= 0 || up >= 1000 || down <= 0 || down >= up {
+		t.Errorf("rates %.0f up and %.0f down B/s, want smoothed below the first second's bytes", up, down)
+	}
+
+	m := &metrics{}
+	meter.addMetrics(m)
+	var prom bytes.Buffer
+	m.writePrometheus(&prom)
+	if !strings.Contains(prom.String(), `peerchat_link_bytes_total{direction="up"} 1000`) || !strings.Contains(prom.String(), `peerchat_link_bytes_total{direction="down"} 500`) {
+		t.Errorf("Prometheus output:\n%s", prom.String())
+	}
+	if got := meter.String(); !strings.HasPrefix(got, "Bandwidth: up ") || !strings.Contains(got, " B/s, down ") {
+		t.Errorf("/stats line %q", got)
+	}
+}
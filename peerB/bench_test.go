@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchSizes are the chat line sizes each benchmark runs at | اندازه‌های خط چت در هر benchmark
+var benchSizes = []int{64, 1024, 16384}
+
+/*
+BenchmarkChat sends signed chat messages between two in-process peers
+over each transport, through the same mux, writer and reader the chat
+uses, and reports the p99 latency under full load, like the bench
+subcommand:
+
+	go test -run '^$' -bench Chat/tcp
+
+این benchmark پیام‌های امضاشده را روی هر انتقال و از همان مسیر mux،
+نویسنده و خواننده‌ی چت بین دو peer درون یک پردازه می‌فرستد و تأخیر p99
+زیر بار کامل را گزارش می‌دهد
+*/
+func BenchmarkChat(b *testing.B) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer id.wipe()
+	for _, transport := range []string{"pipe", "tcp", "udp"} {
+		for _, size := range benchSizes {
+			b.Run(fmt.Sprintf("%s/%d", transport, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				_, p99, err := benchChat(transport, id, size, b.N, b.ResetTimer)
+				b.StopTimer()
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(p99), "p99-ns")
+			})
+		}
+	}
+}
+
+/*
+BenchmarkRelayFanout publishes b.N lines to a daemon relay with a full
+history, a local log and four attached clients.
+
+این benchmark تعداد b.N خط را برای relay حالت daemon با تاریخچه‌ی پر،
+log محلی و چهار کلاینت متصل منتشر می‌کند
+*/
+func BenchmarkRelayFanout(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			d := &daemonRelay{clients: make(map[net.Conn]struct{}), local: io.Discard}
+			for i := 0; i < 4; i++ {
+				d.clients[&discardConn{}] = struct{}{}
+			}
+			line := []byte(strings.Repeat("x", size))
+			for i := 0; i < daemonHistoryLines; i++ {
+				d.publish(line) // Steady state: every slot has its buffer | حالت پایدار: هر خانه بافر دارد
+			}
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d.publish(line)
+			}
+		})
+	}
+}
+
+func TestRunBenchFlags(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want error
+	}{
+		{[]string{"-sizes", "0"}, errBenchSize},
+		{[]string{"-sizes", "64,x"}, errBenchSize},
+		{[]string{"-sizes", strconv.Itoa(maxMessageSize)}, errBenchSize},
+		{[]string{"-transport", "carrier-pigeon"}, errBenchTransport},
+		{[]string{"-n", "10"}, errBenchCount},
+	} {
+		if err := runBench(c.args); !errors.Is(err, c.want) {
+			t.Errorf("runBench(%q) = %v, want %v", c.args, err, c.want)
+		}
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the chat path")
+	}
+	if err := runBench([]string{"-sizes", "64", "-transport", "pipe,udp", "-n", "200", "-relay-clients", "2"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net"  // For the link and listener types
+	"os"   // For polling the sockets
+	"time" // For the connect budget
+
+	"golang.org/x/sys/unix" // For the RFCOMM sockets
+)
+
+// sockaddr returns a in the kernel's byte order, least significant first | آدرس به ترتیب بایت هسته، کم‌ارزش‌ترین اول
+func (a rfcommAddr) sockaddr() *unix.SockaddrRFCOMM {
+	sa := &unix.SockaddrRFCOMM{Channel: a.channel}
+	for i, b := range a.device {
+		sa.Addr[len(sa.Addr)-1-i] = b
+	}
+	return sa
+}
+
+// rfcommFromSockaddr is the reverse of sockaddr | عکس sockaddr
+func rfcommFromSockaddr(sa unix.Sockaddr) rfcommAddr {
+	var a rfcommAddr
+	if rc, ok := sa.(*unix.SockaddrRFCOMM); ok {
+		a.channel = rc.Channel
+		for i, b := range rc.Addr {
+			a.device[len(a.device)-1-i] = b
+		}
+	}
+	return a
+}
+
+// rfcommSocket opens a non-blocking socket, so the runtime poller serves it | socket غیرمسدودکننده تا poller زمان اجرا آن را سرویس دهد
+func rfcommSocket() (*os.File, error) {
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.BTPROTO_RFCOMM)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	return os.NewFile(uintptr(fd), "rfcomm"), nil
+}
+
+/*
+dialRFCOMM connects to a, giving up after dialTimeout. The connect runs
+in the background and the poller reports when it is done, like a TCP
+dial.
+
+این تابع به a وصل می‌شود و پس از dialTimeout منصرف می‌شود؛ connect در
+پس‌زمینه اجرا می‌شود و poller مانند dial در TCP پایان آن را خبر می‌دهد
+*/
+func dialRFCOMM(a rfcommAddr) (net.Conn, error) {
+	f, err := rfcommSocket()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.SyscallConn()
+	if err == nil {
+		_ = f.SetWriteDeadline(time.Now().Add(dialTimeout))
+		var connErr error
+		started := false
+		err = rc.Write(func(fd uintptr) bool {
+			if !started {
+				started = true
+				connErr = unix.Connect(int(fd), a.sockaddr())
+				return connErr != unix.EINPROGRESS
+			}
+			if _, err := unix.Getpeername(int(fd)); err == nil {
+				connErr = nil
+				return true
+			}
+			n, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ERROR)
+			switch {
+			case err != nil:
+				connErr = err
+			case n != 0:
+				connErr = unix.Errno(n)
+			default:
+				return false // Still connecting | هنوز در حال اتصال
+			}
+			return true
+		})
+		if err == nil {
+			err = connErr
+		}
+		_ = f.SetWriteDeadline(time.Time{})
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, &net.OpError{Op: "dial", Net: "rfcomm", Addr: a, Err: err}
+	}
+	return newRFCOMMConn(f, a), nil
+}
+
+// listenRFCOMM binds a and listens for one peer at a time | bind روی a و گوش‌دادن برای یک peer در هر زمان
+func listenRFCOMM(a rfcommAddr) (net.Listener, error) {
+	f, err := rfcommSocket()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.SyscallConn()
+	if err == nil {
+		ctlErr := rc.Control(func(fd uintptr) {
+			if err = unix.Bind(int(fd), a.sockaddr()); err == nil {
+				err = unix.Listen(int(fd), 1)
+			}
+		})
+		if err == nil {
+			err = ctlErr
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, &net.OpError{Op: "listen", Net: "rfcomm", Addr: a, Err: err}
+	}
+	return &rfcommListener{f: f, addr: a}, nil
+}
+
+// rfcommListener accepts RFCOMM links | پذیرش اتصال‌های RFCOMM
+type rfcommListener struct {
+	f    *os.File
+	addr rfcommAddr
+}
+
+func (l *rfcommListener) Accept() (net.Conn, error) {
+	rc, err := l.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var nfd int
+	var sa unix.Sockaddr
+	var acceptErr error
+	err = rc.Read(func(fd uintptr) bool {
+		nfd, sa, acceptErr = unix.Accept4(int(fd), unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC)
+		return acceptErr != unix.EAGAIN
+	})
+	if err == nil {
+		err = acceptErr
+	}
+	if err != nil {
+		return nil, &net.OpError{Op: "accept", Net: "rfcomm", Addr: l.addr, Err: err}
+	}
+	return newRFCOMMConn(os.NewFile(uintptr(nfd), "rfcomm"), rfcommFromSockaddr(sa)), nil
+}
+
+func (l *rfcommListener) Close() error   { return l.f.Close() }
+func (l *rfcommListener) Addr() net.Addr { return l.addr }
+
+// rfcommConn is an RFCOMM socket; the file already has deadlines | socket RFCOMM؛ فایل خود deadline دارد
+type rfcommConn struct {
+	*os.File
+	local, remote rfcommAddr
+}
+
+// newRFCOMMConn wraps a connected socket with its addresses | بسته‌بندی socket متصل همراه با آدرس‌هایش
+func newRFCOMMConn(f *os.File, remote rfcommAddr) *rfcommConn {
+	c := &rfcommConn{File: f, remote: remote}
+	if rc, err := f.SyscallConn(); err == nil {
+		_ = rc.Control(func(fd uintptr) {
+			if sa, err := unix.Getsockname(int(fd)); err == nil {
+				c.local = rfcommFromSockaddr(sa)
+			}
+		})
+	}
+	return c
+}
+
+func (c *rfcommConn) LocalAddr() net.Addr  { return c.local }
+func (c *rfcommConn) RemoteAddr() net.Addr { return c.remote }
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestRFCOMMSockaddrByteOrder(t *testing.T) {
+	a, err := parseRFCOMMAddr("01:02:03:04:05:06/7", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa := a.sockaddr()
+	if sa.Addr != [6]byte{6, 5, 4, 3, 2, 1} || sa.Channel != 7 {
+		t.Fatalf("sockaddr %v channel %d, want the device least significant byte first", sa.Addr, sa.Channel)
+	}
+	if back := rfcommFromSockaddr(sa); back != a {
+		t.Fatalf("round trip gave %v, want %v", back, a)
+	}
+}
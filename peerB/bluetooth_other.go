@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors" // For the unsupported platform
+	"net"    // For the link and listener types
+)
+
+var errBluetoothPlatform = errors.New("bluetooth is only supported on Linux") // No RFCOMM sockets here | socket RFCOMM برای این سیستم نیست
+
+// dialRFCOMM is not available on this platform | dialRFCOMM روی این سیستم در دسترس نیست
+func dialRFCOMM(rfcommAddr) (net.Conn, error) {
+	return nil, errBluetoothPlatform
+}
+
+// listenRFCOMM is not available on this platform | listenRFCOMM روی این سیستم در دسترس نیست
+func listenRFCOMM(rfcommAddr) (net.Listener, error) {
+	return nil, errBluetoothPlatform
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// codeSession returns a session whose queued lines arrive on out | نشستی که خطوط صف‌شده‌اش به out می‌رسند
+func codeSession(t *testing.T, caps capabilities, out chan string) *session {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := newDoneSignal()
+	t.Cleanup(done.close)
+	m := newMetrics()
+	return &session{
+		name:     "ann",
+		conn:     &handshakeConn{caps: caps},
+		id:       id,
+		threads:  newThreadIndex(),
+		metrics:  m,
+		acks:     newAckTracker(m),
+		outgoing: out,
+		done:     done,
+	}
+}
+
+func TestRenderCode(t *testing.T) {
+	got := renderCode("go", "package main\n\nfunc main() {}\n")
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "\x1b[0m1 │ ") || !strings.HasPrefix(lines[2], "\x1b[0m3 │ ") || !strings.HasSuffix(got, "\x1b[0m") {
+		t.Errorf("rendering %q, want three numbered lines ending in a reset", got)
+	}
+	if !strings.Contains(lines[0], "\x1b[38;5;") || stripANSI(lines[2]) != "3 │ func main() {}" {
+		t.Errorf("Go not highlighted: %q", got)
+	}
+
+	ten := strings.Repeat("x\n", 10)
+	if got := renderCode("no-such-language", ten); !strings.HasPrefix(got, "\x1b[0m 1 │ ") || !strings.Contains(got, "\x1b[0m10 │ ") {
+		t.Errorf("numbers not padded to the widest: %q", got)
+	}
+}
+
+// stripANSI drops terminal escapes | حذف دنباله‌های ترمینال
+func stripANSI(s string) string {
+	return terminalEscape.ReplaceAllString(s, "")
+}
+
+func TestDisplayCode(t *testing.T) {
+	got := displayMessage(message{From: "bob", Text: "x := 1\n", ID: "c1", Code: "go\x1b]0;pwned\x07", Verified: true})
+	head, body, _ := strings.Cut(got, "\n")
+	if head != "RECV -> bob: [go]0;pwned code]  #c1" {
+		t.Errorf("header %q", head)
+	}
+	if stripANSI(body) != "1 │ x := 1" {
+		t.Errorf("body %q", body)
+	}
+}
+
+func TestCodeCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	keys, _ := loadRegistry("")
+
+	out := make(chan string, 4)
+	s := codeSession(t, capabilities{MaxMessage: 4096, CodeSnippets: true}, out)
+	runCommand(s, "/code Go")
+	for _, line := range []string{"func f() {", "\treturn", "}", " /end "} {
+		handleInput(s, line)
+	}
+	m, ok := decodeChatLine(<-out, keys)
+	if !ok || m.Code != "go" || m.Text != "func f() {\n\treturn\n}" {
+		t.Errorf("sent %+v, want the Go snippet with its indentation", m)
+	}
+	if !strings.Contains(buf.String(), "Code sent (go, 3 lines)  #"+m.ID) {
+		t.Errorf("output:\n%s", buf.String())
+	}
+
+	s = codeSession(t, capabilities{MaxMessage: 4096}, out)
+	runCommand(s, "/code")
+	handleInput(s, "plain")
+	handleInput(s, "/end")
+	if m, _ := decodeChatLine(<-out, keys); m.Code != "" || m.Text != "plain" {
+		t.Errorf("sent %+v to a remote without snippets, want plain text", m)
+	}
+	runCommand(s, "/code")
+	handleInput(s, "/end")
+	if len(out) != 0 || !strings.Contains(buf.String(), "Discarded empty snippet") {
+		t.Errorf("empty snippet sent; output:\n%s", buf.String())
+	}
+}
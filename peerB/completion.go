@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"           // For listing ambiguous completions
+	"os"            // For completing paths
+	"path/filepath" // For splitting paths
+	"sort"          // For a stable candidate order
+	"strings"       // For matching prefixes
+	"unicode/utf8"  // For not splitting a character
+)
+
+const completionList = 40 // Most candidates printed for an ambiguous Tab | بیشترین گزینه‌های چاپ‌شده
+
+/*
+wordCompleter lists the completions of a word that starts with the
+character it is registered for, such as "@" for nicks.
+
+این نوع تکمیل‌های کلمه‌ای را فهرست می‌کند که با نویسه‌ی ثبت‌شده‌ی آن شروع
+می‌شود، مانند "@" برای نام‌ها
+*/
+type wordCompleter func(s *session, word string) []string
+
+/*
+argCompleter lists the completions of the word being typed after a
+command; args are the complete arguments before it.
+
+این نوع تکمیل‌های کلمه‌ی در حال تایپ پس از یک دستور را فهرست می‌کند؛
+args آرگومان‌های کامل پیش از آن هستند
+*/
+type argCompleter func(s *session, args []string, word string) []string
+
+var (
+	wordCompleters = make(map[byte]wordCompleter)  // By leading character | بر اساس نویسه‌ی اول
+	argCompleters  = make(map[string]argCompleter) // By command name | بر اساس نام دستور
+)
+
+/*
+registerWordCompleter and registerArgCompleter add completers; like
+commands, features register theirs from init.
+
+این توابع completer ثبت می‌کنند؛ مانند دستورها، هر قابلیت completerهای خود
+را در init ثبت می‌کند
+*/
+func registerWordCompleter(lead byte, c wordCompleter) {
+	wordCompleters[lead] = c
+}
+
+func registerArgCompleter(name string, c argCompleter) {
+	argCompleters[name] = c
+}
+
+func init() {
+	registerWordCompleter('@', completeNick)
+}
+
+/*
+tabCompleter returns the editor callback that completes the word before
+the cursor on Tab: a lone match is filled in, several are extended to
+their common prefix or listed above the prompt.
+
+این تابع callback ویرایشگر را برمی‌گرداند که با Tab کلمه‌ی پیش از مکان‌نما را
+کامل می‌کند: تنها گزینه جایگزین می‌شود و چند گزینه تا پیشوند مشترکشان
+کامل یا بالای خط ورودی فهرست می‌شوند
+*/
+func tabCompleter(s *session) func(line string, pos int, key rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		start := strings.LastIndexByte(line[:pos], ' ') + 1
+		word := line[start:pos]
+		cands := candidates(s, line[:start], word)
+		if len(cands) == 0 {
+			return "", 0, false
+		}
+		sort.Strings(cands)
+		fill := cands[0]
+		if len(cands) > 1 {
+			fill = commonPrefix(cands)
+			if len(fill) <= len(word) {
+				if len(cands) > completionList {
+					cands = append(cands[:completionList], "…")
+				}
+				fmt.Fprintln(stdout, strings.Join(cands, "  "))
+				return "", 0, false
+			}
+		} else if !strings.HasSuffix(fill, "/") {
+			fill += " " // Done with this word | کلمه کامل شد
+		}
+		return line[:start] + fill + line[pos:], start + len(fill), true
+	}
+}
+
+// candidates lists the completions of word after before | فهرست تکمیل‌های word پس از before
+func candidates(s *session, before, word string) []string {
+	if before == "" && strings.HasPrefix(word, "/") {
+		var out []string
+		for name := range commands {
+			if strings.HasPrefix(name, word[1:]) {
+				out = append(out, "/"+name)
+			}
+		}
+		for _, name := range s.aliases.names() {
+			if strings.HasPrefix(name, word[1:]) {
+				out = append(out, "/"+name)
+			}
+		}
+		return out
+	}
+	if fields := strings.Fields(before); len(fields) > 0 && strings.HasPrefix(fields[0], "/") {
+		if c, ok := argCompleters[fields[0][1:]]; ok {
+			return c(s, fields[1:], word)
+		}
+	}
+	if word == "" {
+		return nil
+	}
+	if c, ok := wordCompleters[word[0]]; ok {
+		return c(s, word)
+	}
+	return nil
+}
+
+// commonPrefix returns the longest prefix shared by every string in list | بلندترین پیشوند مشترک
+func commonPrefix(list []string) string {
+	prefix := list[0]
+	for _, c := range list[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1] // Cut inside a character | برش وسط یک نویسه
+	}
+	return prefix
+}
+
+// matching returns the choices starting with word | گزینه‌هایی که با word شروع می‌شوند
+func matching(word string, choices ...string) []string {
+	var out []string
+	for _, c := range choices {
+		if strings.HasPrefix(c, word) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// completeNick completes "@nick" from the nicks we know | تکمیل "@nick" از نام‌های شناخته‌شده
+func completeNick(s *session, word string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, nick := range append(append(s.keys.nicks(), s.seen.recent()...), s.presence.peerName()) {
+		if nick != "" && nick != s.name && !seen[nick] && strings.HasPrefix("@"+nick, word) {
+			seen[nick] = true
+			out = append(out, "@"+nick)
+		}
+	}
+	return out
+}
+
+/*
+completePath completes a local file path, adding "/" to directories.
+Hidden entries are offered only once the name starts with a dot.
+
+این تابع مسیر فایل محلی را کامل می‌کند و به پوشه‌ها "/" اضافه می‌کند؛
+ورودی‌های پنهان فقط وقتی پیشنهاد می‌شوند که نام با نقطه شروع شود
+*/
+func completePath(_ *session, _ []string, word string) []string {
+	dir, base := filepath.Split(word)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		out = append(out, dir+name)
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// completionSession returns a session that knows the nicks bob and bea | نشستی که نام‌های bob و bea را می‌شناسد
+func completionSession(t *testing.T) *session {
+	keys, _ := loadRegistry("")
+	keys.check("bob", "fp1")
+	keys.check("ann", "fp2")
+	seen, err := loadLastSeen("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen.touch("bea", false)
+	presence := newPresence("")
+	presence.setRemoteName("bob")
+	r, _ := loadRoster("")
+	return &session{
+		name:     "ann",
+		keys:     keys,
+		seen:     seen,
+		presence: presence,
+		aliases:  newAliasTable(map[string]string{"/greet": "/me waves"}, ""),
+		roster:   r,
+	}
+}
+
+func TestTabCompleter(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	complete := tabCompleter(completionSession(t))
+	for _, c := range []struct {
+		line  string
+		pos   int
+		want  string
+		cur   int
+		ok    bool
+		shown string
+	}{
+		{"/sy", 3, "/syncdir ", 9, true, ""},
+		{"/gre", 4, "/greet ", 7, true, ""},
+		{"/re", 3, "", 0, false, "/recent  /reply\n"},
+		{"hi @b there", 5, "", 0, false, "@bea  @bob\n"},
+		{"hi @bo there", 6, "hi @bob  there", 8, true, ""},
+		{"@an", 3, "", 0, false, ""}, // Our own nick | نام خودمان
+		{"so :thu", 7, "so :thumbs", 10, true, ""},
+		{"so :thumbsu", 11, "so " + emojiShortcodes["thumbsup"] + " ", 8, true, ""},
+		{"/roster al", 10, "/roster alias ", 14, true, ""},
+		{"/roster remove ", 15, "", 0, false, ""},
+		{"plain", 5, "", 0, false, ""},
+	} {
+		buf.Reset()
+		line, cur, ok := complete(c.line, c.pos, '\t')
+		if line != c.want || cur != c.cur || ok != c.ok || buf.String() != c.shown {
+			t.Errorf("Tab in %q at %d: %q at %d, %v, listed %q; want %q at %d, %v, listed %q", c.line, c.pos, line, cur, ok, buf.String(), c.want, c.cur, c.ok, c.shown)
+		}
+	}
+	if _, _, ok := complete("/sy", 3, 'a'); ok {
+		t.Error("a key other than Tab completed")
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"notes.txt": "", "novel/ch1.txt": "", ".notes": ""})
+	for _, c := range []struct {
+		word string
+		want string
+	}{
+		{dir + "/no", dir + "/notes.txt|" + dir + "/novel/"},
+		{dir + "/nov", dir + "/novel/"},
+		{dir + "/.no", dir + "/.notes"},
+		{dir + "/novel/c", dir + "/novel/ch1.txt"},
+		{filepath.Join(dir, "missing") + "/x", ""},
+	} {
+		if got := strings.Join(completePath(nil, nil, c.word), "|"); got != c.want {
+			t.Errorf("completePath(%q) = %q, want %q", c.word, got, c.want)
+		}
+	}
+
+	complete := tabCompleter(completionSession(t))
+	line := "/send " + dir + "/nov"
+	if got, _, _ := complete(line, len(line), '\t'); got != "/send "+dir+"/novel/" {
+		t.Errorf("completed directory %q, want no space after the slash", got)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if got := strings.Join(completePath(nil, nil, "not"), "|"); got != "notes.txt" {
+		t.Errorf("relative completion %q", got)
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	for _, c := range []struct {
+		list []string
+		want string
+	}{
+		{[]string{"/recent", "/reply"}, "/re"},
+		{[]string{"same", "same"}, "same"},
+		{[]string{"😄", "😂"}, ""}, // Share leading bytes, not a character | بایت‌های مشترک، نه نویسه
+		{[]string{"abc"}, "abc"},
+	} {
+		if got := commonPrefix(c.list); got != c.want {
+			t.Errorf("commonPrefix(%q) = %q, want %q", c.list, got, c.want)
+		}
+	}
+}
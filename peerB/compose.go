@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"     // For the capture prompts
+	"strings" // For joining the captured lines
+	"sync"    // For guarding the capture state
+)
+
+/*
+Capture markers
+
+خطوط پایان ضبط چندخطی:
+- /end متن جمع‌شده را تحویل می‌دهد
+- /cancel آن را دور می‌ریزد
+*/
+const (
+	captureEnd    = "/end"
+	captureCancel = "/cancel"
+)
+
+func init() {
+	registerCommand("paste", "/paste  type or paste several lines, sent as one message on /end", pasteCommand)
+}
+
+// pasteCommand captures lines until /end and sends them as one message | ضبط خطوط تا /end و ارسال آن‌ها به‌صورت یک پیام
+func pasteCommand(s *session, args []string) {
+	s.compose.begin("/paste", "the message", func(text string) { sendComposed(s, text) })
+}
+
+// sendComposed sends a captured block as one chat message | ارسال متن ضبط‌شده به‌صورت یک پیام
+func sendComposed(s *session, text string) {
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(stdout, "Discarded empty message")
+		return
+	}
+	if _, err := sendChat(s, text, nil, false); err != nil {
+		fmt.Fprintln(stdout, "Send error:", err)
+	}
+}
+
+/*
+composer collects several typed lines into one text, for input that a
+single prompt line cannot hold such as code or a pasted paragraph.
+While it is capturing, every line goes to it verbatim, leading spaces
+included.
+
+این نوع چند خط تایپ‌شده را به یک متن تبدیل می‌کند، برای ورودی‌ای که در
+یک خط جا نمی‌شود مانند کد یا پاراگراف چسبانده‌شده؛ تا وقتی در حال ضبط است
+هر خط بدون تغییر و همراه فاصله‌های ابتدایی به آن می‌رسد
+*/
+type composer struct {
+	mu    sync.Mutex
+	lines []string
+	done  func(text string) // Set while capturing | در حال ضبط مقدار دارد
+	auto  bool              // Started by a paste, ends at the next typed line | آغازشده با چسباندن، با خط تایپ‌شده‌ی بعدی پایان می‌یابد
+	cmd   string            // Command that starts this capture again | دستوری که این ضبط را دوباره آغاز می‌کند
+}
+
+/*
+begin starts a capture that hands the text to done on /end; cmd is the
+command that started it, kept for drafts.
+
+این تابع ضبطی را آغاز می‌کند که متن را با /end به done تحویل می‌دهد؛ cmd
+دستور آغازکننده‌ی آن است که برای پیش‌نویس نگه داشته می‌شود
+*/
+func (c *composer) begin(cmd, what string, done func(text string)) {
+	c.mu.Lock()
+	c.lines, c.done, c.auto, c.cmd = nil, done, false, cmd
+	c.mu.Unlock()
+	fmt.Fprintf(stdout, "Enter %s; %s sends it, %s drops it\n", what, captureEnd, captureCancel)
+}
+
+/*
+feed takes line if a capture is running and reports whether it did.
+The text is handed over outside the lock, so done may send it.
+
+این تابع در صورت فعال‌بودن ضبط line را می‌گیرد و اعلام می‌کند که گرفته
+است یا نه؛ متن بیرون از قفل تحویل داده می‌شود تا done بتواند آن را ارسال کند
+*/
+func (c *composer) feed(line string) bool {
+	c.mu.Lock()
+	if c.done == nil {
+		c.mu.Unlock()
+		return false
+	}
+	cmd := strings.TrimSpace(line)
+	if c.auto && cmd != captureCancel {
+		if line != "" && cmd != captureEnd {
+			c.lines = append(c.lines, line) // Typed after the pasted lines | تایپ‌شده پس از خطوط چسبانده‌شده
+		}
+		cmd = captureEnd
+	}
+	switch cmd {
+	case captureEnd:
+		text, done := strings.Join(c.lines, "\n"), c.done
+		c.lines, c.done = nil, nil
+		c.mu.Unlock()
+		done(text)
+	case captureCancel:
+		c.lines, c.done = nil, nil
+		c.mu.Unlock()
+		fmt.Fprintln(stdout, "Discarded")
+	default:
+		c.lines = append(c.lines, strings.TrimRight(line, "\r"))
+		c.mu.Unlock()
+	}
+	return true
+}
+
+/*
+paste takes one line of a bracketed paste, or one ended with the newline
+key. Outside a capture it starts one that sends the block as a single
+message when the next line is entered, so pressing Enter after a paste
+sends the whole paragraph instead of a burst of fragments.
+
+این تابع یک خط از چسباندن (bracketed paste) یا خطی را که با کلید newline تمام شده
+می‌گیرد؛ بیرون از ضبط، ضبطی
+آغاز می‌کند که با تایپ خط بعدی کل متن را به‌صورت یک پیام ارسال می‌کند،
+بنابراین زدن Enter پس از چسباندن کل پاراگراف را به‌جای چند تکه می‌فرستد
+*/
+func (c *composer) paste(s *session, line string) {
+	c.mu.Lock()
+	if c.done == nil {
+		c.done, c.auto, c.cmd = func(text string) { sendComposed(s, text) }, true, "/paste"
+		fmt.Fprintf(stdout, "Multi-line message; Enter sends it, %s drops it\n", captureCancel)
+	}
+	c.lines = append(c.lines, strings.TrimRight(line, "\r"))
+	c.mu.Unlock()
+}
+
+// snapshot returns the running capture's command and lines, if any | دستور و خطوط ضبط جاری
+func (c *composer) snapshot() (cmd string, lines []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done == nil {
+		return "", nil
+	}
+	return c.cmd, append([]string(nil), c.lines...)
+}
+
+// restore puts saved lines back into a capture just begun | بازگرداندن خطوط ذخیره‌شده به ضبط تازه آغازشده
+func (c *composer) restore(lines []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		c.lines = append(c.lines, lines...)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComposerCapture(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	var c composer
+	if c.feed("hello") {
+		t.Fatal("a line was taken with no capture running")
+	}
+
+	var got []string
+	c.begin("/paste", "the message", func(text string) { got = append(got, text) })
+	for _, line := range []string{"  indented\r", "", "/reply x"} {
+		if !c.feed(line) {
+			t.Fatalf("capture did not take %q", line)
+		}
+	}
+	if cmd, lines := c.snapshot(); cmd != "/paste" || strings.Join(lines, "|") != "  indented||/reply x" {
+		t.Errorf("snapshot %q %q", cmd, lines)
+	}
+	c.feed(" /end")
+	if len(got) != 1 || got[0] != "  indented\n\n/reply x" {
+		t.Errorf("captured %q", got)
+	}
+	if cmd, _ := c.snapshot(); cmd != "" || c.feed("after") {
+		t.Error("capture still running after /end")
+	}
+
+	c.begin("/paste", "the message", func(text string) { got = append(got, text) })
+	c.feed("dropped")
+	c.feed("/cancel")
+	c.restore([]string{"late"})
+	if len(got) != 1 || !strings.HasSuffix(buf.String(), "Discarded\n") || c.feed("after") {
+		t.Errorf("cancelled capture delivered %q; output:\n%s", got, buf.String())
+	}
+}
+
+func TestPasteSendsOnEnter(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	keys, _ := loadRegistry("")
+	out := make(chan string, 4)
+	s := codeSession(t, capabilities{MaxMessage: 4096}, out)
+
+	s.compose.paste(s, "first line\r")
+	s.compose.paste(s, "second line")
+	if len(out) != 0 {
+		t.Fatal("a pasted line was sent before Enter")
+	}
+	handleInput(s, " and a typed one")
+	if m, _ := decodeChatLine(<-out, keys); m.Text != "first line\nsecond line\n and a typed one" {
+		t.Errorf("sent %q, want the pasted lines and the typed one as one message", m.Text)
+	}
+	if strings.Count(buf.String(), "Multi-line message;") != 1 {
+		t.Errorf("output:\n%s", buf.String())
+	}
+
+	s.compose.paste(s, "pasted")
+	handleInput(s, "")
+	if m, _ := decodeChatLine(<-out, keys); m.Text != "pasted" {
+		t.Errorf("Enter alone sent %q", m.Text)
+	}
+
+	s.compose.paste(s, "oops")
+	handleInput(s, "/cancel")
+	if len(out) != 0 || s.compose.feed("more") {
+		t.Errorf("a cancelled paste was sent: %q", <-out)
+	}
+
+	runCommand(s, "/paste")
+	s.compose.paste(s, "pasted into /paste")
+	handleInput(s, "typed")
+	if len(out) != 0 {
+		t.Fatal("a paste inside /paste ended it")
+	}
+	handleInput(s, "/end")
+	if m, _ := decodeChatLine(<-out, keys); m.Text != "pasted into /paste\ntyped" {
+		t.Errorf("/paste sent %q", m.Text)
+	}
+}
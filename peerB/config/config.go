@@ -0,0 +1,379 @@
+/*
+Package config loads the peer's settings from several layers.
+Later layers override earlier ones:
+
+ 1. built-in defaults
+ 2. JSON config file (-config or PEERCHAT_CONFIG)
+ 3. PEERCHAT_* environment variables (e.g. PEERCHAT_LISTEN)
+ 4. command-line flags that were set explicitly
+
+پکیج config تنظیمات برنامه را از چند لایه می‌خواند؛
+هر لایه لایه‌های قبلی را بازنویسی می‌کند:
+۱. مقادیر پیش‌فرض
+۲. فایل پیکربندی JSON (پرچم -config یا PEERCHAT_CONFIG)
+۳. متغیرهای محیطی PEERCHAT_*
+۴. پرچم‌های خط فرمانی که صراحتاً تنظیم شده‌اند
+*/
+package config
+
+import (
+	"bytes"         // For decoding the file with exact numbers
+	"encoding/json" // For the config file
+	"errors"        // For a config file that does not exist yet
+	"flag"          // For command-line flags
+	"fmt"           // For error wrapping
+	"os"            // For reading the file and environment
+	"strconv"       // For parsing booleans
+	"strings"       // For building environment variable names
+	"time"          // For duration settings
+)
+
+const envPrefix = "PEERCHAT_" // Prefix of every environment variable | پیشوند متغیرهای محیطی
+
+/*
+Config holds every setting that can come from a file, the
+environment or a flag.
+
+این ساختار همه‌ی تنظیماتی را نگه می‌دارد که از فایل،
+متغیر محیطی یا پرچم خط فرمان می‌آیند
+*/
+type Config struct {
+	Listen    string        // Local listen address | آدرس Listen محلی
+	Dial      string        // Remote peer address | آدرس peer مقابل
+	Name      string        // Name shown to the remote peer | نام نمایشی
+	Socket    string        // Daemon/attach socket path | مسیر socket حالت daemon
+	Daemon    bool          // Run as a daemon | اجرا به‌صورت daemon
+	LAN       bool          // Multicast to the local network, no link | multicast به شبکه‌ی محلی، بدون اتصال
+	Wait      time.Duration // Pipe mode reply window | مهلت دریافت پاسخ در حالت pipe
+	Stream    bool          // Pipe mode: all of stdin is one message | حالت pipe: کل ورودی یک پیام است
+	Output    string        // "text" or "json" events on stdout | قالب خروجی stdout
+	HTTP      string        // Health/debug HTTP address | آدرس سرور HTTP سلامت/دیباگ
+	HTTPToken string        // Bearer token /metrics, /transfers and /events require | token لازم برای /metrics، /transfers و /events
+
+	CheckUpdate bool   // Query the release endpoint at startup | بررسی نسخه‌ی جدید هنگام شروع
+	Identity    string // Signing key file | فایل کلید امضا
+
+	FilterWords    string // Comma-separated words to filter | کلمات فیلترشده (با کاما)
+	FilterAction   string // "mask" or "drop" | پوشاندن یا حذف
+	FilterOutbound bool   // Filter our own messages too | فیلتر پیام‌های خروجی
+
+	SpamRate     int           // Messages per minute before a mute (0 disables) | حداکثر پیام در دقیقه
+	SpamRepeat   int           // Identical messages in a row before a mute (0 disables) | حداکثر پیام تکراری پشت سر هم
+	SpamCooldown time.Duration // How long a spammer stays muted | مدت سکوت اسپمر
+
+	Access   string // open, invite or password | حالت دسترسی
+	Password string // Shared chat password | رمز مشترک گفتگو
+	Pin      string // sha256:<hex> of the only remote key accepted | hash تنها کلید پذیرفتنی طرف مقابل
+	DoH      string // DNS-over-HTTPS server for the dial host | سرور DNS-over-HTTPS برای میزبان dial
+
+	Transport string // tcp, unix, serial, bluetooth or http | نوع انتقال
+	Device    string // Serial device path | مسیر دستگاه سریال
+	Baud      int    // Serial line speed | سرعت خط سریال
+
+	Anon bool // Throwaway identity, nothing persisted | هویت موقت، بدون ذخیره‌سازی
+
+	LinkPreviews bool          // Fetch titles of linked pages | دریافت عنوان صفحات لینک‌شده
+	Padding      bool          // Pad chat lines and send cover traffic | پرکردن خطوط چت و ارسال ترافیک پوششی
+	Hyperlinks   string        // "auto", "on" or "off" | لینک‌های قابل کلیک
+	Notify       string        // Bell/flash events, e.g. "mention,flash" | رویدادهای اعلان
+	AwayReply    string        // Auto-reply while away | پاسخ خودکار در حالت away
+	Idle         time.Duration // Keyboard inactivity before idle (0 disables) | مدت بی‌فعالیتی تا idle
+
+	KeepAlive time.Duration // TCP keepalive probe period (0 disables) | فاصله‌ی keepalive در TCP
+	NoDelay   bool          // Disable Nagle's algorithm | غیرفعال‌کردن الگوریتم Nagle
+	SendBuf   int           // Socket send buffer bytes (0 = OS default) | بافر ارسال socket
+	RecvBuf   int           // Socket receive buffer bytes (0 = OS default) | بافر دریافت socket
+	Linger    int           // SO_LINGER seconds (-1 = OS default) | زمان linger
+
+	Generate string // Synthetic load spec, e.g. "rate=100/s size=256" | مشخصات بار ساختگی
+
+	Log        string        // Operational log: a file, "syslog" or "journald" | مقصد log عملیاتی
+	LogFormat  string        // "text" or "json" | قالب log عملیاتی
+	RotateSize int           // Megabytes before the log or transcript rotates (0 disables) | حجم پیش از چرخش
+	RotateAge  time.Duration // Age before the log or transcript rotates (0 disables) | سن پیش از چرخش
+	RotateKeep int           // Rotated files kept (0 keeps all) | تعداد فایل‌های چرخانده‌شده‌ی نگه‌داشته
+
+	AcceptFiles   string // MIME patterns of incoming files to accept, e.g. "image/*,audio/*" | الگوهای MIME فایل‌های پذیرفتنی
+	Downloads     string // Directory for received files, empty for a temporary one | پوشه‌ی فایل‌های دریافتی
+	MaxFile       int    // Largest received file in megabytes | بزرگ‌ترین فایل دریافتی به مگابایت
+	DownloadQuota int    // Total megabytes received per run (0 is unlimited) | مجموع مگابایت دریافتی در هر اجرا
+
+	InputHistory int    // Typed lines kept for recall across runs (0 disables) | خطوط تایپ‌شده‌ی نگه‌داشته برای بازیابی
+	Theme        string // Terminal colour theme | پوسته‌ی رنگی ترمینال
+
+	Aliases map[string]string            // Command aliases, config file only | نام‌های مستعار دستورها، فقط در فایل
+	Keys    map[string]string            // Key bindings, config file only | کلیدهای میانبر، فقط در فایل
+	Themes  map[string]map[string]string // Colour themes by name, config file only | پوسته‌های رنگی، فقط در فایل
+	File    string                       // Config file in use, empty if none | فایل پیکربندی در حال استفاده
+}
+
+// setting describes one configurable value | توصیف یک تنظیم قابل پیکربندی
+type setting struct {
+	name  string     // Flag name and config file key | نام پرچم و کلید فایل
+	usage string     // Flag help text | متن راهنمای پرچم
+	value flag.Value // Points into a Config | اشاره‌گر به فیلد Config
+}
+
+/*
+settings lists the configurable values of c; the environment variable
+of each one is PEERCHAT_ followed by its upper-cased name, with dashes
+turned into underscores.
+
+این تابع تنظیمات قابل پیکربندی c را برمی‌گرداند؛ نام متغیر محیطی
+هر کدام PEERCHAT_ به‌همراه نام آن با حروف بزرگ است
+*/
+func (c *Config) settings() []setting {
+	return []setting{
+		{"listen", "local address to listen on", (*stringValue)(&c.Listen)},
+		{"dial", "remote peer address to dial, a roster nick or a peerchat:// link", (*stringValue)(&c.Dial)},
+		{"name", "name shown to the remote peer", (*stringValue)(&c.Name)},
+		{"socket", "local socket shared by -daemon and -attach", (*stringValue)(&c.Socket)},
+		{"daemon", "run in the background holding the connection; attach with -attach", (*boolValue)(&c.Daemon)},
+		{"lan", "chat with everyone on the local network over UDP multicast, with no connection", (*boolValue)(&c.LAN)},
+		{"wait", "pipe mode: how long to wait for replies after stdin ends", (*durationValue)(&c.Wait)},
+		{"stream", "pipe mode: send all of stdin as one message, streamed in parts", (*boolValue)(&c.Stream)},
+		{"output", `stdout format: "text", or "json" for one event per line (connected, message, transfer, disconnected)`, (*stringValue)(&c.Output)},
+		{"http", "address for the /healthz and /readyz endpoints (empty disables)", (*stringValue)(&c.HTTP)},
+		{"http-token", "bearer token /metrics, /transfers and /events require; without one they are served only when -http is a loopback address", (*stringValue)(&c.HTTPToken)},
+		{"check-update", "check the release endpoint for a newer build at startup", (*boolValue)(&c.CheckUpdate)},
+		{"identity", "Ed25519 identity key file (created on first run)", (*stringValue)(&c.Identity)},
+		{"filter-words", "comma-separated words to mask or drop", (*stringValue)(&c.FilterWords)},
+		{"filter-action", `what to do with filtered messages: "mask" or "drop"`, (*stringValue)(&c.FilterAction)},
+		{"filter-outbound", "apply the word filter to outgoing messages too", (*boolValue)(&c.FilterOutbound)},
+		{"spam-rate", "messages per minute before the sender is muted (0 disables)", (*intValue)(&c.SpamRate)},
+		{"spam-repeat", "identical messages in a row before the sender is muted (0 disables)", (*intValue)(&c.SpamRepeat)},
+		{"spam-cooldown", "how long a throttled sender stays muted", (*durationValue)(&c.SpamCooldown)},
+		{"access", `who may connect: "open", "invite" or "password"`, (*stringValue)(&c.Access)},
+		{"password", "shared password, presented to the remote and required in password mode", (*stringValue)(&c.Password)},
+		{"pin", "sha256:<hex> hash (or fingerprint) of the only remote key to accept", (*stringValue)(&c.Pin)},
+		{"doh", "DNS-over-HTTPS URL to resolve the dial host with instead of the system resolver", (*stringValue)(&c.DoH)},
+		{"transport", `how links are made: "tcp", "unix" with socket paths as listen and dial, "serial", "bluetooth", "http" (long-polling) or "udp"`, (*stringValue)(&c.Transport)},
+		{"device", `serial device for transport "serial", e.g. /dev/ttyUSB0`, (*stringValue)(&c.Device)},
+		{"baud", `serial line speed for transport "serial"`, (*intValue)(&c.Baud)},
+		{"anon", "throwaway identity and guest nick; nothing is written to disk", (*boolValue)(&c.Anon)},
+		{"link-previews", "fetch and show the title of links in incoming messages (contacts the linked sites)", (*boolValue)(&c.LinkPreviews)},
+		{"padding", "pad chat lines to size buckets and send cover traffic at random intervals", (*boolValue)(&c.Padding)},
+		{"hyperlinks", `clickable links in terminal output: "auto", "on" or "off"`, (*stringValue)(&c.Hyperlinks)},
+		{"notify", `new message alerts: "off" or a list of "message", "mention" and "flash"`, (*stringValue)(&c.Notify)},
+		{"away-reply", "auto-reply sent once to each peer while you are /away", (*stringValue)(&c.AwayReply)},
+		{"idle", "time without keystrokes before presence turns idle (0 disables)", (*durationValue)(&c.Idle)},
+		{"keepalive", "TCP keepalive probe period on the established link (0 disables)", (*durationValue)(&c.KeepAlive)},
+		{"tcp-nodelay", "send small writes immediately instead of batching them (Nagle off)", (*boolValue)(&c.NoDelay)},
+		{"tcp-sndbuf", "socket send buffer size in bytes (0 keeps the OS default)", (*intValue)(&c.SendBuf)},
+		{"tcp-rcvbuf", "socket receive buffer size in bytes (0 keeps the OS default)", (*intValue)(&c.RecvBuf)},
+		{"tcp-linger", "seconds to keep sending unsent data after close (-1 keeps the OS default, 0 resets)", (*intValue)(&c.Linger)},
+		{"generate", `send synthetic messages for soak tests, e.g. "rate=100/s size=256 count=5000" (empty disables)`, (*stringValue)(&c.Generate)},
+		{"log", `operational log: a file path, "syslog" or "journald" (empty disables)`, (*stringValue)(&c.Log)},
+		{"log-format", `operational log lines: "text" or "json" (one object per line)`, (*stringValue)(&c.LogFormat)},
+		{"rotate-size", "megabytes before the log or the transcript is rotated (0 disables)", (*intValue)(&c.RotateSize)},
+		{"rotate-age", "age before the log or the transcript is rotated (0 disables)", (*durationValue)(&c.RotateAge)},
+		{"rotate-keep", "rotated log and transcript files kept (0 keeps all)", (*intValue)(&c.RotateKeep)},
+		{"accept-files", `MIME patterns of incoming files to accept, e.g. "image/*,audio/*" (empty refuses every file)`, (*stringValue)(&c.AcceptFiles)},
+		{"downloads", "directory for received files (empty uses a new temporary directory)", (*stringValue)(&c.Downloads)},
+		{"max-file", "largest received file in megabytes (at most 100)", (*intValue)(&c.MaxFile)},
+		{"download-quota", "total megabytes of files received per run (0 is unlimited)", (*intValue)(&c.DownloadQuota)},
+		{"input-history", "typed lines kept for arrow-key recall across runs (0 disables)", (*intValue)(&c.InputHistory)},
+		{"theme", `terminal colours: "plain", "dark", "light" or a theme from the config file`, (*stringValue)(&c.Theme)},
+	}
+}
+
+/*
+Load registers the setting flags (plus -config) on fs, parses args and
+merges all layers on top of defaults. fs may already hold flags that
+are not settings, such as -attach.
+
+این تابع پرچم‌های تنظیمات (و -config) را روی fs ثبت می‌کند، args را
+تجزیه و همه‌ی لایه‌ها را روی مقادیر پیش‌فرض اعمال می‌کند
+*/
+func Load(fs *flag.FlagSet, args []string, defaults Config) (Config, error) {
+	cfg := defaults
+	flagged := defaults // Flags are parsed into a shadow copy | پرچم‌ها در یک کپی جداگانه تجزیه می‌شوند
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "JSON config file")
+	for _, st := range flagged.settings() {
+		fs.Var(st.value, st.name, st.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return cfg, err
+		}
+		cfg.File = *configPath
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return cfg, err
+	}
+
+	// Only flags given explicitly win over file and environment | فقط پرچم‌های صریح اولویت دارند
+	byName := make(map[string]setting)
+	for _, st := range cfg.settings() {
+		byName[st.name] = st
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if st, ok := byName[f.Name]; ok && err == nil {
+			err = st.value.Set(f.Value.String())
+		}
+	})
+	return cfg, err
+}
+
+/*
+loadFile applies the keys present in a JSON config file, e.g.
+{"listen": "0.0.0.0:9000", "daemon": true, "wait": "5s"}, plus the
+"aliases", "keys" and "themes" objects, which have no flag or variable.
+
+این تابع کلیدهای موجود در فایل پیکربندی JSON را اعمال می‌کند، به‌همراه
+اشیای "aliases"، "keys" و "themes" که پرچم یا متغیر محیطی ندارند
+*/
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // 1048576 stays 1048576, not 1.048576e+06 | عدد به همان شکل نوشته‌شده می‌ماند
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	for _, st := range c.settings() {
+		v, ok := raw[st.name]
+		if !ok {
+			continue
+		}
+		if err := st.value.Set(fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("config %s: %s: %w", path, st.name, err)
+		}
+	}
+	for key, dst := range map[string]*map[string]string{aliasesKey: &c.Aliases, keysKey: &c.Keys} {
+		v, ok := raw[key]
+		if !ok {
+			continue
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("config %s: %s: not an object", path, key)
+		}
+		*dst = make(map[string]string, len(obj))
+		for name, value := range obj {
+			(*dst)[name] = fmt.Sprint(value)
+		}
+	}
+	if v, ok := raw[themesKey]; ok {
+		themes, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("config %s: %s: not an object", path, themesKey)
+		}
+		c.Themes = make(map[string]map[string]string, len(themes))
+		for name, t := range themes {
+			colours, ok := t.(map[string]any)
+			if !ok {
+				return fmt.Errorf("config %s: %s: %s: not an object", path, themesKey, name)
+			}
+			c.Themes[name] = make(map[string]string, len(colours))
+			for part, colour := range colours {
+				c.Themes[name][part] = fmt.Sprint(colour)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Object keys of the config file
+
+کلیدهای شیء در فایل پیکربندی:
+- aliases نام‌های مستعار دستورها
+- keys کلیدهای میانبر ویرایشگر
+- themes پوسته‌های رنگی
+*/
+const (
+	aliasesKey = "aliases"
+	keysKey    = "keys"
+	themesKey  = "themes"
+)
+
+/*
+SaveAliases writes aliases into the config file at path, keeping its
+other keys as they are; the file is created if it does not exist.
+
+این تابع نام‌های مستعار را در فایل پیکربندی path می‌نویسد و کلیدهای دیگر
+آن را دست‌نخورده نگه می‌دارد؛ اگر فایل وجود نداشته باشد ساخته می‌شود
+*/
+func SaveAliases(path string, aliases map[string]string) error {
+	raw := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	raw[aliasesKey], _ = json.Marshal(aliases) // Strings cannot fail | برای رشته‌ها خطا نمی‌دهد
+	data, err = json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+/*
+loadEnv applies every PEERCHAT_* variable that is set.
+
+این تابع متغیرهای محیطی PEERCHAT_* تنظیم‌شده را اعمال می‌کند
+*/
+func (c *Config) loadEnv() error {
+	for _, st := range c.settings() {
+		key := envPrefix + strings.ToUpper(strings.ReplaceAll(st.name, "-", "_"))
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := st.value.Set(v); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// stringValue is a flag.Value over a string field | پیاده‌سازی flag.Value برای رشته
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+// boolValue is a flag.Value over a bool field | پیاده‌سازی flag.Value برای بولین
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	*v = boolValue(b)
+	return err
+}
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+// durationValue is a flag.Value over a time.Duration field | پیاده‌سازی flag.Value برای مدت زمان
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	*v = durationValue(d)
+	return err
+}
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// intValue is a flag.Value over an int field | پیاده‌سازی flag.Value برای عدد صحیح
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	*v = intValue(n)
+	return err
+}
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
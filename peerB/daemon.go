@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"         // For reading client lines
+	"bytes"         // For splitting output into lines
+	"errors"        // For recognising a deliberate detach
+	"io"            // For copying between the socket and the terminal
+	"net"           // For the local unix socket
+	"os"            // For the real stdout and socket cleanup
+	"os/signal"     // For surviving a closed terminal
+	"path/filepath" // For the default socket path
+	"sync"          // For guarding the client list and history
+	"sync/atomic"   // For the session of the current link
+	"syscall"       // For SIGHUP
+	"time"          // For client write deadlines
+)
+
+/*
+Daemon configuration
+
+مقادیر پیکربندی حالت daemon:
+- تعداد خطوط تاریخچه‌ای که برای ترمینال تازه متصل‌شده پخش می‌شود
+- تایم‌اوت نوشتن برای هر ترمینال متصل
+*/
+const (
+	daemonHistoryLines  = 500             // Lines replayed on attach | خطوط پخش‌شده هنگام اتصال
+	daemonClientTimeout = 2 * time.Second // Write timeout per client | تایم‌اوت نوشتن برای هر کلاینت
+)
+
+/*
+daemonRelay fans the daemon's terminal output out to every attached
+client and keeps a bounded history for clients that attach later.
+
+این نوع خروجی ترمینال daemon را برای همه‌ی کلاینت‌های متصل ارسال
+می‌کند و تاریخچه‌ی محدودی برای کلاینت‌هایی که بعداً وصل می‌شوند نگه می‌دارد
+*/
+type daemonRelay struct {
+	mu      sync.Mutex
+	history [][]byte  // Last lines with their newline, a ring once full | آخرین خطوط با newline، پس از پر شدن حلقوی
+	oldest  int       // Ring index of the oldest line | اندیس قدیمی‌ترین خط در حلقه
+	local   io.Writer // The real stdout, if kept as a local log | stdout واقعی در صورت نگه‌داشتن log محلی
+	clients map[net.Conn]struct{}
+	partial []byte // Output after the last newline; stdout serialises writes | خروجی پس از آخرین newline
+}
+
+/*
+defaultSocketPath returns the per-name local socket used between the
+daemon and attached terminals: in $XDG_RUNTIME_DIR when it is set, and
+otherwise next to the other state files in the user's config
+directory. Both belong to the user alone, unlike the shared temp
+directory, where anyone could put a socket at a predictable name first.
+
+این تابع مسیر پیش‌فرض socket محلی بین daemon و ترمینال‌ها را برمی‌گرداند:
+در $XDG_RUNTIME_DIR اگر تنظیم شده باشد و در غیر این صورت کنار فایل‌های وضعیت
+دیگر در پوشه‌ی تنظیمات کاربر. هر دو فقط مال کاربرند، برخلاف پوشه‌ی موقت
+مشترک که هر کسی می‌تواند زودتر socketی با نام قابل حدس در آن بگذارد
+*/
+func defaultSocketPath(name string) string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "peerchat-"+name+".sock")
+	}
+	return dataPath(name + ".sock")
+}
+
+var errNotSocket = errors.New("refusing to replace a file that is not a socket") // Path taken by something else | مسیر در اختیار چیز دیگری است
+
+// checkStaleSocket allows a missing path or a socket left by an earlier run, and nothing else | فقط مسیر خالی یا socket اجرای قبلی
+func checkStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case fi.Mode().Type() != os.ModeSocket:
+		return errNotSocket
+	}
+	return nil
+}
+
+/*
+socketListener removes its socket file on Close, under the name the
+socket ended up with rather than the one it was made under.
+
+این نوع هنگام Close فایل socket خود را با نامی که در نهایت گرفته، نه نامی
+که با آن ساخته شده، حذف می‌کند
+*/
+type socketListener struct {
+	net.Listener
+	path string
+}
+
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+/*
+startDaemon turns this process into a daemon and returns a stop function
+that flushes pending output and removes the socket:
+- stdout is redirected into a relay that keeps it as history and
+broadcasts it to attached clients (and still copies it to the real stdout)
+- a local socket accepts attach clients; their lines arrive on input
+- SIGHUP is ignored so closing the terminal does not drop the chat
+
+این تابع برنامه را به daemon تبدیل می‌کند و تابع stop را برمی‌گرداند
+که خروجی باقی‌مانده را ارسال و socket را حذف می‌کند:
+- stdout به relayی هدایت می‌شود که آن را ذخیره و برای کلاینت‌ها ارسال می‌کند
+- یک socket محلی کلاینت‌ها را می‌پذیرد و خطوط آن‌ها داخل input می‌آید
+- سیگنال SIGHUP نادیده گرفته می‌شود تا بستن ترمینال چت را قطع نکند
+*/
+func startDaemon(path string) (input <-chan string, stop func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, nil, err
+	}
+	ln, err := listenPrivate(path) // Only our user may attach | فقط کاربر جاری اجازه اتصال دارد
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signal.Ignore(syscall.SIGHUP)
+
+	d := &daemonRelay{clients: make(map[net.Conn]struct{}), local: stdout.target()} // Keep a local log too | ثبت در stdout اصلی
+	stdout.redirect(d)                                                              // All printed output now flows through the relay | همه‌ی خروجی‌ها از relay عبور می‌کنند
+	lines := make(chan string, 32)
+	go d.serve(ln, lines)
+
+	stop = func() {
+		stdout.redirect(d.local)
+		d.flush() // Deliver the last line before exiting | ارسال خط آخر قبل از خروج
+		_ = ln.Close()
+		d.closeClients()
+		_ = os.Remove(path)
+	}
+	return lines, stop, nil
+}
+
+/*
+Write publishes every complete line in p and keeps the rest until its
+newline arrives. Only stdout calls it, one write at a time.
+
+این تابع هر خط کامل p را منتشر می‌کند و بقیه را تا رسیدن newline نگه
+می‌دارد. فقط stdout آن را صدا می‌زند، هر بار یک نوشتن
+*/
+func (d *daemonRelay) Write(p []byte) (int, error) {
+	d.partial = append(d.partial, p...)
+	rest := d.partial
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		d.publish(bytes.TrimSuffix(rest[:i], []byte("\r"))) // Copied straight into the history | کپی مستقیم در تاریخچه
+		rest = rest[i+1:]
+	}
+	d.partial = append(d.partial[:0], rest...)
+	return len(p), nil
+}
+
+// flush publishes output left without a newline | انتشار خروجی بدون newline
+func (d *daemonRelay) flush() {
+	if len(d.partial) > 0 {
+		d.publish(d.partial)
+		d.partial = d.partial[:0]
+	}
+}
+
+/*
+publish copies a line into the history and writes it to the local log
+and every client; clients that cannot keep up are dropped. The line is
+encoded once, with its newline, into the history slot it replaces, and
+that same buffer is what every write sends, so once the history is full
+a publish allocates nothing. The line may be reused after the call.
+
+این تابع یک خط را در تاریخچه کپی و برای log محلی و همه‌ی کلاینت‌ها
+ارسال می‌کند؛ کلاینت‌هایی که عقب بمانند حذف می‌شوند. خط فقط یک بار همراه
+newline در بافر خانه‌ای از تاریخچه که جایگزین می‌کند کدگذاری می‌شود و همه‌ی
+نوشتن‌ها همان بافر را می‌فرستند؛ پس از پر شدن تاریخچه هیچ تخصیص حافظه‌ای
+رخ نمی‌دهد. line پس از فراخوانی قابل استفاده‌ی دوباره است
+*/
+func (d *daemonRelay) publish(line []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var slot *[]byte
+	if len(d.history) < daemonHistoryLines {
+		d.history = append(d.history, nil)
+		slot = &d.history[len(d.history)-1]
+	} else {
+		slot = &d.history[d.oldest] // Its buffer is reused | بافر آن دوباره استفاده می‌شود
+		d.oldest = (d.oldest + 1) % len(d.history)
+	}
+	*slot = append(append((*slot)[:0], line...), '\n')
+	frame := *slot
+	if d.local != nil {
+		_, _ = d.local.Write(frame)
+	}
+	for c := range d.clients {
+		_ = c.SetWriteDeadline(time.Now().Add(daemonClientTimeout))
+		if _, err := c.Write(frame); err != nil {
+			_ = c.Close()
+			delete(d.clients, c)
+		}
+	}
+}
+
+/*
+serve accepts attach clients until the listener is closed.
+
+این تابع تا بسته‌شدن listener کلاینت‌های attach را می‌پذیرد
+*/
+func (d *daemonRelay) serve(ln net.Listener, input chan<- string) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go d.handleClient(c, input)
+	}
+}
+
+/*
+handleClient replays the history to a new client, registers it for
+live output and forwards every line it types into input.
+
+این تابع تاریخچه را برای کلاینت جدید پخش می‌کند، آن را برای خروجی زنده
+ثبت می‌کند و هر خطی که تایپ کند را داخل input می‌فرستد
+*/
+func (d *daemonRelay) handleClient(c net.Conn, input chan<- string) {
+	d.mu.Lock()
+	for i := range d.history {
+		_ = c.SetWriteDeadline(time.Now().Add(daemonClientTimeout))
+		if _, err := c.Write(d.history[(d.oldest+i)%len(d.history)]); err != nil { // Oldest first | از قدیمی‌ترین
+			d.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+	}
+	d.clients[c] = struct{}{}
+	d.mu.Unlock()
+
+	sc := bufio.NewScanner(c)
+	for sc.Scan() {
+		input <- sc.Text()
+	}
+
+	d.mu.Lock()
+	delete(d.clients, c) // Client detached | کلاینت جدا شد
+	d.mu.Unlock()
+	_ = c.Close()
+}
+
+// closeClients disconnects every attached client | قطع اتصال همه‌ی کلاینت‌ها
+func (d *daemonRelay) closeClients() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for c := range d.clients {
+		_ = c.Close()
+		delete(d.clients, c)
+	}
+}
+
+/*
+daemonReader handles lines typed in attached terminals exactly like
+lines typed at a local prompt, on the session of the current link.
+
+این تابع خطوط تایپ‌شده در ترمینال‌های متصل را دقیقاً مثل
+ورودی ترمینال محلی روی نشست اتصال فعلی پردازش می‌کند
+*/
+func daemonReader(live *atomic.Pointer[session], input <-chan string, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case line := <-input:
+			handleInput(live.Load(), line)
+		}
+	}
+}
+
+/*
+runAttach connects this terminal to a running daemon: daemon output is
+printed, typed lines are forwarded. Ctrl+D (or Ctrl+C) detaches without
+affecting the chat.
+
+این تابع ترمینال را به daemon در حال اجرا متصل می‌کند: خروجی daemon چاپ
+و خطوط تایپ‌شده ارسال می‌شوند. با Ctrl+D یا Ctrl+C جدا می‌شود و چت قطع نمی‌شود
+*/
+func runAttach(path string) error {
+	if err := checkSocketOwner(path); err != nil {
+		return err // Typed lines could go to someone else's process | خطوط تایپ‌شده ممکن است به پردازه‌ی کس دیگری برسد
+	}
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	go func() {
+		_, _ = io.Copy(c, os.Stdin) // Typed lines to the daemon | ارسال خطوط به daemon
+		_ = c.Close()               // Stdin closed: detach | پایان ورودی: جدا شدن
+	}()
+	_, err = io.Copy(os.Stdout, c) // Daemon output to the terminal | نمایش خروجی daemon
+	if errors.Is(err, net.ErrClosed) {
+		return nil // We detached ourselves | خودمان جدا شدیم
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"        // For a socket of another user
+	"net"           // For the unix listener
+	"os"            // For the socket's owner and the private directory
+	"path/filepath" // For the private directory next to the socket
+	"syscall"       // For the file owner
+)
+
+var errSocketOwner = errors.New("socket belongs to another user") // Not our daemon | daemon ما نیست
+
+/*
+listenPrivate listens on a unix socket that only our user can connect
+to. The socket is made in a fresh 0700 directory next to path, limited
+to 0600 there and only then renamed into place, so there is no moment
+when another user could open it, and the process umask is left alone
+for the files other goroutines create meanwhile. A stale socket at path
+is replaced; any other file is refused.
+
+این تابع روی socket یونیکسی گوش می‌دهد که فقط کاربر ما می‌تواند به آن وصل
+شود. socket در پوشه‌ی تازه‌ای با دسترسی 0700 کنار path ساخته، همان‌جا به 0600
+محدود و تنها پس از آن به جای خود منتقل می‌شود تا لحظه‌ای نباشد که کاربر دیگری
+بتواند آن را باز کند، و umask برنامه برای فایل‌هایی که goroutineهای دیگر در
+همین حین می‌سازند دست نمی‌خورد. socket کهنه در path جایگزین و هر فایل دیگری
+رد می‌شود
+*/
+func listenPrivate(path string) (net.Listener, error) {
+	if err := checkStaleSocket(path); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock") // Created 0700 | با دسترسی 0700 ساخته می‌شود
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false) // Its name is no longer tmp | نام آن دیگر tmp نیست
+	return &socketListener{Listener: ln, path: path}, nil
+}
+
+// checkSocketOwner refuses a socket owned by another user | رد socket متعلق به کاربر دیگر
+func checkSocketOwner(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return errSocketOwner
+	}
+	return nil
+}
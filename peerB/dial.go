@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context" // For cancelling the losing dial attempts
+	"net"     // For TCP networking and name resolution
+	"time"    // For the stagger delay and overall timeout
+)
+
+/*
+Happy Eyeballs tuning values (RFC 8305)
+
+مقادیر تنظیم Happy Eyeballs:
+- فاصله شروع هر تلاش اتصال نسبت به تلاش قبلی
+- حداکثر زمان کل برای resolve و dial
+*/
+const (
+	dialStagger = 250 * time.Millisecond // Delay before starting the next address | فاصله شروع تلاش بعدی
+	dialTimeout = 5 * time.Second        // Overall budget for one dial round | سقف زمان یک دور اتصال
+)
+
+// dialResult carries the outcome of one connection attempt | نتیجه‌ی یک تلاش اتصال
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+/*
+dialHappyEyeballs resolves the remote host and dials all of its
+addresses in staggered parallel, returning the first one that
+connects. A new attempt starts every dialStagger, or immediately
+when the previous attempt fails; the losers are cancelled and closed.
+
+این تابع آدرس‌های peer مقابل را resolve می‌کند و به‌صورت موازی
+(با فاصله زمانی) به همه‌ی آن‌ها وصل می‌شود؛ اولین اتصال موفق
+برگردانده می‌شود و بقیه لغو و بسته می‌شوند
+*/
+func dialHappyEyeballs(remote string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel() // Abort attempts still in flight | لغو تلاش‌های باقی‌مانده
+
+	ips, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := interleaveFamilies(ips, port)
+	if len(addrs) == 0 {
+		return nil, &net.AddrError{Err: "no addresses", Addr: host}
+	}
+
+	results := make(chan dialResult, len(addrs)) // Buffered so losers never block | بافر تا بازنده‌ها گیر نکنند
+	var d net.Dialer
+	start := func(addr string) {
+		go func() {
+			c, err := d.DialContext(ctx, "tcp", addr)
+			results <- dialResult{conn: c, err: err}
+		}()
+	}
+
+	start(addrs[0])
+	next, pending := 1, 1
+	var lastErr error
+	for pending > 0 {
+		var stagger <-chan time.Time
+		if next < len(addrs) {
+			stagger = time.After(dialStagger)
+		}
+
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go closeLosers(results, pending) // Drop late winners | بستن اتصال‌های دیررس
+				return r.conn, nil
+			}
+			lastErr = r.err
+		case <-stagger:
+			// Previous attempt is slow, start the next one | تلاش قبلی کند است، بعدی را شروع کن
+		}
+
+		if next < len(addrs) {
+			start(addrs[next])
+			next++
+			pending++
+		}
+	}
+	return nil, lastErr
+}
+
+/*
+interleaveFamilies orders resolved addresses IPv6-first, alternating
+between families so a broken IPv6 path cannot hide a working IPv4 one.
+
+این تابع آدرس‌ها را به‌ترتیب IPv6 و IPv4 یکی‌درمیان مرتب می‌کند
+تا مسیر خراب IPv6 جلوی IPv4 سالم را نگیرد
+*/
+func interleaveFamilies(ips []net.IPAddr, port string) []string {
+	var v6, v4 []string
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip.String(), port)
+		if ip.IP.To4() != nil {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+
+	out := make([]string, 0, len(ips))
+	for len(v6) > 0 || len(v4) > 0 {
+		if len(v6) > 0 {
+			out = append(out, v6[0])
+			v6 = v6[1:]
+		}
+		if len(v4) > 0 {
+			out = append(out, v4[0])
+			v4 = v4[1:]
+		}
+	}
+	return out
+}
+
+/*
+closeLosers waits for the remaining attempts and closes any
+connection that succeeded after a winner was already chosen.
+
+این تابع منتظر تلاش‌های باقی‌مانده می‌ماند و اتصال‌هایی را
+که بعد از انتخاب برنده موفق شده‌اند می‌بندد
+*/
+func closeLosers(results <-chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		if r := <-results; r.err == nil {
+			_ = r.conn.Close()
+		}
+	}
+}
+
+/*
+dialLoop keeps dialing the remote peer over tr until one attempt
+succeeds (and hands it over through dialCh) or stop is closed.
+
+این تابع تا زمان موفقیت یا بسته‌شدن stop از طریق tr به peer مقابل dial می‌کند
+و اتصال موفق را داخل dialCh می‌فرستد
+*/
+func dialLoop(tr transport, remote string, dialCh chan<- net.Conn, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return // Link already chosen | اتصال قبلاً انتخاب شده
+		default:
+		}
+
+		c, err := tr.dial(remote)
+		if err == nil {
+			dialCh <- c
+			return
+		}
+		time.Sleep(dialRetryEvery) // Wait before retry | صبر قبل از تلاش مجدد
+	}
+}
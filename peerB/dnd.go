@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"     // For command output and the summary
+	"strings" // For joining the auto-reply text
+	"time"    // For the DND duration
+)
+
+const dndMaxQueued = 50 // Mentions kept for the summary | ذکرهای نگه‌داشته‌شده برای خلاصه
+
+func init() {
+	registerCommand("dnd", "/dnd [duration|off] [auto-reply]  do not disturb", dndCommand)
+}
+
+/*
+dndState is the do-not-disturb part of the notifier: while active no
+bell or flash fires, mentions are queued for the summary and the first
+message may get an auto-reply.
+
+این ساختار بخش «مزاحم نشوید» در notifier است: تا وقتی فعال است زنگ و
+چشمکی نیست، ذکرهای نام برای خلاصه نگه داشته می‌شوند و اولین پیام
+می‌تواند پاسخ خودکار بگیرد
+*/
+type dndState struct {
+	active   bool
+	until    time.Time // Zero means until /dnd off | صفر یعنی تا /dnd off
+	gen      int       // Bumped on every start so stale timers do nothing | شمارنده برای نادیده‌گرفتن تایمرهای قدیمی
+	reply    string    // Auto-reply text, if any | متن پاسخ خودکار
+	replied  bool      // Auto-reply already sent | پاسخ خودکار ارسال شده
+	missed   int       // Messages received meanwhile | پیام‌های دریافتی در این مدت
+	mentions []message // Queued mention alerts | ذکرهای نام در صف
+}
+
+// hold records a message received during DND and returns the auto-reply to send, if any | ثبت پیام در حالت DND
+func (d *dndState) hold(m message, mentioned bool) string {
+	d.missed++
+	if mentioned && len(d.mentions) < dndMaxQueued {
+		d.mentions = append(d.mentions, m)
+	}
+	if d.reply == "" || d.replied || m.Auto {
+		return "" // Once per DND, never to another auto-reply | یک بار، و هرگز به پاسخ خودکار دیگر
+	}
+	d.replied = true
+	return d.reply
+}
+
+/*
+startDND turns do-not-disturb on for d (0 means until stopped); a
+running DND is replaced without a summary.
+
+این تابع حالت «مزاحم نشوید» را به مدت d (صفر یعنی تا توقف) فعال می‌کند؛
+حالت فعال قبلی بدون خلاصه جایگزین می‌شود
+*/
+func (n *notifier) startDND(d time.Duration, reply string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	gen := n.dnd.gen + 1
+	n.dnd = dndState{active: true, gen: gen, reply: reply}
+	if d > 0 {
+		n.dnd.until = time.Now().Add(d)
+		time.AfterFunc(d, func() { n.stopDND(gen) })
+	}
+}
+
+/*
+stopDND ends do-not-disturb and prints what was missed; gen 0 stops
+any DND, otherwise only the one that timer was started for.
+
+این تابع حالت «مزاحم نشوید» را پایان می‌دهد و خلاصه‌ی پیام‌های از دست رفته
+را چاپ می‌کند؛ gen صفر هر DND را متوقف می‌کند و در غیر این صورت فقط همان DND تایمر را
+*/
+func (n *notifier) stopDND(gen int) bool {
+	n.mu.Lock()
+	d := n.dnd
+	if !d.active || gen != 0 && gen != d.gen {
+		n.mu.Unlock()
+		return false
+	}
+	n.dnd = dndState{gen: d.gen}
+	n.mu.Unlock()
+
+	fmt.Fprintf(stdout, "Do not disturb is off: %d %s, %d %s while you were away\n",
+		d.missed, plural(d.missed, "message", "messages"),
+		len(d.mentions), plural(len(d.mentions), "mention", "mentions"))
+	for _, m := range d.mentions {
+		fmt.Fprintln(stdout, "  "+formatHistoryLine(m))
+	}
+	return true
+}
+
+// plural picks the singular or plural word for n | انتخاب شکل مفرد یا جمع
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+/*
+dndCommand turns do-not-disturb on, optionally for a duration and with
+an auto-reply, or off with "/dnd off".
+
+این دستور حالت «مزاحم نشوید» را (در صورت نیاز با مدت و پاسخ خودکار)
+فعال و با "/dnd off" غیرفعال می‌کند
+*/
+func dndCommand(s *session, args []string) {
+	if len(args) > 0 && args[0] == "off" {
+		if !s.notify.stopDND(0) {
+			fmt.Fprintln(stdout, "Do not disturb is not on")
+		}
+		return
+	}
+	var d time.Duration
+	if len(args) > 0 {
+		if v, err := time.ParseDuration(args[0]); err == nil && v > 0 {
+			d, args = v, args[1:]
+		}
+	}
+	reply := strings.Join(args, " ")
+	s.notify.startDND(d, reply)
+
+	msg := "Do not disturb is on"
+	if d > 0 {
+		msg += " for " + d.String()
+	} else {
+		msg += " until /dnd off"
+	}
+	if reply != "" {
+		msg += "; auto-reply: " + reply
+	}
+	fmt.Fprintln(stdout, msg)
+}
+
+// sendAutoReply answers m with text, marked so the other side never auto-replies to it | ارسال پاسخ خودکار
+func sendAutoReply(s *session, m message, text string) {
+	if _, err := sendChat(s, text, &m, true); err != nil {
+		fmt.Fprintln(stdout, "Auto-reply error:", err)
+	}
+}
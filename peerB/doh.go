@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"    // For the query body
+	"context"  // For the dial budget
+	"errors"   // For resolver error values
+	"fmt"      // For the server status
+	"io"       // For bounding the answer
+	"net"      // For the resolved addresses
+	"net/http" // For the DoH requests
+	"net/url"  // For checking the server URL
+	"strings"  // For the fully qualified name
+
+	"golang.org/x/net/dns/dnsmessage" // For the DNS wire format
+)
+
+const dohMaxAnswer = 64 << 10 // Largest DNS answer read from the server | بزرگ‌ترین پاسخ DNS خوانده‌شده
+
+var errDoHURL = errors.New("doh must be an https:// URL") // Plain or malformed server URL | آدرس سرور ساده یا نامعتبر
+
+// lookupHost resolves the dial host; DoH replaces it when configured | resolve میزبان dial؛ با DoH جایگزین می‌شود
+var lookupHost = net.DefaultResolver.LookupIPAddr
+
+/*
+dohResolver resolves names over DNS-over-HTTPS (RFC 8484), so the
+contact's hostname travels inside TLS to the DoH server instead of as a
+plain query on the local network. Only the server's own name goes to
+the system resolver.
+
+این نوع نام‌ها را با DNS-over-HTTPS (RFC 8484) resolve می‌کند تا نام میزبان
+طرف مقابل داخل TLS به سرور DoH برود نه به‌صورت پرس‌وجوی ساده روی شبکه‌ی
+محلی؛ فقط نام خود سرور به resolver سیستم می‌رسد
+*/
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+// newDoHResolver checks the server URL | بررسی آدرس سرور
+func newDoHResolver(server string) (*dohResolver, error) {
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errDoHURL
+	}
+	return &dohResolver{url: server, client: http.DefaultClient}, nil
+}
+
+/*
+lookup returns the IPv4 and IPv6 addresses of host, like
+net.Resolver.LookupIPAddr. IP literals are returned as they are.
+
+این تابع مانند net.Resolver.LookupIPAddr آدرس‌های IPv4 و IPv6 میزبان را
+برمی‌گرداند؛ آدرس IP همان‌طور برگردانده می‌شود
+*/
+func (r *dohResolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host}
+	}
+	var addrs []net.IPAddr
+	var lastErr error
+	for _, t := range []dnsmessage.Type{dnsmessage.TypeAAAA, dnsmessage.TypeA} {
+		found, err := r.query(ctx, name, t)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		addrs = append(addrs, found...)
+	}
+	if len(addrs) == 0 {
+		if lastErr != nil {
+			return nil, &net.DNSError{Err: lastErr.Error(), Name: host, Server: r.url}
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: r.url, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// query asks the server for one record type of name | پرس‌وجوی یک نوع رکورد name از سرور
+func (r *dohResolver) query(ctx context.Context, name dnsmessage.Name, t dnsmessage.Type) ([]net.IPAddr, error) {
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true}, // ID 0 keeps answers cacheable | شناسه‌ی صفر برای cache
+		Questions: []dnsmessage.Question{{Name: name, Type: t, Class: dnsmessage.ClassINET}},
+	}
+	body, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, dohMaxAnswer))
+	if err != nil {
+		return nil, err
+	}
+
+	var m dnsmessage.Message
+	if err := m.Unpack(data); err != nil {
+		return nil, err
+	}
+	if m.RCode != dnsmessage.RCodeSuccess && m.RCode != dnsmessage.RCodeNameError {
+		return nil, fmt.Errorf("server answered %v", m.RCode) // A failure is not an unknown name | شکست به معنای نام ناشناخته نیست
+	}
+	var addrs []net.IPAddr
+	for _, a := range m.Answers {
+		switch rr := a.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, net.IPAddr{IP: net.IP(rr.A[:])})
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, net.IPAddr{IP: net.IP(rr.AAAA[:])})
+		}
+	}
+	return addrs, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohServer answers RFC 8484 queries from a fixed zone over TLS | سرور DoH با یک zone ثابت روی TLS
+func dohServer(t *testing.T) *dohResolver {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var q dnsmessage.Message
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/dns-message" || q.Unpack(body) != nil || len(q.Questions) != 1 {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		question := q.Questions[0]
+		a := dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: q.Questions}
+		hdr := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET}
+		switch question.Name.String() {
+		case "both.example.":
+			if question.Type == dnsmessage.TypeA {
+				a.Answers = append(a.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}})
+			} else {
+				a.Answers = append(a.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}})
+			}
+		case "gone.example.":
+			a.RCode = dnsmessage.RCodeNameError
+		case "fail.example.":
+			a.RCode = dnsmessage.RCodeServerFailure
+		case "down.example.":
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		out, err := a.Pack()
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(srv.Close)
+	r, err := newDoHResolver(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.client = srv.Client()
+	return r
+}
+
+func TestDoHResolver(t *testing.T) {
+	for _, bad := range []string{"http://dns.example/dns-query", "dns.example", "https://", "::"} {
+		if _, err := newDoHResolver(bad); err != errDoHURL {
+			t.Errorf("newDoHResolver(%q): %v", bad, err)
+		}
+	}
+
+	r := dohServer(t)
+	ctx := context.Background()
+	addrs, err := r.lookup(ctx, "both.example")
+	if err != nil || len(addrs) != 2 || addrs[0].String() != "2001:db8::1" || addrs[1].String() != "192.0.2.1" {
+		t.Errorf("both.example: %v, %v", addrs, err)
+	}
+	if addrs, err := r.lookup(ctx, "198.51.100.7"); err != nil || len(addrs) != 1 || addrs[0].String() != "198.51.100.7" {
+		t.Errorf("an IP literal: %v, %v", addrs, err)
+	}
+
+	var dnsErr *net.DNSError
+	if _, err := r.lookup(ctx, "gone.example"); !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("an unknown name: %v", err)
+	}
+	for _, host := range []string{"fail.example", "down.example"} {
+		if _, err := r.lookup(ctx, host); !errors.As(err, &dnsErr) || dnsErr.IsNotFound {
+			t.Errorf("%s: %v, want a failure rather than no such host", host, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json" // For the draft file
+	"errors"        // For a missing file
+	"fmt"           // For the restore notice
+	"os"            // For reading and writing the file
+	"path/filepath" // For creating the data directory
+	"strings"       // For cleaning the restored line
+)
+
+/*
+draft is input that was not sent when the program stopped: the line
+being typed and the lines of an unfinished capture, with the command
+that starts that capture again.
+
+این نوع ورودی ارسال‌نشده هنگام توقف برنامه است: خط در حال تایپ و خطوط
+ضبط ناتمام همراه دستوری که آن ضبط را دوباره آغاز می‌کند
+*/
+type draft struct {
+	Line    string   `json:"line,omitempty"`
+	Compose string   `json:"compose,omitempty"` // e.g. "/code go" | مثلاً "/code go"
+	Lines   []string `json:"lines,omitempty"`
+}
+
+// defaultDraftPath returns the per-name draft file | مسیر پیش‌فرض فایل پیش‌نویس
+func defaultDraftPath(name string) string {
+	return dataPath(name + ".draft")
+}
+
+func (d draft) empty() bool {
+	return d.Line == "" && d.Compose == ""
+}
+
+/*
+saveDraft writes the unsent input of s and con to path, or removes the
+file when there is none. An empty path (anonymous mode) keeps nothing.
+
+این تابع ورودی ارسال‌نشده‌ی s و con را در path می‌نویسد یا اگر چیزی نباشد
+فایل را حذف می‌کند؛ path خالی (حالت ناشناس) چیزی نگه نمی‌دارد
+*/
+func saveDraft(path string, s *session, con *console) error {
+	if path == "" {
+		return nil
+	}
+	var d draft
+	if con != nil {
+		d.Line = con.line()
+	}
+	d.Compose, d.Lines = s.compose.snapshot()
+	if d.empty() {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(d) // Strings cannot fail | برای رشته‌ها خطا نمی‌دهد
+	return os.WriteFile(path, data, 0o600)
+}
+
+/*
+restoreDraft puts a saved draft back, once: the capture is started
+again with its lines, and the line is typed into the editor so it can
+be finished or edited. Without an editor the line is only printed.
+
+این تابع پیش‌نویس ذخیره‌شده را یک بار بازمی‌گرداند: ضبط همراه خطوطش دوباره
+آغاز می‌شود و خط در ویرایشگر تایپ می‌شود تا بتوان آن را کامل یا ویرایش کرد؛
+بدون ویرایشگر خط فقط چاپ می‌شود
+*/
+func restoreDraft(path string, s *session, con *console) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(path) // Restored once | فقط یک بار بازگردانی می‌شود
+	var d draft
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	if d.Compose != "" {
+		fmt.Fprintf(stdout, "Restored draft: %s with %d %s\n", d.Compose, len(d.Lines), plural(len(d.Lines), "line", "lines"))
+		runCommand(s, d.Compose)
+		s.compose.restore(d.Lines)
+	}
+	line := strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1 // Would act as a key | به‌عنوان کلید عمل می‌کند
+		}
+		return r
+	}, d.Line)
+	if line == "" {
+		return nil
+	}
+	if con == nil {
+		fmt.Fprintln(stdout, "Restored draft:", line)
+		return nil
+	}
+	fmt.Fprintln(stdout, "Restored draft")
+	con.typeAhead(line)
+	return nil
+}
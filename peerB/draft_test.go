@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDraftRoundTrip(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	path := filepath.Join(t.TempDir(), "data", "ann.draft")
+	out := make(chan string, 1)
+
+	s := codeSession(t, capabilities{MaxMessage: 4096, CodeSnippets: true}, out)
+	if err := saveDraft(path, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("nothing unsent, yet a draft was written: %v", err)
+	}
+	runCommand(s, "/code go")
+	handleInput(s, "func f() {")
+	handleInput(s, "}")
+	if err := saveDraft(path, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDraft("", s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s = codeSession(t, capabilities{MaxMessage: 4096, CodeSnippets: true}, out)
+	if err := restoreDraft(path, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("draft kept after restoring: %v", err)
+	}
+	if cmd, lines := s.compose.snapshot(); cmd != "/code go" || strings.Join(lines, "\n") != "func f() {\n}" {
+		t.Errorf("restored capture %q %q", cmd, lines)
+	}
+	if !strings.Contains(buf.String(), "Restored draft: /code go with 2 lines\n") {
+		t.Errorf("output:\n%s", buf.String())
+	}
+	handleInput(s, "/end")
+	keys, _ := loadRegistry("")
+	if m, _ := decodeChatLine(<-out, keys); m.Code != "go" || m.Text != "func f() {\n}" {
+		t.Errorf("restored snippet sent as %+v", m)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"line":"half a\u001b[2J thought\r"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreDraft(path, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "Restored draft: half a[2J thought\n") {
+		t.Errorf("typed line restored as:\n%s", buf.String())
+	}
+	if err := restoreDraft(path, s, nil); err != nil {
+		t.Errorf("restoring with no draft: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings" // For matching shortcodes
+)
+
+/*
+emojiShortcodes maps the shortcodes that Tab completes to their emoji,
+using the names common to chat apps.
+
+این جدول کدهای کوتاهی را که Tab کامل می‌کند به emoji آن‌ها نگاشت می‌کند؛
+نام‌ها همان نام‌های رایج در برنامه‌های چت هستند
+*/
+var emojiShortcodes = map[string]string{
+	"100":          "💯",
+	"angry":        "😠",
+	"beer":         "🍺",
+	"blush":        "😊",
+	"broken_heart": "💔",
+	"bug":          "🐛",
+	"bulb":         "💡",
+	"cake":         "🍰",
+	"check":        "✅",
+	"clap":         "👏",
+	"coffee":       "☕",
+	"cry":          "😢",
+	"eyes":         "👀",
+	"facepalm":     "🤦",
+	"fire":         "🔥",
+	"ghost":        "👻",
+	"grin":         "😁",
+	"heart":        "❤️",
+	"heart_eyes":   "😍",
+	"hourglass":    "⏳",
+	"joy":          "😂",
+	"key":          "🔑",
+	"link":         "🔗",
+	"lock":         "🔒",
+	"memo":         "📝",
+	"moon":         "🌙",
+	"muscle":       "💪",
+	"neutral_face": "😐",
+	"ok_hand":      "👌",
+	"partying":     "🥳",
+	"pizza":        "🍕",
+	"pray":         "🙏",
+	"rainbow":      "🌈",
+	"robot":        "🤖",
+	"rocket":       "🚀",
+	"rofl":         "🤣",
+	"scream":       "😱",
+	"shrug":        "🤷",
+	"skull":        "💀",
+	"sleeping":     "😴",
+	"smile":        "😄",
+	"smirk":        "😏",
+	"sob":          "😭",
+	"sparkles":     "✨",
+	"star":         "⭐",
+	"sun":          "☀️",
+	"sunglasses":   "😎",
+	"sweat_smile":  "😅",
+	"tada":         "🎉",
+	"thinking":     "🤔",
+	"thumbsdown":   "👎",
+	"thumbsup":     "👍",
+	"upside_down":  "🙃",
+	"warning":      "⚠️",
+	"wave":         "👋",
+	"wink":         "😉",
+	"x":            "❌",
+	"zap":          "⚡",
+}
+
+func init() {
+	registerWordCompleter(':', completeEmoji)
+}
+
+/*
+completeEmoji completes ":name" to its emoji once the shortcode is
+unambiguous or typed in full (":smile:"); until then it offers the
+matching shortcodes.
+
+این تابع ":name" را وقتی کد کوتاه یکتا یا کامل (":smile:") باشد به emoji
+آن تبدیل می‌کند و تا آن زمان کدهای کوتاه منطبق را پیشنهاد می‌دهد
+*/
+func completeEmoji(_ *session, word string) []string {
+	name := strings.TrimPrefix(word, ":")
+	if e, ok := emojiShortcodes[strings.TrimSuffix(name, ":")]; ok && strings.HasSuffix(name, ":") {
+		return []string{e}
+	}
+	var out []string
+	for code := range emojiShortcodes {
+		if strings.HasPrefix(code, name) {
+			out = append(out, ":"+code+":")
+		}
+	}
+	if len(out) == 1 {
+		return []string{emojiShortcodes[strings.Trim(out[0], ":")]}
+	}
+	return out
+}
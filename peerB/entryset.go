@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"        // For a missing file on first run
+	"fmt"           // For listing entries
+	"os"            // For reading and writing the file
+	"path/filepath" // For creating the parent directory
+	"sort"          // For a stable listing
+	"strings"       // For parsing the file
+	"sync"          // For guarding the entries
+)
+
+/*
+entrySet is a set of strings (nicks, key fingerprints) saved to disk,
+one entry per line, after every change. The ignore and ban lists are
+both entry sets.
+
+این نوع مجموعه‌ای از رشته‌ها (نام‌ها، fingerprintها) است که پس از هر
+تغییر روی دیسک (هر خط یک مورد) ذخیره می‌شود؛ لیست نادیده‌گیری و
+لیست مسدودی هر دو از این نوع هستند
+*/
+type entrySet struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]bool
+}
+
+/*
+loadEntrySet reads the set stored at path; a missing file is an
+empty set. An empty path keeps the set in memory only.
+
+این تابع مجموعه‌ی ذخیره‌شده در path را می‌خواند؛ نبود فایل یعنی
+مجموعه‌ی خالی و path خالی یعنی نگهداری فقط در حافظه
+*/
+func loadEntrySet(path string) (*entrySet, error) {
+	l := &entrySet{path: path, entries: make(map[string]bool)}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			l.entries[line] = true
+		}
+	}
+	return l, nil
+}
+
+// has reports whether any non-empty value is in the set | آیا یکی از مقادیر غیرخالی در مجموعه هست
+func (l *entrySet) has(values ...string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, v := range values {
+		if v != "" && l.entries[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// set adds or removes an entry and saves the set | افزودن یا حذف یک مورد و ذخیره مجموعه
+func (l *entrySet) set(entry string, present bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if present {
+		l.entries[entry] = true
+	} else {
+		delete(l.entries, entry)
+	}
+	return l.save()
+}
+
+// sorted returns the entries in order | برگرداندن موارد به‌صورت مرتب
+func (l *entrySet) sorted() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]string, 0, len(l.entries))
+	for e := range l.entries {
+		out = append(out, e)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// len returns the number of entries | تعداد موارد
+func (l *entrySet) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// save writes the set to disk; the caller holds mu | ذخیره مجموعه روی دیسک (mu باید گرفته شده باشد)
+func (l *entrySet) save() error {
+	if l.path == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for e := range l.entries {
+		b.WriteString(e + "\n")
+	}
+	return os.WriteFile(l.path, []byte(b.String()), 0o600)
+}
+
+/*
+printEntries lists a set for a "/<cmd> list" command.
+
+این تابع موارد یک مجموعه را برای دستور "/<cmd> list" چاپ می‌کند
+*/
+func printEntries(l *entrySet, empty string) {
+	entries := l.sorted()
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, empty)
+	}
+	for _, e := range entries {
+		fmt.Fprintln(stdout, "  "+e)
+	}
+}
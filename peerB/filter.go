@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"       // For filter configuration errors
+	"regexp"       // For matching the word list
+	"sort"         // For trying longer words first
+	"strings"      // For parsing the word list and masking
+	"unicode"      // For word boundaries in any script
+	"unicode/utf8" // For the runes around a match
+)
+
+var errFilterAction = errors.New(`filter action must be "mask" or "drop"`) // Unknown filter-action value | مقدار نامعتبر برای filter-action
+
+/*
+messageFilter is one stage of the filter pipeline. It may rewrite the
+message in place; returning false drops it.
+
+هر messageFilter یک مرحله از زنجیره‌ی فیلتر است؛ می‌تواند پیام را
+تغییر دهد و با برگرداندن false آن را حذف کند
+*/
+type messageFilter interface {
+	filter(m *message) bool
+}
+
+// filterChain runs stages in order until one drops the message | اجرای مراحل به ترتیب تا حذف پیام
+type filterChain []messageFilter
+
+// apply reports whether the message survived every stage | آیا پیام از همه‌ی مراحل عبور کرد
+func (c filterChain) apply(m *message) bool {
+	for _, f := range c {
+		if !f.filter(m) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+wordFilter masks (or drops messages containing) any word from a
+configured list; matching is case-insensitive on whole words, in any
+script: a match must not touch a letter, digit or underscore.
+
+این فیلتر هر کلمه از لیست پیکربندی‌شده را با * می‌پوشاند (یا پیام را
+حذف می‌کند)؛ تطبیق بدون حساسیت به حروف و روی کل کلمه در هر خطی انجام
+می‌شود: کنار تطبیق نباید حرف، رقم یا زیرخط باشد
+*/
+type wordFilter struct {
+	pattern *regexp.Regexp
+	drop    bool
+}
+
+// filter implements messageFilter | پیاده‌سازی messageFilter
+func (w *wordFilter) filter(m *message) bool {
+	var b strings.Builder
+	last := 0
+	for _, at := range w.pattern.FindAllStringIndex(m.Text, -1) {
+		if !wholeWord(m.Text, at[0], at[1]) {
+			continue // Part of a longer word | بخشی از کلمه‌ای بلندتر
+		}
+		if w.drop {
+			return false
+		}
+		b.WriteString(m.Text[last:at[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(m.Text[at[0]:at[1]])))
+		last = at[1]
+	}
+	if last > 0 {
+		m.Text = b.String() + m.Text[last:]
+	}
+	return true
+}
+
+// wholeWord reports whether text[start:end] is not part of a longer word | آیا text[start:end] بخشی از کلمه‌ای بلندتر نیست
+func wholeWord(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return !wordRune(before) && !wordRune(after)
+}
+
+// wordRune reports whether r can be part of a word | آیا r می‌تواند بخشی از کلمه باشد
+func wordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+/*
+newFilters builds the inbound and outbound pipelines from the
+configured word list ("a,b,c") and action; outbound messages are only
+filtered when outbound is set.
+
+این تابع زنجیره‌های ورودی و خروجی را از لیست کلمات ("a,b,c") و
+عملیات پیکربندی‌شده می‌سازد؛ پیام‌های خروجی فقط با outbound فیلتر می‌شوند
+*/
+func newFilters(words, action string, outbound bool) (in, out filterChain, err error) {
+	if action != "mask" && action != "drop" {
+		return nil, nil, errFilterAction
+	}
+	var quoted []string
+	for _, w := range strings.Split(words, ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, nil, nil
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) }) // "ab" must not hide "abc" | "ab" نباید "abc" را پنهان کند
+
+	wf := &wordFilter{
+		pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`),
+		drop:    action == "drop",
+	}
+	in = filterChain{wf}
+	if outbound {
+		out = filterChain{wf}
+	}
+	return in, out, nil
+}
+
+/*
+outgoingText runs our own text through the outbound filters; false
+means the message must not be sent.
+
+این تابع متن خودمان را از فیلترهای خروجی عبور می‌دهد؛
+false یعنی پیام نباید ارسال شود
+*/
+func outgoingText(s *session, text string) (string, bool) {
+	m := message{From: s.name, Text: text}
+	ok := s.outbound.apply(&m)
+	return m.Text, ok
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"      // For spec and send errors
+	"fmt"         // For the payload and summary
+	"io"          // For the summary writer
+	"strconv"     // For parsing the spec values
+	"strings"     // For splitting the spec and padding payloads
+	"sync/atomic" // For counters read by the summary
+	"time"        // For pacing
+)
+
+var errLoadSpec = errors.New(`generate takes "rate=<n>/s size=<bytes> [count=<n>]"`) // Malformed -generate | مقدار نامعتبر generate
+
+/*
+loadGenerator sends synthetic chat messages at a fixed rate for soak
+tests and counts what happened, for the summary printed on exit.
+
+این نوع برای آزمون‌های طولانی پیام‌های ساختگی با نرخ ثابت ارسال می‌کند
+و نتیجه را برای گزارشی که هنگام خروج چاپ می‌شود می‌شمارد
+*/
+type loadGenerator struct {
+	rate  float64 // Messages per second | پیام در ثانیه
+	size  int     // Bytes per message | حجم هر پیام
+	count int64   // Messages before exiting (0 = until stopped) | تعداد پیام تا خروج
+
+	start  atomic.Int64 // When run began (unix nano) | زمان شروع
+	sent   atomic.Int64 // Queued messages | پیام‌های در صف قرارگرفته
+	bytes  atomic.Int64 // Text bytes queued | بایت‌های متن
+	failed atomic.Int64 // Rejected by sendChat | پیام‌های ردشده
+	late   atomic.Int64 // Sent over one interval behind schedule | ارسال‌شده با تأخیر بیش از یک بازه
+	maxLag atomic.Int64 // Worst delay behind schedule (ns) | بیشترین تأخیر از برنامه
+}
+
+/*
+newLoadGenerator parses a spec such as "rate=100/s size=256 count=5000"
+(fields may also be separated by commas); the rate unit is /s, /m or
+/h. An empty spec returns nil.
+
+این تابع مشخصاتی مانند "rate=100/s size=256 count=5000" را تجزیه
+می‌کند (جداکننده می‌تواند کاما هم باشد)؛ واحد نرخ /s، /m یا /h است.
+مشخصات خالی nil برمی‌گرداند
+*/
+func newLoadGenerator(spec string) (*loadGenerator, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	g := &loadGenerator{rate: 10, size: 64}
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", errLoadSpec, field)
+		}
+		var err error
+		switch key {
+		case "rate":
+			g.rate, err = parseRate(value)
+		case "size":
+			g.size, err = strconv.Atoi(value)
+		case "count":
+			g.count, err = strconv.ParseInt(value, 10, 64)
+		default:
+			err = errLoadSpec
+		}
+		if err != nil || g.rate <= 0 || g.size <= 0 || g.count < 0 {
+			return nil, fmt.Errorf("%w: %q", errLoadSpec, field)
+		}
+	}
+	return g, nil
+}
+
+// parseRate converts "100/s", "600/m" or "100" to messages per second | تبدیل نرخ به پیام در ثانیه
+func parseRate(s string) (float64, error) {
+	n, unit, _ := strings.Cut(s, "/")
+	rate, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch unit {
+	case "", "s":
+		return rate, nil
+	case "m":
+		return rate / 60, nil
+	case "h":
+		return rate / 3600, nil
+	default:
+		return 0, errLoadSpec
+	}
+}
+
+/*
+run sends messages on a fixed schedule until the session ends or count
+is reached; in the latter case it waits for them to be flushed and
+closes the session. A send that falls behind is made at once, so the
+average rate holds, and is counted as late.
+
+این تابع پیام‌ها را طبق برنامه‌ی ثابت ارسال می‌کند تا نشست تمام شود یا
+به count برسد؛ در حالت دوم منتظر ارسال کامل می‌ماند و نشست را می‌بندد.
+ارسالی که عقب بیفتد فوراً انجام و به‌عنوان تأخیری شمرده می‌شود تا نرخ
+میانگین حفظ شود
+*/
+func (g *loadGenerator) run(s *session) {
+	interval := time.Duration(float64(time.Second) / g.rate)
+	start, base := time.Now(), s.sent.Load()
+	g.start.Store(start.UnixNano())
+	for seq := int64(0); g.count == 0 || seq < g.count; seq++ {
+		due := start.Add(time.Duration(seq) * interval)
+		if wait := time.Until(due); wait > 0 {
+			select {
+			case <-s.done.c:
+				return
+			case <-time.After(wait):
+			}
+		}
+		if lag := time.Since(due); lag > interval {
+			g.late.Add(1)
+			if int64(lag) > g.maxLag.Load() {
+				g.maxLag.Store(int64(lag))
+			}
+		}
+
+		text := loadPayload(seq, g.size)
+		_, err := sendChat(s, text, nil, false)
+		if errors.Is(err, errClosed) {
+			return
+		}
+		if err != nil {
+			g.failed.Add(1)
+			continue
+		}
+		g.sent.Add(1)
+		g.bytes.Add(int64(len(text)))
+	}
+	if waitSent(s, base+g.sent.Load()) {
+		s.done.close() // All generated messages are out | همه‌ی پیام‌ها ارسال شدند
+	}
+}
+
+// loadPayload returns a numbered message padded to size bytes | پیام شماره‌دار با طول size
+func loadPayload(seq int64, size int) string {
+	text := "load " + strconv.FormatInt(seq, 10) + " "
+	if len(text) < size {
+		text += strings.Repeat(".", size-len(text))
+	}
+	return text
+}
+
+// report prints the summary of a run; nil prints nothing | چاپ گزارش اجرا
+func (g *loadGenerator) report(w io.Writer) {
+	if g == nil || g.start.Load() == 0 {
+		return
+	}
+	elapsed := time.Since(time.Unix(0, g.start.Load()))
+	sent := g.sent.Load()
+	fmt.Fprintf(w, "Generated   : %d messages, %d bytes in %s\n", sent, g.bytes.Load(), elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Rate        : %.1f msgs/sec (target %.1f)\n", float64(sent)/elapsed.Seconds(), g.rate)
+	fmt.Fprintf(w, "Late sends  : %d (max lag %s)\n", g.late.Load(), time.Duration(g.maxLag.Load()).Round(time.Millisecond))
+	fmt.Fprintf(w, "Failed      : %d\n", g.failed.Load())
+}
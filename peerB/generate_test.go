@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSpec(t *testing.T) {
+	type want struct {
+		rate  float64
+		size  int
+		count int64
+	}
+	for spec, want := range map[string]want{
+		"rate=100/s size=256":        {rate: 100, size: 256},
+		"rate=600/m,size=10,count=5": {rate: 10, size: 10, count: 5},
+		"rate=7200/h":                {rate: 2, size: 64},
+		"count=3":                    {rate: 10, size: 64, count: 3},
+	} {
+		g, err := newLoadGenerator(spec)
+		if err != nil || g.rate != want.rate || g.size != want.size || g.count != want.count {
+			t.Errorf("%q: %+v, %v; want rate %v size %d count %d", spec, g, err, want.rate, want.size, want.count)
+		}
+	}
+	for _, spec := range []string{"rate=0/s", "rate=5/d", "size=0", "count=-1", "speed=3", "rate"} {
+		if _, err := newLoadGenerator(spec); !errors.Is(err, errLoadSpec) {
+			t.Errorf("%q: %v, want %v", spec, err, errLoadSpec)
+		}
+	}
+	if g, err := newLoadGenerator(""); g != nil || err != nil {
+		t.Errorf("empty spec: %v, %v; want nothing generated", g, err)
+	}
+	if got := loadPayload(7, 12); got != "load 7 ....." {
+		t.Errorf("payload: %q", got)
+	}
+}
+
+func TestGenerateSendsAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two peers")
+	}
+	a, b := freeAddr(t), freeAddr(t)
+	gen, _, genErr := pipePeer(t, "", "-name", "G", "-listen", a, "-dial", b, "-wait", "2s", "-generate", "rate=200/s size=80 count=30")
+	recv, recvOut, recvErr := pipePeer(t, "", "-name", "R", "-listen", b, "-dial", a, "-wait", "2s")
+	for _, cmd := range []*exec.Cmd{gen, recv} {
+		waited := make(chan error, 1)
+		go func() { waited <- cmd.Wait() }()
+		select {
+		case err := <-waited:
+			if err != nil {
+				t.Fatalf("peer: %v\nG: %s\nR: %s", err, genErr, recvErr)
+			}
+		case <-time.After(20 * time.Second):
+			_ = gen.Process.Kill()
+			_ = recv.Process.Kill()
+			t.Fatalf("peers did not exit\nG: %s\nR: %s", genErr, recvErr)
+		}
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(recvOut.String()), "\n") {
+		var m message
+		if json.Unmarshal([]byte(line), &m) == nil && strings.HasPrefix(m.Text, "load ") {
+			if len(m.Text) != 80 || !m.Verified {
+				t.Errorf("generated message %q (%d bytes, verified %v)", m.Text, len(m.Text), m.Verified)
+			}
+			got = append(got, strings.TrimRight(m.Text, "."))
+		}
+	}
+	for i := range 30 {
+		if i >= len(got) || got[i] != fmt.Sprintf("load %d ", i) {
+			t.Fatalf("received %d generated messages, want 30 in order:\n%v", len(got), got)
+		}
+	}
+	if !strings.Contains(genErr.String(), "Generated   : 30 messages, 2400 bytes") || !strings.Contains(genErr.String(), "Failed      : 0") {
+		t.Errorf("summary:\n%s", genErr)
+	}
+}
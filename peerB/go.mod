@@ -1,3 +1,14 @@
 module peerB
 
-go 1.22
+go 1.23.0
+
+require (
+	github.com/alecthomas/chroma/v2 v2.20.0
+	github.com/hashicorp/yamux v0.1.2
+	golang.org/x/net v0.41.0
+	golang.org/x/sys v0.33.0
+	golang.org/x/term v0.32.0
+	rsc.io/qr v0.2.0
+)
+
+require github.com/dlclark/regexp2 v1.11.5 // indirect
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"           // For reading handshake lines without losing buffered bytes
+	"crypto/rand"     // For generating the random node ID
+	"encoding/binary" // For turning random bytes into a node ID
+	"encoding/hex"    // For the HELLO nonce
+	"errors"          // For handshake error values
+	"fmt"             // For formatting handshake lines
+	"net"             // For TCP networking
+	"strconv"         // For parsing the remote node ID
+	"strings"         // For splitting handshake lines
+	"sync/atomic"     // For the arbiter's "already kept a link" flag
+	"time"            // For the handshake deadline
+)
+
+/*
+Handshake configuration
+
+مقادیر پیکربندی handshake:
+- حداکثر زمان انتظار برای تکمیل handshake
+- مدتی که اتصال‌های دیررس همچنان بسته می‌شوند
+*/
+const (
+	handshakeTimeout = 5 * time.Second                // Max time to complete the handshake | حداکثر زمان handshake
+	lateConnWindow   = dialTimeout + handshakeTimeout // How long stray candidates are still closed | مدت بستن اتصال‌های دیررس
+	authContext      = "peerchat-auth"                // Signed with the HELLO lines in AUTH | همراه خطوط HELLO در AUTH امضا می‌شود
+)
+
+/*
+localNodeID identifies this process during the handshake.
+The peer with the lower ID arbitrates which connection survives.
+
+شناسه‌ی تصادفی این برنامه در handshake؛
+peerی که شناسه‌ی کوچک‌تری دارد تعیین می‌کند کدام اتصال باقی بماند
+*/
+var localNodeID = newNodeID()
+
+var (
+	errSelfConnect = errors.New("connected to self")             // Both ends share one node ID | اتصال به خود
+	errBadHello    = errors.New("malformed handshake")           // Unexpected handshake line | خط handshake نامعتبر
+	errDropped     = errors.New("connection dropped by arbiter") // Remote chose another link | طرف مقابل اتصال دیگری را انتخاب کرد
+	errBadAuth     = errors.New("remote failed key proof")       // AUTH signature invalid | امضای AUTH نامعتبر است
+	errDenied      = errors.New("remote was denied access")      // We refused the remote | ما peer مقابل را رد کردیم
+)
+
+/*
+refusedError carries the reason the remote gave for not admitting us,
+e.g. "this chat is invite-only".
+
+این خطا دلیلی را که peer مقابل برای نپذیرفتن ما اعلام کرده نگه می‌دارد
+*/
+type refusedError struct {
+	reason string
+}
+
+func (e *refusedError) Error() string { return "refused by remote: " + e.reason }
+
+/*
+handshakeConn is a connection that completed the handshake. It keeps
+the reader used during the handshake so bytes that arrived right after
+it are not lost, plus what was learned about the remote peer.
+
+این نوع اتصالی است که handshake را کامل کرده؛ reader استفاده‌شده
+در handshake را نگه می‌دارد تا داده‌های بعدی از دست نروند،
+به‌همراه اطلاعات به‌دست‌آمده درباره‌ی peer مقابل
+*/
+type handshakeConn struct {
+	net.Conn
+	r              *bufio.Reader
+	remoteID       uint64       // Remote node ID | شناسه‌ی peer مقابل
+	remoteProtocol int          // Remote wire protocol version | نسخه پروتکل peer مقابل
+	remoteVersion  string       // Remote build version | نسخه build طرف مقابل
+	caps           capabilities // Negotiated features | قابلیت‌های توافق‌شده
+	remoteKey      string       // Proven key fingerprint, if any | fingerprint کلید اثبات‌شده
+	resumed        bool         // Admitted on a resumption ticket | با ticket ازسرگیری پذیرفته شد
+	sentFrames     uint64       // Frame number our writer continues after | شماره‌ی فریمی که نویسنده‌ی ما پس از آن ادامه می‌دهد
+	seenFrames     uint64       // Frame number our reader continues after | شماره‌ی فریمی که خواننده‌ی ما پس از آن ادامه می‌دهد
+	arbiter        bool         // We decided which link survived | ما داور انتخاب اتصال بودیم
+	dialed         bool         // We dialed this link | این اتصال را ما برقرار کردیم
+}
+
+func (c *handshakeConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// handshakeResult is the outcome of one candidate connection | نتیجه‌ی handshake یک اتصال کاندید
+type handshakeResult struct {
+	conn   *handshakeConn
+	dialed bool
+	err    error
+}
+
+// newNonce returns a fresh random value for our HELLO line | مقدار تصادفی تازه برای خط HELLO ما
+func newNonce() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+/*
+newNodeID returns a random 64-bit node identifier.
+
+این تابع یک شناسه‌ی تصادفی ۶۴ بیتی می‌سازد
+*/
+func newNodeID() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+/*
+handshake exchanges node IDs, versions, capabilities and identity keys
+on a fresh connection and decides whether it becomes the active link:
+- each side proves its key by signing both HELLO lines (AUTH)
+- each side answers OK, or DENIED with a reason (ban, invite-only, password)
+- the peer with the lower ID is the arbiter and answers KEEP or DROP
+- on a kept link each side issues the other a resumption ticket (RESUME)
+- a resumption ticket or invite token that let the remote in is used up
+only on a kept link
+- claimed records whether the arbiter already kept a connection
+This guarantees exactly one link even when both peers dial at once.
+
+این تابع شناسه‌ها را روی اتصال جدید مبادله می‌کند و تصمیم می‌گیرد
+که آیا این اتصال، اتصال فعال شود:
+- هر طرف با امضای هر دو خط HELLO مالکیت کلیدش را ثابت می‌کند (AUTH)
+- هر طرف OK یا DENIED همراه با دلیل (مسدودی، دعوتی، رمز) می‌فرستد
+- peer با شناسه‌ی کوچک‌تر داور است و KEEP یا DROP می‌فرستد
+- روی اتصال نگه‌داشته‌شده هر طرف یک ticket ازسرگیری به دیگری می‌دهد (RESUME)
+- ticket ازسرگیری یا token دعوتی که peer را پذیرفته فقط روی اتصال
+نگه‌داشته‌شده مصرف می‌شود
+- claimed مشخص می‌کند داور قبلاً اتصالی را نگه داشته یا نه
+به این ترتیب حتی در اتصال همزمان، دقیقاً یک اتصال باقی می‌ماند
+*/
+func handshake(conn net.Conn, claimed *atomic.Bool, auth *peerAuth) (*handshakeConn, error) {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout)) // Bound the handshake | محدودکردن زمان handshake
+	defer conn.SetDeadline(time.Time{})                    // Clear deadline afterwards | حذف تایم‌اوت پس از پایان
+
+	r := bufio.NewReader(conn)
+	hello := fmt.Sprintf("HELLO %d %d %s %s %s %s", localNodeID, protocolVersion, strings.Join(strings.Fields(version), "_"), localCapabilities, auth.id.publicKey(), newNonce())
+	if _, err := fmt.Fprintf(conn, "%s\n", hello); err != nil {
+		return nil, err
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	fields := strings.Fields(line) // HELLO <node-id> [<protocol> <version> [<capabilities> [<key> [<nonce>]]]]
+	if len(fields) < 2 || fields[0] != "HELLO" {
+		return nil, errBadHello
+	}
+	remoteID, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, errBadHello
+	}
+	remoteProtocol, remoteVersion := 0, "unknown" // Older peers send only the ID | peerهای قدیمی فقط شناسه می‌فرستند
+	if len(fields) >= 4 {
+		remoteProtocol, _ = strconv.Atoi(fields[2])
+		remoteVersion = stripControl(fields[3]) // Printed to the terminal | در ترمینال چاپ می‌شود
+	}
+	remoteCaps := parseCapabilities("")
+	if len(fields) >= 5 {
+		remoteCaps = parseCapabilities(fields[4])
+	}
+	if remoteID == localNodeID {
+		return nil, errSelfConnect
+	}
+	caps := negotiate(localCapabilities, remoteCaps)
+
+	// Prove keys, then admit or refuse | اثبات کلیدها، سپس پذیرش یا رد
+	var remoteKey string
+	var resumed, token bool
+	var proof string
+	var last resumeTicket // The ticket the remote resumed on, if any | ticketی که طرف مقابل با آن ازسر گرفت
+	if len(fields) >= 6 {
+		fp, p, ticket, err := proveKeys(conn, r, auth, fields[5], [2]string{hello, line}, caps.Resume)
+		if err != nil {
+			return nil, err
+		}
+		last, resumed = auth.resume.check(fp, ticket, auth.policy(), [2]string{line, hello})
+		var reason string
+		reason, token = auth.admit(fields[5], p, [2]string{line, hello}, resumed)
+		if err := exchangeAdmission(conn, r, reason); err != nil {
+			return nil, err
+		}
+		remoteKey, proof = fp, p
+	} else if reason, _ := auth.admit("", noProof, [2]string{line, hello}, false); reason != "" {
+		return nil, errDenied // Keyless peers only get into open chats | peer بدون کلید فقط به چت آزاد راه دارد
+	}
+
+	arbiter := localNodeID < remoteID
+	switch {
+	case arbiter:
+		// We arbitrate: keep the first link, drop the rest | ما داور هستیم: اولین اتصال می‌ماند
+		if !claimed.CompareAndSwap(false, true) {
+			_, _ = fmt.Fprint(conn, "DROP\n")
+			return nil, errDropped
+		}
+		if _, err := fmt.Fprint(conn, "KEEP\n"); err != nil {
+			claimed.Store(false) // Let another candidate win | اجازه به کاندید دیگر
+			return nil, err
+		}
+	default:
+		// Remote arbitrates: wait for its verdict | طرف مقابل داور است: منتظر تصمیم می‌مانیم
+		verdict, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		switch strings.TrimSpace(verdict) {
+		case "KEEP":
+		case "DROP":
+			return nil, errDropped
+		default:
+			return nil, errBadHello
+		}
+	}
+	if resumed && !auth.resume.use(remoteKey, last.Ticket) {
+		// Another link resumed on the ticket first | اتصال دیگری زودتر با ticket ازسر گرفت
+		if arbiter {
+			claimed.Store(false) // Let another candidate win | اجازه به کاندید دیگر
+		}
+		return nil, errDenied
+	}
+	var sentFrames, seenFrames uint64
+	if caps.Resume && remoteKey != "" {
+		remoteLast, err := swapTickets(conn, r, auth, remoteKey, last.Sent, caps.ResumeFrames)
+		if err != nil {
+			if arbiter {
+				claimed.Store(false) // Let another candidate win | اجازه به کاندید دیگر
+			}
+			return nil, err
+		}
+		if caps.ResumeFrames {
+			sentFrames, seenFrames = resumeFrames(last, remoteLast)
+		}
+	}
+	if token && !auth.tokens.redeem(proof, [2]string{line, hello}) {
+		// Another link used the token first | اتصال دیگری زودتر token را مصرف کرد
+		if arbiter {
+			claimed.Store(false) // Let another candidate win | اجازه به کاندید دیگر
+		}
+		return nil, errDenied
+	}
+	return &handshakeConn{
+		Conn:           conn,
+		r:              r,
+		remoteID:       remoteID,
+		remoteProtocol: remoteProtocol,
+		remoteVersion:  remoteVersion,
+		caps:           caps,
+		remoteKey:      remoteKey,
+		resumed:        resumed,
+		sentFrames:     sentFrames,
+		seenFrames:     seenFrames,
+		arbiter:        arbiter,
+	}, nil
+}
+
+/*
+proveKeys sends our AUTH line, a signature over the handshake transcript
+(our HELLO line, then the remote's) plus a password proof over the same
+lines and, when both sides resume, a proof of the ticket we hold from
+the announced key, over them too. It checks the remote's AUTH against
+the key it announced, over the same two lines in its order. Our HELLO
+carries a fresh nonce, so a proof made for another connection cannot be
+replayed on this one, and a relay that alters either HELLO, say to
+speak to each side under its own node ID, breaks both signatures. It
+returns the remote's fingerprint and its password and ticket proofs.
+
+این تابع خط AUTH ما (امضای رونوشت handshake یعنی خط HELLO ما و سپس خط
+طرف مقابل، و اثبات رمز روی همان خطوط) را می‌فرستد و در صورت پشتیبانی هر دو طرف از
+ازسرگیری، اثبات ticketی را که از کلید اعلام‌شده داریم، باز روی همان خطوط، اضافه می‌کند؛ سپس AUTH
+طرف مقابل را با کلید اعلام‌شده‌اش روی همان دو خط به ترتیب او بررسی می‌کند.
+HELLO ما یک nonce تازه دارد، پس اثبات ساخته‌شده برای اتصال دیگر در این
+اتصال قابل تکرار نیست و relayی که یکی از دو HELLO را تغییر دهد، مثلاً تا با
+هر طرف با شناسه‌ی خودش حرف بزند، هر دو امضا را باطل می‌کند
+*/
+func proveKeys(conn net.Conn, r *bufio.Reader, auth *peerAuth, remoteKey string, hellos [2]string, resume bool) (string, string, string, error) {
+	_, sig := auth.id.sign(authContext, hellos[0], hellos[1])
+	line := fmt.Sprintf("AUTH %s %s", sig, auth.passwordProof(hellos))
+	want := 3
+	if resume {
+		line += " " + auth.resume.proof(keyFingerprint(remoteKey), hellos)
+		want = 4
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", line); err != nil {
+		return "", "", "", err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", "", "", err
+	}
+	fields := strings.Fields(line) // AUTH <signature> <password-proof> [<ticket-proof>]
+	if len(fields) != want || fields[0] != "AUTH" {
+		return "", "", "", errBadHello
+	}
+	fp, ok := verifySignature(remoteKey, fields[1], authContext, hellos[1], hellos[0])
+	if !ok {
+		return "", "", "", errBadAuth
+	}
+	ticket := noProof
+	if resume {
+		ticket = fields[3]
+	}
+	return fp, fields[2], ticket, nil
+}
+
+/*
+exchangeAdmission tells the remote whether we admit it ("OK" or
+"DENIED <reason>") and reads its verdict about us.
+
+این تابع به peer مقابل اعلام می‌کند که آن را می‌پذیریم ("OK") یا نه
+("DENIED <reason>") و تصمیم او درباره‌ی ما را می‌خواند
+*/
+func exchangeAdmission(conn net.Conn, r *bufio.Reader, reason string) error {
+	verdict := "OK"
+	if reason != "" {
+		verdict = "DENIED " + reason
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", verdict); err != nil {
+		return err
+	}
+	if reason != "" {
+		return fmt.Errorf("%w: %s", errDenied, reason)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimSpace(line)
+	if line == "OK" {
+		return nil
+	}
+	if why, ok := strings.CutPrefix(line, "DENIED "); ok {
+		return &refusedError{reason: why}
+	}
+	return errBadHello
+}
+
+/*
+swapTickets sends the remote a fresh resumption ticket for its key and
+keeps the one it sends us ("RESUME <ticket>"). When frame numbers
+resume, each side also announces the last frame number of its old link
+it continues after, 0 when starting over ("RESUME <ticket> <last>"); the
+remote's is returned.
+
+این تابع یک ticket ازسرگیری تازه برای کلید طرف مقابل می‌فرستد و ticketی
+را که او برای ما می‌فرستد نگه می‌دارد ("RESUME <ticket>")؛ اگر شماره‌ی
+فریم‌ها ادامه یابد هر طرف آخرین شماره‌ی فریم اتصال قبلی را که پس از آن
+ادامه می‌دهد اعلام می‌کند، یا ۰ برای شروع از نو ("RESUME <ticket> <last>")؛
+شماره‌ی طرف مقابل برگردانده می‌شود
+*/
+func swapTickets(conn net.Conn, r *bufio.Reader, auth *peerAuth, fp string, last uint64, frames bool) (uint64, error) {
+	ticket := auth.resume.issue(fp, auth.policy())
+	if ticket == "" {
+		ticket = noProof // Nothing to resume with | چیزی برای ازسرگیری نیست
+	}
+	line, want := "RESUME "+ticket, 2
+	if frames {
+		line, want = line+" "+strconv.FormatUint(last, 10), 3
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", line); err != nil {
+		return 0, err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != want || fields[0] != "RESUME" {
+		return 0, errBadHello
+	}
+	var remoteLast uint64
+	if frames {
+		if remoteLast, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+			return 0, errBadHello
+		}
+	}
+	if fields[1] != noProof {
+		auth.resume.hold(fp, fields[1])
+	}
+	return remoteLast, nil
+}
+
+/*
+runHandshake performs the handshake on a candidate connection, closes it
+if it lost, and reports the outcome into results.
+
+این تابع handshake را روی یک اتصال کاندید اجرا می‌کند،
+در صورت شکست آن را می‌بندد و نتیجه را داخل results می‌فرستد
+*/
+func runHandshake(conn net.Conn, dialed bool, claimed *atomic.Bool, auth *peerAuth, results chan<- handshakeResult) {
+	c, err := handshake(conn, claimed, auth)
+	if err != nil {
+		_ = conn.Close()
+	} else {
+		c.dialed = dialed
+	}
+	results <- handshakeResult{conn: c, dialed: dialed, err: err}
+}
+
+/*
+discardLate closes candidates that show up after the active link was
+chosen (e.g. the remote's dial that was still in flight).
+
+این تابع اتصال‌هایی را که بعد از انتخاب اتصال فعال می‌رسند می‌بندد
+(مثلاً dial طرف مقابل که هنوز در جریان بوده)
+*/
+func discardLate(acceptCh, dialCh <-chan net.Conn, results <-chan handshakeResult) {
+	deadline := time.After(lateConnWindow)
+	for {
+		select {
+		case c := <-acceptCh:
+			_ = c.Close()
+		case c := <-dialCh:
+			_ = c.Close()
+		case r := <-results:
+			if r.err == nil {
+				_ = r.conn.Close()
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
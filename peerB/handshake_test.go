@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// candidate speaks the dialing side of the handshake by hand and returns the listener's verdict | اجرای دستی سمت dial در handshake و بازگرداندن تصمیم listener
+func candidate(t *testing.T, addr string, id *identity, password string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * handshakeTimeout))
+	r := bufio.NewReader(conn)
+
+	node := localNodeID + 1
+	ours := fmt.Sprintf("HELLO %d %d test none %s %s", node, protocolVersion, id.publicKey(), newNonce())
+	fmt.Fprintf(conn, "%s\n", ours)
+	hello, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading HELLO: %v", err)
+	}
+	hello = strings.TrimRight(hello, "\r\n")
+	remote, err := strconv.ParseUint(strings.Fields(hello)[1], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := noProof
+	if password != "" {
+		proof = hex.EncodeToString(transcriptMAC(password, proofPassword, [2]string{ours, hello}))
+	}
+	_, sig := id.sign(authContext, ours, hello)
+	fmt.Fprintf(conn, "AUTH %s %s\n", sig, proof)
+	if _, err := r.ReadString('\n'); err != nil { // The listener's AUTH
+		t.Fatalf("reading AUTH: %v", err)
+	}
+	verdict, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading verdict: %v", err)
+	}
+	verdict = strings.TrimSpace(verdict)
+	if verdict != "OK" {
+		return verdict
+	}
+	fmt.Fprint(conn, "OK\n")
+	if remote < node {
+		_, err = r.ReadString('\n') // KEEP from the arbiter
+	} else {
+		_, err = fmt.Fprint(conn, "KEEP\n")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // Let the listener report before we hang up
+	return verdict
+}
+
+func TestRefusedCandidateKeepsListening(t *testing.T) {
+	dir := t.TempDir()
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, err := loadEntrySet(filepath.Join(dir, "bans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := loadEntrySet(filepath.Join(dir, "members"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newPeerAuth(server, bans, members, accessPassword, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tcpTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := newDoneSignal()
+	defer done.close()
+	linked := make(chan *handshakeConn, 1)
+	go func() {
+		linked <- establishConn(tcpTransport{}, ln, "127.0.0.1:1", auth, socketOptions{linger: -1}, io.Discard, done.c)
+	}()
+
+	if v := candidate(t, ln.Addr().String(), client, "wrong"); !strings.HasPrefix(v, "DENIED") {
+		t.Fatalf("wrong password: verdict %q, want DENIED", v)
+	}
+	if v := candidate(t, ln.Addr().String(), client, "secret"); v != "OK" {
+		t.Fatalf("right password after a refusal: verdict %q, want OK", v)
+	}
+	select {
+	case c := <-linked:
+		if c == nil {
+			t.Fatal("no link")
+		}
+		defer c.Close()
+		if c.remoteKey != client.fingerprint {
+			t.Fatalf("linked to %s, want %s", c.remoteKey, client.fingerprint)
+		}
+	case <-time.After(2 * handshakeTimeout):
+		t.Fatal("the listener never linked the second candidate")
+	}
+}
+
+func TestAuthSignsTranscript(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, _ := loadEntrySet("")
+	members, _ := loadEntrySet("")
+	auth, err := newPeerAuth(server, bans, members, accessOpen, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := localNodeID + 1
+	stale := fmt.Sprintf("HELLO %d %d test none %s %s", localNodeID, protocolVersion, server.publicKey(), newNonce())
+
+	for _, c := range []struct {
+		name string
+		sign func(ours, theirs string) string // The signature the client sends | امضایی که client می‌فرستد
+		ok   bool
+	}{
+		{"transcript", func(ours, theirs string) string {
+			_, sig := client.sign(authContext, ours, theirs)
+			return sig
+		}, true},
+		{"swapped lines", func(ours, theirs string) string {
+			_, sig := client.sign(authContext, theirs, ours)
+			return sig
+		}, false},
+		{"node ID only", func(string, string) string {
+			_, sig := client.sign(authContext, strconv.FormatUint(localNodeID, 10))
+			return sig
+		}, false},
+		{"relayed under another node ID", func(ours, theirs string) string {
+			_, sig := client.sign(authContext, strings.Replace(ours, strconv.FormatUint(node, 10), "7", 1), theirs)
+			return sig
+		}, false},
+		{"replayed from another connection", func(ours, _ string) string {
+			_, sig := client.sign(authContext, ours, stale)
+			return sig
+		}, false},
+		{"joined without separator", func(ours, theirs string) string {
+			_, sig := client.sign(authContext + ours + theirs)
+			return sig
+		}, false},
+		{"other key", func(ours, theirs string) string {
+			_, sig := server.sign(authContext, ours, theirs)
+			return sig
+		}, false},
+	} {
+		a, b := net.Pipe()
+		var claimed atomic.Bool
+		done := make(chan error, 1)
+		go func() {
+			_, err := handshake(a, &claimed, auth)
+			a.Close()
+			done <- err
+		}()
+		r := bufio.NewReader(b)
+		theirs, _ := r.ReadString('\n')
+		ours := fmt.Sprintf("HELLO %d %d test none %s %s", node, protocolVersion, client.publicKey(), newNonce())
+		fmt.Fprintf(b, "%s\n", ours)
+		_, _ = r.ReadString('\n') // The server's AUTH
+		fmt.Fprintf(b, "AUTH %s %s\n", c.sign(ours, strings.TrimRight(theirs, "\n")), noProof)
+		go io.Copy(io.Discard, r) // Drain the verdict | خالی‌کردن تصمیم
+		fmt.Fprint(b, "OK\n")
+		err := <-done
+		b.Close()
+		if c.ok && err != nil || !c.ok && !errors.Is(err, errBadAuth) {
+			t.Errorf("%s: handshake error %v, want accepted %v", c.name, err, c.ok)
+		}
+	}
+}
+
+// pipeVerdict runs the listener's handshake against a hand-made client over a pipe and returns the listener's verdict | اجرای handshake شنونده در برابر client دستی روی pipe و بازگرداندن تصمیم آن
+func pipeVerdict(t *testing.T, auth *peerAuth, client *identity, proof func(ours, theirs string) string) string {
+	t.Helper()
+	var claimed atomic.Bool
+	return pipeCandidate(t, auth, &claimed, client, "none", proof)
+}
+
+/*
+pipeCandidate is pipeVerdict with the client's capabilities and the
+listener's claimed flag given. proof returns everything in the client's
+AUTH after the signature. A client that resumes answers RESUME without
+a ticket of its own.
+*/
+func pipeCandidate(t *testing.T, auth *peerAuth, claimed *atomic.Bool, client *identity, caps string, proof func(ours, theirs string) string) string {
+	t.Helper()
+	a, b := net.Pipe()
+	defer b.Close()
+	done := make(chan struct{})
+	go func() {
+		_, _ = handshake(a, claimed, auth)
+		a.Close()
+		close(done)
+	}()
+	defer func() { <-done }() // The listener's handshake is over once we return | پایان handshake شنونده پیش از بازگشت
+	r := bufio.NewReader(b)
+	theirs, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading HELLO: %v", err)
+	}
+	theirs = strings.TrimRight(theirs, "\n")
+	ours := fmt.Sprintf("HELLO %d %d test %s %s %s", localNodeID+1, protocolVersion, caps, client.publicKey(), newNonce())
+	fmt.Fprintf(b, "%s\n", ours)
+	if _, err := r.ReadString('\n'); err != nil { // The listener's AUTH
+		t.Fatalf("reading AUTH: %v", err)
+	}
+	_, sig := client.sign(authContext, ours, theirs)
+	fmt.Fprintf(b, "AUTH %s %s\n", sig, proof(ours, theirs))
+	verdict, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading verdict: %v", err)
+	}
+	go io.Copy(io.Discard, r) // Drain the arbiter's verdict and ticket | خالی‌کردن تصمیم داور و ticket
+	fmt.Fprint(b, "OK\n")
+	if parseCapabilities(caps).Resume {
+		fmt.Fprint(b, "RESUME -\n") // Fails once the listener has dropped us | پس از کنار گذاشته شدن ناموفق است
+	}
+	return strings.TrimSpace(verdict)
+}
+
+func TestPasswordProofBoundToHandshake(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, _ := loadEntrySet("")
+	members, _ := loadEntrySet("")
+	auth, err := newPeerAuth(server, bans, members, accessPassword, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded string // A proof seen on an earlier connection | اثباتی که در اتصال قبلی دیده شد
+	for _, c := range []struct {
+		name  string
+		proof func(ours, theirs string) string
+		ok    bool
+	}{
+		{"over this handshake", func(ours, theirs string) string {
+			recorded = hex.EncodeToString(transcriptMAC("secret", proofPassword, [2]string{ours, theirs}))
+			return recorded
+		}, true},
+		{"replayed under fresh nonces", func(string, string) string { return recorded }, false},
+		{"swapped lines", func(ours, theirs string) string {
+			return hex.EncodeToString(transcriptMAC("secret", proofPassword, [2]string{theirs, ours}))
+		}, false},
+		{"over the listener's node ID only", func(string, string) string {
+			m := hmac.New(sha256.New, []byte("secret"))
+			m.Write([]byte(authContext + "\x00" + strconv.FormatUint(localNodeID, 10)))
+			return hex.EncodeToString(m.Sum(nil))
+		}, false},
+		{"wrong password", func(ours, theirs string) string {
+			return hex.EncodeToString(transcriptMAC("guess", proofPassword, [2]string{ours, theirs}))
+		}, false},
+	} {
+		if v := pipeVerdict(t, auth, client, c.proof); (v == "OK") != c.ok {
+			t.Errorf("%s: verdict %q, want admitted %v", c.name, v, c.ok)
+		}
+	}
+}
+
+func TestTokenProofBoundToHandshake(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, _ := loadEntrySet("")
+	members, _ := loadEntrySet("")
+	auth, err := newPeerAuth(server, bans, members, accessInvite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.tokens = &tokenStore{path: filepath.Join(t.TempDir(), "chat.tokens")}
+	tok, err := auth.tokens.mint(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := fmt.Sprintf("HELLO %d %d test none %s %s", localNodeID, protocolVersion, server.publicKey(), newNonce())
+	for _, c := range []struct {
+		name  string
+		proof func(ours, theirs string) string
+		ok    bool
+	}{
+		{"sniffed on another connection", func(ours, _ string) string {
+			return hex.EncodeToString(transcriptMAC(tok.Token, proofPassword, [2]string{ours, other}))
+		}, false},
+		{"over this handshake", func(ours, theirs string) string {
+			return hex.EncodeToString(transcriptMAC(tok.Token, proofPassword, [2]string{ours, theirs}))
+		}, true},
+		{"again once redeemed", func(ours, theirs string) string {
+			return hex.EncodeToString(transcriptMAC(tok.Token, proofPassword, [2]string{ours, theirs}))
+		}, false},
+	} {
+		if v := pipeVerdict(t, auth, client, c.proof); (v == "OK") != c.ok {
+			t.Errorf("%s: verdict %q, want admitted %v", c.name, v, c.ok)
+		}
+	}
+}
+
+func TestResumeTicketUsedOnKeptLinkOnly(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, _ := loadEntrySet("")
+	members, _ := loadEntrySet("")
+	auth, err := newPeerAuth(server, bans, members, accessPassword, "secret") // Only the ticket lets the client in | فقط ticket اجازه‌ی ورود می‌دهد
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.resume, err = loadResume(""); err != nil {
+		t.Fatal(err)
+	}
+	ticket := auth.resume.issue(client.fingerprint, auth.policy())
+	auth.resume.closed(client.fingerprint, 0, 0)
+	other := fmt.Sprintf("HELLO %d %d test none %s %s", localNodeID, protocolVersion, server.publicKey(), newNonce())
+	onThis := func(ours, theirs string) string {
+		return noProof + " " + hex.EncodeToString(transcriptMAC(ticket, proofTicket, [2]string{ours, theirs}))
+	}
+	for _, c := range []struct {
+		name    string
+		claimed bool   // The listener already kept a link | شنونده قبلاً اتصالی را نگه داشته
+		pin     string // Pinned key hash, if any | hash کلید سنجاق‌شده
+		proof   func(ours, theirs string) string
+		verdict string
+		kept    bool // The ticket is still there afterwards | ticket پس از آن باقی است
+	}{
+		{"replayed from another handshake", false, "", func(ours, _ string) string {
+			return noProof + " " + hex.EncodeToString(transcriptMAC(ticket, proofTicket, [2]string{ours, other}))
+		}, "DENIED wrong password", true},
+		{"refused by a pin", false, keyDigest(server.publicKey()), onThis, "DENIED not the expected key", true},
+		{"dropped by the arbiter", true, "", onThis, "OK", true},
+		{"kept", false, "", onThis, "OK", false},
+		{"again on the used ticket", false, "", onThis, "DENIED wrong password", false},
+	} {
+		var claimed atomic.Bool
+		claimed.Store(c.claimed)
+		auth.pin = c.pin
+		if v := pipeCandidate(t, auth, &claimed, client, "res=1", c.proof); v != c.verdict {
+			t.Errorf("%s: verdict %q, want %q", c.name, v, c.verdict)
+		}
+		auth.resume.mu.Lock()
+		kept := auth.resume.Issued[client.fingerprint].Ticket == ticket
+		auth.resume.mu.Unlock()
+		if kept != c.kept {
+			t.Errorf("%s: ticket still there %v, want %v", c.name, kept, c.kept)
+		}
+	}
+}
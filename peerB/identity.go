@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/ed25519"  // For signing and verifying messages
+	"crypto/rand"     // For generating a new identity key
+	"crypto/sha256"   // For key fingerprints
+	"encoding/base64" // For keys and signatures on the wire
+	"encoding/binary" // For the field lengths in signed payloads
+	"encoding/hex"    // For printable fingerprints
+	"errors"          // For identity error values
+	"os"              // For reading and writing the key file
+	"path/filepath"   // For the default key location
+)
+
+var errBadKeyFile = errors.New("identity key file is corrupt") // Key file has the wrong size | فایل کلید نامعتبر است
+
+/*
+identity is this peer's long-term Ed25519 key pair. Every outgoing
+message is signed with it so nobody relaying the text can forge it.
+
+این نوع جفت‌کلید Ed25519 بلندمدت این peer است؛ هر پیام خروجی با آن
+امضا می‌شود تا هیچ واسطه‌ای نتواند پیام جعلی از طرف ما بسازد
+*/
+type identity struct {
+	priv        ed25519.PrivateKey
+	pub         ed25519.PublicKey
+	fingerprint string // Short printable key ID | شناسه‌ی کوتاه کلید
+}
+
+/*
+dataPath returns the path of a per-user state file such as the
+identity key, inside the user's config directory.
+
+این تابع مسیر یک فایل وضعیت کاربر (مثل کلید هویت) را در پوشه‌ی
+تنظیمات کاربر برمی‌گرداند
+*/
+func dataPath(file string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "peerchat", file)
+}
+
+// defaultIdentityPath returns the per-name key file | مسیر پیش‌فرض فایل کلید برای هر نام
+func defaultIdentityPath(name string) string {
+	return dataPath(name + ".key")
+}
+
+/*
+loadIdentity reads the key seed stored at path, creating a new key
+(readable only by the current user) on first run.
+
+این تابع seed کلید را از path می‌خواند و در اولین اجرا یک کلید جدید
+(فقط قابل خواندن برای کاربر جاری) می‌سازد
+*/
+func loadIdentity(path string) (*identity, error) {
+	seed, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		seed = make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, seed, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	defer clear(seed) // Do not leave a copy of the seed behind | حذف کپی seed از حافظه
+	if len(seed) != ed25519.SeedSize {
+		return nil, errBadKeyFile
+	}
+	return newIdentity(ed25519.NewKeyFromSeed(seed)), nil
+}
+
+/*
+newEphemeralIdentity creates a throwaway key that is never written to
+disk, for anonymous sessions.
+
+این تابع یک کلید موقت می‌سازد که هرگز روی دیسک نوشته نمی‌شود
+(برای نشست‌های ناشناس)
+*/
+func newEphemeralIdentity() (*identity, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newIdentity(priv), nil
+}
+
+// newIdentity derives the public half and fingerprint | ساخت identity از کلید خصوصی
+func newIdentity(priv ed25519.PrivateKey) *identity {
+	pub := priv.Public().(ed25519.PublicKey)
+	return &identity{priv: priv, pub: pub, fingerprint: fingerprint(pub)}
+}
+
+// wipe zeroes the private key in memory | پاک کردن کلید خصوصی از حافظه
+func (id *identity) wipe() {
+	clear(id.priv)
+}
+
+// fingerprint returns a short hex ID for a public key | شناسه‌ی کوتاه hex برای کلید عمومی
+func fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// keyFingerprint returns the fingerprint of an announced base64 key, or "" | fingerprint کلید base64 اعلام‌شده یا ""
+func keyFingerprint(key string) string {
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ""
+	}
+	return fingerprint(pub)
+}
+
+// keyDigest returns the hex SHA-256 of an announced base64 key, or "" | hash کامل کلید base64 اعلام‌شده یا ""
+func keyDigest(key string) string {
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ""
+	}
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// publicKey returns our base64 public key | کلید عمومی ما به‌صورت base64
+func (id *identity) publicKey() string {
+	return base64.StdEncoding.EncodeToString(id.pub)
+}
+
+/*
+sign signs the given fields and returns the public key and signature,
+both base64 encoded. What is signed is signedPayload of the fields.
+
+این تابع فیلدهای داده‌شده را امضا می‌کند و کلید عمومی و امضا را به‌صورت
+base64 برمی‌گرداند؛ آنچه امضا می‌شود signedPayload فیلدهاست
+*/
+func (id *identity) sign(fields ...string) (key, sig string) {
+	return base64.StdEncoding.EncodeToString(id.pub), base64.StdEncoding.EncodeToString(ed25519.Sign(id.priv, signedPayload(fields)))
+}
+
+/*
+signedPayload puts each field after its length, so no byte inside a
+field can pass for a boundary: "a", "b\x00c" and "a\x00b", "c" sign
+differently, whatever the fields hold.
+
+این تابع هر فیلد را پس از طول آن می‌گذارد تا هیچ بایتی درون فیلد جای مرز
+را نگیرد: "a" و "b\x00c" با "a\x00b" و "c" امضای متفاوتی دارند، محتوای
+فیلدها هرچه باشد
+*/
+func signedPayload(fields []string) []byte {
+	var payload []byte
+	for _, f := range fields {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(f)))
+		payload = append(payload, f...)
+	}
+	return payload
+}
+
+/*
+verifySignature checks a signature produced by sign and returns the
+signer's fingerprint.
+
+این تابع امضای تولیدشده توسط sign را بررسی و fingerprint امضاکننده را برمی‌گرداند
+*/
+func verifySignature(key, sig string, fields ...string) (string, bool) {
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return "", false
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	if !ed25519.Verify(pub, signedPayload(fields), raw) {
+		return "", false
+	}
+	return fingerprint(pub), true
+}
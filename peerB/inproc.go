@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"  // For in-process error values
+	"net"     // For the pipe links
+	"strconv" // For generated addresses
+	"sync"    // For the listener table
+)
+
+var errInprocRefused = errors.New("no in-process listener at that address") // Nothing listening | listener وجود ندارد
+
+// inprocListeners are the listening in-process peers by address | peerهای درون‌برنامه در حال listen بر اساس آدرس
+var inprocListeners = struct {
+	sync.Mutex
+	next int
+	m    map[string]*inprocListener
+}{m: make(map[string]*inprocListener)}
+
+/*
+inprocTransport links peers that run in the same process, such as a
+local bot embedded next to the chat, or the two ends of bench and soak.
+Addresses are plain names in a table of this process; a dial hands the
+listener one end of a net.Pipe and keeps the other, so nothing touches
+the network.
+
+این نوع peerهایی را که در یک برنامه اجرا می‌شوند، مانند یک bot محلی کنار
+چت یا دو سر bench و soak، به هم وصل می‌کند؛ آدرس‌ها نام‌هایی در جدول همین
+برنامه هستند و dial یک سر net.Pipe را به listener می‌دهد و سر دیگر را نگه
+می‌دارد، پس چیزی به شبکه نمی‌رسد
+*/
+type inprocTransport struct{}
+
+func (inprocTransport) dial(remote string) (net.Conn, error) {
+	inprocListeners.Lock()
+	l := inprocListeners.m[remote]
+	inprocListeners.Unlock()
+	if l == nil {
+		return nil, &net.OpError{Op: "dial", Net: "inproc", Addr: inprocAddr(remote), Err: errInprocRefused}
+	}
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		_ = client.Close()
+		_ = server.Close()
+		return nil, &net.OpError{Op: "dial", Net: "inproc", Addr: inprocAddr(remote), Err: errInprocRefused}
+	}
+}
+
+// listen registers addr, or a fresh name when addr is empty | ثبت addr یا نامی تازه وقتی addr خالی است
+func (inprocTransport) listen(addr string) (net.Listener, error) {
+	inprocListeners.Lock()
+	defer inprocListeners.Unlock()
+	if addr == "" {
+		for addr == "" || inprocListeners.m[addr] != nil { // Skip names a caller chose itself | رد شدن از نام‌هایی که خود فراخواننده انتخاب کرده
+			inprocListeners.next++
+			addr = "inproc-" + strconv.Itoa(inprocListeners.next)
+		}
+	}
+	if inprocListeners.m[addr] != nil {
+		return nil, &net.OpError{Op: "listen", Net: "inproc", Addr: inprocAddr(addr), Err: errors.New("address already in use")}
+	}
+	l := &inprocListener{addr: inprocAddr(addr), conns: make(chan net.Conn), closed: make(chan struct{})}
+	inprocListeners.m[addr] = l
+	return l, nil
+}
+
+// inprocListener hands dialed pipes to Accept | تحویل pipeهای dial‌شده به Accept
+type inprocListener struct {
+	addr   inprocAddr
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (l *inprocListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close frees the address; waiting dials fail | آزادسازی آدرس؛ dialهای منتظر شکست می‌خورند
+func (l *inprocListener) Close() error {
+	l.once.Do(func() {
+		inprocListeners.Lock()
+		delete(inprocListeners.m, string(l.addr))
+		inprocListeners.Unlock()
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *inprocListener) Addr() net.Addr {
+	return l.addr
+}
+
+// inprocAddr is the name of an in-process listener | نام یک listener درون‌برنامه
+type inprocAddr string
+
+func (inprocAddr) Network() string  { return "inproc" }
+func (a inprocAddr) String() string { return string(a) }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "ann.input_history")
+	h, err := loadInputHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one", "  ", " secret", "two", "two", "/nick ann", "three"} {
+		h.Add(line)
+	}
+	if h.Len() != 3 || h.At(0) != "three" || h.At(2) != "two" || h.At(3) != "" || h.At(-1) != "" {
+		t.Errorf("recalled %d lines, newest %q, oldest %q", h.Len(), h.At(0), h.At(2))
+	}
+
+	h, err = loadInputHistory(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 2 || h.At(0) != "three" || h.At(1) != "/nick ann" {
+		t.Errorf("reloaded %d lines: %q, %q; want the last two", h.Len(), h.At(0), h.At(1))
+	}
+
+	off, err := loadInputHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off.Add("four")
+	if off.Len() != 0 {
+		t.Errorf("input-history 0 recalled %d lines", off.Len())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two\n/nick ann\nthree\n" {
+		t.Errorf("file changed while off: %q", data)
+	}
+}
+
+func TestInputHistoryCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	path := filepath.Join(t.TempDir(), "ann.input_history")
+	inputs, err := loadInputHistory(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{inputs: inputs}
+
+	inputs.Add("kept")
+	runCommand(s, "/inputhistory off")
+	inputs.Add("not kept")
+	runCommand(s, "/inputhistory")
+	runCommand(s, "/inputhistory on")
+	inputs.Add("kept again")
+	runCommand(s, "/inputhistory clear")
+	runCommand(s, "/inputhistory")
+	want := "Input history paused\n" +
+		"Input history: paused, 1 of 10 lines\n" +
+		"Input history resumed\n" +
+		"Input history cleared\n" +
+		"Input history: on, 0 of 10 lines\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file kept after clear: %v", err)
+	}
+}
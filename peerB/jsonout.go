@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json" // For the event lines
+	"errors"        // For the format error
+	"os"            // For stdout
+	"time"          // For event times
+)
+
+/*
+Output formats: terminal text, or one JSON event per line for scripts
+
+قالب‌های خروجی: متن ترمینال، یا یک رویداد JSON در هر خط برای اسکریپت‌ها
+*/
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+/*
+Event kinds written with -output json
+
+انواع رویدادهای نوشته‌شده با -output json:
+- connected و disconnected برای برقراری و قطع اتصال
+- message برای هر پیام دریافتی
+- transfer برای هر فایلی که کامل ارسال یا دریافت شد
+- error برای هر panic بازیابی‌شده
+*/
+const (
+	eventConnected    = "connected"
+	eventDisconnected = "disconnected"
+	eventMessage      = "message"
+	eventTransfer     = "transfer"
+	eventError        = "error"
+)
+
+var errOutputFormat = errors.New(`output must be "text" or "json"`) // Unknown -output | قالب نامعتبر
+
+// jsonOutput is set from -output before any event happens | پیش از هر رویدادی از -output تنظیم می‌شود
+var (
+	jsonOutput bool
+	eventOut   *os.File // The real stdout, events only | stdout واقعی، فقط رویدادها
+)
+
+/*
+outputEvent is one line of the JSON output. Only the fields of its kind
+are present; message carries the same object as the pipe mode output.
+
+این نوع یک خط از خروجی JSON است؛ فقط فیلدهای مربوط به نوع آن حاضرند و
+message همان شیء خروجی حالت pipe را دارد
+*/
+type outputEvent struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Remote    string    `json:"remote,omitempty"`    // Link address | آدرس اتصال
+	Key       string    `json:"key,omitempty"`       // Remote key fingerprint | fingerprint کلید طرف مقابل
+	Message   *message  `json:"message,omitempty"`   // Received message | پیام دریافتی
+	Direction string    `json:"direction,omitempty"` // "send" or "recv" | جهت انتقال
+	File      string    `json:"file,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"`
+	Path      string    `json:"path,omitempty"`      // Where a received file was stored | محل ذخیره‌ی فایل دریافتی
+	Goroutine string    `json:"goroutine,omitempty"` // Where a panic was recovered | محل بازیابی panic
+	Error     string    `json:"error,omitempty"`     // The panic value | مقدار panic
+	Dump      string    `json:"dump,omitempty"`      // Crash dump of the panic | گزارش خرابی panic
+}
+
+/*
+setOutput checks the -output format. For JSON it keeps the real stdout
+for events and redirects stdout to stderr, so command output and
+notices, printed to stdout all over, never get between the events.
+
+این تابع قالب -output را بررسی می‌کند. برای JSON، stdout واقعی را برای
+رویدادها نگه می‌دارد و stdout را به stderr هدایت می‌کند تا خروجی دستورها و
+اعلان‌ها که همه‌جا روی stdout چاپ می‌شوند میان رویدادها نیایند
+*/
+func setOutput(format string) error {
+	switch format {
+	case outputText:
+	case outputJSON:
+		jsonOutput, eventOut = true, os.Stdout
+		stdout.redirect(os.Stderr)
+	default:
+		return errOutputFormat
+	}
+	return nil
+}
+
+// emitEvent writes e to stdout as one JSON line, when JSON output is on | نوشتن e به‌صورت یک خط JSON در صورت روشن بودن خروجی JSON
+func emitEvent(e outputEvent) {
+	if !jsonOutput {
+		return
+	}
+	e.Time = time.Now()
+	_ = json.NewEncoder(eventOut).Encode(e)
+}
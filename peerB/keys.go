@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"      // For key binding error values
+	"fmt"         // For naming bad bindings
+	"sort"        // For a stable /keys listing
+	"strings"     // For parsing key names
+	"sync/atomic" // For the session of the current link
+)
+
+/*
+Key binding actions
+
+کارهای قابل‌انتساب به کلید:
+- send: ارسال خط فعلی مانند Enter
+- newline: رفتن به خط بعد در همان پیام
+- dnd: روشن و خاموش کردن «مزاحم نشوید»
+- هر مقداری که با / شروع شود آن دستور را اجرا می‌کند
+*/
+const (
+	keySend    = "send"
+	keyNewline = "newline"
+	keyDND     = "dnd"
+)
+
+/*
+defaultKeys are bound unless the config's "keys" object rebinds them
+(or unbinds them with "").
+
+این کلیدها متصل هستند مگر آنکه شیء "keys" پیکربندی آن‌ها را تغییر دهد
+(یا با "" آزاد کند)
+*/
+var defaultKeys = map[string]string{
+	"ctrl-j": keyNewline,
+	"ctrl-t": keyDND,
+}
+
+var (
+	errKeyName     = errors.New(`keys are named "ctrl-" and a letter`)                     // Unparsable name | نام نامعتبر
+	errKeyReserved = errors.New("the line editor uses this key")                           // Built-in editing key | کلید ویرایشگر
+	errKeyAction   = errors.New(`actions are "send", "newline", "dnd" or a /command line`) // Unknown action | کار ناشناخته
+)
+
+/*
+editorReserved are the control keys the line editor handles itself:
+movement, deletion, history, Tab, Enter and quitting.
+
+این‌ها کلیدهای کنترلی هستند که خود ویرایشگر خط استفاده می‌کند: حرکت،
+حذف، تاریخچه، Tab، Enter و خروج
+*/
+const editorReserved = "abcdefhiklmnpuw"
+
+func init() {
+	registerCommand("keys", "/keys  list the key bindings", keysCommand)
+}
+
+// keyBindings maps control keys to actions | نگاشت کلیدهای کنترلی به کارها
+type keyBindings map[rune]string
+
+/*
+parseKeys merges the user's bindings over defaultKeys. Names are
+"ctrl-" and a letter not taken by the editor.
+
+این تابع کلیدهای کاربر را روی defaultKeys اعمال می‌کند؛ نام‌ها "ctrl-" و
+حرفی هستند که ویرایشگر از آن استفاده نمی‌کند
+*/
+func parseKeys(user map[string]string) (keyBindings, error) {
+	merged := make(map[string]string, len(defaultKeys)+len(user))
+	for name, action := range defaultKeys {
+		merged[name] = action
+	}
+	for name, action := range user {
+		merged[strings.ToLower(name)] = strings.TrimSpace(action)
+	}
+	kb := make(keyBindings, len(merged))
+	for name, action := range merged {
+		letter, ok := strings.CutPrefix(name, "ctrl-")
+		if !ok || len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' {
+			return nil, fmt.Errorf("%s: %w", name, errKeyName)
+		}
+		if strings.Contains(editorReserved, letter) {
+			return nil, fmt.Errorf("%s: %w", name, errKeyReserved)
+		}
+		switch {
+		case action == "":
+			continue // Unbound | آزاد
+		case action == keySend || action == keyNewline || action == keyDND || strings.HasPrefix(action, "/"):
+		default:
+			return nil, fmt.Errorf("%s: %w", name, errKeyAction)
+		}
+		kb[rune(letter[0]-'a'+1)] = action
+	}
+	return kb, nil
+}
+
+/*
+editorKeys returns the line editor's key callback: Tab completes and
+bound keys run their action on the line being typed, both on the
+session of the current link.
+
+این تابع callback کلیدهای ویرایشگر خط را برمی‌گرداند: Tab تکمیل می‌کند
+و کلیدهای متصل کار خود را روی خط در حال تایپ انجام می‌دهند؛ هر دو روی نشست
+اتصال فعلی
+*/
+func editorKeys(live *atomic.Pointer[session], kb keyBindings) func(line string, pos int, key rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		s := live.Load()
+		action, ok := kb[key]
+		if !ok {
+			return tabCompleter(s)(line, pos, key)
+		}
+		switch action {
+		case keySend, keyNewline:
+			th := s.theme.current()
+			fmt.Fprintln(stdout, paint(th.Status, consolePrompt)+paint(th.Own, line)) // Left above the prompt as Enter would | مانند Enter بالای خط ورودی می‌ماند
+		}
+		switch action {
+		case keySend:
+			handleInput(s, line)
+			return "", 0, true
+		case keyNewline:
+			s.compose.paste(s, line) // Like a pasted line: Enter sends the block | مانند خط چسبانده‌شده: Enter کل متن را ارسال می‌کند
+			return "", 0, true
+		case keyDND:
+			if !s.notify.stopDND(0) {
+				runCommand(s, "/dnd")
+			}
+		default:
+			runCommand(s, action)
+		}
+		return line, pos, true // The typed line stays | خط تایپ‌شده باقی می‌ماند
+	}
+}
+
+// keysCommand lists the bindings | فهرست کلیدهای میانبر
+func keysCommand(s *session, args []string) {
+	names := make([]string, 0, len(s.keymap))
+	for key, action := range s.keymap {
+		names = append(names, fmt.Sprintf("  ctrl-%c  %s", 'a'+key-1, action))
+	}
+	sort.Strings(names)
+	for _, line := range names {
+		fmt.Fprintln(stdout, line)
+	}
+}
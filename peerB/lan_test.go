@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLanReplayRefused(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := loadRegistry("")
+	now := time.Now()
+	l := &lanChat{seen: make(map[string]time.Time)}
+	for _, c := range []struct {
+		name string
+		sent time.Time
+		ok   bool
+	}{
+		{"fresh", now, true},
+		{"slow clock", now.Add(-lanDedupWindow / 2), true},
+		{"replayed", now.Add(-lanDedupWindow), false},
+		{"future", now.Add(lanDedupWindow), false},
+	} {
+		m, ok := decodeChatLine(encodeChat(id, message{Time: c.sent, From: "ann", Text: "hi", ID: c.name}), keys)
+		if !ok || !m.Verified || !m.Sent.Equal(c.sent) {
+			t.Fatalf("%s: ok %v, verified %v, sent %v; want signed time %v", c.name, ok, m.Verified, m.Sent, c.sent)
+		}
+		if got := !stale(m, now) && l.remember(m); got != c.ok {
+			t.Errorf("%s: shown %v, want %v", c.name, got, c.ok)
+		}
+		if c.ok && l.remember(m) {
+			t.Errorf("%s: shown twice", c.name)
+		}
+	}
+	if m, _ := decodeChatLine("ann: hi", keys); stale(m, now) {
+		t.Error("unsigned legacy line refused as stale")
+	}
+}
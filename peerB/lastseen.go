@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"        // For a missing file on first run
+	"fmt"           // For formatting ages
+	"io"            // For the status writer
+	"os"            // For reading and writing the file
+	"path/filepath" // For creating the data directory
+	"sort"          // For newest-first listings
+	"strconv"       // For the stored timestamps
+	"strings"       // For parsing the file
+	"sync"          // For updates from several goroutines
+	"time"          // For timestamps and ages
+)
+
+const lastSeenBanner = 3 // Peers listed at startup | تعداد peerهای نمایش‌داده‌شده هنگام شروع
+
+/*
+lastSeen records when each nick was last connected or last sent a
+message, persisted as "nick unix-seconds" lines. Updates from messages
+stay in memory until the next login or disconnect writes the file.
+
+این نوع زمان آخرین اتصال یا آخرین پیام هر نام را نگه می‌دارد و به‌صورت
+خطوط "nick unix-seconds" ذخیره می‌کند؛ به‌روزرسانی‌های ناشی از پیام تا
+ورود یا قطع اتصال بعدی فقط در حافظه می‌مانند
+*/
+type lastSeen struct {
+	mu     sync.Mutex
+	path   string
+	byNick map[string]time.Time
+}
+
+// defaultLastSeenPath returns the per-name last-seen file | مسیر پیش‌فرض فایل آخرین بازدید
+func defaultLastSeenPath(name string) string {
+	return dataPath(name + ".last_seen")
+}
+
+/*
+loadLastSeen reads the last-seen file; a missing file is empty and an
+empty path keeps everything in memory.
+
+این تابع فایل آخرین بازدید را می‌خواند؛ نبود فایل یعنی خالی و
+path خالی یعنی نگهداری فقط در حافظه
+*/
+func loadLastSeen(path string) (*lastSeen, error) {
+	l := &lastSeen{path: path, byNick: make(map[string]time.Time)}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		nick, ts, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if n, err := strconv.ParseInt(ts, 10, 64); ok && err == nil {
+			l.byNick[nick] = time.Unix(n, 0)
+		}
+	}
+	return l, nil
+}
+
+// get returns when nick was last seen | زمان آخرین بازدید nick
+func (l *lastSeen) get(nick string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, ok := l.byNick[nick]
+	return t, ok
+}
+
+// touch marks nick as seen now; persist also writes the file | ثبت بازدید اکنون؛ با persist در فایل هم ذخیره می‌شود
+func (l *lastSeen) touch(nick string, persist bool) {
+	if nick == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byNick[nick] = time.Now()
+	if persist {
+		if err := l.save(); err != nil {
+			fmt.Fprintln(stdout, "Last seen error:", err)
+		}
+	}
+}
+
+// recent lists nicks newest first | فهرست نام‌ها از جدیدترین
+func (l *lastSeen) recent() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	nicks := make([]string, 0, len(l.byNick))
+	for nick := range l.byNick {
+		nicks = append(nicks, nick)
+	}
+	sort.Slice(nicks, func(i, j int) bool { return l.byNick[nicks[i]].After(l.byNick[nicks[j]]) })
+	return nicks
+}
+
+// save writes the file; the caller holds mu | ذخیره فایل (mu باید گرفته شده باشد)
+func (l *lastSeen) save() error {
+	if l.path == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for nick, t := range l.byNick {
+		b.WriteString(nick + " " + strconv.FormatInt(t.Unix(), 10) + "\n")
+	}
+	return os.WriteFile(l.path, []byte(b.String()), 0o600)
+}
+
+// describe renders "last seen 2h ago" for nick, or "never seen" | متن آخرین بازدید nick
+func (l *lastSeen) describe(nick string) string {
+	t, ok := l.get(nick)
+	if !ok {
+		return "never seen"
+	}
+	return "last seen " + formatAgo(time.Since(t))
+}
+
+// formatAgo renders a coarse age such as "5m ago" | نمایش تقریبی گذشت زمان
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+/*
+printLastSeen lists the most recently seen peers while we try to
+connect, so it is clear who is likely to be around.
+
+این تابع هنگام تلاش برای اتصال peerهایی را که اخیراً دیده شده‌اند
+فهرست می‌کند تا مشخص باشد چه کسی احتمالاً در دسترس است
+*/
+func printLastSeen(w io.Writer, l *lastSeen) {
+	for i, nick := range l.recent() {
+		if i == lastSeenBanner {
+			break
+		}
+		fmt.Fprintf(w, "Known peer  : %s (%s)\n", nick, l.describe(nick))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLastSeenPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ann.last_seen")
+	old := time.Now().Add(-3 * time.Hour).Unix()
+	if err := os.WriteFile(path, []byte("carol "+strconv.FormatInt(old, 10)+"\ngarbage\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	seen, err := loadLastSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seen.describe("carol"); got != "last seen 3h ago" {
+		t.Errorf("carol: %q", got)
+	}
+	seen.touch("bob", false) // From a message, kept in memory | از پیام، فقط در حافظه
+	if again, _ := loadLastSeen(path); again.describe("bob") != "never seen" {
+		t.Error("a message wrote the file")
+	}
+	seen.touch("dave", true) // From a login, written at once | از ورود، فوراً ذخیره
+	again, err := loadLastSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.recent(); len(got) != 3 || got[2] != "carol" {
+		t.Errorf("after a restart: %v, want bob and dave before carol", got)
+	}
+	if got := again.describe("dave"); got != "last seen just now" {
+		t.Errorf("dave: %q", got)
+	}
+
+	var buf bytes.Buffer
+	for _, nick := range []string{"e", "f"} {
+		again.touch(nick, false)
+	}
+	printLastSeen(&buf, again)
+	if got := bytes.Count(buf.Bytes(), []byte("Known peer")); got != lastSeenBanner {
+		t.Errorf("startup lists %d peers, want %d:\n%s", got, lastSeenBanner, buf.String())
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second: "just now",
+		5 * time.Minute:  "5m ago",
+		47 * time.Hour:   "47h ago",
+		72 * time.Hour:   "3d ago",
+	} {
+		if got := formatAgo(d); got != want {
+			t.Errorf("formatAgo(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
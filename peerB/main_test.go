@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeCounter counts the writes that reach a connection | شمارش نوشتن‌ها روی اتصال
+type writeCounter struct {
+	net.Conn
+	writes atomic.Int64
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes.Add(1)
+	return w.Conn.Write(p)
+}
+
+func TestConnWriterCoalesces(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	conn := &writeCounter{Conn: local}
+	lines := make(chan string, 200)
+	go func() {
+		sc := bufio.NewScanner(remote)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+
+	outgoing := make(chan string, 200)
+	for i := 0; i < 100; i++ {
+		outgoing <- fmt.Sprintf(`{"text":"burst %d"}`, i) // Queued before the writer starts | در صف پیش از شروع نویسنده
+	}
+	var sent, taken atomic.Int64
+	var frames atomic.Uint64
+	done := newDoneSignal()
+	defer done.close()
+	go connWriter(conn, outgoing, &sent, &taken, &frames, nil, done)
+
+	for i := 0; i < 100; i++ {
+		want := fmt.Sprintf(`{"seq":%d,"text":"burst %d"}`, i+1, i)
+		if got := <-lines; got != want {
+			t.Fatalf("line %d: %s, want %s", i, got, want)
+		}
+	}
+	if n := conn.writes.Load(); n > 2 {
+		t.Errorf("a queued burst of 100 lines took %d writes", n)
+	}
+
+	before := conn.writes.Load()
+	outgoing <- `{"text":"typed"}`
+	select {
+	case got := <-lines:
+		if got != `{"seq":101,"text":"typed"}` {
+			t.Errorf("lone line: %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a lone line waited for more")
+	}
+	if n := conn.writes.Load() - before; n != 1 {
+		t.Errorf("a lone line took %d writes", n)
+	}
+	for deadline := time.Now().Add(time.Second); sent.Load() != 101 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if sent.Load() != 101 || taken.Load() != 101 {
+		t.Errorf("sent %d, taken %d; want 101 each", sent.Load(), taken.Load())
+	}
+}
+
+func TestConnReaderGaps(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, remote := net.Pipe()
+	defer local.Close()
+	keys, _ := loadRegistry("")
+	stats := newMetrics()
+	var seen atomic.Uint64
+	seen.Store(4) // Resumed link: frames up to 4 arrived before | اتصال ازسرگرفته: تا فریم ۴ قبلاً رسیده
+	incoming := make(chan message, 10)
+	done := newDoneSignal()
+	go connReader(local, incoming, keys, stats, &seen, done)
+
+	go func() {
+		for _, seq := range []uint64{5, 8, 9, 9, 12} {
+			line := withSeq(encodeChat(id, message{Time: time.Now(), From: "ann", Text: fmt.Sprint("frame ", seq), ID: newMessageID()}), seq)
+			fmt.Fprintln(remote, line)
+		}
+		remote.Close()
+	}()
+	var lost []uint64
+	for m := range incoming {
+		lost = append(lost, m.Lost)
+		if len(lost) == 5 {
+			break
+		}
+	}
+	<-done.c
+	if want := []uint64{0, 2, 0, 0, 2}; fmt.Sprint(lost) != fmt.Sprint(want) {
+		t.Errorf("lost before each frame %v, want %v", lost, want)
+	}
+	if got := stats.lost.Load(); got != 4 {
+		t.Errorf("lost counter %d, want 4", got)
+	}
+	if got := seen.Load(); got != 12 {
+		t.Errorf("last frame seen %d, want 12 for the next resume", got)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/rand"     // For message IDs
+	"encoding/base64" // For signing inline images
+	"encoding/hex"    // For printable message IDs
+	"encoding/json"   // For the signed chat envelope
+	"errors"          // For send error values
+	"strconv"         // For the signed timestamp field
+	"strings"         // For splitting legacy "name: text" chat lines
+	"time"            // For timestamps
+)
+
+/*
+message is one chat message received from the remote peer,
+kept structured until the display loop decides how to render it.
+
+هر message یک پیام چت دریافتی از peer مقابل است که تا رسیدن به
+حلقه نمایش به‌صورت ساختاریافته نگه داشته می‌شود
+*/
+type message struct {
+	Time     time.Time `json:"time"`             // When it was received | زمان دریافت
+	From     string    `json:"from"`             // Sender name | نام فرستنده
+	Text     string    `json:"text"`             // Message body | متن پیام
+	ID       string    `json:"id,omitempty"`     // Sender-chosen message ID | شناسه‌ی پیام
+	Parent   string    `json:"parent,omitempty"` // ID of the message this replies to | شناسه‌ی پیام والد
+	Quote    string    `json:"quote,omitempty"`  // Excerpt of the parent | بخشی از متن پیام والد
+	Auto     bool      `json:"auto,omitempty"`   // Sent by an auto-reply | ارسال‌شده توسط پاسخ خودکار
+	Part     string    `json:"part,omitempty"`   // start, more or end of a streamed message | بخش پیام جریانی
+	Image    []byte    `json:"image,omitempty"`  // Inline image, Text is its name | تصویر داخل پیام، Text نام آن است
+	MIME     string    `json:"mime,omitempty"`   // Type of Image | نوع تصویر
+	Code     string    `json:"code,omitempty"`   // Language of a code snippet in Text | زبان قطعه کد داخل Text
+	Key      string    `json:"key,omitempty"`    // Sender key fingerprint | fingerprint کلید فرستنده
+	Verified bool      `json:"verified"`         // Signature checked out | امضا معتبر است
+	Seq      uint64    `json:"-"`                // Frame number on the link, 0 from older peers | شماره‌ی فریم روی اتصال
+	Lost     uint64    `json:"-"`                // Frames missing just before this one | فریم‌های گم‌شده پیش از این پیام
+	Cover    bool      `json:"-"`                // Cover traffic, dropped on arrival | ترافیک پوششی که هنگام دریافت دور ریخته می‌شود
+	Sent     time.Time `json:"-"`                // Signed sender clock, zero unless Verified | زمان امضاشده‌ی فرستنده، صفر مگر Verified
+}
+
+/*
+chatEnvelope is one line on the chat stream: the text, its ID and
+optional parent with a quoted excerpt of it, plus the sender's public
+key and an Ed25519 signature over all of those fields.
+
+هر chatEnvelope یک خط روی stream چت است: متن پیام، شناسه و والد
+اختیاری آن همراه با بخشی از متن والد، به همراه کلید عمومی فرستنده و
+امضای Ed25519 روی همه‌ی این فیلدها
+*/
+type chatEnvelope struct {
+	From   string `json:"from"`             // Sender name | نام فرستنده
+	Text   string `json:"text"`             // Message body | متن پیام
+	Time   int64  `json:"time"`             // Sender clock in unix nanoseconds | زمان فرستنده
+	ID     string `json:"id"`               // Message ID | شناسه‌ی پیام
+	Parent string `json:"parent,omitempty"` // Replied-to message ID | شناسه‌ی پیام والد
+	Quote  string `json:"quote,omitempty"`  // Excerpt of the parent | بخشی از متن پیام والد
+	Auto   bool   `json:"auto,omitempty"`   // Auto-reply, never answered automatically | پاسخ خودکار
+	Part   string `json:"part,omitempty"`   // Streamed message part | بخش پیام جریانی
+	Image  []byte `json:"image,omitempty"`  // Inline image, base64 on the wire | تصویر داخل پیام
+	MIME   string `json:"mime,omitempty"`   // Type of Image | نوع تصویر
+	Code   string `json:"code,omitempty"`   // Snippet language | زبان قطعه کد
+	Key    string `json:"key"`              // Base64 public key | کلید عمومی
+	Sig    string `json:"sig"`              // Base64 signature | امضا
+	Seq    uint64 `json:"seq,omitempty"`    // Per-link frame number, added by the writer and not signed | شماره‌ی فریم، بدون امضا
+	Cover  bool   `json:"cover,omitempty"`  // Cover line from a shaping writer | خط پوششی نویسنده‌ی شکل‌دهنده
+}
+
+const seqOverhead = len(`"seq":18446744073709551615,`) // Longest sequence field withSeq adds | طولانی‌ترین فیلد شماره
+
+/*
+withSeq stamps the per-link frame number onto an encoded chat line. It
+is added by the writer, in wire order, and left out of the signature
+so older peers still verify the message.
+
+این تابع شماره‌ی فریم روی اتصال را به خط چت رمزشده اضافه می‌کند؛ نویسنده
+آن را به ترتیب ارسال می‌افزاید و خارج از امضا است تا peerهای قدیمی همچنان
+پیام را تأیید کنند
+*/
+func withSeq(line string, seq uint64) string {
+	if !strings.HasPrefix(line, "{") {
+		return line // Legacy plain line | خط ساده‌ی قدیمی
+	}
+	return `{"seq":` + strconv.FormatUint(seq, 10) + "," + line[1:]
+}
+
+// signedFields lists the envelope fields covered by the signature | فیلدهای امضاشده‌ی پاکت
+func (e chatEnvelope) signedFields() []string {
+	fields := []string{e.From, strconv.FormatInt(e.Time, 10), e.Text, e.ID, e.Parent, e.Quote, strconv.FormatBool(e.Auto)}
+	if e.Part != "" {
+		fields = append(fields, e.Part) // Whole messages sign as before | پیام‌های کامل مانند قبل امضا می‌شوند
+	}
+	if len(e.Image) > 0 {
+		fields = append(fields, e.MIME, base64.StdEncoding.EncodeToString(e.Image))
+	}
+	if e.Code != "" {
+		fields = append(fields, "code:"+e.Code)
+	}
+	return fields
+}
+
+// newMessageID returns a short random message ID | ساخت شناسه‌ی کوتاه تصادفی برای پیام
+func newMessageID() string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+/*
+encodeChat signs a chat message and returns its wire line (without
+the trailing newline).
+
+این تابع پیام چت را امضا می‌کند و خط ارسالی آن را
+(بدون newline انتهایی) برمی‌گرداند
+*/
+func encodeChat(id *identity, m message) string {
+	e := chatEnvelope{From: m.From, Text: m.Text, Time: m.Time.UnixNano(), ID: m.ID, Parent: m.Parent, Quote: m.Quote, Auto: m.Auto, Part: m.Part, Image: m.Image, MIME: m.MIME, Code: m.Code}
+	e.Key, e.Sig = id.sign(e.signedFields()...)
+	data, _ := json.Marshal(e) // Cannot fail for plain strings | برای رشته‌ها خطا نمی‌دهد
+	return string(data)
+}
+
+/*
+decodeChatLine parses a chat stream line and verifies its signature.
+A validly signed message under a nick registered to another key is
+impersonation and is rejected (ok is false). Verified messages carry
+the signed sender clock in Sent. Plain "name: text" lines from older
+peers are accepted but never verified.
+
+این تابع یک خط stream چت را تجزیه و امضای آن را بررسی می‌کند؛
+پیام امضاشده با نامی که برای کلید دیگری ثبت شده جعل هویت است و رد
+می‌شود (ok برابر false). پیام تأییدشده زمان امضاشده‌ی فرستنده را در Sent
+دارد. خطوط ساده‌ی peerهای قدیمی پذیرفته ولی تأیید نمی‌شوند
+*/
+func decodeChatLine(line string, keys *registry) (m message, ok bool) {
+	var e chatEnvelope
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil {
+		return parseChatLine(line), true
+	}
+	m = message{Time: time.Now(), From: e.From, Text: e.Text, ID: e.ID, Parent: e.Parent, Quote: e.Quote, Auto: e.Auto, Part: e.Part, Image: e.Image, MIME: e.MIME, Code: e.Code, Seq: e.Seq, Cover: e.Cover}
+	if fp, valid := verifySignature(e.Key, e.Sig, e.signedFields()...); valid {
+		if !keys.check(e.From, fp) {
+			return m, false
+		}
+		m.Key, m.Verified, m.Sent = fp, true, time.Unix(0, e.Time)
+	}
+	return m, true
+}
+
+/*
+parseChatLine splits a legacy wire line of the form "name: text".
+Lines without a name are attributed to nobody.
+
+این تابع خط قدیمی به شکل "name: text" را جدا می‌کند؛
+خطوط بدون نام، فرستنده‌ی خالی دارند
+*/
+func parseChatLine(line string) message {
+	m := message{Time: time.Now(), Text: line}
+	if from, text, ok := strings.Cut(line, ": "); ok {
+		m.From, m.Text = from, text
+	}
+	return m
+}
+
+/*
+displayMessage renders a message for the terminal. Parts of a streamed
+message after the first are shown as bare text, and only the last one
+carries the ID. Code snippets follow their header line highlighted.
+
+این تابع پیام را برای ترمینال نمایش می‌دهد؛ بخش‌های بعدی پیام جریانی
+فقط متن خود را نشان می‌دهند و شناسه فقط همراه بخش آخر می‌آید؛ قطعه کد
+به‌صورت رنگی زیر خط عنوان خود می‌آید
+*/
+func displayMessage(m message) string {
+	text := strings.TrimSuffix(m.Text, "\n") // Parts end where a line did | بخش‌ها در انتهای خط تمام می‌شوند
+	switch m.Part {
+	case partStart:
+		m.ID = ""
+	case partMore:
+		return text
+	case partEnd:
+		return text + "  #" + m.ID
+	}
+	m.Text = text
+	if m.Code != "" {
+		m.Text = "[" + stripControl(m.Code) + " code]" // The language is the sender's choice | زبان انتخاب فرستنده است
+	}
+	from := m.From
+	if !m.Verified {
+		from = strings.TrimSpace(from + " [unverified]") // Signature missing or wrong | امضا ندارد یا نامعتبر است
+	}
+	if m.Auto {
+		from += " [auto-reply]" // Not typed by a person | توسط کاربر تایپ نشده
+	}
+	line := "RECV -> " + from + ": " + m.Text
+	if from == "" {
+		line = "RECV -> " + m.Text
+	}
+	if m.ID != "" {
+		line += "  #" + m.ID // Handle for /reply | شناسه برای /reply
+	}
+	if m.Code != "" {
+		line += "\n" + renderCode(m.Code, text)
+	}
+	return line
+}
+
+var (
+	errBlocked = errors.New("message blocked by the word filter") // Outbound filter dropped it | فیلتر خروجی آن را حذف کرد
+	errTooLong = errors.New("message too long")                   // Over the negotiated limit | بیش از حد توافق‌شده
+	errClosed  = errors.New("connection closed")                  // Session ended while sending | نشست هنگام ارسال بسته شد
+)
+
+/*
+sendChat filters, signs and queues one of our messages (streamed in
+parts when it is too long for one line and the remote supports it),
+optionally as a reply to parent (which then travels quoted) and marked as an
+auto-reply when auto is set, and records it in the history and thread
+index.
+
+این تابع یکی از پیام‌های ما را (اگر برای یک خط بیش از حد طولانی باشد و طرف مقابل
+پشتیبانی کند، به‌صورت جریانی و بخش‌بخش؛ و در صورت نیاز به‌عنوان پاسخ به parent که
+نقل‌قول آن هم ارسال می‌شود) فیلتر، امضا و در صف ارسال قرار می‌دهد و در
+تاریخچه و فهرست رشته‌ها ثبت می‌کند
+*/
+func sendChat(s *session, text string, parent *message, auto bool) (message, error) {
+	text, ok := outgoingText(s, text)
+	if !ok {
+		s.metrics.drop(dropBlocked)
+		return message{}, errBlocked
+	}
+	m := message{
+		Time:     time.Now(),
+		From:     s.name,
+		Text:     text,
+		ID:       newMessageID(),
+		Auto:     auto,
+		Key:      s.id.fingerprint,
+		Verified: true,
+	}
+	if parent != nil {
+		m.Parent = parent.ID
+		m.Quote = quoteOf(*parent)
+	}
+	err := queueChat(s, m)
+	if errors.Is(err, errTooLong) && s.conn.caps.Streaming {
+		return sendStreamed(s, strings.NewReader(text), parent, auto) // Too long for one line | برای یک خط بیش از حد طولانی
+	}
+	if err != nil {
+		return message{}, err
+	}
+	s.acks.track(m.ID)
+	s.threads.add(m)
+	return m, nil
+}
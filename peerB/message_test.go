@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestChatSignatureCoversFields(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := encodeChat(id, message{Time: time.Unix(0, 42), From: "ann", Text: "hello", ID: "id1", Parent: "p1", Quote: "hi", Part: partStart, Image: []byte{1, 2}, MIME: "image/png", Code: "go"})
+	for _, c := range []struct {
+		field string
+		value any
+		ok    bool
+	}{
+		{"", nil, true},
+		{"seq", 9, true}, // Added by the writer, outside the signature | افزوده‌ی نویسنده، خارج از امضا
+		{"from", "bob", false},
+		{"text", "hellO", false},
+		{"time", 43, false},
+		{"id", "id2", false},
+		{"parent", "p2", false},
+		{"quote", "ho", false},
+		{"auto", true, false},
+		{"part", partEnd, false},
+		{"image", []byte{1, 3}, false},
+		{"mime", "image/gif", false},
+		{"code", "rust", false},
+		{"text", "hello\x00id1", false}, // A separator smuggled into a field | جداکننده‌ی قاچاق‌شده در یک فیلد
+	} {
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if c.field != "" {
+			e[c.field] = c.value
+		}
+		tampered, _ := json.Marshal(e)
+		keys, _ := loadRegistry("")
+		m, ok := decodeChatLine(string(tampered), keys)
+		if !ok || m.Verified != c.ok {
+			t.Errorf("%s set to %v: verified %v, want %v", c.field, c.value, m.Verified, c.ok)
+		}
+	}
+}
+
+func TestChatImpersonationRejected(t *testing.T) {
+	ann, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mallory, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := loadRegistry("")
+	if _, ok := decodeChatLine(encodeChat(ann, message{Time: time.Now(), From: "ann", Text: "hi", ID: "1"}), keys); !ok {
+		t.Fatal("first key for a nick was refused")
+	}
+	m, ok := decodeChatLine(encodeChat(mallory, message{Time: time.Now(), From: "ann", Text: "hi", ID: "2"}), keys)
+	if ok {
+		t.Fatalf("another key signing as ann was accepted: %+v", m)
+	}
+	if m, ok := decodeChatLine("ann: hi", keys); !ok || m.Verified {
+		t.Fatalf("legacy line: ok %v, verified %v; want accepted unverified", ok, m.Verified)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"  // For notify configuration errors
+	"fmt"     // For writing the bell and flash sequences
+	"regexp"  // For spotting mentions of our name
+	"strings" // For parsing the notify list
+	"sync"    // For changing settings at runtime
+	"time"    // For the flash length
+)
+
+/*
+Notification events
+
+رویدادهای اعلان (با کاما ترکیب می‌شوند):
+- off: بدون اعلان
+- message: زنگ برای هر پیام
+- mention: زنگ فقط وقتی نام ما در پیام باشد
+- flash: چشمک‌زدن صفحه برای هر پیام
+*/
+const (
+	notifyOff     = "off"     // No notifications | بدون اعلان
+	notifyMessage = "message" // Bell on every message | زنگ برای هر پیام
+	notifyMention = "mention" // Bell when our name is mentioned | زنگ هنگام ذکر نام ما
+	notifyFlash   = "flash"   // Flash the screen on every message | چشمک‌زدن صفحه
+
+	notifyFlashTime = 100 * time.Millisecond // How long the screen stays inverted | مدت معکوس‌ماندن صفحه
+)
+
+var errNotify = errors.New(`notify takes "off" or a comma-separated list of "message", "mention" and "flash"`) // Unknown notify value | مقدار نامعتبر notify
+
+func init() {
+	registerSetting("notify", "off | message,mention,flash",
+		func(s *session) string { return s.notify.String() },
+		func(s *session, v string) error { return s.notify.set(v) })
+}
+
+/*
+notifier rings the terminal bell and flashes the screen for incoming
+messages according to the notify setting.
+
+این نوع بر اساس تنظیم notify برای پیام‌های دریافتی زنگ ترمینال
+را به صدا درمی‌آورد و صفحه را چشمک می‌زند
+*/
+type notifier struct {
+	mu      sync.Mutex
+	mention *regexp.Regexp // Our name as a whole word | نام ما به‌صورت یک کلمه‌ی کامل
+	bell    bool           // Bell on every message | زنگ برای هر پیام
+	named   bool           // Bell on mentions | زنگ هنگام ذکر نام
+	flash   bool           // Flash on every message | چشمک‌زدن برای هر پیام
+	dnd     dndState       // Do not disturb | حالت «مزاحم نشوید»
+}
+
+// newNotifier parses the notify setting for the given nickname | ساخت notifier برای نام داده‌شده
+func newNotifier(name, spec string) (*notifier, error) {
+	n := &notifier{
+		mention: regexp.MustCompile(`(?i)(^|[^\pL\pN_])@?` + regexp.QuoteMeta(name) + `($|[^\pL\pN_])`),
+	}
+	return n, n.set(spec)
+}
+
+// set replaces the enabled events | تغییر رویدادهای فعال
+func (n *notifier) set(spec string) error {
+	var bell, named, flash bool
+	for _, ev := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(ev) {
+		case notifyOff, "":
+		case notifyMessage:
+			bell = true
+		case notifyMention:
+			named = true
+		case notifyFlash:
+			flash = true
+		default:
+			return errNotify
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.bell, n.named, n.flash = bell, named, flash
+	return nil
+}
+
+// String lists the enabled events | فهرست رویدادهای فعال
+func (n *notifier) String() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var on []string
+	if n.bell {
+		on = append(on, notifyMessage)
+	}
+	if n.named {
+		on = append(on, notifyMention)
+	}
+	if n.flash {
+		on = append(on, notifyFlash)
+	}
+	if len(on) == 0 {
+		return notifyOff
+	}
+	return strings.Join(on, ",")
+}
+
+/*
+alert notifies about one displayed message: a BEL for any message or
+only for mentions, and a short reverse-video flash of the screen. In
+do-not-disturb mode nothing fires; it returns the auto-reply to send
+instead, if any.
+
+این تابع برای یک پیام نمایش‌داده‌شده اعلان می‌دهد: کاراکتر BEL برای
+هر پیام یا فقط هنگام ذکر نام، و چشمک کوتاه صفحه با معکوس‌کردن رنگ‌ها.
+در حالت «مزاحم نشوید» اعلانی نیست و به‌جای آن پاسخ خودکار (در صورت وجود) برگردانده می‌شود
+*/
+func (n *notifier) alert(m message) (autoReply string) {
+	n.mu.Lock()
+	mentioned := n.mention.MatchString(m.Text)
+	if n.dnd.active {
+		defer n.mu.Unlock()
+		return n.dnd.hold(m, mentioned)
+	}
+	bell := n.bell || n.named && mentioned
+	flash := n.flash
+	n.mu.Unlock()
+
+	if bell {
+		fmt.Fprint(stdout, "\a")
+	}
+	if flash {
+		fmt.Fprint(stdout, "\x1b[?5h") // Reverse video on | معکوس‌کردن رنگ‌ها
+		time.AfterFunc(notifyFlashTime, func() {
+			fmt.Fprint(stdout, "\x1b[?5l") // And back | بازگشت
+		})
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyAlerts(t *testing.T) {
+	for _, c := range []struct {
+		spec, text, want string
+	}{
+		{"off", "hi ann", ""},
+		{"message", "hi", "\a"},
+		{"mention", "hi", ""},
+		{"mention", "hi @Ann!", "\a"},
+		{"mention", "hi annie", ""},                 // Part of another word | بخشی از کلمه‌ی دیگر
+		{"mention", "hi joanne", ""},                // Part of another word | بخشی از کلمه‌ی دیگر
+		{"mention,flash", "hi", "\x1b[?5h\x1b[?5l"}, // Flash without a bell | چشمک بدون زنگ
+	} {
+		n, err := newNotifier("ann", c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		restore := stdout.redirect(&buf)
+		n.alert(message{From: "bob", Text: c.text})
+		if strings.Contains(c.spec, notifyFlash) {
+			time.Sleep(2 * notifyFlashTime) // Until the screen is back to normal | تا بازگشت صفحه
+		}
+		stdout.redirect(restore)
+		got := buf.String()
+		if got != c.want {
+			t.Errorf("%s on %q: %q, want %q", c.spec, c.text, got, c.want)
+		}
+	}
+}
+
+func TestSetNotify(t *testing.T) {
+	n, err := newNotifier("ann", "off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{notify: n}
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+
+	runCommand(s, "/set notify flash, message")
+	if got := n.String(); got != "message,flash" {
+		t.Errorf("after /set notify flash, message: %q", got)
+	}
+	runCommand(s, "/set notify loud")
+	if !strings.Contains(buf.String(), errNotify.Error()) || n.String() != "message,flash" {
+		t.Errorf("a bad value changed the setting to %q:\n%s", n, buf.String())
+	}
+	if _, err := newNotifier("ann", "bell"); err != errNotify {
+		t.Errorf("bell: %v, want %v", err, errNotify)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenGram returns a datagram socket and a connection to it | ساخت socket دیتاگرام و اتصال به آن
+func listenGram(t *testing.T) (*net.UnixConn, net.Conn) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	c, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln, c
+}
+
+// readGram reads one datagram | خواندن یک دیتاگرام
+func readGram(t *testing.T, ln *net.UnixConn) string {
+	buf := make([]byte, 4096)
+	_ = ln.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := ln.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSystemLogSinks(t *testing.T) {
+	ln, c := listenGram(t)
+	l := &opLog{sink: syslogSink{conn: c, tag: "peerchat-ann[42]"}, name: "ann"}
+	defer l.close()
+	l.logf(priErr, "Send error: %s", "broken\npipe \x1b[31mred\x1b[0m")
+	got := readGram(t, ln)
+	if !strings.HasPrefix(got, "<27>") || !strings.HasSuffix(got, " peerchat-ann[42]: Send error: broken pipe red") {
+		t.Errorf("syslog datagram %q, want daemon.err on one line without escapes", got)
+	}
+
+	ln, c = listenGram(t)
+	l = &opLog{sink: journalSink{conn: c, name: "peerchat-ann"}, name: "ann"}
+	defer l.close()
+	l.logf(priNotice, "Connected to %s", "127.0.0.1:9000")
+	if got, want := readGram(t, ln), "PRIORITY=5\nSYSLOG_FACILITY=3\nSYSLOG_IDENTIFIER=peerchat-ann\nMESSAGE=Connected to 127.0.0.1:9000\n"; got != want {
+		t.Errorf("journal datagram %q, want %q", got, want)
+	}
+
+	var none *opLog
+	none.logf(priCrit, "nothing") // Without -log | بدون -log
+	none.close()
+}
+
+func TestLogFormats(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{logText, logJSON} {
+		path := filepath.Join(dir, format+".log")
+		l, err := openOpLog(path, format, "ann", rotation{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.logf(priWarning, "Link lost: %s", `timeout "read"`)
+		l.close()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := strings.TrimSuffix(string(data), "\n")
+		if strings.Contains(line, "\n") {
+			t.Errorf("%s: more than one line: %q", format, data)
+		}
+		switch format {
+		case logText:
+			stamp, msg, _ := strings.Cut(line, " ")
+			if _, err := time.Parse(time.RFC3339, stamp); err != nil || msg != `Link lost: timeout "read"` {
+				t.Errorf("text line %q", line)
+			}
+		case logJSON:
+			var e map[string]any
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("JSON line %q: %v", line, err)
+			}
+			if _, err := time.Parse(time.RFC3339Nano, e["time"].(string)); err != nil || len(e) != 4 ||
+				e["level"] != "warning" || e["peer"] != "ann" || e["msg"] != `Link lost: timeout "read"` {
+				t.Errorf("JSON event %v, want time, level, peer and msg", e)
+			}
+		}
+	}
+	if _, err := openOpLog(filepath.Join(dir, "x.log"), "xml", "ann", rotation{}); !errors.Is(err, errLogFormat) {
+		t.Errorf("xml: %v, want %v", err, errLogFormat)
+	}
+	if l, err := openOpLog("", logJSON, "ann", rotation{}); l != nil || err != nil {
+		t.Errorf("no target: %v, %v; want no log", l, err)
+	}
+}
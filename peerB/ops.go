@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"     // For command output and notices
+	"strings" // For joining the kick reason
+	"sync"    // For guarding the mute list
+	"time"    // For mute durations
+)
+
+/*
+Operator frame types
+
+انواع فریم‌های کنترلی اپراتور:
+- kick: اتصال به دستور طرف مقابل بسته می‌شود
+- mute: طرف مقابل برای مدتی ساکت شده است
+*/
+const (
+	ctrlKick = "kick" // Remote is closing the link | طرف مقابل اتصال را می‌بندد
+	ctrlMute = "mute" // Remote muted us | طرف مقابل ما را ساکت کرد
+)
+
+const kickGrace = 500 * time.Millisecond // Time for the kick notice to leave | فرصت ارسال پیام kick
+
+// defaultBanPath returns the per-name ban list file | مسیر پیش‌فرض لیست مسدودی
+func defaultBanPath(name string) string {
+	return dataPath(name + ".bans")
+}
+
+/*
+muteFilter is an inbound filter stage that drops messages from muted
+nicks until their mute expires.
+
+این فیلتر ورودی پیام‌های نام‌های ساکت‌شده را تا پایان مدت سکوت حذف می‌کند
+*/
+type muteFilter struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// newMuteFilter creates an empty mute list | ساخت لیست سکوت خالی
+func newMuteFilter() *muteFilter {
+	return &muteFilter{until: make(map[string]time.Time)}
+}
+
+// filter implements messageFilter | پیاده‌سازی messageFilter
+func (f *muteFilter) filter(m *message) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	until, ok := f.until[m.From]
+	if !ok {
+		return true
+	}
+	if time.Now().After(until) {
+		delete(f.until, m.From) // Mute expired | پایان سکوت
+		return true
+	}
+	return false
+}
+
+// mute silences nick for d; zero d lifts the mute | ساکت کردن nick به مدت d؛ صفر یعنی لغو
+func (f *muteFilter) mute(nick string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d <= 0 {
+		delete(f.until, nick)
+		return
+	}
+	f.until[nick] = time.Now().Add(d)
+}
+
+func init() {
+	registerCommand("kick", "/kick [reason]  disconnect the remote peer", kickCommand)
+	registerCommand("ban", "/ban <fingerprint> | /ban list  refuse a key at connect time", banCommand)
+	registerCommand("unban", "/unban <fingerprint>  allow a banned key again", unbanCommand)
+	registerCommand("mute", "/mute <nick> <duration>  drop someone's messages for a while", muteCommand)
+	registerCommand("unmute", "/unmute <nick>  lift a mute", unmuteCommand)
+}
+
+/*
+handleOpsFrames registers the handlers that render operator actions
+taken by the remote against us.
+
+این تابع handlerهای نمایش اقدامات اپراتوری طرف مقابل علیه ما را ثبت می‌کند
+*/
+func handleOpsFrames(s *session) {
+	s.ctrl.handle(ctrlKick, func(f controlFrame) {
+		fmt.Fprintln(s.status, "Kicked by remote:", f.Text)
+	})
+	s.ctrl.handle(ctrlMute, func(f controlFrame) {
+		fmt.Fprintln(s.status, "Muted by remote:", f.Text)
+	})
+}
+
+/*
+kick tells the remote why it is being disconnected and closes the link
+once the notice had a moment to go out.
+
+این تابع دلیل قطع اتصال را به طرف مقابل اعلام و پس از فرصت کوتاهی
+برای ارسال پیام، اتصال را می‌بندد
+*/
+func kick(s *session, reason string) {
+	s.ctrl.send(controlFrame{Type: ctrlKick, Text: reason})
+	time.AfterFunc(kickGrace, func() { s.done.close() })
+}
+
+// kickCommand disconnects the remote peer | قطع اتصال peer مقابل
+func kickCommand(s *session, args []string) {
+	reason := "no reason given"
+	if len(args) > 0 {
+		reason = strings.Join(args, " ")
+	}
+	fmt.Fprintln(stdout, "Kicking", s.conn.RemoteAddr())
+	kick(s, reason)
+}
+
+/*
+banCommand adds a key fingerprint to the persisted ban list, which is
+checked during every handshake; banning the current peer also kicks it.
+
+این دستور یک fingerprint را به لیست مسدودی ذخیره‌شده اضافه می‌کند که
+در هر handshake بررسی می‌شود؛ مسدود کردن peer فعلی آن را اخراج هم می‌کند
+*/
+func banCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /ban <fingerprint> | /ban list")
+		return
+	}
+	if args[0] == "list" {
+		printEntries(s.auth.bans, "Ban list is empty")
+		return
+	}
+	if err := s.auth.bans.set(args[0], true); err != nil {
+		fmt.Fprintln(stdout, "Ban error:", err)
+		return
+	}
+	s.auth.resume.revoke(args[0]) // Its ticket goes too | ticket آن هم حذف می‌شود
+	fmt.Fprintln(stdout, "Banned", args[0])
+	if s.conn.remoteKey == args[0] {
+		kick(s, "banned")
+	}
+}
+
+// unbanCommand removes a fingerprint from the ban list | حذف یک fingerprint از لیست مسدودی
+func unbanCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /unban <fingerprint>")
+		return
+	}
+	if err := s.auth.bans.set(args[0], false); err != nil {
+		fmt.Fprintln(stdout, "Ban error:", err)
+		return
+	}
+	fmt.Fprintln(stdout, "Unbanned", args[0])
+}
+
+/*
+muteCommand drops a nick's messages for a duration and lets the remote
+know it has been muted.
+
+این دستور پیام‌های یک نام را برای مدتی حذف می‌کند و به طرف مقابل
+اطلاع می‌دهد که ساکت شده است
+*/
+func muteCommand(s *session, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(stdout, "Usage: /mute <nick> <duration>")
+		return
+	}
+	d, err := time.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		fmt.Fprintln(stdout, "Usage: /mute <nick> <duration>  (e.g. 10m)")
+		return
+	}
+	s.mutes.mute(args[0], d)
+	s.ctrl.send(controlFrame{Type: ctrlMute, Text: fmt.Sprintf("%s for %s", args[0], d)})
+	fmt.Fprintf(stdout, "Muted %s for %s\n", args[0], d)
+}
+
+// unmuteCommand lifts a mute early | لغو زودتر سکوت
+func unmuteCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /unmute <nick>")
+		return
+	}
+	s.mutes.mute(args[0], 0)
+	fmt.Fprintln(stdout, "Unmuted", args[0])
+}
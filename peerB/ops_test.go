@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMuteFilter(t *testing.T) {
+	f := newMuteFilter()
+	f.mute("bob", time.Hour)
+	if f.filter(&message{From: "bob", Text: "hi"}) {
+		t.Fatal("a muted nick got through")
+	}
+	if !f.filter(&message{From: "ann", Text: "hi"}) {
+		t.Fatal("a nick nobody muted was dropped")
+	}
+	f.mute("bob", 0)
+	if !f.filter(&message{From: "bob", Text: "hi"}) {
+		t.Fatal("an unmuted nick is still dropped")
+	}
+
+	f.mute("bob", time.Hour)
+	f.until["bob"] = time.Now().Add(-time.Second) // The hour is up | یک ساعت گذشت
+	if !f.filter(&message{From: "bob", Text: "hi"}) {
+		t.Fatal("a nick is still dropped after its mute expired")
+	}
+	if _, ok := f.until["bob"]; ok {
+		t.Fatal("an expired mute was kept")
+	}
+}
+
+func TestBanRefusesKey(t *testing.T) {
+	server, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	banned, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test.bans")
+	bans, _ := loadEntrySet(path)
+	if err := bans.set(banned.fingerprint, true); err != nil {
+		t.Fatal(err)
+	}
+	bans, err = loadEntrySet(path) // The ban outlives a restart | مسدودی پس از راه‌اندازی دوباره می‌ماند
+	if err != nil || !bans.has(banned.fingerprint) {
+		t.Fatalf("ban not saved: %v", err)
+	}
+	members, _ := loadEntrySet("")
+	a := &peerAuth{id: server, bans: bans, members: members, access: accessOpen}
+	for _, resumed := range []bool{false, true} {
+		if reason, _ := a.admit(banned.publicKey(), noProof, testHellos, resumed); reason != "you are banned" {
+			t.Errorf("resumed %v: banned key admitted with %q", resumed, reason)
+		}
+	}
+	if reason, _ := a.admit("", noProof, testHellos, false); reason == "" {
+		t.Error("a keyless peer was admitted while a ban list is set")
+	}
+
+	if err := bans.set(banned.fingerprint, false); err != nil {
+		t.Fatal(err)
+	}
+	if reason, _ := a.admit(banned.publicKey(), noProof, testHellos, false); reason != "" {
+		t.Errorf("unbanned key refused with %q", reason)
+	}
+}
+
+func TestKickNotifiesBeforeClosing(t *testing.T) {
+	done := newDoneSignal()
+	s := &session{ctrl: newControlLink(done), done: done}
+	start := time.Now()
+	kick(s, "flooding")
+	if f := <-s.ctrl.out; f.Type != ctrlKick || f.Text != "flooding" {
+		t.Fatalf("sent %+v, want a kick with the reason", f)
+	}
+	select {
+	case <-done.c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the link was not closed after a kick")
+	}
+	if waited := time.Since(start); waited < kickGrace {
+		t.Errorf("link closed after %s, before the notice had %s to leave", waited, kickGrace)
+	}
+	if done.redialing() {
+		t.Error("a kick asks for a redial")
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/hex" // For checking fingerprints
+	"errors"       // For pairing error values
+	"fmt"          // For the code and notices
+	"net"          // For the advertised address
+	"net/url"      // For the pairing URI
+	"strconv"      // For checking the port
+	"strings"      // For drawing the QR code
+
+	"rsc.io/qr" // For encoding the pairing URI
+)
+
+/*
+Pairing URI parts
+
+اجزای URI جفت‌سازی و دعوت:
+- pairScheme طرح URI است: peerchat://host:port?fp=…&nick=…&token=…&transport=tcp
+- pairTransport تنها transport پشتیبانی‌شده است
+*/
+const (
+	pairScheme    = "peerchat"
+	pairTransport = "tcp"
+)
+
+var (
+	errPairURI       = errors.New("not a peerchat:// link")                       // Wrong scheme or shape | طرح یا شکل نادرست
+	errPairAddress   = errors.New("link needs a host and a port from 1 to 65535") // Bad host:port | host:port نامعتبر
+	errPairFP        = errors.New("fp must be a 16-digit hex key fingerprint")    // Bad or missing fp | fp نامعتبر یا ناموجود
+	errPairEmpty     = errors.New("empty value")                                  // e.g. "token=" | مثلاً "token="
+	errPairParam     = errors.New("unknown parameter")                            // Not part of a link | جزء پیوند نیست
+	errPairNick      = errors.New("pairing needs the nick of the other side")     // No nick= | بدون nick=
+	errPairTransport = errors.New("unsupported transport")                        // Only TCP here | فقط TCP
+	errPairTaken     = errors.New("nick is already pinned to another key")        // Registry conflict | تعارض با registry
+	errPairNoAddr    = errors.New("no address to advertise; pass -listen host:port")
+)
+
+/*
+pairing is what one peer needs to reach and trust the other: the
+address it listens on, the transport, its key fingerprint, its nick and
+the token (the chat password) it asks for. As a URI it is both the
+pairing code and an invitation link that -dial accepts.
+
+این نوع چیزی است که یک peer برای رسیدن به دیگری و اعتماد به آن لازم دارد:
+آدرس گوش‌دادن، transport، fingerprint کلید، نام و token (رمز گفتگو) مورد
+نیاز آن؛ به‌صورت URI هم کد جفت‌سازی است و هم پیوند دعوتی که -dial می‌پذیرد
+*/
+type pairing struct {
+	Address     string
+	Transport   string
+	Fingerprint string
+	Nick        string
+	Token       string
+}
+
+// String renders the pairing as a peerchat:// URI | نمایش جفت‌سازی به‌صورت URI
+func (p pairing) String() string {
+	q := url.Values{"fp": {p.Fingerprint}, "transport": {p.Transport}}
+	if p.Nick != "" {
+		q.Set("nick", p.Nick)
+	}
+	if p.Token != "" {
+		q.Set("token", p.Token)
+	}
+	return (&url.URL{Scheme: pairScheme, Host: p.Address, RawQuery: q.Encode()}).String()
+}
+
+// isPeerLink reports whether s is a peerchat:// URI rather than an address | آیا s یک URI از نوع peerchat:// است
+func isPeerLink(s string) bool {
+	return strings.HasPrefix(s, pairScheme+"://")
+}
+
+/*
+parsePairing reads and validates a peerchat:// link: the address must
+have a host and a valid port, fp must be a key fingerprint, nick and
+token may be left out but not empty, and no other parameter is allowed.
+
+این تابع یک پیوند peerchat:// را می‌خواند و اعتبارسنجی می‌کند: آدرس باید
+میزبان و پورت معتبر داشته باشد، fp باید fingerprint کلید باشد، nick و
+token می‌توانند نباشند ولی خالی نباشند و پارامتر دیگری مجاز نیست
+*/
+func parsePairing(s string) (pairing, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || u.Scheme != pairScheme || u.Host == "" || u.User != nil || strings.Trim(u.Path, "/") != "" || u.Fragment != "" {
+		return pairing{}, errPairURI
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if n, perr := strconv.Atoi(port); err != nil || host == "" || perr != nil || n < 1 || n > 65535 {
+		return pairing{}, errPairAddress
+	}
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return pairing{}, err
+	}
+	p := pairing{Address: u.Host, Transport: pairTransport}
+	for key, values := range q {
+		if len(values) != 1 || values[0] == "" {
+			return pairing{}, fmt.Errorf("%s: %w", key, errPairEmpty)
+		}
+		switch v := values[0]; key {
+		case "fp":
+			p.Fingerprint = v
+		case "nick":
+			p.Nick = v
+		case "token":
+			p.Token = v
+		case "transport":
+			p.Transport = v
+		default:
+			return pairing{}, fmt.Errorf("%s: %w", key, errPairParam)
+		}
+	}
+	if p.Transport != pairTransport {
+		return pairing{}, fmt.Errorf("%s: %w", p.Transport, errPairTransport)
+	}
+	if !isFingerprint(p.Fingerprint) {
+		return pairing{}, errPairFP
+	}
+	return p, nil
+}
+
+// isFingerprint reports whether s looks like a key fingerprint | آیا s شبیه fingerprint کلید است
+func isFingerprint(s string) bool {
+	if len(s) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+/*
+advertiseAddr turns the listen address into one the other side can
+dial: a wildcard host is replaced by the first non-loopback address of
+this machine.
+
+این تابع آدرس گوش‌دادن را به آدرسی تبدیل می‌کند که طرف مقابل بتواند به آن
+وصل شود: میزبان عمومی (wildcard) با اولین آدرس غیر loopback این دستگاه
+جایگزین می‌شود
+*/
+func advertiseAddr(listen string) (string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return listen, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	var v6 string
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || ipn.IP.IsLoopback() || ipn.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipn.IP.To4() != nil {
+			return net.JoinHostPort(ipn.IP.String(), port), nil // IPv4 first | ابتدا IPv4
+		}
+		if v6 == "" {
+			v6 = net.JoinHostPort(ipn.IP.String(), port)
+		}
+	}
+	if v6 == "" {
+		return "", errPairNoAddr
+	}
+	return v6, nil
+}
+
+/*
+renderQR draws text as a QR code with ANSI colours, two modules per
+character cell using half blocks, inside a light quiet zone so phones
+can read it on dark terminals too.
+
+این تابع text را به‌صورت کد QR با رنگ‌های ANSI رسم می‌کند؛ هر خانه‌ی
+کاراکتر با نیم‌بلوک دو ماژول را نشان می‌دهد و حاشیه‌ی روشن باعث می‌شود
+گوشی روی ترمینال تیره هم آن را بخواند
+*/
+func renderQR(text string) (string, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", err
+	}
+	const quiet = 2 // Light modules around the code | ماژول‌های روشن اطراف کد
+	dark := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		return x >= 0 && y >= 0 && x < code.Size && y < code.Size && code.Black(x, y)
+	}
+	colour := func(d bool) int {
+		if d {
+			return 0 // Black | سیاه
+		}
+		return 7 // White | سفید
+	}
+	var b strings.Builder
+	side := code.Size + 2*quiet
+	for y := 0; y < side; y += 2 {
+		for x := 0; x < side; x++ {
+			fmt.Fprintf(&b, "\x1b[3%d;4%dm▀", colour(dark(x, y)), colour(dark(x, y+1)))
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String(), nil
+}
+
+/*
+runPair implements "pair". Without an argument it prints our pairing
+code, which carries token when the chat needs a password, as a QR code
+and as text. Given the code printed by the other
+side, it pins the nick to its key and files it in the roster with its
+address, so "connect <nick>" reaches it.
+
+این تابع زیرفرمان pair را اجرا می‌کند. بدون آرگومان کد جفت‌سازی ما را که
+در صورت نیاز گفتگو به رمز token را هم دارد به‌صورت QR و متن چاپ می‌کند. با کدی که طرف مقابل چاپ کرده، نام را به
+کلید آن سنجاق می‌کند و آن را با آدرسش در فهرست دوستان ثبت می‌کند تا
+"connect <nick>" به آن برسد
+*/
+func runPair(name, listen, token string, id *identity, keys *registry, buddies *roster, args []string) error {
+	if len(args) == 0 {
+		addr, err := advertiseAddr(listen)
+		if err != nil {
+			return err
+		}
+		p := pairing{Address: addr, Transport: pairTransport, Fingerprint: id.fingerprint, Nick: name, Token: token}
+		code, err := renderQR(p.String())
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(stdout, code)
+		fmt.Fprintln(stdout, p)
+		fmt.Fprintln(stdout, "On the other side run: pair '"+p.String()+"', or dial it with -dial")
+		return nil
+	}
+
+	p, err := parsePairing(strings.Join(args, ""))
+	if err != nil {
+		return err
+	}
+	if p.Nick == "" {
+		return errPairNick
+	}
+	if known, _ := keys.bind(p.Nick, p.Fingerprint); known != p.Fingerprint {
+		return fmt.Errorf("%s: %w", p.Nick, errPairTaken)
+	}
+	if err := buddies.file(rosterEntry{Nick: p.Nick, Fingerprint: p.Fingerprint, Address: p.Address}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Paired with %s (%s) at %s; chat with: connect %s\n", p.Nick, p.Fingerprint, p.Address, p.Nick)
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPairingRoundTrip(t *testing.T) {
+	p := pairing{Address: "[2001:db8::1]:9000", Transport: pairTransport, Fingerprint: "0123456789abcdef", Nick: "ann & co", Token: "s3cret?"}
+	got, err := parsePairing(" " + p.String() + "\n")
+	if err != nil || got != p {
+		t.Errorf("%s parsed as %+v, %v", p, got, err)
+	}
+	if !isPeerLink(p.String()) || isPeerLink("host:9000") {
+		t.Error("isPeerLink does not tell links from addresses")
+	}
+}
+
+func TestParsePairing(t *testing.T) {
+	const fp = "fp=0123456789abcdef"
+	for link, want := range map[string]error{
+		"peerchat://host:9000?" + fp:                    nil,
+		"http://host:9000?" + fp:                        errPairURI,
+		"peerchat://host:9000/path?" + fp:               errPairURI,
+		"peerchat://user@host:9000?" + fp:               errPairURI,
+		"peerchat://host:9000?" + fp + "#x":             errPairURI,
+		"peerchat://host?" + fp:                         errPairAddress,
+		"peerchat://:9000?" + fp:                        errPairAddress,
+		"peerchat://host:0?" + fp:                       errPairAddress,
+		"peerchat://host:65536?" + fp:                   errPairAddress,
+		"peerchat://host:9000":                          errPairFP,
+		"peerchat://host:9000?fp=0123":                  errPairFP,
+		"peerchat://host:9000?fp=0123456789abcdeg":      errPairFP,
+		"peerchat://host:9000?" + fp + "&nick=":         errPairEmpty,
+		"peerchat://host:9000?" + fp + "&nick=a&nick=b": errPairEmpty,
+		"peerchat://host:9000?" + fp + "&tls=1":         errPairParam,
+		"peerchat://host:9000?" + fp + "&transport=udp": errPairTransport,
+	} {
+		if _, err := parsePairing(link); !errors.Is(err, want) {
+			t.Errorf("%s: %v, want %v", link, err, want)
+		}
+	}
+}
+
+func TestAdvertiseAddr(t *testing.T) {
+	if got, err := advertiseAddr("192.0.2.1:9000"); got != "192.0.2.1:9000" || err != nil {
+		t.Errorf("a concrete address became %q, %v", got, err)
+	}
+	if got, err := advertiseAddr("chat.example:9000"); got != "chat.example:9000" || err != nil {
+		t.Errorf("a host name became %q, %v", got, err)
+	}
+	if _, err := advertiseAddr("9000"); err == nil {
+		t.Error("an address without a port was advertised")
+	}
+	for _, listen := range []string{":9000", "0.0.0.0:9000", "[::]:9000"} {
+		got, err := advertiseAddr(listen)
+		if errors.Is(err, errPairNoAddr) {
+			continue // No network here | این‌جا شبکه‌ای نیست
+		}
+		host, port, serr := net.SplitHostPort(got)
+		if ip := net.ParseIP(host); err != nil || serr != nil || port != "9000" || ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+			t.Errorf("advertiseAddr(%q) = %q, %v; want a dialable address of this machine", listen, got, err)
+		}
+	}
+}
+
+func TestRenderQR(t *testing.T) {
+	code, err := renderQR("peerchat://192.0.2.1:9000?fp=0123456789abcdef&transport=tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	width := strings.Count(lines[0], "▀")
+	if width < 25 || len(lines) != (width+1)/2 {
+		t.Errorf("%d lines of %d modules, want a square code", len(lines), width)
+	}
+	for _, line := range lines {
+		if strings.Count(line, "▀") != width || !strings.HasSuffix(line, "\x1b[0m") {
+			t.Fatalf("ragged or unreset line %q", line)
+		}
+	}
+	if !strings.HasPrefix(lines[0], strings.Repeat("\x1b[37;47m▀", width)) {
+		t.Error("no light quiet zone above the code")
+	}
+}
+
+func TestRunPair(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	ann, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runPair("ann", "192.0.2.1:9000", "pw", ann, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	link := pairing{Address: "192.0.2.1:9000", Transport: pairTransport, Fingerprint: ann.fingerprint, Nick: "ann", Token: "pw"}.String()
+	if !strings.Contains(buf.String(), "\n"+link+"\n") || !strings.Contains(buf.String(), "pair '"+link+"'") {
+		t.Errorf("printed code:\n%s", buf.String())
+	}
+
+	keys, _ := loadRegistry("")
+	buddies, _ := loadRoster("")
+	buf.Reset()
+	if err := runPair("bob", "", "", nil, keys, buddies, []string{link[:20], link[20:]}); err != nil {
+		t.Fatal(err)
+	}
+	if !keys.check("ann", ann.fingerprint) || keys.check("ann", "ffffffffffffffff") {
+		t.Error("ann is not pinned to the paired key")
+	}
+	if addr, ok := buddies.dialTarget("ann"); !ok || addr != "192.0.2.1:9000" {
+		t.Errorf("connect ann would dial %q, %v", addr, ok)
+	}
+	if want := "Paired with ann (" + ann.fingerprint + ") at 192.0.2.1:9000; chat with: connect ann\n"; buf.String() != want {
+		t.Errorf("output %q, want %q", buf.String(), want)
+	}
+
+	other := pairing{Address: "192.0.2.9:9000", Transport: pairTransport, Fingerprint: "ffffffffffffffff", Nick: "ann"}
+	if err := runPair("bob", "", "", nil, keys, buddies, []string{other.String()}); !errors.Is(err, errPairTaken) {
+		t.Errorf("pairing a pinned nick with another key: %v", err)
+	}
+	other.Nick = ""
+	if err := runPair("bob", "", "", nil, keys, buddies, []string{other.String()}); err != errPairNick {
+		t.Errorf("pairing without a nick: %v", err)
+	}
+	if addr, _ := buddies.dialTarget("ann"); addr != "192.0.2.1:9000" {
+		t.Errorf("a refused pairing changed the roster: %q", addr)
+	}
+}
+
+func TestDialPeerLink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts peers")
+	}
+	cmd, out, _ := pipePeer(t, "", "-name", "X", "-dial", "peerchat://nowhere")
+	if err := cmd.Wait(); err == nil || !strings.Contains(out.String(), "Dial error:") {
+		t.Errorf("dialing an unreadable link: %v\n%s", err, out)
+	}
+
+	// A listener behind a password that only the link carries | listenerی پشت رمزی که فقط پیوند آن را دارد
+	dir := t.TempDir()
+	ann, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, err := loadEntrySet(filepath.Join(dir, "bans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := loadEntrySet(filepath.Join(dir, "members"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newPeerAuth(ann, bans, members, accessPassword, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tcpTransport{}.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := newDoneSignal()
+	defer done.close()
+	linked := make(chan *handshakeConn, 1)
+	go func() {
+		linked <- establishConn(tcpTransport{}, ln, "127.0.0.1:1", auth, socketOptions{linger: -1}, io.Discard, done.c)
+	}()
+
+	link := pairing{Address: ln.Addr().String(), Transport: pairTransport, Fingerprint: "ffffffffffffffff", Token: "pw"}
+	cmd, _, errOut := pipePeer(t, "", "-name", "X", "-listen", freeAddr(t), "-dial", link.String())
+	exited := make(chan struct{})
+	go func() { _ = cmd.Wait(); close(exited) }()
+	errOut.waitFor(t, "Refused the dialed peer", exited)
+	_ = cmd.Process.Kill()
+	<-exited
+
+	link.Fingerprint = ann.fingerprint
+	cmd, _, errOut = pipePeer(t, "", "-name", "X", "-listen", freeAddr(t), "-dial", link.String())
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	select {
+	case c := <-linked:
+		if c == nil {
+			t.Fatal("no link")
+		}
+		c.Close()
+	case <-time.After(20 * time.Second):
+		t.Fatalf("the invited key and token did not link:\n%s", errOut)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"         // For reading the part line before the bytes
+	"encoding/json" // For the part line
+	"io"            // For section readers and offset writers
+	"net"           // For the stream connection type
+	"os"            // For the file being filled
+	"sync"          // For guarding the open transfers
+	"time"          // For the stall check
+)
+
+/*
+Parallel transfer settings
+
+تنظیمات انتقال موازی:
+- فایل‌های بزرگ‌تر از parallelThreshold تقسیم می‌شوند
+- fileParts تعداد streamهای هر فایل بزرگ است
+- maxFileParts بیشترین تعدادی است که گیرنده می‌پذیرد
+*/
+const (
+	parallelThreshold = 8 << 20 // Smallest file split across streams | کوچک‌ترین فایل تقسیم‌شده
+	fileParts         = 4       // Streams per large file | تعداد stream هر فایل بزرگ
+	maxFileParts      = 16      // Most parts a receiver accepts | بیشترین بخش پذیرفتنی
+)
+
+/*
+filePart is the first line of a file-part stream: which transfer it
+belongs to and which part it carries. Part 0 travels on the file stream
+itself, right after the verdict.
+
+این ساختار خط اول stream بخش فایل است: به کدام انتقال تعلق دارد و کدام
+بخش را حمل می‌کند؛ بخش 0 پس از پاسخ گیرنده روی خود stream فایل می‌آید
+*/
+type filePart struct {
+	Transfer string `json:"transfer"`
+	Index    int    `json:"index"`
+}
+
+// partRange returns the offset and length of part i | محدوده‌ی بخش i
+func partRange(size int64, parts, i int) (off, n int64) {
+	chunk := (size + int64(parts) - 1) / int64(parts)
+	off = min(int64(i)*chunk, size)
+	return off, min(chunk, size-off)
+}
+
+/*
+sendParts sends a file split in h.Parts ranges, part 0 on the file
+stream st and each other one on a stream of its own, all at once so a
+high-latency link carries several windows in flight.
+
+این تابع فایلی را که به h.Parts محدوده تقسیم شده ارسال می‌کند؛ بخش 0 روی
+stream فایل st و هر بخش دیگر روی stream جداگانه، همه هم‌زمان تا روی اتصال
+با تأخیر زیاد چند پنجره در راه باشد
+*/
+func sendParts(s *session, f *os.File, h fileHeader, st net.Conn, back io.Reader, progress io.Writer) error {
+	errs := make(chan error, h.Parts-1)
+	for i := 1; i < h.Parts; i++ {
+		go func() { errs <- sendPart(s, f, h, i, progress) }()
+	}
+	off, n := partRange(h.Size, h.Parts, 0)
+	err := copyChunked(s.sched.wrap(st, prioBulk), back, io.TeeReader(io.NewSectionReader(f, off, n), progress), s.conn.caps.FileWindow)
+	for range h.Parts - 1 {
+		if perr := <-errs; err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// sendPart sends part i on a new file-part stream | ارسال بخش i روی یک stream جدید
+func sendPart(s *session, f *os.File, h fileHeader, i int, progress io.Writer) error {
+	st, err := openStream(s.mux, streamFilePart)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if err := json.NewEncoder(st).Encode(filePart{Transfer: h.Transfer, Index: i}); err != nil {
+		return err
+	}
+	off, n := partRange(h.Size, h.Parts, i)
+	return copyChunked(s.sched.wrap(st, prioBulk), st, io.TeeReader(io.NewSectionReader(f, off, n), progress), s.conn.caps.FileWindow)
+}
+
+/*
+partSink is an accepted parallel transfer waiting for its parts: each
+one is written at its offset in f and reports on done. It is complete,
+progress included, before the verdict lets the sender open parts.
+
+این نوع یک انتقال موازی پذیرفته‌شده است که منتظر بخش‌هایش است: هر بخش
+در جای خود در f نوشته می‌شود و نتیجه را روی done اعلام می‌کند؛ پیش از
+اینکه پاسخ گیرنده اجازه‌ی بازکردن بخش‌ها را بدهد، همراه progress کامل است
+*/
+type partSink struct {
+	header   fileHeader
+	file     *os.File
+	progress *transferProgress
+	done     chan error
+
+	mu  sync.Mutex
+	got map[int]bool // Part indexes already taken | اندیس بخش‌های گرفته‌شده
+}
+
+// claim takes part i, reporting false for an index out of range or seen before | گرفتن بخش i؛ false برای اندیس خارج از محدوده یا تکراری
+func (k *partSink) claim(i int) bool {
+	if i < 0 || i >= k.header.Parts {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.got[i] {
+		return false
+	}
+	k.got[i] = true
+	return true
+}
+
+// partTable holds the parallel transfers being received | انتقال‌های موازی در حال دریافت
+type partTable struct {
+	mu    sync.Mutex
+	sinks map[string]*partSink
+}
+
+// newPartTable creates an empty table | ساخت جدول خالی
+func newPartTable() *partTable {
+	return &partTable{sinks: make(map[string]*partSink)}
+}
+
+// open registers an accepted transfer | ثبت انتقال پذیرفته‌شده
+func (t *partTable) open(h fileHeader, f *os.File, progress *transferProgress) *partSink {
+	sink := &partSink{header: h, file: f, progress: progress, done: make(chan error, h.Parts), got: make(map[int]bool)}
+	t.mu.Lock()
+	t.sinks[h.Transfer] = sink
+	t.mu.Unlock()
+	return sink
+}
+
+// get returns the sink of a transfer | برگرداندن sink یک انتقال
+func (t *partTable) get(transfer string) (*partSink, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sink, ok := t.sinks[transfer]
+	return sink, ok
+}
+
+// close forgets a transfer; late parts are then refused | فراموش‌کردن انتقال؛ بخش‌های دیر رسیده رد می‌شوند
+func (t *partTable) close(transfer string) {
+	t.mu.Lock()
+	delete(t.sinks, transfer)
+	t.mu.Unlock()
+}
+
+/*
+receiveParts stores part 0 from the file stream and waits for the other
+parts. It gives up when the session ends, or when the sender has closed
+the file stream and no byte of the transfer arrived for
+fileStallTimeout; while the stream is open the sender may just be
+paused.
+
+این تابع بخش 0 را از stream فایل ذخیره می‌کند و منتظر بخش‌های دیگر
+می‌ماند؛ اگر نشست تمام شود، یا فرستنده stream فایل را بسته باشد و به مدت
+fileStallTimeout هیچ بایتی از انتقال نرسد منصرف می‌شود؛ تا وقتی stream باز
+است ممکن است فرستنده فقط متوقف شده باشد
+*/
+func receiveParts(s *session, sink *partSink, back net.Conn, r io.Reader) error {
+	sink.claim(0) // Part 0 is this stream | بخش 0 همین stream است
+	err := receiveRange(s, sink, 0, back, r)
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, r) // Returns once the sender is done | با پایان کار فرستنده برمی‌گردد
+		close(closed)
+	}()
+	stall := time.NewTicker(fileStallTimeout)
+	defer stall.Stop()
+	last := sink.progress.done.Load()
+	for left := sink.header.Parts - 1; left > 0; {
+		select {
+		case perr := <-sink.done:
+			if err == nil {
+				err = perr
+			}
+			left--
+		case <-stall.C:
+			select {
+			case <-closed:
+			default:
+				continue // Sender still there | فرستنده هنوز هست
+			}
+			if now := sink.progress.done.Load(); now != last {
+				last = now
+				continue
+			}
+			return errFileStalled
+		case <-s.done.c:
+			return errClosed
+		}
+	}
+	return err
+}
+
+// receiveRange writes part i from r at its offset | نوشتن بخش i از r در جای خود
+func receiveRange(s *session, sink *partSink, i int, back net.Conn, r io.Reader) error {
+	off, n := partRange(sink.header.Size, sink.header.Parts, i)
+	dst := io.MultiWriter(io.NewOffsetWriter(sink.file, off), sink.progress)
+	if s.conn.caps.FileWindow {
+		return receiveWindowed(back, r, dst, n)
+	}
+	_, err := io.CopyN(dst, r, n)
+	return err
+}
+
+/*
+receiveFilePart handles a file-part stream of an accepted parallel
+transfer; parts of unknown transfers, and repeated or out-of-range
+indexes, are dropped, so every part is counted once.
+
+این تابع stream بخش فایل مربوط به یک انتقال موازی پذیرفته‌شده را پردازش
+می‌کند؛ بخش‌های انتقال‌های ناشناخته و اندیس‌های تکراری یا خارج از محدوده دور
+ریخته می‌شوند تا هر بخش یک بار شمرده شود
+*/
+func receiveFilePart(s *session, st net.Conn) {
+	defer st.Close()
+
+	r := bufio.NewReader(st)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var part filePart
+	if err := json.Unmarshal(line, &part); err != nil {
+		return
+	}
+	sink, ok := s.parts.get(part.Transfer)
+	if !ok || !sink.claim(part.Index) {
+		return // Not one we accepted, or a part we already have | انتقالی که پذیرفته نشده یا بخشی که داریم
+	}
+	sink.done <- receiveRange(s, sink, part.Index, s.sched.wrap(st, prioControl), r) // Room for every part | جا برای همه‌ی بخش‌ها
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"         // For command output
+	"strings"     // For joining the away reply
+	"sync"        // For presence shared between goroutines
+	"sync/atomic" // For the session of the current link
+	"time"        // For the auto-reply rate limit
+)
+
+/*
+Presence states
+
+وضعیت‌های حضور:
+- online: در دسترس
+- away: دور از سیستم (پاسخ خودکار فعال است)
+- idle: بدون فعالیت صفحه‌کلید برای مدتی
+*/
+const (
+	presenceOnline = "online" // Available | در دسترس
+	presenceAway   = "away"   // Away, auto-replies on | دور از سیستم، پاسخ خودکار فعال
+	presenceIdle   = "idle"   // No keystrokes for a while | بدون فعالیت صفحه‌کلید
+
+	ctrlPresence = "presence" // Presence change frame | فریم تغییر وضعیت حضور
+
+	awayReplyEvery = time.Minute // At most one auto-reply per interval | حداکثر یک پاسخ خودکار در هر بازه
+)
+
+func init() {
+	registerCommand("away", "/away [reply]  mark yourself away; the first message from each peer gets an auto-reply", awayCommand)
+	registerCommand("back", "/back  mark yourself online again", backCommand)
+	registerCommand("status", `/status ["message"]  set or clear your status message`, statusCommand)
+	registerCommand("who", "/who  show who is in the chat and their status", whoCommand)
+}
+
+/*
+presence is our own presence state and status message, the remote's
+last announced ones, and the bookkeeping for away auto-replies.
+
+این نوع وضعیت حضور و پیام وضعیت ما، آخرین موارد اعلام‌شده‌ی طرف مقابل
+و اطلاعات پاسخ‌های خودکار حالت away را نگه می‌دارد
+*/
+type presence struct {
+	mu         sync.Mutex
+	state      string          // Our state | وضعیت ما
+	note       string          // Our status message | پیام وضعیت ما
+	remote     string          // Remote's state | وضعیت طرف مقابل
+	remoteNote string          // Remote's status message | پیام وضعیت طرف مقابل
+	remoteName string          // Remote's nick from its login | نام طرف مقابل
+	reply      string          // Away auto-reply text | متن پاسخ خودکار
+	defReply   string          // Configured auto-reply | پاسخ خودکار پیکربندی‌شده
+	replied    map[string]bool // Peers already answered while away | peerهایی که پاسخ گرفته‌اند
+	lastReply  time.Time       // Last auto-reply sent | زمان آخرین پاسخ خودکار
+}
+
+// newPresence starts online with the configured away reply | ساخت وضعیت حضور
+func newPresence(awayReply string) *presence {
+	return &presence{state: presenceOnline, remote: presenceOnline, defReply: awayReply}
+}
+
+// set changes our state and returns the frame announcing it | تغییر وضعیت و ساخت فریم اعلام آن
+func (p *presence) set(state, reply string) controlFrame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state, p.reply = state, reply
+	p.replied = make(map[string]bool) // Everyone may get one reply per away spell | هر بار away از نو
+	return controlFrame{Type: ctrlPresence, Text: p.state, Note: p.note}
+}
+
+// setIdle moves between online and idle, leaving away alone | جابه‌جایی بین online و idle بدون تغییر away
+func (p *presence) setIdle(idle bool) (controlFrame, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case idle && p.state == presenceOnline:
+		p.state = presenceIdle
+	case !idle && p.state == presenceIdle:
+		p.state = presenceOnline
+	default:
+		return controlFrame{}, false
+	}
+	return controlFrame{Type: ctrlPresence, Text: p.state, Note: p.note}, true
+}
+
+/*
+trackIdle turns idle after the given time without keystrokes and back
+online on the next keystroke, announcing each change to the remote of
+the current link.
+
+این تابع پس از مدت داده‌شده بدون فشردن کلید وضعیت را idle و با
+اولین کلید دوباره online می‌کند و هر تغییر را به طرف مقابل در اتصال فعلی
+اعلام می‌کند
+*/
+func trackIdle(live *atomic.Pointer[session], c *console, after time.Duration, done <-chan struct{}) {
+	c.onKey = func() {
+		s := live.Load()
+		if f, changed := s.presence.setIdle(false); changed {
+			s.ctrl.send(f)
+		}
+	}
+	go func() {
+		tick := time.NewTicker(max(after/10, time.Second))
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick.C:
+				if c.idleFor() < after {
+					continue
+				}
+				s := live.Load()
+				if f, changed := s.presence.setIdle(true); changed {
+					s.ctrl.send(f)
+				}
+			}
+		}
+	}()
+}
+
+// setNote changes our status message and returns the frame announcing it | تغییر پیام وضعیت
+func (p *presence) setNote(note string) controlFrame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.note = note
+	return controlFrame{Type: ctrlPresence, Text: p.state, Note: p.note}
+}
+
+// peerName returns the nick the remote logged in with, if any | نام طرف مقابل
+func (p *presence) peerName() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.remoteName
+}
+
+// setRemoteName records the nick the remote logged in with | ثبت نام طرف مقابل
+func (p *presence) setRemoteName(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.remoteName = name
+}
+
+/*
+autoReply returns the away reply owed to m, or "": only while away, only
+once per sender key or name, never to another auto-reply, and at most
+once per awayReplyEvery so two away peers cannot keep answering each
+other.
+
+این تابع پاسخ خودکار مربوط به m یا رشته‌ی خالی را برمی‌گرداند: فقط در
+حالت away، یک بار برای هر فرستنده، هرگز به پاسخ خودکار دیگر و حداکثر
+یک بار در هر awayReplyEvery تا دو peer دور از سیستم مدام به هم پاسخ ندهند
+*/
+func (p *presence) autoReply(m message) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != presenceAway || p.reply == "" || m.Auto {
+		return ""
+	}
+	who := m.Key
+	if who == "" {
+		who = m.From
+	}
+	if p.replied[who] || time.Since(p.lastReply) < awayReplyEvery {
+		return ""
+	}
+	p.replied[who] = true
+	p.lastReply = time.Now()
+	return p.reply
+}
+
+// handlePresenceFrames shows the remote's presence and status changes | نمایش تغییر وضعیت طرف مقابل
+func handlePresenceFrames(s *session) {
+	s.ctrl.handle(ctrlPresence, func(f controlFrame) {
+		p := s.presence
+		p.mu.Lock()
+		changed := p.remote != f.Text
+		noted := p.remoteNote != f.Note
+		p.remote, p.remoteNote = f.Text, f.Note
+		name := p.remoteName
+		p.mu.Unlock()
+		if name == "" {
+			name = "Remote"
+		}
+		if changed {
+			fmt.Fprintln(s.status, name, "is now", f.Text)
+		}
+		if noted {
+			fmt.Fprintln(s.status, presenceLine(name, f.Text, f.Note))
+		}
+	})
+}
+
+// presenceLine renders a nick with its state and status message | نمایش نام با وضعیت و پیام وضعیت
+func presenceLine(name, state, note string) string {
+	line := name + " (" + state + ")"
+	if note != "" {
+		line += ` "` + note + `"`
+	}
+	return line
+}
+
+// awayCommand marks us away, with the given or configured auto-reply | رفتن به حالت away
+func awayCommand(s *session, args []string) {
+	reply := strings.Join(args, " ")
+	if reply == "" {
+		reply = s.presence.defReply
+	}
+	s.ctrl.send(s.presence.set(presenceAway, reply))
+	if reply == "" {
+		fmt.Fprintln(stdout, "You are away")
+		return
+	}
+	fmt.Fprintln(stdout, "You are away; auto-reply:", reply)
+}
+
+// statusCommand sets our status message, or clears it without arguments | تنظیم یا پاک کردن پیام وضعیت
+func statusCommand(s *session, args []string) {
+	note := strings.Trim(strings.Join(args, " "), `"`)
+	s.ctrl.send(s.presence.setNote(note))
+	if note == "" {
+		fmt.Fprintln(stdout, "Status message cleared")
+		return
+	}
+	fmt.Fprintln(stdout, "Status message:", note)
+}
+
+/*
+whoCommand lists both ends of the chat with presence and status, then
+the other known peers with when they were last seen.
+
+این دستور دو طرف گفتگو را با وضعیت حضور و پیام وضعیت و سپس دیگر
+peerهای شناخته‌شده را با زمان آخرین حضورشان فهرست می‌کند
+*/
+func whoCommand(s *session, _ []string) {
+	p := s.presence
+	p.mu.Lock()
+	remote := p.remoteName
+	fmt.Fprintln(stdout, "  "+presenceLine(s.name+" [you]", p.state, p.note))
+	if remote == "" {
+		fmt.Fprintln(stdout, "  "+presenceLine(s.conn.RemoteAddr().String(), p.remote, p.remoteNote)) // Not logged in yet | هنوز وارد نشده
+	} else {
+		fmt.Fprintln(stdout, "  "+presenceLine(s.roster.name(s.conn.remoteKey, remote), p.remote, p.remoteNote))
+	}
+	p.mu.Unlock()
+
+	for _, nick := range s.seen.recent() {
+		if nick != remote && nick != s.name {
+			fmt.Fprintf(stdout, "  %s (offline, %s)\n", nick, s.seen.describe(nick))
+		}
+	}
+}
+
+// backCommand marks us online | بازگشت به حالت online
+func backCommand(s *session, _ []string) {
+	s.ctrl.send(s.presence.set(presenceOnline, ""))
+	fmt.Fprintln(stdout, "You are online")
+}
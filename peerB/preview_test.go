@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCleanText(t *testing.T) {
+	for in, want := range map[string]string{
+		"  Hello &amp; welcome  ":           "Hello & welcome",
+		"a\n\tb":                            "a b",
+		"&#27;]52;c;ZXZpbA==&#7;title":      "]52;c;ZXZpbA==title",
+		"x\x1b[2Jy":                         "x[2Jy",
+		"\u009b31mred":                      "31mred",
+		"caf&eacute;":                       "café",
+		strings.Repeat("\x00", 3) + "quiet": "quiet",
+	} {
+		if got := cleanText(in); got != want {
+			t.Errorf("cleanText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	} {
+		if got := publicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("publicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckPreviewURL(t *testing.T) {
+	for link, ok := range map[string]bool{
+		"https://example.org/a":              true,
+		"http://169.254.169.254/latest/meta": false,
+		"http://[::1]:8090/metrics":          false,
+		"http://192.168.0.1/":                false,
+		"file:///etc/passwd":                 false,
+		"gopher://example.org/":              false,
+	} {
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkPreviewURL(u); (err == nil) != ok {
+			t.Errorf("checkPreviewURL(%s) = %v, want ok %v", link, err, ok)
+		}
+	}
+}
+
+func TestPreviewRefusesLocalServers(t *testing.T) {
+	fetched := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<title>internal</title>"))
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	for _, link := range []string{srv.URL, "http://localhost:" + port + "/"} { // Literal, then a name resolved to it | آدرس صریح و سپس نامی که به آن می‌رسد
+		if _, _, err := fetchPreview(link); !errors.Is(err, errPreviewAddress) {
+			t.Errorf("fetchPreview(%s) = %v, want %v", link, err, errPreviewAddress)
+		}
+	}
+	if fetched {
+		t.Error("the local server was requested")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net"  // For the wrapped streams
+	"sync" // For the scheduler state
+)
+
+/*
+Outgoing traffic classes, most urgent first
+
+کلاس‌های ترافیک خروجی، از فوری‌ترین:
+- control: ضربان قلب، تأییدها و اعلام پنجره
+- chat: متن چت
+- bulk: تکه‌های انتقال فایل
+*/
+const (
+	prioControl = iota
+	prioChat
+	prioBulk
+	prioClasses
+)
+
+/*
+linkScheduler orders stream writes by class: a write waits while a more
+urgent one is queued or still on its way, so control frames go before
+chat text and chat text before file chunks. It never holds the link
+for a write: a yamux write can block for as long as its stream has no
+send window, and one stalled file chunk must not keep heartbeats off
+the link into a spurious disconnect. So only the lower classes are
+throttled, and a control write never waits at all.
+
+این نوع نوشتن‌های stream را بر اساس کلاس مرتب می‌کند: هر نوشتن تا وقتی
+نوشتن فوری‌تری در صف یا در راه باشد منتظر می‌ماند، پس فریم‌های کنترلی پیش
+از متن چت و متن چت پیش از تکه‌های فایل می‌روند. اتصال را برای هیچ نوشتنی
+در اختیار نمی‌گیرد: نوشتن در yamux تا وقتی stream پنجره‌ی ارسال نداشته
+باشد مسدود می‌ماند و یک تکه‌ی فایل معطل نباید ضربان قلب را از اتصال دور
+نگه دارد و باعث قطع بی‌دلیل شود. پس فقط کلاس‌های پایین‌تر محدود می‌شوند و
+نوشتن کنترلی هرگز منتظر نمی‌ماند
+*/
+type linkScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	active  [prioClasses]int // Writes under way per class | نوشتن‌های در جریان هر کلاس
+	waiting [prioClasses]int // Writes queued per class | نوشتن‌های منتظر هر کلاس
+}
+
+// newLinkScheduler creates an idle scheduler | ساخت زمان‌بند بیکار
+func newLinkScheduler() *linkScheduler {
+	p := &linkScheduler{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// acquire waits for every more urgent write, queued or under way | انتظار برای نوشتن‌های فوری‌تر در صف یا در جریان
+func (p *linkScheduler) acquire(class int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.waiting[class]++
+	for p.urgent(class) {
+		p.cond.Wait()
+	}
+	p.waiting[class]--
+	p.active[class]++
+}
+
+// urgent reports whether a more urgent class is queued or writing | آیا کلاس فوری‌تری در صف یا در حال نوشتن است
+func (p *linkScheduler) urgent(class int) bool {
+	for c := range class {
+		if p.waiting[c] > 0 || p.active[c] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// release ends a write of class and wakes the ones it held back | پایان نوشتن و بیدارکردن نوشتن‌های منتظر
+func (p *linkScheduler) release(class int) {
+	p.mu.Lock()
+	p.active[class]--
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+/*
+wrap returns st with every Write scheduled in class; reads and
+deadlines pass straight through.
+
+این تابع st را طوری برمی‌گرداند که هر Write در کلاس class زمان‌بندی شود؛
+خواندن و deadlineها مستقیم عبور می‌کنند
+*/
+func (p *linkScheduler) wrap(st net.Conn, class int) net.Conn {
+	return scheduledConn{Conn: st, sched: p, class: class}
+}
+
+// scheduledConn is a stream whose writes wait their turn | streamی که نوشتن‌هایش منتظر نوبت می‌مانند
+type scheduledConn struct {
+	net.Conn
+	sched *linkScheduler
+	class int
+}
+
+func (c scheduledConn) Write(b []byte) (int, error) {
+	c.sched.acquire(c.class)
+	defer c.sched.release(c.class)
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestControlWriteSkipsStalledBulk(t *testing.T) {
+	client, server, err := benchLink("pipe", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+	cs, ss, err := benchMux(client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	defer ss.Close()
+
+	done := newDoneSignal()
+	defer done.close()
+	lines := make(chan string, 2)
+	go acceptStreams(ss, map[string]func(net.Conn){
+		streamFile: func(net.Conn) { <-done.c }, // Never read, so its window runs out | هرگز خوانده نمی‌شود تا پنجره‌اش تمام شود
+		streamControl: func(st net.Conn) {
+			sc := bufio.NewScanner(st)
+			for sc.Scan() {
+				lines <- sc.Text()
+			}
+		},
+	}, done)
+
+	sched := newLinkScheduler()
+	bulk, err := openStream(cs, streamFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := openStream(cs, streamControl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stalled := make(chan error, 1)
+	go func() {
+		_, err := sched.wrap(bulk, prioBulk).Write(make([]byte, 4<<20)) // Far past the initial window | بسیار بیشتر از پنجره‌ی اولیه
+		stalled <- err
+	}()
+	time.Sleep(100 * time.Millisecond) // Let the bulk write run dry | فرصت برای تمام‌شدن پنجره
+	select {
+	case err := <-stalled:
+		t.Fatalf("bulk write finished without a window: %v", err)
+	default:
+	}
+
+	wrote := make(chan error, 1)
+	go func() {
+		_, err := sched.wrap(ctrl, prioControl).Write([]byte("ping\n"))
+		wrote <- err
+	}()
+	select {
+	case err := <-wrote:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("control write waited for the stalled bulk write")
+	}
+	select {
+	case line := <-lines:
+		if line != "ping" {
+			t.Fatalf("control stream got %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("control frame never arrived")
+	}
+}
+
+func TestLowerClassWaitsForUrgentWrite(t *testing.T) {
+	sched := newLinkScheduler()
+	sched.acquire(prioChat)
+	started := make(chan struct{})
+	go func() {
+		sched.acquire(prioBulk)
+		close(started)
+		sched.release(prioBulk)
+	}()
+	select {
+	case <-started:
+		t.Fatal("bulk write started during a chat write")
+	case <-time.After(50 * time.Millisecond):
+	}
+	sched.acquire(prioControl) // Never held back | هرگز معطل نمی‌شود
+	sched.release(prioControl)
+	sched.release(prioChat)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("bulk write never started")
+	}
+}
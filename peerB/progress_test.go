@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransferProgress(t *testing.T) {
+	table := &transferTable{active: make(map[int]*transferProgress)}
+	send := table.start(io.Discard, directionSend, "big.iso", 4<<20)
+	recv := table.start(io.Discard, directionRecv, "notes.txt", 1000)
+	send.Started = time.Now().Add(-2 * time.Second)
+	_, _ = send.Write(make([]byte, 1<<20))
+
+	if got := send.String(); !strings.HasPrefix(got, "Sending big.iso: 25% of 4.0 MiB, 51") || !strings.HasSuffix(got, " KiB/s, ETA 6s") {
+		t.Errorf("send line %q", got)
+	}
+	if got := recv.String(); got != "Receiving notes.txt: 0% of 1000 B" {
+		t.Errorf("receive line %q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	table.serveTransfers(rec, httptest.NewRequest("GET", "/transfers", nil))
+	var states []transferState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 2 || states[0].Name != "big.iso" || states[0].Done != 1<<20 || states[1].Direction != directionRecv {
+		t.Errorf("/transfers %+v", states)
+	}
+
+	_, _ = recv.Write(make([]byte, 1000))
+	table.finish(send)
+	table.finish(recv)
+	if len(table.list()) != 0 || table.sent.Load() != 1<<20 || table.recv.Load() != 1000 {
+		t.Errorf("after finishing: %d in flight, %d sent, %d received", len(table.list()), table.sent.Load(), table.recv.Load())
+	}
+	rec = httptest.NewRecorder()
+	table.serveTransfers(rec, httptest.NewRequest("GET", "/transfers", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("/transfers with nothing in flight: %s", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := percent(0, 0); got != 100 {
+		t.Errorf("an empty file is %d%% done, want 100", got)
+	}
+}
+
+func TestTransferHold(t *testing.T) {
+	table := &transferTable{active: make(map[int]*transferProgress)}
+	send := table.start(io.Discard, directionSend, "big.iso", 100)
+	recv := table.start(io.Discard, directionRecv, "notes.txt", 100)
+	defer table.finish(send)
+	defer table.finish(recv)
+	for _, c := range []struct {
+		id    int
+		pause bool
+		want  error
+	}{
+		{99, true, errNoTransfer},
+		{recv.ID, true, errPauseRecv},
+		{send.ID, false, errNotPaused},
+		{send.ID, true, nil},
+		{send.ID, true, errAlreadyHeld},
+	} {
+		if err := table.hold(c.id, c.pause); err != c.want {
+			t.Errorf("hold(%d, %v) = %v, want %v", c.id, c.pause, err, c.want)
+		}
+	}
+	if got := send.String(); got != "Sending big.iso: 0% of 100 B, paused" || !send.state().Paused {
+		t.Errorf("paused send shows %q", got)
+	}
+
+	wrote := make(chan struct{})
+	go func() {
+		_, _ = send.Write(make([]byte, 10))
+		close(wrote)
+	}()
+	select {
+	case <-wrote:
+		t.Fatal("a paused send went on writing")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := table.hold(send.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-wrote:
+	case <-time.After(time.Second):
+		t.Fatal("a resumed send stayed held")
+	}
+	if send.done.Load() != 10 || send.paused() {
+		t.Errorf("after resuming: %d bytes done, paused %v", send.done.Load(), send.paused())
+	}
+
+	ended := make(chan struct{})
+	send.ended = ended
+	_ = table.hold(send.ID, true)
+	go close(ended)
+	if _, err := send.Write(make([]byte, 10)); err != errClosed {
+		t.Errorf("a paused send whose link ended: %v, want %v", err, errClosed)
+	}
+}
+
+func TestTransferCommand(t *testing.T) {
+	defer func(old *transferTable) { transfers = old }(transfers)
+	transfers = &transferTable{active: make(map[int]*transferProgress)}
+	var out strings.Builder
+	defer stdout.redirect(stdout.redirect(&out))
+
+	transferCommand(nil, nil)
+	p := transfers.start(io.Discard, directionSend, "big.iso", 100)
+	defer transfers.finish(p)
+	transferCommand(nil, []string{"pause", "#1"})
+	transferCommand(nil, []string{"list"})
+	transferCommand(nil, []string{"resume", "1"})
+	transferCommand(nil, []string{"resume", "1"})
+	transferCommand(nil, []string{"stop", "1"})
+	want := "No transfers in flight\n" +
+		"Transfer #1 paused\n" +
+		"  #1 Sending big.iso: 0% of 100 B, paused\n" +
+		"Transfer #1 resumed\n" +
+		"Transfer error: transfer is not paused\n" +
+		"Usage: /transfer [list] | /transfer pause|resume <id>\n"
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json" // For the recent connections file
+	"errors"        // For a missing file and error values
+	"fmt"           // For the listing and the prompt
+	"os"            // For the file and the prompt's terminal
+	"path/filepath" // For creating the data directory
+	"strings"       // For reading the answer
+	"time"          // For connection times
+
+	"golang.org/x/term" // For asking only on a terminal
+)
+
+const recentMax = 10 // Connections kept, newest first | تعداد اتصال‌های نگه‌داشته، از جدیدترین
+
+var errNoRecent = errors.New("no recent connection to dial") // Nothing with an address yet | هنوز اتصالی با آدرس نیست
+
+/*
+recentConn is one past link: where we dialed it (empty for an incoming
+link from a peer with no address on file), where it came from, the key
+it proved and the nick it logged in with.
+
+این نوع یک اتصال گذشته است: آدرسی که به آن وصل شدیم (برای اتصال ورودی از
+peerی بدون آدرس ثبت‌شده خالی است)، مبدأ آن، کلید اثبات‌شده و نامی که با آن
+وارد شد
+*/
+type recentConn struct {
+	Address string    `json:"address,omitempty"`
+	Remote  string    `json:"remote"`
+	Key     string    `json:"key,omitempty"`
+	Nick    string    `json:"nick,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// defaultRecentPath returns the per-name recent connections file | مسیر پیش‌فرض فایل اتصال‌های اخیر
+func defaultRecentPath(name string) string {
+	return dataPath(name + ".recent")
+}
+
+/*
+loadRecent reads the recent connections kept next to the roster; a
+missing file is an empty list and an empty path keeps it in memory.
+
+این تابع اتصال‌های اخیر نگه‌داشته‌شده کنار فهرست دوستان را می‌خواند؛ نبود
+فایل یعنی فهرست خالی و path خالی یعنی نگهداری فقط در حافظه
+*/
+func (r *roster) loadRecent(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recentPath = path
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &r.recent); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// saveRecent writes the recent connections; the caller holds mu | ذخیره اتصال‌های اخیر (mu باید گرفته شده باشد)
+func (r *roster) saveRecent() error {
+	if r.recentPath == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if err := os.MkdirAll(filepath.Dir(r.recentPath), 0o700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(r.recent, "", "  ") // Plain structs cannot fail | ساختار ساده خطا نمی‌دهد
+	return os.WriteFile(r.recentPath, append(data, '\n'), 0o600)
+}
+
+/*
+remember puts a new link at the top of the recent connections. An
+incoming link borrows the address filed for its key, if any, so it can
+be dialed later.
+
+این تابع اتصال جدید را در ابتدای اتصال‌های اخیر قرار می‌دهد؛ اتصال ورودی
+آدرس ثبت‌شده برای کلیدش را در صورت وجود می‌گیرد تا بعداً بتوان به آن وصل شد
+*/
+func (r *roster) remember(addr, remote, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.byKey(key); addr == "" && e != nil {
+		addr = e.Address
+	}
+	c := recentConn{Address: addr, Remote: remote, Key: key, Time: time.Now()}
+	r.recent = append([]recentConn{c}, r.recent...)
+	if len(r.recent) > recentMax {
+		r.recent = r.recent[:recentMax]
+	}
+	if err := r.saveRecent(); err != nil {
+		fmt.Fprintln(stdout, "Recent error:", err)
+	}
+}
+
+// named records the nick the current link logged in with | ثبت نام ورود اتصال فعلی
+func (r *roster) named(nick string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.recent) == 0 || r.recent[0].Nick == nick {
+		return
+	}
+	r.recent[0].Nick = nick
+	if err := r.saveRecent(); err != nil {
+		fmt.Fprintln(stdout, "Recent error:", err)
+	}
+}
+
+// last returns the newest connection that can be dialed | جدیدترین اتصال قابل dial
+func (r *roster) last() (recentConn, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.recent {
+		if c.Address != "" {
+			return c, true
+		}
+	}
+	return recentConn{}, false
+}
+
+// recentList returns the recent connections, newest first | اتصال‌های اخیر از جدیدترین
+func (r *roster) recentList() []recentConn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recentConn(nil), r.recent...)
+}
+
+// label names a recent connection by display alias, nick or key | نام اتصال اخیر با نام نمایشی، نام یا کلید
+func (r *roster) label(c recentConn) string {
+	switch {
+	case c.Key != "":
+		nick := c.Nick
+		if nick == "" {
+			nick = c.Key
+		}
+		return r.name(c.Key, nick)
+	case c.Nick != "":
+		return c.Nick
+	}
+	return c.Remote
+}
+
+/*
+offerLast asks on the terminal whether to dial the last peer instead of
+target, and returns the address to dial. Nothing is asked when the last
+peer is target already or stdin is not a terminal.
+
+این تابع روی ترمینال می‌پرسد که آیا به‌جای target به آخرین peer وصل شود و
+آدرس مقصد را برمی‌گرداند؛ اگر آخرین peer همان target باشد یا ورودی ترمینال
+نباشد چیزی پرسیده نمی‌شود
+*/
+func (r *roster) offerLast(target string) string {
+	c, ok := r.last()
+	if !ok || c.Address == target || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return target
+	}
+	fmt.Fprintf(stdout, "Last peer: %s at %s, %s. Dial it instead of %s? [y/N] ", r.label(c), c.Address, formatAgo(time.Since(c.Time)), target)
+	buf := make([]byte, 64) // One line, unbuffered so the editor gets the rest | یک خط، بدون بافر تا بقیه به ویرایشگر برسد
+	n, _ := os.Stdin.Read(buf)
+	if answer := strings.ToLower(strings.TrimSpace(string(buf[:n]))); answer == "y" || answer == "yes" {
+		return c.Address
+	}
+	return target
+}
+
+/*
+recentCommand lists the recent connections, newest first, with where
+each can be dialed.
+
+این دستور اتصال‌های اخیر را از جدیدترین همراه آدرس قابل dial هر کدام
+فهرست می‌کند
+*/
+func recentCommand(s *session, _ []string) {
+	list := s.roster.recentList()
+	if len(list) == 0 {
+		fmt.Fprintln(stdout, "No recent connections")
+	}
+	for _, c := range list {
+		where := c.Address
+		if where == "" {
+			where = "incoming from " + c.Remote
+		}
+		fmt.Fprintf(stdout, "  %s  %s  %s\n", s.roster.label(c), where, formatAgo(time.Since(c.Time)))
+	}
+}
+
+func init() {
+	registerCommand("recent", "/recent  list the recent connections", recentCommand)
+}
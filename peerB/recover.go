@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"           // For the notice
+	"runtime/debug" // For the panicking goroutine's stack
+	"sync/atomic"   // For the process-wide panic counter
+	"time"          // For the report timestamp
+)
+
+// recoveredPanics counts panics turned into reports, for the metrics | تعداد panicهای بازیابی‌شده
+var recoveredPanics atomic.Int64
+
+/*
+panicReport is the structured record of a recovered panic, saved in
+the crash dump so it can be attached to a bug report.
+
+این نوع رکورد ساختاریافته‌ی یک panic بازیابی‌شده است که در گزارش خرابی
+ذخیره می‌شود تا به گزارش خطا پیوست شود
+*/
+type panicReport struct {
+	Time      time.Time `json:"time"`      // When it happened | زمان وقوع
+	Goroutine string    `json:"goroutine"` // Which long-running goroutine | کدام goroutine
+	Panic     string    `json:"panic"`     // The panic value | مقدار panic
+	Version   string    `json:"version"`   // Build version | نسخه‌ی build
+	Stack     string    `json:"stack"`     // Stack of the panicking goroutine | stack همان goroutine
+}
+
+/*
+goSafe runs fn in a goroutine that recovers from a panic instead of the
+whole process crashing: the panic is saved as a panicReport, announced
+as an error event, and done is closed for a redial. For a goroutine of
+the link, done is the link's, so run tears the link down and dials
+again; for typed input it is the run's, which then shuts down cleanly
+(restoring the terminal and closing files). Handlers of side streams
+recover on their own in dispatchStream.
+
+این تابع fn را در goroutineی اجرا می‌کند که به‌جای از کار افتادن کل
+برنامه panic را بازیابی می‌کند: panic به‌صورت panicReport ذخیره، به‌صورت رویداد
+error اعلام و done برای dial دوباره بسته می‌شود. برای goroutineهای اتصال، done
+مال همان اتصال است، پس run اتصال را می‌بندد و دوباره dial می‌کند؛ برای ورودی
+تایپ‌شده done مال کل اجراست که به‌شکل امن تمام می‌شود (بازگردانی ترمینال و بستن
+فایل‌ها). handlerهای streamهای جانبی در dispatchStream خودشان بازیابی می‌شوند
+*/
+func goSafe(name string, done *doneSignal, fn func()) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				reportPanic(name, v)
+				done.redial()
+			}
+		}()
+		fn()
+	}()
+}
+
+/*
+reportPanic saves and announces a recovered panic: printed, logged,
+written to the -output json stream and sent to /events subscribers as
+an error event.
+
+این تابع panic بازیابی‌شده را ذخیره و اعلام می‌کند: چاپ، ثبت در لاگ،
+نوشتن در خروجی -output json و ارسال برای مشترکان /events به‌صورت رویداد error
+*/
+func reportPanic(name string, v any) {
+	recoveredPanics.Add(1)
+	r := panicReport{Time: time.Now(), Goroutine: name, Panic: fmt.Sprint(v), Version: version, Stack: string(debug.Stack())}
+	path, err := writeCrashDump("panic in "+name, &r)
+	if err != nil {
+		fmt.Fprintln(stdout, "Internal error in", name+":", r.Panic, "(crash dump not saved:", err.Error()+")")
+		oplog.logf(priCrit, "Internal error in %s; crash dump not saved: %v", name, err)
+	} else {
+		fmt.Fprintln(stdout, "Internal error in", name+":", r.Panic, "- crash dump saved to", path)
+		oplog.logf(priCrit, "Internal error in %s; crash dump saved to %s", name, path)
+	}
+	ev := outputEvent{Time: r.Time, Event: eventError, Goroutine: name, Error: r.Panic, Dump: path}
+	emitEvent(ev)           // For -output json | برای -output json
+	events.publishEvent(ev) // For /events subscribers | برای مشترکان /events
+}
+
+/*
+crashOnPanic is deferred at the top of main: a panic outside the
+session goroutines still leaves a crash dump before the process dies.
+
+این تابع در ابتدای main با defer اجرا می‌شود تا panic بیرون از
+goroutineهای نشست هم پیش از پایان برنامه گزارش خرابی بنویسد
+*/
+func crashOnPanic() {
+	if v := recover(); v != nil {
+		reportPanic("main", v)
+		panic(v)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryBindsNickToKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ann.known_peers")
+	r, err := loadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.check("bob", "key1") {
+		t.Fatal("first use of a nick was refused")
+	}
+	if !r.check("bob", "key1") || r.check("bob", "key2") {
+		t.Fatal("a nick is not held for its first key")
+	}
+	if !r.check("carol", "key2") {
+		t.Fatal("one key cannot hold two nicks")
+	}
+
+	r, err = loadRegistry(path) // Bindings outlive a restart | ثبت‌ها پس از راه‌اندازی دوباره می‌مانند
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.check("bob", "key2") || len(r.nicks()) != 2 {
+		t.Fatalf("reloaded nicks %v without bob's binding", r.nicks())
+	}
+	if err := r.forget("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = loadRegistry(path); !r.check("bob", "key2") {
+		t.Fatal("a forgotten nick is still held for its old key")
+	}
+}
+
+func TestLoginFrames(t *testing.T) {
+	newSession := func(keys *registry, remoteKey string) *session {
+		done := newDoneSignal()
+		t.Cleanup(done.close)
+		seen, _ := loadLastSeen("")
+		roster, _ := loadRoster("")
+		s := &session{ctrl: newControlLink(done), done: done, keys: keys, seen: seen, roster: roster, presence: newPresence(""),
+			conn: &handshakeConn{remoteKey: remoteKey}, status: io.Discard}
+		handleLoginFrames(s)
+		return s
+	}
+	keys, _ := loadRegistry("")
+	for _, c := range []struct {
+		key  string
+		want controlFrame
+	}{
+		{"key1", controlFrame{Type: ctrlWelcome, Text: "registered as bob"}},
+		{"key1", controlFrame{Type: ctrlWelcome, Text: "welcome back, bob"}},
+		{"key2", controlFrame{Type: ctrlKick, Text: "nickname bob is registered to another key"}},
+	} {
+		s := newSession(keys, c.key)
+		s.ctrl.handlers[ctrlLogin](controlFrame{Type: ctrlLogin, Text: "bob"})
+		if got := <-s.ctrl.out; got != c.want {
+			t.Errorf("login as bob with %s: sent %+v, want %+v", c.key, got, c.want)
+		}
+		if s.presence.peerName() != "bob" {
+			t.Errorf("login as bob with %s: /who shows %q", c.key, s.presence.peerName())
+		}
+	}
+
+	s := newSession(keys, "") // Nothing proven, nothing bound | چیزی اثبات نشده، چیزی ثبت نمی‌شود
+	s.ctrl.handlers[ctrlLogin](controlFrame{Type: ctrlLogin, Text: "dave"})
+	if len(s.ctrl.out) != 0 || !keys.check("dave", "key3") {
+		t.Error("a login without a proven key was bound or answered")
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/hmac"   // For matching a ticket proof
+	"crypto/rand"   // For issuing tickets
+	"encoding/hex"  // For tickets and proofs as text
+	"encoding/json" // For the tickets file
+	"errors"        // For a missing file
+	"fmt"           // For file errors
+	"io"            // For where errors are printed
+	"os"            // For the tickets file
+	"path/filepath" // For creating the data directory
+	"sync"          // For handshakes running at once
+	"time"          // For the resumption window
+)
+
+const (
+	resumeWindow = 10 * time.Minute // How long after a link a ticket still works | مدت اعتبار ticket پس از اتصال
+	proofTicket  = "ticket"         // What a ticket proof is made for | کاربرد اثبات ticket
+)
+
+/*
+resumeTicket is a secret one side hands the other at the end of a
+handshake. Presented again within resumeWindow, by the same key, it
+lets that key back in without the password, invite or token check.
+The window starts when the link ends; until then Expires is zero.
+A ticket we issued also records the access policy it was issued under
+and the last frame numbers of its link, so a resumed link carries on
+numbering where the old one stopped.
+
+این نوع رازی است که یک طرف در پایان handshake به طرف دیگر می‌دهد؛ اگر
+همان کلید آن را در مدت resumeWindow دوباره ارائه کند بدون بررسی رمز،
+دعوت یا token دوباره وارد می‌شود؛ مهلت از پایان اتصال شروع می‌شود و تا
+آن زمان Expires صفر است. ticketی که صادر کرده‌ایم سیاست دسترسی زمان صدور
+و آخرین شماره‌ی فریم‌های اتصالش را هم نگه می‌دارد تا اتصال ازسرگرفته
+شماره‌گذاری را از همان جا ادامه دهد
+*/
+type resumeTicket struct {
+	Ticket  string    `json:"ticket"`
+	Expires time.Time `json:"expires"`
+	Policy  string    `json:"policy,omitempty"` // Access mode and password hash at issue | حالت دسترسی و hash رمز هنگام صدور
+	Sent    uint64    `json:"sent,omitempty"`   // Last frame number we wrote | آخرین شماره‌ی فریم نوشته‌شده
+	Seen    uint64    `json:"seen,omitempty"`   // Last frame number we read | آخرین شماره‌ی فریم خوانده‌شده
+}
+
+// live reports whether the ticket is inside its window | آیا ticket در مهلت خود است
+func (t resumeTicket) live() bool {
+	return t.Ticket != "" && time.Now().Before(t.Expires)
+}
+
+// expired reports whether the ticket's window has passed | آیا مهلت ticket گذشته است
+func (t resumeTicket) expired() bool {
+	return !t.Expires.IsZero() && !t.live()
+}
+
+/*
+resumeStore keeps the tickets we issued and the ones we hold, each by
+the other side's key fingerprint, in "<name>.resume". An empty path
+keeps them in memory.
+
+این نوع ticketهایی را که صادر کرده‌ایم و ticketهایی را که در دست داریم،
+هر کدام بر اساس fingerprint کلید طرف دیگر، در "<name>.resume" نگه می‌دارد؛
+path خالی یعنی نگهداری فقط در حافظه
+*/
+type resumeStore struct {
+	mu     sync.Mutex
+	path   string
+	Issued map[string]resumeTicket `json:"issued"` // Tickets we accept | ticketهایی که می‌پذیریم
+	Held   map[string]resumeTicket `json:"held"`   // Tickets we present | ticketهایی که ارائه می‌کنیم
+	status io.Writer               // Where save errors are printed | محل چاپ خطاهای ذخیره
+}
+
+// defaultResumePath returns the per-name resumption tickets file | مسیر پیش‌فرض فایل ticketهای ازسرگیری
+func defaultResumePath(name string) string {
+	return dataPath(name + ".resume")
+}
+
+/*
+loadResume reads the tickets file; a missing file is empty. Tickets of
+a link that never ended cleanly have no window and are dropped.
+
+این تابع فایل ticketها را می‌خواند؛ نبود فایل یعنی خالی. ticketهای اتصالی
+که درست پایان نیافته مهلتی ندارند و حذف می‌شوند
+*/
+func loadResume(path string) (*resumeStore, error) {
+	r := &resumeStore{path: path, status: stdout}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, r); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	if r.Issued == nil {
+		r.Issued = make(map[string]resumeTicket)
+	}
+	if r.Held == nil {
+		r.Held = make(map[string]resumeTicket)
+	}
+	for _, m := range []map[string]resumeTicket{r.Issued, r.Held} {
+		for fp, t := range m {
+			if !t.live() {
+				delete(m, fp)
+			}
+		}
+	}
+	return r, nil
+}
+
+// save writes the unexpired tickets; the caller holds mu | ذخیره ticketهای منقضی‌نشده (mu باید گرفته شده باشد)
+func (r *resumeStore) save() {
+	for _, m := range []map[string]resumeTicket{r.Issued, r.Held} {
+		for fp, t := range m {
+			if t.expired() {
+				delete(m, fp)
+			}
+		}
+	}
+	if r.path == "" {
+		return // Memory only | فقط در حافظه
+	}
+	err := os.MkdirAll(filepath.Dir(r.path), 0o700)
+	if err == nil {
+		data, _ := json.MarshalIndent(r, "", "  ") // Plain maps cannot fail | map ساده خطا نمی‌دهد
+		err = os.WriteFile(r.path, append(data, '\n'), 0o600)
+	}
+	if err != nil {
+		fmt.Fprintln(r.status, "Resume error:", err)
+	}
+}
+
+/*
+proof returns what we present for the ticket we hold from the key fp:
+an HMAC over both HELLO lines like the password proof, ours first, or
+noProof.
+
+این تابع چیزی را برمی‌گرداند که برای ticket دریافتی از کلید fp ارائه
+می‌کنیم: مانند اثبات رمز، HMAC روی هر دو خط HELLO با خط ما در ابتدا، یا noProof
+*/
+func (r *resumeStore) proof(fp string, hellos [2]string) string {
+	if r == nil {
+		return noProof
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.Held[fp]
+	if !t.live() {
+		return noProof
+	}
+	return hex.EncodeToString(transcriptMAC(t.Ticket, proofTicket, hellos))
+}
+
+/*
+check reports whether proof over hellos, in the remote's order, matches
+the live ticket issued to fp under the current access policy, and
+returns that ticket without using it up: a resumed candidate may still
+be refused or lose arbitration, and the handshake calls use only once
+the link is kept. A ticket from before the access mode or password
+changed is dropped.
+
+این تابع بررسی می‌کند که proof روی hellos به ترتیب طرف مقابل با ticket
+معتبر صادرشده برای fp تحت سیاست دسترسی فعلی بخواند و آن ticket را بدون مصرف
+برمی‌گرداند: کاندید ازسرگرفته ممکن است هنوز رد شود یا در داوری ببازد و
+handshake فقط پس از نگه‌داشتن اتصال use را صدا می‌زند. ticket مربوط به پیش از
+تغییر حالت دسترسی یا رمز حذف می‌شود
+*/
+func (r *resumeStore) check(fp, proof, policy string, hellos [2]string) (resumeTicket, bool) {
+	got, err := hex.DecodeString(proof)
+	if r == nil || fp == "" || err != nil {
+		return resumeTicket{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.Issued[fp]
+	if ok && t.Policy != policy {
+		delete(r.Issued, fp) // Issued under other rules | صادرشده تحت قوانین دیگر
+		r.save()
+		return resumeTicket{}, false
+	}
+	if !t.live() || !hmac.Equal(got, transcriptMAC(t.Ticket, proofTicket, hellos)) {
+		return resumeTicket{}, false
+	}
+	return t, true
+}
+
+/*
+use uses up the ticket issued to fp that a kept link resumed on and
+reports whether it was still there; of two links racing on one ticket
+only the first gets it.
+
+این تابع ticket صادرشده برای fp را که اتصال نگه‌داشته‌شده با آن ازسر گرفته
+مصرف می‌کند و گزارش می‌دهد که هنوز وجود داشت یا نه؛ از دو اتصالی که با یک
+ticket رقابت می‌کنند فقط اولی آن را می‌گیرد
+*/
+func (r *resumeStore) use(fp, ticket string) bool {
+	if r == nil || fp == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.Issued[fp]; !ok || t.Ticket != ticket {
+		return false
+	}
+	delete(r.Issued, fp)
+	r.save()
+	return true
+}
+
+// issue makes a new ticket for the key fp under policy, replacing any older one | صدور ticket جدید برای کلید fp تحت policy به‌جای ticket قبلی
+func (r *resumeStore) issue(fp, policy string) string {
+	var b [16]byte
+	if r == nil || fp == "" {
+		return ""
+	}
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	ticket := hex.EncodeToString(b[:])
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Issued[fp] = resumeTicket{Ticket: ticket, Policy: policy}
+	r.save()
+	return ticket
+}
+
+// hold keeps the ticket the key fp issued to us | نگه‌داشتن ticketی که کلید fp به ما داده
+func (r *resumeStore) hold(fp, ticket string) {
+	if r == nil || fp == "" || ticket == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Held[fp] = resumeTicket{Ticket: ticket}
+	r.save()
+}
+
+/*
+closed opens the window of both tickets shared with fp when the link
+ends, and records the last frame numbers written and read on it.
+
+این تابع هنگام پایان اتصال مهلت هر دو ticket مشترک با fp را شروع می‌کند
+و آخرین شماره‌ی فریم‌های نوشته‌شده و خوانده‌شده را ثبت می‌کند
+*/
+func (r *resumeStore) closed(fp string, sent, seen uint64) {
+	if r == nil || fp == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.Issued[fp]; ok {
+		t.Sent, t.Seen = sent, seen
+		r.Issued[fp] = t
+	}
+	for _, m := range []map[string]resumeTicket{r.Issued, r.Held} {
+		if t, ok := m[fp]; ok {
+			t.Expires = time.Now().Add(resumeWindow)
+			m[fp] = t
+		}
+	}
+	r.save()
+}
+
+// revoke drops the ticket issued to fp, so the key must pass the checks again | حذف ticket صادرشده برای fp تا کلید دوباره بررسی شود
+func (r *resumeStore) revoke(fp string) {
+	if r == nil || fp == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.Issued[fp]; ok {
+		delete(r.Issued, fp)
+		r.save()
+	}
+}
+
+/*
+resumeFrames returns the frame numbers a link continues after: ours,
+from the ticket we accepted, and the remote's, from the last number it
+announced. We resume reading after the last frame we saw from it, so
+frames lost around the drop show up as a gap; without a record we
+simply continue after its announced number.
+
+این تابع شماره‌ی فریمی را که اتصال پس از آن ادامه می‌دهد برمی‌گرداند:
+مال ما از ticket پذیرفته‌شده و مال طرف مقابل از آخرین شماره‌ی اعلام‌شده؛
+خواندن پس از آخرین فریم دیده‌شده از او ادامه می‌یابد تا فریم‌های گم‌شده
+هنگام قطع به‌صورت شکاف دیده شوند؛ بدون سابقه، پس از شماره‌ی اعلام‌شده ادامه
+می‌دهیم
+*/
+func resumeFrames(t resumeTicket, remoteLast uint64) (sent, seen uint64) {
+	if remoteLast == 0 {
+		return t.Sent, 0 // The remote starts over | طرف مقابل از نو شروع می‌کند
+	}
+	if t.Ticket != "" && t.Seen <= remoteLast {
+		return t.Sent, t.Seen
+	}
+	return t.Sent, remoteLast
+}
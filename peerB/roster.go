@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json" // For the roster file
+	"errors"        // For roster error values
+	"fmt"           // For command output
+	"net"           // For telling addresses from nicks
+	"os"            // For reading and writing the file
+	"path/filepath" // For creating the data directory
+	"sort"          // For a stable listing
+	"strings"       // For joining notes
+	"sync"          // For guarding the entries
+)
+
+var (
+	errRosterNoKey     = errors.New("the remote has not proven a key")   // Nothing to remember it by | چیزی برای شناختن آن نیست
+	errRosterUnknown   = errors.New("not in the roster")                 // No such entry | چنین ورودی‌ای نیست
+	errRosterNoAddress = errors.New("not in the roster with an address") // Nothing to connect to | آدرسی برای اتصال نیست
+)
+
+/*
+rosterEntry is a known peer: the nick we filed it under, the key that
+identifies it, the address we last dialed it at, our own notes and an
+optional display alias shown instead of the nick it announces.
+
+این نوع یک peer شناخته‌شده است: نامی که آن را با آن ثبت کرده‌ایم، کلیدی که
+آن را مشخص می‌کند، آدرسی که آخرین بار به آن وصل شده‌ایم، یادداشت‌های ما و
+نام نمایشی اختیاری که به جای نام اعلام‌شده‌ی آن نشان داده می‌شود
+*/
+type rosterEntry struct {
+	Nick        string `json:"nick"`
+	Fingerprint string `json:"fingerprint"`
+	Address     string `json:"address,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+	Alias       string `json:"alias,omitempty"`
+}
+
+/*
+roster is the persistent buddy list. It also remembers the key and the
+dialed address of the current link, so /roster add can file the remote
+without typing either.
+
+این نوع فهرست دوستان ماندگار است؛ کلید و آدرس اتصال فعلی را هم نگه
+می‌دارد تا /roster add بدون تایپ آن‌ها طرف مقابل را ثبت کند
+*/
+type roster struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*rosterEntry // By nick | بر اساس نام
+	key     string                  // Key of the current link | کلید اتصال فعلی
+	addr    string                  // Dialed address of the current link | آدرس dial اتصال فعلی
+
+	recentPath string       // Recent connections file | فایل اتصال‌های اخیر
+	recent     []recentConn // Newest first | از جدیدترین
+}
+
+// defaultRosterPath returns the per-name roster file | مسیر پیش‌فرض فایل فهرست دوستان
+func defaultRosterPath(name string) string {
+	return dataPath(name + ".roster")
+}
+
+/*
+loadRoster reads the roster file; a missing file is an empty roster and
+an empty path keeps it in memory only.
+
+این تابع فایل فهرست دوستان را می‌خواند؛ نبود فایل یعنی فهرست خالی و
+path خالی یعنی نگهداری فقط در حافظه
+*/
+func loadRoster(path string) (*roster, error) {
+	r := &roster{path: path, entries: make(map[string]*rosterEntry)}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*rosterEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, e := range list {
+		r.entries[e.Nick] = e
+	}
+	return r, nil
+}
+
+// save writes the roster; the caller holds mu | ذخیره فهرست (mu باید گرفته شده باشد)
+func (r *roster) save() error {
+	if r.path == "" {
+		return nil // Memory only | فقط در حافظه
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(r.sorted(), "", "  ") // Plain structs cannot fail | ساختار ساده خطا نمی‌دهد
+	return os.WriteFile(r.path, append(data, '\n'), 0o600)
+}
+
+// sorted lists the entries by nick; the caller holds mu | فهرست ورودی‌ها بر اساس نام (mu باید گرفته شده باشد)
+func (r *roster) sorted() []*rosterEntry {
+	list := make([]*rosterEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Nick < list[j].Nick })
+	return list
+}
+
+/*
+dialTarget resolves a -dial value: a nick in the roster becomes the
+address it was last dialed at, anything else is used as given.
+
+این تابع مقدار -dial را تفسیر می‌کند: نامی که در فهرست باشد به آدرس آخرین
+اتصال آن تبدیل می‌شود و هر چیز دیگری همان‌طور استفاده می‌شود
+*/
+func (r *roster) dialTarget(target string) (string, bool) {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target, false // Already an address | از قبل یک آدرس است
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.entries[target]; ok && e.Address != "" {
+		return e.Address, true
+	}
+	return target, false
+}
+
+/*
+connected notes the key and, for a link we dialed, the address of the
+current link, updates the address of the matching entry and returns
+that entry, if any.
+
+این تابع کلید و برای اتصالی که ما برقرار کرده‌ایم آدرس اتصال فعلی را ثبت
+می‌کند، آدرس ورودی متناظر را به‌روز می‌کند و آن ورودی را در صورت وجود
+برمی‌گرداند
+*/
+func (r *roster) connected(key, addr string) (rosterEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.key, r.addr = key, addr
+	e := r.byKey(key)
+	if e == nil {
+		return rosterEntry{}, false
+	}
+	if addr != "" && addr != e.Address {
+		e.Address = addr
+		if err := r.save(); err != nil {
+			fmt.Fprintln(stdout, "Roster error:", err)
+		}
+	}
+	return *e, true
+}
+
+// byKey returns the entry for fingerprint key; the caller holds mu | ورودی کلید key (mu باید گرفته شده باشد)
+func (r *roster) byKey(key string) *rosterEntry {
+	if key == "" {
+		return nil
+	}
+	for _, e := range r.entries {
+		if e.Fingerprint == key {
+			return e
+		}
+	}
+	return nil
+}
+
+/*
+add files the current remote under nick with notes, replacing an entry
+of the same nick or key.
+
+این تابع طرف مقابل فعلی را با نام nick و یادداشت notes ثبت می‌کند و
+ورودی هم‌نام یا هم‌کلید را جایگزین می‌کند
+*/
+func (r *roster) add(nick, notes string) (rosterEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.key == "" {
+		return rosterEntry{}, errRosterNoKey
+	}
+	e := &rosterEntry{Nick: nick, Fingerprint: r.key, Address: r.addr, Notes: notes}
+	err := r.put(e)
+	return *e, err
+}
+
+/*
+file adds e to the roster, replacing an entry of the same nick or key;
+pairing uses it to file a peer that has not connected yet.
+
+این تابع e را به فهرست اضافه می‌کند و ورودی هم‌نام یا هم‌کلید را جایگزین
+می‌کند؛ جفت‌سازی از آن برای ثبت peerی که هنوز وصل نشده استفاده می‌کند
+*/
+func (r *roster) file(e rosterEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.put(&e)
+}
+
+/*
+put stores e, replacing an entry of the same nick or key; a re-filed key
+keeps its alias and, when e has none, its address. The caller holds mu.
+
+این تابع e را ذخیره می‌کند و ورودی هم‌نام یا هم‌کلید را جایگزین می‌کند؛
+کلیدی که دوباره ثبت شود نام نمایشی و در صورت نبود آدرس، آدرس قبلی خود را
+حفظ می‌کند؛ mu باید گرفته شده باشد
+*/
+func (r *roster) put(e *rosterEntry) error {
+	if old := r.byKey(e.Fingerprint); old != nil {
+		delete(r.entries, old.Nick)
+		if e.Address == "" {
+			e.Address = old.Address // Keep where we last reached it | حفظ آدرس قبلی
+		}
+		if e.Alias == "" {
+			e.Alias = old.Alias
+		}
+	}
+	r.entries[e.Nick] = e
+	return r.save()
+}
+
+// setAlias sets or, when alias is empty, clears the display alias of nick | تعیین یا پاک‌کردن نام نمایشی nick
+func (r *roster) setAlias(nick, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[nick]
+	if !ok {
+		return errRosterUnknown
+	}
+	e.Alias = alias
+	return r.save()
+}
+
+/*
+name returns the display alias filed for key, or nick when there is
+none.
+
+این تابع نام نمایشی ثبت‌شده برای key را برمی‌گرداند یا اگر نباشد nick را
+*/
+func (r *roster) name(key, nick string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.byKey(key); e != nil && e.Alias != "" {
+		return e.Alias
+	}
+	return nick
+}
+
+/*
+relabel returns m with its sender shown under the display alias of its
+key. Only verified messages are relabelled: an unsigned one could claim
+any key.
+
+این تابع m را با نام نمایشی کلید فرستنده برمی‌گرداند؛ فقط پیام‌های
+تأییدشده تغییر می‌کنند چون پیام بدون امضا می‌تواند هر کلیدی را ادعا کند
+*/
+func (r *roster) relabel(m message) message {
+	if m.Verified {
+		m.From = r.name(m.Key, m.From)
+	}
+	return m
+}
+
+// remove drops the entry for nick | حذف ورودی nick
+func (r *roster) remove(nick string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[nick]; !ok {
+		return errRosterUnknown
+	}
+	delete(r.entries, nick)
+	return r.save()
+}
+
+// list returns the entries by nick | ورودی‌ها بر اساس نام
+func (r *roster) list() []rosterEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]rosterEntry, 0, len(r.entries))
+	for _, e := range r.sorted() {
+		out = append(out, *e)
+	}
+	return out
+}
+
+// nicks lists the nicks in the roster | فهرست نام‌های فهرست دوستان
+func (r *roster) nicks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.entries))
+	for nick := range r.entries {
+		out = append(out, nick)
+	}
+	return out
+}
+
+// noteSuffix renders notes in parentheses after a name, if any | نمایش یادداشت در پرانتز پس از نام
+func noteSuffix(notes string) string {
+	if notes == "" {
+		return ""
+	}
+	return " (" + notes + ")"
+}
+
+/*
+rosterCommand manages the roster: list shows it with the current remote
+starred, add files the remote under a nick with optional notes, alias
+sets or clears the name its messages are shown under, and remove drops
+an entry.
+
+این دستور فهرست دوستان را مدیریت می‌کند: list آن را با ستاره کنار طرف
+مقابل فعلی نشان می‌دهد، add طرف مقابل را با یک نام و یادداشت اختیاری ثبت
+می‌کند، alias نامی را که پیام‌هایش با آن نمایش داده می‌شوند تعیین یا پاک
+می‌کند و remove یک ورودی را حذف می‌کند
+*/
+func rosterCommand(s *session, args []string) {
+	switch {
+	case len(args) == 0 || (args[0] == "list" && len(args) == 1):
+		list := s.roster.list()
+		if len(list) == 0 {
+			fmt.Fprintln(stdout, "The roster is empty")
+		}
+		for _, e := range list {
+			marker := "  "
+			if e.Fingerprint == s.conn.remoteKey {
+				marker = "* "
+			}
+			line := marker + e.Nick
+			if e.Alias != "" {
+				line += " as " + e.Alias
+			}
+			line += "  " + e.Fingerprint
+			if e.Address != "" {
+				line += "  " + e.Address
+			}
+			if e.Notes != "" {
+				line += "  " + e.Notes
+			}
+			fmt.Fprintln(stdout, line)
+		}
+	case args[0] == "add" && len(args) >= 2:
+		e, err := s.roster.add(args[1], strings.Join(args[2:], " "))
+		if err != nil {
+			fmt.Fprintln(stdout, "Roster error:", err)
+			return
+		}
+		fmt.Fprintf(stdout, "Added %s (%s) to the roster\n", e.Nick, e.Fingerprint)
+	case args[0] == "alias" && len(args) >= 2:
+		alias := strings.Join(args[2:], " ")
+		if err := s.roster.setAlias(args[1], alias); err != nil {
+			fmt.Fprintln(stdout, "Roster error:", err)
+			return
+		}
+		if alias == "" {
+			fmt.Fprintln(stdout, "Cleared the alias of", args[1])
+			return
+		}
+		fmt.Fprintf(stdout, "%s is shown as %s\n", args[1], alias)
+	case args[0] == "remove" && len(args) == 2:
+		if err := s.roster.remove(args[1]); err != nil {
+			fmt.Fprintln(stdout, "Roster error:", err)
+			return
+		}
+		fmt.Fprintln(stdout, "Removed", args[1], "from the roster")
+	default:
+		fmt.Fprintln(stdout, "Usage: /roster [list] | /roster add <nick> [notes] | /roster alias <nick> [alias] | /roster remove <nick>")
+	}
+}
+
+func init() {
+	registerCommand("roster", "/roster [list] | /roster add <nick> [notes] | /roster alias <nick> [alias] | /roster remove <nick>  manage the known peers", rosterCommand)
+	registerArgCompleter("roster", func(s *session, args []string, word string) []string {
+		switch {
+		case len(args) == 0:
+			return matching(word, "list", "add", "alias", "remove")
+		case len(args) == 1 && (args[0] == "remove" || args[0] == "alias"):
+			return matching(word, s.roster.nicks()...)
+		case len(args) == 1 && args[0] == "add":
+			return completeNick(s, word)
+		}
+		return nil
+	})
+}
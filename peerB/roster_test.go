@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRosterFiling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "ann.roster")
+	r, err := loadRoster(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.add("bob", ""); err != errRosterNoKey {
+		t.Errorf("filing before a key was proven: %v", err)
+	}
+	if _, ok := r.connected("fp-bob", "bob.example:9000"); ok {
+		t.Error("an unknown key matched an entry")
+	}
+	if e, err := r.add("bob", "from work"); err != nil || e != (rosterEntry{Nick: "bob", Fingerprint: "fp-bob", Address: "bob.example:9000", Notes: "from work"}) {
+		t.Errorf("filed %+v, %v", e, err)
+	}
+
+	r.connected("fp-bob", "") // Accepted link: no address to keep | اتصال پذیرفته‌شده: آدرسی برای نگه‌داشتن نیست
+	if _, err := r.add("robert", ""); err != nil {
+		t.Fatal(err)
+	}
+	if list := r.list(); len(list) != 1 || list[0].Nick != "robert" || list[0].Address != "bob.example:9000" {
+		t.Errorf("re-filing a key: %+v, want one entry keeping the address", list)
+	}
+
+	if e, ok := r.connected("fp-bob", "10.0.0.2:9000"); !ok || e.Address != "10.0.0.2:9000" {
+		t.Errorf("dialing a new address: %+v, %v", e, ok)
+	}
+	r, err = loadRoster(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for target, want := range map[string]string{"robert": "10.0.0.2:9000", "bob": "bob", "host:1": "host:1"} {
+		if got, _ := r.dialTarget(target); got != want {
+			t.Errorf("dialTarget(%q) = %q after reloading, want %q", target, got, want)
+		}
+	}
+	if err := r.remove("robert"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.remove("robert"); err != errRosterUnknown {
+		t.Errorf("removing twice: %v", err)
+	}
+	if r, _ = loadRoster(path); len(r.list()) != 0 {
+		t.Errorf("removed entry came back: %+v", r.list())
+	}
+}
+
+func TestRosterCommand(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	r, _ := loadRoster("")
+	s := &session{roster: r, conn: &handshakeConn{remoteKey: "fp-bob"}}
+
+	runCommand(s, "/roster")
+	runCommand(s, "/roster add bob")
+	r.connected("fp-bob", "bob.example:9000")
+	runCommand(s, "/roster add bob met at the meetup")
+	r.connected("fp-cy", "")
+	runCommand(s, "/roster add cy")
+	runCommand(s, "/roster list")
+	runCommand(s, "/roster remove dan")
+	runCommand(s, "/roster remove cy")
+	runCommand(s, "/roster drop cy")
+	want := "The roster is empty\n" +
+		"Roster error: the remote has not proven a key\n" +
+		"Added bob (fp-bob) to the roster\n" +
+		"Added cy (fp-cy) to the roster\n" +
+		"* bob  fp-bob  bob.example:9000  met at the meetup\n" +
+		"  cy  fp-cy\n" +
+		"Roster error: not in the roster\n" +
+		"Removed cy from the roster\n" +
+		"Usage: /roster [list] | /roster add <nick> [notes] | /roster alias <nick> [alias] | /roster remove <nick>\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRosterAlias(t *testing.T) {
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	r, _ := loadRoster("")
+	r.connected("fp-bob", "")
+	if _, err := r.add("bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	s := &session{roster: r, conn: &handshakeConn{}}
+
+	runCommand(s, "/roster alias dan Daniel")
+	runCommand(s, "/roster alias bob Bob from work")
+	if _, err := r.add("robert", ""); err != nil { // Re-filed under another nick | ثبت دوباره با نام دیگر
+		t.Fatal(err)
+	}
+	runCommand(s, "/roster")
+	want := "Roster error: not in the roster\n" +
+		"bob is shown as Bob from work\n" +
+		"  robert as Bob from work  fp-bob\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	signed := message{From: "bob", Key: "fp-bob", Verified: true}
+	if got := r.relabel(signed).From; got != "Bob from work" {
+		t.Errorf("signed message shown as %q", got)
+	}
+	if got := r.relabel(message{From: "bob", Key: "fp-bob"}).From; got != "bob" {
+		t.Errorf("unsigned message claiming the key shown as %q", got)
+	}
+	if got := r.name("fp-other", "eve"); got != "eve" {
+		t.Errorf("key without an entry shown as %q", got)
+	}
+
+	buf.Reset()
+	runCommand(s, "/roster alias robert")
+	if got := r.relabel(signed).From; got != "bob" || buf.String() != "Cleared the alias of robert\n" {
+		t.Errorf("after clearing: shown as %q; output %q", got, buf.String())
+	}
+}
+
+func TestSearchShowsAliases(t *testing.T) {
+	s := searchSession(t)
+	s.history.add(message{Time: time.Now(), From: "bob", Text: "signed hello", Key: "fp-bob", Verified: true})
+	s.history.add(message{Time: time.Now(), From: "bob", Text: "unsigned hello"})
+	s.roster.connected("fp-bob", "")
+	if _, err := s.roster.add("bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.roster.setAlias("bob", "Robert"); err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	defer stdout.redirect(stdout.redirect(&buf))
+	runCommand(s, "/search hello")
+	if !strings.Contains(buf.String(), "Robert: signed hello") || !strings.Contains(buf.String(), "bob: unsigned hello") {
+		t.Errorf("search output:\n%s", buf.String())
+	}
+}
+
+func TestConnectSubcommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a peer")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dir := t.TempDir()
+	r, err := loadRoster(filepath.Join(dir, "peerchat", "X.roster"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.file(rosterEntry{Nick: "bob", Fingerprint: "fp-bob", Address: ln.Addr().String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.file(rosterEntry{Nick: "cy", Fingerprint: "fp-cy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, out, _ := pipePeerIn(t, dir, "", "connect", "cy", "-name", "X")
+	if err := cmd.Wait(); err == nil || !strings.Contains(out.String(), "Connect error: cy: not in the roster with an address") {
+		t.Errorf("connecting to an entry without an address: %v\n%s", err, out)
+	}
+
+	cmd, _, _ = pipePeerIn(t, dir, "", "connect", "bob", "-name", "X", "-listen", freeAddr(t))
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	_ = ln.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("bob's filed address was not dialed: %v", err)
+	}
+	conn.Close()
+}
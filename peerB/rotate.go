@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"            // For the log files
+	"path/filepath" // For the directory and rotated segments
+	"sort"          // For ordering rotated segments
+	"strings"       // For recognising rotated segments
+	"sync"          // For serialising writes and rotations
+	"time"          // For the age limit and segment names
+)
+
+const rotatedStamp = "20060102-150405.000" // Suffix of rotated segments | پسوند فایل‌های چرخانده‌شده
+
+/*
+rotation limits how large and how old an append-only file may grow
+before it is moved aside, and how many moved-aside segments are kept.
+Zero disables each limit.
+
+این نوع تعیین می‌کند یک فایل افزودنی تا چه اندازه و چه سنی بزرگ شود
+پیش از آنکه کنار گذاشته شود و چند فایل کنارگذاشته نگه داشته شوند؛
+مقدار صفر هر محدودیت را غیرفعال می‌کند
+*/
+type rotation struct {
+	maxSize int64         // Bytes before rotating | حداکثر حجم پیش از چرخش
+	maxAge  time.Duration // Age before rotating | حداکثر سن پیش از چرخش
+	keep    int           // Rotated segments kept | تعداد فایل‌های چرخانده‌شده‌ی نگه‌داشته
+}
+
+/*
+rotatingFile is an append-only file that, before a write would cross
+the rotation limits, renames itself to <path>.<time>, starts afresh and
+deletes the oldest segments beyond keep. Each Write lands whole in one
+segment, so writers that write whole lines never split one.
+
+این نوع فایلی افزودنی است که پیش از آنکه نوشتنی از محدودیت‌های چرخش
+بگذرد، خود را به <path>.<time> تغییر نام می‌دهد، از نو شروع می‌کند و
+قدیمی‌ترین فایل‌های اضافه بر keep را حذف می‌کند. هر Write کامل در یک فایل
+قرار می‌گیرد، پس خطوط کامل هیچ‌گاه دو تکه نمی‌شوند
+*/
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	rot     rotation
+	f       *os.File
+	size    int64     // Bytes in the current segment | حجم فایل جاری
+	started time.Time // When the current segment began | زمان شروع فایل جاری
+}
+
+// openRotating opens path for appending under rot | بازکردن فایل برای افزودن با محدودیت چرخش
+func openRotating(path string, rot rotation) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{path: path, rot: rot}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open appends to the current segment; an existing one dates from its last change | بازکردن فایل جاری
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size, r.started = f, fi.Size(), time.Now()
+	if fi.Size() > 0 {
+		r.started = fi.ModTime()
+	}
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.due(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// due reports whether writing n more bytes should start a new segment | آیا پیش از نوشتن باید چرخاند
+func (r *rotatingFile) due(n int) bool {
+	if r.size == 0 {
+		return false // Never leave an empty segment behind | هیچ‌گاه فایل خالی کنار گذاشته نمی‌شود
+	}
+	return (r.rot.maxSize > 0 && r.size+int64(n) > r.rot.maxSize) ||
+		(r.rot.maxAge > 0 && time.Since(r.started) >= r.rot.maxAge)
+}
+
+// rotate moves the current segment aside and prunes old ones | کنار گذاشتن فایل جاری و حذف فایل‌های قدیمی
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(r.path, nextSegmentName(r.path)); err != nil {
+		_ = r.open() // Keep appending to the old segment | ادامه‌ی نوشتن در فایل قبلی
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if old := rotatedFiles(r.path); r.rot.keep > 0 && len(old) > r.rot.keep {
+		for _, p := range old[:len(old)-r.rot.keep] {
+			_ = os.Remove(p)
+		}
+	}
+	return nil
+}
+
+/*
+nextSegmentName names the segment path is rotated to: the current time,
+or a millisecond after the newest existing segment when that is not
+earlier, so names always sort in rotation order and Rename never
+replaces a segment rotated in the same millisecond.
+
+این تابع نام فایل چرخانده‌شده را انتخاب می‌کند: زمان فعلی، یا اگر از
+جدیدترین فایل موجود جلوتر نباشد یک میلی‌ثانیه پس از آن، تا نام‌ها همیشه به
+ترتیب چرخش مرتب شوند و Rename فایل چرخانده‌شده در همان میلی‌ثانیه را جایگزین نکند
+*/
+func nextSegmentName(path string) string {
+	at := time.Now().Truncate(time.Millisecond)
+	if old := rotatedFiles(path); len(old) > 0 {
+		last, _ := time.ParseInLocation(rotatedStamp, strings.TrimPrefix(old[len(old)-1], path+"."), time.Local) // Listed segments parse | فایل‌های فهرست‌شده قابل تجزیه‌اند
+		if !at.After(last) {
+			at = last.Add(time.Millisecond)
+		}
+	}
+	return path + "." + at.Format(rotatedStamp)
+}
+
+// Close closes the current segment | بستن فایل جاری
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// rotatedFiles lists the rotated segments of path, oldest first | فهرست فایل‌های چرخانده‌شده، قدیمی‌ترین اول
+func rotatedFiles(path string) []string {
+	matches, _ := filepath.Glob(path + ".*") // Only a bad pattern fails | فقط الگوی نادرست خطا می‌دهد
+	var out []string
+	for _, m := range matches {
+		if _, err := time.Parse(rotatedStamp, strings.TrimPrefix(m, path+".")); err == nil {
+			out = append(out, m)
+		}
+	}
+	sort.Strings(out) // The stamp sorts by time | برچسب زمانی به ترتیب زمان مرتب می‌شود
+	return out
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	r, err := openRotating(path, rotation{maxSize: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 6 {
+		if _, err := fmt.Fprintf(r, "line %d of the log\n", i); err != nil { // 19 bytes, one per segment | ۱۹ بایت، هر خط در یک فایل
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	segments := append(rotatedFiles(path), path)
+	if len(segments) != 6 {
+		t.Fatalf("%d segments, want 6 (rotations in one millisecond must not overwrite each other)", len(segments))
+	}
+	for i, p := range segments {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("line %d of the log\n", i); string(data) != want {
+			t.Errorf("%s holds %q, want %q", filepath.Base(p), data, want)
+		}
+	}
+}
+
+func TestRotateKeepsAndAges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log")
+	if err := os.WriteFile(path+".notes", nil, 0o600); err != nil { // Not a segment | فایل چرخانده‌شده نیست
+		t.Fatal(err)
+	}
+	r, err := openRotating(path, rotation{maxSize: 10, keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		fmt.Fprintf(r, "entry %d\n", i)
+	}
+	r.Close()
+	if old := rotatedFiles(path); len(old) != 2 {
+		t.Errorf("kept %v, want the 2 newest segments", old)
+	} else if data, _ := os.ReadFile(old[0]); string(data) != "entry 2\n" {
+		t.Errorf("oldest kept segment holds %q, want entry 2", data)
+	}
+	if _, err := os.Stat(path + ".notes"); err != nil {
+		t.Errorf("pruning removed a file that is not a segment: %v", err)
+	}
+
+	aged := filepath.Join(dir, "aged")
+	if err := os.WriteFile(aged, []byte("yesterday\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	yesterday := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(aged, yesterday, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	r, err = openRotating(aged, rotation{maxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(r, "today")
+	fmt.Fprintln(r, "still today")
+	r.Close()
+	data, _ := os.ReadFile(aged)
+	if old := rotatedFiles(aged); len(old) != 1 || string(data) != "today\nstill today\n" {
+		t.Errorf("after a day: segments %v, current %q", old, data)
+	}
+}
+
+func TestHistoryReadsAcrossSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h, err := openHistory(path, rotation{maxSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		h.add(message{Time: time.Now(), From: "bob", Text: fmt.Sprint("msg ", i)})
+	}
+	h.close()
+	msgs, err := readHistory(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, m := range msgs {
+		texts = append(texts, m.Text)
+	}
+	if got := strings.Join(texts, ","); got != "msg 0,msg 1,msg 2" {
+		t.Errorf("history across rotations: %s", got)
+	}
+}
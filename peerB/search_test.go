@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchSession returns a session whose history holds the given texts, oldest first | نشستی با تاریخچه‌ی متن‌های داده‌شده
+func searchSession(t *testing.T, texts ...string) *session {
+	h, err := openHistory(filepath.Join(t.TempDir(), "history.jsonl"), rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.close)
+	start := time.Now().Add(-time.Hour)
+	for i, text := range texts {
+		h.add(message{Time: start.Add(time.Duration(i) * time.Second), From: "bob", Text: text})
+	}
+	buddies, _ := loadRoster("")
+	return &session{history: h, roster: buddies}
+}
+
+func TestSearchPagesAndShow(t *testing.T) {
+	var texts []string
+	for i := 1; i <= 25; i++ {
+		texts = append(texts, fmt.Sprintf("Deploy %d done", i), "lunch?")
+	}
+	s := searchSession(t, texts...)
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+
+	runCommand(s, "/search deploy DONE")
+	out := buf.String()
+	if !strings.Contains(out, `25 matches for "deploy DONE" (page 1/3)`) || !strings.Contains(out, "[1] ") || !strings.Contains(out, "bob: Deploy 25 done") {
+		t.Errorf("first page:\n%s", out)
+	}
+	if strings.Contains(out, "lunch") || strings.Contains(out, "[11]") {
+		t.Errorf("first page shows more than ten matches:\n%s", out)
+	}
+
+	runCommand(s, "/more")
+	runCommand(s, "/more")
+	buf.Reset()
+	runCommand(s, "/more")
+	if !strings.Contains(buf.String(), "No more results") {
+		t.Errorf("past the last page: %q", buf.String())
+	}
+
+	buf.Reset()
+	runCommand(s, "/show 25") // Oldest match, at the start of the history | قدیمی‌ترین نتیجه در ابتدای تاریخچه
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1+searchContext || !strings.HasPrefix(lines[0], ">> ") || !strings.HasSuffix(lines[0], "Deploy 1 done") {
+		t.Errorf("/show 25:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	runCommand(s, "/show 26")
+	if !strings.Contains(buf.String(), "Usage: /show") {
+		t.Errorf("/show past the results: %q", buf.String())
+	}
+	buf.Reset()
+	runCommand(s, "/search nothing-like-this")
+	if !strings.Contains(buf.String(), "No matches") {
+		t.Errorf("search without matches: %q", buf.String())
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	h, err := openHistory(defaultHistoryPath("ann"), rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.add(message{Time: time.Now().Add(-48 * time.Hour), From: "bob", Text: "release one"})
+	h.add(message{Time: time.Now(), From: "bob", Text: "release two"})
+	h.add(message{Time: time.Now(), From: "bob", Text: "release three"})
+	h.add(message{Time: time.Now(), From: "bob", Text: "lunch"})
+	h.close()
+
+	var buf bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&buf))
+	if err := runHistory("ann", []string{"--since", "24h", "-n", "1", "release"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); strings.Count(got, "\n") != 0 || !strings.HasSuffix(got, "bob: release three") {
+		t.Errorf("history --since 24h -n 1 release:\n%s", got)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"           // For reading frames byte by byte
+	"encoding/binary" // For the checksum trailer
+	"errors"          // For serial error values
+	"hash/crc32"      // For the frame checksum
+	"io"              // For the serial line
+	"net"             // For the packet link and listener
+	"sync"            // For whole-frame writes and closing once
+	"time"            // For the unsupported deadlines
+)
+
+const defaultBaud = 115200 // Serial line speed unless -baud says otherwise | سرعت پیش‌فرض خط سریال
+
+/*
+SLIP framing (RFC 1055)
+
+قاب‌بندی SLIP:
+- end هر قاب را می‌بندد و نویز پیش از آن را جدا می‌کند
+- esc بایت بعدی را escape می‌کند: escEnd به‌جای end و escEsc به‌جای esc
+*/
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+const serialFrameMax = arqHeader + arqPayload + crc32.Size // Largest unescaped frame | بزرگ‌ترین قاب بدون escape
+
+var (
+	errSerialDevice = errors.New(`transport "serial" needs a device`)                // No -device | بدون device
+	errSerialDial   = errors.New("a serial line is opened by listen, not dialed")    // Nothing to dial | چیزی برای dial نیست
+	errSerialBaud   = errors.New("baud must be a standard rate from 1200 to 921600") // Rate the line cannot take | سرعت نامعتبر
+)
+
+/*
+serialTransport chats over a serial line, for machines with no network
+between them. Both ends open the device, so listen opens it and hands
+the one link to Accept, and dial never succeeds. The line is only a
+byte stream that may drop or garble bytes: packets travel as SLIP
+frames with a CRC-32, damaged frames are discarded, and the ARQ layer
+that makes UDP reliable resends them. The link lasts for one run on
+both ends.
+
+این نوع چت را روی خط سریال برای ماشین‌هایی بدون شبکه‌ی میان آن‌ها انجام
+می‌دهد؛ هر دو سر دستگاه را باز می‌کنند، پس listen آن را باز می‌کند و تنها
+اتصال را به Accept می‌دهد و dial هرگز موفق نمی‌شود. خط فقط یک stream بایتی
+است که ممکن است بایت‌ها را گم یا خراب کند: بسته‌ها در قاب‌های SLIP با CRC-32
+می‌روند، قاب‌های خراب دور ریخته می‌شوند و لایه‌ی ARQ که UDP را
+قابل‌اعتماد می‌کند آن‌ها را دوباره می‌فرستد؛ اتصال برای یک اجرا در هر دو سر برقرار است
+*/
+type serialTransport struct {
+	device string
+	baud   int
+}
+
+func (serialTransport) dial(string) (net.Conn, error) {
+	return nil, errSerialDial
+}
+
+// listen opens the device; the listen address does not apply | باز کردن دستگاه؛ آدرس listen کاربردی ندارد
+func (t serialTransport) listen(string) (net.Listener, error) {
+	port, err := openSerial(t.device, t.baud)
+	if err != nil {
+		return nil, err
+	}
+	addr := serialAddr(t.device)
+	l := &serialListener{addr: addr, conn: make(chan net.Conn, 1), closed: make(chan struct{})}
+	l.conn <- newARQConn(&serialPacketConn{port: port, r: bufio.NewReader(port), addr: addr}, addr, 0)
+	return l, nil
+}
+
+// serialListener hands the line's one link to Accept | تحویل تنها اتصال خط به Accept
+type serialListener struct {
+	addr   serialAddr
+	conn   chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (l *serialListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conn:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close releases the line unless the link was accepted | آزادسازی خط اگر اتصال پذیرفته نشده باشد
+func (l *serialListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		select {
+		case c := <-l.conn:
+			_ = c.Close()
+		default:
+		}
+	})
+	return nil
+}
+
+func (l *serialListener) Addr() net.Addr {
+	return l.addr
+}
+
+/*
+serialPacketConn is the packet link the ARQ layer runs over: every
+packet is one SLIP frame carrying its CRC-32, and a frame that is too
+long or fails the check is skipped like a lost packet.
+
+این نوع اتصال بسته‌ای است که لایه‌ی ARQ روی آن اجرا می‌شود: هر بسته یک
+قاب SLIP همراه با CRC-32 خود است و قابی که بیش از حد بلند باشد یا بررسی را
+رد کند مانند بسته‌ی گم‌شده نادیده گرفته می‌شود
+*/
+type serialPacketConn struct {
+	port io.ReadWriteCloser
+	r    *bufio.Reader
+	addr serialAddr
+	mu   sync.Mutex // One frame on the line at a time | هر بار یک قاب روی خط
+}
+
+func (c *serialPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		frame, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(frame) < crc32.Size {
+			continue
+		}
+		body := frame[:len(frame)-crc32.Size]
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(frame[len(body):]) {
+			continue // Line noise | نویز خط
+		}
+		return copy(p, body), c.addr, nil
+	}
+}
+
+// readFrame returns the next unescaped frame, skipping empty and oversized ones | قاب بعدی بدون escape؛ قاب خالی و بیش از حد بلند نادیده گرفته می‌شود
+func (c *serialPacketConn) readFrame() ([]byte, error) {
+	var frame []byte
+	esc, over := false, false
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b == slipEnd:
+			if len(frame) > 0 && !over {
+				return frame, nil
+			}
+			frame, esc, over = frame[:0], false, false
+			continue
+		case esc:
+			esc = false
+			switch b {
+			case slipEscEnd:
+				b = slipEnd
+			case slipEscEsc:
+				b = slipEsc
+			}
+		case b == slipEsc:
+			esc = true
+			continue
+		}
+		if len(frame) == serialFrameMax {
+			over = true
+			continue
+		}
+		frame = append(frame, b)
+	}
+}
+
+func (c *serialPacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	frame := []byte{slipEnd}
+	frame = slipEscape(frame, p)
+	frame = slipEscape(frame, binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(p)))
+	frame = append(frame, slipEnd)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.port.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// slipEscape appends p to frame with END and ESC bytes escaped | افزودن p به قاب با escape بایت‌های END و ESC
+func slipEscape(frame, p []byte) []byte {
+	for _, b := range p {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return frame
+}
+
+func (c *serialPacketConn) Close() error                       { return c.port.Close() }
+func (c *serialPacketConn) LocalAddr() net.Addr                { return c.addr }
+func (c *serialPacketConn) SetDeadline(t time.Time) error      { return nil }
+func (c *serialPacketConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *serialPacketConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// serialAddr is the device path of a serial line | مسیر دستگاه خط سریال
+type serialAddr string
+
+func (serialAddr) Network() string  { return "serial" }
+func (a serialAddr) String() string { return string(a) }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os" // For the device file
+
+	"golang.org/x/sys/unix" // For the termios settings
+)
+
+// serialSpeeds maps -baud to the termios speeds | نگاشت baud به سرعت‌های termios
+var serialSpeeds = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+/*
+openSerial opens a serial device in raw mode at baud: 8 data bits, no
+parity, no flow control, and no echo or line editing, so every byte
+passes through unchanged.
+
+این تابع دستگاه سریال را در حالت خام با سرعت baud باز می‌کند: ۸ بیت داده،
+بدون parity، بدون کنترل جریان و بدون echo یا ویرایش خط، تا هر بایت
+بدون تغییر عبور کند
+*/
+func openSerial(device string, baud int) (*os.File, error) {
+	speed, ok := serialSpeeds[baud]
+	if !ok {
+		return nil, errSerialBaud
+	}
+	f, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.SyscallConn() // Not Fd(), which would make reads block Close | نه Fd() که خواندن را مانع Close می‌کند
+	if err == nil {
+		ctlErr := rc.Control(func(fd uintptr) {
+			err = makeRaw(int(fd), speed)
+		})
+		if err == nil {
+			err = ctlErr
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// makeRaw applies the raw line settings, like cfmakeraw | اعمال تنظیمات خام خط مانند cfmakeraw
+func makeRaw(fd int, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed, t.Ospeed = speed, speed
+	t.Cc[unix.VMIN], t.Cc[unix.VTIME] = 1, 0
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
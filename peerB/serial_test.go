@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serialLine joins two packet conns with a byte pipe | اتصال دو packet conn با یک pipe بایتی
+func serialLine(a, b io.ReadWriteCloser) (*serialPacketConn, *serialPacketConn) {
+	return &serialPacketConn{port: a, r: bufio.NewReader(a), addr: "a"}, &serialPacketConn{port: b, r: bufio.NewReader(b), addr: "b"}
+}
+
+func TestSerialFraming(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	ca, cb := serialLine(a, b)
+	packets := [][]byte{
+		[]byte("hello"),
+		{slipEnd, slipEsc, slipEscEnd, slipEscEsc, slipEnd}, // Every special byte | همه‌ی بایت‌های خاص
+		bytes.Repeat([]byte{slipEnd}, arqHeader+arqPayload), // Largest packet, all escaped | بزرگ‌ترین بسته، همه escape‌شده
+	}
+	go func() {
+		for _, p := range packets {
+			if _, err := ca.WriteTo(p, nil); err != nil {
+				return
+			}
+		}
+	}()
+	buf := make([]byte, serialFrameMax)
+	for _, want := range packets {
+		n, _, err := cb.ReadFrom(buf)
+		if err != nil || !bytes.Equal(buf[:n], want) {
+			t.Fatalf("read %x, %v; want %x", buf[:n], err, want)
+		}
+	}
+}
+
+func TestSerialSkipsDamagedFrames(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	ca, cb := serialLine(a, b)
+	go func() {
+		bad := []byte{slipEnd}
+		bad = slipEscape(bad, []byte("garbled"))
+		bad = append(bad, 0, 0, 0, 0, slipEnd) // Wrong checksum | checksum نادرست
+		long := append([]byte{slipEnd}, bytes.Repeat([]byte{'x'}, serialFrameMax+1)...)
+		for _, raw := range [][]byte{[]byte("noise"), bad, long, {slipEnd, 1, 2, slipEnd}} {
+			if _, err := a.Write(raw); err != nil {
+				return
+			}
+		}
+		_, _ = ca.WriteTo([]byte("intact"), nil)
+	}()
+	buf := make([]byte, serialFrameMax)
+	n, _, err := cb.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != "intact" {
+		t.Fatalf("read %q, %v; want only the intact frame", buf[:n], err)
+	}
+}
+
+// noisyLine is one end of a buffered line, like a UART's, garbling every third write | یک سر خط بافردار مانند UART که هر سومین نوشتن را خراب می‌کند
+type noisyLine struct {
+	r, w   *os.File
+	mu     sync.Mutex
+	writes int
+}
+
+// newNoisyLine returns both ends of a noisy line | هر دو سر یک خط پرنویز
+func newNoisyLine(t *testing.T) (*noisyLine, *noisyLine) {
+	ar, bw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, aw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &noisyLine{r: ar, w: aw}, &noisyLine{r: br, w: bw}
+}
+
+func (l *noisyLine) Read(p []byte) (int, error) { return l.r.Read(p) }
+
+func (l *noisyLine) Close() error {
+	l.w.Close()
+	return l.r.Close()
+}
+
+func (l *noisyLine) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	l.writes++
+	if l.writes%3 == 0 && len(p) > 2 {
+		p = bytes.Clone(p)
+		p[len(p)/2] ^= 0x55
+	}
+	l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestSerialLinkSurvivesNoise(t *testing.T) {
+	ca, cb := serialLine(newNoisyLine(t))
+	left, right := newARQConn(ca, ca.addr, 0), newARQConn(cb, cb.addr, 0)
+	defer left.Close()
+	defer right.Close()
+
+	var want bytes.Buffer
+	for i := 0; i < 200; i++ { // Several packets, so some are garbled | چندین بسته، تا برخی خراب شوند
+		want.WriteString("line of chat over a noisy serial cable\n")
+	}
+	go func() { _, _ = left.Write(want.Bytes()) }()
+	_ = right.SetReadDeadline(time.Now().Add(20 * time.Second))
+	got := make([]byte, want.Len())
+	if _, err := io.ReadFull(right, got); err != nil || !bytes.Equal(got, want.Bytes()) {
+		t.Fatalf("read %d bytes (%v), want the text intact", len(got), err)
+	}
+}
+
+func TestSerialListenerHandsOverOneLink(t *testing.T) {
+	if _, err := (serialTransport{}).dial("x"); err != errSerialDial {
+		t.Fatalf("dial: %v, want %v", err, errSerialDial)
+	}
+	a, b := net.Pipe()
+	defer b.Close()
+	l := &serialListener{addr: "ttyX", conn: make(chan net.Conn, 1), closed: make(chan struct{})}
+	l.conn <- a
+	if c, err := l.Accept(); err != nil || c != a {
+		t.Fatalf("Accept = %v, %v; want the line", c, err)
+	}
+	l.Close()
+	if _, err := l.Accept(); err != net.ErrClosed {
+		t.Fatalf("second Accept: %v, want %v", err, net.ErrClosed)
+	}
+}
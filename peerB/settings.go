@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"     // For printing setting values
+	"sort"    // For a stable /set listing
+	"strings" // For joining multi-word values
+)
+
+func init() {
+	registerCommand("set", "/set [name [value]]  show or change a runtime setting", setCommand)
+	registerArgCompleter("set", func(_ *session, args []string, word string) []string {
+		if len(args) > 0 {
+			return nil // Values are free-form | مقدارها آزاد هستند
+		}
+		var out []string
+		for name := range runtimeSettings {
+			if strings.HasPrefix(name, word) {
+				out = append(out, name)
+			}
+		}
+		return out
+	})
+}
+
+/*
+runtimeSetting is a value that can be changed while the chat runs with
+/set; get and set read and write it on the session.
+
+هر runtimeSetting مقداری است که هنگام اجرای چت با /set قابل تغییر
+است؛ get و set آن را روی نشست می‌خوانند و می‌نویسند
+*/
+type runtimeSetting struct {
+	usage string                               // Accepted values | مقادیر مجاز
+	get   func(s *session) string              // Current value | مقدار فعلی
+	set   func(s *session, value string) error // Change the value | تغییر مقدار
+}
+
+// runtimeSettings holds every registered setting by name | همه‌ی تنظیمات ثبت‌شده بر اساس نام
+var runtimeSettings = make(map[string]runtimeSetting)
+
+/*
+registerSetting adds a /set setting; like commands, features register
+their settings from init.
+
+این تابع یک تنظیم /set ثبت می‌کند؛ مثل دستورها، هر قابلیت
+تنظیمات خود را در init ثبت می‌کند
+*/
+func registerSetting(name, usage string, get func(*session) string, set func(*session, string) error) {
+	runtimeSettings[name] = runtimeSetting{usage: usage, get: get, set: set}
+}
+
+/*
+setCommand lists every setting, shows one, or changes one for the rest
+of this session; the config file is not rewritten.
+
+این دستور همه‌ی تنظیمات را فهرست، یکی را نمایش یا آن را تا پایان این
+نشست تغییر می‌دهد؛ فایل پیکربندی بازنویسی نمی‌شود
+*/
+func setCommand(s *session, args []string) {
+	if len(args) == 0 {
+		names := make([]string, 0, len(runtimeSettings))
+		for name := range runtimeSettings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "  %s = %s\n", name, runtimeSettings[name].get(s))
+		}
+		return
+	}
+	st, ok := runtimeSettings[args[0]]
+	if !ok {
+		fmt.Fprintln(stdout, "Unknown setting:", args[0], "(try /set)")
+		return
+	}
+	if len(args) == 1 {
+		fmt.Fprintf(stdout, "%s = %s  (%s)\n", args[0], st.get(s), st.usage)
+		return
+	}
+	if err := st.set(s, strings.Join(args[1:], " ")); err != nil {
+		fmt.Fprintln(stdout, "Set error:", err)
+		return
+	}
+	fmt.Fprintf(stdout, "%s = %s\n", args[0], st.get(s))
+}
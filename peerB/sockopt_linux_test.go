@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpPair returns both ends of a loopback TCP connection | دو سر یک اتصال TCP محلی
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dialed, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dialed.Close(); accepted.Close() })
+	return dialed.(*net.TCPConn), accepted.(*net.TCPConn)
+}
+
+// sockopt reads one integer socket option from c | خواندن یک تنظیم عددی socket
+func sockopt(t *testing.T, c *net.TCPConn, level, opt int) int {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var gerr error
+	if err := raw.Control(func(fd uintptr) { v, gerr = unix.GetsockoptInt(int(fd), level, opt) }); err != nil {
+		t.Fatal(err)
+	}
+	if gerr != nil {
+		t.Fatal(gerr)
+	}
+	return v
+}
+
+// lingerOf reads SO_LINGER from c | خواندن SO_LINGER
+func lingerOf(t *testing.T, c *net.TCPConn) *unix.Linger {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l *unix.Linger
+	var lerr error
+	if err := raw.Control(func(fd uintptr) { l, lerr = unix.GetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER) }); err != nil {
+		t.Fatal(err)
+	}
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	return l
+}
+
+func TestSocketKeepAlive(t *testing.T) {
+	dialed, accepted := tcpPair(t)
+	if err := (socketOptions{keepAlive: 7 * time.Second, linger: -1}).apply(dialed); err != nil {
+		t.Fatal(err)
+	}
+	if on, idle, every := sockopt(t, dialed, unix.SOL_SOCKET, unix.SO_KEEPALIVE), sockopt(t, dialed, unix.IPPROTO_TCP, unix.TCP_KEEPIDLE), sockopt(t, dialed, unix.IPPROTO_TCP, unix.TCP_KEEPINTVL); on == 0 || idle != 7 || every != 7 {
+		t.Errorf("keepalive %d, idle %ds, interval %ds; want on every 7s", on, idle, every)
+	}
+	if err := (socketOptions{linger: -1}).apply(accepted); err != nil {
+		t.Fatal(err)
+	}
+	if on := sockopt(t, accepted, unix.SOL_SOCKET, unix.SO_KEEPALIVE); on != 0 {
+		t.Error("a zero period left keepalive on")
+	}
+	if err := (socketOptions{keepAlive: time.Second}).apply(&net.UnixConn{}); err != nil {
+		t.Errorf("a non-TCP link: %v", err)
+	}
+}
+
+func TestSocketTuning(t *testing.T) {
+	dialed, accepted := tcpPair(t)
+	opts := socketOptions{noDelay: true, sendBuf: 64 << 10, recvBuf: 32 << 10, linger: 3}
+	if err := opts.apply(dialed); err != nil {
+		t.Fatal(err)
+	}
+	if got := sockopt(t, dialed, unix.IPPROTO_TCP, unix.TCP_NODELAY); got == 0 {
+		t.Error("Nagle is still on")
+	}
+	if got := sockopt(t, dialed, unix.SOL_SOCKET, unix.SO_SNDBUF); got < opts.sendBuf {
+		t.Errorf("send buffer %d, want at least %d", got, opts.sendBuf)
+	}
+	if got := sockopt(t, dialed, unix.SOL_SOCKET, unix.SO_RCVBUF); got < opts.recvBuf {
+		t.Errorf("receive buffer %d, want at least %d", got, opts.recvBuf)
+	}
+	if l := lingerOf(t, dialed); l.Onoff == 0 || l.Linger != 3 {
+		t.Errorf("linger %+v, want on for 3s", l)
+	}
+
+	if err := (socketOptions{linger: -1}).apply(accepted); err != nil {
+		t.Fatal(err)
+	}
+	if got := sockopt(t, accepted, unix.IPPROTO_TCP, unix.TCP_NODELAY); got != 0 {
+		t.Error("Nagle is off without tcp-nodelay")
+	}
+	if l := lingerOf(t, accepted); l.Onoff != 0 {
+		t.Errorf("linger %+v, want the OS default (off)", l)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"  // For the mute notification
+	"sync" // For guarding per-sender state
+	"time" // For the rate window and cooldown
+)
+
+/*
+Spam throttle configuration
+
+مقادیر پیکربندی محدودکننده‌ی اسپم:
+- بازه‌ی محاسبه‌ی نرخ پیام
+- مقادیر پیش‌فرض نرخ، تکرار و مدت سکوت
+*/
+const (
+	spamWindow          = time.Minute     // Window for the rate limit | بازه‌ی محاسبه نرخ پیام
+	spamDefaultRate     = 60              // Messages per minute | پیام در دقیقه
+	spamDefaultRepeat   = 5               // Identical messages in a row | پیام یکسان پشت سر هم
+	spamDefaultCooldown = 2 * time.Minute // Mute length | مدت سکوت
+)
+
+/*
+spamState is what the throttle remembers about one sender.
+
+این ساختار اطلاعاتی است که throttle درباره‌ی هر فرستنده نگه می‌دارد
+*/
+type spamState struct {
+	recent     []time.Time // Arrivals inside spamWindow | زمان پیام‌های داخل بازه
+	last       string      // Previous text | متن پیام قبلی
+	repeats    int         // Identical messages in a row | تعداد تکرار پشت سر هم
+	mutedUntil time.Time   // End of the current mute | پایان سکوت فعلی
+}
+
+/*
+spamFilter is an inbound filter stage that mutes a sender for a
+cooldown once they exceed rate messages per minute or send the same
+text repeat times in a row. notify tells the local user when a mute
+starts.
+
+این فیلتر ورودی، فرستنده‌ای را که بیش از rate پیام در دقیقه یا
+repeat پیام یکسان پشت سر هم بفرستد برای مدت cooldown ساکت می‌کند
+و با notify به کاربر محلی اطلاع می‌دهد
+*/
+type spamFilter struct {
+	mu       sync.Mutex
+	rate     int
+	repeat   int
+	cooldown time.Duration
+	notify   func(string)
+	senders  map[string]*spamState
+}
+
+// newSpamFilter creates the throttle; zero rate/repeat disable that check | ساخت throttle؛ مقدار صفر آن بررسی را غیرفعال می‌کند
+func newSpamFilter(rate, repeat int, cooldown time.Duration, notify func(string)) *spamFilter {
+	return &spamFilter{
+		rate:     rate,
+		repeat:   repeat,
+		cooldown: cooldown,
+		notify:   notify,
+		senders:  make(map[string]*spamState),
+	}
+}
+
+// filter implements messageFilter | پیاده‌سازی messageFilter
+func (f *spamFilter) filter(m *message) bool {
+	sender := m.From
+	if m.Key != "" {
+		sender = m.Key // Nick changes do not reset the count | تغییر نام شمارش را صفر نمی‌کند
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st, ok := f.senders[sender]
+	if !ok {
+		st = &spamState{}
+		f.senders[sender] = st
+	}
+
+	now := time.Now()
+	if now.Before(st.mutedUntil) {
+		return false
+	}
+
+	// Keep only arrivals inside the window | فقط پیام‌های داخل بازه می‌مانند
+	keep := st.recent[:0]
+	for _, t := range st.recent {
+		if now.Sub(t) < spamWindow {
+			keep = append(keep, t)
+		}
+	}
+	st.recent = append(keep, now)
+
+	if m.Text == st.last {
+		st.repeats++
+	} else {
+		st.last, st.repeats = m.Text, 1
+	}
+
+	var reason string
+	switch {
+	case f.rate > 0 && len(st.recent) > f.rate:
+		reason = fmt.Sprintf("more than %d messages a minute", f.rate)
+	case f.repeat > 0 && st.repeats >= f.repeat:
+		reason = fmt.Sprintf("%d identical messages in a row", st.repeats)
+	default:
+		return true
+	}
+
+	st.mutedUntil = now.Add(f.cooldown)
+	st.recent, st.repeats = nil, 0
+	f.notify(fmt.Sprintf("Muted %s for %s: %s", m.From, f.cooldown, reason))
+	return false
+}
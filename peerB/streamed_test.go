@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNextPart(t *testing.T) {
+	br := bufio.NewReaderSize(strings.NewReader("ab\ncd\nefgh"+strings.Repeat("ش", 4)), 16)
+	var parts []string
+	for {
+		p, err := nextPart(br, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p) == 0 {
+			break
+		}
+		parts = append(parts, string(p))
+	}
+	if want := []string{"ab\ncd\n", "efghشششش"}; strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Errorf("parts %q, want %q", parts, want)
+	}
+
+	br = bufio.NewReaderSize(strings.NewReader("a"+strings.Repeat("ش", 10)), 16)
+	p, _ := nextPart(br, 16) // 16 bytes would split a character | ۱۶ بایت یک نویسه را نصف می‌کند
+	if !utf8.Valid(p) || len(p) != 15 {
+		t.Errorf("part %q (%d bytes), want 15 bytes of whole characters", p, len(p))
+	}
+}
+
+func TestSendStreamed(t *testing.T) {
+	id, err := newEphemeralIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan string, 100)
+	done := newDoneSignal()
+	defer done.close()
+	m := newMetrics()
+	s := &session{
+		name:     "ann",
+		conn:     &handshakeConn{caps: capabilities{MaxMessage: 4096, Streaming: true}},
+		id:       id,
+		threads:  newThreadIndex(),
+		metrics:  m,
+		acks:     newAckTracker(m),
+		outgoing: out,
+		done:     done,
+	}
+	var text strings.Builder
+	for i := range 200 {
+		text.WriteString(strings.Repeat("line ", 10) + string(rune('a'+i%26)) + "\n")
+	}
+	parent := message{ID: "p1", Text: "the question"}
+	head, err := sendChat(s, text.String(), &parent, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Part != partStart || head.Parent != "p1" {
+		t.Errorf("returned %+v, want the start part of a reply", head)
+	}
+
+	keys, _ := loadRegistry("")
+	var got strings.Builder
+	var kinds []string
+	for len(out) > 0 {
+		line := <-out
+		if len(line)+seqOverhead+1 > 4096 {
+			t.Errorf("line of %d bytes is over the limit", len(line))
+		}
+		part, ok := decodeChatLine(line, keys)
+		if !ok || !part.Verified || part.ID != head.ID {
+			t.Fatalf("part %+v does not verify or belong to %s", part, head.ID)
+		}
+		if part.Part != partStart && (part.Parent != "" || part.Quote != "") {
+			t.Errorf("%s part repeats the reply", part.Part)
+		}
+		if !strings.HasSuffix(part.Text, "\n") {
+			t.Errorf("part does not end at a line break: %q", part.Text)
+		}
+		kinds = append(kinds, part.Part)
+		got.WriteString(part.Text)
+	}
+	if got.String() != text.String() {
+		t.Error("the parts do not add up to the message")
+	}
+	if len(kinds) < 3 || kinds[0] != partStart || kinds[len(kinds)-1] != partEnd {
+		t.Errorf("parts %v, want start, more… and end", kinds)
+	}
+	if pending := s.acks.unacked(); len(pending) != 1 || pending[0] != head.ID {
+		t.Errorf("waiting for acks on %v, want only the message ID", pending)
+	}
+
+	s.conn.caps.Streaming = false // A peer that cannot reassemble parts | peerی که بخش‌ها را سرهم نمی‌کند
+	if _, err := sendChat(s, text.String(), nil, false); !errors.Is(err, errTooLong) {
+		t.Errorf("without streaming: %v, want %v", err, errTooLong)
+	}
+}
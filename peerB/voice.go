@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"     // For command output
+	"os"      // For temporary recording files
+	"os/exec" // For running the ffmpeg/ffplay helpers
+	"runtime" // For choosing the platform's capture device
+	"strconv" // For parsing command arguments
+	"strings" // For trimming helper output
+	"time"    // For the recording length
+)
+
+/*
+Voice note configuration
+
+مقادیر پیکربندی پیام صوتی:
+- مدت پیش‌فرض و حداکثر ضبط
+- نوع محتوای فایل صوتی
+*/
+const (
+	voiceDefaultSeconds = 10          // Default recording length | مدت پیش‌فرض ضبط
+	voiceMaxSeconds     = 60          // Longest allowed recording | حداکثر مدت ضبط
+	voiceMIME           = "audio/ogg" // Opus in Ogg container | فرمت فایل صوتی
+)
+
+func init() {
+	registerCommand("voice", "/voice [seconds]  record and send a voice note", voiceCommand)
+	registerCommand("play", "/play <id>  play a received voice note", playCommand)
+}
+
+/*
+voiceCommand records a short clip with ffmpeg and sends it as a file
+transfer. Recording runs in the background so typing is not blocked.
+
+این دستور با ffmpeg یک صدای کوتاه ضبط و به‌صورت انتقال فایل ارسال می‌کند؛
+ضبط در پس‌زمینه انجام می‌شود تا تایپ کردن متوقف نشود
+*/
+func voiceCommand(s *session, args []string) {
+	seconds := voiceDefaultSeconds
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > voiceMaxSeconds {
+			fmt.Fprintf(stdout, "Usage: /voice [1-%d]\n", voiceMaxSeconds)
+			return
+		}
+		seconds = n
+	}
+
+	go func() {
+		fmt.Fprintf(stdout, "Recording %ds...\n", seconds)
+		path, err := recordVoice(seconds)
+		if err != nil {
+			fmt.Fprintln(stdout, "Record error:", err)
+			return
+		}
+		defer os.Remove(path) // Local copy no longer needed | نسخه محلی دیگر لازم نیست
+
+		h := fileHeader{
+			From:       s.name,
+			Name:       "voice.ogg",
+			MIME:       voiceMIME,
+			DurationMS: (time.Duration(seconds) * time.Second).Milliseconds(),
+		}
+		sum, err := sendFile(s, path, h)
+		if err != nil {
+			fmt.Fprintln(stdout, "Send error:", err)
+			return
+		}
+		fmt.Fprintf(stdout, "Voice note sent (%s)  sha256 %s\n", formatDuration(h.DurationMS), sum)
+		recordSent(s, fmt.Sprintf("[voice note, %s]", formatDuration(h.DurationMS)))
+	}()
+}
+
+/*
+playCommand plays a received voice note with ffplay.
+
+این دستور یک پیام صوتی دریافتی را با ffplay پخش می‌کند
+*/
+func playCommand(s *session, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(stdout, "Usage: /play <id>")
+		return
+	}
+	id, _ := strconv.Atoi(args[0])
+	f, ok := s.files.get(id)
+	if !ok || f.header.MIME != voiceMIME {
+		fmt.Fprintln(stdout, "No voice note with id", args[0])
+		return
+	}
+
+	go func() {
+		cmd := exec.Command("ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", f.path)
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintln(stdout, "Play error:", err)
+		}
+	}()
+}
+
+/*
+recordVoice captures audio from the default input device into a
+temporary Ogg/Opus file and returns its path.
+
+این تابع صدا را از ورودی پیش‌فرض سیستم ضبط و در یک فایل موقت
+Ogg/Opus ذخیره می‌کند و مسیر آن را برمی‌گرداند
+*/
+func recordVoice(seconds int) (string, error) {
+	f, err := os.CreateTemp("", "peerchat-voice-*.ogg")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	_ = f.Close()
+
+	args := []string{"-nostdin", "-loglevel", "error", "-y"} // Never read the chat's stdin | ورودی چت خوانده نشود
+	args = append(args, captureInputArgs()...)
+	args = append(args, "-t", strconv.Itoa(seconds), "-c:a", "libopus", path)
+
+	if out, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+		_ = os.Remove(path)
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("ffmpeg: %s", msg)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+/*
+captureInputArgs returns the ffmpeg input flags for the platform's
+default microphone.
+
+این تابع پارامترهای ورودی ffmpeg را برای میکروفون پیش‌فرض سیستم برمی‌گرداند
+*/
+func captureInputArgs() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"-f", "avfoundation", "-i", ":0"}
+	case "windows":
+		return []string{"-f", "dshow", "-i", "audio=default"}
+	default:
+		return []string{"-f", "pulse", "-i", "default"}
+	}
+}
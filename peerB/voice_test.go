@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestVoiceCommandBounds(t *testing.T) {
+	var out bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&out))
+	for _, arg := range []string{"0", "61", "ten"} {
+		out.Reset()
+		voiceCommand(&session{}, []string{arg})
+		if !strings.HasPrefix(out.String(), "Usage: /voice [1-60]") {
+			t.Errorf("/voice %s printed %q, want the usage", arg, out.String())
+		}
+	}
+}
+
+func TestPlayOnlyVoiceNotes(t *testing.T) {
+	var out bytes.Buffer
+	defer stdout.redirect(stdout.redirect(&out))
+	s := &session{files: newFileStore(t.TempDir(), maxFileSize, 0)}
+	id := s.files.add(fileHeader{Name: "notes.txt", MIME: "text/plain"}, "notes.txt")
+	for _, arg := range []string{"x", "0", "9", strconv.Itoa(id)} {
+		out.Reset()
+		playCommand(s, []string{arg})
+		if got := out.String(); got != "No voice note with id "+arg+"\n" {
+			t.Errorf("/play %s printed %q", arg, got)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for ms, want := range map[int64]string{0: "0:00", 9500: "0:09", 60000: "1:00", 61000: "1:01"} {
+		if got := formatDuration(ms); got != want {
+			t.Errorf("formatDuration(%d) = %q, want %q", ms, got, want)
+		}
+	}
+}
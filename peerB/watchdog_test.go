@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestDoneSignalRedial(t *testing.T) {
+	d := newDoneSignal()
+	d.redial()
+	d.close() // Too late to change the reason | دیرتر از آن که دلیل را عوض کند
+	if !d.redialing() {
+		t.Fatal("a link torn down for a redial does not ask for one")
+	}
+
+	d = newDoneSignal()
+	d.close()
+	d.redial() // The link already ended on its own | اتصال خودش تمام شده بود
+	if d.redialing() {
+		t.Fatal("a link that ended on its own asks for a redial")
+	}
+
+	parent, child := newDoneSignal(), newDoneSignal()
+	child.follow(parent)
+	parent.close()
+	<-child.c // Shutting down ends the link | خروج اتصال را پایان می‌دهد
+	if child.redialing() {
+		t.Fatal("shutting down asks for a redial")
+	}
+}
+
+func TestRelinkedSession(t *testing.T) {
+	old := &session{name: "b", parts: newPartTable(), sched: newLinkScheduler(), threads: newThreadIndex(), done: newDoneSignal()}
+	old.sent.Store(7)
+	old.taken.Store(8)
+	old.queued.Store(9)
+	old.done.redial()
+
+	done := newDoneSignal()
+	ctrl := newControlLink(done)
+	conn := &handshakeConn{}
+	s := old.relinked(conn, nil, ctrl, done)
+	if s.conn != conn || s.ctrl != ctrl || s.done != done {
+		t.Fatal("the new session is not on the new link")
+	}
+	if s.parts == old.parts || s.sched == old.sched {
+		t.Fatal("the new link shares the old link's transfers or scheduler")
+	}
+	if s.name != "b" || s.threads != old.threads {
+		t.Fatal("the new session lost what outlives a link")
+	}
+	if s.sent.Load() != 7 || s.taken.Load() != 8 || s.queued.Load() != 9 {
+		t.Fatalf("counters restarted: sent %d, taken %d, queued %d", s.sent.Load(), s.taken.Load(), s.queued.Load())
+	}
+	select {
+	case <-s.done.c:
+		t.Fatal("the new link starts closed")
+	default:
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"       // For the shutdown deadline
+	"crypto/subtle" // For comparing the bearer token
+	"net"           // For binding the listener up front
+	"net/http"      // For the health/debug HTTP server
+	"strings"       // For the Authorization header
+	"sync/atomic"   // For the readiness flag
+	"time"          // For server timeouts
+)
+
+const webShutdownTimeout = 2 * time.Second // Max wait for in-flight requests on exit | حداکثر انتظار برای درخواست‌های باز هنگام خروج
+
+/*
+startWebServer serves the health/debug endpoints on addr:
+- /healthz answers 200 as long as the process is alive
+- /readyz answers 200 only while the peer link is established
+- /metrics serves the self-metrics in the Prometheus text format
+- /transfers lists the file transfers in flight as JSON
+- /events streams received messages as Server-Sent Events
+The last three go to holders of token, or without a token only on a
+loopback address and to requests for localhost or a loopback IP. The returned stop function shuts the server down.
+
+این تابع endpointهای سلامت/دیباگ را روی addr ارائه می‌کند:
+- /healthz تا وقتی برنامه زنده است 200 برمی‌گرداند
+- /readyz فقط وقتی اتصال به peer برقرار است 200 برمی‌گرداند
+- /metrics متریک‌های برنامه را در قالب متنی پرومتئوس ارائه می‌کند
+- /transfers انتقال‌های فایل در جریان را به‌صورت JSON فهرست می‌کند
+- /events پیام‌های دریافتی را به‌صورت Server-Sent Events پخش می‌کند
+سه مورد آخر فقط به دارندگان token، یا بدون token فقط روی آدرس loopback
+و به درخواست‌هایی با Host برابر localhost یا IP از نوع loopback ارائه می‌شوند. تابع stop سرور را خاموش می‌کند
+*/
+func startWebServer(addr, token string, ready *atomic.Bool, stats *metrics) (stop func(), err error) {
+	ln, err := net.Listen("tcp", addr) // Bind now so errors surface at startup | خطای bind همان ابتدا گزارش شود
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not connected", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ready\n"))
+	})
+	loopback := isLoopback(ln.Addr())
+	mux.HandleFunc("/metrics", guardPrivate(token, loopback, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		stats.writePrometheus(w)
+	}))
+	mux.HandleFunc("/transfers", guardPrivate(token, loopback, transfers.serveTransfers))
+	mux.HandleFunc("GET /events", guardPrivate(token, loopback, events.serveEvents))
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	srv.RegisterOnShutdown(events.closeAll) // Streams never finish on their own | streamها خودشان تمام نمی‌شوند
+	go func() { _ = srv.Serve(ln) }()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}, nil
+}
+
+/*
+guardPrivate lets a request through to an endpoint that shows the chat,
+its transfers or its traffic only with the bearer token or, when none
+is configured, only on a loopback listener, so none of it is ever
+served to the network unauthenticated. Without a token the Host must
+name this machine too: a web page whose DNS name is rebound to 127.0.0.1
+reaches a loopback listener from the browser, but still sends its own
+name as Host.
+
+این تابع درخواست را به endpointی که چت، انتقال‌ها یا ترافیک آن را نشان
+می‌دهد فقط با token از نوع bearer راه می‌دهد، یا اگر tokenی تنظیم نشده فقط
+روی listener از نوع loopback، تا هیچ‌کدام هرگز بدون احراز هویت در شبکه ارائه نشوند.
+بدون token سرآیند Host هم باید همین دستگاه را نام ببرد: صفحه‌ی وبی که نام DNS
+آن دوباره به 127.0.0.1 نگاشت شده از مرورگر به listener از نوع loopback می‌رسد
+ولی همچنان نام خودش را در Host می‌فرستد
+*/
+func guardPrivate(token string, loopback bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case token != "":
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing or wrong bearer token", http.StatusUnauthorized)
+				return
+			}
+		case !loopback:
+			http.Error(w, "set http-token to serve "+r.URL.Path+" beyond loopback", http.StatusForbidden)
+			return
+		case !isLocalHost(r.Host):
+			http.Error(w, "set http-token to serve "+r.URL.Path+" under another host name", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isLocalHost reports whether a Host header names this machine: localhost or a loopback IP | آیا سرآیند Host همین دستگاه را نام می‌برد
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLoopback reports whether a listener only accepts local connections | آیا listener فقط اتصال محلی می‌پذیرد
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
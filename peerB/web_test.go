@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGuardPrivate(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) {}
+	for _, c := range []struct {
+		name     string
+		token    string
+		loopback bool
+		host     string
+		auth     string
+		want     int
+	}{
+		{"loopback without a token", "", true, "127.0.0.1:8090", "", http.StatusOK},
+		{"localhost without a token", "", true, "localhost:8090", "", http.StatusOK},
+		{"IPv6 loopback without a token", "", true, "[::1]:8090", "", http.StatusOK},
+		{"rebound name without a token", "", true, "evil.example:8090", "", http.StatusForbidden},
+		{"localhost subdomain without a token", "", true, "localhost.evil.example", "", http.StatusForbidden},
+		{"network without a token", "", false, "127.0.0.1:8090", "", http.StatusForbidden},
+		{"no bearer", "s3cret", true, "127.0.0.1:8090", "", http.StatusUnauthorized},
+		{"wrong bearer", "s3cret", false, "peer.example", "Bearer guess", http.StatusUnauthorized},
+		{"right bearer", "s3cret", false, "peer.example", "Bearer s3cret", http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Host = c.host
+		if c.auth != "" {
+			r.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		guardPrivate(c.token, c.loopback, next)(w, r)
+		if w.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, c.want)
+		}
+	}
+}
+
+func TestIsLocalHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost": true, "LOCALHOST:8090": true, "localhost.:8090": true, "127.0.0.1": true, "127.1.2.3:80": true, "[::1]:8090": true,
+		"": false, "evil.example": false, "localhost.evil.example:8090": false, "192.0.2.1:8090": false, "0.0.0.0": false,
+	} {
+		if got := isLocalHost(host); got != want {
+			t.Errorf("isLocalHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{"127.0.0.1:8090": true, "[::1]:8090": true, "0.0.0.0:8090": false, "192.0.2.1:8090": false} {
+		a, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isLoopback(a); got != want {
+			t.Errorf("isLoopback(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	var ready atomic.Bool
+	addr := freeAddr(t)
+	stop, err := startWebServer(addr, "", &ready, newMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK || body != "ok\n" {
+		t.Errorf("/healthz: %d %q, want 200 while alive", code, body)
+	}
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz without a link: %d, want 503", code)
+	}
+	ready.Store(true)
+	if code, body := get("/readyz"); code != http.StatusOK || body != "ready\n" {
+		t.Errorf("/readyz with a link: %d %q, want 200", code, body)
+	}
+	ready.Store(false)
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz after the link dropped: %d, want 503", code)
+	}
+
+	if _, err := startWebServer(addr, "", &ready, newMetrics()); err == nil {
+		t.Error("a busy address was only noticed after startup")
+	}
+}